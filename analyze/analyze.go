@@ -0,0 +1,343 @@
+// Package analyze inspects a repository for what makes agents slow or awkward to run in it, its size,
+// hooks, submodules, dependencies and tests, and recommends claude-squad settings for it.
+package analyze
+
+import (
+	"bytes"
+	"claude-squad/session"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// largeRepoFiles is the number of tracked files from which a repository counts as large: diffing and
+// watching its worktrees gets slow.
+const largeRepoFiles = 20000
+
+// heavyDependencySize is the size from which a dependency directory is worth linking into worktrees
+// rather than installing in each of them.
+const heavyDependencySize = 100 << 20
+
+// hookManagers are the files of tools that install git hooks.
+var hookManagers = []string{".husky", ".pre-commit-config.yaml", "lefthook.yml", "lefthook.yaml", ".lefthook.yml", ".lefthook.yaml"}
+
+var makeTestTargetRegex = regexp.MustCompile(`(?m)^test\s*:`)
+
+// lockfile pins the dependencies of an ecosystem, and is installed with its command.
+type lockfile struct {
+	name    string
+	install string
+}
+
+// ecosystemRule detects an ecosystem from the files in the root of a repository.
+type ecosystemRule struct {
+	name    string
+	markers []string
+	// lockfiles are in order of preference: a repository with several uses the first.
+	lockfiles     []lockfile
+	dependencyDir string
+	test          func(root string) string
+}
+
+var ecosystemRules = []ecosystemRule{
+	{
+		name:      "Go",
+		markers:   []string{"go.mod"},
+		lockfiles: []lockfile{{"go.sum", "go mod download"}},
+		test:      func(string) string { return "go test ./..." },
+	},
+	{
+		name:    "Node.js",
+		markers: []string{"package.json"},
+		lockfiles: []lockfile{
+			{"pnpm-lock.yaml", "pnpm install --frozen-lockfile"},
+			{"yarn.lock", "yarn install --frozen-lockfile"},
+			{"bun.lockb", "bun install --frozen-lockfile"},
+			{"package-lock.json", "npm ci"},
+		},
+		dependencyDir: "node_modules",
+		test:          npmTest,
+	},
+	{
+		name:          "Rust",
+		markers:       []string{"Cargo.toml"},
+		lockfiles:     []lockfile{{"Cargo.lock", "cargo fetch"}},
+		dependencyDir: "target",
+		test:          func(string) string { return "cargo test" },
+	},
+	{
+		name:          "Python",
+		markers:       []string{"pyproject.toml", "setup.py", "requirements.txt"},
+		lockfiles:     []lockfile{{"uv.lock", "uv sync"}, {"poetry.lock", "poetry install"}},
+		dependencyDir: ".venv",
+		test:          pytest,
+	},
+	{
+		name:          "Ruby",
+		markers:       []string{"Gemfile"},
+		lockfiles:     []lockfile{{"Gemfile.lock", "bundle install"}},
+		dependencyDir: "vendor/bundle",
+		test:          rubyTest,
+	},
+}
+
+// Ecosystem is a language ecosystem the repository uses.
+type Ecosystem struct {
+	Name string
+	// Lockfile is the file its dependencies are pinned in, if any.
+	Lockfile string
+	// Install installs its dependencies, if it's known how.
+	Install string
+	// DependencyDir is the directory its dependencies are installed into, relative to the root of the
+	// repository, if they aren't in a shared cache.
+	DependencyDir string
+	// DependencySize is the size of the dependency directory in the repository, 0 if they aren't
+	// installed there.
+	DependencySize int64
+}
+
+// DirCount is the number of tracked files of a directory.
+type DirCount struct {
+	Dir   string
+	Files int
+}
+
+// Report is what an analysis found in a repository.
+type Report struct {
+	Root string
+	// Files and Bytes are the number and size of the tracked files.
+	Files int
+	Bytes int64
+	// LargestDirs are the top-level directories with the most tracked files, most first.
+	LargestDirs []DirCount
+	// Hooks are the active git hooks, and the files of the tools that install them.
+	Hooks      []string
+	Submodules []string
+	Ecosystems []Ecosystem
+	// EnvFiles are the dotenv files in the root of the repository that aren't tracked.
+	EnvFiles []string
+	// HasOrigin is true if the repository has an origin remote.
+	HasOrigin bool
+	// TestCommand runs the tests of the repository, if it's known how.
+	TestCommand string
+	// TestDuration is how long the test command took, if it was run.
+	TestDuration time.Duration
+	// TestErr is why the test command failed, if it was run.
+	TestErr error
+}
+
+// Analyze inspects the repository at root. The test command is only run, and timed, if runTests is true,
+// since it may take a while.
+func Analyze(root string, runTests bool) (*Report, error) {
+	r := &Report{Root: root}
+	files, err := git(root, "ls-files", "-z")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tracked files: %w", err)
+	}
+	dirs := make(map[string]int)
+	for _, file := range strings.Split(strings.TrimRight(files, "\x00"), "\x00") {
+		if file == "" {
+			continue
+		}
+		r.Files++
+		if info, err := os.Lstat(filepath.Join(root, file)); err == nil {
+			r.Bytes += info.Size()
+		}
+		if dir, _, ok := strings.Cut(file, "/"); ok {
+			dirs[dir]++
+		}
+	}
+	for dir, count := range dirs {
+		r.LargestDirs = append(r.LargestDirs, DirCount{Dir: dir, Files: count})
+	}
+	sort.Slice(r.LargestDirs, func(i, j int) bool {
+		if r.LargestDirs[i].Files != r.LargestDirs[j].Files {
+			return r.LargestDirs[i].Files > r.LargestDirs[j].Files
+		}
+		return r.LargestDirs[i].Dir < r.LargestDirs[j].Dir
+	})
+
+	r.Hooks = hooks(root)
+	if output, err := git(root, "config", "--file", ".gitmodules", "--get-regexp", `submodule\..*\.path`); err == nil {
+		for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+			if _, path, ok := strings.Cut(line, " "); ok {
+				r.Submodules = append(r.Submodules, path)
+			}
+		}
+	}
+	r.Ecosystems = ecosystems(root)
+	r.TestCommand = testCommand(root)
+	if matches, err := filepath.Glob(filepath.Join(root, ".env*")); err == nil {
+		for _, match := range matches {
+			name := filepath.Base(match)
+			if name != ".env" && !strings.HasPrefix(name, ".env.") {
+				continue
+			}
+			if _, err := git(root, "ls-files", "--error-unmatch", name); err != nil {
+				r.EnvFiles = append(r.EnvFiles, name)
+			}
+		}
+	}
+	_, err = git(root, "remote", "get-url", "origin")
+	r.HasOrigin = err == nil
+
+	if runTests && r.TestCommand != "" {
+		r.TestDuration, r.TestErr = session.TimeCheckCommand(root, r.TestCommand)
+	}
+	return r, nil
+}
+
+// Large returns true if the repository has so many files that diffing and watching its worktrees is
+// slow.
+func (r *Report) Large() bool {
+	return r.Files >= largeRepoFiles
+}
+
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %s (%w)", strings.Join(args, " "), strings.TrimSpace(stderr.String()), err)
+	}
+	return string(output), nil
+}
+
+func exists(root string, name string) bool {
+	_, err := os.Stat(filepath.Join(root, name))
+	return err == nil
+}
+
+// hooks returns the git hooks of the repository that aren't samples, and the files of the hook managers
+// it uses.
+func hooks(root string) []string {
+	var found []string
+	dir, err := git(root, "config", "core.hooksPath")
+	if err != nil {
+		dir, err = git(root, "rev-parse", "--git-path", "hooks")
+	}
+	if err == nil {
+		dir = strings.TrimSpace(dir)
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(root, dir)
+		}
+		entries, _ := os.ReadDir(dir)
+		for _, entry := range entries {
+			if entry.Type().IsRegular() && !strings.HasSuffix(entry.Name(), ".sample") {
+				found = append(found, entry.Name())
+			}
+		}
+	}
+	for _, manager := range hookManagers {
+		if exists(root, manager) {
+			found = append(found, manager)
+		}
+	}
+	return found
+}
+
+func ecosystems(root string) []Ecosystem {
+	var found []Ecosystem
+	for _, rule := range ecosystemRules {
+		if !slices.ContainsFunc(rule.markers, func(marker string) bool { return exists(root, marker) }) {
+			continue
+		}
+		ecosystem := Ecosystem{Name: rule.name, DependencyDir: rule.dependencyDir}
+		for _, lock := range rule.lockfiles {
+			if exists(root, lock.name) {
+				ecosystem.Lockfile = lock.name
+				ecosystem.Install = lock.install
+				break
+			}
+		}
+		if rule.dependencyDir != "" {
+			ecosystem.DependencySize = dirSize(filepath.Join(root, rule.dependencyDir))
+		}
+		found = append(found, ecosystem)
+	}
+	return found
+}
+
+// testCommand returns the command that runs the tests of the repository: its make target if it has
+// one, and otherwise the test runner of the first ecosystem that has one.
+func testCommand(root string) string {
+	if data, err := os.ReadFile(filepath.Join(root, "Makefile")); err == nil && makeTestTargetRegex.Match(data) {
+		return "make test"
+	}
+	for _, rule := range ecosystemRules {
+		if !slices.ContainsFunc(rule.markers, func(marker string) bool { return exists(root, marker) }) {
+			continue
+		}
+		if command := rule.test(root); command != "" {
+			return command
+		}
+	}
+	return ""
+}
+
+// npmTest returns "npm test" if the package has a test script other than the one npm init writes.
+func npmTest(root string) string {
+	data, err := os.ReadFile(filepath.Join(root, "package.json"))
+	if err != nil {
+		return ""
+	}
+	var pkg struct {
+		Scripts map[string]string `json:"scripts"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return ""
+	}
+	script := pkg.Scripts["test"]
+	if script == "" || strings.Contains(script, "no test specified") {
+		return ""
+	}
+	return "npm test"
+}
+
+func pytest(root string) string {
+	for _, name := range []string{"pytest.ini", "conftest.py", "tox.ini"} {
+		if exists(root, name) {
+			return "pytest"
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(root, "pyproject.toml")); err == nil && bytes.Contains(data, []byte("[tool.pytest")) {
+		return "pytest"
+	}
+	return ""
+}
+
+func rubyTest(root string) string {
+	if exists(root, "spec") {
+		return "bundle exec rspec"
+	}
+	if exists(root, "Rakefile") {
+		return "bundle exec rake test"
+	}
+	return ""
+}
+
+// dirSize returns the size of the files under dir, 0 if it doesn't exist.
+func dirSize(dir string) int64 {
+	var size int64
+	_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
@@ -0,0 +1,156 @@
+package analyze
+
+import (
+	"claude-squad/config"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRepo creates a repository with the files, committed.
+func newRepo(t *testing.T, files map[string]string) string {
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "."},
+		{"-c", "user.name=Test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "initial"},
+	} {
+		output, err := exec.Command("git", append([]string{"-C", root}, args...)...).CombinedOutput()
+		require.NoError(t, err, string(output))
+	}
+	return root
+}
+
+func TestAnalyze(t *testing.T) {
+	root := newRepo(t, map[string]string{
+		"go.mod":                  "module example\n",
+		"go.sum":                  "",
+		"package.json":            `{"scripts": {"test": "echo \"Error: no test specified\" && exit 1"}}`,
+		"package-lock.json":       "{}",
+		"web/index.js":            "",
+		"web/app.js":              "",
+		".gitmodules":             "[submodule \"vendor/lib\"]\n\tpath = vendor/lib\n\turl = https://example.com/lib.git\n",
+		".pre-commit-config.yaml": "repos: []\n",
+		".env.example":            "KEY=\n",
+	})
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".env"), []byte("KEY=secret\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".git", "hooks", "pre-push"), []byte("#!/bin/sh\n"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "node_modules", "left-pad"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "node_modules", "left-pad", "index.js"), []byte("module.exports = 1\n"), 0644))
+
+	report, err := Analyze(root, false)
+	require.NoError(t, err)
+	assert.Equal(t, 9, report.Files)
+	assert.Equal(t, []DirCount{{Dir: "web", Files: 2}}, report.LargestDirs)
+	assert.Equal(t, []string{"pre-push", ".pre-commit-config.yaml"}, report.Hooks)
+	assert.Equal(t, []string{"vendor/lib"}, report.Submodules)
+	assert.Equal(t, []string{".env"}, report.EnvFiles)
+	assert.False(t, report.HasOrigin)
+	// The npm test script is the placeholder of npm init, so the Go tests are the ones run.
+	assert.Equal(t, "go test ./...", report.TestCommand)
+	require.Len(t, report.Ecosystems, 2)
+	assert.Equal(t, Ecosystem{Name: "Go", Lockfile: "go.sum", Install: "go mod download"}, report.Ecosystems[0])
+	assert.Equal(t, "npm ci", report.Ecosystems[1].Install)
+	assert.Equal(t, int64(len("module.exports = 1\n")), report.Ecosystems[1].DependencySize)
+}
+
+func TestTestCommand(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{"make target first", map[string]string{"Makefile": "build:\n\tgo build\ntest: build\n\tgo test\n", "go.mod": ""}, "make test"},
+		{"npm script", map[string]string{"package.json": `{"scripts": {"test": "vitest"}}`}, "npm test"},
+		{"pytest config", map[string]string{"pyproject.toml": "[tool.pytest.ini_options]\n"}, "pytest"},
+		{"python without pytest", map[string]string{"requirements.txt": "requests\n"}, ""},
+		{"rspec", map[string]string{"Gemfile": "", "spec/a_spec.rb": ""}, "bundle exec rspec"},
+		{"nothing", map[string]string{"README.md": ""}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			for name, content := range tt.files {
+				require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(root, name)), 0755))
+				require.NoError(t, os.WriteFile(filepath.Join(root, name), []byte(content), 0644))
+			}
+			assert.Equal(t, tt.want, testCommand(root))
+		})
+	}
+}
+
+func TestRecommend(t *testing.T) {
+	report := &Report{
+		Files:       largeRepoFiles,
+		LargestDirs: []DirCount{{"services", 15000}, {"web", 4000}, {"docs", 900}, {"tools", 100}},
+		Hooks:       []string{"pre-commit"},
+		Submodules:  []string{"vendor/lib"},
+		Ecosystems: []Ecosystem{
+			{Name: "Go", Lockfile: "go.sum", Install: "go mod download"},
+			{Name: "Node.js", Lockfile: "yarn.lock", Install: "yarn install --frozen-lockfile", DependencyDir: "node_modules", DependencySize: heavyDependencySize},
+		},
+		EnvFiles:    []string{".env", ".env.local"},
+		HasOrigin:   true,
+		TestCommand: "make test",
+	}
+
+	t.Run("nothing set", func(t *testing.T) {
+		recs := report.Recommend(&config.RepoConfig{}, &config.Config{SecretEnvFiles: []string{".env"}})
+		settings := make(map[string]Recommendation)
+		for _, rec := range recs {
+			settings[rec.Setting] = rec
+		}
+		assert.Equal(t, "make test", settings["check_command"].Value)
+		assert.Equal(t, true, settings["require_checks"].Value)
+		// The node modules are linked rather than installed.
+		assert.Equal(t, []string{"git submodule update --init --recursive", "go mod download"}, settings["warmup_commands"].Value)
+		assert.Equal(t, []string{"node_modules"}, settings["link_on_create"].Value)
+		assert.True(t, settings["link_on_create"].Global)
+		assert.Nil(t, settings["diff_paths"].Value)
+		assert.Contains(t, settings["diff_paths"].Reason, "services (15000 files), web (4000 files), docs (900 files)")
+		assert.Equal(t, []string{".env", ".env.local"}, settings["secret_env_files"].Value)
+		assert.Equal(t, largeUpstreamCheckInterval, settings["upstream_check_interval"].Value)
+
+		assert.Equal(t, map[string]any{
+			"check_command":   "make test",
+			"require_checks":  true,
+			"warmup_commands": []string{"git submodule update --init --recursive", "go mod download"},
+		}, RepoSettings(recs))
+	})
+
+	t.Run("everything set", func(t *testing.T) {
+		recs := report.Recommend(&config.RepoConfig{
+			CheckCommand:   "make check",
+			RequireChecks:  true,
+			WarmupCommands: []string{"make deps"},
+			DiffPaths:      []string{"services/auth"},
+		}, &config.Config{
+			LinkOnCreate:          []string{"node_modules"},
+			SecretEnvFiles:        []string{".env", ".env.local"},
+			UpstreamCheckInterval: 60,
+		})
+		assert.Empty(t, recs)
+	})
+
+	t.Run("tests too slow", func(t *testing.T) {
+		slow := *report
+		slow.TestErr = fmt.Errorf("make test timed out: %w", context.DeadlineExceeded)
+		recs := slow.Recommend(&config.RepoConfig{}, &config.Config{})
+		assert.Equal(t, "check_command", recs[0].Setting)
+		assert.Nil(t, recs[0].Value)
+		assert.Contains(t, recs[0].Reason, "longer than checks may")
+		// Without a check command to require, there's nothing to require.
+		assert.NotContains(t, RepoSettings(recs), "require_checks")
+	})
+}
@@ -0,0 +1,238 @@
+package analyze
+
+import (
+	"claude-squad/config"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// upstreamCheckInterval is the recommended interval, in seconds, at which origin is fetched, and
+// largeUpstreamCheckInterval the one for large repositories, whose fetches are slower.
+const (
+	upstreamCheckInterval      = 300
+	largeUpstreamCheckInterval = 900
+)
+
+// Recommendation is a setting recommended for the repository.
+type Recommendation struct {
+	// Setting is the name of the setting in the settings file.
+	Setting string
+	// Value is the recommended value, nil for advice that has to be acted on by hand.
+	Value  any
+	Reason string
+	// Global settings belong in the global config file, the others in the settings file of the
+	// repository.
+	Global bool
+}
+
+// Recommend returns the settings recommended for the repository that aren't set yet, given its settings
+// and the global ones.
+func (r *Report) Recommend(repo *config.RepoConfig, global *config.Config) []Recommendation {
+	var recs []Recommendation
+
+	if repo.CheckCommand == "" && r.TestCommand != "" {
+		switch {
+		case errors.Is(r.TestErr, context.DeadlineExceeded):
+			recs = append(recs, Recommendation{
+				Setting: "check_command",
+				Reason:  fmt.Sprintf("%s ran longer than checks may; set a faster subset of the tests", r.TestCommand),
+			})
+		case r.TestErr != nil:
+			recs = append(recs, Recommendation{
+				Setting: "check_command",
+				Reason:  fmt.Sprintf("%s failed on the repository as it is; set a command that passes", r.TestCommand),
+			})
+		default:
+			reason := "runs the tests of the repository"
+			if r.TestDuration > 0 {
+				reason = fmt.Sprintf("runs the tests of the repository, in %s", r.TestDuration.Round(100*time.Millisecond))
+			}
+			recs = append(recs, Recommendation{Setting: "check_command", Value: r.TestCommand, Reason: reason})
+		}
+	}
+	if !repo.RequireChecks && len(r.Hooks) > 0 && (repo.CheckCommand != "" || (r.TestCommand != "" && r.TestErr == nil)) {
+		recs = append(recs, Recommendation{
+			Setting: "require_checks",
+			Value:   true,
+			Reason: fmt.Sprintf("the repository has commit hooks (%s), which the commits of instances skip",
+				strings.Join(r.Hooks, ", ")),
+		})
+	}
+
+	var warmup []string
+	var links []string
+	if len(r.Submodules) > 0 {
+		warmup = append(warmup, "git submodule update --init --recursive")
+	}
+	for _, ecosystem := range r.Ecosystems {
+		linked := ecosystem.DependencyDir != "" && slices.Contains(global.LinkOnCreate, ecosystem.DependencyDir)
+		switch {
+		case linked:
+		case ecosystem.DependencySize >= heavyDependencySize:
+			// Linking spares installing them, and installing into a linked directory would change it for
+			// every instance.
+			links = append(links, ecosystem.DependencyDir)
+		case ecosystem.Install != "":
+			warmup = append(warmup, ecosystem.Install)
+		}
+	}
+	if len(repo.WarmupCommands) == 0 && len(warmup) > 0 {
+		recs = append(recs, Recommendation{
+			Setting: "warmup_commands",
+			Value:   warmup,
+			Reason:  "new worktrees have no submodules or dependencies installed",
+		})
+	}
+	if len(links) > 0 {
+		recs = append(recs, Recommendation{
+			Setting: "link_on_create",
+			Value:   append(slices.Clone(global.LinkOnCreate), links...),
+			Reason:  fmt.Sprintf("%s is too heavy to install in every worktree", strings.Join(links, " and ")),
+			Global:  true,
+		})
+	}
+
+	if r.Large() && len(repo.DiffPaths) == 0 {
+		var dirs []string
+		for _, dir := range r.LargestDirs[:min(3, len(r.LargestDirs))] {
+			dirs = append(dirs, fmt.Sprintf("%s (%d files)", dir.Dir, dir.Files))
+		}
+		recs = append(recs, Recommendation{
+			Setting: "diff_paths",
+			Reason: fmt.Sprintf("the repository has %d files, which are slow to diff and watch; limit them to the "+
+				"areas agents work in, the largest being %s", r.Files, strings.Join(dirs, ", ")),
+		})
+	}
+
+	var envFiles []string
+	for _, file := range r.EnvFiles {
+		if !slices.Contains(global.SecretEnvFiles, file) && !slices.Contains(global.CopyOnCreate, file) {
+			envFiles = append(envFiles, file)
+		}
+	}
+	if len(envFiles) > 0 {
+		recs = append(recs, Recommendation{
+			Setting: "secret_env_files",
+			Value:   append(slices.Clone(global.SecretEnvFiles), envFiles...),
+			Reason:  "new worktrees don't have the untracked dotenv files, and agents shouldn't read them",
+			Global:  true,
+		})
+	}
+
+	if r.HasOrigin && global.UpstreamCheckInterval == 0 {
+		interval := upstreamCheckInterval
+		if r.Large() {
+			interval = largeUpstreamCheckInterval
+		}
+		recs = append(recs, Recommendation{
+			Setting: "upstream_check_interval",
+			Value:   interval,
+			Reason:  "flags instances that drifted behind origin",
+			Global:  true,
+		})
+	}
+	return recs
+}
+
+// RepoSettings returns the values of the recommendations for the settings file of the repository.
+func RepoSettings(recs []Recommendation) map[string]any {
+	settings := make(map[string]any)
+	for _, rec := range recs {
+		if !rec.Global && rec.Value != nil {
+			settings[rec.Setting] = rec.Value
+		}
+	}
+	return settings
+}
+
+// Format formats the report and its recommendations as text.
+func Format(r *Report, recs []Recommendation) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Repository: %s\n", r.Root)
+	fmt.Fprintf(&b, "  Files: %d tracked, %s\n", r.Files, formatSize(r.Bytes))
+	if len(r.Hooks) > 0 {
+		fmt.Fprintf(&b, "  Hooks: %s\n", strings.Join(r.Hooks, ", "))
+	}
+	if len(r.Submodules) > 0 {
+		fmt.Fprintf(&b, "  Submodules: %s\n", strings.Join(r.Submodules, ", "))
+	}
+	for _, ecosystem := range r.Ecosystems {
+		fmt.Fprintf(&b, "  %s:", ecosystem.Name)
+		if ecosystem.Lockfile != "" {
+			fmt.Fprintf(&b, " %s", ecosystem.Lockfile)
+		}
+		if ecosystem.DependencySize > 0 {
+			fmt.Fprintf(&b, ", %s in %s", formatSize(ecosystem.DependencySize), ecosystem.DependencyDir)
+		}
+		b.WriteString("\n")
+	}
+	if r.TestCommand != "" {
+		fmt.Fprintf(&b, "  Tests: %s", r.TestCommand)
+		switch {
+		case r.TestErr != nil:
+			fmt.Fprintf(&b, " (failed after %s)", r.TestDuration.Round(100*time.Millisecond))
+		case r.TestDuration > 0:
+			fmt.Fprintf(&b, " (%s)", r.TestDuration.Round(100*time.Millisecond))
+		}
+		b.WriteString("\n")
+	}
+
+	if len(recs) == 0 {
+		b.WriteString("\nNo recommendations: the settings suit the repository.\n")
+		return b.String()
+	}
+	for _, global := range []bool{false, true} {
+		var section []Recommendation
+		for _, rec := range recs {
+			if rec.Global == global {
+				section = append(section, rec)
+			}
+		}
+		if len(section) == 0 {
+			continue
+		}
+		if global {
+			b.WriteString("\nRecommended for the global config:\n")
+		} else {
+			fmt.Fprintf(&b, "\nRecommended for %s:\n", config.RepoConfigFileName)
+		}
+		for _, rec := range section {
+			if rec.Value == nil {
+				fmt.Fprintf(&b, "  %s: %s\n", rec.Setting, rec.Reason)
+				continue
+			}
+			fmt.Fprintf(&b, "  %s = %s\n      %s\n", rec.Setting, formatValue(rec.Value), rec.Reason)
+		}
+	}
+	return b.String()
+}
+
+func formatValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return fmt.Sprintf("%q", v)
+	case []string:
+		quoted := make([]string, len(v))
+		for i, s := range v {
+			quoted[i] = fmt.Sprintf("%q", s)
+		}
+		return "[" + strings.Join(quoted, ", ") + "]"
+	}
+	return fmt.Sprint(value)
+}
+
+func formatSize(bytes int64) string {
+	switch {
+	case bytes >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(bytes)/(1<<30))
+	case bytes >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(bytes)/(1<<20))
+	case bytes >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(bytes)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", bytes)
+}
@@ -4,12 +4,18 @@ import (
 	"claude-squad/config"
 	"claude-squad/keys"
 	"claude-squad/log"
+	"claude-squad/notify"
+	"claude-squad/report"
 	"claude-squad/session"
+	"claude-squad/slack"
+	"claude-squad/telemetry"
 	"claude-squad/ui"
 	"claude-squad/ui/overlay"
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"regexp"
 	"time"
 
 	"github.com/charmbracelet/bubbles/spinner"
@@ -19,15 +25,30 @@ import (
 
 const GlobalInstanceLimit = 10
 
+// heatMapDepth is the number of directory levels shown in the heat map.
+const heatMapDepth = 3
+
 // Run is the main entrypoint into the application.
-func Run(ctx context.Context, program string, autoYes bool) error {
+// Run runs the app until it's quit. It returns the profile to switch to if it was quit for that, see
+// config.SetProfile.
+func Run(ctx context.Context, program string, autoYes bool) (string, error) {
+	if err := keys.Remap(config.LoadConfig().Keys); err != nil {
+		return "", fmt.Errorf("invalid keys in config: %w", err)
+	}
+	// Stop what runs in the background, e.g. the Slack bridge, once the app quits, so it doesn't keep
+	// running for the old profile after a switch.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 	p := tea.NewProgram(
 		newHome(ctx, program, autoYes),
 		tea.WithAltScreen(),
 		tea.WithMouseCellMotion(), // Mouse scroll
 	)
-	_, err := p.Run()
-	return err
+	model, err := p.Run()
+	if err != nil {
+		return "", err
+	}
+	return model.(*home).nextProfile, nil
 }
 
 type state int
@@ -42,6 +63,16 @@ const (
 	stateHelp
 	// stateConfirm is the state when a confirmation modal is displayed.
 	stateConfirm
+	// stateSelect is the state when a selection list is displayed.
+	stateSelect
+	// stateCompare is the state when the instances of a fan-out group are compared.
+	stateCompare
+	// stateReview is the state when the diff of an instance is reviewed.
+	stateReview
+	// stateObserve is the state when the pane of an instance is observed read-only.
+	stateObserve
+	// stateQuickInput is the state when the keys typed go to an instance through the quick input.
+	stateQuickInput
 )
 
 type home struct {
@@ -58,6 +89,22 @@ type home struct {
 	appConfig *config.Config
 	// appState stores persistent application state like seen help screens
 	appState config.AppState
+	// backoff coordinates the instances when the provider rate limits
+	backoff session.Backoff
+	// slack is the client of the Slack bridge, if it's configured
+	slack *slack.Client
+	// slackCommands receives the /squad commands from Slack
+	slackCommands chan slack.Command
+	// slackThreads are the timestamps of the Slack threads of the instances
+	slackThreads map[*session.Instance]string
+	// notifier posts the events of the instances to the webhooks, and tracks the instances posted as ready
+	notifier *notify.Notifier
+	// stuckPatterns are the prompts that make an instance need attention
+	stuckPatterns []*regexp.Regexp
+	// approvalPolicy tells which permission prompts auto-yes answers
+	approvalPolicy session.ApprovalPolicy
+	// readyDetectors tell when the programs of the instances are done working
+	readyDetectors []config.ReadyDetector
 
 	// -- State --
 
@@ -76,6 +123,18 @@ type home struct {
 	// keySent is used to manage underlining menu items
 	keySent bool
 
+	// nextProfile is the profile to switch to once the app quits, empty to exit
+	nextProfile string
+
+	// windowSize is the size of the terminal, to lay the components out again when the layout changes
+	windowSize tea.WindowSizeMsg
+	// split shows the list, the preview and the diff side by side if the terminal is wide enough
+	split bool
+	// splitRatios are the relative widths of the list, the preview and the diff in the split layout
+	splitRatios [3]int
+	// diffFocused gives the focus to the diff instead of the list in the split layout
+	diffFocused bool
+
 	// -- UI Components --
 
 	// list displays the list of instances
@@ -94,6 +153,30 @@ type home struct {
 	textOverlay *overlay.TextOverlay
 	// confirmationOverlay displays confirmation modals
 	confirmationOverlay *overlay.ConfirmationOverlay
+	// confirmedMsg is the result of the confirmed action, e.g. its error, handled once the modal closes
+	confirmedMsg tea.Msg
+	// selectionOverlay displays selection lists
+	selectionOverlay *overlay.SelectionOverlay
+	// selectionHandler is called with the selected index when an item of the selection overlay is chosen
+	selectionHandler func(idx int) tea.Cmd
+	// promptHandler, if set, is called with the value of the text input overlay instead of sending it to
+	// the selected instance
+	promptHandler func(value string) tea.Cmd
+	// comparisonOverlay displays the diffs of a fan-out group side by side
+	comparisonOverlay *overlay.ComparisonOverlay
+	// comparisonHandler is called with the index of the column picked in the comparison overlay
+	comparisonHandler func(idx int) tea.Cmd
+	// reviewOverlay displays the diff of an instance to comment on
+	reviewOverlay *overlay.ReviewOverlay
+	// reviewHandler is called with the action picked in the review overlay and the line under the cursor
+	reviewHandler func(action overlay.ReviewAction, idx int) tea.Cmd
+	// observeOverlay mirrors the pane of the observed instance read-only
+	observeOverlay *overlay.ObserveOverlay
+	// observed is the instance whose pane the observe overlay mirrors
+	observed *session.Instance
+	// quickInput types into quickInputTarget from the bottom of the preview
+	quickInput       *ui.QuickInput
+	quickInputTarget *session.Instance
 }
 
 func newHome(ctx context.Context, program string, autoYes bool) *home {
@@ -102,6 +185,10 @@ func newHome(ctx context.Context, program string, autoYes bool) *home {
 
 	// Load application state
 	appState := config.LoadState()
+	if err := appState.LoadError(); err != nil {
+		fmt.Printf("Failed to load the state: %v\n", err)
+		os.Exit(1)
+	}
 
 	// Initialize storage
 	storage, err := session.NewStorage(appState)
@@ -114,7 +201,7 @@ func newHome(ctx context.Context, program string, autoYes bool) *home {
 		ctx:          ctx,
 		spinner:      spinner.New(spinner.WithSpinner(spinner.MiniDot)),
 		menu:         ui.NewMenu(),
-		tabbedWindow: ui.NewTabbedWindow(ui.NewPreviewPane(), ui.NewDiffPane()),
+		tabbedWindow: ui.NewTabbedWindow(ui.NewPreviewPane(), ui.NewDiffPane(), ui.NewNotesPane()),
 		errBox:       ui.NewErrBox(),
 		storage:      storage,
 		appConfig:    appConfig,
@@ -122,8 +209,30 @@ func newHome(ctx context.Context, program string, autoYes bool) *home {
 		autoYes:      autoYes,
 		state:        stateDefault,
 		appState:     appState,
+		split:        appConfig.Layout.Mode == config.LayoutSplit,
+		splitRatios:  appConfig.Layout.SplitRatios(),
 	}
 	h.list = ui.NewList(&h.spinner, autoYes)
+	h.list.SetDriftThreshold(appConfig.GetDriftThreshold())
+	h.list.SetDiffBudget(appConfig.DiffBudget)
+	h.list.SetProfile(config.Profile())
+	h.applyTheme()
+	h.notifier, err = notify.New(appConfig)
+	if err != nil {
+		log.ErrorLog.Printf("failed to load notify backends: %v", err)
+	}
+	h.stuckPatterns, err = appConfig.GetStuckPatterns()
+	if err != nil {
+		log.ErrorLog.Printf("failed to load stuck patterns: %v", err)
+	}
+	h.readyDetectors, err = appConfig.GetReadyDetectors()
+	if err != nil {
+		log.ErrorLog.Printf("failed to load ready rules: %v", err)
+	}
+	h.approvalPolicy, err = session.NewApprovalPolicy(appConfig.AutoYesPolicy)
+	if err != nil {
+		log.ErrorLog.Printf("failed to load the auto-yes policy, holding every prompt: %v", err)
+	}
 
 	// Load saved instances
 	instances, err := storage.LoadInstances()
@@ -147,8 +256,14 @@ func newHome(ctx context.Context, program string, autoYes bool) *home {
 // updateHandleWindowSizeEvent sets the sizes of the components.
 // The components will try to render inside their bounds.
 func (m *home) updateHandleWindowSizeEvent(msg tea.WindowSizeMsg) {
-	// List takes 30% of width, preview takes 70%
+	m.windowSize = msg
+	split := m.splitFits(msg.Width)
+
+	// List takes 30% of width, preview takes 70%, unless the layout is split
 	listWidth := int(float32(msg.Width) * 0.3)
+	if split {
+		listWidth = msg.Width * m.splitRatios[0] / (m.splitRatios[0] + m.splitRatios[1] + m.splitRatios[2])
+	}
 	tabsWidth := msg.Width - listWidth
 
 	// Menu takes 10% of height, list and window take 90%
@@ -156,7 +271,14 @@ func (m *home) updateHandleWindowSizeEvent(msg tea.WindowSizeMsg) {
 	menuHeight := msg.Height - contentHeight - 1     // minus 1 for error box
 	m.errBox.SetSize(int(float32(msg.Width)*0.9), 1) // error box takes 1 row
 
-	m.tabbedWindow.SetSize(tabsWidth, contentHeight)
+	if split {
+		m.tabbedWindow.SetSplitSize(tabsWidth, contentHeight, m.splitRatios[1], m.splitRatios[2])
+	} else {
+		m.tabbedWindow.SetSize(tabsWidth, contentHeight)
+	}
+	m.tabbedWindow.SetDiffFocused(m.diffFocused)
+	m.list.SetFocused(!split || !m.diffFocused)
+	m.menu.SetInDiffTab(m.tabbedWindow.IsInDiffTab())
 	m.list.SetSize(listWidth, contentHeight)
 
 	if m.textInputOverlay != nil {
@@ -165,6 +287,15 @@ func (m *home) updateHandleWindowSizeEvent(msg tea.WindowSizeMsg) {
 	if m.textOverlay != nil {
 		m.textOverlay.SetWidth(int(float32(msg.Width) * 0.6))
 	}
+	if m.comparisonOverlay != nil {
+		m.comparisonOverlay.SetSize(int(float32(msg.Width)*0.9), int(float32(msg.Height)*0.8))
+	}
+	if m.observeOverlay != nil {
+		m.observeOverlay.SetSize(int(float32(msg.Width)*0.9), int(float32(msg.Height)*0.9))
+	}
+	if m.reviewOverlay != nil {
+		m.reviewOverlay.SetSize(int(float32(msg.Width)*0.9), int(float32(msg.Height)*0.8))
+	}
 
 	previewWidth, previewHeight := m.tabbedWindow.GetPreviewSize()
 	if err := m.list.SetSessionPreviewSize(previewWidth, previewHeight); err != nil {
@@ -183,6 +314,14 @@ func (m *home) Init() tea.Cmd {
 			return previewTickMsg{}
 		},
 		tickUpdateMetadataCmd,
+		tickResourceUsageCmd,
+		tickBackupConversationsCmd,
+		m.appendPaneLogs(),
+		m.checkUpstream(),
+		pollTriggers(m.appConfig.Triggers, 0),
+		m.startSlack(),
+		m.offerRecovery(),
+		pruneSnapshots,
 	)
 }
 
@@ -192,6 +331,9 @@ func (m *home) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.errBox.Clear()
 	case previewTickMsg:
 		cmd := m.instanceChanged()
+		if m.state == stateObserve {
+			cmd = tea.Batch(cmd, m.refreshObserver())
+		}
 		return m, tea.Batch(
 			cmd,
 			func() tea.Msg {
@@ -203,26 +345,133 @@ func (m *home) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.menu.ClearKeydown()
 		return m, nil
 	case tickUpdateMetadataMessage:
+		var errs []error
+		cmds := []tea.Cmd{tickUpdateMetadataCmd}
 		for _, instance := range m.list.GetInstances() {
-			if !instance.Started() || instance.Paused() {
+			// Gone instances keep their status until they're recreated or paused.
+			if !instance.Started() || instance.Paused() || instance.Status == session.Gone {
+				continue
+			}
+			if instance.MarkGone() {
+				errs = append(errs, fmt.Errorf("the session of instance '%s' is gone", instance.Title))
+				cmds = append(cmds, m.notifyWebhooks(config.NotifyEventCrashed, instance, "crashed: its session is gone"))
 				continue
 			}
-			updated, prompt := instance.HasUpdated()
-			if updated {
+			// The pane is captured once and shared by the checks, each capture being a tmux call.
+			content, err := instance.CapturePane()
+			if err != nil {
+				log.ErrorLog.Printf("error capturing pane content in status monitor: %v", err)
+				continue
+			}
+			needsAuth, changed := instance.CheckAuth(content)
+			if needsAuth && changed {
+				errs = append(errs, fmt.Errorf("instance '%s' needs you to log in again: attach to it and run /login", instance.Title))
+				cmds = append(cmds, m.notifySlack(instance, "needs you to log in again"),
+					m.notifyWebhooks(config.NotifyEventNeedsInput, instance, "needs you to log in again"))
+			}
+			stuck := false
+			if !needsAuth {
+				var stuckChanged bool
+				stuck, stuckChanged = instance.CheckStuck(content, m.stuckPatterns)
+				if stuck && stuckChanged {
+					cmds = append(cmds, m.notifyStuck(instance))
+				}
+				if stuck && stuckChanged && m.appConfig.NotifyStuck {
+					errs = append(errs, fmt.Errorf("instance '%s' is waiting on a prompt auto-yes doesn't answer: attach to it to answer", instance.Title))
+					cmds = append(cmds, m.notifySlack(instance, "is waiting on a prompt and needs attention"))
+				}
+			}
+			updated, prompt := instance.HasUpdated(content, m.readyDetectors)
+			if needsAuth {
+				// Keep the NeedsAuth status until the login screen is gone.
+				if prompt && m.appConfig.AutoYesOnAuth {
+					instance.TapEnter()
+				}
+			} else if stuck {
+				// Keep the NeedsAttention status until the prompt is answered.
+			} else if updated {
 				instance.SetStatus(session.Running)
 			} else {
 				if prompt {
-					instance.TapEnter()
+					if held, changed := instance.AnswerPrompt(m.approvalPolicy); held && changed {
+						cmds = append(cmds, m.notifyApproval(instance))
+					}
 				} else {
 					instance.SetStatus(session.Ready)
 				}
 			}
-			if err := instance.UpdateDiffStats(); err != nil {
-				log.WarningLog.Printf("could not update diff stats: %v", err)
+			cmds = append(cmds, m.notifyFinished(instance))
+			if err := instance.RefreshDiffStats(); err != nil {
+				instance.RecordError(session.ErrorOpDiff, err)
+			} else {
+				instance.ResolveErrors(session.ErrorOpDiff)
+			}
+			instance.TrackConversation()
+			if err := instance.UpdateStackStatus(); err != nil {
+				log.WarningLog.Printf("could not update stack status: %v", err)
+			}
+			if committed, err := instance.AutoCommit(m.appConfig.CommitStrategy, time.Now()); err != nil {
+				instance.RecordError(session.ErrorOpCommit, err)
+			} else if committed {
+				instance.ResolveErrors(session.ErrorOpCommit)
 			}
+			if event, err := instance.CheckTimeBudget(m.appConfig.TimeBudget, time.Now()); err != nil {
+				errs = append(errs, err)
+			} else if event == session.BudgetExhausted {
+				errs = append(errs, fmt.Errorf("paused instance '%s': its time budget ran out", instance.Title))
+				cmds = append(cmds, m.instanceChanged())
+			}
+			if _, err := instance.CheckDiffBudget(m.appConfig.DiffBudget); err != nil {
+				errs = append(errs, err)
+			}
+			if instance.CheckRateLimit(content, time.Now()) {
+				m.backoff.Report(instance, time.Now())
+			}
+		}
+		if err := m.checkRateLimits(time.Now()); err != nil {
+			errs = append(errs, err)
+		}
+		if err := m.advancePipelines(time.Now()); err != nil {
+			errs = append(errs, err)
 		}
-		return m, tickUpdateMetadataCmd
+		if _, err := session.DispatchQueued(m.appConfig.MaxRunningInstances, m.appConfig.Providers, m.list.GetInstances()); err != nil {
+			errs = append(errs, err)
+		}
+		if _, err := session.DispatchWaiting(m.appConfig.ExternalResources, m.list.GetInstances()); err != nil {
+			errs = append(errs, err)
+		}
+		if len(errs) > 0 {
+			cmds = append(cmds, m.handleError(errors.Join(errs...)))
+		}
+		return m, tea.Batch(cmds...)
+	case tickResourceUsageMessage:
+		return m, m.sampleResourceUsage()
+	case resourceUsageSampledMsg:
+		return m, m.checkResourceUsage(msg)
+	case overLimitsMsg:
+		return m, m.pauseOverLimits(msg)
+	case tickBackupConversationsMessage:
+		return m, m.backupConversations()
+	case tickPaneLogMessage:
+		return m, m.appendPaneLogs()
+	case tickCheckUpstreamMessage:
+		return m, m.checkUpstream()
+	case upstreamCheckedMsg:
+		return m, m.upstreamChecked(msg)
 	case tea.MouseMsg:
+		// The selection stays on the instance the quick input types into.
+		if m.state == stateQuickInput {
+			return m, nil
+		}
+		if m.state == stateObserve && msg.Action == tea.MouseActionPress {
+			switch msg.Button {
+			case tea.MouseButtonWheelUp:
+				m.observeOverlay.Scroll(-3)
+			case tea.MouseButtonWheelDown:
+				m.observeOverlay.Scroll(3)
+			}
+			return m, nil
+		}
 		// Handle mouse wheel scrolling in the diff view
 		if m.tabbedWindow.IsInDiffTab() {
 			if msg.Action == tea.MouseActionPress {
@@ -248,6 +497,19 @@ func (m *home) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case instanceChangedMsg:
 		// Handle instance changed after confirmation action
 		return m, m.instanceChanged()
+	case checksDoneMsg:
+		return m, m.checksDone(msg)
+	case issuesListedMsg:
+		return m, m.issuesListed(msg)
+	case snapshotDoneMsg:
+		return m, m.snapshotDone(msg)
+	case triggerEventsMsg:
+		return m, m.handleTriggerEvents(msg)
+	case slackCommandMsg:
+		return m, m.handleSlackCommand(msg.command)
+	case slackPostedMsg:
+		m.slackPosted(msg)
+		return m, nil
 	case spinner.TickMsg:
 		var cmd tea.Cmd
 		m.spinner, cmd = m.spinner.Update(msg)
@@ -270,7 +532,8 @@ func (m *home) handleMenuHighlighting(msg tea.KeyMsg) (cmd tea.Cmd, returnEarly
 		m.keySent = false
 		return nil, false
 	}
-	if m.state == statePrompt || m.state == stateHelp || m.state == stateConfirm {
+	if m.state == statePrompt || m.state == stateHelp || m.state == stateConfirm || m.state == stateSelect ||
+		m.state == stateCompare || m.state == stateReview || m.state == stateObserve || m.state == stateQuickInput {
 		return nil, false
 	}
 	// If it's in the global keymap, we should try to highlight it.
@@ -291,6 +554,9 @@ func (m *home) handleMenuHighlighting(msg tea.KeyMsg) (cmd tea.Cmd, returnEarly
 	if name == keys.KeyEnter && m.state == stateNew {
 		name = keys.KeySubmitName
 	}
+	if name == keys.KeyTab && m.state == stateNew {
+		name = keys.KeyNextProvider
+	}
 	m.keySent = true
 	return tea.Batch(
 		func() tea.Msg { return msg },
@@ -330,8 +596,12 @@ func (m *home) handleKeyPress(msg tea.KeyMsg) (mod tea.Model, cmd tea.Cmd) {
 			if len(instance.Title) == 0 {
 				return m, m.handleError(fmt.Errorf("title cannot be empty"))
 			}
+			if err := session.CheckProviderCapacity(m.appConfig.Providers, instance.Provider, instance.Program,
+				m.list.GetInstances()[:m.list.NumInstances()-1]); err != nil {
+				return m, m.handleError(err)
+			}
 
-			if err := instance.Start(true); err != nil {
+			if _, err := instance.StartOrQueue(m.appConfig.MaxRunningInstances, m.list.GetInstances()); err != nil {
 				m.list.Kill()
 				m.state = stateDefault
 				return m, m.handleError(err)
@@ -382,6 +652,8 @@ func (m *home) handleKeyPress(msg tea.KeyMsg) (mod tea.Model, cmd tea.Cmd) {
 			if err := instance.SetTitle(instance.Title + " "); err != nil {
 				return m, m.handleError(err)
 			}
+		case tea.KeyTab:
+			return m, m.nextProvider(instance)
 		case tea.KeyEsc:
 			m.list.Kill()
 			m.state = stateDefault
@@ -401,6 +673,19 @@ func (m *home) handleKeyPress(msg tea.KeyMsg) (mod tea.Model, cmd tea.Cmd) {
 		// Use the new TextInputOverlay component to handle all key events
 		shouldClose := m.textInputOverlay.HandleKeyPress(msg)
 
+		// Prompts that don't target the selected instance are handed to the prompt handler.
+		if shouldClose && m.promptHandler != nil {
+			input, handler := m.textInputOverlay, m.promptHandler
+			m.textInputOverlay = nil
+			m.promptHandler = nil
+			m.state = stateDefault
+			m.menu.SetState(ui.StateDefault)
+			if input.IsSubmitted() {
+				return m, tea.Batch(tea.WindowSize(), handler(input.GetValue()))
+			}
+			return m, tea.WindowSize()
+		}
+
 		// Check if the form was submitted or canceled
 		if shouldClose {
 			selected := m.list.GetSelectedInstance()
@@ -409,6 +694,12 @@ func (m *home) handleKeyPress(msg tea.KeyMsg) (mod tea.Model, cmd tea.Cmd) {
 				return m, nil
 			}
 			if m.textInputOverlay.IsSubmitted() {
+				if err := m.checkDispatch(); err != nil {
+					m.textInputOverlay = nil
+					m.state = stateDefault
+					m.menu.SetState(ui.StateDefault)
+					return m, tea.Batch(tea.WindowSize(), m.handleError(err))
+				}
 				if err := selected.SendPrompt(m.textInputOverlay.GetValue()); err != nil {
 					// TODO: we probably end up in a bad state here.
 					return m, m.handleError(err)
@@ -437,20 +728,89 @@ func (m *home) handleKeyPress(msg tea.KeyMsg) (mod tea.Model, cmd tea.Cmd) {
 		if shouldClose {
 			m.state = stateDefault
 			m.confirmationOverlay = nil
+			if confirmed := m.confirmedMsg; confirmed != nil {
+				m.confirmedMsg = nil
+				return m, func() tea.Msg { return confirmed }
+			}
 			return m, nil
 		}
 		return m, nil
 	}
 
-	// Handle quit commands first
-	if msg.String() == "ctrl+c" || msg.String() == "q" {
-		return m.handleQuit()
+	// Handle selection state
+	if m.state == stateSelect {
+		shouldClose := m.selectionOverlay.HandleKeyPress(msg)
+		if shouldClose {
+			m.state = stateDefault
+			selection, handler := m.selectionOverlay, m.selectionHandler
+			m.selectionOverlay = nil
+			m.selectionHandler = nil
+			if selection.Submitted && handler != nil {
+				return m, tea.Batch(tea.WindowSize(), handler(selection.GetSelectedIndex()))
+			}
+			return m, tea.WindowSize()
+		}
+		return m, nil
+	}
+
+	// Handle comparison state
+	if m.state == stateCompare {
+		shouldClose := m.comparisonOverlay.HandleKeyPress(msg)
+		if shouldClose {
+			m.state = stateDefault
+			comparison, handler := m.comparisonOverlay, m.comparisonHandler
+			m.comparisonOverlay = nil
+			m.comparisonHandler = nil
+			if comparison.Submitted && handler != nil {
+				return m, tea.Batch(tea.WindowSize(), handler(comparison.GetSelectedIndex()))
+			}
+			return m, tea.WindowSize()
+		}
+		return m, nil
+	}
+
+	// Handle review state
+	if m.state == stateReview {
+		shouldClose := m.reviewOverlay.HandleKeyPress(msg)
+		if shouldClose {
+			m.state = stateDefault
+			review, handler := m.reviewOverlay, m.reviewHandler
+			m.reviewOverlay = nil
+			m.reviewHandler = nil
+			if review.Action != overlay.ReviewClose && handler != nil {
+				return m, tea.Batch(tea.WindowSize(), handler(review.Action, review.GetCursor()))
+			}
+			return m, tea.WindowSize()
+		}
+		return m, nil
+	}
+
+	if m.state == stateQuickInput {
+		return m, m.handleQuickInputKey(msg)
 	}
 
+	// Handle observe state. No key reaches the observed instance.
+	if m.state == stateObserve {
+		if m.observeOverlay.HandleKeyPress(msg) {
+			m.state = stateDefault
+			m.observeOverlay = nil
+			m.observed = nil
+			return m, tea.WindowSize()
+		}
+		return m, nil
+	}
+
+	// Handle quit commands first
 	name, ok := keys.GlobalKeyStringsMap[msg.String()]
+	if msg.String() == "ctrl+c" || (ok && name == keys.KeyQuit) {
+		return m.handleQuit()
+	}
 	if !ok {
 		return m, nil
 	}
+	if action, ok := keys.ActionName(name); ok {
+		telemetry.Feature("key:" + action)
+	}
 
 	switch name {
 	case keys.KeyHelp:
@@ -519,12 +879,73 @@ func (m *home) handleKeyPress(msg tea.KeyMsg) (mod tea.Model, cmd tea.Cmd) {
 		m.menu.SetState(ui.StateNewInstance)
 		m.claudeResumeAfterName = true
 
+		return m, nil
+	case keys.KeyIssue:
+		if m.list.NumInstances() >= GlobalInstanceLimit {
+			return m, m.handleError(
+				fmt.Errorf("you can't create more than %d instances", GlobalInstanceLimit))
+		}
+		return m, m.showIssuePicker()
+	case keys.KeyTemplate:
+		if m.list.NumInstances() >= GlobalInstanceLimit {
+			return m, m.handleError(
+				fmt.Errorf("you can't create more than %d instances", GlobalInstanceLimit))
+		}
+		return m, m.showTemplatePicker()
+	case keys.KeyBranch:
+		return m, m.showBranchPicker()
+	case keys.KeyHeatMap:
+		heatMap := report.HeatMapFromInstances(m.list.GetInstances())
+		m.textOverlay = overlay.NewTextOverlay(lipgloss.JoinVertical(lipgloss.Left,
+			titleStyle.Render("Heat Map"),
+			"",
+			report.FormatHeatMap(heatMap, heatMapDepth),
+		))
+		m.state = stateHelp
+		return m, nil
+	case keys.KeyFanOut:
+		return m, m.showFanOutPrompt()
+	case keys.KeyCompare:
+		return m, m.showComparison()
+	case keys.KeyScope:
+		return m, m.showScopeInput()
+	case keys.KeyNotes:
+		return m, m.showNotesInput()
+	case keys.KeyStack:
+		return m, m.newStackedInstance()
+	case keys.KeyRestack:
+		return m, m.restack()
+	case keys.KeyTask:
+		return m, m.showTaskPrompt()
+	case keys.KeyTimeline:
+		selected := m.list.GetSelectedInstance()
+		if selected == nil {
+			return m, nil
+		}
+		events, err := selected.AuditLog()
+		if err != nil {
+			return m, m.handleError(err)
+		}
+		m.textOverlay = overlay.NewTextOverlay(lipgloss.JoinVertical(lipgloss.Left,
+			titleStyle.Render("Timeline of "+selected.Title),
+			"",
+			session.FormatTimeline(events),
+		))
+		m.state = stateHelp
 		return m, nil
 	case keys.KeyUp:
-		m.list.Up()
+		if m.diffHasFocus() {
+			m.tabbedWindow.ScrollUp()
+		} else {
+			m.list.Up()
+		}
 		return m, m.instanceChanged()
 	case keys.KeyDown:
-		m.list.Down()
+		if m.diffHasFocus() {
+			m.tabbedWindow.ScrollDown()
+		} else {
+			m.list.Down()
+		}
 		return m, m.instanceChanged()
 	case keys.KeyShiftUp:
 		if m.tabbedWindow.IsInDiffTab() {
@@ -537,9 +958,19 @@ func (m *home) handleKeyPress(msg tea.KeyMsg) (mod tea.Model, cmd tea.Cmd) {
 		}
 		return m, m.instanceChanged()
 	case keys.KeyTab:
+		if m.tabbedWindow.IsSplit() {
+			m.setDiffFocused(!m.diffFocused)
+			return m, nil
+		}
 		m.tabbedWindow.Toggle()
 		m.menu.SetInDiffTab(m.tabbedWindow.IsInDiffTab())
 		return m, m.instanceChanged()
+	case keys.KeyLayout:
+		return m, m.toggleLayout()
+	case keys.KeyShrink:
+		return m, m.resizePane(-1)
+	case keys.KeyGrow:
+		return m, m.resizePane(1)
 	case keys.KeyKill:
 		selected := m.list.GetSelectedInstance()
 		if selected == nil {
@@ -556,6 +987,7 @@ func (m *home) handleKeyPress(msg tea.KeyMsg) (mod tea.Model, cmd tea.Cmd) {
 
 			checkedOut, err := worktree.IsBranchCheckedOut()
 			if err != nil {
+				selected.RecordError(session.ErrorOpCleanup, err)
 				return err
 			}
 
@@ -563,6 +995,11 @@ func (m *home) handleKeyPress(msg tea.KeyMsg) (mod tea.Model, cmd tea.Cmd) {
 				return fmt.Errorf("instance %s is currently checked out", selected.Title)
 			}
 
+			// The kill was confirmed, so this only backs up the unsaved work if kill_backup_refs is set
+			if err := selected.CheckKill(true); err != nil {
+				return err
+			}
+
 			// Delete from storage first
 			if err := m.storage.DeleteInstance(selected.Title); err != nil {
 				return err
@@ -573,6 +1010,14 @@ func (m *home) handleKeyPress(msg tea.KeyMsg) (mod tea.Model, cmd tea.Cmd) {
 			return instanceChangedMsg{}
 		}
 
+		// Work that would be lost has to be confirmed by typing the title of the session
+		var unsaved *session.UnsavedWorkError
+		if err := selected.CheckKill(false); errors.As(err, &unsaved) {
+			return m, m.confirmKillByTitle(selected, unsaved, killAction)
+		} else if err != nil {
+			log.WarningLog.Print(err)
+		}
+
 		// Show confirmation modal
 		message := fmt.Sprintf("[!] Kill session '%s'?", selected.Title)
 		return m, m.confirmAction(message, killAction)
@@ -581,18 +1026,30 @@ func (m *home) handleKeyPress(msg tea.KeyMsg) (mod tea.Model, cmd tea.Cmd) {
 		if selected == nil {
 			return m, nil
 		}
+		if err := selected.CheckPushAllowed(); err != nil {
+			return m, m.handleError(err)
+		}
 
 		// Create the push action as a tea.Cmd
 		pushAction := func() tea.Msg {
 			// Default commit message with timestamp
-			commitMsg := fmt.Sprintf("[claudesquad] update from '%s' on %s", selected.Title, time.Now().Format(time.RFC822))
+			commitMsg := selected.CommitMessage(m.appConfig.CommitStrategy, session.CommitReasonPush,
+				fmt.Sprintf("[claudesquad] update from '%s' on %s", selected.Title, time.Now().Format(time.RFC822)))
 			worktree, err := selected.GetGitWorktree()
 			if err != nil {
 				return err
 			}
+			// The changelog fragment is committed with the changes.
+			if _, err := selected.WriteChangelogFragment(); err != nil {
+				selected.RecordError(session.ErrorOpPush, err)
+				return err
+			}
 			if err = worktree.PushChanges(commitMsg, true); err != nil {
+				selected.RecordError(session.ErrorOpPush, err)
 				return err
 			}
+			selected.ResolveErrors(session.ErrorOpPush)
+			selected.Audit(session.AuditPush, worktree.GetBranchName())
 			return nil
 		}
 
@@ -618,15 +1075,80 @@ func (m *home) handleKeyPress(msg tea.KeyMsg) (mod tea.Model, cmd tea.Cmd) {
 		if selected == nil {
 			return m, nil
 		}
-		if err := selected.Resume(); err != nil {
+		if _, err := selected.ResumeOrQueue(m.appConfig.MaxRunningInstances, m.appConfig.Providers, m.list.GetInstances()); err != nil {
 			return m, m.handleError(err)
 		}
 		return m, tea.WindowSize()
+	case keys.KeyReview:
+		selected := m.list.GetSelectedInstance()
+		if selected == nil {
+			return m, nil
+		}
+		return m, m.showReview(selected, 0)
+	case keys.KeyArchive:
+		selected := m.list.GetSelectedInstance()
+		if selected == nil {
+			return m, nil
+		}
+		return m, m.archiveInstance(selected)
+	case keys.KeyArchived:
+		return m, m.showArchived()
+	case keys.KeyPipeline:
+		return m, m.showPipelinePicker()
+	case keys.KeyChecks:
+		return m, m.runChecks(m.list.GetSelectedInstance())
+	case keys.KeySummary:
+		return m, m.showSummaryActions(m.list.GetSelectedInstance())
+	case keys.KeyFork:
+		return m, m.showForkInput(m.list.GetSelectedInstance())
+	case keys.KeySuspend:
+		return m, m.showSuspendActions()
+	case keys.KeyErrors:
+		return m, m.showErrors(m.list.GetSelectedInstance())
+	case keys.KeyHandoff:
+		return m, m.showHandoff(m.list.GetSelectedInstance())
+	case keys.KeyDoctor:
+		return m, m.showDoctor()
+	case keys.KeyPalette:
+		return m, m.showPalette()
+	case keys.KeyProfile:
+		return m, m.showProfiles()
+	case keys.KeyObserve:
+		return m, m.showObserver(m.list.GetSelectedInstance())
+	case keys.KeyBudget:
+		return m, m.showBudgetInput()
+	case keys.KeyHistory:
+		return m, m.showHistory()
+	case keys.KeyApprove:
+		return m, m.answerHeldPrompt(m.list.GetSelectedInstance(), true)
+	case keys.KeyDeny:
+		return m, m.answerHeldPrompt(m.list.GetSelectedInstance(), false)
+	case keys.KeyImport:
+		return m, m.showImportActions(m.list.GetSelectedInstance())
+	case keys.KeyTheme:
+		return m, m.showThemePicker()
+	case keys.KeyQuickInput:
+		return m, m.showQuickInput(m.list.GetSelectedInstance())
+	case keys.KeyExternal:
+		selected := m.list.GetSelectedInstance()
+		if selected != nil && selected.SessionGone() {
+			return m, m.offerRecreate(selected)
+		}
+		if selected == nil || selected.Paused() || !selected.TmuxAlive() {
+			return m, nil
+		}
+		if err := selected.OpenInExternalTerminal(); err != nil {
+			return m, m.handleError(err)
+		}
+		return m, nil
 	case keys.KeyEnter:
 		if m.list.NumInstances() == 0 {
 			return m, nil
 		}
 		selected := m.list.GetSelectedInstance()
+		if selected != nil && selected.SessionGone() {
+			return m, m.offerRecreate(selected)
+		}
 		if selected == nil || selected.Paused() || !selected.TmuxAlive() {
 			return m, nil
 		}
@@ -653,6 +1175,7 @@ func (m *home) instanceChanged() tea.Cmd {
 	selected := m.list.GetSelectedInstance()
 
 	m.tabbedWindow.UpdateDiff(selected)
+	m.tabbedWindow.UpdateNotes(selected)
 	// Update menu with current instance
 	m.menu.SetInstance(selected)
 
@@ -710,6 +1233,23 @@ func (m *home) handleError(err error) tea.Cmd {
 	}
 }
 
+// selectItem shows a selection overlay and stores the handler to call with the selected index
+func (m *home) selectItem(title string, items []string, handler func(idx int) tea.Cmd) {
+	m.state = stateSelect
+	m.selectionOverlay = overlay.NewSelectionOverlay(title, items)
+	m.selectionOverlay.SetWidth(70)
+	m.selectionHandler = handler
+}
+
+// findItem shows a selection list filtered by what's typed, and stores the handler to call with the index
+// of the selected item.
+func (m *home) findItem(title string, items []string, handler func(idx int) tea.Cmd) {
+	m.state = stateSelect
+	m.selectionOverlay = overlay.NewFilterSelectionOverlay(title, items)
+	m.selectionOverlay.SetWidth(90)
+	m.selectionHandler = handler
+}
+
 // confirmAction shows a confirmation modal and stores the action to execute on confirm
 func (m *home) confirmAction(message string, action tea.Cmd) tea.Cmd {
 	m.state = stateConfirm
@@ -722,9 +1262,9 @@ func (m *home) confirmAction(message string, action tea.Cmd) tea.Cmd {
 	// Set callbacks for confirmation and cancellation
 	m.confirmationOverlay.OnConfirm = func() {
 		m.state = stateDefault
-		// Execute the action if it exists
+		// Execute the action if it exists, keeping its result for when the modal closes
 		if action != nil {
-			_ = action()
+			m.confirmedMsg = action()
 		}
 	}
 
@@ -762,6 +1302,26 @@ func (m *home) View() string {
 			log.ErrorLog.Printf("confirmation overlay is nil")
 		}
 		return overlay.PlaceOverlay(0, 0, m.confirmationOverlay.Render(), mainView, true, true)
+	} else if m.state == stateSelect {
+		if m.selectionOverlay == nil {
+			log.ErrorLog.Printf("selection overlay is nil")
+		}
+		return overlay.PlaceOverlay(0, 0, m.selectionOverlay.Render(), mainView, true, true)
+	} else if m.state == stateCompare {
+		if m.comparisonOverlay == nil {
+			log.ErrorLog.Printf("comparison overlay is nil")
+		}
+		return overlay.PlaceOverlay(0, 0, m.comparisonOverlay.Render(), mainView, true, true)
+	} else if m.state == stateReview {
+		if m.reviewOverlay == nil {
+			log.ErrorLog.Printf("review overlay is nil")
+		}
+		return overlay.PlaceOverlay(0, 0, m.reviewOverlay.Render(), mainView, true, true)
+	} else if m.state == stateObserve {
+		if m.observeOverlay == nil {
+			log.ErrorLog.Printf("observe overlay is nil")
+		}
+		return overlay.PlaceOverlay(0, 0, m.observeOverlay.Render(), mainView, true, true)
 	}
 
 	return mainView
@@ -0,0 +1,22 @@
+package app
+
+import (
+	"claude-squad/session"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// answerHeldPrompt approves or denies the prompt auto-yes holds in the instance.
+func (m *home) answerHeldPrompt(selected *session.Instance, approve bool) tea.Cmd {
+	if selected == nil {
+		return nil
+	}
+	answer := selected.DenyPrompt
+	if approve {
+		answer = selected.ApprovePrompt
+	}
+	if err := answer(); err != nil {
+		return m.handleError(err)
+	}
+	return m.instanceChanged()
+}
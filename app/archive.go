@@ -0,0 +1,130 @@
+package app
+
+import (
+	"claude-squad/session"
+	"claude-squad/ui"
+	"claude-squad/ui/overlay"
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// archivedDiffLines is how many lines of the diff of an archived instance are shown when inspecting it.
+const archivedDiffLines = 40
+
+// archiveInstance asks for confirmation, then archives the instance and moves it to the archived
+// instances.
+func (m *home) archiveInstance(selected *session.Instance) tea.Cmd {
+	archiveAction := func() tea.Msg {
+		archived, err := selected.Archive()
+		if err != nil {
+			return err
+		}
+		// Store the archive before dropping the instance so it isn't lost if saving fails.
+		if err := m.storage.ArchiveInstance(archived); err != nil {
+			return err
+		}
+		m.list.RemoveInstance(selected)
+		if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
+			return err
+		}
+		return instanceChangedMsg{}
+	}
+
+	message := fmt.Sprintf("[!] Archive session '%s'?", selected.Title)
+	return m.confirmAction(message, archiveAction)
+}
+
+// showArchived lists the archived instances, most recent first, to inspect or resurrect one.
+func (m *home) showArchived() tea.Cmd {
+	archived, err := m.storage.ArchivedInstances()
+	if err != nil {
+		return m.handleError(err)
+	}
+	if len(archived) == 0 {
+		return m.handleError(fmt.Errorf("no archived sessions"))
+	}
+	slices.Reverse(archived)
+
+	items := make([]string, len(archived))
+	for i, instance := range archived {
+		items[i] = fmt.Sprintf("%s (%s, archived %s)", instance.Title, instance.Branch,
+			instance.ArchivedAt.Format("2006-01-02"))
+	}
+	m.selectItem("Archived sessions", items, func(idx int) tea.Cmd {
+		instance := archived[idx]
+		m.selectItem(instance.Title, []string{"Inspect", "Resurrect"}, func(action int) tea.Cmd {
+			if action == 0 {
+				return m.inspectArchived(instance)
+			}
+			return m.resurrect(instance)
+		})
+		return nil
+	})
+	return nil
+}
+
+// inspectArchived shows the metadata and the final diff of an archived instance.
+func (m *home) inspectArchived(archived session.ArchivedInstance) tea.Cmd {
+	archived, err := session.RestoreArchive(archived)
+	if err != nil {
+		return m.handleError(err)
+	}
+	diff := "No changes"
+	if content := strings.TrimRight(archived.DiffStats.Content, "\n"); content != "" {
+		lines := strings.Split(content, "\n")
+		if len(lines) > archivedDiffLines {
+			lines = append(lines[:archivedDiffLines],
+				fmt.Sprintf("… %d more lines", len(lines)-archivedDiffLines))
+		}
+		diff = ui.ColorizeDiff(strings.Join(lines, "\n"))
+	}
+	m.textOverlay = overlay.NewTextOverlay(lipgloss.JoinVertical(lipgloss.Left,
+		titleStyle.Render("Archived "+archived.Title),
+		"",
+		archived.Summary(),
+		"",
+		diff,
+	))
+	m.state = stateHelp
+	return nil
+}
+
+// resurrect brings an archived instance back on its branch and moves it back to the instances.
+func (m *home) resurrect(archived session.ArchivedInstance) tea.Cmd {
+	if err := m.checkDispatch(); err != nil {
+		return m.handleError(err)
+	}
+	if m.list.NumInstances() >= GlobalInstanceLimit {
+		return m.handleError(fmt.Errorf("you can't create more than %d instances", GlobalInstanceLimit))
+	}
+	for _, existing := range m.list.GetInstances() {
+		if existing.Title == archived.Title {
+			return m.handleError(fmt.Errorf("instance %s already exists", archived.Title))
+		}
+	}
+	if err := session.CheckProviderCapacity(m.appConfig.Providers, archived.Provider, archived.Program, m.list.GetInstances()); err != nil {
+		return m.handleError(err)
+	}
+
+	restored, err := session.RestoreArchive(archived)
+	if err != nil {
+		return m.handleError(err)
+	}
+	instance, err := session.ResurrectInstance(restored)
+	if err != nil {
+		return m.handleError(err)
+	}
+	m.list.AddInstance(instance)()
+	m.list.SetSelectedInstance(m.list.NumInstances() - 1)
+	if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
+		return m.handleError(err)
+	}
+	if err := m.storage.DeleteArchivedInstance(archived); err != nil {
+		return m.handleError(err)
+	}
+	return m.instanceChanged()
+}
@@ -0,0 +1,48 @@
+package app
+
+import (
+	"claude-squad/session"
+	"fmt"
+	"time"
+)
+
+// checkRateLimits retries the instances whose agent stopped on a rate limit once their turn came and the
+// backoff is over, and updates the backoff banner. The instances are reported to the backoff by the tick,
+// which has their pane content.
+func (m *home) checkRateLimits(now time.Time) error {
+	var err error
+	if instance := m.backoff.NextRetry(now); instance != nil && m.hasInstance(instance) && !instance.Paused() {
+		if retryErr := instance.RetryAfterRateLimit(now); retryErr != nil {
+			err = fmt.Errorf("could not retry instance '%s' after rate limit: %w", instance.Title, retryErr)
+		}
+	}
+
+	switch {
+	case m.backoff.Active(now):
+		m.list.SetBanner(fmt.Sprintf(" rate limited · new tasks paused for %s ",
+			m.backoff.Remaining(now).Round(time.Second)))
+	case m.backoff.Waiting() > 0:
+		m.list.SetBanner(fmt.Sprintf(" rate limited · retrying %d more ", m.backoff.Waiting()))
+	default:
+		m.list.SetBanner("")
+	}
+	return err
+}
+
+// checkDispatch returns an error if new tasks shouldn't be dispatched because of a rate limit.
+func (m *home) checkDispatch() error {
+	if remaining := m.backoff.Remaining(time.Now()); remaining > 0 {
+		return fmt.Errorf("the provider is rate limiting the squad: new tasks are paused for %s",
+			remaining.Round(time.Second))
+	}
+	return nil
+}
+
+func (m *home) hasInstance(instance *session.Instance) bool {
+	for _, existing := range m.list.GetInstances() {
+		if existing == instance {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package app
+
+import (
+	"claude-squad/session"
+	"claude-squad/session/git"
+	"claude-squad/ui"
+	"claude-squad/ui/overlay"
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// showBranchPicker lets the user pick an existing branch to create an instance on, instead of a new one.
+func (m *home) showBranchPicker() tea.Cmd {
+	if m.list.NumInstances() >= GlobalInstanceLimit {
+		return m.handleError(
+			fmt.Errorf("you can't create more than %d instances", GlobalInstanceLimit))
+	}
+	branches, err := git.ListAvailableBranches(".")
+	if err != nil {
+		return m.handleError(err)
+	}
+
+	// The first item fetches a branch that isn't local yet, e.g. a colleague's.
+	items := append([]string{fetchRemoteBranchItem}, branches...)
+	m.selectItem("Create instance on existing branch", items, func(idx int) tea.Cmd {
+		if idx == 0 {
+			return m.showRemoteBranchInput()
+		}
+		return m.launchOnBranch(branches[idx-1])
+	})
+	return nil
+}
+
+// fetchRemoteBranchItem is the item of the branch picker that fetches a remote branch.
+const fetchRemoteBranchItem = "⇣ fetch a remote branch to review…"
+
+// showRemoteBranchInput asks for a remote branch, e.g. the branch of a colleague's pull request, to fetch
+// and create an instance on. The agent is asked to review and fix it.
+func (m *home) showRemoteBranchInput() tea.Cmd {
+	m.state = statePrompt
+	m.menu.SetState(ui.StatePrompt)
+	m.textInputOverlay = overlay.NewTextInputOverlay("Remote branch to review, e.g. origin/fix-login", "")
+	m.promptHandler = func(value string) tea.Cmd {
+		branch, err := git.FetchRemoteBranch(".", value)
+		if err != nil {
+			return m.handleError(err)
+		}
+		if _, err := m.launchInstance(session.InstanceOptions{
+			Title:   session.TitleFromText(branch),
+			Path:    ".",
+			Program: m.program,
+			Branch:  branch,
+		}, m.appConfig.GetReviewPrompt()); err != nil {
+			return m.handleError(err)
+		}
+		return m.instanceChanged()
+	}
+	return nil
+}
+
+// launchOnBranch creates an instance that checks out the existing branch.
+func (m *home) launchOnBranch(branch string) tea.Cmd {
+	if _, err := m.launchInstance(session.InstanceOptions{
+		Title:   session.TitleFromText(branch),
+		Path:    ".",
+		Program: m.program,
+		Branch:  branch,
+	}, ""); err != nil {
+		return m.handleError(err)
+	}
+	return m.instanceChanged()
+}
@@ -0,0 +1,44 @@
+package app
+
+import (
+	"claude-squad/session"
+	"claude-squad/ui"
+	"claude-squad/ui/overlay"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// showBudgetInput asks how long the selected instance may run, e.g. "2h" or "90m", before it's asked to
+// wrap up and then paused. An empty budget, or 0, removes it.
+func (m *home) showBudgetInput() tea.Cmd {
+	selected := m.list.GetSelectedInstance()
+	if selected == nil {
+		return nil
+	}
+	current := ""
+	if selected.TimeBudget > 0 {
+		current = session.FormatBudget(selected.TimeBudget)
+	}
+	m.state = statePrompt
+	m.menu.SetState(ui.StatePrompt)
+	m.textInputOverlay = overlay.NewTextInputOverlay("Time budget of "+selected.Title+", e.g. 2h or 90m (empty for none)",
+		current)
+	m.promptHandler = func(value string) tea.Cmd {
+		var budget time.Duration
+		if value = strings.TrimSpace(value); value != "" && value != "0" {
+			var err error
+			if budget, err = time.ParseDuration(value); err != nil || budget < 0 {
+				return m.handleError(fmt.Errorf("invalid time budget %q, use e.g. 2h or 90m", value))
+			}
+		}
+		selected.SetTimeBudget(budget, time.Now())
+		if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
+			return m.handleError(err)
+		}
+		return m.instanceChanged()
+	}
+	return nil
+}
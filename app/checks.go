@@ -0,0 +1,61 @@
+package app
+
+import (
+	"claude-squad/session"
+	"claude-squad/ui/overlay"
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// checksDoneMsg is sent when the check command of an instance finished.
+type checksDoneMsg struct {
+	instance *session.Instance
+	result   *session.CheckResult
+	err      error
+}
+
+// runChecks runs the check command of the repository of the instance in the background. The result is
+// shown in the list once it's done.
+func (m *home) runChecks(instance *session.Instance) tea.Cmd {
+	if instance == nil || instance.ChecksState() == session.ChecksRunning {
+		return nil
+	}
+	instance.SetChecksRunning(true)
+	return func() tea.Msg {
+		result, err := instance.RunChecks()
+		return checksDoneMsg{instance: instance, result: result, err: err}
+	}
+}
+
+// checksDone records the result of the checks of an instance, and shows the output if they failed.
+func (m *home) checksDone(msg checksDoneMsg) tea.Cmd {
+	if msg.err != nil {
+		msg.instance.SetChecksRunning(false)
+		return m.handleError(msg.err)
+	}
+	if !m.hasInstance(msg.instance) {
+		// The instance was killed while the checks ran.
+		return nil
+	}
+	msg.instance.RecordChecks(msg.result)
+	if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
+		return m.handleError(err)
+	}
+	if msg.result.Passed {
+		return m.instanceChanged()
+	}
+	notify := m.notifySlack(msg.instance, "failed its checks: `"+msg.result.Command+"`")
+	if m.state != stateDefault {
+		return tea.Batch(notify, m.handleError(fmt.Errorf("the checks of %s failed", msg.instance.Title)))
+	}
+	m.textOverlay = overlay.NewTextOverlay(lipgloss.JoinVertical(lipgloss.Left,
+		titleStyle.Render(fmt.Sprintf("Checks of %s failed", msg.instance.Title)),
+		"",
+		"$ "+msg.result.Command,
+		msg.result.Output,
+	))
+	m.state = stateHelp
+	return tea.Batch(notify, m.instanceChanged())
+}
@@ -0,0 +1,31 @@
+package app
+
+import (
+	"claude-squad/log"
+	"claude-squad/session"
+	"slices"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type tickBackupConversationsMessage struct{}
+
+var tickBackupConversationsCmd = func() tea.Msg {
+	time.Sleep(session.ConversationBackupInterval)
+	return tickBackupConversationsMessage{}
+}
+
+// backupConversations backs up the Claude conversations of the instances in the background, then waits
+// for the next backup.
+func (m *home) backupConversations() tea.Cmd {
+	instances := slices.Clone(m.list.GetInstances())
+	return func() tea.Msg {
+		for _, instance := range instances {
+			if err := instance.BackupConversations(); err != nil {
+				log.WarningLog.Printf("could not back up the conversations of %s: %v", instance.Title, err)
+			}
+		}
+		return tickBackupConversationsCmd()
+	}
+}
@@ -0,0 +1,123 @@
+package app
+
+import (
+	"claude-squad/session"
+	"fmt"
+	"slices"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// showDoctor lists the tmux sessions of claude-squad that no instance owns, to adopt or kill them, the
+// worktrees of the repository that no instance owns, to adopt them, and the instances whose sessions are
+// gone, to recreate or pause them.
+func (m *home) showDoctor() tea.Cmd {
+	orphans, err := session.FindOrphanTerminals(m.list.GetInstances())
+	if err != nil {
+		return m.handleError(err)
+	}
+	adoptable, err := session.FindAdoptable(".", m.appConfig.Adopt, m.list.GetInstances())
+	if err != nil {
+		return m.handleError(err)
+	}
+	// A tmux session of claude-squad running in an adoptable worktree is listed with the worktree.
+	orphans = slices.DeleteFunc(orphans, func(orphan session.OrphanTerminal) bool {
+		return slices.ContainsFunc(adoptable, func(candidate session.AdoptCandidate) bool {
+			return candidate.Terminal == orphan.Name
+		})
+	})
+	var gone []*session.Instance
+	for _, instance := range m.list.GetInstances() {
+		if instance.SessionGone() {
+			gone = append(gone, instance)
+		}
+	}
+	if len(orphans) == 0 && len(adoptable) == 0 && len(gone) == 0 {
+		m.showSummary("Doctor", "Every tmux session and worktree belongs to a session in the list, and every running session has its tmux session.")
+		return nil
+	}
+
+	items := make([]string, 0, len(orphans)+len(adoptable)+len(gone))
+	for _, orphan := range orphans {
+		items = append(items, fmt.Sprintf("%s: no session in the list (in %s)", orphan.Name, orphan.Path))
+	}
+	for _, candidate := range adoptable {
+		items = append(items, fmt.Sprintf("%s (worktree without a session in the list)", candidate))
+	}
+	for _, instance := range gone {
+		items = append(items, fmt.Sprintf("%s: tmux session gone", instance.Title))
+	}
+	m.selectItem("Doctor", items, func(idx int) tea.Cmd {
+		if idx >= len(orphans)+len(adoptable) {
+			return m.offerRecreate(gone[idx-len(orphans)-len(adoptable)])
+		}
+		if idx >= len(orphans) {
+			return m.offerAdoptWorktree(adoptable[idx-len(orphans)])
+		}
+		orphan := orphans[idx]
+		actions := []string{"Adopt it as a session running " + m.program, "Kill it", "Leave it"}
+		m.selectItem(orphan.Name, actions, func(action int) tea.Cmd {
+			switch action {
+			case 0:
+				return m.adoptTerminal(orphan)
+			case 1:
+				return m.confirmAction(fmt.Sprintf("[!] Kill tmux session '%s'?", orphan.Name), func() tea.Msg {
+					if err := orphan.Kill(); err != nil {
+						return err
+					}
+					return instanceChangedMsg{}
+				})
+			}
+			return nil
+		})
+		return nil
+	})
+	return nil
+}
+
+// adoptTerminal adds an instance for the orphan tmux session, running in its worktree.
+func (m *home) adoptTerminal(orphan session.OrphanTerminal) tea.Cmd {
+	return m.adopt(orphan.Title(), func() (*session.Instance, error) {
+		return orphan.Adopt(m.program)
+	})
+}
+
+// adopt adds the instance titled title that adopt returns, unless there's one with that title already.
+func (m *home) adopt(title string, adopt func() (*session.Instance, error)) tea.Cmd {
+	if m.list.NumInstances() >= GlobalInstanceLimit {
+		return m.handleError(fmt.Errorf("you can't create more than %d instances", GlobalInstanceLimit))
+	}
+	for _, existing := range m.list.GetInstances() {
+		if existing.Title == title {
+			return m.handleError(fmt.Errorf("instance %s already exists", title))
+		}
+	}
+	instance, err := adopt()
+	if err != nil {
+		return m.handleError(err)
+	}
+	m.list.AddInstance(instance)()
+	m.list.SetSelectedInstance(m.list.NumInstances() - 1)
+	if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
+		return m.handleError(err)
+	}
+	return m.instanceChanged()
+}
+
+// offerAdoptWorktree offers to adopt the worktree of a hand-rolled setup, along with its tmux session if it
+// has one.
+func (m *home) offerAdoptWorktree(candidate session.AdoptCandidate) tea.Cmd {
+	action := "Adopt it, starting " + m.program + " in a new tmux session"
+	if candidate.Terminal != "" {
+		action = fmt.Sprintf("Adopt it with tmux session %s, as a session running %s", candidate.Terminal, m.program)
+	}
+	m.selectItem(candidate.Worktree.Path, []string{action, "Leave it"}, func(idx int) tea.Cmd {
+		if idx != 0 {
+			return nil
+		}
+		return m.adopt(candidate.Title, func() (*session.Instance, error) {
+			return candidate.Adopt(m.program)
+		})
+	})
+	return nil
+}
@@ -0,0 +1,122 @@
+package app
+
+import (
+	"claude-squad/session"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxShownErrors is how many of the latest errors of an instance the error center shows.
+const maxShownErrors = 8
+
+// errorAction is an action of the error center.
+type errorAction struct {
+	label string
+	run   func() tea.Cmd
+}
+
+// showErrors shows the error history of the instance, latest first, with the actions that can recover
+// from what failed.
+func (m *home) showErrors(instance *session.Instance) tea.Cmd {
+	if instance == nil {
+		return nil
+	}
+	history := instance.ErrorHistory()
+	slices.Reverse(history)
+
+	lines := []string{fmt.Sprintf("Errors of '%s'", instance.Title)}
+	if len(history) == 0 {
+		lines = append(lines, "No errors.")
+	}
+	for idx, record := range history {
+		if idx == maxShownErrors {
+			lines = append(lines, fmt.Sprintf("... and %d older", len(history)-maxShownErrors))
+			break
+		}
+		line := record.String()
+		if record.Resolved {
+			line = "✓ " + line
+		} else {
+			line = "✗ " + line
+		}
+		lines = append(lines, line)
+	}
+
+	var actions []errorAction
+	if instance.WorktreeMissing() || instance.HasUnresolved(session.ErrorOpWorktree) {
+		actions = append(actions, errorAction{"Retry worktree setup", func() tea.Cmd {
+			return m.retryInstance(instance, instance.RetryWorktreeSetup)
+		}})
+	}
+	if !instance.Paused() && (instance.SessionGone() || instance.HasUnresolved(session.ErrorOpTmux)) {
+		actions = append(actions, errorAction{"Retry tmux restore", func() tea.Cmd {
+			return m.retryInstance(instance, instance.RetryTmuxRestore)
+		}})
+	}
+	actions = append(actions, errorAction{"Force cleanup: remove the session and its worktree", func() tea.Cmd {
+		return m.forceCleanup(instance)
+	}})
+	if instance.UnresolvedErrors() > 0 {
+		actions = append(actions, errorAction{"Dismiss the errors", func() tea.Cmd {
+			instance.ResolveErrors("")
+			return m.saveAndRefresh()
+		}})
+	}
+	actions = append(actions, errorAction{"Close", func() tea.Cmd { return nil }})
+
+	items := make([]string, len(actions))
+	for idx, action := range actions {
+		items[idx] = action.label
+	}
+	m.selectItem(strings.Join(lines, "\n"), items, func(idx int) tea.Cmd {
+		return actions[idx].run()
+	})
+	return nil
+}
+
+// retryInstance runs the retry of the instance, and saves the instances whether it failed or not, since a
+// failed retry adds to the error history.
+func (m *home) retryInstance(instance *session.Instance, retry func() error) tea.Cmd {
+	err := retry()
+	if err != nil {
+		err = fmt.Errorf("retry of %s failed: %w", instance.Title, err)
+	}
+	if saveErr := m.storage.SaveInstances(m.list.GetInstances()); saveErr != nil {
+		err = errors.Join(err, saveErr)
+	}
+	if err != nil {
+		return tea.Batch(m.handleError(err), m.instanceChanged())
+	}
+	return tea.Batch(tea.WindowSize(), m.instanceChanged())
+}
+
+// forceCleanup asks for confirmation, then removes the instance even if killing it fails, e.g. because
+// its worktree is locked or its repository moved.
+func (m *home) forceCleanup(instance *session.Instance) tea.Cmd {
+	cleanupAction := func() tea.Msg {
+		cleanupErr := instance.ForceCleanup()
+		if err := m.storage.DeleteInstance(instance.Title); err != nil {
+			return errors.Join(cleanupErr, err)
+		}
+		m.list.RemoveInstance(instance)
+		if cleanupErr != nil {
+			return fmt.Errorf("removed %s, but some of it is left: %w", instance.Title, cleanupErr)
+		}
+		return instanceChangedMsg{}
+	}
+
+	message := fmt.Sprintf("[!] Force cleanup of session '%s'?", instance.Title)
+	return m.confirmAction(message, cleanupAction)
+}
+
+// saveAndRefresh saves the instances and updates the views of the selected one.
+func (m *home) saveAndRefresh() tea.Cmd {
+	if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
+		return m.handleError(err)
+	}
+	return m.instanceChanged()
+}
@@ -0,0 +1,143 @@
+package app
+
+import (
+	"claude-squad/session"
+	"claude-squad/ui"
+	"claude-squad/ui/overlay"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// showFanOutPrompt asks for the prompt to run across the instances of a new fan-out group.
+func (m *home) showFanOutPrompt() tea.Cmd {
+	count := m.appConfig.GetFanOutCount()
+	if m.list.NumInstances()+count > GlobalInstanceLimit {
+		return m.handleError(
+			fmt.Errorf("you can't create more than %d instances", GlobalInstanceLimit))
+	}
+	m.state = statePrompt
+	m.menu.SetState(ui.StatePrompt)
+	m.textInputOverlay = overlay.NewTextInputOverlay(fmt.Sprintf("Fan-out prompt (%d instances)", count), "")
+	m.promptHandler = m.fanOut
+	return nil
+}
+
+// fanOut creates the instances of a new fan-out group and sends each of them the prompt.
+func (m *home) fanOut(prompt string) tea.Cmd {
+	if strings.TrimSpace(prompt) == "" {
+		return m.handleError(fmt.Errorf("prompt cannot be empty"))
+	}
+	group := session.FanOutGroupName(prompt)
+	for _, existing := range m.list.GetInstances() {
+		if existing.Group == group {
+			return m.handleError(fmt.Errorf("fan-out group %s already exists", group))
+		}
+	}
+
+	count := m.appConfig.GetFanOutCount()
+	options := session.FanOutOptions(group, ".", count, m.program, m.appConfig.FanOutPrograms)
+	if len(m.appConfig.Providers) > 0 {
+		providers, err := session.AssignProviders(m.appConfig.Providers, m.appConfig.ProviderPolicy, prompt, count,
+			m.list.GetInstances())
+		if err != nil {
+			return m.handleError(err)
+		}
+		options = session.FanOutProviderOptions(group, ".", providers)
+	}
+	for _, opts := range options {
+		if _, err := m.launchInstance(opts, prompt); err != nil {
+			return m.handleError(err)
+		}
+	}
+	return m.instanceChanged()
+}
+
+// groupMembers returns the instances of the fan-out group, in list order.
+func (m *home) groupMembers(group string) []*session.Instance {
+	var members []*session.Instance
+	for _, instance := range m.list.GetInstances() {
+		if instance.Group == group {
+			members = append(members, instance)
+		}
+	}
+	return members
+}
+
+// showComparison shows the diffs of the fan-out group of the selected instance side by side.
+func (m *home) showComparison() tea.Cmd {
+	selected := m.list.GetSelectedInstance()
+	if selected == nil {
+		return nil
+	}
+	if selected.Group == "" {
+		return m.handleError(fmt.Errorf("instance %s is not part of a fan-out group", selected.Title))
+	}
+
+	members := m.groupMembers(selected.Group)
+	columns := make([]overlay.ComparisonColumn, len(members))
+	for i, member := range members {
+		header := fmt.Sprintf("%s\n%s", member.Title, member.Program)
+		body := "No changes"
+		stats := member.GetDiffStats()
+		switch {
+		case stats == nil:
+		case stats.Error != nil:
+			body = stats.Error.Error()
+		case !stats.IsEmpty():
+			header += "\n" + ui.AdditionStyle.Render(fmt.Sprintf("+%d", stats.Added)) + " " +
+				ui.DeletionStyle.Render(fmt.Sprintf("-%d", stats.Removed))
+			body = ui.ColorizeDiff(stats.Content)
+		}
+		columns[i] = overlay.ComparisonColumn{Header: header, Body: body}
+	}
+
+	m.state = stateCompare
+	m.comparisonOverlay = overlay.NewComparisonOverlay(fmt.Sprintf("Compare %s", selected.Group), columns)
+	m.comparisonHandler = func(idx int) tea.Cmd {
+		return m.keepFanOutResult(members[idx], members)
+	}
+	return tea.WindowSize()
+}
+
+// keepFanOutResult asks for confirmation, then kills all the members of the group but the winner. The
+// winner leaves the group and becomes a regular instance.
+func (m *home) keepFanOutResult(winner *session.Instance, members []*session.Instance) tea.Cmd {
+	keepAction := func() tea.Msg {
+		for _, member := range members {
+			if member == winner {
+				continue
+			}
+			worktree, err := member.GetGitWorktree()
+			if err != nil {
+				return err
+			}
+			checkedOut, err := worktree.IsBranchCheckedOut()
+			if err != nil {
+				return err
+			}
+			if checkedOut {
+				return fmt.Errorf("instance %s is currently checked out", member.Title)
+			}
+			if err := m.storage.DeleteInstance(member.Title); err != nil {
+				return err
+			}
+			m.list.KillInstance(member)
+		}
+
+		winner.Group = ""
+		for idx, instance := range m.list.GetInstances() {
+			if instance == winner {
+				m.list.SetSelectedInstance(idx)
+			}
+		}
+		if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
+			return err
+		}
+		return instanceChangedMsg{}
+	}
+
+	message := fmt.Sprintf("[!] Keep '%s' and kill the other %d?", winner.Title, len(members)-1)
+	return m.confirmAction(message, keepAction)
+}
@@ -0,0 +1,31 @@
+package app
+
+import (
+	"claude-squad/session"
+	"claude-squad/ui"
+	"claude-squad/ui/overlay"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// showForkInput asks for the title of a fork of the instance, and starts it.
+func (m *home) showForkInput(instance *session.Instance) tea.Cmd {
+	if instance == nil || !instance.Started() || instance.Paused() {
+		return nil
+	}
+	m.state = statePrompt
+	m.menu.SetState(ui.StatePrompt)
+	m.textInputOverlay = overlay.NewTextInputOverlay("Title of the fork of "+instance.Title, instance.Title+"-fork")
+	m.promptHandler = func(title string) tea.Cmd {
+		title = strings.TrimSpace(title)
+		if title == "" {
+			return nil
+		}
+		if _, err := m.launchInstance(instance.ForkOptions(title), ""); err != nil {
+			return m.handleError(err)
+		}
+		return m.instanceChanged()
+	}
+	return tea.WindowSize()
+}
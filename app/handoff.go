@@ -0,0 +1,75 @@
+package app
+
+import (
+	"claude-squad/config"
+	"claude-squad/session"
+	"claude-squad/ui"
+	"claude-squad/ui/overlay"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// handoffContentLabels describe the contents of the handoff templates in the picker.
+var handoffContentLabels = map[string]string{
+	config.HandoffContentDiff:    "its diff",
+	config.HandoffContentMessage: "the last message of its agent",
+	config.HandoffContentSummary: "a summary of its work",
+}
+
+// showHandoff asks which running instance the selected one hands off to and with which template, then
+// shows the framed prompt to edit before it's sent.
+func (m *home) showHandoff(from *session.Instance) tea.Cmd {
+	if from == nil || !from.Started() {
+		return nil
+	}
+	var targets []*session.Instance
+	var titles []string
+	for _, instance := range m.list.GetInstances() {
+		if instance != from && instance.Started() && !instance.Paused() {
+			targets = append(targets, instance)
+			titles = append(titles, instance.Title)
+		}
+	}
+	if len(targets) == 0 {
+		return m.handleError(fmt.Errorf("no other running session to hand off to"))
+	}
+
+	templates := m.appConfig.GetHandoffTemplates()
+	m.selectItem(fmt.Sprintf("Hand off from '%s' to", from.Title), titles, func(idx int) tea.Cmd {
+		to := targets[idx]
+		items := make([]string, len(templates))
+		for i, tmpl := range templates {
+			items[i] = fmt.Sprintf("%s: %s", tmpl.Name, handoffContentLabels[tmpl.Content])
+		}
+		m.selectItem(fmt.Sprintf("Hand off what from '%s' to '%s'", from.Title, to.Title), items, func(idx int) tea.Cmd {
+			return m.showHandoffPrompt(from, to, templates[idx])
+		})
+		return nil
+	})
+	return nil
+}
+
+// showHandoffPrompt shows the prompt of the handoff to edit, and sends it when it's submitted.
+func (m *home) showHandoffPrompt(from, to *session.Instance, tmpl config.HandoffTemplate) tea.Cmd {
+	prompt, err := from.HandoffPrompt(to, tmpl)
+	if err != nil {
+		return m.handleError(err)
+	}
+	m.state = statePrompt
+	m.menu.SetState(ui.StatePrompt)
+	m.textInputOverlay = overlay.NewTextInputOverlay(
+		fmt.Sprintf("Prompt for '%s' (%s from '%s')", to.Title, tmpl.Name, from.Title), prompt)
+	m.promptHandler = func(value string) tea.Cmd {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			return nil
+		}
+		if err := from.HandOff(to, tmpl.Name, value); err != nil {
+			return m.handleError(err)
+		}
+		return m.instanceChanged()
+	}
+	return tea.WindowSize()
+}
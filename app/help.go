@@ -1,16 +1,22 @@
 package app
 
 import (
+	"claude-squad/keys"
 	"claude-squad/log"
 	"claude-squad/session"
 	"claude-squad/ui"
 	"claude-squad/ui/overlay"
+	"claude-squad/ui/theme"
 	"fmt"
+	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
 
+// helpKeyWidth is the width of the keys column of the help screens.
+const helpKeyWidth = 10
+
 type helpText interface {
 	// toContent returns the help UI content.
 	toContent() string
@@ -34,32 +40,76 @@ func helpStart(instance *session.Instance) helpText {
 }
 
 func (h helpTypeGeneral) toContent() string {
+	navigate := keys.HelpKey(keys.KeyUp) + ", " + keys.HelpKey(keys.KeyDown)
+	scroll := keys.HelpKey(keys.KeyShiftUp) + ", " + keys.HelpKey(keys.KeyShiftDown)
 	content := lipgloss.JoinVertical(lipgloss.Left,
 		titleStyle.Render("Claude Squad"),
 		"",
 		"A terminal UI that manages multiple Claude Code (and other local agents) in separate workspaces.",
 		"",
 		headerStyle.Render("Managing:"),
-		keyStyle.Render("n")+descStyle.Render("         - Create a new session"),
-		keyStyle.Render("N")+descStyle.Render("         - Create a new session with a prompt"),
-		keyStyle.Render("D")+descStyle.Render("         - Kill (delete) the selected session"),
-		keyStyle.Render("↑/j, ↓/k")+descStyle.Render("  - Navigate between sessions"),
-		keyStyle.Render("↵/o")+descStyle.Render("       - Attach to the selected session"),
-		keyStyle.Render("ctrl-q")+descStyle.Render("    - Detach from session"),
+		helpLine(keys.HelpKey(keys.KeyNew), "Create a new session"),
+		helpLine(keys.HelpKey(keys.KeyPrompt), "Create a new session with a prompt"),
+		helpLine(keys.HelpKey(keys.KeyIssue), "Create a new session from a GitHub issue"),
+		helpLine(keys.HelpKey(keys.KeyTemplate), "Create a new session from a template"),
+		helpLine(keys.HelpKey(keys.KeyBranch), "Create a new session on an existing branch"),
+		helpLine(keys.HelpKey(keys.KeyTask), "Give a task to the session that did related work, or a new one"),
+		helpLine(keys.HelpKey(keys.KeyTimeline), "Show the timeline of what the session did"),
+		helpLine(keys.HelpKey(keys.KeyFanOut), "Fan out a prompt across several sessions"),
+		helpLine(keys.HelpKey(keys.KeyCompare), "Compare the sessions of a fan-out and keep one"),
+		helpLine(keys.HelpKey(keys.KeyScope), "Assign the directories the selected session owns"),
+		helpLine(keys.HelpKey(keys.KeyNotes), "Edit the notes and TODOs of the selected session"),
+		helpLine(keys.HelpKey(keys.KeyBudget), "Set how long the selected session may run before it wraps up and pauses"),
+		helpLine(keys.HelpKey(keys.KeyApprove), "Approve the prompt auto-yes holds in the selected session"),
+		helpLine(keys.HelpKey(keys.KeyDeny), "Deny the prompt auto-yes holds in the selected session"),
+		helpLine(keys.HelpKey(keys.KeyImport), "Paste the clipboard or copy a file into the selected session"),
+		helpLine(keys.HelpKey(keys.KeyStack), "Create a new session stacked on the selected one"),
+		helpLine(keys.HelpKey(keys.KeyRestack), "Rebase the selected stack on its parents"),
+		helpLine(keys.HelpKey(keys.KeyFork), "Fork the selected session, its changes and conversation included"),
+		helpLine(keys.HelpKey(keys.KeyPipeline), "Run a pipeline of sessions, each starting from the previous one's branch"),
+		helpLine(keys.HelpKey(keys.KeyKill), "Kill (delete) the selected session"),
+		helpLine(keys.HelpKey(keys.KeyArchive), "Archive the selected session, keeping its branch and diff"),
+		helpLine(keys.HelpKey(keys.KeyArchived), "Inspect or resurrect archived sessions"),
+		helpLine(keys.HelpKey(keys.KeyHistory), "Show the history of killed and archived sessions"),
+		helpLine(navigate, "Navigate between sessions"),
+		helpLine(keys.HelpKey(keys.KeyEnter), "Attach to the selected session"),
+		helpLine(keys.HelpKey(keys.KeyExternal), "Open the selected session in a new terminal window"),
+		helpLine(keys.HelpKey(keys.KeyObserve), "Watch the selected session read-only, with its scrollback"),
+		helpLine(keys.HelpKey(keys.KeyQuickInput), "Type into the selected session from the preview, e.g. to answer a question"),
+		helpLine(keys.HelpKey(keys.KeyReview), "Review the diff with inline comments and send them to the agent"),
+		helpLine(keys.HelpKey(keys.KeyHandoff), "Hand off the diff or last message of the selected session to another"),
+		helpLine(keys.HelpKey(keys.KeyChecks), "Run the repository's checks on the selected session"),
+		helpLine(keys.HelpKey(keys.KeySummary), "Copy or save a markdown summary of the selected session"),
+		helpLine(keys.HelpKey(keys.KeyErrors), "Show the errors of the selected session and retry what failed"),
+		helpLine(keys.HelpKey(keys.KeyDoctor), "Find tmux sessions and worktrees without a session in the list, and the other way round"),
+		helpLine(keys.HelpKey(keys.KeyPalette), "Find a session, branch or action by typing part of its name"),
+		helpLine("ctrl-q", "Detach from session"),
 		"",
 		headerStyle.Render("Handoff:"),
-		keyStyle.Render("p")+descStyle.Render("         - Commit and push branch to github"),
-		keyStyle.Render("c")+descStyle.Render("         - Checkout: commit changes and pause session"),
-		keyStyle.Render("r")+descStyle.Render("         - Resume a paused session"),
+		helpLine(keys.HelpKey(keys.KeySubmit), "Commit and push branch to github"),
+		helpLine(keys.HelpKey(keys.KeyCheckout), "Checkout: commit changes and pause session"),
+		helpLine(keys.HelpKey(keys.KeyResume), "Resume a paused session"),
+		helpLine(keys.HelpKey(keys.KeySuspend), "Suspend all running sessions, or resume the suspended ones"),
 		"",
 		headerStyle.Render("Other:"),
-		keyStyle.Render("tab")+descStyle.Render("       - Switch between preview and diff tabs"),
-		keyStyle.Render("shift-↓/↑")+descStyle.Render(" - Scroll in diff view"),
-		keyStyle.Render("q")+descStyle.Render("         - Quit the application"),
+		helpLine(keys.HelpKey(keys.KeyTab), "Switch between preview and diff tabs, or the focused pane in the split layout"),
+		helpLine(keys.HelpKey(keys.KeyLayout), "Switch between the tabs and the split layout"),
+		helpLine(keys.HelpKey(keys.KeyShrink)+"/"+keys.HelpKey(keys.KeyGrow), "Shrink or grow the focused pane of the split layout"),
+		helpLine(keys.HelpKey(keys.KeyTheme), "Pick the color theme, e.g. for a light terminal"),
+		helpLine(keys.HelpKey(keys.KeyHeatMap), "Show which paths the squad is changing"),
+		helpLine(keys.HelpKey(keys.KeyProfile), "Switch to another profile, with its own sessions and config"),
+		helpLine(scroll, "Scroll in diff view"),
+		helpLine(keys.HelpKey(keys.KeyQuit), "Quit the application"),
 	)
 	return content
 }
 
+// helpLine renders the keys of an action and what it does, with the descriptions aligned.
+func helpLine(key string, desc string) string {
+	padding := max(helpKeyWidth-lipgloss.Width(key), 1)
+	return keyStyle.Render(key) + descStyle.Render(strings.Repeat(" ", padding)+"- "+desc)
+}
+
 func (h helpTypeInstanceStart) toContent() string {
 	content := lipgloss.JoinVertical(lipgloss.Left,
 		titleStyle.Render("Instance Created"),
@@ -71,13 +121,13 @@ func (h helpTypeInstanceStart) toContent() string {
 			lipgloss.NewStyle().Bold(true).Render(h.instance.Program))),
 		"",
 		headerStyle.Render("Managing:"),
-		keyStyle.Render("↵/o")+descStyle.Render("   - Attach to the session to interact with it directly"),
-		keyStyle.Render("tab")+descStyle.Render("   - Switch preview panes to view session diff"),
-		keyStyle.Render("D")+descStyle.Render("     - Kill (delete) the selected session"),
+		helpLine(keys.HelpKey(keys.KeyEnter), "Attach to the session to interact with it directly"),
+		helpLine(keys.HelpKey(keys.KeyTab), "Switch preview panes to view session diff"),
+		helpLine(keys.HelpKey(keys.KeyKill), "Kill (delete) the selected session"),
 		"",
 		headerStyle.Render("Handoff:"),
-		keyStyle.Render("c")+descStyle.Render("     - Checkout this instance's branch"),
-		keyStyle.Render("p")+descStyle.Render("     - Push branch to GitHub to create a PR"),
+		helpLine(keys.HelpKey(keys.KeyCheckout), "Checkout this instance's branch"),
+		helpLine(keys.HelpKey(keys.KeySubmit), "Push branch to GitHub to create a PR"),
 	)
 	return content
 }
@@ -100,8 +150,8 @@ func (h helpTypeInstanceCheckout) toContent() string {
 		"Feel free to make changes to the branch and commit them. When resuming, the session will continue from where you left off.",
 		"",
 		headerStyle.Render("Commands:"),
-		keyStyle.Render("c")+descStyle.Render(" - Checkout: commit changes locally and pause session"),
-		keyStyle.Render("r")+descStyle.Render(" - Resume a paused session"),
+		helpLine(keys.HelpKey(keys.KeyCheckout), "Checkout: commit changes locally and pause session"),
+		helpLine(keys.HelpKey(keys.KeyResume), "Resume a paused session"),
 	)
 	return content
 }
@@ -120,12 +170,22 @@ func (h helpTypeInstanceCheckout) mask() uint32 {
 }
 
 var (
-	titleStyle  = lipgloss.NewStyle().Bold(true).Underline(true).Foreground(lipgloss.Color("#7D56F4"))
-	headerStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#36CFC9"))
-	keyStyle    = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#FFCC00"))
-	descStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#FFFFFF"))
+	titleStyle  lipgloss.Style
+	headerStyle lipgloss.Style
+	keyStyle    lipgloss.Style
+	descStyle   lipgloss.Style
 )
 
+func init() {
+	// The help is restyled when the theme changes.
+	theme.OnChange(func(t theme.Theme) {
+		titleStyle = lipgloss.NewStyle().Bold(true).Underline(true).Foreground(t.Border)
+		headerStyle = lipgloss.NewStyle().Bold(true).Foreground(t.Heading)
+		keyStyle = lipgloss.NewStyle().Bold(true).Foreground(t.Highlight)
+		descStyle = lipgloss.NewStyle().Foreground(t.Text)
+	})
+}
+
 // showHelpScreen displays the help screen overlay if it hasn't been shown before
 func (m *home) showHelpScreen(helpType helpText, onDismiss func()) (tea.Model, tea.Cmd) {
 	// Get the flag for this help type
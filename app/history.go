@@ -0,0 +1,36 @@
+package app
+
+import (
+	"claude-squad/session"
+	"claude-squad/ui/overlay"
+	"fmt"
+	"slices"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// historyEntries is how many of the last killed or archived instances the history shows.
+const historyEntries = 30
+
+// showHistory shows the last killed and archived instances, most recent first, with their outcome, final
+// diff stats, duration and token usage.
+func (m *home) showHistory() tea.Cmd {
+	entries, err := session.LoadHistory()
+	if err != nil {
+		return m.handleError(err)
+	}
+	title := "History"
+	if len(entries) > historyEntries {
+		title = fmt.Sprintf("History (last %d of %d sessions, see cs history)", historyEntries, len(entries))
+		entries = entries[len(entries)-historyEntries:]
+	}
+	slices.Reverse(entries)
+	m.textOverlay = overlay.NewTextOverlay(lipgloss.JoinVertical(lipgloss.Left,
+		titleStyle.Render(title),
+		"",
+		session.FormatHistory(entries),
+	))
+	m.state = stateHelp
+	return nil
+}
@@ -0,0 +1,64 @@
+package app
+
+import (
+	"claude-squad/github"
+	"claude-squad/session"
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// issuePickerLimit is the number of open issues offered in the issue picker.
+const issuePickerLimit = 30
+
+// issuesListedMsg is sent when the open issues for the issue picker were fetched.
+type issuesListedMsg struct {
+	issues []github.Issue
+	err    error
+}
+
+// showIssuePicker fetches the open issues of the current repository in the background, since gh talks to
+// GitHub, then lets the user pick one to create an instance from.
+func (m *home) showIssuePicker() tea.Cmd {
+	return func() tea.Msg {
+		issues, err := github.ListIssues(".", issuePickerLimit)
+		return issuesListedMsg{issues: issues, err: err}
+	}
+}
+
+// issuesListed shows the issue picker with the fetched issues, unless the user moved on to something else
+// meanwhile.
+func (m *home) issuesListed(msg issuesListedMsg) tea.Cmd {
+	if msg.err != nil {
+		return m.handleError(msg.err)
+	}
+	if len(msg.issues) == 0 {
+		return m.handleError(fmt.Errorf("no open issues found"))
+	}
+	if m.state != stateDefault {
+		return nil
+	}
+
+	issues := msg.issues
+	items := make([]string, len(issues))
+	for i, issue := range issues {
+		items[i] = fmt.Sprintf("#%d %s", issue.Number, issue.Title)
+	}
+	m.selectItem("Create instance from issue", items, func(idx int) tea.Cmd {
+		return m.createInstanceFromIssue(&issues[idx])
+	})
+	return nil
+}
+
+// createInstanceFromIssue starts a new instance named after the issue and sends it the issue as its
+// initial prompt.
+func (m *home) createInstanceFromIssue(issue *github.Issue) tea.Cmd {
+	if _, err := m.launchInstance(session.InstanceOptions{
+		Title:   issue.InstanceTitle(),
+		Path:    ".",
+		Program: m.program,
+	}, issue.Prompt()); err != nil {
+		return m.handleError(err)
+	}
+	return m.instanceChanged()
+}
@@ -0,0 +1,28 @@
+package app
+
+import (
+	"claude-squad/session"
+	"claude-squad/ui"
+	"claude-squad/ui/overlay"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// confirmKillByTitle asks for the title of the instance before killing it, since it has work killing it
+// would lose, so a mistyped key can't.
+func (m *home) confirmKillByTitle(instance *session.Instance, unsaved *session.UnsavedWorkError, killAction tea.Cmd) tea.Cmd {
+	m.state = statePrompt
+	m.menu.SetState(ui.StatePrompt)
+	m.textInputOverlay = overlay.NewTextInputOverlay(
+		fmt.Sprintf("[!] '%s' has %s. Type its title to kill it", instance.Title, unsaved.Work), "")
+	m.promptHandler = func(value string) tea.Cmd {
+		if strings.TrimSpace(value) != instance.Title {
+			return m.handleError(fmt.Errorf("the title didn't match, '%s' wasn't killed", instance.Title))
+		}
+		msg := killAction()
+		return func() tea.Msg { return msg }
+	}
+	return tea.WindowSize()
+}
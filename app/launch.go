@@ -0,0 +1,59 @@
+package app
+
+import (
+	"claude-squad/session"
+	"fmt"
+)
+
+// launchInstance creates and starts a new instance without going through the naming flow, adds it to
+// the list, and sends it the prompt, if any. The prompt waits for the external resources of the instance
+// to be free, and for a running slot if max_running_instances are running.
+func (m *home) launchInstance(opts session.InstanceOptions, prompt string) (*session.Instance, error) {
+	if err := m.checkDispatch(); err != nil {
+		return nil, err
+	}
+	if m.list.NumInstances() >= GlobalInstanceLimit {
+		return nil, fmt.Errorf("you can't create more than %d instances", GlobalInstanceLimit)
+	}
+	for _, existing := range m.list.GetInstances() {
+		if existing.Title == opts.Title {
+			return nil, fmt.Errorf("instance %s already exists", opts.Title)
+		}
+	}
+
+	if err := session.CheckProviderCapacity(m.appConfig.Providers, opts.Provider, opts.Program, m.list.GetInstances()); err != nil {
+		return nil, err
+	}
+	if err := session.CheckExternalResources(m.appConfig.ExternalResources, opts.ExternalResources); err != nil {
+		return nil, err
+	}
+
+	instance, err := session.NewInstance(opts)
+	if err != nil {
+		return nil, err
+	}
+	defer instance.ActAs(opts.Actor)()
+	if _, err := instance.StartOrQueue(m.appConfig.MaxRunningInstances, m.list.GetInstances()); err != nil {
+		return nil, err
+	}
+	m.list.AddInstance(instance)()
+	m.list.SetSelectedInstance(m.list.NumInstances() - 1)
+	// If its resources are in use, the prompt is queued until they're free.
+	if _, err := instance.ClaimResources(m.appConfig.ExternalResources, m.list.GetInstances()); err != nil {
+		return instance, err
+	}
+	if m.autoYes {
+		instance.AutoYes = true
+	}
+
+	if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
+		return instance, err
+	}
+
+	if prompt != "" {
+		if err := instance.SendPrompt(prompt); err != nil {
+			return instance, err
+		}
+	}
+	return instance, nil
+}
@@ -0,0 +1,59 @@
+package app
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// minSplitWidth is the width of the terminal from which the split layout is shown. Narrower terminals
+// get the tabs, since the panes would be too narrow to read.
+const minSplitWidth = 120
+
+// splitFits returns true if the split layout is chosen and the terminal is wide enough for it.
+func (m *home) splitFits(width int) bool {
+	return m.split && width >= minSplitWidth
+}
+
+// diffHasFocus returns true if the keys navigating the list scroll the diff instead.
+func (m *home) diffHasFocus() bool {
+	return m.diffFocused && m.tabbedWindow.IsSplit()
+}
+
+// setDiffFocused gives the focus to the diff or the list in the split layout.
+func (m *home) setDiffFocused(focused bool) {
+	m.diffFocused = focused
+	m.tabbedWindow.SetDiffFocused(focused)
+	m.list.SetFocused(!m.diffHasFocus())
+}
+
+// toggleLayout switches between the tabs and the split layout, the focus going back to the list.
+func (m *home) toggleLayout() tea.Cmd {
+	m.split = !m.split
+	if m.split && m.windowSize.Width < minSplitWidth {
+		m.split = false
+		return m.handleError(fmt.Errorf("the split layout needs a terminal at least %d columns wide", minSplitWidth))
+	}
+	m.diffFocused = false
+	m.updateHandleWindowSizeEvent(m.windowSize)
+	return m.instanceChanged()
+}
+
+// resizePane grows the focused pane of the split layout by delta parts of the width, or shrinks it if
+// delta is negative, the preview making room for it. Every pane keeps at least one part.
+func (m *home) resizePane(delta int) tea.Cmd {
+	if !m.tabbedWindow.IsSplit() {
+		return nil
+	}
+	focused := 0
+	if m.diffFocused {
+		focused = 2
+	}
+	if m.splitRatios[focused]+delta < 1 || m.splitRatios[1]-delta < 1 {
+		return nil
+	}
+	m.splitRatios[focused] += delta
+	m.splitRatios[1] -= delta
+	m.updateHandleWindowSizeEvent(m.windowSize)
+	return m.instanceChanged()
+}
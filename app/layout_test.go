@@ -0,0 +1,69 @@
+package app
+
+import (
+	"claude-squad/config"
+	"claude-squad/ui"
+	"context"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+)
+
+func newLayoutHome(mode string) *home {
+	cfg := config.DefaultConfig()
+	cfg.Layout = config.Layout{Mode: mode}
+	h := &home{
+		ctx:          context.Background(),
+		state:        stateDefault,
+		appConfig:    cfg,
+		spinner:      spinner.New(),
+		menu:         ui.NewMenu(),
+		tabbedWindow: ui.NewTabbedWindow(ui.NewPreviewPane(), ui.NewDiffPane(), ui.NewNotesPane()),
+		errBox:       ui.NewErrBox(),
+		split:        cfg.Layout.Mode == config.LayoutSplit,
+		splitRatios:  cfg.Layout.SplitRatios(),
+	}
+	h.list = ui.NewList(&h.spinner, false)
+	return h
+}
+
+func TestSplitLayout(t *testing.T) {
+	h := newLayoutHome(config.LayoutSplit)
+	h.updateHandleWindowSizeEvent(tea.WindowSizeMsg{Width: 200, Height: 50})
+	assert.True(t, h.tabbedWindow.IsSplit())
+	assert.True(t, h.tabbedWindow.IsInDiffTab())
+	assert.NotEmpty(t, h.View())
+
+	// The diff grows at the expense of the preview while it has the focus.
+	h.setDiffFocused(true)
+	assert.True(t, h.diffHasFocus())
+	h.resizePane(1)
+	assert.Equal(t, [3]int{2, 4, 4}, h.splitRatios)
+	h.setDiffFocused(false)
+	assert.False(t, h.diffHasFocus())
+	h.resizePane(-1)
+	assert.Equal(t, [3]int{1, 5, 4}, h.splitRatios)
+	// Panes keep at least one part.
+	h.resizePane(-1)
+	assert.Equal(t, [3]int{1, 5, 4}, h.splitRatios)
+
+	// Narrow terminals get the tabs.
+	h.updateHandleWindowSizeEvent(tea.WindowSizeMsg{Width: 100, Height: 50})
+	assert.False(t, h.tabbedWindow.IsSplit())
+	h.updateHandleWindowSizeEvent(tea.WindowSizeMsg{Width: 200, Height: 50})
+	h.toggleLayout()
+	assert.False(t, h.tabbedWindow.IsSplit())
+	h.toggleLayout()
+	assert.True(t, h.tabbedWindow.IsSplit())
+}
+
+func TestTabsLayoutByDefault(t *testing.T) {
+	h := newLayoutHome("")
+	h.updateHandleWindowSizeEvent(tea.WindowSizeMsg{Width: 200, Height: 50})
+	assert.False(t, h.tabbedWindow.IsSplit())
+	assert.False(t, h.tabbedWindow.IsInDiffTab())
+	assert.Nil(t, h.resizePane(1))
+	assert.Equal(t, [3]int{2, 5, 3}, h.splitRatios)
+}
@@ -0,0 +1,30 @@
+package app
+
+import (
+	"claude-squad/session"
+	"claude-squad/ui"
+	"claude-squad/ui/overlay"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// showNotesInput edits the notes and TODOs of the selected instance as a single text, the TODOs being
+// lines starting with "- [ ]", or "- [x]" once they're done.
+func (m *home) showNotesInput() tea.Cmd {
+	selected := m.list.GetSelectedInstance()
+	if selected == nil {
+		return nil
+	}
+	m.state = statePrompt
+	m.menu.SetState(ui.StatePrompt)
+	m.textInputOverlay = overlay.NewTextInputOverlay("Notes and TODOs of "+selected.Title+" (TODOs start with - [ ])",
+		session.FormatNotes(selected.Notes, selected.Todos))
+	m.promptHandler = func(value string) tea.Cmd {
+		selected.SetNotes(value)
+		if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
+			return m.handleError(err)
+		}
+		return m.instanceChanged()
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package app
+
+import (
+	"claude-squad/config"
+	"claude-squad/session"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// notifyWebhooks posts the text about the instance to the webhooks that post the event, and sends it to
+// the backends the notify rules route it to.
+func (m *home) notifyWebhooks(event string, instance *session.Instance, text string) tea.Cmd {
+	if !m.notifier.Posts(event) {
+		return nil
+	}
+	notifier := m.notifier
+	return func() tea.Msg {
+		if err := notifier.Notify(event, instance, text); err != nil {
+			return err
+		}
+		return nil
+	}
+}
+
+// notifyStuck posts that the instance waits on a prompt matching the stuck patterns: auto-yes was
+// blocked from answering it if it's on, and it needs input otherwise.
+func (m *home) notifyStuck(instance *session.Instance) tea.Cmd {
+	if instance.AutoYes {
+		return m.notifyWebhooks(config.NotifyEventBlocked, instance, "is waiting on a prompt auto-yes doesn't answer")
+	}
+	return m.notifyWebhooks(config.NotifyEventNeedsInput, instance, "is waiting on a prompt")
+}
+
+// notifyApproval posts that auto-yes holds a permission prompt of the instance for approval.
+func (m *home) notifyApproval(instance *session.Instance) tea.Cmd {
+	kind, _ := instance.HeldPrompt()
+	text := "is waiting for approval of a " + string(kind) + " prompt"
+	return tea.Batch(m.notifySlack(instance, text), m.notifyWebhooks(config.NotifyEventBlocked, instance, text))
+}
+
+// notifyFinished posts that the instance is ready once it has been for a while, unless it was already
+// posted since the instance last ran.
+func (m *home) notifyFinished(instance *session.Instance) tea.Cmd {
+	if !m.notifier.Finished(instance, time.Now()) {
+		return nil
+	}
+	return tea.Batch(m.notifySlack(instance, "is ready"),
+		m.notifyWebhooks(config.NotifyEventFinished, instance, "is ready"))
+}
@@ -0,0 +1,41 @@
+package app
+
+import (
+	"claude-squad/session"
+	"claude-squad/ui/overlay"
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// showObserver mirrors the pane of the instance, with its scrollback, without attaching to it, so nothing
+// typed reaches its agent. It's refreshed along with the preview.
+func (m *home) showObserver(selected *session.Instance) tea.Cmd {
+	if selected == nil || selected.Paused() || !selected.TmuxAlive() {
+		return nil
+	}
+	m.observed = selected
+	m.observeOverlay = overlay.NewObserveOverlay(fmt.Sprintf("Observing %s", selected.Title))
+	m.state = stateObserve
+	return tea.Batch(tea.WindowSize(), m.refreshObserver())
+}
+
+// refreshObserver updates the observe overlay with the pane of the observed instance. The overlay is
+// closed if the pane can't be read anymore, e.g. because the instance was paused.
+func (m *home) refreshObserver() tea.Cmd {
+	if m.observeOverlay == nil || m.observed == nil {
+		return nil
+	}
+	content, err := m.observed.Scrollback()
+	if err != nil || m.observed.Paused() {
+		m.state = stateDefault
+		m.observeOverlay = nil
+		m.observed = nil
+		if err != nil {
+			return tea.Batch(tea.WindowSize(), m.handleError(fmt.Errorf("stopped observing: %w", err)))
+		}
+		return tea.WindowSize()
+	}
+	m.observeOverlay.SetContent(content)
+	return nil
+}
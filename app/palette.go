@@ -0,0 +1,131 @@
+package app
+
+import (
+	"claude-squad/keys"
+	"claude-squad/log"
+	"claude-squad/session/git"
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// paletteSkippedActions are the actions the finder doesn't offer: moving around the list, and the finder
+// itself.
+var paletteSkippedActions = map[keys.KeyName]bool{
+	keys.KeyUp:        true,
+	keys.KeyDown:      true,
+	keys.KeyShiftUp:   true,
+	keys.KeyShiftDown: true,
+	keys.KeyPalette:   true,
+}
+
+// specialKeyTypes are the keys of actions that aren't typed as a single character.
+var specialKeyTypes = map[string]tea.KeyType{
+	"enter": tea.KeyEnter,
+	"tab":   tea.KeyTab,
+	"esc":   tea.KeyEsc,
+}
+
+// paletteEntry is an item of the finder, and what selecting it does.
+type paletteEntry struct {
+	label string
+	run   func() tea.Cmd
+}
+
+// showPalette shows the finder, which jumps to an instance by its title, branch or repository, creates
+// an instance on a branch, or runs an action by name, filtered by what's typed.
+func (m *home) showPalette() tea.Cmd {
+	var entries []paletteEntry
+	for idx, instance := range m.list.GetInstances() {
+		label := instance.Title
+		if instance.Branch != "" {
+			label += "  ⎇ " + instance.Branch
+		}
+		if instance.Started() {
+			if repo, err := instance.RepoName(); err == nil {
+				label += "  (" + repo + ")"
+			}
+		}
+		entries = append(entries, paletteEntry{label: label, run: func() tea.Cmd {
+			m.list.SetSelectedInstance(idx)
+			return m.instanceChanged()
+		}})
+	}
+
+	entries = append(entries, paletteEntry{
+		label: "conversations: resume a claude conversation in a new session",
+		run:   func() tea.Cmd { return actionCmd(keys.KeyClaudeResume) },
+	})
+	for _, action := range paletteActions() {
+		binding := keys.GlobalkeyBindings[action.name]
+		entries = append(entries, paletteEntry{
+			label: fmt.Sprintf("action: %s - %s (%s)", action.action, binding.Help().Desc, keys.HelpKey(action.name)),
+			run:   func() tea.Cmd { return actionCmd(action.name) },
+		})
+	}
+
+	if branches, err := git.ListAvailableBranches("."); err != nil {
+		log.WarningLog.Printf("could not list the branches for the finder: %v", err)
+	} else {
+		for _, branch := range branches {
+			entries = append(entries, paletteEntry{
+				label: "branch: " + branch + " - new session",
+				run:   func() tea.Cmd { return m.launchOnBranch(branch) },
+			})
+		}
+	}
+
+	labels := make([]string, len(entries))
+	for idx, entry := range entries {
+		labels[idx] = entry.label
+	}
+	m.findItem("Find a session, branch or action", labels, func(idx int) tea.Cmd {
+		return entries[idx].run()
+	})
+	return nil
+}
+
+type paletteAction struct {
+	action string
+	name   keys.KeyName
+}
+
+// paletteActions returns the actions the finder offers, by name.
+func paletteActions() []paletteAction {
+	var actions []paletteAction
+	for action, name := range keys.KeyActions {
+		if !paletteSkippedActions[name] {
+			actions = append(actions, paletteAction{action: action, name: name})
+		}
+	}
+	sort.Slice(actions, func(i, j int) bool {
+		return actions[i].action < actions[j].action
+	})
+	return actions
+}
+
+// actionCmd returns a command that presses the key of the action, so it runs as if it was typed.
+func actionCmd(name keys.KeyName) tea.Cmd {
+	for _, k := range keys.GlobalkeyBindings[name].Keys() {
+		if msg, ok := keyMsg(k); ok {
+			return func() tea.Msg { return msg }
+		}
+	}
+	return nil
+}
+
+// keyMsg returns the press of the key as it's named in the keybindings, e.g. "a", "enter" or "ctrl+u".
+func keyMsg(k string) (tea.KeyMsg, bool) {
+	if keyType, ok := specialKeyTypes[k]; ok {
+		return tea.KeyMsg{Type: keyType}, true
+	}
+	if runes := []rune(k); len(runes) == 1 {
+		return tea.KeyMsg{Type: tea.KeyRunes, Runes: runes}, true
+	}
+	if letter, ok := strings.CutPrefix(k, "ctrl+"); ok && len(letter) == 1 && letter[0] >= 'a' && letter[0] <= 'z' {
+		return tea.KeyMsg{Type: tea.KeyCtrlA + tea.KeyType(letter[0]-'a')}, true
+	}
+	return tea.KeyMsg{}, false
+}
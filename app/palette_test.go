@@ -0,0 +1,18 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyMsg(t *testing.T) {
+	for _, k := range []string{"a", "D", "?", "enter", "tab", "ctrl+u"} {
+		msg, ok := keyMsg(k)
+		require.True(t, ok, k)
+		assert.Equal(t, k, msg.String(), "the key press reads as the key it was made from")
+	}
+	_, ok := keyMsg("shift+up")
+	assert.False(t, ok)
+}
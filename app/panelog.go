@@ -0,0 +1,47 @@
+package app
+
+import (
+	"claude-squad/log"
+	"claude-squad/session"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type tickPaneLogMessage struct{}
+
+var tickPaneLogCmd = func() tea.Msg {
+	time.Sleep(session.PaneLogInterval)
+	return tickPaneLogMessage{}
+}
+
+// appendPaneLogs captures the output of the instances, which reads their state, and appends it to their
+// logs in the background, then waits for the next append.
+func (m *home) appendPaneLogs() tea.Cmd {
+	cfg := m.appConfig.PaneLog
+	if !cfg.Enabled {
+		return nil
+	}
+	var instances []*session.Instance
+	var contents []string
+	for _, instance := range m.list.GetInstances() {
+		content, err := instance.CapturePaneHistory()
+		if err != nil {
+			log.WarningLog.Printf("could not capture the output of %s: %v", instance.Title, err)
+			continue
+		}
+		if content != "" {
+			instances = append(instances, instance)
+			contents = append(contents, content)
+		}
+	}
+	return func() tea.Msg {
+		now := time.Now()
+		for idx, instance := range instances {
+			if err := instance.AppendPaneOutput(cfg, contents[idx], now); err != nil {
+				log.WarningLog.Printf("could not log the output of %s: %v", instance.Title, err)
+			}
+		}
+		return tickPaneLogCmd()
+	}
+}
@@ -0,0 +1,133 @@
+package app
+
+import (
+	"claude-squad/pipeline"
+	"claude-squad/session"
+	"claude-squad/ui"
+	"claude-squad/ui/overlay"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// showPipelinePicker lets the user pick one of the configured pipelines to run.
+func (m *home) showPipelinePicker() tea.Cmd {
+	pipelines, err := pipeline.Load()
+	if err != nil {
+		return m.handleError(err)
+	}
+	if len(pipelines) == 0 {
+		return m.handleError(fmt.Errorf("no pipelines found, define them in ~/.claude-squad/%s", pipeline.FileName))
+	}
+
+	items := make([]string, len(pipelines))
+	for i, p := range pipelines {
+		names := make([]string, len(p.Stages))
+		for j, stage := range p.Stages {
+			names[j] = stage.Name
+		}
+		items[i] = p.Name
+		if p.Description != "" {
+			items[i] += " - " + p.Description
+		}
+		items[i] += " (" + strings.Join(names, " → ") + ")"
+	}
+	m.selectItem("Run pipeline", items, func(idx int) tea.Cmd {
+		return m.showPipelineTaskInput(pipelines[idx])
+	})
+	return nil
+}
+
+// showPipelineTaskInput asks for the task to run through the pipeline.
+func (m *home) showPipelineTaskInput(p *pipeline.Pipeline) tea.Cmd {
+	m.state = statePrompt
+	m.menu.SetState(ui.StatePrompt)
+	m.textInputOverlay = overlay.NewTextInputOverlay("Task for pipeline "+p.Name, "")
+	m.promptHandler = func(task string) tea.Cmd {
+		if strings.TrimSpace(task) == "" {
+			return m.handleError(fmt.Errorf("task cannot be empty"))
+		}
+		if _, err := m.startStage(p, 0, task, nil); err != nil {
+			return m.handleError(err)
+		}
+		return m.instanceChanged()
+	}
+	return tea.WindowSize()
+}
+
+// startStage starts the instance of the stage of the pipeline at index. Later stages are stacked on the
+// instance of the previous stage and get its summary.
+func (m *home) startStage(p *pipeline.Pipeline, index int, task string, previous *session.Instance) (*session.Instance, error) {
+	stage := p.Stages[index]
+	program := m.program
+	if stage.Program != "" {
+		program = stage.Program
+	}
+	summary := ""
+	if previous != nil {
+		summary = previous.StageSummary()
+	}
+	return m.launchInstance(session.InstanceOptions{
+		Title:    session.TitleFromText(stage.Name + " " + task),
+		Path:     ".",
+		Program:  program,
+		Parent:   previous,
+		Pipeline: &session.PipelineStage{Pipeline: p.Name, Stage: index, Task: task},
+	}, p.Prompt(index, task, summary))
+}
+
+// advancePipelines starts the next stage of the pipelines whose current stage is done. The selection
+// stays where it was, since this happens in the background.
+func (m *home) advancePipelines(now time.Time) error {
+	var finished []*session.Instance
+	for _, instance := range m.list.GetInstances() {
+		if instance.StageFinished(now) {
+			finished = append(finished, instance)
+		}
+	}
+	// The stages are advanced once the backoff is over.
+	if len(finished) == 0 || m.checkDispatch() != nil {
+		return nil
+	}
+
+	selected := m.list.GetSelectedInstance()
+	var errs []error
+	pipelines, loadErr := pipeline.Load()
+	if loadErr != nil {
+		errs = append(errs, loadErr)
+	}
+	for _, instance := range finished {
+		if err := instance.FinishStage(); err != nil {
+			errs = append(errs, fmt.Errorf("could not commit stage '%s': %w", instance.Title, err))
+			continue
+		}
+		if loadErr != nil {
+			continue
+		}
+		stage := instance.Pipeline
+		p, err := pipeline.Find(pipelines, stage.Pipeline)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if stage.Stage+1 >= len(p.Stages) {
+			continue
+		}
+		if _, err := m.startStage(p, stage.Stage+1, stage.Task, instance); err != nil {
+			errs = append(errs, fmt.Errorf("could not start stage %s of pipeline %s after '%s': %w",
+				p.Stages[stage.Stage+1].Name, p.Name, instance.Title, err))
+		}
+	}
+	for idx, instance := range m.list.GetInstances() {
+		if instance == selected {
+			m.list.SetSelectedInstance(idx)
+		}
+	}
+	if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
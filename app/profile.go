@@ -0,0 +1,56 @@
+package app
+
+import (
+	"claude-squad/config"
+	"claude-squad/ui"
+	"claude-squad/ui/overlay"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// showProfiles lists the profiles to switch to, or to create a new one.
+func (m *home) showProfiles() tea.Cmd {
+	profiles, err := config.ListProfiles()
+	if err != nil {
+		return m.handleError(err)
+	}
+	current := config.ProfileName()
+	items := make([]string, 0, len(profiles)+1)
+	for _, profile := range profiles {
+		if profile == current {
+			profile += " (current)"
+		}
+		items = append(items, profile)
+	}
+	items = append(items, "New profile…")
+	m.selectItem("Switch profile", items, func(idx int) tea.Cmd {
+		if idx < len(profiles) {
+			return m.switchProfile(profiles[idx])
+		}
+		m.state = statePrompt
+		m.menu.SetState(ui.StatePrompt)
+		m.textInputOverlay = overlay.NewTextInputOverlay("Name of the new profile", "")
+		m.promptHandler = func(value string) tea.Cmd {
+			return m.switchProfile(strings.TrimSpace(value))
+		}
+		return nil
+	})
+	return nil
+}
+
+// switchProfile saves the instances and quits, for the app to be run again with the profile. The
+// instances of the current profile keep running, like when quitting.
+func (m *home) switchProfile(profile string) tea.Cmd {
+	if profile == config.ProfileName() {
+		return nil
+	}
+	if err := config.ValidateProfile(profile); err != nil {
+		return m.handleError(err)
+	}
+	if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
+		return m.handleError(err)
+	}
+	m.nextProfile = profile
+	return tea.Quit
+}
@@ -0,0 +1,31 @@
+package app
+
+import (
+	"claude-squad/session"
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// nextProvider switches the instance being named to the next provider of the config, with its program
+// and environment, and back to the default program after the last one.
+func (m *home) nextProvider(instance *session.Instance) tea.Cmd {
+	providers := m.appConfig.Providers
+	if len(providers) == 0 {
+		return m.handleError(fmt.Errorf("no providers configured: add them to providers in the config to pick a model per session"))
+	}
+	next := 0
+	for i, provider := range providers {
+		if provider.Name == instance.Provider {
+			next = i + 1
+		}
+	}
+	if next == len(providers) {
+		instance.Provider = ""
+		instance.Program = m.program
+		return nil
+	}
+	instance.Provider = providers[next].Name
+	instance.Program = providers[next].Program
+	return nil
+}
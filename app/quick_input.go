@@ -0,0 +1,64 @@
+package app
+
+import (
+	"claude-squad/session"
+	"claude-squad/ui"
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// quickInputEnterDelay is the pause between a line typed through the quick input and the enter after
+// it, so the agent doesn't take the enter for a new line of a paste.
+const quickInputEnterDelay = 100 * time.Millisecond
+
+// showQuickInput opens the quick input at the bottom of the preview, which types into the selected
+// instance without attaching to it.
+func (m *home) showQuickInput(selected *session.Instance) tea.Cmd {
+	if selected == nil || !selected.Started() || selected.Paused() || !selected.TmuxAlive() {
+		return nil
+	}
+	m.quickInput = ui.NewQuickInput()
+	m.quickInputTarget = selected
+	m.tabbedWindow.SetQuickInput(m.quickInput)
+	m.state = stateQuickInput
+	return m.instanceChanged()
+}
+
+// closeQuickInput closes the quick input.
+func (m *home) closeQuickInput() {
+	m.tabbedWindow.SetQuickInput(nil)
+	m.quickInput = nil
+	m.quickInputTarget = nil
+	m.state = stateDefault
+}
+
+// handleQuickInputKey edits the line of the quick input, or types what the key sends into the instance.
+// The quick input is closed if the instance can't be typed into anymore, e.g. because it was paused.
+func (m *home) handleQuickInputKey(msg tea.KeyMsg) tea.Cmd {
+	keys, closed := m.quickInput.HandleKeyPress(msg)
+	target := m.quickInputTarget
+	if closed {
+		m.closeQuickInput()
+		return m.instanceChanged()
+	}
+	if len(keys) == 0 {
+		return m.instanceChanged()
+	}
+	if target.Paused() || !target.TmuxAlive() {
+		m.closeQuickInput()
+		return m.handleError(fmt.Errorf("%s isn't running anymore", target.Title))
+	}
+	return func() tea.Msg {
+		for i, k := range keys {
+			if i > 0 {
+				time.Sleep(quickInputEnterDelay)
+			}
+			if err := target.SendInput(k); err != nil {
+				return err
+			}
+		}
+		return instanceChangedMsg{}
+	}
+}
@@ -0,0 +1,59 @@
+package app
+
+import (
+	"claude-squad/config"
+	"claude-squad/e2e"
+	"claude-squad/ui"
+	"context"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuickInputTypesIntoInstance(t *testing.T) {
+	h := e2e.New(t)
+	instance := h.NewInstance("quick")
+	s := spinner.New(spinner.WithSpinner(spinner.MiniDot))
+	list := ui.NewList(&s, false)
+	_ = list.AddInstance(instance)
+	list.SetSelectedInstance(0)
+	m := &home{
+		ctx:          context.Background(),
+		state:        stateDefault,
+		appConfig:    config.DefaultConfig(),
+		list:         list,
+		menu:         ui.NewMenu(),
+		errBox:       ui.NewErrBox(),
+		tabbedWindow: ui.NewTabbedWindow(ui.NewPreviewPane(), ui.NewDiffPane(), ui.NewNotesPane()),
+	}
+
+	m.showQuickInput(instance)
+	require.Equal(t, stateQuickInput, m.state)
+
+	// The line is only edited until enter sends it, then enter, to the pane.
+	for _, msg := range []tea.KeyMsg{
+		{Type: tea.KeyRunes, Runes: []rune("hello")},
+		{Type: tea.KeySpace},
+		{Type: tea.KeyRunes, Runes: []rune("world")},
+	} {
+		assert.Nil(t, m.handleQuickInputKey(msg))
+	}
+	cmd := m.handleQuickInputKey(tea.KeyMsg{Type: tea.KeyEnter})
+	require.NotNil(t, cmd)
+	assert.Equal(t, instanceChangedMsg{}, cmd())
+	h.WaitForPane(instance, "done: hello world")
+
+	assert.Nil(t, m.handleQuickInputKey(tea.KeyMsg{Type: tea.KeyCtrlQ}))
+	assert.Equal(t, stateDefault, m.state)
+	assert.Nil(t, m.quickInput)
+
+	// The quick input closes once the instance can't be typed into.
+	m.showQuickInput(instance)
+	require.NoError(t, instance.Kill())
+	assert.NotNil(t, m.handleQuickInputKey(tea.KeyMsg{Type: tea.KeyEnter}))
+	assert.Equal(t, stateDefault, m.state)
+	assert.Nil(t, m.quickInput)
+}
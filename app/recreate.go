@@ -0,0 +1,80 @@
+package app
+
+import (
+	"claude-squad/session"
+	"errors"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// offerRecreate explains that the session of the instance is gone and offers to start it again from its
+// branch, instead of failing to attach to it.
+func (m *home) offerRecreate(instance *session.Instance) tea.Cmd {
+	title := fmt.Sprintf("The session of '%s' is gone, e.g. because the machine rebooted. "+
+		"Its branch '%s' and changes are kept.", instance.Title, instance.Branch)
+	m.selectItem(title, []string{"Recreate the session from the branch", "Leave it"}, func(idx int) tea.Cmd {
+		if idx != 0 {
+			return nil
+		}
+		if err := instance.Recreate(true); err != nil {
+			return m.handleError(fmt.Errorf("failed to recreate the session of %s: %w", instance.Title, err))
+		}
+		if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
+			return m.handleError(err)
+		}
+		return m.instanceChanged()
+	})
+	return nil
+}
+
+// offerRecovery marks the instances whose sessions are gone at startup, e.g. because the machine
+// rebooted, and offers to recreate or pause all of them at once.
+func (m *home) offerRecovery() tea.Cmd {
+	var gone []*session.Instance
+	var titles []string
+	for _, instance := range m.list.GetInstances() {
+		if instance.MarkGone() {
+			gone = append(gone, instance)
+			titles = append(titles, instance.Title)
+		}
+	}
+	if len(gone) == 0 {
+		return nil
+	}
+
+	title := fmt.Sprintf("The sessions of %d instances are gone, e.g. because the machine rebooted: %s. "+
+		"Their branches and changes are kept.", len(gone), strings.Join(titles, ", "))
+	items := []string{
+		"Recreate all, resuming their claude conversations",
+		"Recreate all with new conversations",
+		"Pause all",
+		"Leave them",
+	}
+	m.selectItem(title, items, func(idx int) tea.Cmd {
+		if idx == len(items)-1 {
+			return nil
+		}
+		var errs []error
+		for _, instance := range gone {
+			var err error
+			if idx == 2 {
+				err = instance.Pause()
+			} else {
+				err = instance.Recreate(idx == 0)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", instance.Title, err))
+			}
+		}
+		if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
+			errs = append(errs, err)
+		}
+		if len(errs) > 0 {
+			return tea.Batch(m.handleError(errors.Join(errs...)), m.instanceChanged())
+		}
+		return m.instanceChanged()
+	})
+	return nil
+}
@@ -0,0 +1,103 @@
+package app
+
+import (
+	"claude-squad/config"
+	"claude-squad/log"
+	"claude-squad/session"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// resourceSampleInterval is how often the process trees of the instances are sampled. Listing all
+// processes is expensive, so it's done less often than the metadata updates.
+const resourceSampleInterval = 5 * time.Second
+
+type tickResourceUsageMessage struct{}
+
+var tickResourceUsageCmd = func() tea.Msg {
+	time.Sleep(resourceSampleInterval)
+	return tickResourceUsageMessage{}
+}
+
+// resourceUsageSampledMsg is sent when the processes of the instances were listed.
+type resourceUsageSampledMsg struct {
+	sample *session.ProcessSample
+	err    error
+}
+
+// overLimitsMsg is sent with the instances exceeding the resource limits, to be paused in Update since
+// pausing changes them.
+type overLimitsMsg struct {
+	instances  []*session.Instance
+	violations []string
+}
+
+// sampleResourceUsage lists the processes of the instances in the background, since running ps would
+// block the UI.
+func (m *home) sampleResourceUsage() tea.Cmd {
+	instances := slices.Clone(m.list.GetInstances())
+	return func() tea.Msg {
+		sample, err := session.SampleProcesses(instances, time.Now())
+		return resourceUsageSampledMsg{sample: sample, err: err}
+	}
+}
+
+// checkResourceUsage records the sampled resource usage of the instances and warns about the ones
+// exceeding the configured limits, or has them paused.
+func (m *home) checkResourceUsage(msg resourceUsageSampledMsg) tea.Cmd {
+	if msg.err != nil {
+		log.WarningLog.Printf("could not sample resource usage: %v", msg.err)
+		return tickResourceUsageCmd
+	}
+	msg.sample.Record()
+
+	now := time.Now()
+	limits := m.appConfig.ResourceLimits
+	var errs []error
+	var overLimits []*session.Instance
+	var violations []string
+	for _, instance := range m.list.GetInstances() {
+		violation := instance.CheckResourceLimits(limits, now)
+		if violation == "" {
+			continue
+		}
+		if limits.Action == config.ResourceActionPause {
+			overLimits = append(overLimits, instance)
+			violations = append(violations, violation)
+			continue
+		}
+		errs = append(errs, fmt.Errorf("instance '%s' exceeds its resource limits: %s", instance.Title, violation))
+	}
+	cmds := []tea.Cmd{tickResourceUsageCmd}
+	if len(overLimits) > 0 {
+		cmds = append(cmds, func() tea.Msg {
+			return overLimitsMsg{instances: overLimits, violations: violations}
+		})
+	}
+	if len(errs) > 0 {
+		cmds = append(cmds, m.handleError(errors.Join(errs...)), m.instanceChanged())
+	}
+	return tea.Batch(cmds...)
+}
+
+// pauseOverLimits pauses the instances exceeding the resource limits, and saves them so they stay paused
+// after a crash.
+func (m *home) pauseOverLimits(msg overLimitsMsg) tea.Cmd {
+	var errs []error
+	for idx, instance := range msg.instances {
+		if err := instance.Pause(); err != nil {
+			errs = append(errs, fmt.Errorf("instance '%s' exceeds its resource limits (%s) and could not be paused: %w",
+				instance.Title, msg.violations[idx], err))
+			continue
+		}
+		errs = append(errs, fmt.Errorf("paused instance '%s': %s", instance.Title, msg.violations[idx]))
+	}
+	if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
+		errs = append(errs, err)
+	}
+	return tea.Batch(m.handleError(errors.Join(errs...)), m.instanceChanged())
+}
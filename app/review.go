@@ -0,0 +1,91 @@
+package app
+
+import (
+	"claude-squad/session"
+	"claude-squad/ui"
+	"claude-squad/ui/overlay"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// showReview opens the diff of the selected instance to comment on its lines, with the cursor on the
+// line at cursor.
+func (m *home) showReview(selected *session.Instance, cursor int) tea.Cmd {
+	stats := selected.GetDiffStats()
+	if stats == nil || stats.IsEmpty() {
+		return m.handleError(fmt.Errorf("instance %s has no changes to review", selected.Title))
+	}
+	if stats.Error != nil {
+		return m.handleError(stats.Error)
+	}
+
+	diffLines := session.ParseDiffLines(stats.Content)
+	lines := make([]overlay.ReviewLine, len(diffLines))
+	for i, line := range diffLines {
+		lines[i] = overlay.ReviewLine{
+			Text:        strings.TrimRight(ui.ColorizeDiff(line.Text), "\n"),
+			Commentable: line.Line > 0,
+		}
+		if comment, ok := selected.ReviewCommentOn(line); ok {
+			lines[i].Comment = comment.Text
+		}
+	}
+
+	title := fmt.Sprintf("Review %s", selected.Title)
+	if count := len(selected.ReviewComments()); count > 0 {
+		title += fmt.Sprintf(" (%d comments)", count)
+	}
+	m.state = stateReview
+	m.reviewOverlay = overlay.NewReviewOverlay(title, lines)
+	m.reviewOverlay.SetCursor(cursor)
+	m.reviewHandler = func(action overlay.ReviewAction, idx int) tea.Cmd {
+		switch action {
+		case overlay.ReviewComment:
+			return m.showReviewCommentInput(selected, diffLines[idx], idx)
+		case overlay.ReviewDelete:
+			selected.RemoveReviewComment(diffLines[idx])
+			return m.showReview(selected, idx)
+		case overlay.ReviewSubmit:
+			return m.sendReview(selected)
+		}
+		return nil
+	}
+	return tea.WindowSize()
+}
+
+// showReviewCommentInput asks for the comment on a line of the diff, then goes back to the review.
+func (m *home) showReviewCommentInput(selected *session.Instance, line session.DiffLine, idx int) tea.Cmd {
+	existing, _ := selected.ReviewCommentOn(line)
+	m.state = statePrompt
+	m.menu.SetState(ui.StatePrompt)
+	m.textInputOverlay = overlay.NewTextInputOverlay(fmt.Sprintf("Comment on %s:%d", line.File, line.Line),
+		existing.Text)
+	m.promptHandler = func(value string) tea.Cmd {
+		if strings.TrimSpace(value) == "" {
+			selected.RemoveReviewComment(line)
+		} else {
+			selected.AddReviewComment(line, value)
+		}
+		return m.showReview(selected, idx)
+	}
+	return tea.WindowSize()
+}
+
+// sendReview sends the pending review comments of the instance to its program.
+func (m *home) sendReview(selected *session.Instance) tea.Cmd {
+	if len(selected.ReviewComments()) == 0 {
+		return m.handleError(fmt.Errorf("add a comment before sending the review"))
+	}
+	if selected.Paused() {
+		return m.handleError(fmt.Errorf("instance %s is paused: resume it before sending the review", selected.Title))
+	}
+	if err := m.checkDispatch(); err != nil {
+		return m.handleError(err)
+	}
+	if err := selected.SendReview(); err != nil {
+		return m.handleError(err)
+	}
+	return m.instanceChanged()
+}
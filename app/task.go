@@ -0,0 +1,84 @@
+package app
+
+import (
+	"claude-squad/session"
+	"claude-squad/ui"
+	"claude-squad/ui/overlay"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// showTaskPrompt asks for a task to dispatch to the instance that did related work, or a new instance.
+func (m *home) showTaskPrompt() tea.Cmd {
+	m.state = statePrompt
+	m.menu.SetState(ui.StatePrompt)
+	m.textInputOverlay = overlay.NewTextInputOverlay("Task", "")
+	m.promptHandler = m.dispatchTask
+	return nil
+}
+
+// dispatchTask offers to send a follow-up task to the instance that touched the files it mentions or
+// worked on the same feature, and otherwise starts a new instance for it.
+func (m *home) dispatchTask(task string) tea.Cmd {
+	if strings.TrimSpace(task) == "" {
+		return m.handleError(fmt.Errorf("task cannot be empty"))
+	}
+	if err := m.checkDispatch(); err != nil {
+		return m.handleError(err)
+	}
+
+	related := session.FindAffinity(m.list.GetInstances(), task)
+	if related == nil {
+		return m.startTaskInstance(task)
+	}
+
+	sendTo := fmt.Sprintf("Send to '%s'", related.Title)
+	if files := related.RelatedFiles(task); len(files) > 0 {
+		sendTo += fmt.Sprintf(" (touched %s)", strings.Join(files, ", "))
+	}
+	if related.Paused() {
+		sendTo += ", resuming it"
+	}
+	m.selectItem("Follow-up of earlier work", []string{sendTo, "Start a new instance"}, func(idx int) tea.Cmd {
+		if idx == 1 {
+			return m.startTaskInstance(task)
+		}
+		return m.sendFollowUp(related, task)
+	})
+	return nil
+}
+
+// startTaskInstance starts a new instance named after the task and sends it the task.
+func (m *home) startTaskInstance(task string) tea.Cmd {
+	if _, err := m.launchInstance(session.InstanceOptions{
+		Title:   session.TitleFromText(task),
+		Path:    ".",
+		Program: m.program,
+	}, task); err != nil {
+		return m.handleError(err)
+	}
+	return m.instanceChanged()
+}
+
+// sendFollowUp sends the task to the instance, resuming it first if it is paused. If it's queued for a
+// running slot, the task is sent once it's resumed.
+func (m *home) sendFollowUp(instance *session.Instance, task string) tea.Cmd {
+	if instance.Paused() {
+		if _, err := instance.ResumeOrQueue(m.appConfig.MaxRunningInstances, m.appConfig.Providers, m.list.GetInstances()); err != nil {
+			return m.handleError(err)
+		}
+		// The program was restarted without the context of the earlier work.
+		task = session.FollowUpPrompt(instance, task)
+	}
+	if err := instance.SendPrompt(task); err != nil {
+		return m.handleError(err)
+	}
+	for idx, existing := range m.list.GetInstances() {
+		if existing == instance {
+			m.list.SetSelectedInstance(idx)
+		}
+	}
+	return tea.Batch(tea.WindowSize(), m.instanceChanged())
+}
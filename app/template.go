@@ -0,0 +1,62 @@
+package app
+
+import (
+	"claude-squad/session"
+	"claude-squad/template"
+	"claude-squad/ui"
+	"claude-squad/ui/overlay"
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// showTemplatePicker lets the user pick one of the imported templates to create an instance from.
+func (m *home) showTemplatePicker() tea.Cmd {
+	templates, err := template.LoadTemplates()
+	if err != nil {
+		return m.handleError(err)
+	}
+	if len(templates) == 0 {
+		return m.handleError(fmt.Errorf("no templates found, import a pack with 'cs templates import'"))
+	}
+
+	items := make([]string, len(templates))
+	for i, t := range templates {
+		items[i] = t.FullName()
+		if t.Description != "" {
+			items[i] += " - " + t.Description
+		}
+		if provenance := t.Provenance(); provenance != "" {
+			items[i] += " (" + provenance + ")"
+		}
+	}
+	m.selectItem("Create instance from template", items, func(idx int) tea.Cmd {
+		return m.showTemplateTaskInput(templates[idx])
+	})
+	return nil
+}
+
+// showTemplateTaskInput asks for the task to hand to a new instance created from the template.
+func (m *home) showTemplateTaskInput(t *template.Template) tea.Cmd {
+	m.state = statePrompt
+	m.menu.SetState(ui.StatePrompt)
+	m.textInputOverlay = overlay.NewTextInputOverlay("Task for "+t.FullName(), "")
+	m.promptHandler = func(task string) tea.Cmd {
+		program := m.program
+		if t.Program != "" {
+			program = t.Program
+		}
+		if _, err := m.launchInstance(session.InstanceOptions{
+			Title:    session.TitleFromText(t.Name + " " + task),
+			Path:     ".",
+			Program:  program,
+			Teardown: t.Teardown,
+
+			ExternalResources: t.Resources,
+		}, t.InitialPrompt(task)); err != nil {
+			return m.handleError(err)
+		}
+		return m.instanceChanged()
+	}
+	return tea.WindowSize()
+}
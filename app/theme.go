@@ -0,0 +1,43 @@
+package app
+
+import (
+	"claude-squad/log"
+	"claude-squad/ui/theme"
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// applyTheme switches to the theme picked while the app last ran, or else to the theme of the config.
+func (m *home) applyTheme() {
+	name := m.appState.GetTheme()
+	if name == "" {
+		name = m.appConfig.Theme
+	}
+	t, err := theme.Find(name)
+	if err != nil {
+		log.WarningLog.Printf("%v, using the default theme", err)
+	}
+	theme.Set(t)
+}
+
+// showThemePicker lets the user switch the color theme. The pick is kept for the next runs.
+func (m *home) showThemePicker() tea.Cmd {
+	current := theme.Current().Name
+	items := make([]string, len(theme.Themes))
+	for i, t := range theme.Themes {
+		items[i] = fmt.Sprintf("%s - %s", t.Name, t.Description)
+		if t.Name == current {
+			items[i] += " (current)"
+		}
+	}
+	m.selectItem("Color theme", items, func(idx int) tea.Cmd {
+		picked := theme.Themes[idx]
+		theme.Set(picked)
+		if err := m.appState.SetTheme(picked.Name); err != nil {
+			return m.handleError(err)
+		}
+		return tea.WindowSize()
+	})
+	return nil
+}
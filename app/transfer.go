@@ -0,0 +1,90 @@
+package app
+
+import (
+	"claude-squad/session"
+	"claude-squad/ui"
+	"claude-squad/ui/overlay"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// showImportActions offers to paste the clipboard into the instance as a prompt or as a file, or to copy a
+// local file into its worktree, e.g. logs or specs for the agent to read.
+func (m *home) showImportActions(instance *session.Instance) tea.Cmd {
+	if instance == nil {
+		return nil
+	}
+	items := []string{"Paste the clipboard as a prompt", "Paste the clipboard into a file", "Copy a file into the worktree"}
+	m.selectItem("Import into "+instance.Title, items, func(idx int) tea.Cmd {
+		if idx == 2 {
+			return m.showCopyFileInput(instance)
+		}
+		text, err := clipboard.ReadAll()
+		if err != nil {
+			return m.handleError(fmt.Errorf("failed to read the clipboard: %w", err))
+		}
+		if strings.TrimSpace(text) == "" {
+			return m.handleError(fmt.Errorf("the clipboard is empty"))
+		}
+		if idx == 0 {
+			if err := instance.SendPrompt(text); err != nil {
+				return m.handleError(err)
+			}
+			return m.instanceChanged()
+		}
+		return m.showPasteFileInput(instance, text)
+	})
+	return nil
+}
+
+// showPasteFileInput asks for the name of the file the clipboard is pasted into, in the scratch directory
+// of the worktree of the instance.
+func (m *home) showPasteFileInput(instance *session.Instance, text string) tea.Cmd {
+	m.state = statePrompt
+	m.menu.SetState(ui.StatePrompt)
+	m.textInputOverlay = overlay.NewTextInputOverlay("Name of the file to paste the clipboard into",
+		"clipboard-"+time.Now().Format("20060102-150405")+".txt")
+	m.promptHandler = func(name string) tea.Cmd {
+		if strings.TrimSpace(name) == "" {
+			return nil
+		}
+		path, err := instance.ImportFile(name, []byte(text))
+		if err != nil {
+			return m.handleError(err)
+		}
+		m.showImported(instance, path)
+		return nil
+	}
+	return tea.WindowSize()
+}
+
+// showCopyFileInput asks for the local file to copy into the scratch directory of the worktree of the
+// instance. A file dropped onto the terminal types its path.
+func (m *home) showCopyFileInput(instance *session.Instance) tea.Cmd {
+	m.state = statePrompt
+	m.menu.SetState(ui.StatePrompt)
+	m.textInputOverlay = overlay.NewTextInputOverlay("Path of the file to copy into "+instance.Title, "")
+	m.promptHandler = func(source string) tea.Cmd {
+		if strings.TrimSpace(source) == "" {
+			return nil
+		}
+		path, err := instance.CopyIntoWorktree(source)
+		if err != nil {
+			return m.handleError(err)
+		}
+		m.showImported(instance, path)
+		return nil
+	}
+	return tea.WindowSize()
+}
+
+// showImported tells where the file was written in the worktree, to refer the agent to it.
+func (m *home) showImported(instance *session.Instance, path string) {
+	m.showSummary("Copied into "+instance.Title,
+		fmt.Sprintf("The file is at %s in the worktree, left out of the diff and the commits.\n"+
+			"Refer the agent to it in a prompt, e.g. \"Read %s\".", path, path))
+}
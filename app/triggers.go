@@ -0,0 +1,116 @@
+package app
+
+import (
+	"claude-squad/config"
+	"claude-squad/github"
+	"claude-squad/session"
+	"errors"
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// triggerEvent is an event polled by a trigger.
+type triggerEvent struct {
+	trigger config.Trigger
+	event   github.Event
+}
+
+// triggerEventsMsg is sent when the triggers were polled.
+type triggerEventsMsg struct {
+	events []triggerEvent
+	err    error
+}
+
+// pollTriggers polls GitHub for the events of the triggers in the background, after the delay.
+func pollTriggers(triggers []config.Trigger, delay time.Duration) tea.Cmd {
+	if len(triggers) == 0 {
+		return nil
+	}
+	return func() tea.Msg {
+		time.Sleep(delay)
+		var msg triggerEventsMsg
+		var errs []error
+		for _, trigger := range triggers {
+			events, err := github.PollTrigger(trigger)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("could not poll trigger %s: %w", trigger.Name, err))
+				continue
+			}
+			for _, event := range events {
+				msg.events = append(msg.events, triggerEvent{trigger: trigger, event: event})
+			}
+		}
+		msg.err = errors.Join(errs...)
+		return msg
+	}
+}
+
+// handleTriggerEvents creates an instance for each new event, then schedules the next poll. The events
+// are left for the next poll during a rate limit backoff. The selection stays where it was, since this
+// happens in the background.
+func (m *home) handleTriggerEvents(msg triggerEventsMsg) tea.Cmd {
+	next := pollTriggers(m.appConfig.Triggers, m.appConfig.GetTriggerPollInterval())
+	errs := []error{msg.err}
+	selected := m.list.GetSelectedInstance()
+	created := false
+	for _, e := range msg.events {
+		if m.checkDispatch() != nil {
+			break
+		}
+		if m.appState.IsTriggered(e.event.Key) {
+			continue
+		}
+		exists := false
+		for _, instance := range m.list.GetInstances() {
+			exists = exists || instance.Title == e.event.Title
+		}
+		if exists {
+			// The title is taken, e.g. by an instance created by hand for the event. The event is only
+			// recorded once an instance was created for it, so try again on the next poll.
+			continue
+		}
+		path, program := e.trigger.Path, e.trigger.Program
+		if path == "" {
+			path = "."
+		}
+		if program == "" {
+			program = m.program
+		}
+		instance, err := m.launchInstance(session.InstanceOptions{
+			Title:   e.event.Title,
+			Path:    path,
+			Program: program,
+			Actor:   "trigger:" + e.trigger.Name,
+		}, e.event.Prompt)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("trigger %s could not create instance '%s': %w",
+				e.trigger.Name, e.event.Title, err))
+		}
+		if instance == nil {
+			// Try again on the next poll.
+			continue
+		}
+		created = true
+		if err := m.appState.SetTriggered(e.event.Key); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if selected != nil {
+		for idx, instance := range m.list.GetInstances() {
+			if instance == selected {
+				m.list.SetSelectedInstance(idx)
+			}
+		}
+	}
+
+	cmds := []tea.Cmd{next}
+	if err := errors.Join(errs...); err != nil {
+		cmds = append(cmds, m.handleError(err))
+	}
+	if created {
+		cmds = append(cmds, m.instanceChanged())
+	}
+	return tea.Batch(cmds...)
+}
@@ -0,0 +1,88 @@
+package app
+
+import (
+	"claude-squad/log"
+	"claude-squad/session"
+	"errors"
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type tickCheckUpstreamMessage struct{}
+
+// upstreamCheckedMsg is sent when the branches of the instances were compared with the upstream base
+// branch.
+type upstreamCheckedMsg struct {
+	statuses map[*session.Instance]*session.UpstreamStatus
+}
+
+// tickCheckUpstream waits for the next upstream check. It's nil if the checks are disabled.
+func tickCheckUpstream(interval time.Duration) tea.Cmd {
+	if interval <= 0 {
+		return nil
+	}
+	return func() tea.Msg {
+		time.Sleep(interval)
+		return tickCheckUpstreamMessage{}
+	}
+}
+
+// upstreamCheckInterval is how often origin is fetched to compare the instances with, or 0 if never.
+func (m *home) upstreamCheckInterval() time.Duration {
+	return time.Duration(m.appConfig.UpstreamCheckInterval) * time.Second
+}
+
+// checkUpstream fetches origin once per repository and compares the branches of the started instances
+// with the upstream base branch in the background.
+func (m *home) checkUpstream() tea.Cmd {
+	if m.upstreamCheckInterval() <= 0 {
+		return nil
+	}
+	var instances []*session.Instance
+	for _, instance := range m.list.GetInstances() {
+		if instance.Started() {
+			instances = append(instances, instance)
+		}
+	}
+	return func() tea.Msg {
+		msg := upstreamCheckedMsg{statuses: make(map[*session.Instance]*session.UpstreamStatus)}
+		fetched := make(map[string]bool)
+		for _, instance := range instances {
+			worktree, err := instance.GetGitWorktree()
+			if err != nil {
+				continue
+			}
+			repo := instance.Host + ":" + worktree.GetRepoPath()
+			status, err := instance.CheckUpstream(!fetched[repo])
+			// Don't fetch again for every instance of a repository if it failed, e.g. while offline.
+			fetched[repo] = true
+			if err != nil {
+				log.WarningLog.Printf("could not compare %s with upstream: %v", instance.Title, err)
+				continue
+			}
+			msg.statuses[instance] = status
+		}
+		return msg
+	}
+}
+
+// upstreamChecked records how far the instances are from upstream, reports the ones that drifted since
+// the last check, then waits for the next check.
+func (m *home) upstreamChecked(msg upstreamCheckedMsg) tea.Cmd {
+	threshold := m.appConfig.GetDriftThreshold()
+	var errs []error
+	for instance, status := range msg.statuses {
+		if status.Drifted(threshold) && !instance.Upstream().Drifted(threshold) {
+			errs = append(errs, fmt.Errorf("instance '%s' is %d commits behind %s and needs a rebase",
+				instance.Title, status.Behind, status.Branch))
+		}
+		instance.SetUpstream(status)
+	}
+	cmds := []tea.Cmd{tickCheckUpstream(m.upstreamCheckInterval()), m.instanceChanged()}
+	if len(errs) > 0 {
+		cmds = append(cmds, m.handleError(errors.Join(errs...)))
+	}
+	return tea.Batch(cmds...)
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// InstanceBlobsDirName is the directory in the config directory holding the bulky data of the stored
+// instances, like their diffs, which is kept out of the state file so saving the state stays cheap.
+const InstanceBlobsDirName = "instances"
+
+// instanceBlobPath returns the path of the named blob of the instance stored under key.
+func instanceBlobPath(key, name string) (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get config directory: %w", err)
+	}
+	return filepath.Join(configDir, InstanceBlobsDirName, key, name), nil
+}
+
+// SaveInstanceBlob saves the named blob of the instance stored under key, encrypted like the state.
+func (s *State) SaveInstanceBlob(key, name string, data []byte) error {
+	path, err := instanceBlobPath(key, name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create instance directory: %w", err)
+	}
+	return writeStateFile(path, data, s.encrypt)
+}
+
+// GetInstanceBlob returns the named blob of the instance stored under key, or nil if there is none. Like
+// the state, blobs saved before encryption was enabled or disabled are read either way.
+func (s *State) GetInstanceBlob(key, name string) ([]byte, error) {
+	path, err := instanceBlobPath(key, name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %s of %s: %w", name, key, err)
+	}
+	if data, err = decryptState(data); err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s of %s: %w", name, key, err)
+	}
+	return data, nil
+}
+
+// DeleteInstanceBlobs removes the blobs of the instance stored under key.
+func (s *State) DeleteInstanceBlobs(key string) error {
+	path, err := instanceBlobPath(key, "")
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(path); err != nil {
+		return fmt.Errorf("failed to remove the data of %s: %w", key, err)
+	}
+	return nil
+}
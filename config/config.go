@@ -2,28 +2,66 @@ package config
 
 import (
 	"claude-squad/log"
+	"claude-squad/schema"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"os/user"
+	"path"
 	"path/filepath"
+	"reflect"
 	"regexp"
+	"slices"
 	"strings"
+	"time"
 )
 
 const (
-	ConfigFileName = "config.json"
-	defaultProgram = "claude"
+	ConfigFileName     = "config.json"
+	defaultProgram     = "claude"
+	defaultFanOutCount = 3
+	// defaultTriggerPollInterval is how often, in seconds, the triggers poll GitHub by default.
+	defaultTriggerPollInterval = 60
+	// defaultDriftThreshold is the number of commits behind the upstream base branch that flags an
+	// instance for a rebase by default.
+	defaultDriftThreshold = 20
+	// defaultSnapshotExpireDays is how many days snapshots are kept by default.
+	defaultSnapshotExpireDays = 7
+	// defaultCommitInterval is how often, in minutes, the interval commit mode commits by default.
+	defaultCommitInterval = 10
+	// defaultCommitQuietSeconds is how long an agent must be idle, or its files unchanged, before the idle
+	// and save commit modes commit by default.
+	defaultCommitQuietSeconds = 10
+	// defaultTmuxPrefix is the prefix of the names of the tmux sessions of instances by default.
+	defaultTmuxPrefix = "claudesquad_"
+	// defaultStateBackups is the number of backups of the state file kept by default.
+	defaultStateBackups = 5
+	// defaultPaneLogMaxSizeMB and defaultPaneLogMaxAgeHours are the size and age from which the output log
+	// of an instance is rotated by default, and defaultPaneLogKeep the number of rotated logs kept.
+	defaultPaneLogMaxSizeMB   = 10
+	defaultPaneLogMaxAgeHours = 24
+	defaultPaneLogKeep        = 5
+	// defaultWrapUpMinutes is how long before its time budget runs out an instance is asked to wrap up by
+	// default.
+	defaultWrapUpMinutes = 10
+	// defaultWrapUpPrompt is the prompt sent to an instance whose time budget is nearly exhausted by default.
+	defaultWrapUpPrompt = "Your time is nearly up. Finish what you're doing, summarize what you did and what's left, and commit your work."
+	// defaultSplitPrompt is the prompt sent to an instance whose diff is flagged as too large by default.
+	defaultSplitPrompt = "Your changes have grown too large to review in one go. Stop adding to them, and propose how to split the work into smaller, independently reviewable changes."
+	// defaultReviewPrompt is the prompt sent to an instance created from a remote branch by default.
+	defaultReviewPrompt = "Review the changes of this branch against its base branch. Fix the bugs and problems you find, then summarize what you changed and what still needs attention."
 )
 
-// GetConfigDir returns the path to the application's configuration directory
+// GetConfigDir returns the path to the application's configuration directory, the one of the current
+// profile, see SetProfile.
 func GetConfigDir() (string, error) {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return "", fmt.Errorf("failed to get config home directory: %w", err)
+	root, err := rootConfigDir()
+	if err != nil || profile == "" {
+		return root, err
 	}
-	return filepath.Join(homeDir, ".claude-squad"), nil
+	return filepath.Join(root, ProfilesDirName, profile), nil
 }
 
 // Config represents the application configuration
@@ -36,8 +74,807 @@ type Config struct {
 	DaemonPollInterval int `json:"daemon_poll_interval"`
 	// BranchPrefix is the prefix used for git branches created by the application.
 	BranchPrefix string `json:"branch_prefix"`
-	// CopyOnCreate is a list of files/patterns to copy when creating new spaces
+	// BranchTemplate is the template of the names of the branches created for instances, e.g.
+	// "{{user}}/{{date}}/{{slug title}}". If empty, branches are named BranchPrefix followed by the slug
+	// of the title.
+	BranchTemplate string `json:"branch_template,omitempty"`
+	// TmuxPrefix is the prefix of the names of the tmux sessions of new instances, e.g. to tell the
+	// sessions of several setups apart. If empty, it's "claudesquad_".
+	TmuxPrefix string `json:"tmux_prefix,omitempty"`
+	// CopyOnCreate is a list of files/patterns to copy when creating new spaces. Directories are copied
+	// recursively, patterns like "config/*.local.json" or "**/.env" match the files of the repository,
+	// and entries starting with "!" exclude what they match. Only the files git doesn't track are copied
+	// from directories and patterns, since the worktree has the others.
 	CopyOnCreate []string `json:"copy_on_create"`
+	// CopyEnvFiles copies the dotenv-style files in the root of the repository that git ignores, like
+	// .env.local, on top of CopyOnCreate.
+	CopyEnvFiles bool `json:"copy_env_files,omitempty"`
+	// LinkOnCreate are directories, like node_modules, symlinked from the repository into new worktrees
+	// instead of copied, so they're shared.
+	LinkOnCreate []string `json:"link_on_create,omitempty"`
+	// InitSubmodules checks out the submodules of new worktrees, recursively, if the repository has any.
+	InitSubmodules bool `json:"init_submodules,omitempty"`
+	// PullLFS pulls the Git LFS objects of new worktrees, if the repository stores files with it.
+	PullLFS bool `json:"pull_lfs,omitempty"`
+	// VCS is what the working copies of new instances are made with: "git" for git worktrees, "jj" for
+	// the workspaces of Jujutsu in repositories colocated with jj, or "auto", the default, for jj
+	// workspaces in colocated repositories if jj is installed and git worktrees otherwise.
+	VCS string `json:"vcs,omitempty"`
+	// FanOutCount is the number of instances created by a fan-out.
+	FanOutCount int `json:"fan_out_count"`
+	// FanOutPrograms are the programs the instances of a fan-out run in turn. If empty, all of them run
+	// the default program.
+	FanOutPrograms []string `json:"fan_out_programs,omitempty"`
+	// EncryptState encrypts the state file, which holds the instance data, at rest.
+	EncryptState bool `json:"encrypt_state"`
+	// StateBackups is the number of backups of the state file kept, taken at most every 10 minutes as it's
+	// saved, to restore with `cs state restore`.
+	StateBackups int `json:"state_backups,omitempty"`
+	// SecretEnvFiles are dotenv files, relative to the repository root, whose variables are injected into
+	// the tmux sessions as environment variables instead of being copied into the worktrees. Their
+	// values are redacted from the previews.
+	SecretEnvFiles []string `json:"secret_env_files,omitempty"`
+	// ResourceLimits are the limits on the process tree of each instance.
+	ResourceLimits ResourceLimits `json:"resource_limits"`
+	// TeardownCommands are run in the worktree of each instance before it's removed, on kill and pause,
+	// e.g. to stop dev servers the agent started in the background.
+	TeardownCommands []string `json:"teardown_commands,omitempty"`
+	// Services are containers, such as databases, started for each instance so agents don't share them.
+	Services Services `json:"services"`
+	// PaneLog keeps the output of the instances on disk, past the history limit of tmux.
+	PaneLog PaneLog `json:"pane_log"`
+	// Layout is how the instance list, the preview and the diff are arranged.
+	Layout Layout `json:"layout"`
+	// Theme is the name of the color theme of the TUI: "default", "light" or "colorblind". A theme picked
+	// while the app runs takes precedence.
+	Theme string `json:"theme,omitempty"`
+	// TimeBudget is the time budget of new instances, and how instances whose budget is nearly exhausted
+	// are wrapped up.
+	TimeBudget TimeBudget `json:"time_budget"`
+	// DiffBudget is the soft limits on the size of the diff of each instance, which keep their changes
+	// reviewable.
+	DiffBudget DiffBudget `json:"diff_budget"`
+	// AutoYesPolicy tells which permission prompts auto-yes answers. The others are held for approval.
+	AutoYesPolicy AutoYesPolicy `json:"auto_yes_policy"`
+	// Telemetry is the anonymous usage counts collected to help prioritize development. It's off unless
+	// opted into.
+	Telemetry Telemetry `json:"telemetry"`
+	// Tracing exports OpenTelemetry spans of the operations of the instances. It's off unless an exporter
+	// is set.
+	Tracing Tracing `json:"tracing"`
+	// Providers are the agent programs fan-outs distribute their instances to, with per-provider caps.
+	// If empty, FanOutPrograms are used.
+	Providers []Provider `json:"providers,omitempty"`
+	// ProviderPolicy is how instances are distributed to the providers: "round_robin", "cost" or "task".
+	ProviderPolicy string `json:"provider_policy,omitempty"`
+	// ExternalResources are environments outside of the worktrees, e.g. a staging database or an
+	// emulator, that the instances requiring them take turns using.
+	ExternalResources []ExternalResource `json:"external_resources,omitempty"`
+	// MaxRunningInstances caps the number of instances running at once. The instances created or resumed
+	// beyond it are queued until a running one is paused or killed. If 0, there's no cap.
+	MaxRunningInstances int `json:"max_running_instances,omitempty"`
+	// ExternalTerminal is the command line that opens a terminal window running {{command}}, used to open
+	// instances outside of the app, e.g. "alacritty -e {{command}}". If empty, a terminal is detected.
+	ExternalTerminal string `json:"external_terminal,omitempty"`
+	// AutoYesOnAuth keeps auto-yes pressing enter in an instance while claude waits for the user to log
+	// in again, which picks the highlighted login method. By default, auto-yes pauses until the user
+	// logged in.
+	AutoYesOnAuth bool `json:"auto_yes_on_auth,omitempty"`
+	// MetricsAddress is the address the daemon serves Prometheus metrics on at /metrics, e.g.
+	// "127.0.0.1:9464". If empty, metrics aren't served.
+	MetricsAddress string `json:"metrics_address,omitempty"`
+	// ArtifactStorage is the object storage archives are offloaded to.
+	ArtifactStorage ArtifactStorage `json:"artifact_storage"`
+	// Keys remaps the keys of the actions of the app, by action name, e.g. {"kill": ["X"]}. The other
+	// actions keep their default keys.
+	Keys map[string][]string `json:"keys,omitempty"`
+	// APITokens grant programs, e.g. an orchestrator agent, access to the API of the daemon. If empty,
+	// only the metrics and the list of instances can be read, by anyone who can reach MetricsAddress.
+	APITokens []APIToken `json:"api_tokens,omitempty"`
+	// Triggers create instances automatically from GitHub events while the app is open.
+	Triggers []Trigger `json:"triggers,omitempty"`
+	// TriggerPollInterval is how often, in seconds, the triggers poll GitHub.
+	TriggerPollInterval int `json:"trigger_poll_interval,omitempty"`
+	// UpstreamCheckInterval is how often, in seconds, origin is fetched to compare the branches of the
+	// instances with the upstream base branch. If 0, they aren't compared.
+	UpstreamCheckInterval int `json:"upstream_check_interval,omitempty"`
+	// DriftThreshold is the number of commits behind the upstream base branch that flags an instance for
+	// a rebase.
+	DriftThreshold int `json:"drift_threshold,omitempty"`
+	// ReviewPrompt is the prompt sent to an instance created from a remote branch, e.g. a colleague's
+	// pull request, unless it's given another one.
+	ReviewPrompt string `json:"review_prompt,omitempty"`
+	// AutoRebaseInterval is how often, in seconds, the auto-yes daemon fetches origin and rebases the
+	// branches of the paused instances on the upstream base branch. If 0, they aren't rebased.
+	AutoRebaseInterval int `json:"auto_rebase_interval,omitempty"`
+	// Slack is the Slack app the squad is controlled from while the app is open.
+	Slack Slack `json:"slack"`
+	// Webhooks are the Slack and Discord incoming webhooks the events of the instances are posted to, by
+	// the app and by the auto-yes daemon.
+	Webhooks []Webhook `json:"webhooks,omitempty"`
+	// NotifyBackends are the named backends the notify rules route the events of the instances to, e.g.
+	// Pushover for a phone.
+	NotifyBackends []NotifyBackend `json:"notify_backends,omitempty"`
+	// NotifyRules route the events of the instances to backends by their titles and groups. The first rule
+	// matching an event routes it.
+	NotifyRules []NotifyRule `json:"notify_rules,omitempty"`
+	// HandoffTemplates frame what one instance hands off to another as a prompt. They're added to the
+	// default ones, replacing those with the same name.
+	HandoffTemplates []HandoffTemplate `json:"handoff_templates,omitempty"`
+	// SnapshotExpireDays is how many days the shared snapshots of the diffs of instances are kept before
+	// they're deleted.
+	SnapshotExpireDays int `json:"snapshot_expire_days,omitempty"`
+	// CommitStrategy is when the changes of the worktrees of instances are committed, and how.
+	CommitStrategy CommitStrategy `json:"commit_strategy"`
+	// StuckPatterns are regular expressions of prompts auto-yes doesn't answer, e.g. "Press y to continue".
+	// An instance showing one at the bottom of its pane needs attention. If empty, the defaults are used.
+	StuckPatterns []string `json:"stuck_patterns,omitempty"`
+	// NotifyStuck shows a message, and posts to Slack if it's configured, when an instance needs
+	// attention.
+	NotifyStuck bool `json:"notify_stuck"`
+	// ReadyRules tell when the program of an instance is done and ready for input, per program. They're
+	// tried before the defaults, the first one matching the program of an instance applying to it.
+	ReadyRules []ReadyRule `json:"ready_rules,omitempty"`
+	// RedactPatterns are regular expressions of text, e.g. internal hostnames or customer IDs, redacted
+	// from exported summaries and reports on top of common secret formats.
+	RedactPatterns []string `json:"redact_patterns,omitempty"`
+	// ClaudeHooks makes claude report what it's doing through its hooks, which the status of its
+	// instances is derived from instead of their panes. It needs a claude that supports --settings.
+	ClaudeHooks bool `json:"claude_hooks"`
+	// SkipKillChecks kills instances without checking for commits of their branch that aren't merged or
+	// pushed and for uncommitted changes, which otherwise have to be confirmed by typing their title.
+	SkipKillChecks bool `json:"skip_kill_checks,omitempty"`
+	// KillBackupRefs keeps the unsaved work of the instances killed in a ref under
+	// refs/claude-squad/backups of their repository before their branch and worktree are removed.
+	KillBackupRefs bool `json:"kill_backup_refs,omitempty"`
+	// Adopt is which existing worktrees and tmux sessions `cs adopt` and the adopt picker import as
+	// instances.
+	Adopt Adopt `json:"adopt"`
+}
+
+// defaultStuckPatterns are prompts of tools agents commonly run that wait for the user.
+var defaultStuckPatterns = []string{
+	`Do you want to`,
+	`(?i)press y to continue`,
+	`\[y/N\]`,
+	`\(y/n\)`,
+}
+
+// defaultRedactPatterns are the formats of common secrets, always redacted from exported artifacts.
+var defaultRedactPatterns = []string{
+	// Private keys
+	`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`,
+	// AWS access key IDs
+	`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`,
+	// GitHub tokens
+	`\bgh[pousr]_[A-Za-z0-9]{36,}\b`,
+	`\bgithub_pat_[A-Za-z0-9_]{22,}\b`,
+	// Anthropic and OpenAI API keys
+	`\bsk-[A-Za-z0-9_-]{20,}`,
+	// Slack tokens
+	`\bxox[abposr]-[A-Za-z0-9-]{10,}`,
+	// Bearer tokens in headers
+	`(?i)\bbearer\s+[A-Za-z0-9._~+/=-]{20,}`,
+}
+
+const (
+	// ResourceActionWarn shows a warning when an instance exceeds a resource limit.
+	ResourceActionWarn = "warn"
+	// ResourceActionPause pauses an instance that exceeds a resource limit.
+	ResourceActionPause = "pause"
+)
+
+// ResourceLimits are the limits on the process tree of an instance. A zero value disables a limit.
+type ResourceLimits struct {
+	// CPUPercent is the CPU usage, in percent of one core, above which an instance is considered busy.
+	CPUPercent float64 `json:"cpu_percent"`
+	// CPUMinutes is how long an instance may stay above CPUPercent.
+	CPUMinutes int `json:"cpu_minutes"`
+	// MemoryMB is the maximum resident memory of the process tree.
+	MemoryMB int `json:"memory_mb"`
+	// MaxProcesses is the maximum number of processes in the tree, to catch runaway builds.
+	MaxProcesses int `json:"max_processes"`
+	// Action is what happens when a limit is exceeded: "warn" or "pause".
+	Action string `json:"action"`
+}
+
+// The modes of the telemetry.
+const (
+	// TelemetryOff collects nothing.
+	TelemetryOff = "off"
+	// TelemetryPreview counts the usage locally, to see what would be sent, but never sends it.
+	TelemetryPreview = "preview"
+	// TelemetryOn counts the usage and sends it to the endpoint once a day.
+	TelemetryOn = "on"
+)
+
+// Telemetry is the anonymous usage telemetry: how often features are used and errors of each category
+// happen, never code, prompts, titles or paths.
+type Telemetry struct {
+	// Mode is TelemetryOff, TelemetryPreview or TelemetryOn. It's off if empty or unknown.
+	Mode string `json:"mode,omitempty"`
+	// Endpoint is the URL the counts are posted to as JSON in the TelemetryOn mode.
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+// Collecting returns true if the usage is counted, in the preview or on modes.
+func (t Telemetry) Collecting() bool {
+	return t.Mode == TelemetryPreview || t.Mode == TelemetryOn
+}
+
+// Sending returns true if the counts are sent.
+func (t Telemetry) Sending() bool {
+	return t.Mode == TelemetryOn && t.Endpoint != ""
+}
+
+const (
+	// TracingOTLP sends the spans to an OTLP/HTTP endpoint, e.g. an OpenTelemetry Collector.
+	TracingOTLP = "otlp"
+	// TracingFile appends the spans to a file, one OTLP/JSON request per line.
+	TracingFile = "file"
+)
+
+// Tracing is the export of OpenTelemetry spans of the operations of the instances, like setting up their
+// worktree, starting their terminal, sending them prompts and computing their diff.
+type Tracing struct {
+	// Exporter is TracingOTLP or TracingFile. Nothing is traced if it's empty.
+	Exporter string `json:"exporter,omitempty"`
+	// Endpoint is the URL of the OTLP/HTTP traces endpoint. It defaults to $OTEL_EXPORTER_OTLP_TRACES_ENDPOINT,
+	// $OTEL_EXPORTER_OTLP_ENDPOINT/v1/traces, then http://localhost:4318/v1/traces.
+	Endpoint string `json:"endpoint,omitempty"`
+	// Headers are sent with the spans, e.g. the API key of a tracing backend.
+	Headers map[string]string `json:"headers,omitempty"`
+	// File is the file the TracingFile exporter appends to, traces.jsonl in the config directory by default.
+	File string `json:"file,omitempty"`
+	// ServiceName is the service.name of the spans, $OTEL_SERVICE_NAME or "claude-squad" by default.
+	ServiceName string `json:"service_name,omitempty"`
+}
+
+const (
+	// LayoutTabs shows the preview, the diff and the notes in tabs next to the instance list.
+	LayoutTabs = "tabs"
+	// LayoutSplit shows the instance list, the preview and the diff side by side.
+	LayoutSplit = "split"
+)
+
+// defaultSplitRatios are the relative widths of the list, the preview and the diff in the split layout.
+var defaultSplitRatios = [3]int{2, 5, 3}
+
+// Layout is the arrangement of the instance list, the preview and the diff.
+type Layout struct {
+	// Mode is LayoutTabs or LayoutSplit. It's LayoutTabs if empty.
+	Mode string `json:"mode,omitempty"`
+	// Ratios are the relative widths of the list, the preview and the diff in the split layout, e.g.
+	// [2, 5, 3].
+	Ratios []int `json:"ratios,omitempty"`
+}
+
+// SplitRatios returns the relative widths of the list, the preview and the diff in the split layout, the
+// defaults unless Ratios are three positive numbers.
+func (l Layout) SplitRatios() [3]int {
+	if len(l.Ratios) != 3 {
+		return defaultSplitRatios
+	}
+	var ratios [3]int
+	for i, ratio := range l.Ratios {
+		if ratio <= 0 {
+			return defaultSplitRatios
+		}
+		ratios[i] = ratio
+	}
+	return ratios
+}
+
+// TimeBudget bounds how long instances run. An instance with a budget is sent a wrap-up prompt when it's
+// nearly exhausted, and its changes are committed and it's paused when it runs out.
+type TimeBudget struct {
+	// DefaultMinutes is the time budget of new instances. Zero is no budget.
+	DefaultMinutes int `json:"default_minutes,omitempty"`
+	// WrapUpMinutes is how long before the budget runs out the wrap-up prompt is sent.
+	WrapUpMinutes int `json:"wrap_up_minutes,omitempty"`
+	// WrapUpPrompt is the prompt asking the agent to wrap up.
+	WrapUpPrompt string `json:"wrap_up_prompt,omitempty"`
+}
+
+// GetDefault returns the time budget of new instances, zero if they have none.
+func (b TimeBudget) GetDefault() time.Duration {
+	if b.DefaultMinutes <= 0 {
+		return 0
+	}
+	return time.Duration(b.DefaultMinutes) * time.Minute
+}
+
+// GetWrapUp returns how long before the budget runs out the wrap-up prompt is sent, falling back to the
+// default if it's not set.
+func (b TimeBudget) GetWrapUp() time.Duration {
+	if b.WrapUpMinutes <= 0 {
+		return defaultWrapUpMinutes * time.Minute
+	}
+	return time.Duration(b.WrapUpMinutes) * time.Minute
+}
+
+// GetWrapUpPrompt returns the prompt asking the agent to wrap up, falling back to the default if it's not
+// set.
+func (b TimeBudget) GetWrapUpPrompt() string {
+	if strings.TrimSpace(b.WrapUpPrompt) == "" {
+		return defaultWrapUpPrompt
+	}
+	return b.WrapUpPrompt
+}
+
+// DiffBudget is the soft limits on the number of changed lines, added and removed, of each instance. An
+// instance past a limit is marked in the list, and one past the flag limit can be asked to split its work.
+type DiffBudget struct {
+	// WarnLines is the number of changed lines past which an instance is warned about. Zero is no limit.
+	WarnLines int `json:"warn_lines,omitempty"`
+	// FlagLines is the number of changed lines past which an instance is flagged. Zero is no limit.
+	FlagLines int `json:"flag_lines,omitempty"`
+	// SplitOnFlag sends the split prompt to an instance once, when it's flagged.
+	SplitOnFlag bool `json:"split_on_flag,omitempty"`
+	// SplitPrompt is the prompt asking the agent to split its work.
+	SplitPrompt string `json:"split_prompt,omitempty"`
+}
+
+// GetSplitPrompt returns the prompt asking the agent to split its work, falling back to the default if
+// it's not set.
+func (b DiffBudget) GetSplitPrompt() string {
+	if strings.TrimSpace(b.SplitPrompt) == "" {
+		return defaultSplitPrompt
+	}
+	return b.SplitPrompt
+}
+
+// Adopt is the glob patterns of the worktrees and tmux sessions of a hand-rolled setup that are adopted as
+// instances. Empty patterns match everything.
+type Adopt struct {
+	// Worktrees are patterns of the paths of the worktrees, or of their directory names, e.g. "feature-*".
+	Worktrees []string `json:"worktrees,omitempty"`
+	// Sessions are patterns of the names of the tmux sessions, e.g. "work-*". A session running in an
+	// adopted worktree becomes the terminal session of its instance.
+	Sessions []string `json:"sessions,omitempty"`
+}
+
+// AutoYesPolicy tells which permission prompts auto-yes answers, by their kind: "edit" for editing or
+// creating files, "command" for running shell commands, "network" for fetching URLs and commands that
+// access the network, and "other" for the rest, e.g. MCP tools.
+type AutoYesPolicy struct {
+	// Approve are the kinds of prompts auto-yes answers. If empty, it answers all of them.
+	Approve []string `json:"approve,omitempty"`
+	// Hold are regular expressions of prompts auto-yes never answers, whatever their kind, e.g. "rm -rf"
+	// or "git push --force".
+	Hold []string `json:"hold,omitempty"`
+}
+
+// PaneLog is the log of the output of each instance, appended to as it scrolls off the screen and rotated
+// into compressed files.
+type PaneLog struct {
+	// Enabled turns the logs on.
+	Enabled bool `json:"enabled"`
+	// MaxSizeMB is the size from which a log is rotated.
+	MaxSizeMB int `json:"max_size_mb,omitempty"`
+	// MaxAgeHours is the age from which a log is rotated.
+	MaxAgeHours int `json:"max_age_hours,omitempty"`
+	// Keep is the number of rotated logs kept per instance, the oldest being removed.
+	Keep int `json:"keep,omitempty"`
+}
+
+// GetMaxSize returns the size in bytes from which a log is rotated, falling back to the default if it's
+// not set.
+func (p PaneLog) GetMaxSize() int64 {
+	if p.MaxSizeMB <= 0 {
+		return defaultPaneLogMaxSizeMB << 20
+	}
+	return int64(p.MaxSizeMB) << 20
+}
+
+// GetMaxAge returns the age from which a log is rotated, falling back to the default if it's not set.
+func (p PaneLog) GetMaxAge() time.Duration {
+	if p.MaxAgeHours <= 0 {
+		return defaultPaneLogMaxAgeHours * time.Hour
+	}
+	return time.Duration(p.MaxAgeHours) * time.Hour
+}
+
+// GetKeep returns the number of rotated logs kept per instance, falling back to the default if it's not
+// set.
+func (p PaneLog) GetKeep() int {
+	if p.Keep <= 0 {
+		return defaultPaneLogKeep
+	}
+	return p.Keep
+}
+
+// Services are the docker compose services of each instance. Each instance runs them in its own compose
+// project, on its own host ports.
+type Services struct {
+	// ComposeFile is the docker compose file with the services, relative to the repository root. Empty
+	// disables the services.
+	ComposeFile string `json:"compose_file"`
+	// PortVariables are the environment variables the compose file reads its host ports from, e.g.
+	// "POSTGRES_PORT". Each instance gets free ports, which are passed to its program too.
+	PortVariables []string `json:"port_variables"`
+}
+
+// ArtifactStorage is an S3 or Google Cloud Storage bucket the final diffs and conversations of archived
+// instances are offloaded to, so they don't take space locally.
+type ArtifactStorage struct {
+	// URL is the bucket and the prefix of the objects, "s3://bucket/prefix" or "gs://bucket/prefix".
+	// Empty keeps the archives local.
+	URL string `json:"url"`
+	// StorageClass is the storage class of the uploaded objects, e.g. "STANDARD_IA" or "NEARLINE".
+	StorageClass string `json:"storage_class,omitempty"`
+	// ExpireDays is how many days offloaded archives are kept. 0 keeps them forever.
+	ExpireDays int `json:"expire_days,omitempty"`
+}
+
+// Slack is a Slack app connected over socket mode, which takes /squad commands and posts the events of
+// each instance in a thread of a channel.
+type Slack struct {
+	// Channel is the ID of the channel the events are posted to, e.g. "C0123456789". Empty disables the
+	// bridge.
+	Channel string `json:"channel"`
+	// AppToken is the app-level token ("xapp-...") of the app. Defaults to $SLACK_APP_TOKEN.
+	AppToken string `json:"app_token,omitempty"`
+	// BotToken is the bot token ("xoxb-...") of the app. Defaults to $SLACK_BOT_TOKEN.
+	BotToken string `json:"bot_token,omitempty"`
+	// AllowedUsers are the IDs of the Slack users allowed to run commands. Empty allows nobody, since
+	// commands run agents on this machine.
+	AllowedUsers []string `json:"allowed_users,omitempty"`
+}
+
+// GetTokens returns the app-level and bot tokens of the Slack app, falling back to the environment.
+func (s Slack) GetTokens() (appToken string, botToken string) {
+	appToken, botToken = s.AppToken, s.BotToken
+	if appToken == "" {
+		appToken = os.Getenv("SLACK_APP_TOKEN")
+	}
+	if botToken == "" {
+		botToken = os.Getenv("SLACK_BOT_TOKEN")
+	}
+	return appToken, botToken
+}
+
+const (
+	// TriggerEventIssue creates an instance for each open issue with the label of the trigger.
+	TriggerEventIssue = "issue"
+	// TriggerEventWorkflowFailure creates an instance when the latest run of the workflow of the trigger
+	// failed.
+	TriggerEventWorkflowFailure = "workflow_failure"
+)
+
+// Trigger is a rule that creates instances from GitHub events, which are polled with the GitHub CLI. Each
+// event creates one instance.
+type Trigger struct {
+	// Name identifies the trigger in messages, e.g. "agent-issues".
+	Name string `json:"name"`
+	// Event is the kind of event that creates instances: "issue" or "workflow_failure".
+	Event string `json:"event"`
+	// Path is the repository the instances are created in. Defaults to the current directory.
+	Path string `json:"path,omitempty"`
+	// Label is the label of the issues that create instances, e.g. "agent".
+	Label string `json:"label,omitempty"`
+	// Workflow is the workflow whose failed runs create instances, e.g. "nightly.yml".
+	Workflow string `json:"workflow,omitempty"`
+	// Branch restricts the runs of Workflow to a branch, e.g. "main". Empty means any branch.
+	Branch string `json:"branch,omitempty"`
+	// Program overrides the default program, if set.
+	Program string `json:"program,omitempty"`
+	// Prompt is added to the prompt describing the event, e.g. "Open a pull request when you're done.".
+	Prompt string `json:"prompt,omitempty"`
+}
+
+const (
+	// NotifyEventFinished is posted when the agent of an instance is done and has been idle for a while.
+	NotifyEventFinished = "finished"
+	// NotifyEventCrashed is posted when the session of an instance is gone while it should be running.
+	NotifyEventCrashed = "crashed"
+	// NotifyEventNeedsInput is posted when an instance waits for a login or on a prompt.
+	NotifyEventNeedsInput = "needs_input"
+	// NotifyEventBlocked is posted when auto-yes doesn't answer a prompt of an instance because it matches
+	// one of the stuck patterns.
+	NotifyEventBlocked = "blocked"
+)
+
+// Webhook is an incoming webhook of Slack or Discord the events of the instances are posted to.
+type Webhook struct {
+	// URL of the webhook, e.g. "https://hooks.slack.com/services/..." or
+	// "https://discord.com/api/webhooks/...".
+	URL string `json:"url"`
+	// Kind is "slack" or "discord". Empty guesses it from the URL.
+	Kind string `json:"kind,omitempty"`
+	// Events are the events posted: "finished", "crashed", "needs_input" or "blocked". Empty means all.
+	Events []string `json:"events,omitempty"`
+}
+
+// Posts returns true if the webhook posts the event.
+func (w Webhook) Posts(event string) bool {
+	return len(w.Events) == 0 || slices.Contains(w.Events, event)
+}
+
+const (
+	// CommitOnPause commits the changes of an instance only when it's paused.
+	CommitOnPause = "pause"
+	// CommitOnInterval commits the changes of a running instance every few minutes too.
+	CommitOnInterval = "interval"
+	// CommitOnIdle commits the changes of a running instance when its agent becomes idle too.
+	CommitOnIdle = "idle"
+	// CommitOnSave commits the changes of a running instance once a burst of file changes settled too.
+	CommitOnSave = "save"
+)
+
+// CommitStrategy is when and how claude-squad commits the changes of the worktrees of instances.
+type CommitStrategy struct {
+	// Mode is when the changes are committed: "pause", "interval", "idle" or "save". Empty is "pause".
+	// They're committed when an instance is paused in every mode.
+	Mode string `json:"mode,omitempty"`
+	// IntervalMinutes is how often the interval mode commits.
+	IntervalMinutes int `json:"interval_minutes,omitempty"`
+	// QuietSeconds is how long the agent must be idle in the idle mode, or the files unchanged in the
+	// save mode, before the changes are committed.
+	QuietSeconds int `json:"quiet_seconds,omitempty"`
+	// MessageTemplate is a Go template of the commit messages, e.g. "wip({{.Title}}): {{.Reason}}". It can
+	// use {{.Title}}, {{.Branch}}, {{.Program}}, {{.Reason}}, what the commit is for, {{.Time}} and
+	// {{.Files}}, the number of changed files. Empty keeps the default messages.
+	MessageTemplate string `json:"message_template,omitempty"`
+	// Sign signs the commits, with SigningKey or the key configured in git.
+	Sign       bool   `json:"sign,omitempty"`
+	SigningKey string `json:"signing_key,omitempty"`
+	// AuthorName and AuthorEmail are the identity of the commits, instead of the one configured in git, so
+	// the commits of agents can be told apart.
+	AuthorName  string `json:"author_name,omitempty"`
+	AuthorEmail string `json:"author_email,omitempty"`
+}
+
+// GetMode returns the commit mode, falling back to committing on pause if it's not set or unknown.
+func (s CommitStrategy) GetMode() string {
+	switch s.Mode {
+	case CommitOnInterval, CommitOnIdle, CommitOnSave:
+		return s.Mode
+	}
+	return CommitOnPause
+}
+
+// GetInterval returns how often the interval mode commits, falling back to the default if it's not set.
+func (s CommitStrategy) GetInterval() time.Duration {
+	if s.IntervalMinutes <= 0 {
+		return defaultCommitInterval * time.Minute
+	}
+	return time.Duration(s.IntervalMinutes) * time.Minute
+}
+
+// GetQuiet returns how long the idle and save modes wait before committing, falling back to the default
+// if it's not set.
+func (s CommitStrategy) GetQuiet() time.Duration {
+	if s.QuietSeconds <= 0 {
+		return defaultCommitQuietSeconds * time.Second
+	}
+	return time.Duration(s.QuietSeconds) * time.Second
+}
+
+// GitArgs returns the arguments of git commit, before the subcommand and after it, that apply the
+// identity and the signing of the strategy.
+func (s CommitStrategy) GitArgs() (global []string, commit []string) {
+	if s.AuthorName != "" {
+		global = append(global, "-c", "user.name="+s.AuthorName)
+	}
+	if s.AuthorEmail != "" {
+		global = append(global, "-c", "user.email="+s.AuthorEmail)
+	}
+	if s.AuthorName != "" && s.AuthorEmail != "" {
+		// The author given explicitly wins over the one in the environment, e.g. GIT_AUTHOR_NAME.
+		commit = append(commit, fmt.Sprintf("--author=%s <%s>", s.AuthorName, s.AuthorEmail))
+	}
+	if s.Sign {
+		commit = append(commit, "-S"+s.SigningKey)
+	}
+	return global, commit
+}
+
+const (
+	// HandoffContentDiff hands off the diff of the instance.
+	HandoffContentDiff = "diff"
+	// HandoffContentMessage hands off the last message of the agent of the instance.
+	HandoffContentMessage = "message"
+	// HandoffContentSummary hands off the last message of the agent and the files it changed.
+	HandoffContentSummary = "summary"
+)
+
+// HandoffTemplate frames the content one instance hands off to another as a prompt.
+type HandoffTemplate struct {
+	// Name identifies the template, e.g. "review".
+	Name string `json:"name"`
+	// Content is what's handed off: "diff", "message" or "summary".
+	Content string `json:"content"`
+	// Prompt is a Go template of the prompt, e.g. "Review this change by {{.From}}:\n\n{{.Content}}". It
+	// can use {{.From}} and {{.To}}, the titles of the instances, {{.Branch}}, the branch of the instance
+	// handing off, and {{.Content}}.
+	Prompt string `json:"prompt"`
+}
+
+// defaultHandoffTemplates are the handoff templates available without configuring any.
+var defaultHandoffTemplates = []HandoffTemplate{
+	{
+		Name:    "review",
+		Content: HandoffContentDiff,
+		Prompt: "Review this change made by another agent on branch {{.Branch}}. Point out bugs, missing " +
+			"tests and anything that doesn't fit this codebase, but don't change any files.\n\n```diff\n{{.Content}}\n```",
+	},
+	{
+		Name:    "relay",
+		Content: HandoffContentMessage,
+		Prompt:  "Another agent working on branch {{.Branch}} says:\n\n{{.Content}}",
+	},
+	{
+		Name:    "continue",
+		Content: HandoffContentSummary,
+		Prompt: "Another agent worked on branch {{.Branch}}. Here is what it did, take it into account in " +
+			"your work:\n\n{{.Content}}",
+	},
+}
+
+// GetHandoffTemplates returns the default handoff templates followed by the configured ones, a configured
+// template replacing the default one with the same name.
+func (c *Config) GetHandoffTemplates() []HandoffTemplate {
+	templates := slices.Clone(defaultHandoffTemplates)
+	for _, configured := range c.HandoffTemplates {
+		if idx := slices.IndexFunc(templates, func(t HandoffTemplate) bool { return t.Name == configured.Name }); idx >= 0 {
+			templates[idx] = configured
+		} else {
+			templates = append(templates, configured)
+		}
+	}
+	return templates
+}
+
+const (
+	// NotifyKindSlack posts to a Slack incoming webhook.
+	NotifyKindSlack = "slack"
+	// NotifyKindDiscord posts to a Discord incoming webhook.
+	NotifyKindDiscord = "discord"
+	// NotifyKindPushover pushes to the phones of a Pushover user.
+	NotifyKindPushover = "pushover"
+	// NotifyKindDesktop shows a notification on the desktop.
+	NotifyKindDesktop = "desktop"
+	// NotifyKindCommand runs a shell command.
+	NotifyKindCommand = "command"
+)
+
+// NotifyBackend is a named backend the notify rules route events to.
+type NotifyBackend struct {
+	// Name is how the rules refer to the backend, e.g. "phone".
+	Name string `json:"name"`
+	// Kind is "slack", "discord", "pushover", "desktop" or "command".
+	Kind string `json:"kind"`
+	// URL of the webhook of the slack and discord kinds.
+	URL string `json:"url,omitempty"`
+	// Token is the API token of the Pushover application. Empty falls back to $PUSHOVER_TOKEN.
+	Token string `json:"token,omitempty"`
+	// User is the key of the Pushover user or group. Empty falls back to $PUSHOVER_USER.
+	User string `json:"user,omitempty"`
+	// Command is the shell command of the command kind. It gets the event in $CS_EVENT, the title of the
+	// instance in $CS_TITLE and the message in $CS_MESSAGE.
+	Command string `json:"command,omitempty"`
+}
+
+// GetPushoverKeys returns the application token and user key of the Pushover backend, falling back to
+// the environment.
+func (b NotifyBackend) GetPushoverKeys() (token string, user string) {
+	token, user = b.Token, b.User
+	if token == "" {
+		token = os.Getenv("PUSHOVER_TOKEN")
+	}
+	if user == "" {
+		user = os.Getenv("PUSHOVER_USER")
+	}
+	return token, user
+}
+
+// NotifyRule routes the events of the instances it matches to backends.
+type NotifyRule struct {
+	// Title is a glob the title of the instance has to match, e.g. "prod-*". Empty matches any.
+	Title string `json:"title,omitempty"`
+	// Group is a glob the group of the instance has to match. Empty matches any, including none.
+	Group string `json:"group,omitempty"`
+	// Events are the events routed: "finished", "crashed", "needs_input" or "blocked". Empty means all.
+	Events []string `json:"events,omitempty"`
+	// Backends are the names of the backends the events are sent to. Empty drops them. "desktop" is
+	// available without declaring it.
+	Backends []string `json:"backends,omitempty"`
+}
+
+// Matches returns true if the rule routes the event of the instance with the title and group.
+func (r NotifyRule) Matches(event, title, group string) bool {
+	if len(r.Events) > 0 && !slices.Contains(r.Events, event) {
+		return false
+	}
+	if r.Title != "" {
+		if ok, _ := path.Match(r.Title, title); !ok {
+			return false
+		}
+	}
+	if r.Group != "" {
+		if ok, _ := path.Match(r.Group, group); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+const (
+	// ScopeRead allows listing the instances, reading their previews and the metrics.
+	ScopeRead = "read"
+	// ScopePrompt allows sending prompts to the instances.
+	ScopePrompt = "prompt"
+	// ScopeLifecycle allows pausing, resuming and killing the instances.
+	ScopeLifecycle = "lifecycle"
+	// ScopeAdmin allows everything.
+	ScopeAdmin = "admin"
+)
+
+// APIToken grants a program access to the API of the daemon. It's sent as a bearer token.
+type APIToken struct {
+	// Name identifies the token in the logs, e.g. "orchestrator".
+	Name string `json:"name"`
+	// Token is the secret the program sends.
+	Token string `json:"token"`
+	// Scopes are what the token allows: "read", "prompt", "lifecycle" or "admin".
+	Scopes []string `json:"scopes"`
+	// Repos are the names of the repositories whose instances the token can access. Empty means all.
+	Repos []string `json:"repos,omitempty"`
+}
+
+// HasScope returns true if the token allows scope.
+func (t APIToken) HasScope(scope string) bool {
+	return slices.Contains(t.Scopes, scope) || slices.Contains(t.Scopes, ScopeAdmin)
+}
+
+// AllowsRepo returns true if the token can access the instances of the repository.
+func (t APIToken) AllowsRepo(repo string) bool {
+	return len(t.Repos) == 0 || slices.Contains(t.Repos, repo)
+}
+
+const (
+	// ProviderPolicyRoundRobin distributes the instances to the providers in turn.
+	ProviderPolicyRoundRobin = "round_robin"
+	// ProviderPolicyCost gives the instances to the cheapest provider with capacity left.
+	ProviderPolicyCost = "cost"
+	// ProviderPolicyTask distributes the instances to the providers suited for the prompt, cheapest
+	// first, falling back to all of them.
+	ProviderPolicyTask = "task"
+)
+
+// Provider is an agent program, with its provider, instances can be distributed to.
+type Provider struct {
+	// Name identifies the provider in messages, e.g. "aider-openai".
+	Name string `json:"name"`
+	// Program is the command line of the agent, e.g. "aider --model gpt-4o".
+	Program string `json:"program"`
+	// Cost is the relative cost of the provider.
+	Cost float64 `json:"cost,omitempty"`
+	// TaskTypes are keywords of the prompts the provider is suited for, e.g. "docs" or "tests".
+	TaskTypes []string `json:"task_types,omitempty"`
+	// MaxConcurrent is the maximum number of running instances of the provider. 0 means no limit.
+	MaxConcurrent int `json:"max_concurrent,omitempty"`
+	// Env are environment variables set for the program, e.g. "ANTHROPIC_MODEL". Values can reference the
+	// environment of claude-squad, e.g. "$WORK_ANTHROPIC_API_KEY", so keys aren't written in the config.
+	Env map[string]string `json:"env,omitempty"`
+}
+
+// ExternalResource is an environment the instances share, e.g. "staging-db". An instance requiring it
+// waits for it, with its prompts held back, while Capacity other instances use it.
+type ExternalResource struct {
+	// Name identifies the resource in templates and on the command line.
+	Name string `json:"name"`
+	// Capacity is the number of instances that may use the resource at a time. 0 means 1.
+	Capacity int `json:"capacity,omitempty"`
+}
+
+// GetCapacity returns the number of instances that may use the resource at a time.
+func (r ExternalResource) GetCapacity() int {
+	if r.Capacity <= 0 {
+		return 1
+	}
+	return r.Capacity
 }
 
 // DefaultConfig returns the default configuration
@@ -52,18 +889,140 @@ func DefaultConfig() *Config {
 		DefaultProgram:     program,
 		AutoYes:            false,
 		DaemonPollInterval: 1000,
-		BranchPrefix: func() string {
-			user, err := user.Current()
-			if err != nil || user == nil || user.Username == "" {
-				log.ErrorLog.Printf("failed to get current user: %v", err)
-				return "session/"
-			}
-			return fmt.Sprintf("%s/", strings.ToLower(user.Username))
-		}(),
-		CopyOnCreate: []string{},
+		BranchPrefix:       fmt.Sprintf("%s/", Username()),
+		CopyOnCreate:       []string{},
+		FanOutCount:        defaultFanOutCount,
+		ResourceLimits: ResourceLimits{
+			CPUPercent: 90,
+			CPUMinutes: 10,
+			Action:     ResourceActionWarn,
+		},
 	}
 }
 
+// Username returns the lowercased name of the current user, for use in branch names, or "session" if it
+// can't be determined.
+func Username() string {
+	user, err := user.Current()
+	if err != nil || user == nil || user.Username == "" {
+		log.ErrorLog.Printf("failed to get current user: %v", err)
+		return "session"
+	}
+	// On Windows, the username is qualified by the domain (DOMAIN\user), and backslashes aren't allowed in
+	// branch names.
+	username := user.Username
+	if idx := strings.LastIndex(username, `\`); idx >= 0 {
+		username = username[idx+1:]
+	}
+	return strings.ToLower(username)
+}
+
+// GetFanOutCount returns the number of instances created by a fan-out, falling back to the default for
+// configs written before the setting existed.
+func (c *Config) GetFanOutCount() int {
+	if c.FanOutCount <= 0 {
+		return defaultFanOutCount
+	}
+	return c.FanOutCount
+}
+
+// GetTriggerPollInterval returns how often the triggers poll GitHub, falling back to the default if it's
+// not set.
+func (c *Config) GetTriggerPollInterval() time.Duration {
+	if c.TriggerPollInterval <= 0 {
+		return defaultTriggerPollInterval * time.Second
+	}
+	return time.Duration(c.TriggerPollInterval) * time.Second
+}
+
+// GetDriftThreshold returns the number of commits behind the upstream base branch that flags an instance
+// for a rebase, falling back to the default if it's not set.
+func (c *Config) GetDriftThreshold() int {
+	if c.DriftThreshold <= 0 {
+		return defaultDriftThreshold
+	}
+	return c.DriftThreshold
+}
+
+// GetReviewPrompt returns the prompt sent to an instance created from a remote branch, falling back to the
+// default if it's not set.
+func (c *Config) GetReviewPrompt() string {
+	if strings.TrimSpace(c.ReviewPrompt) == "" {
+		return defaultReviewPrompt
+	}
+	return c.ReviewPrompt
+}
+
+// GetStateBackups returns the number of backups of the state file kept, falling back to the default if
+// it's not set.
+func (c *Config) GetStateBackups() int {
+	if c.StateBackups <= 0 {
+		return defaultStateBackups
+	}
+	return c.StateBackups
+}
+
+// GetSnapshotTTL returns how long shared snapshots are kept, falling back to the default if it's not set.
+func (c *Config) GetSnapshotTTL() time.Duration {
+	days := c.SnapshotExpireDays
+	if days <= 0 {
+		days = defaultSnapshotExpireDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// GetTmuxPrefix returns the prefix of the names of the tmux sessions of new instances, falling back to the
+// default if it's not set.
+func (c *Config) GetTmuxPrefix() string {
+	if c.TmuxPrefix == "" && profile != "" {
+		// The sessions of a profile don't share the default prefix, so the other profiles don't take them
+		// for their own.
+		return "claudesquad-" + profile + "_"
+	}
+	if c.TmuxPrefix == "" {
+		return defaultTmuxPrefix
+	}
+	return c.TmuxPrefix
+}
+
+// GetStuckPatterns returns the compiled stuck patterns, falling back to the defaults if none are set.
+// Invalid patterns are skipped and returned in the error.
+func (c *Config) GetStuckPatterns() ([]*regexp.Regexp, error) {
+	sources := c.StuckPatterns
+	if len(sources) == 0 {
+		sources = defaultStuckPatterns
+	}
+	patterns := make([]*regexp.Regexp, 0, len(sources))
+	var errs []error
+	for _, source := range sources {
+		pattern, err := regexp.Compile(source)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid stuck pattern %q: %w", source, err))
+			continue
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, errors.Join(errs...)
+}
+
+// GetRedactPatterns returns the compiled patterns redacted from exported artifacts: the common secret
+// formats followed by the configured ones. Invalid patterns are returned in the error, so nothing is
+// exported with them left out.
+func (c *Config) GetRedactPatterns() ([]*regexp.Regexp, error) {
+	sources := append(append([]string{}, defaultRedactPatterns...), c.RedactPatterns...)
+	patterns := make([]*regexp.Regexp, 0, len(sources))
+	var errs []error
+	for _, source := range sources {
+		pattern, err := regexp.Compile(source)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid redact pattern %q: %w", source, err))
+			continue
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, errors.Join(errs...)
+}
+
 // GetClaudeCommand attempts to find the "claude" command in the user's shell
 // It checks in the following order:
 // 1. Shell alias resolution: using "which" command
@@ -140,10 +1099,61 @@ func LoadConfig() *Config {
 		log.ErrorLog.Printf("failed to parse config file: %v", err)
 		return DefaultConfig()
 	}
+	problems, _ := Validate(data)
+	for _, problem := range problems {
+		log.WarningLog.Printf("%s:%v", configPath, problem)
+	}
 
 	return &config
 }
 
+// LoadTelemetry returns the telemetry settings of the config file. Unlike LoadConfig, it doesn't log or
+// create the config file, so it can run before the log is set up.
+func LoadTelemetry() Telemetry {
+	var config struct {
+		Telemetry Telemetry `json:"telemetry"`
+	}
+	peekConfig(&config)
+	return config.Telemetry
+}
+
+// LoadTracing returns the tracing settings of the config file. Like LoadTelemetry, it can run before the
+// log is set up.
+func LoadTracing() Tracing {
+	var config struct {
+		Tracing Tracing `json:"tracing"`
+	}
+	peekConfig(&config)
+	return config.Tracing
+}
+
+// peekConfig decodes the config file into v, a pointer to a struct, which is left zero if the file can't
+// be read or parsed.
+func peekConfig(v any) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return
+	}
+	data, err := os.ReadFile(filepath.Join(configDir, ConfigFileName))
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		reflect.ValueOf(v).Elem().SetZero()
+	}
+}
+
+// Schema returns the JSON Schema of the config file.
+func Schema() *schema.Schema {
+	return schema.Generate(Config{}, "json", "claude-squad config")
+}
+
+// Validate checks the config file data against its schema, returning the fields that are unknown or have
+// the wrong type, which the config otherwise ignores or fails to load.
+func Validate(data []byte) ([]schema.Problem, error) {
+	return schema.Validate(data, Schema())
+}
+
 // saveConfig saves the configuration to disk
 func saveConfig(config *Config) error {
 	configDir, err := GetConfigDir()
@@ -0,0 +1,110 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// StateKeyFileName is the name of the file holding the key used to encrypt the state file.
+	StateKeyFileName = "state.key"
+	// StateKeyEnv is the environment variable that overrides the key file. It holds a base64 encoded
+	// 32 byte key.
+	StateKeyEnv = "CLAUDE_SQUAD_STATE_KEY"
+)
+
+// encryptedState is the on-disk format of an encrypted state file.
+type encryptedState struct {
+	// Ciphertext is the AES-GCM sealed state, prefixed with its nonce.
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// loadStateKey returns the key used to encrypt the state file. The key is read from StateKeyEnv if set,
+// otherwise from the key file in the config directory, which is created on first use.
+func loadStateKey() ([]byte, error) {
+	if encoded := os.Getenv(StateKeyEnv); encoded != "" {
+		return decodeStateKey(encoded)
+	}
+
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config directory: %w", err)
+	}
+	keyPath := filepath.Join(configDir, StateKeyFileName)
+
+	data, err := os.ReadFile(keyPath)
+	if err == nil {
+		return decodeStateKey(string(data))
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read state key: %w", err)
+	}
+
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, fmt.Errorf("failed to generate state key: %w", err)
+	}
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(keyPath, []byte(base64.StdEncoding.EncodeToString(key)), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write state key: %w", err)
+	}
+	return key, nil
+}
+
+func decodeStateKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode state key: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("state key must be 32 bytes, got %d", len(key))
+	}
+	return key, nil
+}
+
+// encrypt seals the plaintext with AES-256-GCM.
+func encrypt(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// decrypt opens a ciphertext sealed by encrypt.
+func decrypt(key, ciphertext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, fmt.Errorf("ciphertext is too short")
+	}
+	nonce, sealed := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
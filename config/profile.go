@@ -0,0 +1,91 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+const (
+	// ProfileEnv is the environment variable that selects the profile, like --profile. It's how the
+	// daemon of a profile is told which one it serves.
+	ProfileEnv = "CLAUDE_SQUAD_PROFILE"
+	// ProfilesDirName is the directory of the config directory the profiles are kept in.
+	ProfilesDirName = "profiles"
+	// DefaultProfile is the name the default profile is shown as. Its config, state and worktrees are the
+	// ones of the config directory itself.
+	DefaultProfile = "default"
+)
+
+var profileNameRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]{0,31}$`)
+
+// profile is the name of the current profile, empty for the default one.
+var profile = os.Getenv(ProfileEnv)
+
+// Profile returns the name of the current profile, empty for the default one.
+func Profile() string {
+	return profile
+}
+
+// SetProfile switches to the profile, which has its own config, state, worktrees and daemon, in the
+// profiles directory of the config directory. An empty name or DefaultProfile switches to the default
+// profile. The directory of the profile is created on first use, like the config directory.
+func SetProfile(name string) error {
+	if name == "" || name == DefaultProfile {
+		profile = ""
+		return nil
+	}
+	if err := ValidateProfile(name); err != nil {
+		return err
+	}
+	profile = name
+	return nil
+}
+
+// ValidateProfile returns an error if the name can't be the name of a profile.
+func ValidateProfile(name string) error {
+	if !profileNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q: use up to 32 letters, digits, dashes and underscores", name)
+	}
+	return nil
+}
+
+// ProfileName returns the name of the current profile as it's shown, DefaultProfile for the default one.
+func ProfileName() string {
+	if profile == "" {
+		return DefaultProfile
+	}
+	return profile
+}
+
+// rootConfigDir returns the config directory of the default profile, which holds the other profiles.
+func rootConfigDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get config home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".claude-squad"), nil
+}
+
+// ListProfiles returns the names of the profiles, the default one first and the others by name.
+func ListProfiles() ([]string, error) {
+	root, err := rootConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	profiles := []string{DefaultProfile}
+	entries, err := os.ReadDir(filepath.Join(root, ProfilesDirName))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to list profiles: %w", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() && profileNameRegex.MatchString(entry.Name()) && entry.Name() != DefaultProfile {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return append(profiles, names...), nil
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ReadyRule tells when the program of an instance is done working and ready for input. Without a rule,
+// an instance is ready as soon as its pane stops changing.
+type ReadyRule struct {
+	// Program is the program the rule applies to, e.g. "aider" or "./scripts/agent.sh", matched against
+	// the command of instances with or without arguments. Empty matches every program.
+	Program string `json:"program,omitempty"`
+	// IdleSeconds is how long the pane must not change before the instance is ready, unless a prompt or
+	// done pattern matches.
+	IdleSeconds int `json:"idle_seconds,omitempty"`
+	// BusyPatterns are regular expressions of what the program shows at the bottom of its pane while it
+	// works, e.g. "esc to interrupt". The instance isn't ready while one matches, however long the pane
+	// doesn't change.
+	BusyPatterns []string `json:"busy_patterns,omitempty"`
+	// PromptPatterns are regular expressions of the last line of the pane when the program waits for
+	// input, e.g. a shell prompt reappearing. The instance is ready as soon as the pane stops changing
+	// with one matching.
+	PromptPatterns []string `json:"prompt_patterns,omitempty"`
+	// DonePatterns are regular expressions of the completion markers of the program at the bottom of its
+	// pane, e.g. "All tests passed". The instance is ready as soon as the pane stops changing with one
+	// matching.
+	DonePatterns []string `json:"done_patterns,omitempty"`
+	// ExitStatus keeps the pane of the program when it exits instead of the instance being gone. The
+	// instance is ready then, and a non-zero exit status is recorded as an error of the instance.
+	ExitStatus bool `json:"exit_status,omitempty"`
+}
+
+// ReadyDetector is a ReadyRule with its patterns compiled.
+type ReadyDetector struct {
+	Program    string
+	Idle       time.Duration
+	Busy       []*regexp.Regexp
+	Prompt     []*regexp.Regexp
+	Done       []*regexp.Regexp
+	ExitStatus bool
+}
+
+// defaultReadyRules are the rules of the programs claude-squad knows, and of shells and scripts.
+var defaultReadyRules = []ReadyRule{
+	{Program: "claude", IdleSeconds: 2, BusyPatterns: []string{`(?i)esc to interrupt`}},
+	{Program: "aider", IdleSeconds: 3, PromptPatterns: []string{`^\w*> ?$`}},
+	{Program: "gemini", IdleSeconds: 2, BusyPatterns: []string{`(?i)esc to cancel`}},
+	{IdleSeconds: 5, PromptPatterns: []string{`[$#%] ?$`}},
+}
+
+// Matches returns true if the detector applies to the program of an instance, with or without its
+// arguments, or by the name of its executable, e.g. "claude" for "/usr/local/bin/claude --continue".
+func (d ReadyDetector) Matches(program string) bool {
+	if d.Program == "" || program == d.Program || strings.HasPrefix(program, d.Program+" ") {
+		return true
+	}
+	fields := strings.Fields(program)
+	return len(fields) > 0 && filepath.Base(fields[0]) == d.Program
+}
+
+// GetReadyDetectors returns the compiled ready rules followed by the defaults, the first one matching
+// the program of an instance applying to it. Invalid patterns are skipped and returned in the error.
+func (c *Config) GetReadyDetectors() ([]ReadyDetector, error) {
+	rules := append(append([]ReadyRule(nil), c.ReadyRules...), defaultReadyRules...)
+	detectors := make([]ReadyDetector, 0, len(rules))
+	var errs []error
+	compile := func(sources []string) []*regexp.Regexp {
+		patterns := make([]*regexp.Regexp, 0, len(sources))
+		for _, source := range sources {
+			pattern, err := regexp.Compile(source)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("invalid ready pattern %q: %w", source, err))
+				continue
+			}
+			patterns = append(patterns, pattern)
+		}
+		return patterns
+	}
+	for _, rule := range rules {
+		detectors = append(detectors, ReadyDetector{
+			Program:    rule.Program,
+			Idle:       time.Duration(rule.IdleSeconds) * time.Second,
+			Busy:       compile(rule.BusyPatterns),
+			Prompt:     compile(rule.PromptPatterns),
+			Done:       compile(rule.DonePatterns),
+			ExitStatus: rule.ExitStatus,
+		})
+	}
+	return detectors, errors.Join(errs...)
+}
+
+// ReadyDetectorFor returns the detector of the program among the detectors, nil if none matches.
+func ReadyDetectorFor(detectors []ReadyDetector, program string) *ReadyDetector {
+	for idx := range detectors {
+		if detectors[idx].Matches(program) {
+			return &detectors[idx]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,272 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// RepoConfigFileName is the name of the settings file in the root of a repository, shared by everyone
+// working on it.
+const RepoConfigFileName = ".claude-squad.json"
+
+// trustedRepoConfigsFileName is the file in the config directory recording the settings files whose
+// commands were trusted, by the path of their repository.
+const trustedRepoConfigsFileName = "trusted_repo_configs.json"
+
+// RepoConfig holds the settings of a repository.
+type RepoConfig struct {
+	// TeardownCommands are run in the worktree of an instance of the repository before it's removed, on
+	// kill and pause, after the global teardown commands.
+	TeardownCommands []string `json:"teardown_commands,omitempty"`
+	// CheckCommand is run in the worktree of an instance to check its changes, e.g. "go test ./...". Empty
+	// disables the checks.
+	CheckCommand string `json:"check_command,omitempty"`
+	// RequireChecks blocks pushing the changes of an instance until the check command passed on them.
+	RequireChecks bool `json:"require_checks,omitempty"`
+	// WarmupCommands are run in the background in each new worktree of an instance of the repository,
+	// e.g. "gopls check ./..." or "tsc --noEmit", so the caches of the tooling are hot when it's opened in
+	// an editor.
+	WarmupCommands []string `json:"warmup_commands,omitempty"`
+	// DiffPaths are the paths, relative to the root of the repository, the diffs of its instances and the
+	// watching of their worktrees are limited to, e.g. the areas of a monorepo agents work in. Empty means
+	// the whole repository.
+	DiffPaths []string `json:"diff_paths,omitempty"`
+	// Changelog writes a release notes fragment for the changes of an instance into its branch before
+	// it's pushed.
+	Changelog Changelog `json:"changelog"`
+
+	// Untrusted is true if the commands of the settings file were left out, since it's committed by
+	// anyone working on the repository and wasn't trusted with TrustRepoConfig since it last changed.
+	Untrusted bool `json:"-"`
+}
+
+// hasCommands returns true if the settings run commands.
+func (c *RepoConfig) hasCommands() bool {
+	return len(c.TeardownCommands) > 0 || c.CheckCommand != "" || len(c.WarmupCommands) > 0
+}
+
+const (
+	// ChangelogTowncrier writes towncrier news fragments, e.g. "newsfragments/+fix-login.bugfix.md".
+	ChangelogTowncrier = "towncrier"
+	// ChangelogKeepAChangelog writes fragments with Keep a Changelog sections, e.g. "### Fixed", as
+	// collected by scriv, to "changelog.d/fix-login.md".
+	ChangelogKeepAChangelog = "keep-a-changelog"
+)
+
+// Changelog is how the release notes fragments of the instances of a repository are written.
+type Changelog struct {
+	// Format is "towncrier" or "keep-a-changelog". Empty disables the fragments.
+	Format string `json:"format,omitempty"`
+	// Dir is the directory of the fragments, relative to the root of the repository. Empty is
+	// "newsfragments" for towncrier and "changelog.d" for keep-a-changelog.
+	Dir string `json:"dir,omitempty"`
+	// Type is the type of the changes, e.g. "feature" for towncrier or "Added" for keep-a-changelog. Empty
+	// guesses it from the title and the first prompt of the instance.
+	Type string `json:"type,omitempty"`
+	// Template is a Go template of the entry. It can use {{.Title}}, {{.Branch}}, {{.Prompt}}, the first
+	// prompt of the instance, and {{.Summary}}, the first paragraph of the last message of its agent.
+	// Empty is "{{.Title}}".
+	Template string `json:"template,omitempty"`
+}
+
+// LoadRepoConfig loads the settings of the repository at repoPath. A repository without a settings file
+// has empty settings. A bare repository has no files, so the settings file committed on its HEAD is used.
+// The commands of a settings file that isn't trusted are left out, like direnv does with an .envrc that
+// wasn't allowed, so cloning a repository doesn't run the commands of whoever committed them.
+func LoadRepoConfig(repoPath string) (*RepoConfig, error) {
+	data, err := readRepoConfig(repoPath)
+	if err != nil || data == nil {
+		return &RepoConfig{}, err
+	}
+	config, err := parseRepoConfig(data)
+	if err != nil {
+		return nil, err
+	}
+	if config.hasCommands() && !repoConfigTrusted(repoPath, data) {
+		config.TeardownCommands, config.CheckCommand, config.WarmupCommands = nil, "", nil
+		config.Untrusted = true
+	}
+	return config, nil
+}
+
+// readRepoConfig returns the content of the settings file of the repository at repoPath, or nil if it
+// has none.
+func readRepoConfig(repoPath string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(repoPath, RepoConfigFileName))
+	if os.IsNotExist(err) && isBareRepo(repoPath) {
+		data, err = exec.Command("git", "-C", repoPath, "show", "HEAD:"+RepoConfigFileName).Output()
+		if err != nil {
+			// The file isn't committed.
+			return nil, nil
+		}
+	}
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", RepoConfigFileName, err)
+	}
+	return data, nil
+}
+
+func parseRepoConfig(data []byte) (*RepoConfig, error) {
+	var config RepoConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", RepoConfigFileName, err)
+	}
+	return &config, nil
+}
+
+// TrustRepoConfig trusts the commands of the current settings file of the repository at repoPath, until
+// it changes, if confirm, which is shown all its settings, returns true. It returns whether it was trusted.
+func TrustRepoConfig(repoPath string, confirm func(*RepoConfig) bool) (bool, error) {
+	data, err := readRepoConfig(repoPath)
+	if err != nil {
+		return false, err
+	}
+	if data == nil {
+		return false, fmt.Errorf("the repository has no %s", RepoConfigFileName)
+	}
+	config, err := parseRepoConfig(data)
+	if err != nil {
+		return false, err
+	}
+	if !confirm(config) {
+		return false, nil
+	}
+	return true, trustRepoConfigData(repoPath, data)
+}
+
+// trustedRepoConfigsPath returns the path of the file recording the trusted settings files.
+func trustedRepoConfigsPath() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, trustedRepoConfigsFileName), nil
+}
+
+// loadTrustedRepoConfigs returns the hashes of the trusted settings files by the path of their repository.
+func loadTrustedRepoConfigs() (map[string]string, error) {
+	path, err := trustedRepoConfigsPath()
+	if err != nil {
+		return nil, err
+	}
+	trusted := make(map[string]string)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return trusted, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the trusted settings files: %w", err)
+	}
+	if err := json.Unmarshal(data, &trusted); err != nil {
+		return nil, fmt.Errorf("failed to parse the trusted settings files: %w", err)
+	}
+	return trusted, nil
+}
+
+// repoConfigTrusted returns true if data, the settings file of the repository at repoPath, was trusted.
+func repoConfigTrusted(repoPath string, data []byte) bool {
+	trusted, err := loadTrustedRepoConfigs()
+	if err != nil {
+		return false
+	}
+	return trusted[absRepoPath(repoPath)] == hashRepoConfig(data)
+}
+
+func trustRepoConfigData(repoPath string, data []byte) error {
+	trusted, err := loadTrustedRepoConfigs()
+	if err != nil {
+		return err
+	}
+	trusted[absRepoPath(repoPath)] = hashRepoConfig(data)
+	path, err := trustedRepoConfigsPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	output, err := json.MarshalIndent(trusted, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, output, 0600)
+}
+
+func hashRepoConfig(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func absRepoPath(repoPath string) string {
+	if abs, err := filepath.Abs(repoPath); err == nil {
+		return abs
+	}
+	return repoPath
+}
+
+// WriteRepoSettings sets the settings, by their names in the settings file, in the settings file of the
+// repository at repoPath, keeping the others.
+func WriteRepoSettings(repoPath string, settings map[string]any) error {
+	if isBareRepo(repoPath) {
+		return fmt.Errorf("a bare repository has no files: commit the settings to %s instead", RepoConfigFileName)
+	}
+	path := filepath.Join(repoPath, RepoConfigFileName)
+	existing := make(map[string]json.RawMessage)
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", RepoConfigFileName, err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &existing); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", RepoConfigFileName, err)
+		}
+	}
+	// Settings written by the user keep the file trusted, unless it had commands that weren't.
+	trusted := true
+	if err == nil {
+		config, parseErr := parseRepoConfig(data)
+		trusted = parseErr == nil && (!config.hasCommands() || repoConfigTrusted(repoPath, data))
+	}
+	for name, value := range settings {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", name, err)
+		}
+		existing[name] = raw
+	}
+	// Check the result still loads.
+	data, err = json.MarshalIndent(existing, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &RepoConfig{}); err != nil {
+		return fmt.Errorf("invalid settings: %w", err)
+	}
+	data = append(data, '\n')
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", RepoConfigFileName, err)
+	}
+	if trusted {
+		return trustRepoConfigData(repoPath, data)
+	}
+	return nil
+}
+
+// isBareRepo returns true if repoPath is a bare git repository. A repository with a .git directory, or
+// file for a linked worktree, isn't, which spares running git for most repositories.
+func isBareRepo(repoPath string) bool {
+	if _, err := os.Stat(filepath.Join(repoPath, ".git")); err == nil {
+		return false
+	}
+	output, err := exec.Command("git", "-C", repoPath, "rev-parse", "--is-bare-repository").Output()
+	return err == nil && strings.TrimSpace(string(output)) == "true"
+}
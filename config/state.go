@@ -6,11 +6,15 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"time"
 )
 
 const (
 	StateFileName     = "state.json"
 	InstancesFileName = "instances.json"
+	// maxTriggeredEvents is how many trigger events are remembered. The oldest are forgotten first.
+	maxTriggeredEvents = 1000
 )
 
 // InstanceStorage handles instance-related operations
@@ -21,6 +25,16 @@ type InstanceStorage interface {
 	GetInstances() json.RawMessage
 	// DeleteAllInstances removes all stored instances
 	DeleteAllInstances() error
+	// SaveArchivedInstances saves the raw archived instance data
+	SaveArchivedInstances(archivedJSON json.RawMessage) error
+	// GetArchivedInstances returns the raw archived instance data
+	GetArchivedInstances() json.RawMessage
+	// SaveInstanceBlob saves bulky data of an instance, kept out of the instance data
+	SaveInstanceBlob(key, name string, data []byte) error
+	// GetInstanceBlob returns bulky data of an instance, or nil if there is none
+	GetInstanceBlob(key, name string) ([]byte, error)
+	// DeleteInstanceBlobs removes the bulky data of an instance
+	DeleteInstanceBlobs(key string) error
 }
 
 // AppState handles application-level state
@@ -29,6 +43,14 @@ type AppState interface {
 	GetHelpScreensSeen() uint32
 	// SetHelpScreensSeen updates the bitmask of seen help screens
 	SetHelpScreensSeen(seen uint32) error
+	// GetTheme returns the name of the color theme picked while the app ran, empty if none was
+	GetTheme() string
+	// SetTheme saves the name of the color theme picked while the app runs
+	SetTheme(name string) error
+	// IsTriggered returns true if an instance was already created for the trigger event.
+	IsTriggered(event string) bool
+	// SetTriggered records that an instance was created for the trigger event.
+	SetTriggered(event string) error
 }
 
 // StateManager combines instance storage and app state management
@@ -43,6 +65,32 @@ type State struct {
 	HelpScreensSeen uint32 `json:"help_screens_seen"`
 	// Instances stores the serialized instance data as raw JSON
 	InstancesData json.RawMessage `json:"instances"`
+	// ArchivedData stores the serialized archived instance data as raw JSON
+	ArchivedData json.RawMessage `json:"archived,omitempty"`
+	// Theme is the name of the color theme picked while the app ran, which takes precedence over the
+	// theme of the config.
+	Theme string `json:"theme,omitempty"`
+	// TriggeredEvents are the trigger events instances were created for, oldest first.
+	TriggeredEvents []string `json:"triggered_events,omitempty"`
+	// Version is incremented on every save, so a process notices when another one saved the state since it
+	// loaded it.
+	Version uint64 `json:"version"`
+
+	// encrypt is true if the state is encrypted at rest
+	encrypt bool
+	// backups is the number of backups of the state file kept, the default if 0.
+	backups int
+	// knownInstances and knownArchived are the instances this process loaded or saved, by their keys, as
+	// it loaded or saved them. The others in the state file were added by other processes, and the ones
+	// missing from it were removed by them.
+	knownInstances map[string]string
+	knownArchived  map[string]string
+	// recovered is true if the state is the default one because the state file was missing or corrupted.
+	// The backups aren't rotated then, so the ones of the instances that were lost are kept.
+	recovered bool
+	// loadErr is why the state file couldn't be decrypted, in which case the state is the default one and
+	// isn't saved, so the state file is never overwritten with it.
+	loadErr error
 }
 
 // DefaultState returns the default state
@@ -55,10 +103,16 @@ func DefaultState() *State {
 
 // LoadState loads the state from disk. If it cannot be done, we return the default state.
 func LoadState() *State {
+	cfg := LoadConfig()
+	encrypted := cfg.EncryptState
+	defaultState := DefaultState()
+	defaultState.encrypt = encrypted
+	defaultState.backups = cfg.GetStateBackups()
+
 	configDir, err := GetConfigDir()
 	if err != nil {
 		log.ErrorLog.Printf("failed to get config directory: %v", err)
-		return DefaultState()
+		return defaultState
 	}
 
 	statePath := filepath.Join(configDir, StateFileName)
@@ -66,7 +120,7 @@ func LoadState() *State {
 	if err != nil {
 		if os.IsNotExist(err) {
 			// Create and save default state if file doesn't exist
-			defaultState := DefaultState()
+			defaultState.recovered = true
 			if saveErr := SaveState(defaultState); saveErr != nil {
 				log.WarningLog.Printf("failed to save default state: %v", saveErr)
 			}
@@ -74,20 +128,88 @@ func LoadState() *State {
 		}
 
 		log.WarningLog.Printf("failed to get state file: %v", err)
-		return DefaultState()
+		return defaultState
+	}
+
+	data, err = decryptState(data)
+	if err != nil {
+		// The key may only be out of reach for now, e.g. in a locked keychain, so the state file is left
+		// as it is for the next run, and the default state isn't saved over it.
+		defaultState.loadErr = fmt.Errorf("failed to decrypt the state file %s, the key of encrypt_state may be locked away: %w", statePath, err)
+		log.ErrorLog.Print(defaultState.loadErr)
+		return defaultState
 	}
 
 	var state State
 	if err := json.Unmarshal(data, &state); err != nil {
-		log.ErrorLog.Printf("failed to parse state file: %v", err)
-		return DefaultState()
+		// Keep the corrupted file around too, and point at the backups to recover the instances from. It's
+		// named after when it was moved, so it never replaces one moved before.
+		corruptPath := unusedPath(statePath+".corrupt-", time.Now(), "")
+		if renameErr := os.Rename(statePath, corruptPath); renameErr != nil {
+			log.ErrorLog.Printf("failed to move corrupted state file: %v", renameErr)
+		}
+		log.ErrorLog.Printf("failed to parse state file, moved it to %s, run `cs state restore` to restore a backup: %v",
+			corruptPath, err)
+		defaultState.recovered = true
+		return defaultState
 	}
+	state.encrypt = encrypted
+	state.backups = defaultState.backups
+	state.knownInstances = entryVersions(state.InstancesData)
+	state.knownArchived = entryVersions(state.ArchivedData)
 
 	return &state
 }
 
-// SaveState saves the state to disk
+// CheckStateFile reads the state file like LoadState, but without creating, moving or overwriting it, and
+// returns why it can't be loaded, if it can't. A missing state file is the default state.
+func CheckStateFile() (*State, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config directory: %w", err)
+	}
+	statePath := filepath.Join(configDir, StateFileName)
+	data, err := os.ReadFile(statePath)
+	if os.IsNotExist(err) {
+		return DefaultState(), nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", statePath, err)
+	}
+	if data, err = decryptState(data); err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s: %w", statePath, err)
+	}
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", statePath, err)
+	}
+	return &state, nil
+}
+
+// decryptState returns the plaintext of the state file, which is returned as is if it is not encrypted.
+func decryptState(data []byte) ([]byte, error) {
+	var envelope encryptedState
+	if err := json.Unmarshal(data, &envelope); err != nil || len(envelope.Ciphertext) == 0 {
+		return data, nil
+	}
+	key, err := loadStateKey()
+	if err != nil {
+		return nil, err
+	}
+	return decrypt(key, envelope.Ciphertext)
+}
+
+// LoadError returns why the state file couldn't be decrypted, if it couldn't. The state is then the
+// default one, and can't be saved.
+func (s *State) LoadError() error {
+	return s.loadErr
+}
+
+// SaveState saves the state to disk. The state file is locked while it's saved, and the changes other
+// processes saved since the state was loaded are merged into it.
 func SaveState(state *State) error {
+	if state.loadErr != nil {
+		return fmt.Errorf("not saving the state, so the state file isn't overwritten: %w", state.loadErr)
+	}
 	configDir, err := GetConfigDir()
 	if err != nil {
 		return fmt.Errorf("failed to get config directory: %w", err)
@@ -98,12 +220,74 @@ func SaveState(state *State) error {
 	}
 
 	statePath := filepath.Join(configDir, StateFileName)
-	data, err := json.MarshalIndent(state, "", "  ")
+	unlock, err := lockState(statePath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	merged := *state
+	var conflicts []string
+	if onDisk := readStateFile(statePath); onDisk != nil {
+		// Only a state file that could be read is backed up, so a corrupted one never replaces a good backup.
+		keep := state.backups
+		if keep <= 0 {
+			keep = defaultStateBackups
+		}
+		if state.recovered {
+			keep = 0
+		}
+		if err := backupStateFile(statePath, keep, time.Now()); err != nil {
+			log.WarningLog.Printf("failed to back up state file: %v", err)
+		}
+		if onDisk.Version != state.Version {
+			log.InfoLog.Printf("state was saved by another process (version %d, ours %d), merging", onDisk.Version, state.Version)
+		}
+		// The state is merged even if no other process saved it since, since the state file keeps what we
+		// merged from the other processes before, which our instances must not overwrite.
+		merged, conflicts = mergeState(state, onDisk)
+		merged.Version = max(merged.Version, onDisk.Version)
+	}
+	merged.Version++
+
+	data, err := json.MarshalIndent(&merged, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal state: %w", err)
 	}
+	if err := writeStateFile(statePath, data, state.encrypt); err != nil {
+		return err
+	}
+
+	state.Version = merged.Version
+	state.HelpScreensSeen = merged.HelpScreensSeen
+	state.Theme = merged.Theme
+	state.TriggeredEvents = merged.TriggeredEvents
+	state.knownInstances = entryVersions(state.InstancesData)
+	state.knownArchived = entryVersions(state.ArchivedData)
+	if len(conflicts) > 0 {
+		return &StateConflictError{Titles: conflicts}
+	}
+	return nil
+}
+
+// writeStateFile writes the marshaled state to disk, encrypted if encrypted is true.
+func writeStateFile(statePath string, data []byte, encrypted bool) error {
+	if encrypted {
+		key, err := loadStateKey()
+		if err != nil {
+			return err
+		}
+		ciphertext, err := encrypt(key, data)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt state: %w", err)
+		}
+		if data, err = json.MarshalIndent(encryptedState{Ciphertext: ciphertext}, "", "  "); err != nil {
+			return fmt.Errorf("failed to marshal state: %w", err)
+		}
+		return writeFileAtomic(statePath, data, 0600)
+	}
 
-	return os.WriteFile(statePath, data, 0644)
+	return writeFileAtomic(statePath, data, 0644)
 }
 
 // InstanceStorage interface implementation
@@ -119,12 +303,36 @@ func (s *State) GetInstances() json.RawMessage {
 	return s.InstancesData
 }
 
-// DeleteAllInstances removes all stored instances
+// DeleteAllInstances removes all stored instances, and their bulky data
 func (s *State) DeleteAllInstances() error {
 	s.InstancesData = json.RawMessage("[]")
+	if err := SaveState(s); err != nil {
+		return err
+	}
+	blobsDir, err := instanceBlobPath("", "")
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(blobsDir); err != nil {
+		return fmt.Errorf("failed to remove the data of the instances: %w", err)
+	}
+	return nil
+}
+
+// SaveArchivedInstances saves the raw archived instance data
+func (s *State) SaveArchivedInstances(archivedJSON json.RawMessage) error {
+	s.ArchivedData = archivedJSON
 	return SaveState(s)
 }
 
+// GetArchivedInstances returns the raw archived instance data
+func (s *State) GetArchivedInstances() json.RawMessage {
+	if len(s.ArchivedData) == 0 {
+		return json.RawMessage("[]")
+	}
+	return s.ArchivedData
+}
+
 // AppState interface implementation
 
 // GetHelpScreensSeen returns the bitmask of seen help screens
@@ -137,3 +345,31 @@ func (s *State) SetHelpScreensSeen(seen uint32) error {
 	s.HelpScreensSeen = seen
 	return SaveState(s)
 }
+
+// GetTheme returns the name of the color theme picked while the app ran
+func (s *State) GetTheme() string {
+	return s.Theme
+}
+
+// SetTheme saves the name of the color theme picked while the app runs
+func (s *State) SetTheme(name string) error {
+	s.Theme = name
+	return SaveState(s)
+}
+
+// IsTriggered returns true if an instance was already created for the trigger event.
+func (s *State) IsTriggered(event string) bool {
+	return slices.Contains(s.TriggeredEvents, event)
+}
+
+// SetTriggered records that an instance was created for the trigger event.
+func (s *State) SetTriggered(event string) error {
+	if s.IsTriggered(event) {
+		return nil
+	}
+	s.TriggeredEvents = append(s.TriggeredEvents, event)
+	if len(s.TriggeredEvents) > maxTriggeredEvents {
+		s.TriggeredEvents = s.TriggeredEvents[len(s.TriggeredEvents)-maxTriggeredEvents:]
+	}
+	return SaveState(s)
+}
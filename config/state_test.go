@@ -0,0 +1,157 @@
+package config
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptedState(t *testing.T) {
+	originalHome := os.Getenv("HOME")
+	tempHome := t.TempDir()
+	os.Setenv("HOME", tempHome)
+	defer os.Setenv("HOME", originalHome)
+
+	cfg := DefaultConfig()
+	cfg.EncryptState = true
+	require.NoError(t, SaveConfig(cfg))
+
+	state := LoadState()
+	require.NoError(t, state.SaveInstances(json.RawMessage(`[{"title":"secret-instance"}]`)))
+
+	statePath := filepath.Join(tempHome, ".claude-squad", StateFileName)
+	data, err := os.ReadFile(statePath)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "secret-instance")
+
+	info, err := os.Stat(filepath.Join(tempHome, ".claude-squad", StateKeyFileName))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	loaded := LoadState()
+	assert.JSONEq(t, `[{"title":"secret-instance"}]`, string(loaded.GetInstances()))
+
+	t.Run("disabling encryption decrypts the state", func(t *testing.T) {
+		cfg.EncryptState = false
+		require.NoError(t, SaveConfig(cfg))
+		plain := LoadState()
+		require.NoError(t, plain.SaveInstances(plain.GetInstances()))
+
+		data, err := os.ReadFile(statePath)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "secret-instance")
+	})
+
+	t.Run("a state that can't be decrypted is never overwritten", func(t *testing.T) {
+		cfg.EncryptState = true
+		require.NoError(t, SaveConfig(cfg))
+		require.NoError(t, LoadState().SaveInstances(json.RawMessage(`[{"title":"secret-instance"}]`)))
+		encrypted, err := os.ReadFile(statePath)
+		require.NoError(t, err)
+
+		// Another key, as if the right one were locked away.
+		t.Setenv(StateKeyEnv, base64.StdEncoding.EncodeToString(make([]byte, 32)))
+		for i := 0; i < 2; i++ {
+			unreadable := LoadState()
+			assert.Error(t, unreadable.LoadError())
+			assert.JSONEq(t, `[]`, string(unreadable.GetInstances()))
+			assert.Error(t, unreadable.SaveInstances(json.RawMessage(`[]`)))
+		}
+		data, err := os.ReadFile(statePath)
+		require.NoError(t, err)
+		assert.Equal(t, encrypted, data)
+	})
+}
+
+func TestDecodeStateKey(t *testing.T) {
+	_, err := decodeStateKey("c2hvcnQ=")
+	assert.Error(t, err)
+
+	key := make([]byte, 32)
+	ciphertext, err := encrypt(key, []byte("plaintext"))
+	require.NoError(t, err)
+	plaintext, err := decrypt(key, ciphertext)
+	require.NoError(t, err)
+	assert.Equal(t, "plaintext", string(plaintext))
+
+	key[0] = 1
+	_, err = decrypt(key, ciphertext)
+	assert.Error(t, err)
+}
+
+func TestTriggeredEvents(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	state := LoadState()
+	assert.False(t, state.IsTriggered("agent/issue/12"))
+	require.NoError(t, state.SetTriggered("agent/issue/12"))
+	require.NoError(t, state.SetTriggered("agent/issue/12"))
+	assert.True(t, LoadState().IsTriggered("agent/issue/12"))
+	assert.Len(t, LoadState().TriggeredEvents, 1)
+
+	// The oldest events are forgotten first.
+	for i := 0; i < maxTriggeredEvents; i++ {
+		state.TriggeredEvents = append(state.TriggeredEvents, "nightly/run/"+strconv.Itoa(i))
+	}
+	require.NoError(t, state.SetTriggered("nightly/run/new"))
+	assert.Len(t, state.TriggeredEvents, maxTriggeredEvents)
+	assert.False(t, state.IsTriggered("agent/issue/12"))
+	assert.True(t, state.IsTriggered("nightly/run/new"))
+}
+
+func TestConcurrentStateSaves(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	require.NoError(t, SaveConfig(DefaultConfig()))
+
+	// Two processes, e.g. two apps, load the same state.
+	first, second := LoadState(), LoadState()
+	require.NoError(t, first.SaveInstances(json.RawMessage(`[{"title":"first"}]`)))
+	require.NoError(t, second.SaveInstances(json.RawMessage(`[{"title":"second"}]`)))
+	require.NoError(t, first.SetHelpScreensSeen(1))
+	require.NoError(t, second.SetHelpScreensSeen(2))
+
+	loaded := LoadState()
+	assert.JSONEq(t, `[{"title":"second"},{"title":"first"}]`, string(loaded.GetInstances()))
+	assert.Equal(t, uint32(3), loaded.GetHelpScreensSeen())
+
+	// A theme picked in one app isn't unpicked by the other saving.
+	require.NoError(t, first.SetTheme("light"))
+	require.NoError(t, second.SaveInstances(json.RawMessage(`[{"title":"second"},{"title":"first"}]`)))
+	assert.Equal(t, "light", LoadState().GetTheme())
+
+	// An instance a process removes stays removed, while the other's are kept.
+	require.NoError(t, first.SaveInstances(json.RawMessage(`[]`)))
+	loaded = LoadState()
+	assert.JSONEq(t, `[{"title":"second"}]`, string(loaded.GetInstances()))
+	assert.Equal(t, first.Version, loaded.Version)
+
+	// An instance the other process removed isn't brought back by one still holding it.
+	third := LoadState()
+	require.NoError(t, second.SaveInstances(json.RawMessage(`[]`)))
+	require.NoError(t, third.SaveInstances(json.RawMessage(`[{"title":"second"},{"title":"third"}]`)))
+	assert.JSONEq(t, `[{"title":"third"}]`, string(LoadState().GetInstances()))
+
+	// The changes of the other process to an instance this one didn't change are kept, while an instance
+	// both changed is a conflict, keeping the changes saved first.
+	first, second = LoadState(), LoadState()
+	require.NoError(t, first.SaveInstances(json.RawMessage(`[{"title":"third","status":1}]`)))
+	require.NoError(t, second.SetHelpScreensSeen(4))
+	assert.JSONEq(t, `[{"title":"third","status":1}]`, string(LoadState().GetInstances()))
+	err := second.SaveInstances(json.RawMessage(`[{"title":"third","status":2}]`))
+	var conflict *StateConflictError
+	require.ErrorAs(t, err, &conflict)
+	assert.Equal(t, []string{"third"}, conflict.Titles)
+	assert.JSONEq(t, `[{"title":"third","status":1}]`, string(LoadState().GetInstances()))
+
+	entries, err := os.ReadDir(filepath.Join(os.Getenv("HOME"), ".claude-squad"))
+	require.NoError(t, err)
+	for _, entry := range entries {
+		assert.NotContains(t, entry.Name(), ".tmp", "temporary files are renamed or removed")
+	}
+}
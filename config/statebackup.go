@@ -0,0 +1,227 @@
+package config
+
+import (
+	"claude-squad/log"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// StateBackupDirName is the directory of the config directory the backups of the state file are kept in.
+	StateBackupDirName = "state-backups"
+	// stateBackupInterval is how often the state file is backed up while it's saved.
+	stateBackupInterval = 10 * time.Minute
+	// stateBackupTimeFormat names the backups after when they were taken, so they sort by time.
+	stateBackupTimeFormat = "20060102-150405"
+)
+
+// StateBackup is a backup of the state file.
+type StateBackup struct {
+	// Path is where the backup is.
+	Path string
+	// Time is when the state file was backed up.
+	Time time.Time
+	// Instances is the number of instances in the backup.
+	Instances int
+	// Err is why the backup can't be read, if it can't.
+	Err error
+}
+
+// getStateBackupDir returns the directory the backups of the state file are kept in.
+func getStateBackupDir() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get config directory: %w", err)
+	}
+	return filepath.Join(configDir, StateBackupDirName), nil
+}
+
+// backupStateFile copies the state file into the backup directory, if the last backup is older than
+// stateBackupInterval, and deletes the oldest backups past keep, none if keep is 0. The state file must be
+// locked and readable, so a corrupted one never replaces a good backup.
+func backupStateFile(statePath string, keep int, now time.Time) error {
+	dir, err := getStateBackupDir()
+	if err != nil {
+		return err
+	}
+	backups, err := stateBackupPaths(dir)
+	if err != nil {
+		return err
+	}
+	if len(backups) > 0 {
+		if last, ok := stateBackupTime(backups[0]); ok && now.Sub(last) < stateBackupInterval {
+			return nil
+		}
+	}
+
+	data, err := os.ReadFile(statePath)
+	if err != nil {
+		return fmt.Errorf("failed to read state file: %w", err)
+	}
+	path, err := writeStateBackup(dir, data, now)
+	if err != nil {
+		return err
+	}
+
+	if keep == 0 {
+		return nil
+	}
+	backups = append([]string{path}, backups...)
+	for _, old := range backups[min(keep, len(backups)):] {
+		if err := os.Remove(old); err != nil {
+			log.WarningLog.Printf("failed to delete old state backup %s: %v", old, err)
+		}
+	}
+	return nil
+}
+
+// writeStateBackup writes data, the content of the state file, as a backup taken at now into dir, and
+// returns its path.
+func writeStateBackup(dir string, data []byte, now time.Time) (string, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create state backup directory: %w", err)
+	}
+	// A backup taken in the same second, e.g. by a restore, isn't overwritten.
+	path := unusedPath(filepath.Join(dir, stateBackupPrefix()), now, ".json")
+	if err := writeFileAtomic(path, data, 0600); err != nil {
+		return "", fmt.Errorf("failed to back up state file: %w", err)
+	}
+	return path, nil
+}
+
+// unusedPath returns the path of prefix followed by now and the suffix, or by the first second after now
+// whose path doesn't exist yet.
+func unusedPath(prefix string, now time.Time, suffix string) string {
+	for {
+		path := prefix + now.Format(stateBackupTimeFormat) + suffix
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return path
+		}
+		now = now.Add(time.Second)
+	}
+}
+
+// stateBackupPrefix is the prefix of the names of the backups, before the time they were taken.
+func stateBackupPrefix() string {
+	return strings.TrimSuffix(StateFileName, filepath.Ext(StateFileName)) + "-"
+}
+
+// stateBackupPaths returns the paths of the backups in dir, newest first.
+func stateBackupPaths(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to list state backups: %w", err)
+	}
+	var paths []string
+	for _, entry := range entries {
+		if _, ok := stateBackupTime(entry.Name()); ok {
+			paths = append(paths, filepath.Join(dir, entry.Name()))
+		}
+	}
+	slices.Sort(paths)
+	slices.Reverse(paths)
+	return paths, nil
+}
+
+// stateBackupTime returns when the backup at path was taken, from its name.
+func stateBackupTime(path string) (time.Time, bool) {
+	name := filepath.Base(path)
+	stamp, ok := strings.CutPrefix(name, stateBackupPrefix())
+	if !ok || !strings.HasSuffix(stamp, ".json") {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation(stateBackupTimeFormat, strings.TrimSuffix(stamp, ".json"), time.Local)
+	return t, err == nil
+}
+
+// ListStateBackups returns the backups of the state file, newest first, with the number of instances in
+// each.
+func ListStateBackups() ([]StateBackup, error) {
+	dir, err := getStateBackupDir()
+	if err != nil {
+		return nil, err
+	}
+	paths, err := stateBackupPaths(dir)
+	if err != nil {
+		return nil, err
+	}
+	backups := make([]StateBackup, len(paths))
+	for i, path := range paths {
+		backups[i].Path = path
+		backups[i].Time, _ = stateBackupTime(path)
+		state, err := readStateBackup(path)
+		if err != nil {
+			backups[i].Err = err
+			continue
+		}
+		var instances []json.RawMessage
+		if err := json.Unmarshal(state.InstancesData, &instances); err != nil {
+			backups[i].Err = fmt.Errorf("failed to parse the instances: %w", err)
+			continue
+		}
+		backups[i].Instances = len(instances)
+	}
+	return backups, nil
+}
+
+// readStateBackup reads and parses the backup at path.
+func readStateBackup(path string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if data, err = decryptState(data); err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s: %w", path, err)
+	}
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &state, nil
+}
+
+// RestoreState replaces the state file with the backup. The state file it replaces is kept as a backup
+// itself, so restoring can be undone, unless it's missing.
+func RestoreState(backup StateBackup) error {
+	if _, err := readStateBackup(backup.Path); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(backup.Path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", backup.Path, err)
+	}
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return fmt.Errorf("failed to get config directory: %w", err)
+	}
+	statePath := filepath.Join(configDir, StateFileName)
+	unlock, err := lockState(statePath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if current, err := os.ReadFile(statePath); err == nil {
+		dir, err := getStateBackupDir()
+		if err != nil {
+			return err
+		}
+		if _, err := writeStateBackup(dir, current, time.Now()); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read state file: %w", err)
+	}
+	perm := os.FileMode(0644)
+	if LoadConfig().EncryptState {
+		perm = 0600
+	}
+	return writeFileAtomic(statePath, data, perm)
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupStateFile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	require.NoError(t, SaveConfig(DefaultConfig()))
+	configDir, err := GetConfigDir()
+	require.NoError(t, err)
+	statePath := filepath.Join(configDir, StateFileName)
+	require.NoError(t, os.WriteFile(statePath, []byte(`{"instances": [{"title": "a"}]}`), 0644))
+
+	now := time.Date(2030, 1, 1, 12, 0, 0, 0, time.Local)
+	require.NoError(t, backupStateFile(statePath, 2, now))
+	// The state file isn't backed up again until the interval passed.
+	require.NoError(t, backupStateFile(statePath, 2, now.Add(time.Minute)))
+	backups, err := ListStateBackups()
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	assert.Equal(t, now, backups[0].Time)
+	assert.Equal(t, 1, backups[0].Instances)
+
+	// The oldest backups past the limit are deleted.
+	require.NoError(t, os.WriteFile(statePath, []byte(`{"instances": [{"title": "a"}, {"title": "b"}]}`), 0644))
+	require.NoError(t, backupStateFile(statePath, 2, now.Add(stateBackupInterval)))
+	require.NoError(t, backupStateFile(statePath, 2, now.Add(2*stateBackupInterval)))
+	backups, err = ListStateBackups()
+	require.NoError(t, err)
+	require.Len(t, backups, 2)
+	assert.Equal(t, now.Add(2*stateBackupInterval), backups[0].Time)
+	assert.Equal(t, 2, backups[1].Instances)
+
+	// With keep 0, as after the state file was corrupted, none are deleted.
+	require.NoError(t, backupStateFile(statePath, 0, now.Add(3*stateBackupInterval)))
+	backups, err = ListStateBackups()
+	require.NoError(t, err)
+	assert.Len(t, backups, 3)
+}
+
+func TestCorruptedStateKeepsBackups(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	require.NoError(t, SaveConfig(DefaultConfig()))
+	configDir, err := GetConfigDir()
+	require.NoError(t, err)
+	statePath := filepath.Join(configDir, StateFileName)
+
+	backupDir := filepath.Join(configDir, StateBackupDirName)
+	for i := 0; i < defaultStateBackups; i++ {
+		_, err := writeStateBackup(backupDir, []byte(`{"instances": [{"title": "fix-login"}]}`),
+			time.Date(2000, 1, 1, 0, 0, i, 0, time.Local))
+		require.NoError(t, err)
+	}
+
+	// Corrupted twice, each corrupted file is kept.
+	for i := 0; i < 2; i++ {
+		require.NoError(t, os.WriteFile(statePath, []byte(`{"instances": [`), 0644))
+		state := LoadState()
+		assert.JSONEq(t, `[]`, string(state.GetInstances()))
+		// Saving the empty state backs it up without deleting the backups of the lost instances.
+		require.NoError(t, state.SaveInstances(json.RawMessage(`[]`)))
+		require.NoError(t, state.SaveInstances(json.RawMessage(`[{"title":"new"}]`)))
+	}
+	corrupted, err := filepath.Glob(statePath + ".corrupt-*")
+	require.NoError(t, err)
+	assert.Len(t, corrupted, 2)
+	backups, err := ListStateBackups()
+	require.NoError(t, err)
+	require.Len(t, backups, defaultStateBackups+1)
+	assert.Equal(t, 0, backups[0].Instances)
+	for _, backup := range backups[1:] {
+		assert.Equal(t, 1, backup.Instances)
+	}
+}
+
+func TestRestoreState(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	require.NoError(t, SaveConfig(DefaultConfig()))
+	configDir, err := GetConfigDir()
+	require.NoError(t, err)
+	statePath := filepath.Join(configDir, StateFileName)
+
+	state := LoadState()
+	require.NoError(t, state.SaveInstances(json.RawMessage(`[{"title":"fix-login"}]`)))
+	// Once the interval passed since the last backup, which is simulated by deleting it, saving backs up
+	// the state file as it was before.
+	require.NoError(t, os.RemoveAll(filepath.Join(configDir, StateBackupDirName)))
+	require.NoError(t, state.SaveInstances(json.RawMessage(`[{"title":"fix-login"},{"title":"refactor"}]`)))
+	backups, err := ListStateBackups()
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	assert.Equal(t, 1, backups[0].Instances)
+
+	// An unclean shutdown corrupts the state file, which is moved aside as it's loaded.
+	require.NoError(t, os.WriteFile(statePath, []byte(`{"instances": [{"title": "fix-`), 0644))
+	assert.JSONEq(t, `[]`, string(LoadState().GetInstances()))
+	corrupted, err := filepath.Glob(statePath + ".corrupt-*")
+	require.NoError(t, err)
+	assert.Len(t, corrupted, 1)
+
+	// A corrupted state file is never backed up, so the good backup is kept.
+	require.NoError(t, os.WriteFile(statePath, []byte(`{"instances": [`), 0644))
+	require.NoError(t, state.SaveInstances(json.RawMessage(`[]`)))
+	backups, err = ListStateBackups()
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+
+	require.NoError(t, RestoreState(backups[0]))
+	assert.JSONEq(t, `[{"title":"fix-login"}]`, string(LoadState().GetInstances()))
+	// The state file it replaced is backed up.
+	backups, err = ListStateBackups()
+	require.NoError(t, err)
+	assert.Len(t, backups, 2)
+}
@@ -0,0 +1,231 @@
+package config
+
+import (
+	"bytes"
+	"claude-squad/log"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// lockState takes the lock of the state file, so processes saving the state at the same time, like two
+// apps or an app and the daemon, merge their changes one after the other. It returns the function
+// releasing the lock.
+func lockState(statePath string) (func(), error) {
+	return LockPath(statePath)
+}
+
+// LockPath takes the lock of the file at path, a ".lock" file next to it, so processes updating the file
+// at the same time do it one after the other. It returns the function releasing the lock.
+func LockPath(path string) (func(), error) {
+	name := filepath.Base(path)
+	f, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the lock of %s: %w", name, err)
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock %s: %w", name, err)
+	}
+	return func() {
+		if err := unlockFile(f); err != nil {
+			log.WarningLog.Printf("failed to unlock %s: %v", name, err)
+		}
+		f.Close()
+	}, nil
+}
+
+// readStateFile returns the state on disk, or nil if there is none or it can't be read.
+func readStateFile(statePath string) *State {
+	data, err := os.ReadFile(statePath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.WarningLog.Printf("failed to read state file: %v", err)
+		}
+		return nil
+	}
+	if data, err = decryptState(data); err != nil {
+		log.WarningLog.Printf("failed to decrypt state file: %v", err)
+		return nil
+	}
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		log.WarningLog.Printf("failed to parse state file: %v", err)
+		return nil
+	}
+	return &state
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it over path, so readers
+// never see a partly written file.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmpPath := f.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to sync temporary file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file: %w", err)
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return fmt.Errorf("failed to set permissions of temporary file: %w", err)
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// StateConflictError is returned when the state was saved with instances that another process changed
+// too since this one loaded or saved them. The changes of the other process are kept.
+type StateConflictError struct {
+	// Titles are the titles of the instances changed by both.
+	Titles []string
+}
+
+func (e *StateConflictError) Error() string {
+	return fmt.Sprintf("%s changed in another claude-squad process too, kept its changes", strings.Join(e.Titles, ", "))
+}
+
+// mergeState merges the state file into ours: the instances, see mergeEntries, and the rest if another
+// process saved it since this one last loaded or saved it. It returns the titles of the instances both changed.
+func mergeState(ours *State, theirs *State) (State, []string) {
+	merged := *ours
+	var conflicts, archivedConflicts []string
+	merged.InstancesData, conflicts = mergeEntries(ours.InstancesData, theirs.InstancesData, ours.knownInstances)
+	merged.ArchivedData, archivedConflicts = mergeEntries(ours.ArchivedData, theirs.ArchivedData, ours.knownArchived)
+	for _, key := range archivedConflicts {
+		// The key of an archived instance ends with when it was archived.
+		conflicts = append(conflicts, key[:strings.LastIndex(key, "@")])
+	}
+	if ours.Version == theirs.Version {
+		// The rest of the state file is ours if no other process saved it since.
+		return merged, conflicts
+	}
+
+	merged.HelpScreensSeen = ours.HelpScreensSeen | theirs.HelpScreensSeen
+	// A process that loaded the state before a theme was picked doesn't unpick it.
+	if merged.Theme == "" {
+		merged.Theme = theirs.Theme
+	}
+	merged.TriggeredEvents = slices.Clone(ours.TriggeredEvents)
+	for _, event := range theirs.TriggeredEvents {
+		if !slices.Contains(merged.TriggeredEvents, event) {
+			merged.TriggeredEvents = append(merged.TriggeredEvents, event)
+		}
+	}
+	if len(merged.TriggeredEvents) > maxTriggeredEvents {
+		merged.TriggeredEvents = merged.TriggeredEvents[len(merged.TriggeredEvents)-maxTriggeredEvents:]
+	}
+	return merged, conflicts
+}
+
+// mergeEntries merges the entries of theirs into ours, known being the entries as we last loaded or saved
+// them:
+//   - theirs that we don't know of were added by the other process and are kept,
+//   - the ones we knew of but that aren't in theirs anymore were removed by the other process, and the
+//     ones that aren't in ours anymore were removed by us, so they're dropped,
+//   - the ones we knew of and didn't change since are theirs, which the other process may have changed,
+//   - the ones both changed are conflicts. Theirs are kept, and their keys returned.
+func mergeEntries(ours, theirs json.RawMessage, known map[string]string) (json.RawMessage, []string) {
+	theirsByKey := make(map[string]json.RawMessage)
+	var theirsOrder []string
+	for _, entry := range unmarshalEntries(theirs) {
+		key := entryKey(entry)
+		theirsByKey[key] = entry
+		theirsOrder = append(theirsOrder, key)
+	}
+
+	var merged []json.RawMessage
+	var conflicts []string
+	inOurs := make(map[string]bool)
+	for _, entry := range unmarshalEntries(ours) {
+		key := entryKey(entry)
+		inOurs[key] = true
+		theirEntry, inTheirs := theirsByKey[key]
+		base, isKnown := known[key]
+		switch {
+		case isKnown && !inTheirs:
+			// Removed by the other process, e.g. killed or archived.
+			continue
+		case !inTheirs:
+			merged = append(merged, entry)
+		case compactEntry(entry) == base:
+			merged = append(merged, theirEntry)
+		case compactEntry(theirEntry) == base || compactEntry(theirEntry) == compactEntry(entry):
+			merged = append(merged, entry)
+		default:
+			conflicts = append(conflicts, key)
+			merged = append(merged, theirEntry)
+		}
+	}
+	for _, key := range theirsOrder {
+		if _, isKnown := known[key]; !inOurs[key] && !isKnown {
+			merged = append(merged, theirsByKey[key])
+		}
+	}
+
+	if merged == nil {
+		merged = []json.RawMessage{}
+	}
+	data, err := json.Marshal(merged)
+	if err != nil {
+		log.ErrorLog.Printf("failed to marshal merged instances: %v", err)
+		return ours, nil
+	}
+	return data, conflicts
+}
+
+// entryVersions returns the stored instances in data by their keys, compacted to be compared.
+func entryVersions(data json.RawMessage) map[string]string {
+	versions := make(map[string]string)
+	for _, entry := range unmarshalEntries(data) {
+		versions[entryKey(entry)] = compactEntry(entry)
+	}
+	return versions
+}
+
+// compactEntry returns the stored instance without its whitespace, which indenting the state file adds.
+func compactEntry(entry json.RawMessage) string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, entry); err != nil {
+		return string(entry)
+	}
+	return buf.String()
+}
+
+func unmarshalEntries(data json.RawMessage) []json.RawMessage {
+	if len(data) == 0 {
+		return nil
+	}
+	var entries []json.RawMessage
+	if err := json.Unmarshal(data, &entries); err != nil {
+		log.WarningLog.Printf("failed to parse stored instances: %v", err)
+		return nil
+	}
+	return entries
+}
+
+// entryKey identifies a stored instance by its title, and an archived one by when it was archived too,
+// since an instance can be archived more than once.
+func entryKey(entry json.RawMessage) string {
+	var id struct {
+		Title      string `json:"title"`
+		ArchivedAt string `json:"archived_at"`
+	}
+	_ = json.Unmarshal(entry, &id)
+	if id.ArchivedAt == "" {
+		return id.Title
+	}
+	return id.Title + "@" + id.ArchivedAt
+}
@@ -0,0 +1,243 @@
+package daemon
+
+import (
+	"claude-squad/config"
+	"claude-squad/session"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	// defaultPageSize is how many instances a page of /instances has unless limit is set.
+	defaultPageSize = 50
+	// maxPageSize bounds the limit of a page of /instances.
+	maxPageSize = 500
+)
+
+// instanceFilters are the query parameters /instances filters on, and the field of an instance each
+// one matches.
+var instanceFilters = map[string]func(s instanceSummary) string{
+	"status":  func(s instanceSummary) string { return s.Status },
+	"repo":    func(s instanceSummary) string { return s.Repo },
+	"program": func(s instanceSummary) string { return s.Program },
+	"group":   func(s instanceSummary) string { return s.Group },
+	"host":    func(s instanceSummary) string { return s.Host },
+}
+
+// instanceSorts are the values of the sort parameter of /instances, and the key of an instance each one
+// sorts by. Keys compare as strings.
+var instanceSorts = map[string]func(s instanceSummary) string{
+	"created": func(s instanceSummary) string { return sortableTime(s.CreatedAt) },
+	"updated": func(s instanceSummary) string { return sortableTime(s.UpdatedAt) },
+	"title":   func(s instanceSummary) string { return s.Title },
+}
+
+// instanceSummary is an instance, running or archived, as listed by /instances.
+type instanceSummary struct {
+	// ID identifies the instance across pages. Archived instances may share the title of another one.
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Status    string    `json:"status"`
+	Repo      string    `json:"repo"`
+	Branch    string    `json:"branch"`
+	Program   string    `json:"program"`
+	Group     string    `json:"group,omitempty"`
+	Host      string    `json:"host,omitempty"`
+	Added     int       `json:"added"`
+	Removed   int       `json:"removed"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// instancePage is a page of /instances.
+type instancePage struct {
+	Instances []instanceSummary `json:"instances"`
+	// NextCursor is passed as cursor to get the next page. It's empty on the last page.
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// pageCursor is the position after the last instance of a page: its sort key and its ID. New instances
+// don't shift the pages that follow, since they're found by key rather than by offset.
+type pageCursor struct {
+	Key string `json:"k"`
+	ID  string `json:"i"`
+}
+
+// summarizeInstances returns the summaries of the instances and the archived instances.
+func summarizeInstances(instances []*session.Instance, archived []session.ArchivedInstance) []instanceSummary {
+	summaries := make([]instanceSummary, 0, len(instances)+len(archived))
+	for _, instance := range instances {
+		data := instance.ToInstanceData()
+		// ToInstanceData stamps the current time.
+		data.UpdatedAt = instance.UpdatedAt
+		summary := newInstanceSummary(data)
+		summary.ID = "instance/" + data.Title
+		summaries = append(summaries, summary)
+	}
+	for _, a := range archived {
+		summary := newInstanceSummary(a.InstanceData)
+		summary.ID = fmt.Sprintf("archive/%s/%d", a.Title, a.ArchivedAt.UnixNano())
+		summary.Status = "archived"
+		summary.UpdatedAt = a.ArchivedAt
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
+
+func newInstanceSummary(data session.InstanceData) instanceSummary {
+	summary := instanceSummary{
+		Title:     data.Title,
+		Status:    data.Status.String(),
+		Branch:    data.Branch,
+		Program:   data.Program,
+		Group:     data.Group,
+		Host:      data.Host,
+		Added:     data.DiffStats.Added,
+		Removed:   data.DiffStats.Removed,
+		CreatedAt: data.CreatedAt,
+		UpdatedAt: data.UpdatedAt,
+	}
+	if data.Worktree.RepoPath != "" {
+		summary.Repo = filepath.Base(data.Worktree.RepoPath)
+	}
+	return summary
+}
+
+// listInstances returns the page of the instances selected by the query: the filters, sort (created,
+// updated or title), order (asc or desc, newest or A to Z first by default), limit and cursor.
+func listInstances(summaries []instanceSummary, query url.Values) (instancePage, error) {
+	filters := make(map[string]string)
+	for param, values := range query {
+		switch param {
+		case "sort", "order", "limit", "cursor":
+			continue
+		}
+		if _, ok := instanceFilters[param]; !ok {
+			return instancePage{}, fmt.Errorf("unknown parameter %q", param)
+		}
+		filters[param] = values[0]
+	}
+
+	sortBy := query.Get("sort")
+	if sortBy == "" {
+		sortBy = "created"
+	}
+	key, ok := instanceSorts[sortBy]
+	if !ok {
+		return instancePage{}, fmt.Errorf("cannot sort by %q", sortBy)
+	}
+	desc := sortBy != "title"
+	switch query.Get("order") {
+	case "":
+	case "asc":
+		desc = false
+	case "desc":
+		desc = true
+	default:
+		return instancePage{}, fmt.Errorf("invalid order %q", query.Get("order"))
+	}
+
+	limit := defaultPageSize
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxPageSize {
+			return instancePage{}, fmt.Errorf("limit must be between 1 and %d", maxPageSize)
+		}
+		limit = n
+	}
+
+	// before returns true if a comes before b in the requested order.
+	before := func(aKey, aID, bKey, bID string) bool {
+		if aKey != bKey {
+			return (aKey < bKey) != desc
+		}
+		return aID != bID && (aID < bID) != desc
+	}
+
+	var after *pageCursor
+	if value := query.Get("cursor"); value != "" {
+		cursor, err := decodeCursor(value)
+		if err != nil {
+			return instancePage{}, err
+		}
+		after = &cursor
+	}
+
+	var selected []instanceSummary
+	for _, summary := range summaries {
+		matches := true
+		for param, value := range filters {
+			if instanceFilters[param](summary) != value {
+				matches = false
+				break
+			}
+		}
+		if !matches {
+			continue
+		}
+		if after != nil && !before(after.Key, after.ID, key(summary), summary.ID) {
+			continue
+		}
+		selected = append(selected, summary)
+	}
+	sort.Slice(selected, func(i, j int) bool {
+		return before(key(selected[i]), selected[i].ID, key(selected[j]), selected[j].ID)
+	})
+
+	page := instancePage{Instances: selected}
+	if len(selected) > limit {
+		page.Instances = selected[:limit]
+		last := page.Instances[limit-1]
+		page.NextCursor = encodeCursor(pageCursor{Key: key(last), ID: last.ID})
+	}
+	if page.Instances == nil {
+		page.Instances = []instanceSummary{}
+	}
+	return page, nil
+}
+
+// handleInstances serves /instances with the summaries returned by list that the token can access.
+func handleInstances(list func() ([]instanceSummary, error)) func(w http.ResponseWriter, r *http.Request, token *config.APIToken) {
+	return func(w http.ResponseWriter, r *http.Request, token *config.APIToken) {
+		summaries, err := list()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		summaries = slices.DeleteFunc(summaries, func(s instanceSummary) bool { return !allowsRepo(token, s.Repo) })
+		page, err := listInstances(summaries, r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(page)
+	}
+}
+
+// sortableTime formats t so that times compare as strings.
+func sortableTime(t time.Time) string {
+	return fmt.Sprintf("%020d", t.UnixNano())
+}
+
+func encodeCursor(cursor pageCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(value string) (pageCursor, error) {
+	var cursor pageCursor
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || json.Unmarshal(data, &cursor) != nil {
+		return pageCursor{}, fmt.Errorf("invalid cursor")
+	}
+	return cursor, nil
+}
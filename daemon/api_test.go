@@ -0,0 +1,116 @@
+package daemon
+
+import (
+	"claude-squad/config"
+	"claude-squad/session"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testSummaries() []instanceSummary {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	archived := []session.ArchivedInstance{{
+		InstanceData: session.InstanceData{Title: "fix login", Program: "claude", CreatedAt: start,
+			Worktree: session.GitWorktreeData{RepoPath: "/src/web"}},
+		ArchivedAt: start.Add(time.Hour),
+	}}
+	summaries := summarizeInstances(nil, archived)
+	for i, title := range []string{"fix login", "dark mode", "docs", "api client"} {
+		summary := newInstanceSummary(session.InstanceData{
+			Title:     title,
+			Status:    session.Running,
+			Program:   "claude",
+			CreatedAt: start.Add(time.Duration(i+1) * time.Minute),
+			Worktree:  session.GitWorktreeData{RepoPath: "/src/web"},
+		})
+		summary.ID = "instance/" + title
+		if title == "docs" {
+			summary.Program = "aider"
+			summary.Repo = "site"
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
+
+func titles(page instancePage) []string {
+	var result []string
+	for _, s := range page.Instances {
+		result = append(result, s.Title)
+	}
+	return result
+}
+
+func TestListInstances(t *testing.T) {
+	summaries := testSummaries()
+
+	page, err := listInstances(summaries, url.Values{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"api client", "docs", "dark mode", "fix login", "fix login"}, titles(page))
+	assert.Equal(t, "archived", page.Instances[4].Status)
+	assert.Empty(t, page.NextCursor)
+
+	page, err = listInstances(summaries, url.Values{"repo": {"web"}, "status": {"running"}, "sort": {"title"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"api client", "dark mode", "fix login"}, titles(page))
+
+	page, err = listInstances(summaries, url.Values{"program": {"claude"}, "status": {"running"}, "order": {"asc"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"fix login", "dark mode", "api client"}, titles(page))
+
+	for _, query := range []url.Values{{"owner": {"me"}}, {"sort": {"size"}}, {"limit": {"0"}}, {"order": {"up"}}, {"cursor": {"!"}}} {
+		_, err = listInstances(summaries, query)
+		assert.Error(t, err, query)
+	}
+}
+
+func TestListInstancesPages(t *testing.T) {
+	summaries := testSummaries()
+
+	page, err := listInstances(summaries, url.Values{"limit": {"2"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"api client", "docs"}, titles(page))
+	require.NotEmpty(t, page.NextCursor)
+
+	// A new instance doesn't shift the next pages.
+	summaries = append(summaries, instanceSummary{ID: "instance/new", Title: "new", CreatedAt: time.Now()})
+	page, err = listInstances(summaries, url.Values{"limit": {"2"}, "cursor": {page.NextCursor}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dark mode", "fix login"}, titles(page))
+
+	page, err = listInstances(summaries, url.Values{"limit": {"2"}, "cursor": {page.NextCursor}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"fix login"}, titles(page))
+	assert.Equal(t, "archived", page.Instances[0].Status)
+	assert.Empty(t, page.NextCursor)
+}
+
+func TestHandleInstances(t *testing.T) {
+	handler := handleInstances(func() ([]instanceSummary, error) { return testSummaries(), nil })
+
+	recorder := httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest(http.MethodGet, "/instances?program=aider", nil), nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var page instancePage
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &page))
+	assert.Equal(t, []string{"docs"}, titles(page))
+
+	recorder = httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest(http.MethodGet, "/instances?tag=x", nil), nil)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	// A token limited to a repository only sees its instances.
+	recorder = httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest(http.MethodGet, "/instances", nil),
+		&config.APIToken{Name: "site", Scopes: []string{config.ScopeRead}, Repos: []string{"site"}})
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &page))
+	assert.Equal(t, []string{"docs"}, titles(page))
+}
@@ -0,0 +1,278 @@
+package daemon
+
+import (
+	"claude-squad/config"
+	"claude-squad/log"
+	"claude-squad/session"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// maxPromptSize bounds the body of a prompt sent through the API.
+const maxPromptSize = 1 << 20
+
+// errInstanceNotFound is returned for the instances that don't exist, and the ones the token can't access.
+var errInstanceNotFound = errors.New("instance not found")
+
+// authorize serves handler to the requests whose bearer token has scope, passing the token along.
+// Without tokens, the read scope is served to anyone and the others to no one, as the API was read-only
+// before it had tokens.
+func authorize(tokens []config.APIToken, scope string, handler func(w http.ResponseWriter, r *http.Request, token *config.APIToken)) http.HandlerFunc {
+	return authorizeWith(tokens, scope, scope == config.ScopeRead, handler)
+}
+
+// authorizeContent serves handler to the requests whose bearer token has the read scope, like authorize,
+// but to no one without tokens, since it serves what the instances show, like prompts, code and the
+// secrets echoed in their terminals.
+func authorizeContent(tokens []config.APIToken, handler func(w http.ResponseWriter, r *http.Request, token *config.APIToken)) http.HandlerFunc {
+	return authorizeWith(tokens, config.ScopeRead, false, handler)
+}
+
+// authorizeWith serves handler to the requests whose bearer token has scope, and without tokens to
+// anyone if open is true and to no one otherwise.
+func authorizeWith(tokens []config.APIToken, scope string, open bool, handler func(w http.ResponseWriter, r *http.Request, token *config.APIToken)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if len(tokens) == 0 {
+			if !open {
+				http.Error(w, "api_tokens must be set to use this endpoint", http.StatusForbidden)
+				return
+			}
+			handler(w, r, nil)
+			return
+		}
+		token := findToken(tokens, r.Header.Get("Authorization"))
+		if token == nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="claude-squad"`)
+			http.Error(w, "missing or invalid token", http.StatusUnauthorized)
+			return
+		}
+		if !token.HasScope(scope) {
+			log.WarningLog.Printf("token %s was denied %s %s without the %s scope", token.Name, r.Method, r.URL.Path, scope)
+			http.Error(w, fmt.Sprintf("the token doesn't have the %s scope", scope), http.StatusForbidden)
+			return
+		}
+		handler(w, r, token)
+	}
+}
+
+// findToken returns the token sent in the Authorization header, if it's one of tokens.
+func findToken(tokens []config.APIToken, header string) *config.APIToken {
+	secret, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || secret == "" {
+		return nil
+	}
+	for i := range tokens {
+		if tokens[i].Token != "" && subtle.ConstantTimeCompare([]byte(tokens[i].Token), []byte(secret)) == 1 {
+			return &tokens[i]
+		}
+	}
+	return nil
+}
+
+// allowsRepo returns true if the token can access the instances of the repository. A nil token is the
+// access without tokens, which is read-only but not limited to repositories.
+func allowsRepo(token *config.APIToken, repo string) bool {
+	return token == nil || token.AllowsRepo(repo)
+}
+
+// allowsInstance returns true if the token can access the instance.
+func allowsInstance(token *config.APIToken, instance *session.Instance) bool {
+	repo, _ := instance.RepoName()
+	return allowsRepo(token, repo)
+}
+
+// control gives the API access to the instances the daemon polls.
+type control struct {
+	// mu guards the instances, which the daemon polls at the same time.
+	mu        *sync.Mutex
+	instances *[]*session.Instance
+	storage   *session.Storage
+	// handoffTemplates are the templates the handoffs between instances can use.
+	handoffTemplates []config.HandoffTemplate
+	// maxRunning is the cap of running instances, beyond which resumed instances are queued.
+	maxRunning int
+	// providers are checked for capacity before resuming instances.
+	providers []config.Provider
+}
+
+// withInstance runs fn on the instance with the title while holding the lock, if the token can access it.
+func (c *control) withInstance(title string, token *config.APIToken, fn func(instance *session.Instance) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, instance := range *c.instances {
+		if instance.Title == title && allowsInstance(token, instance) {
+			return fn(instance)
+		}
+	}
+	return errInstanceNotFound
+}
+
+// findInstance returns the instance with the title if the token can access it. The lock must be held.
+func (c *control) findInstance(title string, token *config.APIToken) *session.Instance {
+	for _, instance := range *c.instances {
+		if instance.Title == title && allowsInstance(token, instance) {
+			return instance
+		}
+	}
+	return nil
+}
+
+// register registers the endpoints of the instances on mux, each behind the scope it needs.
+func (c *control) register(mux *http.ServeMux, tokens []config.APIToken) {
+	mux.HandleFunc("GET /instances/{title}/preview", authorizeContent(tokens, c.handlePreview))
+	mux.HandleFunc("POST /instances/{title}/prompt", authorize(tokens, config.ScopePrompt, c.handlePrompt))
+	mux.HandleFunc("POST /instances/{title}/handoff", authorize(tokens, config.ScopePrompt, c.handleHandoff))
+	mux.HandleFunc("POST /instances/{title}/pause", authorize(tokens, config.ScopeLifecycle, c.handleLifecycle(func(instance *session.Instance) error {
+		return instance.Pause()
+	})))
+	mux.HandleFunc("POST /instances/{title}/resume", authorize(tokens, config.ScopeLifecycle, c.handleLifecycle(func(instance *session.Instance) error {
+		_, err := instance.ResumeOrQueue(c.maxRunning, c.providers, *c.instances)
+		return err
+	})))
+	mux.HandleFunc("DELETE /instances/{title}", authorize(tokens, config.ScopeLifecycle, c.handleKill))
+}
+
+func (c *control) handlePreview(w http.ResponseWriter, r *http.Request, token *config.APIToken) {
+	var content string
+	err := c.withInstance(r.PathValue("title"), token, func(instance *session.Instance) error {
+		if !instance.Started() || instance.Paused() {
+			return fmt.Errorf("instance '%s' is not running", instance.Title)
+		}
+		var err error
+		content, err = instance.Preview()
+		return err
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = io.WriteString(w, content)
+}
+
+func (c *control) handlePrompt(w http.ResponseWriter, r *http.Request, token *config.APIToken) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxPromptSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	prompt := strings.TrimSpace(string(body))
+	if prompt == "" {
+		http.Error(w, "the prompt is empty", http.StatusBadRequest)
+		return
+	}
+	err = c.withInstance(r.PathValue("title"), token, func(instance *session.Instance) error {
+		defer instance.ActAs(apiActor(token))()
+		return instance.SendPrompt(prompt)
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	log.InfoLog.Printf("token %s sent a prompt to %s", token.Name, r.PathValue("title"))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handoffRequest is the body of a handoff: the instance the content is handed off to, and the template
+// framing it.
+type handoffRequest struct {
+	To       string `json:"to"`
+	Template string `json:"template"`
+}
+
+// handleHandoff sends the content of the instance, e.g. its diff, to another one as a prompt framed by a
+// handoff template.
+func (c *control) handleHandoff(w http.ResponseWriter, r *http.Request, token *config.APIToken) {
+	var req handoffRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxPromptSize)).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid handoff: %v", err), http.StatusBadRequest)
+		return
+	}
+	tmpl, err := session.FindHandoffTemplate(c.handoffTemplates, req.Template)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	from, to := c.findInstance(r.PathValue("title"), token), c.findInstance(req.To, token)
+	if from == nil || to == nil {
+		writeError(w, errInstanceNotFound)
+		return
+	}
+	defer from.ActAs(apiActor(token))()
+	defer to.ActAs(apiActor(token))()
+	prompt, err := from.HandoffPrompt(to, tmpl)
+	if err == nil {
+		err = from.HandOff(to, tmpl.Name, prompt)
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	log.InfoLog.Printf("token %s handed off %s from %s to %s", token.Name, tmpl.Name, from.Title, to.Title)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleLifecycle serves an action that changes the state of an instance, then saves the instances.
+func (c *control) handleLifecycle(action func(instance *session.Instance) error) func(w http.ResponseWriter, r *http.Request, token *config.APIToken) {
+	return func(w http.ResponseWriter, r *http.Request, token *config.APIToken) {
+		err := c.withInstance(r.PathValue("title"), token, func(instance *session.Instance) error {
+			defer instance.ActAs(apiActor(token))()
+			if err := action(instance); err != nil {
+				return err
+			}
+			return c.storage.SaveInstances(*c.instances)
+		})
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		log.InfoLog.Printf("token %s ran %s %s", token.Name, r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// handleKill kills the instance. An instance with unsaved work is only killed with ?force=true.
+func (c *control) handleKill(w http.ResponseWriter, r *http.Request, token *config.APIToken) {
+	force := r.URL.Query().Get("force") == "true"
+	err := c.withInstance(r.PathValue("title"), token, func(instance *session.Instance) error {
+		defer instance.ActAs(apiActor(token))()
+		if err := instance.CheckKill(force); err != nil {
+			return err
+		}
+		if err := instance.Kill(); err != nil {
+			return err
+		}
+		*c.instances = slices.DeleteFunc(*c.instances, func(i *session.Instance) bool { return i == instance })
+		return c.storage.SaveInstances(*c.instances)
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	log.InfoLog.Printf("token %s killed %s", token.Name, r.PathValue("title"))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// apiActor is the actor the audit events caused by requests with the token are recorded as.
+func apiActor(token *config.APIToken) string {
+	return "api:" + token.Name
+}
+
+// writeError answers with the error of an action on an instance.
+func writeError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errInstanceNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusConflict)
+}
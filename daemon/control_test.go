@@ -0,0 +1,88 @@
+package daemon
+
+import (
+	"claude-squad/config"
+	"claude-squad/session"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestControlAPIScopes(t *testing.T) {
+	tokens := []config.APIToken{
+		{Name: "dashboard", Token: "read-token", Scopes: []string{config.ScopeRead}},
+		{Name: "orchestrator", Token: "prompt-token", Scopes: []string{config.ScopeRead, config.ScopePrompt}, Repos: []string{"web"}},
+		{Name: "ops", Token: "admin-token", Scopes: []string{config.ScopeAdmin}},
+	}
+	instances := []*session.Instance{{Title: "fix-login", Status: session.Running}}
+	var mu sync.Mutex
+	c := &control{mu: &mu, instances: &instances}
+	mux := http.NewServeMux()
+	c.register(mux, tokens)
+
+	request := func(method, path, token, body string) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodPost, "/instances/fix-login/prompt", "", "fix it"))
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodPost, "/instances/fix-login/prompt", "wrong", "fix it"))
+	assert.Equal(t, http.StatusForbidden, request(http.MethodPost, "/instances/fix-login/prompt", "read-token", "fix it"))
+	assert.Equal(t, http.StatusForbidden, request(http.MethodDelete, "/instances/fix-login", "prompt-token", ""))
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodPost, "/instances/fix-login/prompt", "prompt-token", " "))
+	// The instance isn't started, so it isn't in the repository of the orchestrator.
+	assert.Equal(t, http.StatusNotFound, request(http.MethodPost, "/instances/fix-login/prompt", "prompt-token", "fix it"))
+	assert.Equal(t, http.StatusNotFound, request(http.MethodGet, "/instances/fix-login/preview", "prompt-token", ""))
+	// The admin reaches it, but it can't take a prompt.
+	assert.Equal(t, http.StatusConflict, request(http.MethodPost, "/instances/fix-login/prompt", "admin-token", "fix it"))
+	assert.Equal(t, http.StatusNotFound, request(http.MethodPost, "/instances/docs/pause", "admin-token", ""))
+}
+
+func TestControlAPIHandoff(t *testing.T) {
+	tokens := []config.APIToken{{Name: "orchestrator", Token: "prompt-token", Scopes: []string{config.ScopePrompt}}}
+	instances := []*session.Instance{{Title: "dark-mode", Status: session.Running}, {Title: "reviewer", Status: session.Running}}
+	var mu sync.Mutex
+	c := &control{mu: &mu, instances: &instances, handoffTemplates: (&config.Config{}).GetHandoffTemplates()}
+	mux := http.NewServeMux()
+	c.register(mux, tokens)
+
+	request := func(body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/instances/dark-mode/handoff", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer prompt-token")
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	assert.Equal(t, http.StatusBadRequest, request("review"))
+	assert.Equal(t, http.StatusBadRequest, request(`{"to": "reviewer", "template": "missing"}`))
+	assert.Equal(t, http.StatusNotFound, request(`{"to": "docs", "template": "review"}`))
+	// dark-mode has no changes to hand off.
+	assert.Equal(t, http.StatusConflict, request(`{"to": "reviewer", "template": "review"}`))
+}
+
+func TestControlAPIWithoutTokens(t *testing.T) {
+	instances := []*session.Instance{{Title: "fix-login", Status: session.Running}}
+	var mu sync.Mutex
+	c := &control{mu: &mu, instances: &instances}
+	mux := http.NewServeMux()
+	c.register(mux, nil)
+
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/instances/fix-login/prompt", strings.NewReader("fix it")))
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+
+	// Previews show what's in the terminals, so they need a token too.
+	recorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/instances/fix-login/preview", nil))
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+}
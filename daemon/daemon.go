@@ -3,22 +3,37 @@ package daemon
 import (
 	"claude-squad/config"
 	"claude-squad/log"
+	"claude-squad/notify"
 	"claude-squad/session"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"regexp"
+	"runtime/debug"
+	"slices"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 )
 
+// erroredRetryInterval is how long the daemon leaves an instance alone after polling it panicked.
+const erroredRetryInterval = time.Minute
+
+// stopTimeout is how long StopDaemon waits for the daemon to save its state and exit before killing it.
+const stopTimeout = 5 * time.Second
+
 // RunDaemon runs the daemon process which iterates over all sessions and runs AutoYes mode on them.
 // It's expected that the main process kills the daemon when the main process starts.
 func RunDaemon(cfg *config.Config) error {
 	log.InfoLog.Printf("starting daemon")
 	state := config.LoadState()
+	if err := state.LoadError(); err != nil {
+		return err
+	}
 	storage, err := session.NewStorage(state)
 	if err != nil {
 		return fmt.Errorf("failed to initialize storage: %w", err)
@@ -34,30 +49,101 @@ func RunDaemon(cfg *config.Config) error {
 	}
 
 	pollInterval := time.Duration(cfg.DaemonPollInterval) * time.Millisecond
+	stuckPatterns, err := cfg.GetStuckPatterns()
+	if err != nil {
+		log.ErrorLog.Printf("failed to load stuck patterns: %v", err)
+	}
+	readyDetectors, err := cfg.GetReadyDetectors()
+	if err != nil {
+		log.ErrorLog.Printf("failed to load ready rules: %v", err)
+	}
+	policy, err := session.NewApprovalPolicy(cfg.AutoYesPolicy)
+	if err != nil {
+		log.ErrorLog.Printf("failed to load the auto-yes policy, holding every prompt: %v", err)
+	}
+
+	notifier, err := notify.New(cfg)
+	if err != nil {
+		log.ErrorLog.Printf("failed to load notify backends: %v", err)
+	}
 
 	// If we get an error for a session, it's likely that we'll keep getting the error. Log every 30 seconds.
 	everyN := log.NewEvery(60 * time.Second)
 
+	// mu guards the instances and the errors while they're polled, saved or measured.
+	var mu sync.Mutex
+	var errs daemonErrors
+	snapshot := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if err := storage.SaveInstances(instances); err != nil {
+			log.ErrorLog.Printf("failed to save instances: %v", err)
+		}
+	}
+
 	wg := &sync.WaitGroup{}
 	wg.Add(1)
 	stopCh := make(chan struct{})
 	go func() {
 		defer wg.Done()
 		ticker := time.NewTimer(pollInterval)
+		// erroredUntil is when the instances whose polling panicked are polled again.
+		erroredUntil := make(map[*session.Instance]time.Time)
+		lastBackup := time.Now()
+		lastPaneLog := time.Now()
+		lastRebase := time.Now()
 		for {
+			mu.Lock()
+			now := time.Now()
 			for _, instance := range instances {
-				// We only store started instances, but check anyway.
-				if instance.Started() && !instance.Paused() {
-					if _, hasPrompt := instance.HasUpdated(); hasPrompt {
-						instance.TapEnter()
-						if err := instance.UpdateDiffStats(); err != nil {
-							if everyN.ShouldLog() {
-								log.WarningLog.Printf("could not update diff stats for %s: %v", instance.Title, err)
-							}
-						}
+				if now.Before(erroredUntil[instance]) {
+					continue
+				}
+				err := recoverPanic(func() {
+					pollInstance(instance, cfg, stuckPatterns, readyDetectors, policy, notifier, everyN, &errs)
+				})
+				if err != nil {
+					errs.panics++
+					log.ErrorLog.Printf("polling %s failed, retrying in %s: %v", instance.Title, erroredRetryInterval, err)
+					erroredUntil[instance] = now.Add(erroredRetryInterval)
+				}
+			}
+			resumed, err := session.DispatchQueued(cfg.MaxRunningInstances, cfg.Providers, instances)
+			for _, instance := range resumed {
+				log.InfoLog.Printf("%s got a running slot", instance.Title)
+			}
+			if err != nil {
+				log.ErrorLog.Printf("failed to resume the instances queued for a running slot: %v", err)
+			}
+			dispatched, err := session.DispatchWaiting(cfg.ExternalResources, instances)
+			for _, instance := range dispatched {
+				log.InfoLog.Printf("%s got its external resources %s", instance.Title,
+					strings.Join(instance.ExternalResources, ", "))
+			}
+			if err != nil {
+				log.ErrorLog.Printf("failed to dispatch the instances waiting for external resources: %v", err)
+			}
+			if now.Sub(lastBackup) >= session.ConversationBackupInterval {
+				lastBackup = now
+				for _, instance := range instances {
+					if err := instance.BackupConversations(); err != nil && everyN.ShouldLog() {
+						log.WarningLog.Printf("could not back up the conversations of %s: %v", instance.Title, err)
 					}
 				}
 			}
+			if interval := time.Duration(cfg.AutoRebaseInterval) * time.Second; interval > 0 && now.Sub(lastRebase) >= interval {
+				lastRebase = now
+				rebasePaused(instances, everyN)
+			}
+			if cfg.PaneLog.Enabled && now.Sub(lastPaneLog) >= session.PaneLogInterval {
+				lastPaneLog = now
+				for _, instance := range instances {
+					if err := instance.AppendPaneLog(cfg.PaneLog, now); err != nil && everyN.ShouldLog() {
+						log.WarningLog.Printf("could not log the output of %s: %v", instance.Title, err)
+					}
+				}
+			}
+			mu.Unlock()
 
 			// Handle stop before ticker.
 			select {
@@ -71,19 +157,181 @@ func RunDaemon(cfg *config.Config) error {
 		}
 	}()
 
-	// Notify on SIGINT (Ctrl+C) and SIGTERM. Save instances before
+	if cfg.MetricsAddress != "" {
+		server := serveMetrics(cfg.MetricsAddress, cfg.APITokens, func(w io.Writer, token *config.APIToken) {
+			mu.Lock()
+			defer mu.Unlock()
+			allowed := slices.DeleteFunc(slices.Clone(instances), func(instance *session.Instance) bool {
+				return !allowsInstance(token, instance)
+			})
+			writeMetrics(w, allowed, errs)
+		}, func() ([]instanceSummary, error) {
+			// The app archives instances while the daemon runs, so they're read from the latest state.
+			latest, err := session.NewStorage(config.LoadState())
+			if err != nil {
+				return nil, err
+			}
+			archived, err := latest.ArchivedInstances()
+			if err != nil {
+				return nil, err
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			return summarizeInstances(instances, archived), nil
+		}, &control{mu: &mu, instances: &instances, storage: storage,
+			handoffTemplates: cfg.GetHandoffTemplates(), maxRunning: cfg.MaxRunningInstances, providers: cfg.Providers})
+		defer server.Close()
+	}
+
+	// Save the instances on SIGHUP and keep going. Save them and exit on SIGINT (Ctrl+C) and SIGTERM.
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-	sig := <-sigChan
-	log.InfoLog.Printf("received signal %s", sig.String())
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	for sig := range sigChan {
+		log.InfoLog.Printf("received signal %s", sig.String())
+		if sig != syscall.SIGHUP {
+			break
+		}
+		snapshot()
+	}
 
 	// Stop the goroutine so we don't race.
 	close(stopCh)
 	wg.Wait()
 
-	if err := storage.SaveInstances(instances); err != nil {
-		log.ErrorLog.Printf("failed to save instances when terminating daemon: %v", err)
+	snapshot()
+	return nil
+}
+
+// pollInstance answers the prompt the instance's program is waiting on if the policy approves it, and
+// keeps its status up to date like the app does.
+func pollInstance(instance *session.Instance, cfg *config.Config, stuckPatterns []*regexp.Regexp,
+	readyDetectors []config.ReadyDetector, policy session.ApprovalPolicy, notifier *notify.Notifier, everyN *log.Every, errs *daemonErrors) {
+	// We only store started instances, but check anyway. Gone instances are recovered from the app.
+	if !instance.Started() || instance.Paused() || instance.Status == session.Gone {
+		return
+	}
+	if instance.MarkGone() {
+		notifyEvent(notifier, config.NotifyEventCrashed, instance, "crashed: its session is gone")
+		return
 	}
+	content, err := instance.CapturePane()
+	if err != nil {
+		log.ErrorLog.Printf("error capturing pane content in status monitor: %v", err)
+		return
+	}
+	needsAuth, authChanged := instance.CheckAuth(content)
+	if needsAuth && authChanged {
+		notifyEvent(notifier, config.NotifyEventNeedsInput, instance, "needs you to log in again")
+	}
+	if needsAuth && !cfg.AutoYesOnAuth {
+		return
+	}
+	stuck := false
+	if !needsAuth {
+		var stuckChanged bool
+		stuck, stuckChanged = instance.CheckStuck(content, stuckPatterns)
+		if stuck && stuckChanged {
+			// The daemon answers the prompts of every instance, so auto-yes was blocked.
+			notifyEvent(notifier, config.NotifyEventBlocked, instance, "is waiting on a prompt auto-yes doesn't answer")
+		}
+	}
+	updated, hasPrompt := instance.HasUpdated(content, readyDetectors)
+	switch {
+	case needsAuth:
+		// Keep the NeedsAuth status until the login screen is gone.
+	case stuck:
+		// Keep the NeedsAttention status until the prompt is answered.
+	case updated:
+		instance.SetStatus(session.Running)
+	case !hasPrompt:
+		instance.SetStatus(session.Ready)
+	}
+	if notifier.Finished(instance, time.Now()) {
+		notifyEvent(notifier, config.NotifyEventFinished, instance, "is ready")
+	}
+	instance.TrackConversation()
+	if committed, err := instance.AutoCommit(cfg.CommitStrategy, time.Now()); err != nil {
+		instance.RecordError(session.ErrorOpCommit, err)
+	} else if committed {
+		instance.ResolveErrors(session.ErrorOpCommit)
+	}
+	if hasPrompt {
+		if held, changed := instance.AnswerPrompt(policy); held && changed {
+			kind, _ := instance.HeldPrompt()
+			notifyEvent(notifier, config.NotifyEventBlocked, instance, "is waiting for approval of a "+string(kind)+" prompt")
+		}
+		if err := instance.UpdateDiffStats(); err != nil {
+			errs.diff++
+			if everyN.ShouldLog() {
+				log.WarningLog.Printf("could not update diff stats for %s: %v", instance.Title, err)
+			}
+		}
+	}
+	if event, err := instance.CheckTimeBudget(cfg.TimeBudget, time.Now()); err != nil {
+		log.ErrorLog.Print(err)
+	} else if event == session.BudgetExhausted {
+		log.InfoLog.Printf("paused %s: its time budget ran out", instance.Title)
+	}
+	if split, err := instance.CheckDiffBudget(cfg.DiffBudget); err != nil {
+		log.ErrorLog.Print(err)
+	} else if split {
+		log.InfoLog.Printf("asked %s to split its work: its diff is past its budget", instance.Title)
+	}
+}
+
+// rebasePaused fetches origin once per repository of the paused instances and rebases their branches on
+// the upstream base branch.
+func rebasePaused(instances []*session.Instance, everyN *log.Every) {
+	fetched := make(map[string]bool)
+	for _, instance := range instances {
+		if !instance.Started() || !instance.Paused() || instance.Host != "" {
+			continue
+		}
+		worktree, err := instance.GetGitWorktree()
+		if err != nil {
+			continue
+		}
+		repo := worktree.GetRepoPath()
+		if !fetched[repo] {
+			// Don't fetch again for every instance of the repository if it failed, e.g. while offline.
+			fetched[repo] = true
+			if err := worktree.FetchOrigin(); err != nil {
+				if everyN.ShouldLog() {
+					log.WarningLog.Printf("could not fetch origin of %s: %v", repo, err)
+				}
+				continue
+			}
+		}
+		rebased, err := instance.RebaseOnUpstream()
+		if err != nil {
+			log.WarningLog.Printf("could not rebase %s on upstream: %v", instance.Title, err)
+		} else if rebased {
+			log.InfoLog.Printf("rebased %s on %s", instance.Title, instance.Upstream().Branch)
+		}
+	}
+}
+
+// notifyEvent posts the event in the background, so a slow webhook or backend doesn't hold up the polling.
+func notifyEvent(notifier *notify.Notifier, event string, instance *session.Instance, text string) {
+	if !notifier.Posts(event) {
+		return
+	}
+	go func() {
+		if err := notifier.Notify(event, instance, text); err != nil {
+			log.WarningLog.Printf("could not notify that %s %s: %v", instance.Title, text, err)
+		}
+	}()
+}
+
+// recoverPanic runs fn and returns the panic it raised, if any, as an error, so one bad instance can't
+// take down the monitoring of the others.
+func recoverPanic(fn func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+	fn()
 	return nil
 }
 
@@ -96,6 +344,8 @@ func LaunchDaemon() error {
 	}
 
 	cmd := exec.Command(execPath, "--daemon")
+	// The daemon serves the profile it's launched from.
+	cmd.Env = append(os.Environ(), config.ProfileEnv+"="+config.Profile())
 
 	// Detach the process from the parent
 	cmd.Stdin = nil
@@ -153,7 +403,7 @@ func StopDaemon() error {
 		return fmt.Errorf("failed to find daemon process: %w", err)
 	}
 
-	if err := proc.Kill(); err != nil {
+	if err := stopProcess(proc, stopTimeout); err != nil {
 		return fmt.Errorf("failed to stop daemon process: %w", err)
 	}
 
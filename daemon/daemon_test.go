@@ -0,0 +1,19 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecoverPanic(t *testing.T) {
+	assert.NoError(t, recoverPanic(func() {}))
+
+	err := recoverPanic(func() {
+		var m map[string]int
+		m["boom"] = 1
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "assignment to entry in nil map")
+}
@@ -3,7 +3,10 @@
 package daemon
 
 import (
+	"errors"
+	"os"
 	"syscall"
+	"time"
 )
 
 // getSysProcAttr returns platform-specific process attributes for detaching the child process
@@ -12,3 +15,20 @@ func getSysProcAttr() *syscall.SysProcAttr {
 		Setsid: true, // Create a new session
 	}
 }
+
+// stopProcess asks the process to save its state and exit with SIGTERM, and kills it if it's still
+// running after timeout.
+func stopProcess(proc *os.Process, timeout time.Duration) error {
+	if err := proc.Signal(syscall.SIGTERM); err != nil {
+		if errors.Is(err, os.ErrProcessDone) {
+			return nil
+		}
+		return proc.Kill()
+	}
+	for deadline := time.Now().Add(timeout); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+		if err := proc.Signal(syscall.Signal(0)); err != nil {
+			return nil
+		}
+	}
+	return proc.Kill()
+}
@@ -0,0 +1,43 @@
+//go:build !windows
+
+package daemon
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStopProcess(t *testing.T) {
+	t.Run("exits on SIGTERM", func(t *testing.T) {
+		cmd := exec.Command("sleep", "30")
+		require.NoError(t, cmd.Start())
+		go cmd.Wait()
+
+		start := time.Now()
+		require.NoError(t, stopProcess(cmd.Process, 5*time.Second))
+		assert.Less(t, time.Since(start), 5*time.Second)
+	})
+
+	t.Run("is killed if it ignores SIGTERM", func(t *testing.T) {
+		cmd := exec.Command("sh", "-c", "trap '' TERM; exec sleep 30")
+		require.NoError(t, cmd.Start())
+		done := make(chan struct{})
+		go func() {
+			cmd.Wait()
+			close(done)
+		}()
+		// Let the shell install its trap.
+		time.Sleep(200 * time.Millisecond)
+
+		require.NoError(t, stopProcess(cmd.Process, 200*time.Millisecond))
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("process was not killed")
+		}
+	})
+}
@@ -4,7 +4,9 @@ package daemon
 
 import (
 	"golang.org/x/sys/windows"
+	"os"
 	"syscall"
+	"time"
 )
 
 // getSysProcAttr returns platform-specific process attributes for detaching the child process
@@ -13,3 +15,9 @@ func getSysProcAttr() *syscall.SysProcAttr {
 		CreationFlags: windows.CREATE_NEW_PROCESS_GROUP | windows.DETACHED_PROCESS,
 	}
 }
+
+// stopProcess kills the process. Windows can't deliver SIGTERM to a detached process, so it doesn't get
+// to save its state.
+func stopProcess(proc *os.Process, timeout time.Duration) error {
+	return proc.Kill()
+}
@@ -0,0 +1,135 @@
+package daemon
+
+import (
+	"claude-squad/config"
+	"claude-squad/log"
+	"claude-squad/session"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// setupDurationBuckets are the upper bounds, in seconds, of the buckets of the worktree setup duration
+// histogram.
+var setupDurationBuckets = []float64{0.5, 1, 2, 5, 10, 30, 60}
+
+// daemonErrors counts the errors of the daemon while polling the instances.
+type daemonErrors struct {
+	// diff is the number of times the diff of an instance couldn't be updated.
+	diff int
+	// panics is the number of times polling an instance panicked.
+	panics int
+}
+
+// serveMetrics serves the metrics written by write on /metrics, the instances returned by list on
+// /instances and the endpoints of control, to the tokens with the scopes they need, at addr until the
+// returned server is shut down. write and list only get the instances the token can access.
+func serveMetrics(addr string, tokens []config.APIToken, write func(w io.Writer, token *config.APIToken),
+	list func() ([]instanceSummary, error), c *control) *http.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /metrics", authorize(tokens, config.ScopeRead, func(w http.ResponseWriter, r *http.Request, token *config.APIToken) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		write(w, token)
+	}))
+	mux.HandleFunc("GET /instances", authorize(tokens, config.ScopeRead, handleInstances(list)))
+	c.register(mux, tokens)
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.ErrorLog.Printf("failed to serve metrics on %s: %v", addr, err)
+		}
+	}()
+	log.InfoLog.Printf("serving metrics on %s/metrics", addr)
+	return server
+}
+
+// writeMetrics writes the metrics of the instances and the daemon in the Prometheus text format. The
+// prompts, auto-confirms and worktree setups are counted from the audit logs of the instances, so the
+// ones from the app are included.
+func writeMetrics(w io.Writer, instances []*session.Instance, errs daemonErrors) {
+	byStatus := make(map[session.Status]int)
+	for _, instance := range instances {
+		byStatus[instance.Status]++
+	}
+	fmt.Fprintln(w, "# HELP claude_squad_instances Number of instances by status.")
+	fmt.Fprintln(w, "# TYPE claude_squad_instances gauge")
+	for _, status := range []session.Status{session.Running, session.Ready, session.Loading, session.Paused, session.NeedsAuth, session.NeedsAttention, session.Gone, session.NeedsApproval} {
+		fmt.Fprintf(w, "claude_squad_instances{status=%q} %d\n", status.String(), byStatus[status])
+	}
+
+	fmt.Fprintln(w, "# HELP claude_squad_diff_lines Lines added and removed in the diff of each instance.")
+	fmt.Fprintln(w, "# TYPE claude_squad_diff_lines gauge")
+	for _, instance := range instances {
+		stats := instance.GetDiffStats()
+		if stats == nil {
+			continue
+		}
+		fmt.Fprintf(w, "claude_squad_diff_lines{instance=\"%s\",kind=\"added\"} %d\n", labelValue(instance.Title), stats.Added)
+		fmt.Fprintf(w, "claude_squad_diff_lines{instance=\"%s\",kind=\"removed\"} %d\n", labelValue(instance.Title), stats.Removed)
+	}
+
+	prompts := make(map[string]int)
+	confirms := make(map[string]int)
+	var setups []float64
+	for _, instance := range instances {
+		events, err := instance.AuditLog()
+		if err != nil {
+			log.WarningLog.Printf("could not read the audit log of %s: %v", instance.Title, err)
+			continue
+		}
+		for _, event := range events {
+			switch event.Type {
+			case session.AuditPrompt:
+				prompts[instance.Title]++
+			case session.AuditAutoConfirm:
+				confirms[instance.Title]++
+			case session.AuditWorktree:
+				if duration, err := time.ParseDuration(event.Detail); err == nil {
+					setups = append(setups, duration.Seconds())
+				}
+			}
+		}
+	}
+	writeCounter(w, "claude_squad_prompts_sent_total", "Prompts sent to each instance.", instances, prompts)
+	writeCounter(w, "claude_squad_auto_confirms_total", "Prompts auto-confirmed in each instance.", instances, confirms)
+
+	fmt.Fprintln(w, "# HELP claude_squad_worktree_setup_seconds Time it took to set up the worktree of an instance.")
+	fmt.Fprintln(w, "# TYPE claude_squad_worktree_setup_seconds histogram")
+	var sum float64
+	for _, seconds := range setups {
+		sum += seconds
+	}
+	for _, bound := range setupDurationBuckets {
+		count := 0
+		for _, seconds := range setups {
+			if seconds <= bound {
+				count++
+			}
+		}
+		fmt.Fprintf(w, "claude_squad_worktree_setup_seconds_bucket{le=\"%g\"} %d\n", bound, count)
+	}
+	fmt.Fprintf(w, "claude_squad_worktree_setup_seconds_bucket{le=\"+Inf\"} %d\n", len(setups))
+	fmt.Fprintf(w, "claude_squad_worktree_setup_seconds_sum %g\n", sum)
+	fmt.Fprintf(w, "claude_squad_worktree_setup_seconds_count %d\n", len(setups))
+
+	fmt.Fprintln(w, "# HELP claude_squad_daemon_errors_total Errors of the daemon while polling instances.")
+	fmt.Fprintln(w, "# TYPE claude_squad_daemon_errors_total counter")
+	fmt.Fprintf(w, "claude_squad_daemon_errors_total{kind=\"diff\"} %d\n", errs.diff)
+	fmt.Fprintf(w, "claude_squad_daemon_errors_total{kind=\"panic\"} %d\n", errs.panics)
+}
+
+// writeCounter writes a counter with a value for each instance.
+func writeCounter(w io.Writer, name string, help string, instances []*session.Instance, counts map[string]int) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s counter\n", name)
+	for _, instance := range instances {
+		fmt.Fprintf(w, "%s{instance=\"%s\"} %d\n", name, labelValue(instance.Title), counts[instance.Title])
+	}
+}
+
+// labelValue escapes a label value of the Prometheus text format.
+func labelValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
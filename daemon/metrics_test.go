@@ -0,0 +1,51 @@
+package daemon
+
+import (
+	"claude-squad/log"
+	"claude-squad/session"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	log.Initialize(false)
+	defer log.Close()
+	os.Exit(m.Run())
+}
+
+func TestWriteMetrics(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	ready := &session.Instance{Title: `fix "login"`, Status: session.Ready, CreatedAt: time.Now()}
+	paused := &session.Instance{Title: "docs", Status: session.Paused, CreatedAt: time.Now()}
+	ready.Audit(session.AuditWorktree, "1.5s")
+	ready.Audit(session.AuditPrompt, "fix the login")
+	ready.Audit(session.AuditAutoConfirm, "")
+	ready.Audit(session.AuditAutoConfirm, "")
+	paused.Audit(session.AuditWorktree, "40s")
+
+	var out strings.Builder
+	writeMetrics(&out, []*session.Instance{ready, paused}, daemonErrors{diff: 2, panics: 1})
+	metrics := out.String()
+
+	for _, line := range []string{
+		`claude_squad_instances{status="ready"} 1`,
+		`claude_squad_instances{status="paused"} 1`,
+		`claude_squad_instances{status="running"} 0`,
+		`claude_squad_prompts_sent_total{instance="fix \"login\""} 1`,
+		`claude_squad_auto_confirms_total{instance="fix \"login\""} 2`,
+		`claude_squad_auto_confirms_total{instance="docs"} 0`,
+		`claude_squad_worktree_setup_seconds_bucket{le="2"} 1`,
+		`claude_squad_worktree_setup_seconds_bucket{le="60"} 2`,
+		`claude_squad_worktree_setup_seconds_bucket{le="+Inf"} 2`,
+		`claude_squad_worktree_setup_seconds_sum 41.5`,
+		`claude_squad_worktree_setup_seconds_count 2`,
+		`claude_squad_daemon_errors_total{kind="diff"} 2`,
+		`claude_squad_daemon_errors_total{kind="panic"} 1`,
+	} {
+		assert.Contains(t, metrics, line+"\n")
+	}
+}
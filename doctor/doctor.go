@@ -0,0 +1,275 @@
+// Package doctor checks the setup claude-squad runs in: tmux, git, the agent programs, the config and the
+// state, and the tmux sessions and worktrees left behind, and tells how to fix what's wrong. It also has
+// the wizard that writes the config on the first run.
+package doctor
+
+import (
+	"claude-squad/config"
+	"claude-squad/session"
+	"claude-squad/session/git"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Minimum versions of tmux and git. tmux 3.0 added the environment of new sessions (new-session -e), and
+// git 2.31 absolute paths in rev-parse, used to adopt worktrees.
+var (
+	minTmuxVersion = []int{3, 0}
+	minGitVersion  = []int{2, 31}
+)
+
+// agentPrograms are the agents whose binaries are looked for.
+var agentPrograms = []string{"claude", "aider"}
+
+var versionRegex = regexp.MustCompile(`\d+(\.\d+)+`)
+
+// lookPath and output are exec.LookPath and the output of a command, replaced in tests.
+var (
+	lookPath = exec.LookPath
+	output   = func(name string, args ...string) (string, error) {
+		out, err := exec.Command(name, args...).CombinedOutput()
+		return strings.TrimSpace(string(out)), err
+	}
+)
+
+// Status is how a check went.
+type Status int
+
+const (
+	OK Status = iota
+	// Warning is something that doesn't keep claude-squad from running, but should be looked at.
+	Warning
+	// Failed is something that keeps claude-squad, or its sessions, from running.
+	Failed
+)
+
+// Result is the result of a check.
+type Result struct {
+	Status Status
+	// Message tells what was found.
+	Message string
+	// Fix tells how to fix it, if it isn't OK.
+	Fix string
+}
+
+func ok(format string, args ...any) Result {
+	return Result{Status: OK, Message: fmt.Sprintf(format, args...)}
+}
+
+// Run runs the checks for running program in the repository at repoPath.
+func Run(program, repoPath string) []Result {
+	var results []Result
+	results = append(results, checkTmux()...)
+	results = append(results, checkGit(repoPath)...)
+	results = append(results, checkPrograms(program)...)
+	results = append(results, checkConfig()...)
+	results = append(results, checkState()...)
+	return results
+}
+
+// Print prints the results, with the fixes of the ones that aren't OK, and returns how many failed.
+func Print(w io.Writer, results []Result) int {
+	failed := 0
+	for _, result := range results {
+		icon := "✓"
+		switch result.Status {
+		case Warning:
+			icon = "!"
+		case Failed:
+			icon = "✗"
+			failed++
+		}
+		fmt.Fprintf(w, "%s %s\n", icon, result.Message)
+		if result.Status != OK && result.Fix != "" {
+			fmt.Fprintf(w, "    fix: %s\n", result.Fix)
+		}
+	}
+	return failed
+}
+
+func checkTmux() []Result {
+	// Sessions run in consoles owned by claude-squad on Windows.
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	if _, err := lookPath("tmux"); err != nil {
+		return []Result{{Status: Failed, Message: "tmux not found on PATH",
+			Fix: "install tmux, e.g. `brew install tmux` or `sudo apt install tmux`"}}
+	}
+	out, err := output("tmux", "-V")
+	if err != nil {
+		return []Result{{Status: Failed, Message: fmt.Sprintf("tmux -V failed: %s", out), Fix: "reinstall tmux"}}
+	}
+	if version := parseVersion(out); version != nil && older(version, minTmuxVersion) {
+		return []Result{{Status: Failed, Message: fmt.Sprintf("%s is too old, claude-squad needs tmux %s or newer", out, formatVersion(minTmuxVersion)),
+			Fix: "upgrade tmux, e.g. `brew upgrade tmux` or from https://github.com/tmux/tmux/wiki/Installing"}}
+	}
+	return []Result{ok("%s", out)}
+}
+
+func checkGit(repoPath string) []Result {
+	if _, err := lookPath("git"); err != nil {
+		return []Result{{Status: Failed, Message: "git not found on PATH", Fix: "install git from https://git-scm.com"}}
+	}
+	var results []Result
+	out, err := output("git", "--version")
+	if err != nil {
+		return []Result{{Status: Failed, Message: fmt.Sprintf("git --version failed: %s", out), Fix: "reinstall git"}}
+	}
+	if version := parseVersion(out); version != nil && older(version, minGitVersion) {
+		results = append(results, Result{Status: Failed, Message: fmt.Sprintf("%s is too old, claude-squad needs git %s or newer", out, formatVersion(minGitVersion)),
+			Fix: "upgrade git, e.g. `brew upgrade git` or from https://git-scm.com"})
+	} else {
+		results = append(results, ok("%s", out))
+	}
+
+	if repoPath == "" || !git.IsGitRepo(repoPath) {
+		return append(results, Result{Status: Warning, Message: "not in a git repository, so its worktrees weren't checked",
+			Fix: "run `cs doctor` from the repository you use claude-squad in, or pass --repo"})
+	}
+	if out, err := output("git", "-C", repoPath, "worktree", "list"); err != nil {
+		return append(results, Result{Status: Failed, Message: fmt.Sprintf("git worktree list failed in %s: %s", repoPath, out),
+			Fix: "check that the repository isn't corrupted with `git fsck`"})
+	}
+	return append(results, ok("%s supports worktrees", repoPath))
+}
+
+func checkPrograms(program string) []Result {
+	var results []Result
+	if err := session.Preflight(program); err != nil {
+		results = append(results, Result{Status: Failed, Message: fmt.Sprintf("the default program %q can't start", program), Fix: err.Error()})
+	} else {
+		results = append(results, ok("the default program %q is ready", program))
+	}
+	for _, name := range agentPrograms {
+		if path, err := lookPath(name); err == nil {
+			results = append(results, ok("%s found at %s", name, path))
+		} else {
+			results = append(results, Result{Status: Warning, Message: fmt.Sprintf("%s not found on PATH", name),
+				Fix: fmt.Sprintf("install %s if you want to run it in sessions, or ignore this", name)})
+		}
+	}
+	return results
+}
+
+func checkConfig() []Result {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return []Result{{Status: Failed, Message: fmt.Sprintf("failed to get the config directory: %v", err), Fix: "set $HOME"}}
+	}
+	configPath := filepath.Join(configDir, config.ConfigFileName)
+	data, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return []Result{{Status: Warning, Message: fmt.Sprintf("no config file at %s, the defaults are used", configPath),
+			Fix: "run `cs init` to write one"}}
+	} else if err != nil {
+		return []Result{{Status: Failed, Message: fmt.Sprintf("failed to read %s: %v", configPath, err), Fix: "check its permissions"}}
+	}
+	problems, err := config.Validate(data)
+	if err != nil {
+		return []Result{{Status: Failed, Message: fmt.Sprintf("%s is invalid, the defaults are used instead: %v", configPath, err),
+			Fix: "fix the JSON, or move the file away and run `cs init`"}}
+	}
+	if len(problems) == 0 {
+		return []Result{ok("%s is valid", configPath)}
+	}
+	var results []Result
+	for _, problem := range problems {
+		results = append(results, Result{Status: Warning, Message: fmt.Sprintf("%s:%v", configPath, problem),
+			Fix: "fix the setting, `cs schema` has what they can be"})
+	}
+	return results
+}
+
+func checkState() []Result {
+	state, err := config.CheckStateFile()
+	if err != nil {
+		return []Result{{Status: Failed, Message: err.Error(),
+			Fix: "restore the state file from a backup with `cs state restore`, or run `cs reset` to start over without the sessions"}}
+	}
+	instances, err := session.ParseInstances(state)
+	if err != nil {
+		return []Result{{Status: Failed, Message: fmt.Sprintf("the sessions in the state file are corrupted: %v", err),
+			Fix: "restore the state file from a backup with `cs state restore`, or run `cs reset` to start over without the sessions"}}
+	}
+	results := []Result{ok("the state file has %d sessions", len(instances))}
+
+	titles := make(map[string]bool)
+	for _, data := range instances {
+		if titles[data.Title] {
+			results = append(results, Result{Status: Failed, Message: fmt.Sprintf("there are several sessions titled %q in the state file", data.Title),
+				Fix: fmt.Sprintf("kill one of them with `cs kill %s`", data.Title)})
+		}
+		titles[data.Title] = true
+		if data.Host != "" || data.Status == session.Paused {
+			continue
+		}
+		if _, err := os.Stat(data.Worktree.WorktreePath); os.IsNotExist(err) {
+			results = append(results, Result{Status: Warning, Message: fmt.Sprintf("the worktree of session %q is gone (%s)", data.Title, data.Worktree.WorktreePath),
+				Fix: "attach to the session in `cs` to recreate it from its branch, or kill it"})
+		}
+	}
+
+	if runtime.GOOS != "windows" {
+		orphans, err := session.FindStoredOrphanTerminals(instances)
+		if err != nil {
+			results = append(results, Result{Status: Warning, Message: fmt.Sprintf("failed to list the tmux sessions: %v", err)})
+		}
+		for _, orphan := range orphans {
+			results = append(results, Result{Status: Warning, Message: fmt.Sprintf("tmux session %s isn't owned by any session (in %s)", orphan.Name, orphan.Path),
+				Fix: fmt.Sprintf("press `d` in `cs` to adopt or kill it, or run `tmux kill-session -t %s`", orphan.Name)})
+		}
+	}
+	worktrees, err := session.FindOrphanWorktrees(instances)
+	if err != nil {
+		results = append(results, Result{Status: Warning, Message: fmt.Sprintf("failed to list the worktrees: %v", err)})
+	}
+	for _, worktree := range worktrees {
+		results = append(results, Result{Status: Warning, Message: fmt.Sprintf("worktree %s isn't owned by any session", worktree),
+			Fix: fmt.Sprintf("remove it with `git worktree remove --force %s` from its repository, then `git worktree prune`", worktree)})
+	}
+	return results
+}
+
+// parseVersion returns the numbers of the first version in s, e.g. [3 3] for "tmux 3.3a", or nil if it
+// has none.
+func parseVersion(s string) []int {
+	match := versionRegex.FindString(s)
+	if match == "" {
+		return nil
+	}
+	var version []int
+	for _, part := range strings.Split(match, ".") {
+		n, _ := strconv.Atoi(part)
+		version = append(version, n)
+	}
+	return version
+}
+
+// older returns true if version is older than min.
+func older(version, min []int) bool {
+	for i := range min {
+		if i >= len(version) {
+			return true
+		}
+		if version[i] != min[i] {
+			return version[i] < min[i]
+		}
+	}
+	return false
+}
+
+func formatVersion(version []int) string {
+	parts := make([]string, len(version))
+	for i, n := range version {
+		parts[i] = strconv.Itoa(n)
+	}
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,127 @@
+package doctor
+
+import (
+	"bytes"
+	"claude-squad/config"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVersion(t *testing.T) {
+	assert.Equal(t, []int{3, 3}, parseVersion("tmux 3.3a"))
+	assert.Equal(t, []int{2, 39, 5}, parseVersion("git version 2.39.5 (Apple Git-154)"))
+	assert.Nil(t, parseVersion("tmux master"))
+
+	assert.True(t, older([]int{2, 9}, minTmuxVersion))
+	assert.False(t, older([]int{3, 0}, minTmuxVersion))
+	assert.False(t, older([]int{3, 4}, minTmuxVersion))
+	assert.True(t, older([]int{2, 30, 9}, minGitVersion))
+	assert.True(t, older([]int{2}, minGitVersion))
+}
+
+func TestCheckConfig(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	configDir, err := config.GetConfigDir()
+	require.NoError(t, err)
+	configPath := filepath.Join(configDir, config.ConfigFileName)
+
+	results := checkConfig()
+	require.Len(t, results, 1)
+	assert.Equal(t, Warning, results[0].Status)
+	assert.Contains(t, results[0].Fix, "cs init")
+
+	require.NoError(t, os.MkdirAll(configDir, 0755))
+	require.NoError(t, os.WriteFile(configPath, []byte(`{"default_program": `), 0644))
+	results = checkConfig()
+	require.Len(t, results, 1)
+	assert.Equal(t, Failed, results[0].Status)
+
+	require.NoError(t, os.WriteFile(configPath, []byte(`{"default_program": "claude"}`), 0644))
+	results = checkConfig()
+	require.Len(t, results, 1)
+	assert.Equal(t, OK, results[0].Status)
+}
+
+func TestCheckState(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	configDir, err := config.GetConfigDir()
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(configDir, 0755))
+	statePath := filepath.Join(configDir, config.StateFileName)
+
+	require.NoError(t, os.WriteFile(statePath, []byte(`{"instances": [`), 0644))
+	results := checkState()
+	require.Len(t, results, 1)
+	assert.Equal(t, Failed, results[0].Status)
+	// The corrupted state file is left as it is.
+	data, err := os.ReadFile(statePath)
+	require.NoError(t, err)
+	assert.Equal(t, `{"instances": [`, string(data))
+
+	worktreeDir := filepath.Join(configDir, "worktrees")
+	owned := filepath.Join(worktreeDir, "fix-login")
+	orphan := filepath.Join(worktreeDir, "old-session")
+	require.NoError(t, os.MkdirAll(owned, 0755))
+	require.NoError(t, os.MkdirAll(orphan, 0755))
+	state := fmt.Sprintf(`{"instances": [
+		{"title": "fix login", "status": 0, "worktree": {"worktree_path": %q}},
+		{"title": "fix login", "status": 0, "worktree": {"worktree_path": %q}}
+	]}`, owned, filepath.Join(worktreeDir, "gone"))
+	require.NoError(t, os.WriteFile(statePath, []byte(state), 0644))
+
+	var messages []string
+	for _, result := range checkState() {
+		if result.Status != OK {
+			messages = append(messages, result.Message)
+		}
+	}
+	joined := strings.Join(messages, "\n")
+	assert.Contains(t, joined, `several sessions titled "fix login"`)
+	assert.Contains(t, joined, "is gone")
+	assert.Contains(t, joined, "worktree "+orphan+" isn't owned")
+	assert.NotContains(t, joined, "worktree "+owned+" isn't owned")
+}
+
+func TestPrint(t *testing.T) {
+	var out bytes.Buffer
+	failed := Print(&out, []Result{
+		ok("git version 2.43.0"),
+		{Status: Warning, Message: "aider not found on PATH", Fix: "install aider"},
+		{Status: Failed, Message: "tmux not found on PATH", Fix: "install tmux"},
+	})
+	assert.Equal(t, 1, failed)
+	assert.Equal(t, "✓ git version 2.43.0\n! aider not found on PATH\n    fix: install aider\n✗ tmux not found on PATH\n    fix: install tmux\n", out.String())
+}
+
+func TestWizard(t *testing.T) {
+	oldLookPath := lookPath
+	defer func() { lookPath = oldLookPath }()
+	lookPath = func(name string) (string, error) {
+		if name == "codex" || name == "aider" {
+			return "/usr/bin/" + name, nil
+		}
+		return "", fmt.Errorf("%s not found", name)
+	}
+
+	var out bytes.Buffer
+	cfg, err := Wizard(strings.NewReader("2\nme/\ny\n\n"), &out)
+	require.NoError(t, err)
+	assert.Equal(t, "aider", cfg.DefaultProgram)
+	assert.Equal(t, "me/", cfg.BranchPrefix)
+	assert.True(t, cfg.AutoYes)
+	assert.False(t, cfg.CopyEnvFiles)
+	assert.Contains(t, out.String(), "1. codex")
+
+	// Empty answers keep the defaults, and a command line is taken as it is.
+	cfg, err = Wizard(strings.NewReader("codex --full-auto\n\n"), &out)
+	require.NoError(t, err)
+	assert.Equal(t, "codex --full-auto", cfg.DefaultProgram)
+	assert.Equal(t, config.DefaultConfig().BranchPrefix, cfg.BranchPrefix)
+	assert.False(t, cfg.AutoYes)
+}
@@ -0,0 +1,100 @@
+package doctor
+
+import (
+	"bufio"
+	"claude-squad/config"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// wizardPrograms are the agent programs the wizard offers, if they're installed.
+var wizardPrograms = []string{"claude", "codex", "gemini", "aider", "amp"}
+
+// Wizard asks the settings that matter most on the first run, the program, the branch prefix, auto-yes
+// and copying the dotenv files, on in and out, and returns the config with them. Empty answers, or no
+// answers, keep the defaults.
+func Wizard(in io.Reader, out io.Writer) (*config.Config, error) {
+	cfg := config.DefaultConfig()
+	reader := bufio.NewReader(in)
+	ask := func(question, defaultAnswer string) (string, error) {
+		if defaultAnswer != "" {
+			fmt.Fprintf(out, "%s [%s] ", question, defaultAnswer)
+		} else {
+			fmt.Fprintf(out, "%s ", question)
+		}
+		answer, err := reader.ReadString('\n')
+		// The input running out keeps the defaults of the questions left.
+		if err != nil && err != io.EOF {
+			return "", fmt.Errorf("failed to read the answer: %w", err)
+		}
+		if answer = strings.TrimSpace(answer); answer != "" {
+			return answer, nil
+		}
+		return defaultAnswer, nil
+	}
+	confirm := func(question string, defaultYes bool) (bool, error) {
+		defaultAnswer := "y/N"
+		if defaultYes {
+			defaultAnswer = "Y/n"
+		}
+		answer, err := ask(question, defaultAnswer)
+		if err != nil || answer == defaultAnswer {
+			return defaultYes, err
+		}
+		answer = strings.ToLower(answer)
+		return answer == "y" || answer == "yes", nil
+	}
+
+	fmt.Fprintln(out, "Welcome to Claude Squad! A few questions to set it up, press enter to keep the defaults.")
+	fmt.Fprintln(out)
+
+	var installed []string
+	for _, program := range wizardPrograms {
+		if _, err := lookPath(program); err == nil {
+			installed = append(installed, program)
+		}
+	}
+	if len(installed) > 0 {
+		fmt.Fprintln(out, "Agents found on PATH:")
+		for i, program := range installed {
+			fmt.Fprintf(out, "  %d. %s\n", i+1, program)
+		}
+	}
+	defaultProgram := cfg.DefaultProgram
+	if len(installed) > 0 && !strings.Contains(defaultProgram, "claude") {
+		defaultProgram = installed[0]
+	}
+	program, err := ask("Program to run in new sessions, a number above or a command line:", defaultProgram)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := strconv.Atoi(program); err == nil && n >= 1 && n <= len(installed) {
+		program = installed[n-1]
+	}
+	cfg.DefaultProgram = program
+
+	if cfg.BranchPrefix, err = ask("Prefix of the branches of sessions:", cfg.BranchPrefix); err != nil {
+		return nil, err
+	}
+	if cfg.AutoYes, err = confirm("Accept the prompts of the agents automatically (auto-yes)?", false); err != nil {
+		return nil, err
+	}
+	if cfg.CopyEnvFiles, err = confirm("Copy the .env files git ignores into new sessions?", false); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// FirstRun returns true if there's no config file yet, so the wizard should run.
+func FirstRun() bool {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(filepath.Join(configDir, config.ConfigFileName))
+	return os.IsNotExist(err)
+}
@@ -0,0 +1,159 @@
+// Package e2e is a harness for end-to-end tests of instances with real git and tmux. Each test gets a
+// throwaway repository, its own home directory, and so its own config and state, and its own tmux server,
+// so full lifecycles can run in CI containers without touching the sessions of the user.
+package e2e
+
+import (
+	"claude-squad/config"
+	"claude-squad/session"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// waitTimeout is how long WaitForPane waits for the pane of an instance to show a text.
+const waitTimeout = 10 * time.Second
+
+// agentScript is the program of the instances: it answers each prompt with "done: <prompt>" and appends
+// it to agent.log in its worktree, like an agent changing files.
+const agentScript = `#!/bin/sh
+echo "agent ready"
+while IFS= read -r line; do
+  echo "$line" >> agent.log
+  echo "done: $line"
+done
+`
+
+// ghStub stands in for the GitHub CLI, which instances check for when they're created.
+const ghStub = `#!/bin/sh
+exit 0
+`
+
+// Harness is the environment of an end-to-end test.
+type Harness struct {
+	t *testing.T
+	// Repo is the repository the instances are created in, on its main branch.
+	Repo string
+	// Agent is the program the instances run, see agentScript.
+	Agent string
+}
+
+// New sets up a harness for the test: a home directory, a tmux server and a repository with one commit.
+// The test is skipped if git or tmux aren't installed, on Windows, and with -short.
+func New(t *testing.T) *Harness {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("end-to-end tests are skipped with -short")
+	}
+	if runtime.GOOS == "windows" {
+		t.Skip("end-to-end tests need tmux, which isn't available on Windows")
+	}
+	for _, tool := range []string{"git", "tmux"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("end-to-end tests need %s", tool)
+		}
+	}
+
+	dir := t.TempDir()
+	home := filepath.Join(dir, "home")
+	bin := filepath.Join(dir, "bin")
+	tmuxDir := filepath.Join(dir, "tmux")
+	for _, path := range []string{home, bin, tmuxDir} {
+		require.NoError(t, os.MkdirAll(path, 0700))
+	}
+	t.Setenv("HOME", home)
+	// tmux puts the socket of its server in TMUX_TMPDIR, so the test gets a server of its own. TMUX is
+	// cleared so tmux doesn't think it runs inside the session of the user.
+	t.Setenv("TMUX_TMPDIR", tmuxDir)
+	t.Setenv("TMUX", "")
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("GIT_AUTHOR_NAME", "e2e")
+	t.Setenv("GIT_AUTHOR_EMAIL", "e2e@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "e2e")
+	t.Setenv("GIT_COMMITTER_EMAIL", "e2e@example.com")
+	t.Cleanup(func() {
+		// Kills the sessions a failed test left behind.
+		_ = exec.Command("tmux", "kill-server").Run()
+	})
+
+	h := &Harness{t: t, Repo: filepath.Join(dir, "repo"), Agent: filepath.Join(bin, "agent")}
+	require.NoError(t, os.WriteFile(h.Agent, []byte(agentScript), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "gh"), []byte(ghStub), 0755))
+
+	cfg := config.DefaultConfig()
+	cfg.DefaultProgram = h.Agent
+	cfg.BranchPrefix = "e2e/"
+	require.NoError(t, config.SaveConfig(cfg))
+
+	require.NoError(t, os.MkdirAll(h.Repo, 0755))
+	h.Git(h.Repo, "init", "--quiet", "--initial-branch", "main")
+	require.NoError(t, os.WriteFile(filepath.Join(h.Repo, "README.md"), []byte("# e2e\n"), 0644))
+	h.Git(h.Repo, "add", "README.md")
+	h.Git(h.Repo, "commit", "--quiet", "-m", "Initial commit")
+	return h
+}
+
+// Git runs git in dir and returns its trimmed output, failing the test if it fails.
+func (h *Harness) Git(dir string, args ...string) string {
+	h.t.Helper()
+	output, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+	require.NoError(h.t, err, "git %s: %s", strings.Join(args, " "), output)
+	return strings.TrimSpace(string(output))
+}
+
+// NewInstance creates and starts an instance running the agent in the repository. It's killed at the end
+// of the test if it's still running.
+func (h *Harness) NewInstance(title string) *session.Instance {
+	h.t.Helper()
+	instance, err := session.NewInstance(session.InstanceOptions{Title: title, Path: h.Repo, Program: h.Agent})
+	require.NoError(h.t, err)
+	require.NoError(h.t, instance.Start(true))
+	h.t.Cleanup(func() {
+		if instance.Started() && !instance.Paused() {
+			_ = instance.Kill()
+		}
+	})
+	h.WaitForPane(instance, "agent ready")
+	return instance
+}
+
+// Storage returns the storage of the instances in the state of the test.
+func (h *Harness) Storage() *session.Storage {
+	h.t.Helper()
+	storage, err := session.NewStorage(config.LoadState())
+	require.NoError(h.t, err)
+	return storage
+}
+
+// WaitForPane waits for the pane of the instance to show the text, failing the test if it doesn't in
+// time.
+func (h *Harness) WaitForPane(instance *session.Instance, text string) {
+	h.t.Helper()
+	deadline := time.Now().Add(waitTimeout)
+	var content string
+	for time.Now().Before(deadline) {
+		var err error
+		if content, err = instance.Preview(); err == nil && strings.Contains(content, text) {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	h.t.Fatalf("the pane of %s didn't show %q in %s:\n%s", instance.Title, text, waitTimeout, content)
+}
+
+// TmuxSessions returns the names of the sessions of the tmux server of the test.
+func (h *Harness) TmuxSessions() []string {
+	h.t.Helper()
+	output, err := exec.Command("tmux", "list-sessions", "-F", "#{session_name}").Output()
+	if err != nil {
+		// The server exits along with its last session.
+		return nil
+	}
+	return strings.Fields(string(output))
+}
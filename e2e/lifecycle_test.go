@@ -0,0 +1,72 @@
+package e2e
+
+import (
+	"claude-squad/log"
+	"claude-squad/session"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	log.Initialize(false)
+	defer log.Close()
+	os.Exit(m.Run())
+}
+
+func TestInstanceLifecycle(t *testing.T) {
+	h := New(t)
+
+	instance := h.NewInstance("lifecycle")
+	worktree, err := instance.GetGitWorktree()
+	require.NoError(t, err)
+	worktreePath, branch := worktree.GetWorktreePath(), worktree.GetBranchName()
+	assert.Equal(t, "e2e/lifecycle", branch)
+	assert.Len(t, h.TmuxSessions(), 1)
+
+	// The prompt reaches the agent, which changes the worktree.
+	require.NoError(t, instance.SendPrompt("write the first note"))
+	h.WaitForPane(instance, "done: write the first note")
+	require.NoError(t, instance.UpdateDiffStats())
+	assert.Equal(t, 1, instance.GetDiffStats().Added)
+
+	// Pausing commits the changes, removes the worktree and closes the session, and the paused instance
+	// survives a round trip through the state.
+	require.NoError(t, instance.Pause())
+	assert.NoDirExists(t, worktreePath)
+	assert.Empty(t, h.TmuxSessions())
+	assert.Contains(t, instance.PausedScreen(), "done: write the first note")
+	assert.Equal(t, "write the first note", h.Git(h.Repo, "show", branch+":agent.log"))
+	require.NoError(t, h.Storage().SaveInstances([]*session.Instance{instance}))
+
+	loaded, err := h.Storage().LoadInstances()
+	require.NoError(t, err)
+	require.Len(t, loaded, 1)
+	instance = loaded[0]
+	assert.True(t, instance.Paused())
+	assert.Equal(t, 1, instance.GetDiffStats().Added)
+
+	// Resuming brings back the worktree with the committed changes and a new session.
+	require.NoError(t, instance.Resume())
+	assert.FileExists(t, filepath.Join(worktreePath, "agent.log"))
+	h.WaitForPane(instance, "agent ready")
+	require.NoError(t, instance.SendPrompt("write the second note"))
+	h.WaitForPane(instance, "done: write the second note")
+	require.NoError(t, instance.Pause())
+
+	// The branch merges into main.
+	h.Git(h.Repo, "merge", "--quiet", "--no-ff", "-m", "Merge lifecycle", branch)
+	content, err := os.ReadFile(filepath.Join(h.Repo, "agent.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "write the first note\nwrite the second note\n", string(content))
+
+	// Killing the instance leaves nothing behind.
+	require.NoError(t, instance.Resume())
+	require.NoError(t, instance.Kill())
+	assert.NoDirExists(t, worktreePath)
+	assert.Empty(t, h.TmuxSessions())
+	assert.Empty(t, h.Git(h.Repo, "branch", "--list", branch))
+}
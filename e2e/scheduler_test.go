@@ -0,0 +1,47 @@
+package e2e
+
+import (
+	"claude-squad/session"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueuedInstanceStartsWhenASlotFrees(t *testing.T) {
+	h := New(t)
+	first := h.NewInstance("first")
+
+	// With a cap of one running instance, the second one is queued without a worktree or a session.
+	second, err := session.NewInstance(session.InstanceOptions{Title: "second", Path: h.Repo, Program: h.Agent})
+	require.NoError(t, err)
+	instances := []*session.Instance{first, second}
+	queued, err := second.StartOrQueue(1, instances)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = second.Kill() })
+	assert.True(t, queued)
+	assert.True(t, second.Paused())
+	assert.Len(t, h.TmuxSessions(), 1)
+	require.NoError(t, second.SendPrompt("write the queued note"))
+
+	// The queue survives a round trip through the state.
+	require.NoError(t, h.Storage().SaveInstances(instances))
+	loaded, err := h.Storage().LoadInstances()
+	require.NoError(t, err)
+	require.Len(t, loaded, 2)
+	assert.True(t, loaded[1].Queued())
+	assert.Equal(t, []string{"write the queued note"}, loaded[1].QueuedPrompts)
+
+	dispatched, err := session.DispatchQueued(1, nil, instances)
+	require.NoError(t, err)
+	assert.Empty(t, dispatched)
+
+	// Pausing the first instance frees its slot for the second, which gets the prompt sent meanwhile.
+	require.NoError(t, first.Pause())
+	dispatched, err = session.DispatchQueued(1, nil, instances)
+	require.NoError(t, err)
+	assert.Equal(t, []*session.Instance{second}, dispatched)
+	assert.False(t, second.Queued())
+	h.WaitForPane(second, "done: write the queued note")
+	assert.Equal(t, "e2e/second", second.Branch)
+}
@@ -0,0 +1,172 @@
+package github
+
+import (
+	"claude-squad/config"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// triggerIssueLimit is the number of labeled issues a trigger looks at per poll.
+	triggerIssueLimit = 20
+	// maxFailedLogLength is how much of the end of the log of a failed run is put in the prompt.
+	maxFailedLogLength = 4000
+)
+
+// Event is something that happened on GitHub that a trigger creates an instance for.
+type Event struct {
+	// Key identifies the event, so that only one instance is created for it.
+	Key string
+	// Title is the title of the instance.
+	Title string
+	// Prompt is the initial prompt of the instance.
+	Prompt string
+}
+
+// WorkflowRun holds the parts of a GitHub Actions run used to seed an instance.
+type WorkflowRun struct {
+	ID         int64  `json:"databaseId"`
+	Title      string `json:"displayTitle"`
+	Workflow   string `json:"workflowName"`
+	Branch     string `json:"headBranch"`
+	SHA        string `json:"headSha"`
+	URL        string `json:"url"`
+	Status     string `json:"status"`
+	Conclusion string `json:"conclusion"`
+}
+
+// runFields are the fields requested from `gh run list`.
+const runFields = "databaseId,displayTitle,workflowName,headBranch,headSha,url,status,conclusion"
+
+// PollTrigger returns the events of the trigger: the open issues with its label, or the latest run of
+// its workflow if it failed. Events that already have an instance are returned too; the caller keeps
+// track of those.
+func PollTrigger(trigger config.Trigger) ([]Event, error) {
+	dir := trigger.Path
+	if dir == "" {
+		dir = "."
+	}
+	var events []Event
+	switch trigger.Event {
+	case config.TriggerEventIssue:
+		if trigger.Label == "" {
+			return nil, fmt.Errorf("trigger %s has no label", trigger.Name)
+		}
+		issues, err := listLabeledIssues(dir, trigger.Label)
+		if err != nil {
+			return nil, err
+		}
+		for _, issue := range issues {
+			events = append(events, Event{
+				Key:    fmt.Sprintf("%s/issue/%d", trigger.Name, issue.Number),
+				Title:  issue.InstanceTitle(),
+				Prompt: issue.Prompt(),
+			})
+		}
+	case config.TriggerEventWorkflowFailure:
+		if trigger.Workflow == "" {
+			return nil, fmt.Errorf("trigger %s has no workflow", trigger.Name)
+		}
+		run, err := latestRun(dir, trigger.Workflow, trigger.Branch)
+		if err != nil {
+			return nil, err
+		}
+		if run == nil || run.Status != "completed" || run.Conclusion != "failure" {
+			return nil, nil
+		}
+		log, err := failedLog(dir, run.ID)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, Event{
+			Key:    fmt.Sprintf("%s/run/%d", trigger.Name, run.ID),
+			Title:  run.InstanceTitle(),
+			Prompt: run.Prompt(log),
+		})
+	default:
+		return nil, fmt.Errorf("trigger %s has unknown event %q", trigger.Name, trigger.Event)
+	}
+
+	if extra := strings.TrimSpace(trigger.Prompt); extra != "" {
+		for i := range events {
+			events[i].Prompt += "\n\n" + extra
+		}
+	}
+	return events, nil
+}
+
+// listLabeledIssues returns the open issues with the label of the repository in dir.
+func listLabeledIssues(dir string, label string) ([]Issue, error) {
+	output, err := runGH(dir, "issue", "list", "--state", "open", "--label", label,
+		"--limit", strconv.Itoa(triggerIssueLimit), "--json", issueFields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list issues labeled %s: %w", label, err)
+	}
+	var issues []Issue
+	if err := json.Unmarshal(output, &issues); err != nil {
+		return nil, fmt.Errorf("failed to parse issues: %w", err)
+	}
+	return issues, nil
+}
+
+// latestRun returns the latest run of the workflow of the repository in dir, on the branch if it's set,
+// or nil if there's none.
+func latestRun(dir string, workflow string, branch string) (*WorkflowRun, error) {
+	args := []string{"run", "list", "--workflow", workflow, "--limit", "1", "--json", runFields}
+	if branch != "" {
+		args = append(args, "--branch", branch)
+	}
+	output, err := runGH(dir, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list runs of %s: %w", workflow, err)
+	}
+	var runs []WorkflowRun
+	if err := json.Unmarshal(output, &runs); err != nil {
+		return nil, fmt.Errorf("failed to parse runs: %w", err)
+	}
+	if len(runs) == 0 {
+		return nil, nil
+	}
+	return &runs[0], nil
+}
+
+// failedLog returns the end of the log of the failed steps of the run.
+func failedLog(dir string, id int64) (string, error) {
+	output, err := runGH(dir, "run", "view", strconv.FormatInt(id, 10), "--log-failed")
+	if err != nil {
+		return "", fmt.Errorf("failed to get the log of run %d: %w", id, err)
+	}
+	log := strings.TrimSpace(string(output))
+	if len(log) > maxFailedLogLength {
+		start := len(log) - maxFailedLogLength
+		for start < len(log) && !utf8.RuneStart(log[start]) {
+			start++
+		}
+		log = "..." + log[start:]
+	}
+	return log, nil
+}
+
+// InstanceTitle returns an instance title derived from the run.
+func (r *WorkflowRun) InstanceTitle() string {
+	return cutTitle(fmt.Sprintf("fix %s %d", r.Workflow, r.ID))
+}
+
+// Prompt returns the initial prompt for an agent fixing the failed run, with the log of its failed steps.
+func (r *WorkflowRun) Prompt(log string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "The %s workflow failed on %s (commit %s): %s\n", r.Workflow, r.Branch, r.SHA, r.Title)
+	if r.URL != "" {
+		fmt.Fprintf(&b, "%s\n", r.URL)
+	}
+	if log != "" {
+		b.WriteString("\nLog of the failed steps:\n")
+		b.WriteString(log)
+		b.WriteString("\n")
+	}
+	b.WriteString("\nFind the cause of the failure and fix it.")
+	return b.String()
+}
@@ -0,0 +1,76 @@
+package github
+
+import (
+	"claude-squad/config"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubGH replaces gh with responses by subcommand, e.g. "issue list", and records the calls.
+func stubGH(t *testing.T, responses map[string]string) *[]string {
+	var calls []string
+	original := runGH
+	runGH = func(dir string, args ...string) ([]byte, error) {
+		calls = append(calls, strings.Join(args, " "))
+		response, ok := responses[args[0]+" "+args[1]]
+		if !ok {
+			return nil, fmt.Errorf("unexpected gh %s", strings.Join(args, " "))
+		}
+		return []byte(response), nil
+	}
+	t.Cleanup(func() { runGH = original })
+	return &calls
+}
+
+func TestPollTriggerIssues(t *testing.T) {
+	calls := stubGH(t, map[string]string{
+		"issue list": `[{"number": 12, "title": "Crash on start", "body": "It crashes.", "url": "https://github.com/o/r/issues/12"}]`,
+	})
+
+	events, err := PollTrigger(config.Trigger{Name: "agent", Event: config.TriggerEventIssue, Label: "agent",
+		Prompt: "Open a pull request when you're done."})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "agent/issue/12", events[0].Key)
+	assert.Equal(t, "issue-12 Crash on start", events[0].Title)
+	assert.True(t, strings.HasPrefix(events[0].Prompt, "Resolve GitHub issue #12: Crash on start"))
+	assert.True(t, strings.HasSuffix(events[0].Prompt, "\n\nOpen a pull request when you're done."))
+	assert.Contains(t, (*calls)[0], "--label agent")
+
+	_, err = PollTrigger(config.Trigger{Name: "agent", Event: config.TriggerEventIssue})
+	assert.ErrorContains(t, err, "no label")
+	_, err = PollTrigger(config.Trigger{Name: "agent", Event: "push"})
+	assert.ErrorContains(t, err, "unknown event")
+}
+
+func TestPollTriggerWorkflowFailure(t *testing.T) {
+	run := `[{"databaseId": 987, "displayTitle": "Nightly", "workflowName": "nightly", "headBranch": "main",
+		"headSha": "abc123", "url": "https://github.com/o/r/actions/runs/987", "status": "completed", "conclusion": "%s"}]`
+	calls := stubGH(t, map[string]string{
+		"run list": fmt.Sprintf(run, "failure"),
+		"run view": "test\tFAIL TestLogin\n",
+	})
+
+	trigger := config.Trigger{Name: "nightly", Event: config.TriggerEventWorkflowFailure, Workflow: "nightly.yml", Branch: "main"}
+	events, err := PollTrigger(trigger)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "nightly/run/987", events[0].Key)
+	assert.Equal(t, "fix nightly 987", events[0].Title)
+	assert.Contains(t, events[0].Prompt, "The nightly workflow failed on main (commit abc123): Nightly")
+	assert.Contains(t, events[0].Prompt, "FAIL TestLogin")
+	assert.Equal(t, []string{
+		"run list --workflow nightly.yml --limit 1 --json " + runFields + " --branch main",
+		"run view 987 --log-failed",
+	}, *calls)
+
+	// Nothing to fix once the latest run passed.
+	stubGH(t, map[string]string{"run list": fmt.Sprintf(run, "success")})
+	events, err = PollTrigger(trigger)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
@@ -0,0 +1,197 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxTitleLength mirrors the limit the TUI enforces on instance titles.
+const maxTitleLength = 32
+
+// cutTitle cuts title to maxTitleLength bytes, without splitting a character of a non-ASCII title.
+func cutTitle(title string) string {
+	if len(title) <= maxTitleLength {
+		return title
+	}
+	n := maxTitleLength
+	for n > 0 && !utf8.RuneStart(title[n]) {
+		n--
+	}
+	return strings.TrimSpace(title[:n])
+}
+
+// IssueRef identifies a GitHub issue. Repo is empty when the issue belongs to the repository of the
+// current working directory.
+type IssueRef struct {
+	// Repo is the "owner/repo" the issue belongs to.
+	Repo string
+	// Number is the issue number.
+	Number int
+}
+
+func (r IssueRef) String() string {
+	if r.Repo == "" {
+		return fmt.Sprintf("#%d", r.Number)
+	}
+	return fmt.Sprintf("%s#%d", r.Repo, r.Number)
+}
+
+var issueRefRegex = regexp.MustCompile(`^(?:([\w.-]+/[\w.-]+))?#?(\d+)$`)
+var issueURLRegex = regexp.MustCompile(`^https?://github\.com/([\w.-]+/[\w.-]+)/issues/(\d+)/?$`)
+
+// ParseIssueRef parses an issue reference. Supported formats are "owner/repo#123", "#123", "123" and
+// full issue URLs like "https://github.com/owner/repo/issues/123".
+func ParseIssueRef(s string) (IssueRef, error) {
+	s = strings.TrimSpace(s)
+	matches := issueURLRegex.FindStringSubmatch(s)
+	if matches == nil {
+		matches = issueRefRegex.FindStringSubmatch(s)
+	}
+	if matches == nil {
+		return IssueRef{}, fmt.Errorf("invalid issue reference %q: expected owner/repo#123", s)
+	}
+
+	number, err := strconv.Atoi(matches[2])
+	if err != nil || number <= 0 {
+		return IssueRef{}, fmt.Errorf("invalid issue number in %q", s)
+	}
+	return IssueRef{Repo: matches[1], Number: number}, nil
+}
+
+// Issue holds the parts of a GitHub issue used to seed an instance.
+type Issue struct {
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	Body   string `json:"body"`
+	URL    string `json:"url"`
+}
+
+// issueFields are the fields requested from `gh issue view/list`.
+const issueFields = "number,title,body,url"
+
+// FetchIssue fetches a single issue using the GitHub CLI. dir is the directory gh is run from, which
+// determines the repository when ref.Repo is empty.
+func FetchIssue(ref IssueRef, dir string) (*Issue, error) {
+	if err := checkGHCLI(); err != nil {
+		return nil, err
+	}
+
+	args := []string{"issue", "view", strconv.Itoa(ref.Number), "--json", issueFields}
+	if ref.Repo != "" {
+		args = append(args, "-R", ref.Repo)
+	}
+	output, err := runGH(dir, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch issue %s: %w", ref, err)
+	}
+
+	var issue Issue
+	if err := json.Unmarshal(output, &issue); err != nil {
+		return nil, fmt.Errorf("failed to parse issue %s: %w", ref, err)
+	}
+	return &issue, nil
+}
+
+// ListIssues returns up to limit open issues of the repository in dir.
+func ListIssues(dir string, limit int) ([]Issue, error) {
+	if err := checkGHCLI(); err != nil {
+		return nil, err
+	}
+
+	output, err := runGH(dir, "issue", "list", "--state", "open", "--limit", strconv.Itoa(limit), "--json", issueFields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list issues: %w", err)
+	}
+
+	var issues []Issue
+	if err := json.Unmarshal(output, &issues); err != nil {
+		return nil, fmt.Errorf("failed to parse issues: %w", err)
+	}
+	return issues, nil
+}
+
+// InstanceTitle returns an instance title derived from the issue. The branch name is derived from the
+// title, so this also names the branch after the issue.
+func (i *Issue) InstanceTitle() string {
+	return cutTitle(fmt.Sprintf("issue-%d %s", i.Number, strings.TrimSpace(i.Title)))
+}
+
+// Prompt returns the initial prompt for an agent working on the issue. It contains the issue
+// description and repeats the acceptance criteria, if the issue has any, as the definition of done.
+func (i *Issue) Prompt() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Resolve GitHub issue #%d: %s\n", i.Number, i.Title)
+	if i.URL != "" {
+		fmt.Fprintf(&b, "%s\n", i.URL)
+	}
+
+	body := strings.TrimSpace(i.Body)
+	if body != "" {
+		b.WriteString("\nIssue description:\n")
+		b.WriteString(body)
+		b.WriteString("\n")
+	}
+
+	if criteria := acceptanceCriteria(body); criteria != "" {
+		b.WriteString("\nThe work is done when all of these acceptance criteria are met:\n")
+		b.WriteString(criteria)
+		b.WriteString("\n")
+	}
+	return strings.TrimSpace(b.String())
+}
+
+var criteriaHeadingRegex = regexp.MustCompile(`(?i)^(#+\s*|\*\*)?acceptance criteria`)
+var markdownHeadingRegex = regexp.MustCompile(`^#+\s`)
+
+// acceptanceCriteria extracts the "Acceptance criteria" section from a markdown issue body. The section
+// ends at the next heading of any level.
+func acceptanceCriteria(body string) string {
+	var section []string
+	inSection := false
+	for _, line := range strings.Split(body, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if criteriaHeadingRegex.MatchString(trimmed) {
+			inSection = true
+			continue
+		}
+		if inSection && markdownHeadingRegex.MatchString(trimmed) {
+			break
+		}
+		if inSection {
+			section = append(section, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(section, "\n"))
+}
+
+// checkGHCLI checks if GitHub CLI is installed and configured
+func checkGHCLI() error {
+	if _, err := exec.LookPath("gh"); err != nil {
+		return fmt.Errorf("GitHub CLI (gh) is not installed. Please install it first")
+	}
+
+	cmd := exec.Command("gh", "auth", "status")
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("GitHub CLI is not configured. Please run 'gh auth login' first")
+	}
+	return nil
+}
+
+// runGH runs a gh command in dir and returns its stdout. It's a variable so tests can stub gh.
+var runGH = func(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("gh", args...)
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("%s (%w)", strings.TrimSpace(string(exitErr.Stderr)), err)
+		}
+		return nil, err
+	}
+	return output, nil
+}
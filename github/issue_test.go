@@ -0,0 +1,73 @@
+package github
+
+import (
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIssueRef(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected IssueRef
+		wantErr  bool
+	}{
+		{name: "owner/repo#number", input: "smtg-ai/claude-squad#123", expected: IssueRef{Repo: "smtg-ai/claude-squad", Number: 123}},
+		{name: "hash number", input: "#42", expected: IssueRef{Number: 42}},
+		{name: "bare number", input: "7", expected: IssueRef{Number: 7}},
+		{name: "issue url", input: "https://github.com/owner/repo/issues/9", expected: IssueRef{Repo: "owner/repo", Number: 9}},
+		{name: "missing number", input: "owner/repo#", wantErr: true},
+		{name: "zero", input: "#0", wantErr: true},
+		{name: "garbage", input: "not an issue", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref, err := ParseIssueRef(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, ref)
+		})
+	}
+}
+
+func TestIssueInstanceTitle(t *testing.T) {
+	issue := &Issue{Number: 123, Title: "Crash when the config file is missing entirely"}
+	title := issue.InstanceTitle()
+	assert.LessOrEqual(t, len(title), maxTitleLength)
+	assert.Equal(t, "issue-123 Crash when the config", title)
+
+	short := &Issue{Number: 1, Title: "  Typo  "}
+	assert.Equal(t, "issue-1 Typo", short.InstanceTitle())
+
+	// Non-ASCII titles aren't cut in the middle of a character.
+	accented := &Issue{Number: 42, Title: "Gérer les éléments déjà créés"}
+	title = accented.InstanceTitle()
+	assert.True(t, utf8.ValidString(title))
+	assert.Equal(t, "issue-42 Gérer les éléments d", title)
+}
+
+func TestIssuePrompt(t *testing.T) {
+	issue := &Issue{
+		Number: 5,
+		Title:  "Add dark mode",
+		URL:    "https://github.com/owner/repo/issues/5",
+		Body:   "We need a dark mode.\n\n## Acceptance Criteria\n- [ ] toggle in settings\n- [ ] persists across restarts\n\n## Notes\nnothing else",
+	}
+
+	prompt := issue.Prompt()
+	assert.Contains(t, prompt, "Resolve GitHub issue #5: Add dark mode")
+	assert.Contains(t, prompt, "We need a dark mode.")
+	assert.Contains(t, prompt, "acceptance criteria are met:\n- [ ] toggle in settings\n- [ ] persists across restarts")
+	assert.NotContains(t, prompt, "met:\n- [ ] toggle in settings\n- [ ] persists across restarts\n\n## Notes")
+}
+
+func TestAcceptanceCriteriaMissing(t *testing.T) {
+	assert.Equal(t, "", acceptanceCriteria("just a description"))
+}
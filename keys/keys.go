@@ -17,14 +17,51 @@ const (
 	KeyPush
 	KeySubmit
 
-	KeyTab        // Tab is a special keybinding for switching between panes.
-	KeySubmitName // SubmitName is a special keybinding for submitting the name of a new instance.
+	KeyTab          // Tab is a special keybinding for switching between panes.
+	KeySubmitName   // SubmitName is a special keybinding for submitting the name of a new instance.
+	KeyNextProvider // NextProvider is a special keybinding for switching a new instance to the next provider.
 
 	KeyCheckout
 	KeyResume
 	KeyPrompt       // New key for entering a prompt
 	KeyHelp         // Key for showing help screen
 	KeyClaudeResume // New key for creating instance with Claude resume
+	KeyIssue        // Key for creating an instance from a GitHub issue
+	KeyHeatMap      // Key for showing the heat map of changed paths
+	KeyFanOut       // Key for running a prompt across several instances
+	KeyCompare      // Key for comparing the instances of a fan-out group
+	KeyScope        // Key for assigning directory scopes to an instance
+	KeyNotes        // Key for editing the notes and TODOs of an instance
+	KeyTemplate     // Key for creating an instance from a template
+	KeyStack        // Key for creating an instance stacked on the selected one
+	KeyRestack      // Key for rebasing stacked instances on their parents
+	KeyTask         // Key for dispatching a task to a related instance or a new one
+	KeyTimeline     // Key for showing the timeline of the selected instance
+	KeyBranch       // Key for creating an instance on an existing branch
+	KeyExternal     // Key for opening the selected instance in an external terminal
+	KeyArchive      // Key for archiving the selected instance
+	KeyArchived     // Key for listing the archived instances
+	KeyPipeline     // Key for running a pipeline of instances
+	KeyChecks       // Key for running the checks of the selected instance
+	KeySummary      // Key for sharing a markdown summary of the selected instance
+	KeyFork         // Key for forking the selected instance, its conversation included
+	KeySuspend      // Key for suspending all running instances or resuming the suspended ones
+	KeyErrors       // Key for the error history and retry actions of the selected instance
+	KeyHandoff      // Key for handing off the diff or last message of the selected instance to another one
+	KeyDoctor       // Key for the terminal sessions without an instance and the instances without a session
+	KeyPalette      // Key for the fuzzy finder of instances, branches and actions
+	KeyProfile      // Key for switching to another profile
+	KeyObserve      // Key for watching the selected instance read-only
+	KeyLayout       // Key for switching between the tabs and the split layout
+	KeyShrink       // Key for shrinking the focused pane of the split layout
+	KeyGrow         // Key for growing the focused pane of the split layout
+	KeyBudget       // Key for setting the time budget of the selected instance
+	KeyHistory      // Key for showing the history of killed and archived instances
+	KeyApprove      // Key for approving the prompt auto-yes holds in the selected instance
+	KeyDeny         // Key for denying the prompt auto-yes holds in the selected instance
+	KeyImport       // Key for pasting the clipboard or copying a file into the selected instance
+	KeyTheme        // Key for picking the color theme
+	KeyQuickInput   // Key for typing into the selected instance from the preview, without attaching to it
 
 	// Diff keybindings
 	KeyShiftUp
@@ -51,6 +88,43 @@ var GlobalKeyStringsMap = map[string]KeyName{
 	"p":          KeySubmit,
 	"?":          KeyHelp,
 	"C":          KeyClaudeResume,
+	"i":          KeyIssue,
+	"h":          KeyHeatMap,
+	"F":          KeyFanOut,
+	"V":          KeyCompare,
+	"O":          KeyScope,
+	"m":          KeyNotes,
+	"T":          KeyTemplate,
+	"B":          KeyStack,
+	"R":          KeyRestack,
+	"t":          KeyTask,
+	"L":          KeyTimeline,
+	"b":          KeyBranch,
+	"e":          KeyExternal,
+	"v":          KeyReview,
+	"a":          KeyArchive,
+	"A":          KeyArchived,
+	"P":          KeyPipeline,
+	"x":          KeyChecks,
+	"M":          KeySummary,
+	"f":          KeyFork,
+	"S":          KeySuspend,
+	"E":          KeyErrors,
+	"H":          KeyHandoff,
+	"d":          KeyDoctor,
+	"ctrl+p":     KeyPalette,
+	"W":          KeyProfile,
+	"w":          KeyObserve,
+	"|":          KeyLayout,
+	"<":          KeyShrink,
+	">":          KeyGrow,
+	"u":          KeyBudget,
+	"Y":          KeyHistory,
+	"y":          KeyApprove,
+	"z":          KeyDeny,
+	"I":          KeyImport,
+	"U":          KeyTheme,
+	"s":          KeyQuickInput,
 }
 
 // GlobalkeyBindings is a global, immutable map of KeyName tot keybinding.
@@ -115,6 +189,154 @@ var GlobalkeyBindings = map[KeyName]key.Binding{
 		key.WithKeys("C"),
 		key.WithHelp("C", "new with resume"),
 	),
+	KeyIssue: key.NewBinding(
+		key.WithKeys("i"),
+		key.WithHelp("i", "new from issue"),
+	),
+	KeyHeatMap: key.NewBinding(
+		key.WithKeys("h"),
+		key.WithHelp("h", "heat map"),
+	),
+	KeyFanOut: key.NewBinding(
+		key.WithKeys("F"),
+		key.WithHelp("F", "fan-out"),
+	),
+	KeyCompare: key.NewBinding(
+		key.WithKeys("V"),
+		key.WithHelp("V", "compare"),
+	),
+	KeyScope: key.NewBinding(
+		key.WithKeys("O"),
+		key.WithHelp("O", "scope"),
+	),
+	KeyNotes: key.NewBinding(
+		key.WithKeys("m"),
+		key.WithHelp("m", "notes"),
+	),
+	KeyTemplate: key.NewBinding(
+		key.WithKeys("T"),
+		key.WithHelp("T", "new from template"),
+	),
+	KeyStack: key.NewBinding(
+		key.WithKeys("B"),
+		key.WithHelp("B", "new stacked"),
+	),
+	KeyRestack: key.NewBinding(
+		key.WithKeys("R"),
+		key.WithHelp("R", "restack"),
+	),
+	KeyTask: key.NewBinding(
+		key.WithKeys("t"),
+		key.WithHelp("t", "task"),
+	),
+	KeyTimeline: key.NewBinding(
+		key.WithKeys("L"),
+		key.WithHelp("L", "timeline"),
+	),
+	KeyBranch: key.NewBinding(
+		key.WithKeys("b"),
+		key.WithHelp("b", "new on branch"),
+	),
+	KeyExternal: key.NewBinding(
+		key.WithKeys("e"),
+		key.WithHelp("e", "open in terminal"),
+	),
+	KeyReview: key.NewBinding(
+		key.WithKeys("v"),
+		key.WithHelp("v", "review"),
+	),
+	KeyArchive: key.NewBinding(
+		key.WithKeys("a"),
+		key.WithHelp("a", "archive"),
+	),
+	KeyArchived: key.NewBinding(
+		key.WithKeys("A"),
+		key.WithHelp("A", "archived"),
+	),
+	KeyPipeline: key.NewBinding(
+		key.WithKeys("P"),
+		key.WithHelp("P", "pipeline"),
+	),
+	KeyChecks: key.NewBinding(
+		key.WithKeys("x"),
+		key.WithHelp("x", "checks"),
+	),
+	KeySummary: key.NewBinding(
+		key.WithKeys("M"),
+		key.WithHelp("M", "summary"),
+	),
+	KeyFork: key.NewBinding(
+		key.WithKeys("f"),
+		key.WithHelp("f", "fork"),
+	),
+	KeySuspend: key.NewBinding(
+		key.WithKeys("S"),
+		key.WithHelp("S", "suspend all"),
+	),
+	KeyErrors: key.NewBinding(
+		key.WithKeys("E"),
+		key.WithHelp("E", "errors"),
+	),
+	KeyHandoff: key.NewBinding(
+		key.WithKeys("H"),
+		key.WithHelp("H", "hand off"),
+	),
+	KeyDoctor: key.NewBinding(
+		key.WithKeys("d"),
+		key.WithHelp("d", "doctor"),
+	),
+	KeyPalette: key.NewBinding(
+		key.WithKeys("ctrl+p"),
+		key.WithHelp("ctrl+p", "find"),
+	),
+	KeyProfile: key.NewBinding(
+		key.WithKeys("W"),
+		key.WithHelp("W", "profile"),
+	),
+	KeyObserve: key.NewBinding(
+		key.WithKeys("w"),
+		key.WithHelp("w", "observe"),
+	),
+	KeyLayout: key.NewBinding(
+		key.WithKeys("|"),
+		key.WithHelp("|", "layout"),
+	),
+	KeyShrink: key.NewBinding(
+		key.WithKeys("<"),
+		key.WithHelp("<", "shrink pane"),
+	),
+	KeyGrow: key.NewBinding(
+		key.WithKeys(">"),
+		key.WithHelp(">", "grow pane"),
+	),
+	KeyBudget: key.NewBinding(
+		key.WithKeys("u"),
+		key.WithHelp("u", "time budget"),
+	),
+	KeyHistory: key.NewBinding(
+		key.WithKeys("Y"),
+		key.WithHelp("Y", "history"),
+	),
+	KeyApprove: key.NewBinding(
+		key.WithKeys("y"),
+		key.WithHelp("y", "approve"),
+	),
+	KeyDeny: key.NewBinding(
+		key.WithKeys("z"),
+		key.WithHelp("z", "deny"),
+	),
+	KeyImport: key.NewBinding(
+		key.WithKeys("I"),
+		key.WithHelp("I", "import"),
+	),
+	KeyTheme: key.NewBinding(
+		key.WithKeys("U"),
+		key.WithHelp("U", "theme"),
+	),
+	KeyQuickInput: key.NewBinding(
+		key.WithKeys("s"),
+		key.WithHelp("s", "type"),
+	),
 
 	// -- Special keybindings --
 
@@ -122,4 +344,8 @@ var GlobalkeyBindings = map[KeyName]key.Binding{
 		key.WithKeys("enter"),
 		key.WithHelp("enter", "submit name"),
 	),
+	KeyNextProvider: key.NewBinding(
+		key.WithKeys("tab"),
+		key.WithHelp("tab", "provider"),
+	),
 }
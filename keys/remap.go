@@ -0,0 +1,158 @@
+package keys
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+)
+
+// KeyActions maps the names of the actions in the keys section of the config to their keybindings.
+var KeyActions = map[string]KeyName{
+	"up":            KeyUp,
+	"down":          KeyDown,
+	"scroll-up":     KeyShiftUp,
+	"scroll-down":   KeyShiftDown,
+	"open":          KeyEnter,
+	"new":           KeyNew,
+	"prompt":        KeyPrompt,
+	"kill":          KeyKill,
+	"quit":          KeyQuit,
+	"tab":           KeyTab,
+	"push":          KeySubmit,
+	"checkout":      KeyCheckout,
+	"resume":        KeyResume,
+	"help":          KeyHelp,
+	"claude-resume": KeyClaudeResume,
+	"issue":         KeyIssue,
+	"heat-map":      KeyHeatMap,
+	"fan-out":       KeyFanOut,
+	"compare":       KeyCompare,
+	"scope":         KeyScope,
+	"notes":         KeyNotes,
+	"template":      KeyTemplate,
+	"stack":         KeyStack,
+	"restack":       KeyRestack,
+	"task":          KeyTask,
+	"timeline":      KeyTimeline,
+	"branch":        KeyBranch,
+	"external":      KeyExternal,
+	"review":        KeyReview,
+	"archive":       KeyArchive,
+	"archived":      KeyArchived,
+	"pipeline":      KeyPipeline,
+	"checks":        KeyChecks,
+	"summary":       KeySummary,
+	"fork":          KeyFork,
+	"suspend":       KeySuspend,
+	"errors":        KeyErrors,
+	"handoff":       KeyHandoff,
+	"doctor":        KeyDoctor,
+	"palette":       KeyPalette,
+	"profile":       KeyProfile,
+	"observe":       KeyObserve,
+	"layout":        KeyLayout,
+	"shrink":        KeyShrink,
+	"grow":          KeyGrow,
+	"budget":        KeyBudget,
+	"history":       KeyHistory,
+	"approve":       KeyApprove,
+	"deny":          KeyDeny,
+	"import":        KeyImport,
+	"theme":         KeyTheme,
+	"quick-input":   KeyQuickInput,
+}
+
+// helpKeyNames are how keys are shown in the help, if not as they're typed.
+var helpKeyNames = map[string]string{
+	"up":         "↑",
+	"down":       "↓",
+	"enter":      "↵",
+	"shift+up":   "shift+↑",
+	"shift+down": "shift+↓",
+}
+
+// Remap replaces the keys of the actions named in remap, e.g. {"kill": ["X"]}, in GlobalkeyBindings and
+// GlobalKeyStringsMap. The other actions keep their keys. Unknown actions and keys bound to several
+// actions are errors, and leave the keybindings unchanged.
+func Remap(remap map[string][]string) error {
+	if len(remap) == 0 {
+		return nil
+	}
+	bindings := make(map[KeyName]key.Binding, len(GlobalkeyBindings))
+	for name, binding := range GlobalkeyBindings {
+		bindings[name] = binding
+	}
+	for action, remapped := range remap {
+		name, ok := KeyActions[action]
+		if !ok {
+			return fmt.Errorf("unknown action %q", action)
+		}
+		if len(remapped) == 0 {
+			return fmt.Errorf("no keys for action %q", action)
+		}
+		bindings[name] = key.NewBinding(
+			key.WithKeys(remapped...),
+			key.WithHelp(helpKeys(remapped), bindings[name].Help().Desc),
+		)
+	}
+
+	keyStrings := make(map[string]KeyName)
+	for _, action := range sortedActions() {
+		name := KeyActions[action]
+		for _, k := range bindings[name].Keys() {
+			if other, ok := keyStrings[k]; ok && other != name {
+				return fmt.Errorf("key %q is bound to both %s and %s", k, actionOf(other), action)
+			}
+			keyStrings[k] = name
+		}
+	}
+	GlobalkeyBindings = bindings
+	GlobalKeyStringsMap = keyStrings
+	return nil
+}
+
+// HelpKey returns the keys of the action as shown in the help, e.g. "↵/o".
+func HelpKey(name KeyName) string {
+	return GlobalkeyBindings[name].Help().Key
+}
+
+func helpKeys(keys []string) string {
+	shown := make([]string, len(keys))
+	for i, k := range keys {
+		shown[i] = k
+		if name, ok := helpKeyNames[k]; ok {
+			shown[i] = name
+		}
+	}
+	return strings.Join(shown, "/")
+}
+
+// sortedActions returns the names of the actions in order, so conflicts are reported the same way.
+func sortedActions() []string {
+	actions := make([]string, 0, len(KeyActions))
+	for action := range KeyActions {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	return actions
+}
+
+func actionOf(name KeyName) string {
+	if action, ok := ActionName(name); ok {
+		return action
+	}
+	return fmt.Sprintf("key %d", name)
+}
+
+// ActionName returns the name of the action of the keybinding in the keys section of the config, e.g.
+// "kill", if it has one.
+func ActionName(name KeyName) (string, bool) {
+	for action, n := range KeyActions {
+		if n == name {
+			return action, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,53 @@
+package keys
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func restoreKeys(t *testing.T) {
+	bindings, keyStrings := GlobalkeyBindings, GlobalKeyStringsMap
+	t.Cleanup(func() {
+		GlobalkeyBindings, GlobalKeyStringsMap = bindings, keyStrings
+	})
+}
+
+func TestKeyActionsCoverKeyStrings(t *testing.T) {
+	actions := make(map[KeyName]bool)
+	for _, name := range KeyActions {
+		actions[name] = true
+	}
+	for k, name := range GlobalKeyStringsMap {
+		assert.True(t, actions[name], "no action for key %q", k)
+		assert.Contains(t, GlobalkeyBindings[name].Keys(), k)
+	}
+}
+
+func TestRemap(t *testing.T) {
+	restoreKeys(t)
+	defaults := GlobalKeyStringsMap
+
+	require.NoError(t, Remap(map[string][]string{"kill": {"X"}, "open": {"enter", "l"}}))
+	assert.Equal(t, KeyKill, GlobalKeyStringsMap["X"])
+	assert.NotContains(t, GlobalKeyStringsMap, "D")
+	assert.Equal(t, KeyEnter, GlobalKeyStringsMap["l"])
+	assert.NotContains(t, GlobalKeyStringsMap, "o")
+	assert.Equal(t, "X", HelpKey(KeyKill))
+	assert.Equal(t, "↵/l", HelpKey(KeyEnter))
+	assert.Equal(t, "kill", GlobalkeyBindings[KeyKill].Help().Desc)
+	// The other keys are kept.
+	assert.Equal(t, defaults["n"], GlobalKeyStringsMap["n"])
+	assert.Len(t, GlobalKeyStringsMap, len(defaults))
+}
+
+func TestRemapErrors(t *testing.T) {
+	restoreKeys(t)
+	defaults := GlobalKeyStringsMap
+
+	assert.ErrorContains(t, Remap(map[string][]string{"explode": {"x"}}), `unknown action "explode"`)
+	assert.ErrorContains(t, Remap(map[string][]string{"kill": {"n"}}), `key "n" is bound to both kill and new`)
+	assert.ErrorContains(t, Remap(map[string][]string{"kill": {}}), `no keys for action "kill"`)
+	assert.Equal(t, defaults, GlobalKeyStringsMap)
+}
@@ -1,10 +1,12 @@
 package log
 
 import (
+	"bufio"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -74,3 +76,40 @@ func (e *Every) ShouldLog() bool {
 		return false
 	}
 }
+
+// logTimeLayout is the layout of the timestamps written by the loggers (log.Ldate|log.Ltime).
+const logTimeLayout = "2006/01/02 15:04:05"
+
+// ErrorsSince returns the messages of the errors logged after since, oldest first. Errors logged by the
+// daemon are included.
+func ErrorsSince(since time.Time) ([]string, error) {
+	f, err := os.Open(logFileName)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer f.Close()
+
+	var errs []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		idx := strings.Index(line, "ERROR:")
+		if idx < 0 {
+			continue
+		}
+		rest := line[idx+len("ERROR:"):]
+		if len(rest) < len(logTimeLayout) {
+			continue
+		}
+		t, err := time.ParseInLocation(logTimeLayout, rest[:len(logTimeLayout)], time.Local)
+		if err != nil || t.Before(since) {
+			continue
+		}
+		errs = append(errs, strings.TrimSpace(rest[len(logTimeLayout):]))
+	}
+	return errs, scanner.Err()
+}
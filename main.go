@@ -1,83 +1,1417 @@
 package main
 
 import (
+	"bufio"
+	"claude-squad/analyze"
 	"claude-squad/app"
-	cmd2 "claude-squad/cmd"
 	"claude-squad/config"
 	"claude-squad/daemon"
+	"claude-squad/doctor"
+	"claude-squad/github"
 	"claude-squad/log"
+	"claude-squad/pipeline"
+	"claude-squad/report"
 	"claude-squad/session"
+	"claude-squad/session/claude"
 	"claude-squad/session/git"
-	"claude-squad/session/tmux"
+	"claude-squad/snapshot"
+	"claude-squad/telemetry"
+	"claude-squad/template"
+	"claude-squad/tracing"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"os/signal"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/atotto/clipboard"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
 var (
-	version     = "1.0.5"
-	programFlag string
-	autoYesFlag bool
-	daemonFlag  bool
-	rootCmd     = &cobra.Command{
+	version        = "1.0.5"
+	programFlag    string
+	autoYesFlag    bool
+	daemonFlag     bool
+	fromIssueFlag  string
+	newPromptFlag  string
+	newProgramFlag string
+	sinceFlag      time.Duration
+	copyFlag       bool
+	newScopeFlag   []string
+	stackOnFlag    string
+	branchFlag     string
+	remoteBranch   string
+	templateFlag   string
+	fanOutCount    int
+	fanOutPrograms []string
+	migratePath    string
+	gistFlag       bool
+	outputFlag     string
+	formatFlag     string
+	migrateCommand string
+	receivePath    string
+	hostFlag       string
+	hostPathFlag   string
+	runPathFlag    string
+	runTitleFlag   string
+	runTimeoutFlag time.Duration
+	repoFlag       string
+	resourceFlag   []string
+	runTestsFlag   bool
+	yesFlag        bool
+	profileFlag    string
+	forceFlag      bool
+	timeBudgetFlag time.Duration
+	providerFlag   string
+	historySince   time.Duration
+	historyJSON    bool
+	rootCmd        = &cobra.Command{
 		Use:   "claude-squad",
 		Short: "Claude Squad - Manage multiple AI agents like Claude Code, Aider, Codex, and Amp.",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// Everything reads its config and state from the config directory of the profile, so it's
+			// selected first.
+			if cmd.Flags().Changed("profile") {
+				if err := config.SetProfile(profileFlag); err != nil {
+					return err
+				}
+			}
+			telemetry.Configure(config.LoadTelemetry())
+			tracing.Configure(config.LoadTracing(), version)
+			// The hidden commands are run by claude-squad itself, e.g. by the hooks of claude.
+			if cmd != cmd.Root() && !cmd.Hidden {
+				telemetry.Feature("command:" + strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" "))
+			}
+			// Everything resolves the repository from the current directory, so --repo is the same as
+			// running from it.
+			if repoFlag == "" {
+				return nil
+			}
+			if err := os.Chdir(repoFlag); err != nil {
+				return fmt.Errorf("failed to use repository %s: %w", repoFlag, err)
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			log.Initialize(daemonFlag)
+			defer log.Close()
+
+			if daemonFlag {
+				cfg := config.LoadConfig()
+				err := daemon.RunDaemon(cfg)
+				log.ErrorLog.Printf("failed to start daemon %v", err)
+				return err
+			}
+
+			// Check if we're in a git repository
+			currentDir, err := filepath.Abs(".")
+			if err != nil {
+				return fmt.Errorf("failed to get current directory: %w", err)
+			}
+
+			if !git.IsGitRepo(currentDir) {
+				return fmt.Errorf("error: claude-squad must be run from within a git repository, or pointed at one with --repo")
+			}
+			if doctor.FirstRun() && term.IsTerminal(int(os.Stdin.Fd())) {
+				if err := runWizard(); err != nil {
+					return err
+				}
+			}
+
+			// The app quits to switch to another profile, and is run again with its config.
+			for {
+				cfg := config.LoadConfig()
+
+				// Program flag overrides config
+				program := cfg.DefaultProgram
+				if programFlag != "" {
+					program = programFlag
+				}
+				// AutoYes flag overrides config
+				autoYes := cfg.AutoYes
+				if autoYesFlag {
+					autoYes = true
+				}
+				// Kill any daemon that's running.
+				if err := daemon.StopDaemon(); err != nil {
+					log.ErrorLog.Printf("failed to stop daemon: %v", err)
+				}
+
+				nextProfile, err := app.Run(ctx, program, autoYes)
+				// The daemon only makes sense if the sessions outlive this process.
+				if autoYes && session.TerminalsPersist {
+					if err := daemon.LaunchDaemon(); err != nil {
+						log.ErrorLog.Printf("failed to launch daemon: %v", err)
+					}
+				}
+				if err != nil || nextProfile == "" {
+					return err
+				}
+				if err := config.SetProfile(nextProfile); err != nil {
+					return err
+				}
+			}
+		},
+	}
+
+	resetCmd = &cobra.Command{
+		Use:   "reset",
+		Short: "Reset all stored instances",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log.Initialize(false)
+			defer log.Close()
+
+			state := config.LoadState()
+			storage, err := session.NewStorage(state)
+			if err != nil {
+				return fmt.Errorf("failed to initialize storage: %w", err)
+			}
+			// Branches the instances reused aren't theirs to delete.
+			keepBranches, err := storage.ExistingBranches()
+			if err != nil {
+				return err
+			}
+			if err := storage.DeleteAllInstances(); err != nil {
+				return fmt.Errorf("failed to reset storage: %w", err)
+			}
+			fmt.Println("Storage has been reset successfully")
+
+			if err := session.CleanupTerminals(); err != nil {
+				return fmt.Errorf("failed to cleanup tmux sessions: %w", err)
+			}
+			fmt.Println("Tmux sessions have been cleaned up")
+
+			if err := git.CleanupWorktrees(keepBranches); err != nil {
+				return fmt.Errorf("failed to cleanup worktrees: %w", err)
+			}
+			fmt.Println("Worktrees have been cleaned up")
+
+			// Kill any daemon that's running.
+			if err := daemon.StopDaemon(); err != nil {
+				return err
+			}
+			fmt.Println("daemon has been stopped")
+
+			return nil
+		},
+	}
+
+	newCmd = &cobra.Command{
+		Use:   "new [title]",
+		Short: "Create a new instance in the background",
+		Long: "Create a new instance in the background. With --from-issue, the instance and its branch are named " +
+			"after the GitHub issue and the issue description is sent as the initial prompt. With --branch, the " +
+			"instance checks out an existing branch instead of creating one. With --remote-branch, it fetches the " +
+			"branch from its remote first and the agent is asked to review and fix it. With --host, the worktree and the " +
+			"session are created on another machine over ssh. With --resource, the prompt waits while other instances " +
+			"use the external resource. With --time-budget, the agent is asked to wrap up when the budget is nearly " +
+			"exhausted, and the instance is committed and paused when it runs out.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log.Initialize(false)
+			defer log.Close()
+
+			currentDir, err := filepath.Abs(".")
+			if err != nil {
+				return fmt.Errorf("failed to get current directory: %w", err)
+			}
+			path := currentDir
+			if hostFlag != "" {
+				path = hostPathFlag
+				if path == "" {
+					path = session.RemoteRepoPath(currentDir)
+				}
+			} else if !git.IsGitRepo(currentDir) {
+				return fmt.Errorf("error: claude-squad must be run from within a git repository, or pointed at one with --repo")
+			}
+
+			var title, prompt, program string
+			var teardown, resources []string
+			if len(args) > 0 {
+				title = args[0]
+			}
+			prompt = newPromptFlag
+			if templateFlag != "" {
+				templates, err := template.LoadTemplates()
+				if err != nil {
+					return err
+				}
+				t, err := template.Find(templates, templateFlag)
+				if err != nil {
+					return err
+				}
+				if title == "" {
+					title = session.TitleFromText(t.Name + " " + prompt)
+				}
+				prompt = t.InitialPrompt(prompt)
+				program = t.Program
+				teardown = t.Teardown
+				resources = t.Resources
+			}
+			if fromIssueFlag != "" {
+				ref, err := github.ParseIssueRef(fromIssueFlag)
+				if err != nil {
+					return err
+				}
+				issue, err := github.FetchIssue(ref, currentDir)
+				if err != nil {
+					return err
+				}
+				if title == "" {
+					title = issue.InstanceTitle()
+				}
+				if prompt == "" {
+					prompt = issue.Prompt()
+				}
+			}
+			branch := branchFlag
+			if remoteBranch != "" {
+				if branchFlag != "" || hostFlag != "" || stackOnFlag != "" {
+					return fmt.Errorf("--remote-branch can't be used with --branch, --host or --stack-on")
+				}
+				if branch, err = git.FetchRemoteBranch(path, remoteBranch); err != nil {
+					return err
+				}
+			}
+			if title == "" && branch != "" {
+				title = session.TitleFromText(branch)
+			}
+			if title == "" {
+				return fmt.Errorf("a title, --from-issue, --template, --branch or --remote-branch is required")
+			}
+
+			cfg := config.LoadConfig()
+			if newProgramFlag != "" && providerFlag != "" {
+				return fmt.Errorf("--program and --provider can't be used together")
+			}
+			if newProgramFlag != "" {
+				program = newProgramFlag
+			} else if providerFlag != "" {
+				provider, ok := session.FindProvider(cfg.Providers, providerFlag, "")
+				if !ok {
+					return fmt.Errorf("provider %s isn't configured", providerFlag)
+				}
+				program = provider.Program
+			} else if program == "" {
+				program = cfg.DefaultProgram
+			}
+			// The agent reviews the branch unless it's told what to do.
+			if remoteBranch != "" && prompt == "" {
+				prompt = cfg.GetReviewPrompt()
+			}
+
+			state := config.LoadState()
+			storage, err := session.NewStorage(state)
+			if err != nil {
+				return fmt.Errorf("failed to initialize storage: %w", err)
+			}
+			instances, err := storage.LoadInstances()
+			if err != nil {
+				return fmt.Errorf("failed to load instances: %w", err)
+			}
+			if len(instances) >= app.GlobalInstanceLimit {
+				return fmt.Errorf("you can't create more than %d instances", app.GlobalInstanceLimit)
+			}
+			var parent *session.Instance
+			for _, existing := range instances {
+				if existing.Title == title {
+					return fmt.Errorf("instance %s already exists", title)
+				}
+				if existing.Title == stackOnFlag {
+					parent = existing
+				}
+			}
+			if stackOnFlag != "" && parent == nil {
+				return fmt.Errorf("instance %s not found", stackOnFlag)
+			}
+			if err := session.CheckProviderCapacity(cfg.Providers, providerFlag, program, instances); err != nil {
+				return err
+			}
+			for _, resource := range resourceFlag {
+				if !slices.Contains(resources, resource) {
+					resources = append(resources, resource)
+				}
+			}
+			if err := session.CheckExternalResources(cfg.ExternalResources, resources); err != nil {
+				return err
+			}
+
+			instance, err := session.NewInstance(session.InstanceOptions{
+				Title:    title,
+				Path:     path,
+				Program:  program,
+				Scopes:   newScopeFlag,
+				Parent:   parent,
+				Branch:   branch,
+				Teardown: teardown,
+				Host:     hostFlag,
+
+				ExternalResources: resources,
+				TimeBudget:        timeBudgetFlag,
+				Provider:          providerFlag,
+			})
+			if err != nil {
+				return err
+			}
+			queued, err := instance.StartOrQueue(cfg.MaxRunningInstances, instances)
+			if err != nil {
+				return fmt.Errorf("failed to start instance: %w", err)
+			}
+			instances = append(instances, instance)
+			if _, err := instance.ClaimResources(cfg.ExternalResources, instances); err != nil {
+				return err
+			}
+			// A prompt waiting for the resources is queued in the instance, so it's saved with it.
+			var promptErr error
+			if prompt != "" {
+				promptErr = instance.SendPrompt(prompt)
+			}
+			if err := storage.SaveInstances(instances); err != nil {
+				return fmt.Errorf("failed to save instances: %w", err)
+			}
+			if promptErr != nil {
+				return fmt.Errorf("failed to send prompt: %w", promptErr)
+			}
+
+			if queued {
+				fmt.Printf("Created instance '%s' on branch %s, queued until a running slot is free\n",
+					instance.Title, instance.Branch)
+				return nil
+			}
+			if instance.WaitingForResources {
+				fmt.Printf("Created instance '%s' on branch %s, waiting for %s\n", instance.Title, instance.Branch,
+					strings.Join(instance.ExternalResources, ", "))
+				return nil
+			}
+			fmt.Printf("Created instance '%s' on branch %s\n", instance.Title, instance.Branch)
+			return nil
+		},
+	}
+
+	runCmd = &cobra.Command{
+		Use:   "run",
+		Short: "Run a prompt in a new instance until the agent is done, for CI and scripts",
+		Long: "Create an instance, send it the prompt and answer its prompts like auto-yes until the agent is done " +
+			"or --timeout passes. Then its changes are committed, the instance is paused and its branch and the " +
+			"files it changed are printed as JSON. It exits with an error if the agent didn't finish.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log.Initialize(false)
+			defer log.Close()
+
+			path, err := filepath.Abs(runPathFlag)
+			if err != nil {
+				return fmt.Errorf("failed to get the path of the repository: %w", err)
+			}
+			if !git.IsGitRepo(path) {
+				return fmt.Errorf("error: %s is not a git repository", path)
+			}
+
+			cfg := config.LoadConfig()
+			program := cfg.DefaultProgram
+			if newProgramFlag != "" {
+				program = newProgramFlag
+			}
+			stuckPatterns, err := cfg.GetStuckPatterns()
+			if err != nil {
+				return err
+			}
+			readyDetectors, err := cfg.GetReadyDetectors()
+			if err != nil {
+				return err
+			}
+			policy, err := session.NewApprovalPolicy(cfg.AutoYesPolicy)
+			if err != nil {
+				return err
+			}
+
+			state := config.LoadState()
+			storage, err := session.NewStorage(state)
+			if err != nil {
+				return fmt.Errorf("failed to initialize storage: %w", err)
+			}
+			instances, err := storage.LoadInstances()
+			if err != nil {
+				return fmt.Errorf("failed to load instances: %w", err)
+			}
+			if len(instances) >= app.GlobalInstanceLimit {
+				return fmt.Errorf("you can't create more than %d instances", app.GlobalInstanceLimit)
+			}
+			if err := session.CheckProviderCapacity(cfg.Providers, "", program, instances); err != nil {
+				return err
+			}
+			title := runTitleFlag
+			if title == "" {
+				title = session.TitleFromText(newPromptFlag)
+			}
+
+			instance, err := session.NewInstance(session.InstanceOptions{
+				Title:   session.UniqueTitle(title, instances),
+				Path:    path,
+				Program: program,
+			})
+			if err != nil {
+				return err
+			}
+			defer instance.ActAs("run")()
+			instance.AutoYes = true
+			if err := instance.Start(true); err != nil {
+				return fmt.Errorf("failed to start instance: %w", err)
+			}
+			instances = append(instances, instance)
+			if err := storage.SaveInstances(instances); err != nil {
+				return fmt.Errorf("failed to save instances: %w", err)
+			}
+			if err := instance.SendPrompt(newPromptFlag); err != nil {
+				return fmt.Errorf("failed to send prompt: %w", err)
+			}
+
+			start := time.Now()
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			ctx, cancel := context.WithTimeout(ctx, runTimeoutFlag)
+			defer cancel()
+			waitErr := instance.WaitDone(ctx, time.Duration(cfg.DaemonPollInterval)*time.Millisecond, stuckPatterns,
+				readyDetectors, policy)
+			status := "done"
+			switch {
+			case errors.Is(waitErr, context.DeadlineExceeded):
+				status = "timeout"
+				waitErr = fmt.Errorf("the agent didn't finish within %s", runTimeoutFlag)
+			case errors.Is(waitErr, context.Canceled):
+				status = "interrupted"
+			case errors.Is(waitErr, session.ErrNeedsAttention):
+				status = "needs_attention"
+			case waitErr != nil:
+				status = "failed"
+			}
+
+			if err := instance.UpdateDiffStats(); err != nil {
+				log.WarningLog.Printf("could not update diff stats for %s: %v", instance.Title, err)
+			}
+			var errs []error
+			commitMsg := instance.CommitMessage(cfg.CommitStrategy, session.CommitReasonRun, fmt.Sprintf("[claudesquad] run of '%s'", instance.Title))
+			if err := instance.CommitWork(commitMsg); err != nil {
+				errs = append(errs, fmt.Errorf("failed to commit changes: %w", err))
+			}
+			if err := instance.Pause(); err != nil {
+				errs = append(errs, fmt.Errorf("failed to pause instance: %w", err))
+			}
+			if err := storage.SaveInstances(instances); err != nil {
+				errs = append(errs, fmt.Errorf("failed to save instances: %w", err))
+			}
+
+			result, err := json.MarshalIndent(instance.BatchResult(status, time.Since(start)), "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(result))
+			return errors.Join(append([]error{waitErr}, errs...)...)
+		},
+	}
+
+	fanOutCmd = &cobra.Command{
+		Use:   "fanout [group]",
+		Short: "Run the same prompt across several instances to compare the results",
+		Long: "Create several instances from the same base and send each of them the same prompt. The instances " +
+			"form a comparison group: press V in the UI to compare their diffs side by side and keep the best one.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log.Initialize(false)
+			defer log.Close()
+
+			currentDir, err := filepath.Abs(".")
+			if err != nil {
+				return fmt.Errorf("failed to get current directory: %w", err)
+			}
+			if !git.IsGitRepo(currentDir) {
+				return fmt.Errorf("error: claude-squad must be run from within a git repository, or pointed at one with --repo")
+			}
+
+			group := session.FanOutGroupName(newPromptFlag)
+			if len(args) > 0 {
+				group = args[0]
+			}
+
+			cfg := config.LoadConfig()
+			count := cfg.GetFanOutCount()
+			if fanOutCount > 0 {
+				count = fanOutCount
+			}
+			programs := cfg.FanOutPrograms
+
+			state := config.LoadState()
+			storage, err := session.NewStorage(state)
+			if err != nil {
+				return fmt.Errorf("failed to initialize storage: %w", err)
+			}
+			instances, err := storage.LoadInstances()
+			if err != nil {
+				return fmt.Errorf("failed to load instances: %w", err)
+			}
+			if len(instances)+count > app.GlobalInstanceLimit {
+				return fmt.Errorf("you can't create more than %d instances", app.GlobalInstanceLimit)
+			}
+			for _, existing := range instances {
+				if existing.Group == group {
+					return fmt.Errorf("fan-out group %s already exists", group)
+				}
+			}
+			if len(fanOutPrograms) > 0 {
+				programs = fanOutPrograms
+			}
+			options := session.FanOutOptions(group, currentDir, count, cfg.DefaultProgram, programs)
+			if len(fanOutPrograms) == 0 && len(cfg.Providers) > 0 {
+				providers, err := session.AssignProviders(cfg.Providers, cfg.ProviderPolicy, newPromptFlag, count, instances)
+				if err != nil {
+					return err
+				}
+				options = session.FanOutProviderOptions(group, currentDir, providers)
+			}
+
+			for _, opts := range options {
+				instance, err := session.NewInstance(opts)
+				if err != nil {
+					return err
+				}
+				if _, err := instance.StartOrQueue(cfg.MaxRunningInstances, instances); err != nil {
+					return fmt.Errorf("failed to start instance %s: %w", opts.Title, err)
+				}
+				instances = append(instances, instance)
+				if err := storage.SaveInstances(instances); err != nil {
+					return fmt.Errorf("failed to save instances: %w", err)
+				}
+				if err := instance.SendPrompt(newPromptFlag); err != nil {
+					return fmt.Errorf("failed to send prompt to %s: %w", opts.Title, err)
+				}
+				if instance.Queued() {
+					fmt.Printf("Created instance '%s' running %s on branch %s, queued until a running slot is free\n",
+						instance.Title, instance.Program, instance.Branch)
+					continue
+				}
+				fmt.Printf("Created instance '%s' running %s on branch %s\n", instance.Title, instance.Program,
+					instance.Branch)
+			}
+			return nil
+		},
+	}
+
+	templatesCmd = &cobra.Command{
+		Use:   "templates",
+		Short: "Manage instance template packs",
+	}
+
+	templatesImportCmd = &cobra.Command{
+		Use:   "import <url|file>",
+		Short: "Import a YAML template pack from a URL or file",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pack, err := template.Import(args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Imported %d template(s) from pack '%s'\n", len(pack.Templates), pack.Name)
+			return nil
+		},
+	}
+
+	templatesListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List the imported templates and where they come from",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			packs, err := template.LoadPacks()
+			if err != nil {
+				return err
+			}
+			if len(packs) == 0 {
+				fmt.Println("No template packs imported.")
+				return nil
+			}
+			for _, pack := range packs {
+				fmt.Printf("%s", pack.Name)
+				if provenance := pack.Templates[0].Provenance(); provenance != "" {
+					fmt.Printf(" (%s)", provenance)
+				}
+				fmt.Println()
+				for _, t := range pack.Templates {
+					fmt.Printf("  %s", t.FullName())
+					if t.Description != "" {
+						fmt.Printf(" - %s", t.Description)
+					}
+					fmt.Println()
+				}
+			}
+			return nil
+		},
+	}
+
+	templatesRemoveCmd = &cobra.Command{
+		Use:   "remove <pack>",
+		Short: "Remove an imported template pack",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return template.Remove(args[0])
+		},
+	}
+
+	archiveCmd = &cobra.Command{
+		Use:   "archive",
+		Short: "Inspect and resurrect archived instances",
+	}
+
+	archiveListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List the archived instances",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log.Initialize(false)
+			defer log.Close()
+
+			storage, err := session.NewStorage(config.LoadState())
+			if err != nil {
+				return fmt.Errorf("failed to initialize storage: %w", err)
+			}
+			archived, err := storage.ArchivedInstances()
+			if err != nil {
+				return err
+			}
+			if len(archived) == 0 {
+				fmt.Println("No archived instances.")
+				return nil
+			}
+			for _, instance := range archived {
+				fmt.Printf("%s\t%s\t+%d -%d\tarchived %s\n", instance.Title, instance.Branch,
+					instance.DiffStats.Added, instance.DiffStats.Removed, instance.ArchivedAt.Format("2006-01-02 15:04"))
+			}
+			return nil
+		},
+	}
+
+	archiveShowCmd = &cobra.Command{
+		Use:   "show <title>",
+		Short: "Print the metadata and the final diff of an archived instance",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log.Initialize(false)
+			defer log.Close()
+
+			storage, err := session.NewStorage(config.LoadState())
+			if err != nil {
+				return fmt.Errorf("failed to initialize storage: %w", err)
+			}
+			archived, err := findArchived(storage, args[0])
+			if err != nil {
+				return err
+			}
+			if archived, err = session.RestoreArchive(archived); err != nil {
+				return err
+			}
+			patterns, err := config.LoadConfig().GetRedactPatterns()
+			if err != nil {
+				return err
+			}
+			fmt.Println(report.Redact(archived.Summary(), patterns))
+			if archived.DiffStats.Content != "" {
+				fmt.Printf("\n%s", report.Redact(archived.DiffStats.Content, patterns))
+			}
+			return nil
+		},
+	}
+
+	archiveResurrectCmd = &cobra.Command{
+		Use:   "resurrect <title>",
+		Short: "Recreate the worktree of an archived instance on its branch and restart it",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log.Initialize(false)
+			defer log.Close()
+
+			storage, err := session.NewStorage(config.LoadState())
+			if err != nil {
+				return fmt.Errorf("failed to initialize storage: %w", err)
+			}
+			archived, err := findArchived(storage, args[0])
+			if err != nil {
+				return err
+			}
+			instances, err := storage.LoadInstances()
+			if err != nil {
+				return fmt.Errorf("failed to load instances: %w", err)
+			}
+			if len(instances) >= app.GlobalInstanceLimit {
+				return fmt.Errorf("you can't create more than %d instances", app.GlobalInstanceLimit)
+			}
+			for _, existing := range instances {
+				if existing.Title == archived.Title {
+					return fmt.Errorf("instance %s already exists", archived.Title)
+				}
+			}
+			if err := session.CheckProviderCapacity(config.LoadConfig().Providers, archived.Provider, archived.Program, instances); err != nil {
+				return err
+			}
+
+			restored, err := session.RestoreArchive(archived)
+			if err != nil {
+				return err
+			}
+			instance, err := session.ResurrectInstance(restored)
+			if err != nil {
+				return err
+			}
+			if err := storage.SaveInstances(append(instances, instance)); err != nil {
+				return fmt.Errorf("failed to save instances: %w", err)
+			}
+			if err := storage.DeleteArchivedInstance(archived); err != nil {
+				return err
+			}
+
+			fmt.Printf("Resurrected instance '%s' on branch %s\n", instance.Title, instance.Branch)
+			return nil
+		},
+	}
+
+	archivePruneCmd = &cobra.Command{
+		Use:   "prune",
+		Short: "Delete the offloaded archives older than artifact_storage.expire_days",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log.Initialize(false)
+			defer log.Close()
+
+			storage, err := session.NewStorage(config.LoadState())
+			if err != nil {
+				return fmt.Errorf("failed to initialize storage: %w", err)
+			}
+			deleted, err := storage.PruneArchivedInstances(time.Now())
+			fmt.Printf("Deleted %d expired archive(s)\n", deleted)
+			return err
+		},
+	}
+
+	stateCmd = &cobra.Command{
+		Use:   "state",
+		Short: "List and restore the backups of the state file, which holds the instances",
+	}
+
+	stateListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List the backups of the state file, newest first",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log.Initialize(false)
+			defer log.Close()
+
+			backups, err := config.ListStateBackups()
+			if err != nil {
+				return err
+			}
+			if len(backups) == 0 {
+				fmt.Println("No backups of the state file.")
+				return nil
+			}
+			for i, backup := range backups {
+				if backup.Err != nil {
+					fmt.Printf("%d	%s	unreadable: %v\n", i+1, backup.Time.Format("2006-01-02 15:04:05"), backup.Err)
+					continue
+				}
+				fmt.Printf("%d	%s	%d instance(s)\n", i+1, backup.Time.Format("2006-01-02 15:04:05"), backup.Instances)
+			}
+			return nil
+		},
+	}
+
+	stateRestoreCmd = &cobra.Command{
+		Use:   "restore [n]",
+		Short: "Restore the state file from a backup, the newest readable one or the nth of `cs state list`",
+		Long: "Restore the state file from a backup, e.g. after an unclean shutdown corrupted it. Without n, the " +
+			"newest readable backup is restored. The state file it replaces is backed up first, so restoring can be " +
+			"undone. Quit the app first, since it would save its own instances over the restored ones; the daemon " +
+			"is stopped. The diffs and last screens of paused instances aren't backed up, so the ones killed since " +
+			"the backup come back without them.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log.Initialize(false)
+			defer log.Close()
+
+			backups, err := config.ListStateBackups()
+			if err != nil {
+				return err
+			}
+			var backup *config.StateBackup
+			if len(args) > 0 {
+				n, err := strconv.Atoi(args[0])
+				if err != nil || n < 1 || n > len(backups) {
+					return fmt.Errorf("no backup %s, pick one of `cs state list`", args[0])
+				}
+				backup = &backups[n-1]
+			} else {
+				for i := range backups {
+					if backups[i].Err == nil {
+						backup = &backups[i]
+						break
+					}
+				}
+				if backup == nil {
+					return fmt.Errorf("no readable backup of the state file to restore")
+				}
+			}
+			if backup.Err != nil {
+				return backup.Err
+			}
+
+			if err := daemon.StopDaemon(); err != nil {
+				return err
+			}
+			if err := config.RestoreState(*backup); err != nil {
+				return err
+			}
+			fmt.Printf("Restored the state file from the backup of %s, with %d instance(s)\n",
+				backup.Time.Format("2006-01-02 15:04:05"), backup.Instances)
+			return nil
+		},
+	}
+
+	migrateCmd = &cobra.Command{
+		Use:   "migrate <title> <host>",
+		Short: "Move an instance to another machine running claude-squad",
+		Long: "Move an instance to another machine running claude-squad over ssh. Its branch is pushed to origin, " +
+			"the instance is archived here, and its metadata and Claude conversation are sent to the other " +
+			"machine, which checks the branch out in the same repository and resumes the conversation.",
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log.Initialize(false)
+			defer log.Close()
+
+			title, host := args[0], args[1]
+			storage, err := session.NewStorage(config.LoadState())
+			if err != nil {
+				return fmt.Errorf("failed to initialize storage: %w", err)
+			}
+			instances, err := storage.LoadInstances()
+			if err != nil {
+				return fmt.Errorf("failed to load instances: %w", err)
+			}
+			idx := slices.IndexFunc(instances, func(instance *session.Instance) bool {
+				return instance.Title == title
+			})
+			if idx < 0 {
+				return fmt.Errorf("instance %s not found", title)
+			}
+			instance := instances[idx]
+			remotePath := migratePath
+			if remotePath == "" {
+				worktree, err := instance.GetGitWorktree()
+				if err != nil {
+					return err
+				}
+				remotePath = session.RemoteRepoPath(worktree.GetRepoPath())
+			}
+
+			// Pushing first leaves the instance here if it fails, instead of archived with its work unpushed.
+			if err := instance.PushForMigration(); err != nil {
+				return err
+			}
+			archived, err := instance.Archive()
+			if err != nil {
+				return err
+			}
+			if err := storage.ArchiveInstance(archived); err != nil {
+				return err
+			}
+			if err := storage.SaveInstances(slices.Delete(instances, idx, idx+1)); err != nil {
+				return fmt.Errorf("failed to save instances: %w", err)
+			}
+
+			bundle, err := session.NewMigrationBundle(archived)
+			if err != nil {
+				return fmt.Errorf("%w\nthe instance is archived, resurrect it with `cs archive resurrect %s`", err, title)
+			}
+			output, err := session.SendMigration(bundle, host, migrateCommand, remotePath)
+			if err != nil {
+				return fmt.Errorf("%w\nthe instance is archived, resurrect it with `cs archive resurrect %s`", err, title)
+			}
+			fmt.Print(output)
+			fmt.Printf("Migrated instance '%s' to %s, it's kept archived here\n", title, host)
+			return nil
+		},
+	}
+
+	receiveMigrationCmd = &cobra.Command{
+		Use:    "receive-migration",
+		Short:  "Receive an instance migrated from another machine on stdin",
+		Hidden: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log.Initialize(false)
+			defer log.Close()
+
+			var bundle session.InstanceBundle
+			if err := json.NewDecoder(os.Stdin).Decode(&bundle); err != nil {
+				return fmt.Errorf("failed to read migration bundle: %w", err)
+			}
+
+			storage, err := session.NewStorage(config.LoadState())
+			if err != nil {
+				return fmt.Errorf("failed to initialize storage: %w", err)
+			}
+			instances, err := storage.LoadInstances()
+			if err != nil {
+				return fmt.Errorf("failed to load instances: %w", err)
+			}
+			if len(instances) >= app.GlobalInstanceLimit {
+				return fmt.Errorf("you can't create more than %d instances", app.GlobalInstanceLimit)
+			}
+			for _, existing := range instances {
+				if existing.Title == bundle.Instance.Title {
+					return fmt.Errorf("instance %s already exists", bundle.Instance.Title)
+				}
+			}
+
+			instance, err := session.ReceiveMigration(bundle, receivePath)
+			if err != nil {
+				return err
+			}
+			if err := storage.SaveInstances(append(instances, instance)); err != nil {
+				return fmt.Errorf("failed to save instances: %w", err)
+			}
+			fmt.Printf("Received instance '%s' on branch %s\n", instance.Title, instance.Branch)
+			return nil
+		},
+	}
+
+	hookCmd = &cobra.Command{
+		Use:    "hook <events-file>",
+		Short:  "Record a Claude Code hook event read from stdin",
+		Hidden: true,
+		Args:   cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log.Initialize(false)
+			defer log.Close()
+
+			// A failing hook would get in the way of claude, so errors are only logged.
+			input, err := io.ReadAll(os.Stdin)
+			if err == nil {
+				err = session.RecordHookEvent(args[0], input)
+			}
+			if err != nil {
+				log.ErrorLog.Printf("failed to record hook event: %v", err)
+			}
+			return nil
+		},
+	}
+
+	snapshotCmd = &cobra.Command{
+		Use:   "snapshot",
+		Short: "Share the diff and summary of an instance read-only",
+	}
+
+	snapshotCreateCmd = &cobra.Command{
+		Use:   "create <title>",
+		Short: "Save the diff and summary of an instance as an HTML page, or publish them as a secret gist",
+		Long: "Save the diff and summary of an instance as a self-contained HTML page, or publish them as a secret " +
+			"gist with the gh command line tool, so others can look at them without pulling the branch. " +
+			"Snapshots expire after snapshot_expire_days and are deleted then.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log.Initialize(false)
+			defer log.Close()
+
+			storage, err := session.NewStorage(config.LoadState())
+			if err != nil {
+				return fmt.Errorf("failed to initialize storage: %w", err)
+			}
+			instances, err := storage.LoadInstances()
+			if err != nil {
+				return fmt.Errorf("failed to load instances: %w", err)
+			}
+			idx := slices.IndexFunc(instances, func(instance *session.Instance) bool {
+				return instance.Title == args[0]
+			})
+			if idx < 0 {
+				return fmt.Errorf("instance %s not found", args[0])
+			}
+			instance := instances[idx]
+			if !instance.Paused() {
+				if err := instance.UpdateDiffStats(); err != nil {
+					log.WarningLog.Printf("could not update diff stats for %s: %v", instance.Title, err)
+				}
+			}
+
+			cfg := config.LoadConfig()
+			patterns, err := cfg.GetRedactPatterns()
+			if err != nil {
+				return err
+			}
+			now := time.Now()
+			snap := snapshot.Of(instance, patterns, now, cfg.GetSnapshotTTL())
+			var record snapshot.Record
+			if gistFlag {
+				record, err = snapshot.PublishGist(snap)
+			} else {
+				path := outputFlag
+				if path == "" {
+					if path, err = snapshot.DefaultPath(instance.Title, now); err != nil {
+						return err
+					}
+				}
+				record, err = snapshot.Save(snap, path)
+			}
+			if record.Location() != "" {
+				fmt.Printf("%s (expires %s)\n", record.Location(), record.ExpiresAt.Format("2006-01-02 15:04"))
+			}
+			return err
+		},
+	}
+
+	snapshotListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List the snapshots that haven't been deleted yet",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			records, err := snapshot.Records()
+			if err != nil {
+				return err
+			}
+			if len(records) == 0 {
+				fmt.Println("No snapshots.")
+				return nil
+			}
+			for _, record := range records {
+				fmt.Printf("%s\t%s\texpires %s\n", record.Title, record.Location(), record.ExpiresAt.Format("2006-01-02 15:04"))
+			}
+			return nil
+		},
+	}
+
+	snapshotPruneCmd = &cobra.Command{
+		Use:   "prune",
+		Short: "Delete the expired snapshots",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log.Initialize(false)
+			defer log.Close()
+
+			deleted, err := snapshot.Prune(time.Now())
+			fmt.Printf("Deleted %d expired snapshot(s)\n", deleted)
+			return err
+		},
+	}
+
+	analyzeCmd = &cobra.Command{
+		Use:   "analyze",
+		Short: "Inspect the repository and recommend claude-squad settings for it",
+		Long: "Inspect the repository for its size, hooks, submodules, dependencies and tests, and recommend " +
+			"claude-squad settings for it. The settings of the repository are written to its " +
+			config.RepoConfigFileName + " after confirmation; the global ones are only printed.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log.Initialize(false)
+			defer log.Close()
+
+			currentDir, err := filepath.Abs(".")
+			if err != nil {
+				return fmt.Errorf("failed to get current directory: %w", err)
+			}
+			root, err := git.FindRepoRoot(currentDir)
+			if err != nil {
+				return fmt.Errorf("error: analyze must be run from within a git repository, or pointed at one with --repo")
+			}
+			repoConfig, err := config.LoadRepoConfig(root)
+			if err != nil {
+				return err
+			}
+			if runTestsFlag {
+				fmt.Println("Running the tests...")
+			}
+			result, err := analyze.Analyze(root, runTestsFlag)
+			if err != nil {
+				return err
+			}
+			recs := result.Recommend(repoConfig, config.LoadConfig())
+			fmt.Print(analyze.Format(result, recs))
+
+			settings := analyze.RepoSettings(recs)
+			if len(settings) == 0 {
+				return nil
+			}
+			if !yesFlag {
+				fmt.Printf("\nWrite these settings to %s? [y/N] ", config.RepoConfigFileName)
+				answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+				if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+					return nil
+				}
+			}
+			if err := config.WriteRepoSettings(root, settings); err != nil {
+				return err
+			}
+			fmt.Printf("Wrote %s\n", filepath.Join(root, config.RepoConfigFileName))
+			if repoConfig, err := config.LoadRepoConfig(root); err == nil && repoConfig.Untrusted {
+				fmt.Println("Its commands aren't trusted: run `cs trust` to review and run them")
+			}
+			return nil
+		},
+	}
+
+	trustCmd = &cobra.Command{
+		Use:   "trust",
+		Short: "Trust the commands in the " + config.RepoConfigFileName + " of the repository",
+		Long: "Show the commands in the " + config.RepoConfigFileName + " of the repository and trust them after " +
+			"confirmation. Anyone working on the repository can commit them, so they aren't run until they're " +
+			"trusted, and again each time the file changes.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log.Initialize(false)
+			defer log.Close()
+
+			currentDir, err := filepath.Abs(".")
+			if err != nil {
+				return fmt.Errorf("failed to get current directory: %w", err)
+			}
+			root, err := git.FindRepoRoot(currentDir)
+			if err != nil {
+				return fmt.Errorf("error: trust must be run from within a git repository")
+			}
+			trusted, err := config.TrustRepoConfig(root, func(repoConfig *config.RepoConfig) bool {
+				fmt.Printf("Commands in %s:\n", filepath.Join(root, config.RepoConfigFileName))
+				for _, command := range repoConfig.WarmupCommands {
+					fmt.Printf("  warmup:   %s\n", command)
+				}
+				if repoConfig.CheckCommand != "" {
+					fmt.Printf("  check:    %s\n", repoConfig.CheckCommand)
+				}
+				for _, command := range repoConfig.TeardownCommands {
+					fmt.Printf("  teardown: %s\n", command)
+				}
+				if yesFlag {
+					return true
+				}
+				fmt.Print("\nRun these commands in the sessions of the repository? [y/N] ")
+				answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+				answer = strings.ToLower(strings.TrimSpace(answer))
+				return answer == "y" || answer == "yes"
+			})
+			if err != nil {
+				return err
+			}
+			if trusted {
+				fmt.Println("Trusted until the file changes")
+			}
+			return nil
+		},
+	}
+
+	standupCmd = &cobra.Command{
+		Use:   "standup",
+		Short: "Print a markdown summary of recent activity per instance",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			ctx := context.Background()
-			log.Initialize(daemonFlag)
+			log.Initialize(false)
 			defer log.Close()
 
-			if daemonFlag {
-				cfg := config.LoadConfig()
-				err := daemon.RunDaemon(cfg)
-				log.ErrorLog.Printf("failed to start daemon %v", err)
+			state := config.LoadState()
+			storage, err := session.NewStorage(state)
+			if err != nil {
+				return fmt.Errorf("failed to initialize storage: %w", err)
+			}
+			instances, err := storage.LoadInstances()
+			if err != nil {
+				return fmt.Errorf("failed to load instances: %w", err)
+			}
+
+			patterns, err := config.LoadConfig().GetRedactPatterns()
+			if err != nil {
 				return err
 			}
+			out := report.Redact(report.Standup(instances, time.Now().Add(-sinceFlag)), patterns)
+			fmt.Print(out)
+			if copyFlag {
+				if err := clipboard.WriteAll(out); err != nil {
+					return fmt.Errorf("failed to copy report to clipboard: %w", err)
+				}
+			}
+			return nil
+		},
+	}
 
-			// Check if we're in a git repository
-			currentDir, err := filepath.Abs(".")
+	historyCmd = &cobra.Command{
+		Use:   "history",
+		Short: "Print the history of killed and archived instances",
+		Long: "Print the instances that were killed or archived, oldest first, with their outcome, final diff " +
+			"stats, duration and the tokens their Claude conversations used, followed by their totals.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log.Initialize(false)
+			defer log.Close()
+
+			entries, err := session.LoadHistory()
 			if err != nil {
-				return fmt.Errorf("failed to get current directory: %w", err)
+				return err
+			}
+			if historySince > 0 {
+				since := time.Now().Add(-historySince)
+				entries = slices.DeleteFunc(entries, func(entry session.HistoryEntry) bool {
+					return entry.EndedAt.Before(since)
+				})
 			}
+			if historyJSON {
+				if entries == nil {
+					entries = []session.HistoryEntry{}
+				}
+				data, err := json.MarshalIndent(entries, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal history: %w", err)
+				}
+				fmt.Println(string(data))
+				return nil
+			}
+			fmt.Println(session.FormatHistory(entries))
+			return nil
+		},
+	}
 
-			if !git.IsGitRepo(currentDir) {
-				return fmt.Errorf("error: claude-squad must be run from within a git repository")
+	outputCmd = &cobra.Command{
+		Use:   "output <title>",
+		Short: "Print the logged output of an instance, oldest first",
+		Long: "Print the output of an instance or an archived instance logged with pane_log, rotated logs " +
+			"included, e.g. to search what the agent printed past the history of tmux.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log.Initialize(false)
+			defer log.Close()
+
+			state := config.LoadState()
+			storage, err := session.NewStorage(state)
+			if err != nil {
+				return fmt.Errorf("failed to initialize storage: %w", err)
+			}
+			instances, err := storage.LoadInstances()
+			if err != nil {
+				return fmt.Errorf("failed to load instances: %w", err)
+			}
+			for _, instance := range instances {
+				if instance.Title == args[0] {
+					return session.WritePaneLog(os.Stdout, instance.Title, instance.CreatedAt)
+				}
+			}
+			// The log outlives the instance, for a post-mortem of an archived one.
+			archived, err := findArchived(storage, args[0])
+			if err != nil {
+				return fmt.Errorf("instance %s not found", args[0])
 			}
+			return session.WritePaneLog(os.Stdout, archived.Title, archived.CreatedAt)
+		},
+	}
+
+	exportConversationCmd = &cobra.Command{
+		Use:   "export-conversation <title>",
+		Short: "Export the Claude conversation of an instance as Markdown or HTML",
+		Long: "Export the last Claude conversation of an instance or an archived instance as Markdown or as " +
+			"a self-contained HTML page, with the tool calls collapsed, to share the transcript. The redact " +
+			"patterns of the config are applied.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log.Initialize(false)
+			defer log.Close()
 
 			cfg := config.LoadConfig()
+			patterns, err := cfg.GetRedactPatterns()
+			if err != nil {
+				return err
+			}
+			state := config.LoadState()
+			storage, err := session.NewStorage(state)
+			if err != nil {
+				return fmt.Errorf("failed to initialize storage: %w", err)
+			}
+			instances, err := storage.LoadInstances()
+			if err != nil {
+				return fmt.Errorf("failed to load instances: %w", err)
+			}
+			var path string
+			var pathErr error
+			found := false
+			for _, instance := range instances {
+				if instance.Title == args[0] {
+					path, pathErr = instance.ConversationPath()
+					found = true
+					break
+				}
+			}
+			if !found {
+				archived, err := findArchived(storage, args[0])
+				if err != nil {
+					return fmt.Errorf("instance %s not found", args[0])
+				}
+				path, pathErr = archived.ConversationPath()
+			}
+			if pathErr != nil {
+				return pathErr
+			}
 
-			// Program flag overrides config
-			program := cfg.DefaultProgram
-			if programFlag != "" {
-				program = programFlag
+			content, err := claude.Export(path, claude.ExportOptions{
+				Title:  args[0],
+				Format: formatFlag,
+				Redact: func(text string) string { return report.Redact(text, patterns) },
+			})
+			if err != nil {
+				return err
 			}
-			// AutoYes flag overrides config
-			autoYes := cfg.AutoYes
-			if autoYesFlag {
-				autoYes = true
+			if outputFlag == "" {
+				_, err := os.Stdout.Write(content)
+				return err
 			}
-			if autoYes {
-				defer func() {
-					if err := daemon.LaunchDaemon(); err != nil {
-						log.ErrorLog.Printf("failed to launch daemon: %v", err)
-					}
-				}()
+			if err := os.WriteFile(outputFlag, content, 0644); err != nil {
+				return fmt.Errorf("failed to save the conversation: %w", err)
 			}
-			// Kill any daemon that's running.
+			fmt.Printf("Exported the conversation of '%s' to %s\n", args[0], outputFlag)
+			return nil
+		},
+	}
+
+	suspendCmd = &cobra.Command{
+		Use:   "suspend",
+		Short: "Commit the changes of every running instance, pause them and stop the daemon",
+		Long: "Commit the changes of every running instance, pause them and stop the daemon, e.g. before shutting " +
+			"down the machine. resume-all resumes the instances suspended.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log.Initialize(false)
+			defer log.Close()
+
+			// Stop the daemon first so it doesn't save the instances as running after they're paused.
 			if err := daemon.StopDaemon(); err != nil {
-				log.ErrorLog.Printf("failed to stop daemon: %v", err)
+				return err
+			}
+			fmt.Println("daemon has been stopped")
+
+			state := config.LoadState()
+			storage, err := session.NewStorage(state)
+			if err != nil {
+				return fmt.Errorf("failed to initialize storage: %w", err)
+			}
+			instances, err := storage.LoadInstances()
+			if err != nil {
+				return fmt.Errorf("failed to load instances: %w", err)
 			}
 
-			return app.Run(ctx, program, autoYes)
+			suspended, suspendErr := session.Suspend(instances)
+			if err := storage.SaveInstances(instances); err != nil {
+				return fmt.Errorf("failed to save instances: %w", err)
+			}
+			for _, instance := range suspended {
+				fmt.Printf("Suspended '%s'\n", instance.Title)
+			}
+			if suspendErr != nil {
+				return fmt.Errorf("failed to suspend some instances: %w", suspendErr)
+			}
+			fmt.Printf("Suspended %d instances\n", len(suspended))
+			return nil
 		},
 	}
 
-	resetCmd = &cobra.Command{
-		Use:   "reset",
-		Short: "Reset all stored instances",
+	resumeAllCmd = &cobra.Command{
+		Use:   "resume-all",
+		Short: "Resume the instances paused by suspend",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			log.Initialize(false)
 			defer log.Close()
@@ -87,27 +1421,235 @@ var (
 			if err != nil {
 				return fmt.Errorf("failed to initialize storage: %w", err)
 			}
-			if err := storage.DeleteAllInstances(); err != nil {
-				return fmt.Errorf("failed to reset storage: %w", err)
+			instances, err := storage.LoadInstances()
+			if err != nil {
+				return fmt.Errorf("failed to load instances: %w", err)
 			}
-			fmt.Println("Storage has been reset successfully")
 
-			if err := tmux.CleanupSessions(cmd2.MakeExecutor()); err != nil {
-				return fmt.Errorf("failed to cleanup tmux sessions: %w", err)
+			cfg := config.LoadConfig()
+			resumed, resumeErr := session.ResumeSuspended(cfg.MaxRunningInstances, cfg.Providers, instances)
+			if err := storage.SaveInstances(instances); err != nil {
+				return fmt.Errorf("failed to save instances: %w", err)
+			}
+			for _, instance := range resumed {
+				fmt.Printf("Resumed '%s'\n", instance.Title)
+			}
+			for _, instance := range instances {
+				if instance.Suspended && instance.Queued() {
+					fmt.Printf("Queued '%s' until a running slot is free\n", instance.Title)
+				}
 			}
-			fmt.Println("Tmux sessions have been cleaned up")
 
-			if err := git.CleanupWorktrees(); err != nil {
-				return fmt.Errorf("failed to cleanup worktrees: %w", err)
+			// Start the daemon again, as quitting the application would.
+			if cfg.AutoYes && session.TerminalsPersist && len(resumed) > 0 {
+				if err := daemon.LaunchDaemon(); err != nil {
+					log.ErrorLog.Printf("failed to launch daemon: %v", err)
+				}
 			}
-			fmt.Println("Worktrees have been cleaned up")
+			if resumeErr != nil {
+				return fmt.Errorf("failed to resume some instances: %w", resumeErr)
+			}
+			fmt.Printf("Resumed %d instances\n", len(resumed))
+			return nil
+		},
+	}
 
-			// Kill any daemon that's running.
+	adoptCmd = &cobra.Command{
+		Use:   "adopt [title...]",
+		Short: "Import the worktrees and tmux sessions of a hand-rolled setup as instances",
+		Long: "Import the worktrees of the repository that no instance owns as instances, inferring their branch " +
+			"and base commit, along with the tmux sessions running in them. Only the worktrees and sessions " +
+			"matching the patterns of adopt in the config are adopted, or those with the titles given. A worktree " +
+			"without a session gets a new one running the program, resuming its latest claude conversation.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log.Initialize(false)
+			defer log.Close()
+
+			currentDir, err := filepath.Abs(".")
+			if err != nil {
+				return fmt.Errorf("failed to get current directory: %w", err)
+			}
+			root, err := git.FindRepoRoot(currentDir)
+			if err != nil {
+				return fmt.Errorf("error: adopt must be run from within a git repository")
+			}
+			storage, err := session.NewStorage(config.LoadState())
+			if err != nil {
+				return fmt.Errorf("failed to initialize storage: %w", err)
+			}
+			instances, err := storage.LoadInstances()
+			if err != nil {
+				return fmt.Errorf("failed to load instances: %w", err)
+			}
+			cfg := config.LoadConfig()
+			candidates, err := session.FindAdoptable(root, cfg.Adopt, instances)
+			if err != nil {
+				return err
+			}
+			if len(args) > 0 {
+				candidates = slices.DeleteFunc(candidates, func(c session.AdoptCandidate) bool {
+					return !slices.Contains(args, c.Title)
+				})
+			}
+			if len(candidates) == 0 {
+				fmt.Println("No worktrees to adopt")
+				return nil
+			}
+			for _, candidate := range candidates {
+				fmt.Println(candidate)
+			}
+			if !yesFlag {
+				fmt.Printf("\nAdopt %d worktree(s)? [y/N] ", len(candidates))
+				answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+				if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+					return nil
+				}
+			}
+
+			program := cfg.DefaultProgram
+			if newProgramFlag != "" {
+				program = newProgramFlag
+			}
+			var adoptErr error
+			for _, candidate := range candidates {
+				if len(instances) >= app.GlobalInstanceLimit {
+					adoptErr = errors.Join(adoptErr, fmt.Errorf("you can't create more than %d instances", app.GlobalInstanceLimit))
+					break
+				}
+				if slices.ContainsFunc(instances, func(i *session.Instance) bool { return i.Title == candidate.Title }) {
+					adoptErr = errors.Join(adoptErr, fmt.Errorf("instance %s already exists", candidate.Title))
+					continue
+				}
+				instance, err := candidate.Adopt(program)
+				if err != nil {
+					adoptErr = errors.Join(adoptErr, err)
+					continue
+				}
+				instances = append(instances, instance)
+				fmt.Printf("Adopted '%s' on branch %s\n", instance.Title, instance.Branch)
+			}
+			if err := storage.SaveInstances(instances); err != nil {
+				return fmt.Errorf("failed to save instances: %w", err)
+			}
+			return adoptErr
+		},
+	}
+
+	killCmd = &cobra.Command{
+		Use:   "kill <title>",
+		Short: "Kill an instance, removing its worktree and its branch",
+		Long: "Kill an instance, removing its worktree and its branch. An instance whose branch has commits that " +
+			"aren't merged or pushed, or whose worktree has uncommitted changes, is only killed with --force.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log.Initialize(false)
+			defer log.Close()
+
+			// Stop the daemon so it doesn't save the instance back after it's killed.
 			if err := daemon.StopDaemon(); err != nil {
 				return err
 			}
-			fmt.Println("daemon has been stopped")
 
+			storage, err := session.NewStorage(config.LoadState())
+			if err != nil {
+				return fmt.Errorf("failed to initialize storage: %w", err)
+			}
+			instances, err := storage.LoadInstances()
+			if err != nil {
+				return fmt.Errorf("failed to load instances: %w", err)
+			}
+			idx := slices.IndexFunc(instances, func(instance *session.Instance) bool {
+				return instance.Title == args[0]
+			})
+			if idx < 0 {
+				return fmt.Errorf("instance %s not found", args[0])
+			}
+			instance := instances[idx]
+
+			// Start the daemon again, as quitting the application would.
+			cfg := config.LoadConfig()
+			defer func() {
+				if cfg.AutoYes && session.TerminalsPersist {
+					if err := daemon.LaunchDaemon(); err != nil {
+						log.ErrorLog.Printf("failed to launch daemon: %v", err)
+					}
+				}
+			}()
+
+			var unsaved *session.UnsavedWorkError
+			if err := instance.CheckKill(forceFlag); errors.As(err, &unsaved) {
+				return fmt.Errorf("%w; pass --force to kill it anyway", err)
+			} else if err != nil {
+				return err
+			}
+			if err := instance.Kill(); err != nil {
+				return err
+			}
+			if err := storage.SaveInstances(slices.Delete(instances, idx, idx+1)); err != nil {
+				return fmt.Errorf("failed to save instances: %w", err)
+			}
+			fmt.Printf("Killed '%s'\n", instance.Title)
+			return nil
+		},
+	}
+
+	schemaCmd = &cobra.Command{
+		Use:       "schema [config|templates|pipelines]",
+		Short:     "Print the JSON Schema of a config file, for editors to validate and complete it",
+		Args:      cobra.MatchAll(cobra.MaximumNArgs(1), cobra.OnlyValidArgs),
+		ValidArgs: []string{"config", "templates", "pipelines"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s := config.Schema()
+			if len(args) > 0 {
+				switch args[0] {
+				case "templates":
+					s = template.Schema()
+				case "pipelines":
+					s = pipeline.Schema()
+				}
+			}
+			data, err := json.MarshalIndent(s, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			return nil
+		},
+	}
+
+	initCmd = &cobra.Command{
+		Use:   "init",
+		Short: "Set up claude-squad by answering a few questions, writing its config",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log.Initialize(false)
+			defer log.Close()
+			return runWizard()
+		},
+	}
+
+	doctorCmd = &cobra.Command{
+		Use:   "doctor",
+		Short: "Check tmux, git, the agents, the config and the state, and tell how to fix what's wrong",
+		Long: "Check that tmux and git are installed and recent enough, that the repository supports worktrees, " +
+			"that the agents are installed and the default program can start, that the config is valid and the " +
+			"state file can be read, and look for tmux sessions and worktrees no session owns. Exits with an " +
+			"error if a check failed.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log.Initialize(false)
+			defer log.Close()
+
+			program := config.LoadConfig().DefaultProgram
+			if programFlag != "" {
+				program = programFlag
+			}
+			currentDir, err := filepath.Abs(".")
+			if err != nil {
+				return fmt.Errorf("failed to get current directory: %w", err)
+			}
+			if failed := doctor.Print(os.Stdout, doctor.Run(program, currentDir)); failed > 0 {
+				cmd.SilenceUsage = true
+				return fmt.Errorf("%d checks failed", failed)
+			}
 			return nil
 		},
 	}
@@ -124,8 +1666,71 @@ var (
 			}
 			configJson, _ := json.MarshalIndent(cfg, "", "  ")
 
-			fmt.Printf("Config: %s\n%s\n", filepath.Join(configDir, config.ConfigFileName), configJson)
+			configPath := filepath.Join(configDir, config.ConfigFileName)
+			fmt.Printf("Config: %s\n%s\n", configPath, configJson)
+			if data, err := os.ReadFile(configPath); err == nil {
+				problems, err := config.Validate(data)
+				if err != nil {
+					fmt.Printf("%s: %v\n", configPath, err)
+				}
+				for _, problem := range problems {
+					fmt.Printf("%s:%v\n", configPath, problem)
+				}
+			}
+
+			return nil
+		},
+	}
+
+	telemetryCmd = &cobra.Command{
+		Use:   "telemetry",
+		Short: "Print exactly what the opt-in usage telemetry sends, and whether it's on",
+		Long: "Print the anonymous usage counts that would be sent next, exactly as they're sent. Telemetry is " +
+			"off unless telemetry.mode is set in the config: \"preview\" only counts locally, \"on\" also sends " +
+			"the counts to telemetry.endpoint once a day.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := config.LoadTelemetry()
+			switch {
+			case cfg.Sending():
+				fmt.Printf("Telemetry is on, sent to %s once a day. Next report:\n", cfg.Endpoint)
+			case cfg.Mode == config.TelemetryOn:
+				fmt.Println("Telemetry is on but has no endpoint, so nothing is sent. Next report:")
+			case cfg.Collecting():
+				fmt.Println("Telemetry is in preview: usage is counted locally and never sent. Next report:")
+			default:
+				fmt.Println("Telemetry is off: nothing is counted or sent. Set telemetry.mode to \"preview\" to see what would be.")
+				return nil
+			}
+			report, err := telemetry.Preview(version)
+			if err != nil {
+				return err
+			}
+			data, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			return nil
+		},
+	}
 
+	profilesCmd = &cobra.Command{
+		Use:   "profiles",
+		Short: "List the profiles, marking the current one",
+		Long: "List the profiles. Each profile has its own sessions, config and state, and is selected with " +
+			"--profile or $" + config.ProfileEnv + ". A profile is created the first time it's used.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			profiles, err := config.ListProfiles()
+			if err != nil {
+				return err
+			}
+			for _, profile := range profiles {
+				if profile == config.ProfileName() {
+					fmt.Printf("* %s\n", profile)
+				} else {
+					fmt.Printf("  %s\n", profile)
+				}
+			}
 			return nil
 		},
 	}
@@ -141,6 +1746,10 @@ var (
 )
 
 func init() {
+	rootCmd.PersistentFlags().StringVar(&profileFlag, "profile", "",
+		"Profile to use, with its own sessions, config and state, e.g. 'work' (default: $"+config.ProfileEnv+")")
+	rootCmd.PersistentFlags().StringVar(&repoFlag, "repo", "",
+		"Repository to work on instead of the one of the current directory, e.g. a bare repository")
 	rootCmd.Flags().StringVarP(&programFlag, "program", "p", "",
 		"Program to run in new instances (e.g. 'aider --model ollama_chat/gemma3:1b')")
 	rootCmd.Flags().BoolVarP(&autoYesFlag, "autoyes", "y", false,
@@ -154,13 +1763,169 @@ func init() {
 		panic(err)
 	}
 
+	newCmd.Flags().StringVar(&fromIssueFlag, "from-issue", "",
+		"GitHub issue to create the instance from (e.g. 'owner/repo#123')")
+	newCmd.Flags().StringVar(&newPromptFlag, "prompt", "", "Initial prompt to send to the instance")
+	newCmd.Flags().StringVarP(&newProgramFlag, "program", "p", "", "Program to run in the new instance")
+	newCmd.Flags().StringVar(&templateFlag, "template", "",
+		"Template to create the instance from (e.g. 'starter/bug-fixer'); --prompt becomes the task")
+	newCmd.Flags().StringVar(&stackOnFlag, "stack-on", "",
+		"Title of an instance to stack the new instance on: its branch is based on that instance's branch")
+	newCmd.Flags().StringVar(&branchFlag, "branch", "",
+		"Existing branch to check out in the instance instead of creating a new one; it's kept when the instance is killed")
+	newCmd.Flags().StringVar(&remoteBranch, "remote-branch", "",
+		"Remote branch to fetch and check out in the instance, e.g. a colleague's PR branch (e.g. 'origin/fix-login'); "+
+			"the agent reviews and fixes it unless --prompt is given")
+	newCmd.Flags().StringSliceVar(&newScopeFlag, "scope", nil,
+		"Directories the instance owns, relative to the repository root (e.g. --scope services/auth)")
+	newCmd.Flags().StringSliceVar(&resourceFlag, "resource", nil,
+		"External resource the instance uses, waiting for it while other instances use it (repeatable)")
+	newCmd.Flags().StringVar(&hostFlag, "host", "",
+		"Machine to run the instance on over ssh (e.g. 'me@devbox'); it needs git, tmux and the program")
+	newCmd.Flags().StringVar(&hostPathFlag, "path", "",
+		"Path of the repository on --host (default: the same path relative to the home directory)")
+	newCmd.Flags().StringVar(&providerFlag, "provider", "",
+		"Provider of the providers config to run, with its program and environment (e.g. its model or API key)")
+	newCmd.Flags().DurationVar(&timeBudgetFlag, "time-budget", 0,
+		"How long the instance may run before it's asked to wrap up, then committed and paused (e.g. 8h; default: time_budget.default_minutes)")
+
+	runCmd.Flags().StringVar(&runPathFlag, "path", ".", "Path of the repository")
+	runCmd.Flags().StringVar(&newPromptFlag, "prompt", "", "Prompt to run")
+	runCmd.Flags().StringVarP(&newProgramFlag, "program", "p", "", "Program to run in the instance")
+	runCmd.Flags().StringVar(&runTitleFlag, "title", "", "Title of the instance (default: derived from the prompt)")
+	runCmd.Flags().DurationVar(&runTimeoutFlag, "timeout", 30*time.Minute, "How long to wait for the agent to finish")
+	if err := runCmd.MarkFlagRequired("prompt"); err != nil {
+		panic(err)
+	}
+
+	fanOutCmd.Flags().StringVar(&newPromptFlag, "prompt", "", "Prompt to send to every instance")
+	fanOutCmd.Flags().IntVarP(&fanOutCount, "count", "n", 0, "Number of instances to create")
+	fanOutCmd.Flags().StringSliceVar(&fanOutPrograms, "program", nil,
+		"Programs the instances run in turn (e.g. --program claude --program aider)")
+	if err := fanOutCmd.MarkFlagRequired("prompt"); err != nil {
+		panic(err)
+	}
+
+	migrateCmd.Flags().StringVar(&migratePath, "path", "",
+		"Path of the repository on the other machine (default: the same path relative to the home directory)")
+	migrateCmd.Flags().StringVar(&migrateCommand, "remote-command", "cs",
+		"Command that runs claude-squad on the other machine")
+	receiveMigrationCmd.Flags().StringVar(&receivePath, "path", ".", "Path of the repository")
+
+	snapshotCreateCmd.Flags().BoolVar(&gistFlag, "gist", false, "Publish the snapshot as a secret gist instead of saving it")
+	snapshotCreateCmd.Flags().StringVarP(&outputFlag, "output", "o", "",
+		"File to save the snapshot to (default: in the snapshots directory of the config directory)")
+
+	analyzeCmd.Flags().BoolVar(&runTestsFlag, "run-tests", false,
+		"Run the tests to time them, and only recommend them as the check command if they pass in time")
+	analyzeCmd.Flags().BoolVarP(&yesFlag, "yes", "y", false, "Write the recommended settings without asking")
+
+	standupCmd.Flags().DurationVar(&sinceFlag, "since", 16*time.Hour, "How far back to report activity")
+	standupCmd.Flags().BoolVar(&copyFlag, "copy", false, "Copy the report to the clipboard")
+
+	historyCmd.Flags().DurationVar(&historySince, "since", 0, "Only print the instances that ended this recently (e.g. 168h; default: all)")
+	historyCmd.Flags().BoolVar(&historyJSON, "json", false, "Print the history as a JSON array")
+
+	exportConversationCmd.Flags().StringVar(&formatFlag, "format", claude.FormatMarkdown, "Format of the export: markdown or html")
+	exportConversationCmd.Flags().StringVarP(&outputFlag, "output", "o", "", "File to save the export to (default: stdout)")
+	killCmd.Flags().BoolVarP(&forceFlag, "force", "f", false, "Kill the instance even if it has unsaved work")
+
 	rootCmd.AddCommand(debugCmd)
+	rootCmd.AddCommand(initCmd)
+	rootCmd.AddCommand(doctorCmd)
+	doctorCmd.Flags().StringVarP(&programFlag, "program", "p", "", "Program to check instead of the default program")
+	rootCmd.AddCommand(telemetryCmd)
+	rootCmd.AddCommand(schemaCmd)
+	rootCmd.AddCommand(newCmd)
+	rootCmd.AddCommand(fanOutCmd)
+	rootCmd.AddCommand(runCmd)
+	templatesCmd.AddCommand(templatesImportCmd)
+	templatesCmd.AddCommand(templatesListCmd)
+	templatesCmd.AddCommand(templatesRemoveCmd)
+	rootCmd.AddCommand(templatesCmd)
+	archiveCmd.AddCommand(archiveListCmd)
+	archiveCmd.AddCommand(archiveShowCmd)
+	archiveCmd.AddCommand(archiveResurrectCmd)
+	archiveCmd.AddCommand(archivePruneCmd)
+	rootCmd.AddCommand(archiveCmd)
+	stateCmd.AddCommand(stateListCmd)
+	stateCmd.AddCommand(stateRestoreCmd)
+	rootCmd.AddCommand(stateCmd)
+	rootCmd.AddCommand(migrateCmd)
+	rootCmd.AddCommand(receiveMigrationCmd)
+	snapshotCmd.AddCommand(snapshotCreateCmd)
+	snapshotCmd.AddCommand(snapshotListCmd)
+	snapshotCmd.AddCommand(snapshotPruneCmd)
+	rootCmd.AddCommand(snapshotCmd)
+	rootCmd.AddCommand(analyzeCmd)
+	rootCmd.AddCommand(trustCmd)
+	trustCmd.Flags().BoolVarP(&yesFlag, "yes", "y", false, "Trust the commands without asking")
+	rootCmd.AddCommand(standupCmd)
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(outputCmd)
+	rootCmd.AddCommand(exportConversationCmd)
+	rootCmd.AddCommand(hookCmd)
+	rootCmd.AddCommand(suspendCmd)
+	rootCmd.AddCommand(resumeAllCmd)
+	rootCmd.AddCommand(adoptCmd)
+	adoptCmd.Flags().BoolVarP(&yesFlag, "yes", "y", false, "Adopt the worktrees without asking")
+	adoptCmd.Flags().StringVarP(&newProgramFlag, "program", "p", "", "Program assumed to run in the sessions, and started in the new ones")
+	rootCmd.AddCommand(killCmd)
+	rootCmd.AddCommand(profilesCmd)
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(resetCmd)
 }
 
+// runWizard asks the settings of the first run and writes the config with them, after confirming it should
+// replace the config there is.
+func runWizard() error {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return fmt.Errorf("failed to get config directory: %w", err)
+	}
+	configPath := filepath.Join(configDir, config.ConfigFileName)
+	if !doctor.FirstRun() {
+		fmt.Printf("%s already exists, replace it? [y/N] ", configPath)
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+			fmt.Println("Kept the config.")
+			return nil
+		}
+	}
+	cfg, err := doctor.Wizard(os.Stdin, os.Stdout)
+	if err != nil {
+		return err
+	}
+	if err := config.SaveConfig(cfg); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	fmt.Printf("\nWrote %s. Run `cs doctor` to check the setup.\n", configPath)
+	return nil
+}
+
+// findArchived returns the most recently archived instance with the title.
+func findArchived(storage *session.Storage, title string) (session.ArchivedInstance, error) {
+	archived, err := storage.ArchivedInstances()
+	if err != nil {
+		return session.ArchivedInstance{}, err
+	}
+	for i := len(archived) - 1; i >= 0; i-- {
+		if archived[i].Title == title {
+			return archived[i], nil
+		}
+	}
+	return session.ArchivedInstance{}, fmt.Errorf("archived instance not found: %s", title)
+}
+
 func main() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
 	}
+	// The processes of the instances paused or killed last may still be terminating.
+	session.WaitForTerminations()
+	// Telemetry never gets in the way, so failing to save or send the counts is ignored.
+	_ = telemetry.Flush(version)
+	if err := tracing.Flush(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
 }
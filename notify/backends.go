@@ -0,0 +1,227 @@
+package notify
+
+import (
+	"bytes"
+	"claude-squad/config"
+	"claude-squad/textutil"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// pushoverURL is the endpoint of the Pushover messages API.
+var pushoverURL = "https://api.pushover.net/1/messages.json"
+
+// Message is an event of an instance sent to a backend.
+type Message struct {
+	// Event is one of the config.NotifyEvent constants.
+	Event string
+	// Title is the title of the instance.
+	Title string
+	// Text is what happened to the instance, e.g. "is ready".
+	Text string
+}
+
+// String returns the message as one line, e.g. "`fix-login` is ready".
+func (m Message) String() string {
+	return fmt.Sprintf("`%s` %s", m.Title, m.Text)
+}
+
+// Backend delivers messages, e.g. to a chat channel, a phone or the desktop.
+type Backend interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Factory returns the backend for its config, or an error if the config is incomplete.
+type Factory func(cfg config.NotifyBackend, client *http.Client) (Backend, error)
+
+var (
+	factoriesMu sync.Mutex
+	factories   = map[string]Factory{
+		config.NotifyKindSlack:    newWebhookBackend,
+		config.NotifyKindDiscord:  newWebhookBackend,
+		config.NotifyKindPushover: newPushoverBackend,
+		config.NotifyKindDesktop:  newDesktopBackend,
+		config.NotifyKindCommand:  newCommandBackend,
+	}
+)
+
+// Register adds a kind of backend, or replaces the factory of an existing one.
+func Register(kind string, factory Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	factories[kind] = factory
+}
+
+// newBackend returns the backend of the config from the factory of its kind.
+func newBackend(cfg config.NotifyBackend, client *http.Client) (Backend, error) {
+	factoriesMu.Lock()
+	factory, ok := factories[cfg.Kind]
+	factoriesMu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown kind %q", cfg.Kind)
+	}
+	return factory(cfg, client)
+}
+
+// webhookBackend posts to a Slack or Discord incoming webhook.
+type webhookBackend struct {
+	kind string
+	url  string
+	http *http.Client
+}
+
+func newWebhookBackend(cfg config.NotifyBackend, client *http.Client) (Backend, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("a webhook needs a url")
+	}
+	return &webhookBackend{kind: cfg.Kind, url: cfg.URL, http: client}, nil
+}
+
+func (b *webhookBackend) Send(ctx context.Context, msg Message) error {
+	text := msg.String()
+	var payload map[string]string
+	switch b.kind {
+	case config.NotifyKindDiscord:
+		payload = map[string]string{"content": textutil.Truncate(text, maxDiscordLength)}
+	case config.NotifyKindSlack:
+		payload = map[string]string{"text": text}
+	default:
+		return fmt.Errorf("unknown webhook kind %q", b.kind)
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to post to %s webhook: %w", b.kind, hideURL(err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := b.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post to %s webhook: %w", b.kind, hideURL(err))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to post to %s webhook: %s", b.kind, resp.Status)
+	}
+	return nil
+}
+
+// hideURL strips the URL from the error of a request, since the URL of a webhook is a secret.
+func hideURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
+
+// pushoverBackend pushes to the phones of a Pushover user.
+type pushoverBackend struct {
+	token string
+	user  string
+	http  *http.Client
+}
+
+func newPushoverBackend(cfg config.NotifyBackend, client *http.Client) (Backend, error) {
+	token, user := cfg.GetPushoverKeys()
+	if token == "" || user == "" {
+		return nil, errors.New("pushover needs a token and a user")
+	}
+	return &pushoverBackend{token: token, user: user, http: client}, nil
+}
+
+func (b *pushoverBackend) Send(ctx context.Context, msg Message) error {
+	form := url.Values{
+		"token":   {b.token},
+		"user":    {b.user},
+		"title":   {msg.Title},
+		"message": {msg.Text},
+	}
+	// Crashes and blocked agents bypass the quiet hours of the user.
+	if msg.Event == config.NotifyEventCrashed || msg.Event == config.NotifyEventBlocked {
+		form.Set("priority", "1")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, pushoverURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to push to pushover: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := b.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to push to pushover: %w", hideURL(err))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to push to pushover: %s", resp.Status)
+	}
+	return nil
+}
+
+// desktopBackend shows a notification with osascript on macOS and notify-send elsewhere.
+type desktopBackend struct{}
+
+func newDesktopBackend(config.NotifyBackend, *http.Client) (Backend, error) {
+	if runtime.GOOS == "windows" {
+		return nil, errors.New("desktop notifications aren't supported on windows")
+	}
+	return desktopBackend{}, nil
+}
+
+func (desktopBackend) Send(ctx context.Context, msg Message) error {
+	title := "claude-squad: " + msg.Title
+	var cmd *exec.Cmd
+	if runtime.GOOS == "darwin" {
+		// The texts are passed as arguments, so they don't have to be quoted for AppleScript.
+		cmd = exec.CommandContext(ctx, "osascript",
+			"-e", "on run argv",
+			"-e", "display notification (item 2 of argv) with title (item 1 of argv)",
+			"-e", "end run", title, msg.Text)
+	} else {
+		cmd = exec.CommandContext(ctx, "notify-send", title, msg.Text)
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to show desktop notification: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// commandBackend runs a shell command with the message in its environment.
+type commandBackend struct {
+	command string
+}
+
+func newCommandBackend(cfg config.NotifyBackend, _ *http.Client) (Backend, error) {
+	if cfg.Command == "" {
+		return nil, errors.New("a command backend needs a command")
+	}
+	return &commandBackend{command: cfg.Command}, nil
+}
+
+func (b *commandBackend) Send(ctx context.Context, msg Message) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", b.command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", b.command)
+	}
+	cmd.Env = append(os.Environ(),
+		"CS_EVENT="+msg.Event,
+		"CS_TITLE="+msg.Title,
+		"CS_MESSAGE="+msg.Text,
+	)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("notify command failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+// Package notify sends the events of the instances, e.g. an agent that finished or needs input, to the
+// Slack and Discord incoming webhooks of the config, and to the backends its notify rules route them to.
+package notify
+
+import (
+	"claude-squad/config"
+	"claude-squad/session"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// requestTimeout bounds the sending of an event to a backend.
+	requestTimeout = 10 * time.Second
+	// ReadyDelay is how long an instance has to be ready before it's posted as finished, so short pauses
+	// of the agent aren't.
+	ReadyDelay = 15 * time.Second
+	// maxDiscordLength is the most characters Discord takes in a message.
+	maxDiscordLength = 2000
+)
+
+// Notifier sends events to webhooks and routes them to backends, and remembers the instances already
+// posted as finished since they last ran.
+type Notifier struct {
+	webhooks []config.Webhook
+	http     *http.Client
+	rules    []config.NotifyRule
+	// backends are the backends of the rules by name.
+	backends map[string]Backend
+
+	mu sync.Mutex
+	// finished are the instances posted as finished since they last ran.
+	finished map[*session.Instance]bool
+}
+
+// New returns a notifier posting to the webhooks of the config and routing by its notify rules. Backends
+// that are misconfigured or unknown are returned in the error, and the notifier sends to the others.
+func New(cfg *config.Config) (*Notifier, error) {
+	n := &Notifier{
+		webhooks: cfg.Webhooks,
+		http:     &http.Client{Timeout: requestTimeout},
+		rules:    cfg.NotifyRules,
+		backends: make(map[string]Backend),
+		finished: make(map[*session.Instance]bool),
+	}
+	var errs []error
+	for _, backendConfig := range cfg.NotifyBackends {
+		if _, ok := n.backends[backendConfig.Name]; ok {
+			errs = append(errs, fmt.Errorf("notify backend %q is declared twice", backendConfig.Name))
+			continue
+		}
+		backend, err := newBackend(backendConfig, n.http)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid notify backend %q: %w", backendConfig.Name, err))
+			continue
+		}
+		n.backends[backendConfig.Name] = backend
+	}
+	for _, rule := range n.rules {
+		for _, name := range rule.Backends {
+			if _, ok := n.backends[name]; ok {
+				continue
+			}
+			if name == config.NotifyKindDesktop {
+				if backend, err := newBackend(config.NotifyBackend{Name: name, Kind: name}, n.http); err == nil {
+					n.backends[name] = backend
+					continue
+				}
+			}
+			errs = append(errs, fmt.Errorf("notify rule routes to unknown backend %q", name))
+		}
+	}
+	return n, errors.Join(errs...)
+}
+
+// Posts returns true if a webhook posts the event or a rule routes it to a backend.
+func (n *Notifier) Posts(event string) bool {
+	for _, webhook := range n.webhooks {
+		if webhook.Posts(event) {
+			return true
+		}
+	}
+	for _, rule := range n.rules {
+		if len(rule.Backends) > 0 && (len(rule.Events) == 0 || slices.Contains(rule.Events, event)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Notify posts the text about the instance to the webhooks that post the event, e.g. "is ready" as
+// "`fix-login` is ready", and sends it to the backends of the first rule matching the event of the
+// instance. It joins the errors of the webhooks and backends it couldn't send to.
+func (n *Notifier) Notify(event string, instance *session.Instance, text string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	msg := Message{Event: event, Title: instance.Title, Text: text}
+	var errs []error
+	for _, webhook := range n.webhooks {
+		if !webhook.Posts(event) {
+			continue
+		}
+		backend := &webhookBackend{kind: Kind(webhook), url: webhook.URL, http: n.http}
+		if err := backend.Send(ctx, msg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, name := range n.Route(event, instance) {
+		backend, ok := n.backends[name]
+		if !ok {
+			continue
+		}
+		if err := backend.Send(ctx, msg); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Route returns the names of the backends the first rule matching the event of the instance routes it
+// to, or nil if no rule matches it.
+func (n *Notifier) Route(event string, instance *session.Instance) []string {
+	for _, rule := range n.rules {
+		if rule.Matches(event, instance.Title, instance.Group) {
+			return rule.Backends
+		}
+	}
+	return nil
+}
+
+// Finished returns true once the instance has been ready for ReadyDelay, and not again until it ran.
+func (n *Notifier) Finished(instance *session.Instance, now time.Time) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if instance.Status != session.Ready {
+		delete(n.finished, instance)
+		return false
+	}
+	readySince := instance.ReadySince()
+	if n.finished[instance] || readySince.IsZero() || now.Sub(readySince) < ReadyDelay {
+		return false
+	}
+	n.finished[instance] = true
+	return true
+}
+
+// Kind returns the kind of the webhook, guessing it from its URL if it isn't set.
+func Kind(webhook config.Webhook) string {
+	if webhook.Kind != "" {
+		return webhook.Kind
+	}
+	if u, err := url.Parse(webhook.URL); err == nil {
+		host := u.Hostname()
+		if host == "discord.com" || host == "discordapp.com" || strings.HasSuffix(host, ".discord.com") {
+			return config.NotifyKindDiscord
+		}
+	}
+	return config.NotifyKindSlack
+}
@@ -0,0 +1,221 @@
+package notify
+
+import (
+	"claude-squad/config"
+	"claude-squad/session"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeWebhooks records the payloads posted to each path.
+func fakeWebhooks(t *testing.T) (*httptest.Server, map[string][]map[string]string) {
+	posted := make(map[string][]map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/broken" {
+			http.Error(w, "invalid_token", http.StatusForbidden)
+			return
+		}
+		var payload map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		posted[r.URL.Path] = append(posted[r.URL.Path], payload)
+	}))
+	t.Cleanup(server.Close)
+	return server, posted
+}
+
+func newNotifier(t *testing.T, cfg *config.Config) *Notifier {
+	notifier, err := New(cfg)
+	require.NoError(t, err)
+	return notifier
+}
+
+func TestNotify(t *testing.T) {
+	server, posted := fakeWebhooks(t)
+	notifier := newNotifier(t, &config.Config{Webhooks: []config.Webhook{
+		{URL: server.URL + "/slack"},
+		{URL: server.URL + "/discord", Kind: "discord", Events: []string{config.NotifyEventCrashed}},
+	}})
+	instance := &session.Instance{Title: "fix-login"}
+
+	assert.True(t, notifier.Posts(config.NotifyEventFinished))
+	require.NoError(t, notifier.Notify(config.NotifyEventFinished, instance, "is ready"))
+	require.NoError(t, notifier.Notify(config.NotifyEventCrashed, instance, "crashed: its session is gone"))
+
+	assert.Equal(t, []map[string]string{
+		{"text": "`fix-login` is ready"},
+		{"text": "`fix-login` crashed: its session is gone"},
+	}, posted["/slack"])
+	assert.Equal(t, []map[string]string{{"content": "`fix-login` crashed: its session is gone"}}, posted["/discord"])
+}
+
+func TestNotifyErrorHidesURL(t *testing.T) {
+	server, _ := fakeWebhooks(t)
+	notifier := newNotifier(t, &config.Config{Webhooks: []config.Webhook{{URL: server.URL + "/broken"}}})
+	err := notifier.Notify(config.NotifyEventBlocked, &session.Instance{Title: "a"}, "is waiting")
+	assert.EqualError(t, err, "failed to post to slack webhook: 403 Forbidden")
+
+	notifier = newNotifier(t, &config.Config{Webhooks: []config.Webhook{{URL: "http://127.0.0.1:1/services/secret"}}})
+	err = notifier.Notify(config.NotifyEventBlocked, &session.Instance{Title: "a"}, "is waiting")
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "secret")
+
+	assert.False(t, newNotifier(t, &config.Config{}).Posts(config.NotifyEventFinished))
+}
+
+func TestKind(t *testing.T) {
+	assert.Equal(t, "discord", Kind(config.Webhook{URL: "https://discord.com/api/webhooks/1/abc"}))
+	assert.Equal(t, "discord", Kind(config.Webhook{URL: "https://canary.discord.com/api/webhooks/1/abc"}))
+	assert.Equal(t, "slack", Kind(config.Webhook{URL: "https://hooks.slack.com/services/T0/B0/abc"}))
+	assert.Equal(t, "discord", Kind(config.Webhook{URL: "https://relay.example.com/hook", Kind: "discord"}))
+}
+
+func TestDiscordMessagesAreCut(t *testing.T) {
+	server, posted := fakeWebhooks(t)
+	notifier := newNotifier(t, &config.Config{Webhooks: []config.Webhook{{URL: server.URL + "/discord", Kind: "discord"}}})
+	require.NoError(t, notifier.Notify(config.NotifyEventFinished, &session.Instance{Title: "a"}, strings.Repeat("x", 3000)))
+	assert.Len(t, posted["/discord"][0]["content"], maxDiscordLength)
+
+	// Discord counts characters, which are cut whole.
+	require.NoError(t, notifier.Notify(config.NotifyEventFinished, &session.Instance{Title: "a"}, strings.Repeat("é", 3000)))
+	content := posted["/discord"][1]["content"]
+	assert.True(t, utf8.ValidString(content))
+	assert.Equal(t, maxDiscordLength, utf8.RuneCountInString(content))
+}
+
+func TestFinished(t *testing.T) {
+	notifier := newNotifier(t, &config.Config{})
+	instance := &session.Instance{Title: "a"}
+	instance.SetStatus(session.Ready)
+	readyAt := instance.ReadySince()
+
+	assert.False(t, notifier.Finished(instance, readyAt.Add(time.Second)))
+	assert.True(t, notifier.Finished(instance, readyAt.Add(ReadyDelay)))
+	assert.False(t, notifier.Finished(instance, readyAt.Add(2*ReadyDelay)))
+
+	// Once it ran again, it's posted again.
+	instance.SetStatus(session.Running)
+	assert.False(t, notifier.Finished(instance, readyAt.Add(2*ReadyDelay)))
+	instance.SetStatus(session.Ready)
+	assert.True(t, notifier.Finished(instance, instance.ReadySince().Add(ReadyDelay)))
+}
+
+// recordingBackend records the messages sent to it.
+type recordingBackend struct {
+	sent *[]Message
+}
+
+func (b recordingBackend) Send(_ context.Context, msg Message) error {
+	*b.sent = append(*b.sent, msg)
+	return nil
+}
+
+func TestNotifyRules(t *testing.T) {
+	sent := make(map[string]*[]Message)
+	Register("recording", func(cfg config.NotifyBackend, _ *http.Client) (Backend, error) {
+		sent[cfg.Name] = &[]Message{}
+		return recordingBackend{sent: sent[cfg.Name]}, nil
+	})
+	notifier := newNotifier(t, &config.Config{
+		NotifyBackends: []config.NotifyBackend{
+			{Name: "phone", Kind: "recording"},
+			{Name: "laptop", Kind: "recording"},
+		},
+		NotifyRules: []config.NotifyRule{
+			{Title: "prod-*", Events: []string{config.NotifyEventCrashed, config.NotifyEventBlocked}, Backends: []string{"phone"}},
+			{Group: "quiet"},
+			{Backends: []string{"laptop"}},
+		},
+	})
+	hotfix := &session.Instance{Title: "prod-hotfix"}
+	quiet := &session.Instance{Title: "docs", Group: "quiet"}
+	other := &session.Instance{Title: "refactor"}
+
+	assert.Equal(t, []string{"phone"}, notifier.Route(config.NotifyEventCrashed, hotfix))
+	assert.Equal(t, []string{"laptop"}, notifier.Route(config.NotifyEventFinished, hotfix))
+	assert.Nil(t, notifier.Route(config.NotifyEventCrashed, quiet))
+	assert.True(t, notifier.Posts(config.NotifyEventFinished))
+
+	require.NoError(t, notifier.Notify(config.NotifyEventCrashed, hotfix, "crashed: its session is gone"))
+	require.NoError(t, notifier.Notify(config.NotifyEventFinished, hotfix, "is ready"))
+	require.NoError(t, notifier.Notify(config.NotifyEventFinished, quiet, "is ready"))
+	require.NoError(t, notifier.Notify(config.NotifyEventNeedsInput, other, "is waiting on a prompt"))
+
+	assert.Equal(t, []Message{
+		{Event: config.NotifyEventCrashed, Title: "prod-hotfix", Text: "crashed: its session is gone"},
+	}, *sent["phone"])
+	assert.Equal(t, []Message{
+		{Event: config.NotifyEventFinished, Title: "prod-hotfix", Text: "is ready"},
+		{Event: config.NotifyEventNeedsInput, Title: "refactor", Text: "is waiting on a prompt"},
+	}, *sent["laptop"])
+}
+
+func TestInvalidBackends(t *testing.T) {
+	notifier, err := New(&config.Config{
+		NotifyBackends: []config.NotifyBackend{
+			{Name: "phone", Kind: config.NotifyKindPushover, Token: "app"},
+			{Name: "pager", Kind: "pager"},
+			{Name: "hook", Kind: config.NotifyKindSlack, URL: "https://hooks.slack.com/services/T0/B0/abc"},
+			{Name: "hook", Kind: config.NotifyKindDiscord, URL: "https://discord.com/api/webhooks/1/abc"},
+		},
+		NotifyRules: []config.NotifyRule{{Backends: []string{"hook", "missing"}}},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid notify backend "phone": pushover needs a token and a user`)
+	assert.Contains(t, err.Error(), `invalid notify backend "pager": unknown kind "pager"`)
+	assert.Contains(t, err.Error(), `notify backend "hook" is declared twice`)
+	assert.Contains(t, err.Error(), `notify rule routes to unknown backend "missing"`)
+	assert.Equal(t, []string{"hook", "missing"}, notifier.Route(config.NotifyEventFinished, &session.Instance{Title: "a"}))
+}
+
+func TestPushover(t *testing.T) {
+	var forms []url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		forms = append(forms, r.PostForm)
+	}))
+	t.Cleanup(server.Close)
+	defer func(original string) { pushoverURL = original }(pushoverURL)
+	pushoverURL = server.URL
+
+	notifier := newNotifier(t, &config.Config{
+		NotifyBackends: []config.NotifyBackend{{Name: "phone", Kind: config.NotifyKindPushover, Token: "app", User: "me"}},
+		NotifyRules:    []config.NotifyRule{{Backends: []string{"phone"}}},
+	})
+	instance := &session.Instance{Title: "prod-hotfix"}
+	require.NoError(t, notifier.Notify(config.NotifyEventFinished, instance, "is ready"))
+	require.NoError(t, notifier.Notify(config.NotifyEventCrashed, instance, "crashed: its session is gone"))
+
+	require.Len(t, forms, 2)
+	assert.Equal(t, url.Values{"token": {"app"}, "user": {"me"}, "title": {"prod-hotfix"}, "message": {"is ready"}}, forms[0])
+	assert.Equal(t, "1", forms[1].Get("priority"))
+}
+
+func TestCommandBackend(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the command uses sh")
+	}
+	out := filepath.Join(t.TempDir(), "out")
+	notifier := newNotifier(t, &config.Config{
+		NotifyBackends: []config.NotifyBackend{
+			{Name: "log", Kind: config.NotifyKindCommand, Command: `echo "$CS_EVENT $CS_TITLE $CS_MESSAGE" > ` + out},
+		},
+		NotifyRules: []config.NotifyRule{{Backends: []string{"log"}}},
+	})
+	require.NoError(t, notifier.Notify(config.NotifyEventBlocked, &session.Instance{Title: "a"}, "is waiting"))
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, "blocked a is waiting\n", string(data))
+}
@@ -0,0 +1,118 @@
+package pipeline
+
+import (
+	"claude-squad/config"
+	"claude-squad/schema"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the file in the config directory the pipelines are defined in.
+const FileName = "pipelines.yaml"
+
+// Stage is a step of a pipeline, run by its own instance.
+type Stage struct {
+	// Name identifies the stage within its pipeline, e.g. "tests".
+	Name string `yaml:"name"`
+	// Prompt is sent to the instance of the stage before the task.
+	Prompt string `yaml:"prompt"`
+	// Program overrides the default program, if set.
+	Program string `yaml:"program,omitempty"`
+}
+
+// Pipeline is a chain of stages. Each stage is started once the previous one is done, on its branch.
+type Pipeline struct {
+	// Name identifies the pipeline.
+	Name string `yaml:"name"`
+	// Description is shown in the pipeline picker.
+	Description string `yaml:"description,omitempty"`
+	// Stages of the pipeline, in order.
+	Stages []Stage `yaml:"stages"`
+}
+
+// file is the layout of the pipelines file.
+type file struct {
+	Pipelines []*Pipeline `yaml:"pipelines"`
+}
+
+// Schema returns the JSON Schema of the pipelines file.
+func Schema() *schema.Schema {
+	return schema.Generate(file{}, "yaml", "claude-squad pipelines")
+}
+
+// Parse parses and validates the pipelines file.
+func Parse(data []byte) ([]*Pipeline, error) {
+	var f file
+	if problems, err := schema.Validate(data, Schema()); err == nil && len(problems) > 0 {
+		return nil, fmt.Errorf("invalid pipelines: %w", schema.Join(problems))
+	}
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse pipelines: %w", err)
+	}
+	seen := make(map[string]bool)
+	for i, p := range f.Pipelines {
+		if p.Name == "" {
+			return nil, fmt.Errorf("pipeline %d has no name", i+1)
+		}
+		if seen[p.Name] {
+			return nil, fmt.Errorf("duplicate pipeline %s", p.Name)
+		}
+		seen[p.Name] = true
+		if len(p.Stages) == 0 {
+			return nil, fmt.Errorf("pipeline %s has no stages", p.Name)
+		}
+		for j, stage := range p.Stages {
+			if stage.Name == "" {
+				return nil, fmt.Errorf("stage %d of pipeline %s has no name", j+1, p.Name)
+			}
+			if strings.TrimSpace(stage.Prompt) == "" {
+				return nil, fmt.Errorf("stage %s of pipeline %s has no prompt", stage.Name, p.Name)
+			}
+		}
+	}
+	return f.Pipelines, nil
+}
+
+// Load loads the pipelines from the config directory. There are none if the file doesn't exist.
+func Load() ([]*Pipeline, error) {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config directory: %w", err)
+	}
+	data, err := os.ReadFile(filepath.Join(configDir, FileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read pipelines: %w", err)
+	}
+	return Parse(data)
+}
+
+// Find returns the pipeline with the given name.
+func Find(pipelines []*Pipeline, name string) (*Pipeline, error) {
+	for _, p := range pipelines {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("pipeline %s not found", name)
+}
+
+// Prompt returns the prompt sent to the instance of the stage at index: the stage prompt, the task, and
+// the summary of the previous stage, if any.
+func (p *Pipeline) Prompt(index int, task string, previousSummary string) string {
+	prompt := strings.TrimSpace(p.Stages[index].Prompt)
+	if task = strings.TrimSpace(task); task != "" {
+		prompt += "\n\nTask: " + task
+	}
+	if index > 0 && previousSummary != "" {
+		prompt += fmt.Sprintf("\n\nThe previous stage, %s, is done on the branch you're on. Its summary:\n%s",
+			p.Stages[index-1].Name, previousSummary)
+	}
+	return prompt
+}
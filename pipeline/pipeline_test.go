@@ -0,0 +1,71 @@
+package pipeline
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const featurePipeline = `pipelines:
+  - name: feature
+    description: Implement, test and document a feature
+    stages:
+      - name: implement
+        prompt: Implement the feature.
+      - name: tests
+        prompt: Write tests for the changes on this branch.
+        program: aider
+      - name: docs
+        prompt: Update the documentation.
+`
+
+func TestParse(t *testing.T) {
+	pipelines, err := Parse([]byte(featurePipeline))
+	require.NoError(t, err)
+	require.Len(t, pipelines, 1)
+	assert.Equal(t, "feature", pipelines[0].Name)
+	require.Len(t, pipelines[0].Stages, 3)
+	assert.Equal(t, "aider", pipelines[0].Stages[1].Program)
+
+	_, err = Parse([]byte("pipelines:\n  - name: empty\n"))
+	assert.Error(t, err)
+	_, err = Parse([]byte("pipelines:\n  - name: a\n    stages:\n      - name: b\n"))
+	assert.Error(t, err)
+	_, err = Parse([]byte("pipelines:\n  - name: a\n    stages:\n      - name: b\n        prompt: c\n  - name: a\n    stages:\n      - name: b\n        prompt: c\n"))
+	assert.Error(t, err)
+	_, err = Parse([]byte("pipelines:\n  - name: a\n    stages: implement\n"))
+	assert.ErrorContains(t, err, "3:13: pipelines[0].stages: expected a list")
+}
+
+func TestLoad(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	pipelines, err := Load()
+	require.NoError(t, err)
+	assert.Empty(t, pipelines)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(home, ".claude-squad"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".claude-squad", FileName), []byte(featurePipeline), 0644))
+	pipelines, err = Load()
+	require.NoError(t, err)
+	p, err := Find(pipelines, "feature")
+	require.NoError(t, err)
+	assert.Equal(t, "docs", p.Stages[2].Name)
+	_, err = Find(pipelines, "release")
+	assert.Error(t, err)
+}
+
+func TestPrompt(t *testing.T) {
+	pipelines, err := Parse([]byte(featurePipeline))
+	require.NoError(t, err)
+	p := pipelines[0]
+
+	assert.Equal(t, "Implement the feature.\n\nTask: add dark mode", p.Prompt(0, "add dark mode", ""))
+	assert.Equal(t, "Write tests for the changes on this branch.\n\nTask: add dark mode\n\n"+
+		"The previous stage, implement, is done on the branch you're on. Its summary:\nAdded a theme toggle.",
+		p.Prompt(1, "add dark mode", "Added a theme toggle."))
+}
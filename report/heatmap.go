@@ -0,0 +1,131 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"claude-squad/session"
+)
+
+// HeatNode is a file or directory of the heat map.
+type HeatNode struct {
+	// Name is the last path component.
+	Name string
+	// Files is the number of changed files in the subtree (counting a file once per instance).
+	Files int
+	// Instances is the set of instance titles touching the subtree.
+	Instances map[string]bool
+	// Children are the entries of a directory, keyed by name.
+	Children map[string]*HeatNode
+}
+
+func newHeatNode(name string) *HeatNode {
+	return &HeatNode{
+		Name:      name,
+		Instances: make(map[string]bool),
+		Children:  make(map[string]*HeatNode),
+	}
+}
+
+// IsDir returns true if the node is a directory.
+func (n *HeatNode) IsDir() bool {
+	return len(n.Children) > 0
+}
+
+// BuildHeatMap aggregates the changed paths of each instance, keyed by instance title, into a tree.
+func BuildHeatMap(changedFiles map[string][]string) *HeatNode {
+	root := newHeatNode("")
+	for title, files := range changedFiles {
+		for _, file := range files {
+			node := root
+			node.Files++
+			node.Instances[title] = true
+			for _, part := range strings.Split(file, "/") {
+				child, ok := node.Children[part]
+				if !ok {
+					child = newHeatNode(part)
+					node.Children[part] = child
+				}
+				node = child
+				node.Files++
+				node.Instances[title] = true
+			}
+		}
+	}
+	return root
+}
+
+// HeatMapFromInstances builds the heat map from the current diffs of the instances.
+func HeatMapFromInstances(instances []*session.Instance) *HeatNode {
+	changedFiles := make(map[string][]string)
+	for _, instance := range instances {
+		stats := instance.GetDiffStats()
+		if stats == nil || stats.Error != nil {
+			continue
+		}
+		changedFiles[instance.Title] = stats.ChangedFiles()
+	}
+	return BuildHeatMap(changedFiles)
+}
+
+// FormatHeatMap renders the tree with the number of instances and files per entry, hottest entries first.
+// Directories deeper than maxDepth are collapsed into their parent. Entries touched by more than one
+// instance list the instances, since those are the likely merge conflicts.
+func FormatHeatMap(root *HeatNode, maxDepth int) string {
+	if len(root.Children) == 0 {
+		return "No changes in any instance."
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d changed files across %d instances\n", root.Files, len(root.Instances))
+	formatHeatNodes(&b, root, 0, maxDepth)
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func formatHeatNodes(b *strings.Builder, node *HeatNode, depth int, maxDepth int) {
+	for _, child := range sortedChildren(node) {
+		name := child.Name
+		if child.IsDir() {
+			name += "/"
+		}
+		fmt.Fprintf(b, "%s%s  %d instance(s), %d file(s)", strings.Repeat("  ", depth), name,
+			len(child.Instances), child.Files)
+		if len(child.Instances) > 1 {
+			fmt.Fprintf(b, "  [%s]", strings.Join(sortedTitles(child.Instances), ", "))
+		}
+		b.WriteString("\n")
+		if child.IsDir() && depth+1 < maxDepth {
+			formatHeatNodes(b, child, depth+1, maxDepth)
+		}
+	}
+}
+
+// sortedChildren returns the children sorted by the number of instances touching them, then by the
+// number of files, then by name.
+func sortedChildren(node *HeatNode) []*HeatNode {
+	children := make([]*HeatNode, 0, len(node.Children))
+	for _, child := range node.Children {
+		children = append(children, child)
+	}
+	sort.Slice(children, func(i, j int) bool {
+		a, b := children[i], children[j]
+		if len(a.Instances) != len(b.Instances) {
+			return len(a.Instances) > len(b.Instances)
+		}
+		if a.Files != b.Files {
+			return a.Files > b.Files
+		}
+		return a.Name < b.Name
+	})
+	return children
+}
+
+func sortedTitles(set map[string]bool) []string {
+	titles := make([]string, 0, len(set))
+	for title := range set {
+		titles = append(titles, title)
+	}
+	sort.Strings(titles)
+	return titles
+}
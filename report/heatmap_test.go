@@ -0,0 +1,40 @@
+package report
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildHeatMap(t *testing.T) {
+	root := BuildHeatMap(map[string][]string{
+		"auth":   {"services/auth/login.go", "services/auth/token.go"},
+		"tokens": {"services/auth/token.go", "README.md"},
+	})
+
+	assert.Equal(t, 4, root.Files)
+	assert.Len(t, root.Instances, 2)
+
+	services := root.Children["services"]
+	assert.Equal(t, 3, services.Files)
+	assert.Len(t, services.Instances, 2)
+
+	token := services.Children["auth"].Children["token.go"]
+	assert.Equal(t, 2, token.Files)
+	assert.False(t, token.IsDir())
+}
+
+func TestFormatHeatMap(t *testing.T) {
+	root := BuildHeatMap(map[string][]string{
+		"auth":   {"services/auth/login.go", "services/auth/token.go"},
+		"tokens": {"services/auth/token.go", "README.md"},
+	})
+
+	out := FormatHeatMap(root, 2)
+	assert.Equal(t, "4 changed files across 2 instances\n"+
+		"services/  2 instance(s), 3 file(s)  [auth, tokens]\n"+
+		"  auth/  2 instance(s), 3 file(s)  [auth, tokens]\n"+
+		"README.md  1 instance(s), 1 file(s)", out)
+
+	assert.Equal(t, "No changes in any instance.", FormatHeatMap(BuildHeatMap(nil), 2))
+}
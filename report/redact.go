@@ -0,0 +1,15 @@
+package report
+
+import "regexp"
+
+// redacted replaces the redacted text in exported artifacts.
+const redacted = "[REDACTED]"
+
+// Redact replaces the matches of the patterns in an exported artifact, so it can be shared outside the
+// team without leaking secrets, internal hostnames or customer data.
+func Redact(text string, patterns []*regexp.Regexp) string {
+	for _, pattern := range patterns {
+		text = pattern.ReplaceAllLiteralString(text, redacted)
+	}
+	return text
+}
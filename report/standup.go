@@ -0,0 +1,154 @@
+package report
+
+import (
+	"claude-squad/log"
+	"claude-squad/session"
+	"claude-squad/session/claude"
+	"claude-squad/textutil"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// maxHighlights is the number of agent messages included per instance.
+const maxHighlights = 3
+
+// maxHighlightLength is the maximum length of a single highlight.
+const maxHighlightLength = 200
+
+// Activity is the activity of a single instance over the report period.
+type Activity struct {
+	Title  string
+	Branch string
+	Status string
+	// Commits are the one-line summaries of the commits made in the period.
+	Commits []string
+	// CommittedAdded and CommittedRemoved are the lines changed by the commits made in the period.
+	CommittedAdded   int
+	CommittedRemoved int
+	// DiffAdded and DiffRemoved are the lines changed in total against the base commit.
+	DiffAdded   int
+	DiffRemoved int
+	// Highlights are excerpts of the last agent messages in the period.
+	Highlights []string
+	// Errors are the errors related to the instance in the period.
+	Errors []string
+}
+
+// Standup returns a markdown report of the activity of the instances since the given time.
+func Standup(instances []*session.Instance, since time.Time) string {
+	logErrors, err := log.ErrorsSince(since)
+	if err != nil {
+		log.WarningLog.Printf("could not read errors for standup report: %v", err)
+	}
+
+	activities := make([]Activity, 0, len(instances))
+	for _, instance := range instances {
+		activities = append(activities, CollectActivity(instance, since, logErrors))
+	}
+	return FormatStandup(activities, since, time.Now())
+}
+
+// CollectActivity gathers the activity of an instance since the given time. logErrors are the errors
+// logged in the period; the ones mentioning the instance are attributed to it.
+func CollectActivity(instance *session.Instance, since time.Time, logErrors []string) Activity {
+	activity := Activity{
+		Title:  instance.Title,
+		Branch: instance.Branch,
+		Status: instance.Status.String(),
+	}
+
+	if stats := instance.GetDiffStats(); stats != nil {
+		activity.DiffAdded = stats.Added
+		activity.DiffRemoved = stats.Removed
+		if stats.Error != nil {
+			activity.Errors = append(activity.Errors, fmt.Sprintf("diff: %v", stats.Error))
+		}
+	}
+
+	for _, logErr := range logErrors {
+		if strings.Contains(logErr, instance.Title) {
+			activity.Errors = append(activity.Errors, logErr)
+		}
+	}
+
+	worktree, err := instance.GetGitWorktree()
+	if err != nil {
+		activity.Errors = append(activity.Errors, err.Error())
+		return activity
+	}
+	if !instance.Paused() && !instance.TmuxAlive() {
+		activity.Errors = append(activity.Errors, "tmux session is not running")
+	}
+
+	if commits, err := worktree.CommitsSince(since); err != nil {
+		activity.Errors = append(activity.Errors, err.Error())
+	} else {
+		activity.Commits = commits
+	}
+	if added, removed, err := worktree.ChangesSince(since); err != nil {
+		activity.Errors = append(activity.Errors, err.Error())
+	} else {
+		activity.CommittedAdded, activity.CommittedRemoved = added, removed
+	}
+
+	messages, err := claude.RecentMessages(worktree.GetWorktreePath(), "assistant", since)
+	if err != nil {
+		log.WarningLog.Printf("could not read conversation of %s: %v", instance.Title, err)
+	}
+	if len(messages) > maxHighlights {
+		messages = messages[len(messages)-maxHighlights:]
+	}
+	for _, msg := range messages {
+		activity.Highlights = append(activity.Highlights, excerpt(msg.Text, maxHighlightLength))
+	}
+
+	return activity
+}
+
+// FormatStandup renders the activities as markdown.
+func FormatStandup(activities []Activity, since time.Time, now time.Time) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Squad standup (%s – %s)\n", since.Format("Jan 2 15:04"), now.Format("Jan 2 15:04"))
+
+	if len(activities) == 0 {
+		b.WriteString("\nNo instances.\n")
+		return b.String()
+	}
+
+	for _, a := range activities {
+		fmt.Fprintf(&b, "\n### %s\n", a.Title)
+		fmt.Fprintf(&b, "- Branch: `%s` (%s)\n", a.Branch, a.Status)
+		if len(a.Commits) == 0 {
+			b.WriteString("- Commits: none\n")
+		} else {
+			fmt.Fprintf(&b, "- Commits: %d (+%d/-%d)\n", len(a.Commits), a.CommittedAdded, a.CommittedRemoved)
+			for _, commit := range a.Commits {
+				fmt.Fprintf(&b, "  - %s\n", commit)
+			}
+		}
+		fmt.Fprintf(&b, "- Diff vs base: +%d/-%d\n", a.DiffAdded, a.DiffRemoved)
+		if len(a.Highlights) > 0 {
+			b.WriteString("- Highlights:\n")
+			for _, highlight := range a.Highlights {
+				fmt.Fprintf(&b, "  > %s\n", highlight)
+			}
+		}
+		if len(a.Errors) > 0 {
+			b.WriteString("- Errors:\n")
+			for _, err := range a.Errors {
+				fmt.Fprintf(&b, "  - %s\n", excerpt(err, maxHighlightLength))
+			}
+		}
+	}
+	return b.String()
+}
+
+// excerpt returns the first line of s, cut to at most max characters.
+func excerpt(s string, max int) string {
+	s = strings.TrimSpace(s)
+	if idx := strings.IndexByte(s, '\n'); idx >= 0 {
+		s = s[:idx]
+	}
+	return textutil.Truncate(s, max)
+}
@@ -0,0 +1,56 @@
+package report
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatStandup(t *testing.T) {
+	since := time.Date(2025, 1, 1, 18, 0, 0, 0, time.UTC)
+	now := since.Add(14 * time.Hour)
+
+	t.Run("no instances", func(t *testing.T) {
+		out := FormatStandup(nil, since, now)
+		assert.Contains(t, out, "No instances.")
+	})
+
+	t.Run("renders activity", func(t *testing.T) {
+		out := FormatStandup([]Activity{
+			{
+				Title:            "fix-login",
+				Branch:           "me/fix-login",
+				Status:           "ready",
+				Commits:          []string{"abc123 fix login redirect"},
+				CommittedAdded:   10,
+				CommittedRemoved: 2,
+				DiffAdded:        12,
+				DiffRemoved:      3,
+				Highlights:       []string{"I fixed the redirect loop."},
+				Errors:           []string{"tmux session is not running"},
+			},
+			{
+				Title:  "idle",
+				Branch: "me/idle",
+				Status: "paused",
+			},
+		}, since, now)
+
+		assert.Contains(t, out, "### fix-login")
+		assert.Contains(t, out, "- Branch: `me/fix-login` (ready)")
+		assert.Contains(t, out, "- Commits: 1 (+10/-2)\n  - abc123 fix login redirect")
+		assert.Contains(t, out, "- Diff vs base: +12/-3")
+		assert.Contains(t, out, "  > I fixed the redirect loop.")
+		assert.Contains(t, out, "- Errors:\n  - tmux session is not running")
+		assert.Contains(t, out, "### idle\n- Branch: `me/idle` (paused)\n- Commits: none")
+	})
+}
+
+func TestExcerpt(t *testing.T) {
+	assert.Equal(t, "first line", excerpt("  first line\nsecond line", 100))
+	long := strings.Repeat("a", 50)
+	assert.Equal(t, strings.Repeat("a", 7)+"...", excerpt(long, 10))
+	assert.Equal(t, "Réparé la...", excerpt("Réparé la boucle de redirection", 12))
+}
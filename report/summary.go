@@ -0,0 +1,63 @@
+package report
+
+import (
+	"claude-squad/session"
+	"fmt"
+	"strings"
+)
+
+// Summary is the state of an instance, shared as markdown in pull requests, chats or notes.
+type Summary struct {
+	Title      string
+	Branch     string
+	Status     string
+	BaseCommit string
+	Added      int
+	Removed    int
+	// Files are the files changed against the base commit.
+	Files []string
+	// LastMessage is the last message of the agent, if it's known.
+	LastMessage string
+}
+
+// SummaryOf collects the summary of the instance.
+func SummaryOf(instance *session.Instance) Summary {
+	summary := Summary{
+		Title:       instance.Title,
+		Branch:      instance.Branch,
+		Status:      instance.Status.String(),
+		LastMessage: instance.LastAgentMessage(),
+	}
+	if worktree, err := instance.GetGitWorktree(); err == nil {
+		summary.BaseCommit = worktree.GetBaseCommitSHA()
+	}
+	if stats := instance.GetDiffStats(); stats != nil && stats.Error == nil {
+		summary.Added, summary.Removed = stats.Added, stats.Removed
+		summary.Files = stats.ChangedFiles()
+	}
+	return summary
+}
+
+// FormatSummary renders the summary as markdown.
+func FormatSummary(s Summary) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "### %s\n\n", s.Title)
+	fmt.Fprintf(&b, "- Branch: `%s` (%s)\n", s.Branch, s.Status)
+	if s.BaseCommit != "" {
+		fmt.Fprintf(&b, "- Base commit: `%s`\n", s.BaseCommit)
+	}
+	fmt.Fprintf(&b, "- Diff: +%d/-%d in %d files\n", s.Added, s.Removed, len(s.Files))
+	if len(s.Files) > 0 {
+		b.WriteString("\n#### Changed files\n\n")
+		for _, file := range s.Files {
+			fmt.Fprintf(&b, "- `%s`\n", file)
+		}
+	}
+	if message := strings.TrimSpace(s.LastMessage); message != "" {
+		b.WriteString("\n#### Last agent message\n\n")
+		for _, line := range strings.Split(message, "\n") {
+			b.WriteString(strings.TrimRight("> "+line, " ") + "\n")
+		}
+	}
+	return b.String()
+}
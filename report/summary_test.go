@@ -0,0 +1,29 @@
+package report
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatSummary(t *testing.T) {
+	out := FormatSummary(Summary{
+		Title:       "fix-login",
+		Branch:      "me/fix-login",
+		Status:      "ready",
+		BaseCommit:  "abc123",
+		Added:       12,
+		Removed:     3,
+		Files:       []string{"auth/login.go", "auth/login_test.go"},
+		LastMessage: "I fixed the redirect loop.\n\nThe tests pass.",
+	})
+	assert.Equal(t, "### fix-login\n\n"+
+		"- Branch: `me/fix-login` (ready)\n"+
+		"- Base commit: `abc123`\n"+
+		"- Diff: +12/-3 in 2 files\n\n"+
+		"#### Changed files\n\n- `auth/login.go`\n- `auth/login_test.go`\n\n"+
+		"#### Last agent message\n\n> I fixed the redirect loop.\n>\n> The tests pass.\n", out)
+
+	out = FormatSummary(Summary{Title: "idle", Branch: "me/idle", Status: "paused"})
+	assert.Equal(t, "### idle\n\n- Branch: `me/idle` (paused)\n- Diff: +0/-0 in 0 files\n", out)
+}
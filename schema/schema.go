@@ -0,0 +1,223 @@
+// Package schema derives JSON Schemas from the types of the config files, for editors to validate and
+// complete them, and validates the files against them with the line and column of each problem.
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Draft is the JSON Schema version of the schemas.
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a JSON Schema, limited to what the config files need.
+type Schema struct {
+	SchemaURI  string             `json:"$schema,omitempty"`
+	Title      string             `json:"title,omitempty"`
+	Type       string             `json:"type,omitempty"`
+	Format     string             `json:"format,omitempty"`
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Items      *Schema            `json:"items,omitempty"`
+	// Values is the schema of the values of a map, whose keys are free.
+	Values *Schema `json:"-"`
+	// Closed disallows other properties than Properties.
+	Closed bool `json:"-"`
+}
+
+// MarshalJSON writes additionalProperties from Values and Closed.
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	type plain Schema
+	out := struct {
+		*plain
+		AdditionalProperties any `json:"additionalProperties,omitempty"`
+	}{plain: (*plain)(s)}
+	if s.Values != nil {
+		out.AdditionalProperties = s.Values
+	} else if s.Closed {
+		out.AdditionalProperties = false
+	}
+	return json.Marshal(out)
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Generate returns the schema of the documents v is decoded from, with the field names of tag, "json" or
+// "yaml". Structs don't allow other fields than theirs, except for a "$schema" field at the root, for
+// files to point editors at their schema.
+func Generate(v any, tag string, title string) *Schema {
+	s := generate(reflect.TypeOf(v), tag)
+	s.SchemaURI = Draft
+	s.Title = title
+	if s.Properties != nil {
+		s.Properties["$schema"] = &Schema{Type: "string"}
+	}
+	return s
+}
+
+func generate(t reflect.Type, tag string) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// Raw JSON, or bytes encoded as a string.
+			return &Schema{}
+		}
+		return &Schema{Type: "array", Items: generate(t.Elem(), tag)}
+	case reflect.Map:
+		return &Schema{Type: "object", Values: generate(t.Elem(), tag)}
+	case reflect.Struct:
+		s := &Schema{Type: "object", Properties: make(map[string]*Schema), Closed: true}
+		addFields(s, t, tag)
+		return s
+	default:
+		return &Schema{}
+	}
+}
+
+// addFields adds the fields of the struct t to the properties of s, inlining embedded structs.
+func addFields(s *Schema, t reflect.Type, tag string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, options, _ := strings.Cut(field.Tag.Get(tag), ",")
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if field.Anonymous && name == "" || strings.Contains(options, "inline") {
+			addFields(s, field.Type, tag)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+			if tag == "yaml" {
+				name = strings.ToLower(name)
+			}
+		}
+		s.Properties[name] = generate(field.Type, tag)
+	}
+}
+
+// Problem is a value of a document that doesn't match its schema.
+type Problem struct {
+	Line   int
+	Column int
+	// Path is where the value is in the document, e.g. "triggers[0].event".
+	Path    string
+	Message string
+}
+
+func (p Problem) Error() string {
+	if p.Path == "" {
+		return fmt.Sprintf("%d:%d: %s", p.Line, p.Column, p.Message)
+	}
+	return fmt.Sprintf("%d:%d: %s: %s", p.Line, p.Column, p.Path, p.Message)
+}
+
+// Join returns an error listing the problems, one per line.
+func Join(problems []Problem) error {
+	errs := make([]error, len(problems))
+	for i, problem := range problems {
+		errs[i] = problem
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks a JSON or YAML document against the schema. It returns the problems in the order they
+// appear in the document, or the syntax error if it can't be parsed.
+func Validate(data []byte, s *Schema) ([]Problem, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	var problems []Problem
+	validate(doc.Content[0], s, "", &problems)
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].Line != problems[j].Line {
+			return problems[i].Line < problems[j].Line
+		}
+		return problems[i].Column < problems[j].Column
+	})
+	return problems, nil
+}
+
+func validate(node *yaml.Node, s *Schema, path string, problems *[]Problem) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	report := func(node *yaml.Node, path string, format string, args ...any) {
+		*problems = append(*problems, Problem{Line: node.Line, Column: node.Column, Path: path,
+			Message: fmt.Sprintf(format, args...)})
+	}
+	if node.Tag == "!!null" || s.Type == "" {
+		return
+	}
+
+	switch s.Type {
+	case "object":
+		if node.Kind != yaml.MappingNode {
+			report(node, path, "expected an object")
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			childPath := key.Value
+			if path != "" {
+				childPath = path + "." + key.Value
+			}
+			if property, ok := s.Properties[key.Value]; ok {
+				validate(value, property, childPath, problems)
+			} else if s.Values != nil {
+				validate(value, s.Values, childPath, problems)
+			} else if s.Closed {
+				report(key, path, "unknown field %q", key.Value)
+			}
+		}
+	case "array":
+		if node.Kind != yaml.SequenceNode {
+			report(node, path, "expected a list")
+			return
+		}
+		for i, item := range node.Content {
+			validate(item, s.Items, fmt.Sprintf("%s[%d]", path, i), problems)
+		}
+	case "string":
+		if node.Kind != yaml.ScalarNode || !(node.Tag == "!!str" || s.Format == "date-time" && node.Tag == "!!timestamp") {
+			report(node, path, "expected a string")
+		}
+	case "boolean":
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!bool" {
+			report(node, path, "expected true or false")
+		}
+	case "integer":
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!int" {
+			report(node, path, "expected an integer")
+		}
+	case "number":
+		if node.Kind != yaml.ScalarNode || (node.Tag != "!!int" && node.Tag != "!!float") {
+			report(node, path, "expected a number")
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type item struct {
+	Name  string            `json:"name"`
+	Count int               `json:"count,omitempty"`
+	Tags  []string          `json:"tags,omitempty"`
+	Env   map[string]string `json:"env,omitempty"`
+	At    time.Time         `json:"at"`
+	skip  bool
+}
+
+type doc struct {
+	Enabled bool    `json:"enabled"`
+	Ratio   float64 `json:"ratio"`
+	Items   []item  `json:"items"`
+	Ignored string  `json:"-"`
+}
+
+func TestGenerate(t *testing.T) {
+	s := Generate(doc{}, "json", "doc")
+	data, err := json.Marshal(s)
+	require.NoError(t, err)
+
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(data, &got))
+	assert.Equal(t, Draft, got["$schema"])
+	assert.Equal(t, false, got["additionalProperties"])
+	properties := got["properties"].(map[string]any)
+	assert.ElementsMatch(t, []string{"$schema", "enabled", "ratio", "items"}, keys(properties))
+
+	itemSchema := properties["items"].(map[string]any)["items"].(map[string]any)
+	itemProperties := itemSchema["properties"].(map[string]any)
+	assert.ElementsMatch(t, []string{"name", "count", "tags", "env", "at"}, keys(itemProperties))
+	assert.Equal(t, map[string]any{"type": "string", "format": "date-time"}, itemProperties["at"])
+	assert.Equal(t, map[string]any{"type": "object", "additionalProperties": map[string]any{"type": "string"}},
+		itemProperties["env"])
+}
+
+func TestValidate(t *testing.T) {
+	s := Generate(doc{}, "json", "doc")
+	data := []byte(`{
+  "$schema": "./doc.schema.json",
+  "enabled": "yes",
+  "ratio": 1,
+  "items": [
+    {"name": "a", "count": 2, "env": {"A": "1"}, "at": "2024-01-02T03:04:05Z"},
+    {"name": 3, "colour": "red", "tags": "x"}
+  ]
+}`)
+	problems, err := Validate(data, s)
+	require.NoError(t, err)
+	assert.Equal(t, []Problem{
+		{Line: 3, Column: 14, Path: "enabled", Message: "expected true or false"},
+		{Line: 7, Column: 14, Path: "items[1].name", Message: "expected a string"},
+		{Line: 7, Column: 17, Path: "items[1]", Message: `unknown field "colour"`},
+		{Line: 7, Column: 42, Path: "items[1].tags", Message: "expected a list"},
+	}, problems)
+	assert.EqualError(t, problems[2], `7:17: items[1]: unknown field "colour"`)
+}
+
+func TestValidateYAML(t *testing.T) {
+	s := Generate(doc{}, "json", "doc")
+	problems, err := Validate([]byte("enabled: true\nitems:\n  - name: a\n    at: 2024-01-02\n    cout: 1\n"), s)
+	require.NoError(t, err)
+	assert.Equal(t, []Problem{{Line: 5, Column: 5, Path: "items[0]", Message: `unknown field "cout"`}}, problems)
+
+	_, err = Validate([]byte("{\"enabled\": "), s)
+	assert.Error(t, err)
+}
+
+func keys(m map[string]any) []string {
+	var out []string
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
@@ -0,0 +1,154 @@
+package session
+
+import (
+	"claude-squad/config"
+	"claude-squad/session/git"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// AdoptCandidate is a worktree of a hand-rolled setup, made outside claude-squad, that can be adopted as
+// an instance, along with the tmux session running in it if there's one.
+type AdoptCandidate struct {
+	// Title is the title of the instance adopting it: the name of its tmux session, or else the directory
+	// name of its worktree.
+	Title    string
+	Worktree git.Worktree
+	// Terminal is the name of the tmux session running in the worktree, empty if there's none.
+	Terminal string
+}
+
+// FindAdoptable returns the worktrees of the repository at repoPath that match the patterns and that none
+// of the instances own, each with the tmux session matching the patterns that runs in it, if any. The
+// worktrees in a detached HEAD are left out, since an instance needs a branch.
+func FindAdoptable(repoPath string, patterns config.Adopt, instances []*Instance) ([]AdoptCandidate, error) {
+	for _, pattern := range append(patterns.Worktrees, patterns.Sessions...) {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid adopt pattern %q: %w", pattern, err)
+		}
+	}
+	worktrees, err := git.ListWorktrees(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	terminals, err := listTerminals([]string{""})
+	if err != nil {
+		return nil, err
+	}
+	owned := make(map[string]bool)
+	for _, instance := range instances {
+		if instance.Host != "" {
+			continue
+		}
+		owned[instance.TerminalName()] = true
+		if instance.gitWorktree != nil {
+			owned[filepath.Clean(instance.gitWorktree.GetWorktreePath())] = true
+		}
+	}
+	return matchAdoptable(worktrees, terminals, patterns, owned), nil
+}
+
+// matchAdoptable pairs the worktrees matching the patterns with the tmux sessions matching them that run
+// in them, leaving out the worktrees and sessions in owned.
+func matchAdoptable(worktrees []git.Worktree, terminals []OrphanTerminal, patterns config.Adopt, owned map[string]bool) []AdoptCandidate {
+	taken := make(map[string]bool)
+	var candidates []AdoptCandidate
+	for _, worktree := range worktrees {
+		path := filepath.Clean(worktree.Path)
+		if worktree.Branch == "" || owned[path] ||
+			!matchesAdoptPatterns(patterns.Worktrees, path, filepath.Base(path)) {
+			continue
+		}
+		candidate := AdoptCandidate{Title: filepath.Base(path), Worktree: worktree}
+		for _, terminal := range terminals {
+			if owned[terminal.Name] || taken[terminal.Name] || !matchesAdoptPatterns(patterns.Sessions, terminal.Name) {
+				continue
+			}
+			if dir := filepath.Clean(terminal.Path); dir == path || strings.HasPrefix(dir, path+string(filepath.Separator)) {
+				candidate.Terminal = terminal.Name
+				candidate.Title = terminal.Title()
+				taken[terminal.Name] = true
+				break
+			}
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates
+}
+
+// matchesAdoptPatterns returns true if one of the names matches one of the patterns, or if there are no
+// patterns.
+func matchesAdoptPatterns(patterns []string, names ...string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		for _, name := range names {
+			if matched, _ := filepath.Match(pattern, name); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Adopt returns a running instance of the worktree, inferring its repository, branch and base commit.
+// The instance takes over the tmux session of the worktree, program being assumed to be what runs in it,
+// or starts program in a new one, resuming the latest claude conversation of the worktree if there's one.
+func (c AdoptCandidate) Adopt(program string) (*Instance, error) {
+	worktree, err := git.NewGitWorktreeFromPath(c.Worktree.Path, c.Title)
+	if err != nil {
+		return nil, fmt.Errorf("cannot adopt %s: %w", c.Worktree.Path, err)
+	}
+	terminalName := c.Terminal
+	if terminalName == "" {
+		// The name is picked before the instance is loaded, so it isn't restored in an unrelated session.
+		terminalName = (&Instance{Title: c.Title}).freeTerminalName()
+	}
+	now := time.Now()
+	data := InstanceData{
+		Title:        c.Title,
+		Path:         worktree.GetRepoPath(),
+		Branch:       worktree.GetBranchName(),
+		Status:       Running,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		Program:      program,
+		TerminalName: terminalName,
+		Worktree: GitWorktreeData{
+			RepoPath:      worktree.GetRepoPath(),
+			WorktreePath:  worktree.GetWorktreePath(),
+			SessionName:   c.Title,
+			BranchName:    worktree.GetBranchName(),
+			BaseCommitSHA: worktree.GetBaseCommitSHA(),
+			// The branch was created by hand, so it's kept when the instance is killed.
+			ExistingBranch: true,
+		},
+	}
+	instance, err := FromInstanceData(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot adopt %s: %w", c.Worktree.Path, err)
+	}
+	if c.Terminal == "" {
+		// Without a session to restore, the instance is gone, and a session is started in its worktree.
+		if err := instance.Recreate(true); err != nil {
+			return nil, fmt.Errorf("cannot adopt %s: %w", c.Worktree.Path, err)
+		}
+		instance.ResolveErrors(ErrorOpTmux)
+		instance.Audit(AuditCreated, "adopted worktree "+c.Worktree.Path)
+	} else {
+		instance.Audit(AuditCreated, fmt.Sprintf("adopted worktree %s and terminal session %s", c.Worktree.Path, c.Terminal))
+	}
+	return instance, nil
+}
+
+// String describes the candidate, for the adopt picker and `cs adopt`.
+func (c AdoptCandidate) String() string {
+	s := fmt.Sprintf("%s: %s on %s", c.Title, c.Worktree.Path, c.Worktree.Branch)
+	if c.Terminal != "" {
+		s += fmt.Sprintf(", tmux session %s", c.Terminal)
+	}
+	return s
+}
@@ -0,0 +1,42 @@
+package session
+
+import (
+	"claude-squad/config"
+	"claude-squad/session/git"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchAdoptable(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	worktrees := []git.Worktree{
+		{Path: "/src/app-login", Branch: "fix-login"},
+		{Path: "/src/app-search", Branch: "search"},
+		{Path: "/src/app-owned", Branch: "owned"},
+		{Path: "/src/app-detached"},
+		{Path: "/tmp/scratch", Branch: "scratch"},
+	}
+	terminals := []OrphanTerminal{
+		{Name: "work-login", Path: "/src/app-login/web"},
+		{Name: "notes", Path: "/src/app-search"},
+		{Name: "work-search", Path: "/src/app-search"},
+	}
+	owned := map[string]bool{"/src/app-owned": true}
+
+	candidates := matchAdoptable(worktrees, terminals, config.Adopt{
+		Worktrees: []string{"app-*"},
+		Sessions:  []string{"work-*"},
+	}, owned)
+	assert.Equal(t, []AdoptCandidate{
+		// A session started in a directory of the worktree runs in it.
+		{Title: "work-login", Worktree: worktrees[0], Terminal: "work-login"},
+		{Title: "work-search", Worktree: worktrees[1], Terminal: "work-search"},
+	}, candidates)
+
+	// Without patterns, every worktree on a branch is adopted, with the first session running in it.
+	candidates = matchAdoptable(worktrees, terminals, config.Adopt{}, owned)
+	assert.Len(t, candidates, 3)
+	assert.Equal(t, "notes", candidates[1].Terminal)
+	assert.Equal(t, AdoptCandidate{Title: "scratch", Worktree: worktrees[4]}, candidates[2])
+}
@@ -0,0 +1,107 @@
+package session
+
+import (
+	"claude-squad/session/claude"
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const (
+	// minAffinity is the minimum affinity for a task to be considered a follow-up of an instance's work.
+	minAffinity = 3
+	// fileAffinity is the affinity of each file the instance touched that the task mentions.
+	fileAffinity = 3
+	// maxTouchedFiles caps the touched files kept per instance.
+	maxTouchedFiles = 200
+)
+
+// recordTouchedFiles adds the changed files to the files the instance touched. Unlike the diff, the
+// history keeps files whose changes were reverted or folded into the base by a restack.
+func (i *Instance) recordTouchedFiles(files []string) {
+	var added []string
+	for _, file := range files {
+		if !slices.Contains(i.TouchedFiles, file) && len(i.TouchedFiles) < maxTouchedFiles {
+			i.TouchedFiles = append(i.TouchedFiles, file)
+			added = append(added, file)
+		}
+	}
+	if len(added) > 0 {
+		i.Audit(AuditFilesChanged, strings.Join(added, ", "))
+	}
+}
+
+// TaskAffinity scores how related a task is to the earlier work of the instance: the files it touched
+// that the task mentions, by path or name, and the words of its title the task shares. Instances with a
+// claude conversation in their worktree score a bit higher, since it holds the context of the work.
+func (i *Instance) TaskAffinity(task string) int {
+	task = strings.ToLower(task)
+	words := make(map[string]bool)
+	for _, word := range nonWordRegex.Split(task, -1) {
+		words[word] = true
+	}
+
+	score := fileAffinity * len(i.RelatedFiles(task))
+	for _, word := range nonWordRegex.Split(strings.ToLower(i.Title), -1) {
+		// Short words like "fix" or "the" say nothing about the feature.
+		if len(word) >= 4 && words[word] {
+			score++
+		}
+	}
+
+	if score > 0 && i.hasConversation() {
+		score++
+	}
+	return score
+}
+
+// hasConversation returns true if claude has a conversation for the instance's worktree.
+func (i *Instance) hasConversation() bool {
+	if i.gitWorktree == nil || path.Base(programBinary(i.Program)) != "claude" {
+		return false
+	}
+	conversations, err := claude.ListConversations(i.gitWorktree.GetWorktreePath())
+	return err == nil && len(conversations) > 0
+}
+
+// FindAffinity returns the instance whose earlier work the task follows up on, if any.
+func FindAffinity(instances []*Instance, task string) *Instance {
+	var best *Instance
+	bestScore := minAffinity - 1
+	for _, instance := range instances {
+		if score := instance.TaskAffinity(task); score > bestScore {
+			best, bestScore = instance, score
+		}
+	}
+	return best
+}
+
+// RelatedFiles returns the files the instance touched that the task mentions, sorted.
+func (i *Instance) RelatedFiles(task string) []string {
+	task = strings.ToLower(task)
+	var files []string
+	for _, file := range i.TouchedFiles {
+		lower := strings.ToLower(file)
+		if strings.Contains(task, lower) || strings.Contains(task, path.Base(lower)) {
+			files = append(files, file)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// FollowUpPrompt prefixes a follow-up task with the context of the earlier work of the instance, for
+// programs restarted since.
+func FollowUpPrompt(instance *Instance, task string) string {
+	if len(instance.TouchedFiles) == 0 {
+		return task
+	}
+	files := instance.TouchedFiles
+	if len(files) > 10 {
+		files = files[:10]
+	}
+	return fmt.Sprintf("This is a follow-up to your earlier work on this branch, which changed %s. %s",
+		strings.Join(files, ", "), task)
+}
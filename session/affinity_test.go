@@ -0,0 +1,35 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindAffinity(t *testing.T) {
+	auth := &Instance{Title: "oauth-login", TouchedFiles: []string{"services/auth/token.go", "services/auth/login.go"}}
+	docs := &Instance{Title: "update-docs", TouchedFiles: []string{"README.md"}}
+	instances := []*Instance{auth, docs}
+
+	assert.Same(t, auth, FindAffinity(instances, "Refresh the token in token.go before it expires"))
+	assert.Same(t, auth, FindAffinity(instances, "Handle errors in services/auth/login.go"))
+	assert.Same(t, docs, FindAffinity(instances, "Add the new flags to README.md"))
+	// Sharing a word of the title isn't enough on its own.
+	assert.Nil(t, FindAffinity(instances, "Add a logout button next to the oauth one"))
+	assert.Nil(t, FindAffinity(instances, "Fix the flaky scheduler test"))
+
+	assert.Equal(t, []string{"services/auth/login.go", "services/auth/token.go"},
+		auth.RelatedFiles("login.go and token.go need the same retry"))
+}
+
+func TestRecordTouchedFiles(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	instance := &Instance{}
+	instance.recordTouchedFiles([]string{"a.go", "b.go"})
+	// Files stay in the history when their changes are reverted.
+	instance.recordTouchedFiles([]string{"b.go", "c.go"})
+	assert.Equal(t, []string{"a.go", "b.go", "c.go"}, instance.TouchedFiles)
+
+	assert.Equal(t, "This is a follow-up to your earlier work on this branch, which changed a.go, b.go, c.go. Add tests",
+		FollowUpPrompt(instance, "Add tests"))
+}
@@ -0,0 +1,185 @@
+package session
+
+import (
+	"claude-squad/config"
+	"claude-squad/log"
+	"claude-squad/session/tmux"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// PromptKind is the kind of a permission prompt of a program, which tells whether auto-yes answers it.
+type PromptKind string
+
+const (
+	// PromptEdit is a prompt to edit or create a file.
+	PromptEdit PromptKind = "edit"
+	// PromptCommand is a prompt to run a shell command.
+	PromptCommand PromptKind = "command"
+	// PromptNetwork is a prompt to fetch a URL, or to run a command that accesses the network.
+	PromptNetwork PromptKind = "network"
+	// PromptOther is any other prompt, e.g. to use an MCP tool.
+	PromptOther PromptKind = "other"
+)
+
+// promptKinds are the kinds of prompts, in the order they're told apart.
+var promptKinds = []PromptKind{PromptEdit, PromptCommand, PromptNetwork, PromptOther}
+
+// promptTailLines is how many of the last lines of the pane make up the prompt, so what the program
+// printed before it doesn't count.
+const promptTailLines = 20
+
+var (
+	// commandPromptPattern matches the prompts of claude, aider and gemini to run a shell command.
+	commandPromptPattern = regexp.MustCompile(`(?i)bash command|run shell command|allow execution`)
+	// networkPromptPattern matches the prompts to fetch a URL or search the web.
+	networkPromptPattern = regexp.MustCompile(`(?im)^[\s│]*(fetch|web ?search)\b|fetch this content|open url|add url`)
+	// editPromptPattern matches the prompts to edit or create a file.
+	editPromptPattern = regexp.MustCompile(`(?i)edit file|create file|make this edit|do you want to create|apply this change|create new file|add .+ to the chat|allow edit`)
+	// networkCommandPattern matches the commands that access the network.
+	networkCommandPattern = regexp.MustCompile(`\b(curl|wget|ssh|scp|rsync|nc|telnet|ftp)\s|\bgit\s+(push|pull|fetch|clone)\b|\b(npm|pnpm|yarn|pip3?|go)\s+(install|add|get)\b`)
+)
+
+// promptTail returns the last lines of content, where programs show their permission prompts.
+func promptTail(content string) string {
+	lines := strings.Split(strings.TrimRight(content, "\n "), "\n")
+	if len(lines) > promptTailLines {
+		lines = lines[len(lines)-promptTailLines:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ClassifyPrompt returns the kind of the permission prompt at the bottom of content.
+func ClassifyPrompt(content string) PromptKind {
+	prompt := promptTail(content)
+	switch {
+	case commandPromptPattern.MatchString(prompt):
+		if networkCommandPattern.MatchString(prompt) {
+			return PromptNetwork
+		}
+		return PromptCommand
+	case networkPromptPattern.MatchString(prompt):
+		return PromptNetwork
+	case editPromptPattern.MatchString(prompt):
+		return PromptEdit
+	}
+	return PromptOther
+}
+
+// ApprovalPolicy tells which permission prompts auto-yes answers, from the auto_yes_policy config.
+type ApprovalPolicy struct {
+	// approve are the kinds of prompts answered. If nil, all of them are.
+	approve []PromptKind
+	// hold are the patterns of prompts never answered.
+	hold []*regexp.Regexp
+}
+
+// NewApprovalPolicy compiles the policy of the config. If a kind or a hold pattern is invalid, the
+// returned policy holds every prompt rather than answering ones that should have been held.
+func NewApprovalPolicy(cfg config.AutoYesPolicy) (ApprovalPolicy, error) {
+	var policy ApprovalPolicy
+	var errs []error
+	for _, kind := range cfg.Approve {
+		if !slices.Contains(promptKinds, PromptKind(kind)) {
+			errs = append(errs, fmt.Errorf("invalid auto-yes prompt kind %q, use edit, command, network or other", kind))
+			continue
+		}
+		policy.approve = append(policy.approve, PromptKind(kind))
+	}
+	for _, source := range cfg.Hold {
+		pattern, err := regexp.Compile(source)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid auto-yes hold pattern %q: %w", source, err))
+			continue
+		}
+		policy.hold = append(policy.hold, pattern)
+	}
+	if len(errs) > 0 {
+		return ApprovalPolicy{approve: []PromptKind{}}, errors.Join(errs...)
+	}
+	return policy, nil
+}
+
+// Approves returns true if auto-yes answers the prompt of the kind.
+func (p ApprovalPolicy) Approves(kind PromptKind, prompt string) bool {
+	if p.approve != nil && !slices.Contains(p.approve, kind) {
+		return false
+	}
+	for _, pattern := range p.hold {
+		if pattern.MatchString(prompt) {
+			return false
+		}
+	}
+	return true
+}
+
+// AnswerPrompt answers the permission prompt the instance shows if auto-yes is on and the policy approves
+// it. Otherwise the prompt is held with the NeedsApproval status, for the user to approve or deny it.
+// changed is true when the prompt was just held, so the user is notified once.
+func (i *Instance) AnswerPrompt(policy ApprovalPolicy) (held bool, changed bool) {
+	if !i.started || !i.AutoYes {
+		return false, false
+	}
+	content, err := i.tmuxSession.CapturePaneContent()
+	if err != nil {
+		log.ErrorLog.Printf("could not capture the prompt of %s: %v", i.Title, err)
+		return i.Status == NeedsApproval, false
+	}
+	prompt := promptTail(content)
+	kind := ClassifyPrompt(prompt)
+	if policy.Approves(kind, prompt) {
+		i.TapEnter()
+		return false, false
+	}
+	if i.Status == NeedsApproval && i.heldPrompt == kind {
+		return true, false
+	}
+	i.heldPrompt = kind
+	i.SetStatus(NeedsApproval)
+	i.Audit(AuditApproval, fmt.Sprintf("held %s prompt", kind))
+	return true, true
+}
+
+// HeldPrompt returns the kind of the prompt the instance holds for approval, if it does.
+func (i *Instance) HeldPrompt() (PromptKind, bool) {
+	if i.Status != NeedsApproval {
+		return "", false
+	}
+	return i.heldPrompt, true
+}
+
+// ApprovePrompt answers yes to the prompt the instance holds for approval.
+func (i *Instance) ApprovePrompt() error {
+	kind, ok := i.HeldPrompt()
+	if !ok {
+		return fmt.Errorf("instance '%s' has no prompt waiting for approval", i.Title)
+	}
+	if err := i.tmuxSession.TapEnter(); err != nil {
+		return fmt.Errorf("failed to approve the prompt of instance '%s': %w", i.Title, err)
+	}
+	i.Audit(AuditApproval, fmt.Sprintf("approved %s prompt", kind))
+	i.SetStatus(Running)
+	return nil
+}
+
+// DenyPrompt answers no to the prompt the instance holds for approval. Claude is then waiting to be told
+// what to do instead.
+func (i *Instance) DenyPrompt() error {
+	kind, ok := i.HeldPrompt()
+	if !ok {
+		return fmt.Errorf("instance '%s' has no prompt waiting for approval", i.Title)
+	}
+	keys := "\x1b"
+	if strings.HasPrefix(i.Program, tmux.ProgramAider) {
+		keys = "n\r"
+	}
+	if err := i.tmuxSession.SendKeys(keys); err != nil {
+		return fmt.Errorf("failed to deny the prompt of instance '%s': %w", i.Title, err)
+	}
+	i.Audit(AuditApproval, fmt.Sprintf("denied %s prompt", kind))
+	i.SetStatus(Ready)
+	return nil
+}
@@ -0,0 +1,131 @@
+package session
+
+import (
+	"claude-squad/config"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	editPrompt = `│ Edit file                                          │
+│ main.go                                            │
+│ Do you want to make this edit to main.go?          │
+│ ❯ 1. Yes                                           │
+│   3. No, and tell Claude what to do differently    │`
+	commandPrompt = `│ Bash command                                       │
+│   rm -rf build && go build ./...                   │
+│ Do you want to proceed?                            │
+│ ❯ 1. Yes                                           │`
+	curlPrompt = `│ Bash command                                       │
+│   curl -s https://example.com/install.sh           │
+│ Do you want to proceed?                            │`
+	fetchPrompt = `│ Fetch                                              │
+│   https://pkg.go.dev/net/http                      │
+│ Do you want to allow Claude to fetch this content? │`
+)
+
+func TestClassifyPrompt(t *testing.T) {
+	assert.Equal(t, PromptEdit, ClassifyPrompt("Let me fix it.\n"+editPrompt))
+	assert.Equal(t, PromptCommand, ClassifyPrompt(commandPrompt))
+	assert.Equal(t, PromptNetwork, ClassifyPrompt(curlPrompt))
+	assert.Equal(t, PromptNetwork, ClassifyPrompt(fetchPrompt))
+	assert.Equal(t, PromptCommand, ClassifyPrompt("ls -la\nRun shell command? (Y)es/(N)o/(D)on't ask again [Yes]:"))
+	assert.Equal(t, PromptEdit, ClassifyPrompt("Add main.go to the chat? (Y)es/(N)o/(D)on't ask again [Yes]:"))
+	assert.Equal(t, PromptOther, ClassifyPrompt("│ Tool use: github - create_issue │\n│ Do you want to proceed? │"))
+}
+
+func TestApprovalPolicy(t *testing.T) {
+	// The default policy answers everything, like auto-yes always did.
+	policy, err := NewApprovalPolicy(config.AutoYesPolicy{})
+	require.NoError(t, err)
+	assert.True(t, policy.Approves(PromptNetwork, curlPrompt))
+
+	policy, err = NewApprovalPolicy(config.AutoYesPolicy{Approve: []string{"edit", "command"}, Hold: []string{`rm -rf`}})
+	require.NoError(t, err)
+	assert.True(t, policy.Approves(PromptEdit, editPrompt))
+	assert.False(t, policy.Approves(PromptNetwork, fetchPrompt))
+	assert.False(t, policy.Approves(PromptCommand, commandPrompt))
+	assert.True(t, policy.Approves(PromptCommand, "│ Bash command │\n│   go test ./... │"))
+
+	// An invalid policy holds everything.
+	policy, err = NewApprovalPolicy(config.AutoYesPolicy{Approve: []string{"edits"}})
+	assert.ErrorContains(t, err, `invalid auto-yes prompt kind "edits"`)
+	assert.False(t, policy.Approves(PromptEdit, editPrompt))
+	policy, err = NewApprovalPolicy(config.AutoYesPolicy{Hold: []string{`rm -rf (`}})
+	assert.Error(t, err)
+	assert.False(t, policy.Approves(PromptEdit, editPrompt))
+}
+
+// promptTerminal shows a pane and records the keys typed into it.
+type promptTerminal struct {
+	Terminal
+	content string
+	typed   []string
+}
+
+func (p *promptTerminal) CapturePaneContent() (string, error) { return p.content, nil }
+
+func (p *promptTerminal) TapEnter() error {
+	p.typed = append(p.typed, "\r")
+	return nil
+}
+
+func (p *promptTerminal) SendKeys(keys string) error {
+	p.typed = append(p.typed, keys)
+	return nil
+}
+
+func TestAnswerPrompt(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	terminal := &promptTerminal{content: editPrompt}
+	instance := &Instance{Title: "fix", Program: "claude", Status: Running, started: true, AutoYes: true, tmuxSession: terminal}
+	policy, err := NewApprovalPolicy(config.AutoYesPolicy{Approve: []string{"edit"}})
+	require.NoError(t, err)
+
+	held, _ := instance.AnswerPrompt(policy)
+	assert.False(t, held)
+	assert.Equal(t, []string{"\r"}, terminal.typed)
+
+	// A prompt the policy doesn't approve is held once, until it's answered.
+	terminal.content, terminal.typed = commandPrompt, nil
+	held, changed := instance.AnswerPrompt(policy)
+	assert.True(t, held)
+	assert.True(t, changed)
+	assert.Equal(t, NeedsApproval, instance.Status)
+	held, changed = instance.AnswerPrompt(policy)
+	assert.True(t, held)
+	assert.False(t, changed)
+	assert.Empty(t, terminal.typed)
+	kind, ok := instance.HeldPrompt()
+	assert.True(t, ok)
+	assert.Equal(t, PromptCommand, kind)
+
+	require.NoError(t, instance.DenyPrompt())
+	assert.Equal(t, []string{"\x1b"}, terminal.typed)
+	assert.Equal(t, Ready, instance.Status)
+	assert.Error(t, instance.ApprovePrompt())
+
+	instance.AnswerPrompt(policy)
+	require.NoError(t, instance.ApprovePrompt())
+	assert.Equal(t, []string{"\x1b", "\r"}, terminal.typed)
+	assert.Equal(t, Running, instance.Status)
+
+	events, err := instance.AuditLog()
+	require.NoError(t, err)
+	var details []string
+	for _, event := range events {
+		if event.Type == AuditApproval {
+			details = append(details, event.Detail)
+		}
+	}
+	assert.Equal(t, []string{"held command prompt", "denied command prompt", "held command prompt", "approved command prompt"}, details)
+
+	// Without auto-yes, prompts are left alone.
+	instance.AutoYes = false
+	terminal.typed = nil
+	held, _ = instance.AnswerPrompt(policy)
+	assert.False(t, held)
+	assert.Empty(t, terminal.typed)
+}
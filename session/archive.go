@@ -0,0 +1,215 @@
+package session
+
+import (
+	"claude-squad/config"
+	"claude-squad/log"
+	"claude-squad/session/claude"
+	"claude-squad/session/objectstore"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// ArchivedInstance is an instance whose session and worktree were removed, keeping its branch, its
+// final diff and its metadata so it can be inspected and resurrected later.
+type ArchivedInstance struct {
+	InstanceData
+	// ArchivedAt is when the instance was archived.
+	ArchivedAt time.Time `json:"archived_at"`
+	// Conversation is the session ID of the last Claude conversation of the instance, if any.
+	Conversation string `json:"conversation,omitempty"`
+	// BundleURL is the object the final diff and the conversation were offloaded to, if any. The diff
+	// isn't kept locally then.
+	BundleURL string `json:"bundle_url,omitempty"`
+}
+
+// InstanceBundle is an archived instance along with its Claude conversation, to move it to another
+// machine or to offload it to object storage.
+type InstanceBundle struct {
+	Instance ArchivedInstance `json:"instance"`
+	// ConversationLog is the content of the Claude conversation file of the instance, if any.
+	ConversationLog string `json:"conversation_log,omitempty"`
+}
+
+// newInstanceBundle bundles an archived instance with its conversation.
+func newInstanceBundle(archived ArchivedInstance) (InstanceBundle, error) {
+	bundle := InstanceBundle{Instance: archived}
+	if archived.Conversation != "" {
+		content, err := os.ReadFile(conversationPath(archived.Worktree.WorktreePath, archived.Conversation))
+		if os.IsNotExist(err) {
+			content, err = backedUpConversation(archived.Title, archived.CreatedAt, archived.Conversation)
+		}
+		if err != nil {
+			return InstanceBundle{}, fmt.Errorf("failed to read conversation %s: %w", archived.Conversation, err)
+		}
+		bundle.ConversationLog = string(content)
+	}
+	return bundle, nil
+}
+
+// uploadObject, downloadObject and deleteObject access object storage. They're replaced in tests.
+var (
+	uploadObject = func(storage config.ArtifactStorage, key string, data []byte) (string, error) {
+		store, err := objectstore.New(storage.URL, storage.StorageClass)
+		if err != nil {
+			return "", err
+		}
+		return store.Upload(key, data)
+	}
+	downloadObject = objectstore.Download
+	deleteObject   = objectstore.Delete
+)
+
+// offloadArchive uploads the final diff and the conversation of an archived instance to object storage,
+// and drops the diff from it. The conversation stays where Claude keeps it.
+func offloadArchive(archived ArchivedInstance, storage config.ArtifactStorage) (ArchivedInstance, error) {
+	bundle, err := newInstanceBundle(archived)
+	if err != nil {
+		return archived, err
+	}
+	data, err := json.Marshal(bundle)
+	if err != nil {
+		return archived, fmt.Errorf("failed to marshal archive: %w", err)
+	}
+	key := fmt.Sprintf("archives/%s-%d.json", slug(archived.Title, 64, "instance"), archived.ArchivedAt.Unix())
+	url, err := uploadObject(storage, key, data)
+	if err != nil {
+		return archived, fmt.Errorf("failed to offload archive of %s: %w", archived.Title, err)
+	}
+	archived.BundleURL = url
+	archived.DiffStats.Content = ""
+	return archived, nil
+}
+
+// RestoreArchive returns the archived instance with the diff that was offloaded to object storage, if
+// any. The conversation is restored too if Claude no longer has it, so it can be resumed.
+func RestoreArchive(archived ArchivedInstance) (ArchivedInstance, error) {
+	if archived.BundleURL == "" {
+		return archived, nil
+	}
+	data, err := downloadObject(archived.BundleURL)
+	if err != nil {
+		return archived, fmt.Errorf("failed to download archive of %s: %w", archived.Title, err)
+	}
+	var bundle InstanceBundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return archived, fmt.Errorf("failed to unmarshal archive of %s: %w", archived.Title, err)
+	}
+	archived.DiffStats.Content = bundle.Instance.DiffStats.Content
+
+	worktreePath := archived.Worktree.WorktreePath
+	if archived.Conversation != "" && bundle.ConversationLog != "" {
+		if _, err := os.Stat(conversationPath(worktreePath, archived.Conversation)); os.IsNotExist(err) {
+			if err := writeConversation(worktreePath, archived.Conversation, bundle.ConversationLog, worktreePath); err != nil {
+				return archived, err
+			}
+		}
+	}
+	return archived, nil
+}
+
+// Archive stops the instance like Pause and removes its services, and returns what is kept of it. The
+// caller moves it from the instances to the archived instances of the storage.
+func (i *Instance) Archive() (ArchivedInstance, error) {
+	if !i.started {
+		return ArchivedInstance{}, fmt.Errorf("cannot archive instance that has not been started")
+	}
+
+	if !i.Paused() {
+		// Take the final diff while the worktree is still there. A paused instance kept its last one.
+		if err := i.UpdateDiffStats(); err != nil {
+			log.WarningLog.Printf("failed to update the diff of %s before archiving it: %v", i.Title, err)
+		}
+		if err := i.BackupConversations(); err != nil {
+			log.WarningLog.Printf("failed to back up the conversations of %s before archiving it: %v", i.Title, err)
+		}
+		if err := i.pause("archive"); err != nil {
+			return ArchivedInstance{}, err
+		}
+	}
+	if err := i.removeServices(); err != nil {
+		return ArchivedInstance{}, err
+	}
+
+	i.recordHistory(HistoryArchived)
+	i.removeAuditLog()
+	archived := ArchivedInstance{
+		InstanceData: i.ToInstanceData(),
+		ArchivedAt:   time.Now(),
+	}
+	// The conversations of a remote instance are on its host.
+	if i.Host == "" {
+		archived.Conversation = i.ClaudeSessionID
+		if archived.Conversation == "" {
+			archived.Conversation = latestConversation(i.gitWorktree.GetWorktreePath())
+		}
+	}
+	return archived, nil
+}
+
+// ResurrectInstance recreates the worktree of an archived instance on its branch and starts its program
+// again. Claude resumes the last conversation of the instance.
+func ResurrectInstance(archived ArchivedInstance) (*Instance, error) {
+	data := archived.InstanceData
+	data.Status = Paused
+	instance, err := FromInstanceData(data)
+	if err != nil {
+		return nil, err
+	}
+	if instance.ClaudeSessionID == "" {
+		instance.ClaudeSessionID = archived.Conversation
+	}
+	if err := instance.Resume(); err != nil {
+		return nil, fmt.Errorf("failed to resurrect instance %s: %w", archived.Title, err)
+	}
+	return instance, nil
+}
+
+// latestConversation returns the session ID of the last Claude conversation of the project at path, if
+// any.
+func latestConversation(path string) string {
+	conversations, err := claude.ListConversations(path)
+	if err != nil {
+		log.WarningLog.Printf("failed to list the conversations of %s: %v", path, err)
+		return ""
+	}
+
+	var latest string
+	var latestTime time.Time
+	for _, conversation := range conversations {
+		info, err := os.Stat(conversation.Path)
+		if err != nil {
+			continue
+		}
+		if info.ModTime().After(latestTime) {
+			latest, latestTime = conversation.SessionID, info.ModTime()
+		}
+	}
+	return latest
+}
+
+// Summary formats the metadata of the archived instance for display.
+func (a ArchivedInstance) Summary() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Title: %s\n", a.Title)
+	fmt.Fprintf(&b, "Branch: %s\n", a.Branch)
+	fmt.Fprintf(&b, "Program: %s\n", a.Program)
+	fmt.Fprintf(&b, "Created: %s\n", a.CreatedAt.Format("2006-01-02 15:04"))
+	fmt.Fprintf(&b, "Archived: %s\n", a.ArchivedAt.Format("2006-01-02 15:04"))
+	fmt.Fprintf(&b, "Changes: +%d -%d\n", a.DiffStats.Added, a.DiffStats.Removed)
+	if a.Conversation != "" {
+		fmt.Fprintf(&b, "Conversation: %s\n", a.Conversation)
+	}
+	if a.BundleURL != "" {
+		fmt.Fprintf(&b, "Offloaded to: %s\n", a.BundleURL)
+	}
+	if a.Parent != "" {
+		fmt.Fprintf(&b, "Stacked on: %s\n", a.Parent)
+	}
+	if len(a.TouchedFiles) > 0 {
+		fmt.Fprintf(&b, "Files: %s\n", strings.Join(a.TouchedFiles, ", "))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,126 @@
+package session
+
+import (
+	"claude-squad/config"
+	"claude-squad/session/claude"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchivedInstancesStorage(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	storage, err := NewStorage(config.DefaultState())
+	require.NoError(t, err)
+
+	archived, err := storage.ArchivedInstances()
+	require.NoError(t, err)
+	assert.Empty(t, archived)
+
+	first := ArchivedInstance{
+		InstanceData: InstanceData{Title: "fix login", Branch: "user/fix-login",
+			DiffStats: DiffStatsData{Added: 3, Content: "+new line"}},
+		ArchivedAt:   time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC),
+		Conversation: "abc-123",
+	}
+	second := first
+	second.ArchivedAt = first.ArchivedAt.Add(time.Hour)
+	require.NoError(t, storage.ArchiveInstance(first))
+	require.NoError(t, storage.ArchiveInstance(second))
+
+	archived, err = storage.ArchivedInstances()
+	require.NoError(t, err)
+	require.Len(t, archived, 2)
+	assert.Equal(t, first.Conversation, archived[0].Conversation)
+	assert.Equal(t, first.DiffStats, archived[0].DiffStats)
+	assert.True(t, archived[1].ArchivedAt.Equal(second.ArchivedAt))
+
+	require.NoError(t, storage.DeleteArchivedInstance(archived[0]))
+	archived, err = storage.ArchivedInstances()
+	require.NoError(t, err)
+	require.Len(t, archived, 1)
+	assert.True(t, archived[0].ArchivedAt.Equal(second.ArchivedAt))
+
+	assert.Error(t, storage.DeleteArchivedInstance(first))
+}
+
+func TestLatestConversation(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	worktree := "/tmp/worktrees/fix-login"
+	assert.Empty(t, latestConversation(worktree))
+
+	dir := claude.GetClaudeProjectPath(worktree)
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	now := time.Now()
+	for name, age := range map[string]time.Duration{"older": time.Hour, "newer": time.Minute} {
+		path := filepath.Join(dir, name+".jsonl")
+		require.NoError(t, os.WriteFile(path, []byte("{}\n"), 0644))
+		require.NoError(t, os.Chtimes(path, now.Add(-age), now.Add(-age)))
+	}
+
+	assert.Equal(t, "newer", latestConversation(worktree))
+}
+
+func TestOffloadedArchives(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg := config.DefaultConfig()
+	cfg.ArtifactStorage = config.ArtifactStorage{URL: "s3://bucket/squad", ExpireDays: 30}
+	require.NoError(t, config.SaveConfig(cfg))
+
+	objects := make(map[string][]byte)
+	originalUpload, originalDownload, originalDelete := uploadObject, downloadObject, deleteObject
+	defer func() { uploadObject, downloadObject, deleteObject = originalUpload, originalDownload, originalDelete }()
+	uploadObject = func(storage config.ArtifactStorage, key string, data []byte) (string, error) {
+		url := storage.URL + "/" + key
+		objects[url] = data
+		return url, nil
+	}
+	downloadObject = func(url string) ([]byte, error) { return objects[url], nil }
+	deleteObject = func(url string) error {
+		delete(objects, url)
+		return nil
+	}
+
+	worktree := "/tmp/worktrees/fix-login"
+	require.NoError(t, writeConversation(worktree, "abc", `{"cwd":"/tmp/worktrees/fix-login"}`, worktree))
+
+	storage, err := NewStorage(config.DefaultState())
+	require.NoError(t, err)
+	now := time.Now()
+	old := ArchivedInstance{
+		InstanceData: InstanceData{Title: "old", DiffStats: DiffStatsData{Content: "+old"}},
+		ArchivedAt:   now.AddDate(0, 0, -40),
+	}
+	recent := ArchivedInstance{
+		InstanceData: InstanceData{Title: "fix login", DiffStats: DiffStatsData{Added: 1, Content: "+new line"},
+			Worktree: GitWorktreeData{WorktreePath: worktree}},
+		ArchivedAt:   now,
+		Conversation: "abc",
+	}
+	require.NoError(t, storage.ArchiveInstance(old))
+	require.NoError(t, storage.ArchiveInstance(recent))
+
+	// The old archive expired when the recent one was archived.
+	archived, err := storage.ArchivedInstances()
+	require.NoError(t, err)
+	require.Len(t, archived, 1)
+	assert.Len(t, objects, 1)
+	assert.Equal(t, "s3://bucket/squad/archives/fix-login-"+strconv.FormatInt(now.Unix(), 10)+".json", archived[0].BundleURL)
+	assert.Empty(t, archived[0].DiffStats.Content)
+	assert.Equal(t, 1, archived[0].DiffStats.Added)
+
+	// The diff and the conversation come back from object storage.
+	require.NoError(t, os.Remove(conversationPath(worktree, "abc")))
+	restored, err := RestoreArchive(archived[0])
+	require.NoError(t, err)
+	assert.Equal(t, "+new line", restored.DiffStats.Content)
+	assert.Equal(t, "abc", latestConversation(worktree))
+
+	require.NoError(t, storage.DeleteArchivedInstance(restored))
+	assert.Empty(t, objects)
+}
@@ -0,0 +1,212 @@
+package session
+
+import (
+	"bufio"
+	"claude-squad/config"
+	"claude-squad/log"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// AuditEventType is the kind of an event in the audit log of an instance.
+type AuditEventType string
+
+const (
+	AuditCreated      AuditEventType = "created"
+	AuditPrompt       AuditEventType = "prompt"
+	AuditStatus       AuditEventType = "status"
+	AuditAutoConfirm  AuditEventType = "auto-confirm"
+	AuditCommit       AuditEventType = "commit"
+	AuditPush         AuditEventType = "push"
+	AuditPause        AuditEventType = "pause"
+	AuditResume       AuditEventType = "resume"
+	AuditFilesChanged AuditEventType = "files"
+	AuditKill         AuditEventType = "kill"
+	AuditServices     AuditEventType = "services"
+	AuditTeardown     AuditEventType = "teardown"
+	// AuditChecks records a run of the check command, e.g. "passed: go test ./...".
+	AuditChecks AuditEventType = "checks"
+	// AuditWorktree records how long setting up the worktree took, e.g. "1.25s".
+	AuditWorktree AuditEventType = "worktree"
+	// AuditWarmup records the outcome of the warmup commands of a new worktree, e.g. "ready in 42s".
+	AuditWarmup AuditEventType = "warmup"
+	// AuditHandoff records content handed off between instances, e.g. "review to fix-login" in the log of
+	// the instance handing off and "review from refactor" in the log of the other.
+	AuditHandoff AuditEventType = "handoff"
+	// AuditResources records the instance getting the external resources it waited for, e.g. "claimed
+	// staging-db".
+	AuditResources AuditEventType = "resources"
+	// AuditRebase records the branch of a paused instance being rebased on the upstream base branch, e.g.
+	// "on origin/main".
+	AuditRebase AuditEventType = "rebase"
+	// AuditQueue records the instance being queued for a running slot, e.g. "4 instances running".
+	AuditQueue AuditEventType = "queue"
+	// AuditBackup records the ref the unsaved work of the instance was backed up to before it was killed,
+	// e.g. "refs/claude-squad/backups/fix-login/20260102-150405".
+	AuditBackup AuditEventType = "backup"
+	// AuditApproval records a permission prompt auto-yes held for approval, and whether it was approved or
+	// denied, e.g. "held command prompt".
+	AuditApproval AuditEventType = "approval"
+)
+
+// maxAuditDetailLength caps the detail of an event, e.g. a long prompt.
+const maxAuditDetailLength = 500
+
+// maxAuditLogSize is the size past which the audit log of an instance is rotated: it's moved to a ".1"
+// file, replacing the one rotated before, and a new one is started.
+const maxAuditLogSize = 1 << 20
+
+// AuditEvent is an event in the audit log of an instance.
+type AuditEvent struct {
+	Time   time.Time      `json:"time"`
+	Type   AuditEventType `json:"type"`
+	Detail string         `json:"detail,omitempty"`
+	// Actor is the automation that caused the event, e.g. "api:orchestrator" or "slack:U0123". It's empty
+	// for the events caused from the app or the command line, or by the program itself.
+	Actor string `json:"actor,omitempty"`
+}
+
+func getAuditDir() (string, error) {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "audit"), nil
+}
+
+// auditLogPath returns the path of the audit log of the instance. Titles can be reused once an instance
+// is killed, so the creation time is part of the name.
+func (i *Instance) auditLogPath() (string, error) {
+	dir, err := getAuditDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fmt.Sprintf("%s-%d.jsonl", slug(i.Title, 64, "instance"), i.CreatedAt.Unix())), nil
+}
+
+// ActAs records the audit events of the instance as caused by actor, e.g. "api:orchestrator", until the
+// returned function is called.
+func (i *Instance) ActAs(actor string) func() {
+	previous := i.actor
+	i.actor = actor
+	return func() { i.actor = previous }
+}
+
+// Audit appends an event to the audit log of the instance. The log is append-only JSON lines, so the
+// daemon and the app can both write to it. Failures are logged, not returned, so they never get in the
+// way of the action being recorded.
+func (i *Instance) Audit(eventType AuditEventType, detail string) {
+	detail = RedactSecrets(detail, i.secrets)
+	if len(detail) > maxAuditDetailLength {
+		// Dropping the invalid bytes drops the character the cut split, if any.
+		detail = strings.ToValidUTF8(detail[:maxAuditDetailLength], "") + "..."
+	}
+	data, err := json.Marshal(AuditEvent{Time: time.Now(), Type: eventType, Detail: detail, Actor: i.actor})
+	if err != nil {
+		log.ErrorLog.Printf("could not marshal audit event: %v", err)
+		return
+	}
+
+	path, err := i.auditLogPath()
+	if err != nil {
+		log.ErrorLog.Printf("could not get audit log path: %v", err)
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		log.ErrorLog.Printf("could not create audit log directory: %v", err)
+		return
+	}
+	if info, err := os.Stat(path); err == nil && info.Size() >= maxAuditLogSize {
+		if err := os.Rename(path, path+".1"); err != nil {
+			log.ErrorLog.Printf("could not rotate audit log: %v", err)
+		}
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		log.ErrorLog.Printf("could not open audit log: %v", err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		log.ErrorLog.Printf("could not write audit log: %v", err)
+	}
+}
+
+// AuditLog returns the events recorded for the instance, oldest first, the rotated ones included.
+func (i *Instance) AuditLog() ([]AuditEvent, error) {
+	path, err := i.auditLogPath()
+	if err != nil {
+		return nil, err
+	}
+	rotated, err := readAuditLog(path + ".1")
+	if err != nil {
+		return nil, err
+	}
+	events, err := readAuditLog(path)
+	if err != nil {
+		return nil, err
+	}
+	return append(rotated, events...), nil
+}
+
+// removeAuditLog deletes the audit log of the instance, once it's killed or archived.
+func (i *Instance) removeAuditLog() {
+	path, err := i.auditLogPath()
+	if err != nil {
+		log.ErrorLog.Printf("could not get audit log path: %v", err)
+		return
+	}
+	for _, p := range []string{path, path + ".1"} {
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			log.ErrorLog.Printf("could not remove audit log: %v", err)
+		}
+	}
+}
+
+// readAuditLog returns the events of the audit log at path, none if it doesn't exist.
+func readAuditLog(path string) ([]AuditEvent, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	var events []AuditEvent
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var event AuditEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			// Skip lines cut by a crash.
+			continue
+		}
+		events = append(events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return events, nil
+}
+
+// FormatTimeline formats the events as one line each: time, type and detail.
+func FormatTimeline(events []AuditEvent) string {
+	if len(events) == 0 {
+		return "No events recorded."
+	}
+	var b strings.Builder
+	for _, event := range events {
+		detail := strings.Join(strings.Fields(event.Detail), " ")
+		if event.Actor != "" {
+			detail = strings.TrimSpace(detail + " (by " + event.Actor + ")")
+		}
+		fmt.Fprintf(&b, "%s  %-12s %s\n", event.Time.Local().Format("Jan 02 15:04:05"), event.Type, detail)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
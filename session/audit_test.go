@@ -0,0 +1,117 @@
+package session
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditLog(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	instance := &Instance{
+		Title:     "fix login",
+		CreatedAt: time.Now(),
+		secrets:   map[string]string{"API_KEY": "s3cr3t-value"},
+	}
+
+	events, err := instance.AuditLog()
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	instance.Audit(AuditCreated, "branch user/fix-login")
+	instance.Audit(AuditPrompt, "use the key s3cr3t-value")
+	instance.Audit(AuditPrompt, strings.Repeat("x", 2*maxAuditDetailLength))
+	instance.recordTouchedFiles([]string{"auth/login.go"})
+	// Files already recorded aren't recorded again.
+	instance.recordTouchedFiles([]string{"auth/login.go"})
+
+	events, err = instance.AuditLog()
+	require.NoError(t, err)
+	require.Len(t, events, 4)
+	assert.Equal(t, AuditCreated, events[0].Type)
+	assert.NotContains(t, events[1].Detail, "s3cr3t-value")
+	assert.Len(t, events[2].Detail, maxAuditDetailLength+len("..."))
+	assert.Equal(t, AuditEvent{Time: events[3].Time, Type: AuditFilesChanged, Detail: "auth/login.go"}, events[3])
+
+	// An instance reusing the title of a killed one gets its own log.
+	reused := &Instance{Title: "fix login", CreatedAt: instance.CreatedAt.Add(time.Hour)}
+	events, err = reused.AuditLog()
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestAuditLogLimits(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	instance := &Instance{Title: "fix login", CreatedAt: time.Now()}
+
+	// A detail cut in the middle of a character loses the whole character.
+	instance.Audit(AuditPrompt, strings.Repeat("x", maxAuditDetailLength-1)+"é")
+	events, err := instance.AuditLog()
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.True(t, utf8.ValidString(events[0].Detail))
+	assert.Equal(t, strings.Repeat("x", maxAuditDetailLength-1)+"...", events[0].Detail)
+
+	// A log past the size limit is rotated, and both are read.
+	path, err := instance.auditLogPath()
+	require.NoError(t, err)
+	line := `{"time":"2025-03-14T09:26:53Z","type":"prompt","detail":"old"}` + "\n"
+	require.NoError(t, os.WriteFile(path, []byte(strings.Repeat(line, maxAuditLogSize/len(line)+1)), 0600))
+	instance.Audit(AuditPrompt, "new")
+	assert.FileExists(t, path+".1")
+	events, err = instance.AuditLog()
+	require.NoError(t, err)
+	assert.Equal(t, "old", events[0].Detail)
+	assert.Equal(t, "new", events[len(events)-1].Detail)
+
+	// Status changes are recorded, but not the program going back and forth between running and ready.
+	instance.started = true
+	instance.Status = Running
+	instance.SetStatus(Ready)
+	instance.SetStatus(Running)
+	instance.SetStatus(Loading)
+	events, err = instance.AuditLog()
+	require.NoError(t, err)
+	assert.Equal(t, AuditEvent{Time: events[len(events)-1].Time, Type: AuditStatus, Detail: Loading.String()}, events[len(events)-1])
+	assert.Equal(t, "new", events[len(events)-2].Detail)
+
+	instance.removeAuditLog()
+	assert.NoFileExists(t, path)
+	assert.NoFileExists(t, path+".1")
+}
+
+func TestAuditActor(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	instance := &Instance{Title: "fix login", CreatedAt: time.Now()}
+
+	done := instance.ActAs("api:orchestrator")
+	instance.Audit(AuditPrompt, "fix the login")
+	done()
+	instance.Audit(AuditPrompt, "thanks")
+
+	events, err := instance.AuditLog()
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, "api:orchestrator", events[0].Actor)
+	assert.Empty(t, events[1].Actor)
+	assert.Contains(t, FormatTimeline(events), "fix the login (by api:orchestrator)")
+}
+
+func TestFormatTimeline(t *testing.T) {
+	assert.Equal(t, "No events recorded.", FormatTimeline(nil))
+
+	at := time.Date(2025, 3, 14, 9, 26, 53, 0, time.Local)
+	timeline := FormatTimeline([]AuditEvent{
+		{Time: at, Type: AuditCreated, Detail: "branch user/fix-login"},
+		{Time: at.Add(time.Minute), Type: AuditPrompt, Detail: "fix the\nlogin"},
+		{Time: at.Add(2 * time.Minute), Type: AuditKill, Actor: "api:ops"},
+	})
+	assert.Equal(t, "Mar 14 09:26:53  created      branch user/fix-login\n"+
+		"Mar 14 09:27:53  prompt       fix the login\n"+
+		"Mar 14 09:28:53  kill         (by api:ops)", timeline)
+}
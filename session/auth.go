@@ -0,0 +1,48 @@
+package session
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// loginPatterns are shown by claude when its credentials expired or were revoked and the user has to log
+// in again.
+var loginPatterns = []string{
+	"Please run /login",
+	"OAuth token has expired",
+	"OAuth token revoked",
+	"Invalid API key",
+	"Select login method",
+}
+
+// NeedsLogin returns true if content shows a login screen or an authentication error of program.
+func NeedsLogin(program, content string) bool {
+	if filepath.Base(programBinary(program)) != "claude" {
+		return false
+	}
+	for _, pattern := range loginPatterns {
+		if strings.Contains(content, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckAuth checks whether the pane content of the instance shows it's waiting for the user to log in
+// again, and sets its status to NeedsAuth while it is. changed is true when the status changed, so the
+// user is notified once.
+func (i *Instance) CheckAuth(content string) (needsAuth bool, changed bool) {
+	if !i.started || i.Paused() {
+		return false, false
+	}
+	needsAuth = NeedsLogin(i.Program, content)
+	switch {
+	case needsAuth && i.Status != NeedsAuth:
+		i.SetStatus(NeedsAuth)
+		return true, true
+	case !needsAuth && i.Status == NeedsAuth:
+		i.SetStatus(Ready)
+		return false, true
+	}
+	return needsAuth, false
+}
@@ -0,0 +1,17 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNeedsLogin(t *testing.T) {
+	loginScreen := "API Error: 401 {\"error\":{\"message\":\"OAuth token has expired.\"}} · Please run /login"
+
+	assert.True(t, NeedsLogin("claude", loginScreen))
+	assert.True(t, NeedsLogin("/usr/local/bin/claude --verbose", "Invalid API key · Fix external API key"))
+	assert.False(t, NeedsLogin("claude", "> Try \"fix lint errors\""))
+	// Only claude is checked, other programs may print the same text as part of their work.
+	assert.False(t, NeedsLogin("aider", loginScreen))
+}
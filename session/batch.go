@@ -0,0 +1,134 @@
+package session
+
+import (
+	"claude-squad/config"
+	"claude-squad/log"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// batchSettleTime is how long the agent of a batch run has to be idle after working before it's
+// considered done, so a pause in its output doesn't end the run.
+const batchSettleTime = stageSettleTime
+
+// ErrNeedsAttention is returned by WaitDone when the agent waits on something auto-yes can't answer.
+var ErrNeedsAttention = errors.New("the agent needs attention")
+
+// BatchResult is the outcome of a headless run, printed as JSON by `cs run`.
+type BatchResult struct {
+	Title  string `json:"title"`
+	Branch string `json:"branch"`
+	// Status is "done", or why the run stopped before the agent was done: "timeout", "needs_attention"
+	// or "interrupted".
+	Status   string   `json:"status"`
+	Files    []string `json:"files"`
+	Added    int      `json:"added"`
+	Removed  int      `json:"removed"`
+	Duration string   `json:"duration"`
+}
+
+// UniqueTitle returns title, with a "-N" suffix if an instance already has it.
+func UniqueTitle(title string, instances []*Instance) string {
+	taken := make(map[string]bool, len(instances))
+	for _, instance := range instances {
+		taken[instance.Title] = true
+	}
+	unique := title
+	for n := 2; taken[unique]; n++ {
+		suffix := fmt.Sprintf("-%d", n)
+		base := title
+		if len(base)+len(suffix) > maxTitleLength {
+			base = base[:maxTitleLength-len(suffix)]
+		}
+		unique = base + suffix
+	}
+	return unique
+}
+
+// WaitDone polls the instance every interval, answering its prompts like auto-yes does, until its agent
+// worked and has been idle for a while, per the ready detectors. It returns the error of ctx if it's done
+// first, and ErrNeedsAttention if the agent shows one of stuckPatterns, a login screen or a prompt the
+// policy holds.
+func (i *Instance) WaitDone(ctx context.Context, interval time.Duration, stuckPatterns []*regexp.Regexp,
+	detectors []config.ReadyDetector, policy ApprovalPolicy) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	worked := false
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		if !i.TmuxAlive() {
+			return fmt.Errorf("the session of instance '%s' is gone", i.Title)
+		}
+		content, err := i.CapturePane()
+		if err != nil {
+			log.ErrorLog.Printf("error capturing pane content in status monitor: %v", err)
+			continue
+		}
+		if needsAuth, _ := i.CheckAuth(content); needsAuth {
+			return fmt.Errorf("%w: it's waiting for a login", ErrNeedsAttention)
+		}
+		if stuck, _ := i.CheckStuck(content, stuckPatterns); stuck {
+			return fmt.Errorf("%w: it's waiting on a prompt auto-yes doesn't answer", ErrNeedsAttention)
+		}
+		updated, hasPrompt := i.HasUpdated(content, detectors)
+		switch {
+		case updated:
+			worked = true
+			i.SetStatus(Running)
+		case hasPrompt:
+			if held, _ := i.AnswerPrompt(policy); held {
+				return fmt.Errorf("%w: it's waiting for approval of a %s prompt", ErrNeedsAttention, i.heldPrompt)
+			}
+		default:
+			i.SetStatus(Ready)
+		}
+		if i.batchDone(worked, time.Now()) {
+			return nil
+		}
+	}
+}
+
+// batchDone returns true if the agent worked and has been idle since long enough to be done.
+func (i *Instance) batchDone(worked bool, now time.Time) bool {
+	return worked && i.Status == Ready && now.Sub(i.readyAt) >= batchSettleTime
+}
+
+// CommitWork commits the changes of the worktree of the instance with the message, if there are any.
+func (i *Instance) CommitWork(commitMsg string) error {
+	dirty, err := i.gitWorktree.IsDirty()
+	if err != nil || !dirty {
+		return err
+	}
+	if err := i.gitWorktree.CommitChanges(commitMsg); err != nil {
+		return err
+	}
+	i.Audit(AuditCommit, commitMsg)
+	return nil
+}
+
+// BatchResult returns the outcome of a headless run of the instance that stopped with status, from its
+// last diff stats.
+func (i *Instance) BatchResult(status string, duration time.Duration) BatchResult {
+	result := BatchResult{
+		Title:    i.Title,
+		Branch:   i.Branch,
+		Status:   status,
+		Files:    []string{},
+		Duration: duration.Round(time.Second).String(),
+	}
+	if i.diffStats != nil {
+		if files := i.diffStats.ChangedFiles(); files != nil {
+			result.Files = files
+		}
+		result.Added = i.diffStats.Added
+		result.Removed = i.diffStats.Removed
+	}
+	return result
+}
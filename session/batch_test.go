@@ -0,0 +1,47 @@
+package session
+
+import (
+	"claude-squad/session/git"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUniqueTitle(t *testing.T) {
+	instances := []*Instance{{Title: "fix-tests"}, {Title: "fix-tests-2"}}
+	assert.Equal(t, "docs", UniqueTitle("docs", instances))
+	assert.Equal(t, "fix-tests-3", UniqueTitle("fix-tests", instances))
+
+	long := strings.Repeat("a", maxTitleLength)
+	unique := UniqueTitle(long, []*Instance{{Title: long}})
+	assert.Equal(t, strings.Repeat("a", maxTitleLength-2)+"-2", unique)
+}
+
+func TestBatchDone(t *testing.T) {
+	now := time.Now()
+	instance := &Instance{Title: "run", Status: Ready, readyAt: now.Add(-batchSettleTime)}
+	assert.True(t, instance.batchDone(true, now))
+	// The agent hasn't started working on the prompt yet.
+	assert.False(t, instance.batchDone(false, now))
+	assert.False(t, instance.batchDone(true, instance.readyAt.Add(time.Second)))
+	instance.Status = Running
+	assert.False(t, instance.batchDone(true, now))
+}
+
+func TestBatchResult(t *testing.T) {
+	instance := &Instance{Title: "run", Branch: "me/run"}
+	result := instance.BatchResult("timeout", 90*time.Second)
+	assert.Equal(t, BatchResult{Title: "run", Branch: "me/run", Status: "timeout", Files: []string{}, Duration: "1m30s"}, result)
+
+	instance.diffStats = &git.DiffStats{
+		Added:   3,
+		Removed: 1,
+		Content: "diff --git a/app.go b/app.go\n+x\ndiff --git a/theme.go b/theme.go\n-y\n",
+	}
+	result = instance.BatchResult("done", time.Minute)
+	assert.Equal(t, []string{"app.go", "theme.go"}, result.Files)
+	assert.Equal(t, 3, result.Added)
+	assert.Equal(t, 1, result.Removed)
+}
@@ -0,0 +1,78 @@
+package session
+
+import (
+	"claude-squad/config"
+	"fmt"
+	"time"
+)
+
+// BudgetEvent is what CheckTimeBudget did to an instance.
+type BudgetEvent int
+
+const (
+	// BudgetNone is nothing: the instance has no budget, or time left before it wraps up.
+	BudgetNone BudgetEvent = iota
+	// BudgetWrapUp is the wrap-up prompt being sent, as the budget is nearly exhausted.
+	BudgetWrapUp
+	// BudgetExhausted is the budget running out, and the instance being paused, its changes committed.
+	BudgetExhausted
+)
+
+// SetTimeBudget sets how long the instance may run, zero for no limit. The budget of a running instance
+// starts over from now.
+func (i *Instance) SetTimeBudget(budget time.Duration, now time.Time) {
+	i.TimeBudget = budget
+	if i.started && !i.Paused() {
+		i.startTimeBudget(now)
+	}
+}
+
+// startTimeBudget starts the time budget of the instance, as it starts or resumes.
+func (i *Instance) startTimeBudget(now time.Time) {
+	i.Deadline = time.Time{}
+	i.WrappedUp = false
+	if i.TimeBudget > 0 {
+		i.Deadline = now.Add(i.TimeBudget)
+	}
+}
+
+// TimeLeft returns how long the running instance has before its time budget runs out, and false if it has
+// no budget.
+func (i *Instance) TimeLeft(now time.Time) (time.Duration, bool) {
+	if i.Deadline.IsZero() || i.Paused() {
+		return 0, false
+	}
+	return max(i.Deadline.Sub(now), 0), true
+}
+
+// CheckTimeBudget sends the wrap-up prompt to the instance once its time budget is nearly exhausted, and
+// pauses it, which commits its changes, once it runs out.
+func (i *Instance) CheckTimeBudget(budget config.TimeBudget, now time.Time) (BudgetEvent, error) {
+	if !i.started || i.Deadline.IsZero() || i.Paused() || i.Status == Gone {
+		return BudgetNone, nil
+	}
+	if !now.Before(i.Deadline) {
+		if err := i.Pause(); err != nil {
+			return BudgetExhausted, fmt.Errorf("the time budget of instance '%s' ran out but it could not be paused: %w", i.Title, err)
+		}
+		return BudgetExhausted, nil
+	}
+	if i.WrappedUp || i.Deadline.Sub(now) > budget.GetWrapUp() {
+		return BudgetNone, nil
+	}
+	// The prompt is sent once, even if it fails, so a broken session isn't sent it on every tick.
+	i.WrappedUp = true
+	if err := i.SendPrompt(budget.GetWrapUpPrompt()); err != nil {
+		return BudgetWrapUp, fmt.Errorf("failed to send the wrap-up prompt to instance '%s': %w", i.Title, err)
+	}
+	return BudgetWrapUp, nil
+}
+
+// FormatBudget formats a time budget, or the time left of one, to the minute, e.g. "1h05m" or "12m".
+func FormatBudget(budget time.Duration) string {
+	budget = budget.Round(time.Minute)
+	if budget >= time.Hour {
+		return fmt.Sprintf("%dh%02dm", int(budget.Hours()), int(budget.Minutes())%60)
+	}
+	return fmt.Sprintf("%dm", int(budget.Minutes()))
+}
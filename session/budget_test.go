@@ -0,0 +1,69 @@
+package session
+
+import (
+	"claude-squad/config"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckTimeBudget(t *testing.T) {
+	// The prompts are queued while the instance waits for its resources, which shows what was sent.
+	instance := &Instance{Title: "overnight", Status: Running, started: true,
+		tmuxSession:       newTerminal("overnight", "claude", t.TempDir()),
+		ExternalResources: []string{"staging-db"}, WaitingForResources: true}
+	budget := config.TimeBudget{WrapUpMinutes: 15, WrapUpPrompt: "wrap up"}
+	now := time.Now()
+
+	// Without a budget, nothing happens.
+	event, err := instance.CheckTimeBudget(budget, now.Add(24*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, BudgetNone, event)
+	_, ok := instance.TimeLeft(now)
+	assert.False(t, ok)
+
+	instance.SetTimeBudget(time.Hour, now)
+	left, ok := instance.TimeLeft(now.Add(10 * time.Minute))
+	assert.True(t, ok)
+	assert.Equal(t, 50*time.Minute, left)
+
+	event, err = instance.CheckTimeBudget(budget, now.Add(40*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, BudgetNone, event)
+	assert.Empty(t, instance.QueuedPrompts)
+
+	// The wrap-up prompt is sent once, when the budget is nearly exhausted.
+	event, err = instance.CheckTimeBudget(budget, now.Add(46*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, BudgetWrapUp, event)
+	assert.Equal(t, []string{"wrap up"}, instance.QueuedPrompts)
+	event, err = instance.CheckTimeBudget(budget, now.Add(50*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, BudgetNone, event)
+	assert.Len(t, instance.QueuedPrompts, 1)
+
+	// The budget and the deadline are kept in the state.
+	data := instance.ToInstanceData()
+	assert.Equal(t, time.Hour, data.TimeBudget)
+	assert.True(t, data.WrappedUp)
+	assert.Equal(t, now.Add(time.Hour), data.Deadline)
+
+	// Setting the budget again starts it over.
+	instance.SetTimeBudget(2*time.Hour, now.Add(50*time.Minute))
+	assert.False(t, instance.WrappedUp)
+	left, _ = instance.TimeLeft(now.Add(50 * time.Minute))
+	assert.Equal(t, 2*time.Hour, left)
+
+	// A paused instance has no time left to count.
+	instance.Status = Paused
+	_, ok = instance.TimeLeft(now)
+	assert.False(t, ok)
+}
+
+func TestFormatBudget(t *testing.T) {
+	assert.Equal(t, "12m", FormatBudget(12*time.Minute+10*time.Second))
+	assert.Equal(t, "1h05m", FormatBudget(65*time.Minute))
+	assert.Equal(t, "8h00m", FormatBudget(8*time.Hour))
+}
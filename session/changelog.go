@@ -0,0 +1,170 @@
+package session
+
+import (
+	"claude-squad/config"
+	"claude-squad/textutil"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+)
+
+// maxChangelogSummary caps the summary of the agent an entry can use, since it's a line of release notes.
+const maxChangelogSummary = 300
+
+// ChangelogData is what the template of a changelog entry can use.
+type ChangelogData struct {
+	Title  string
+	Branch string
+	// Prompt is the first prompt sent to the instance, if it's known.
+	Prompt string
+	// Summary is the first paragraph of the last message of the agent, if it's known.
+	Summary string
+}
+
+// changeKind is a kind of change, with its name in each changelog format.
+type changeKind struct {
+	// words start the titles or prompts of the changes of the kind.
+	words          []string
+	towncrier      string
+	keepAChangelog string
+}
+
+// changeKinds are the kinds of changes guessed from the first word of a title or prompt, the last one
+// being the fallback.
+var changeKinds = []changeKind{
+	{words: []string{"fix", "fixes", "fixed", "bug", "bugfix", "hotfix", "repair"}, towncrier: "bugfix", keepAChangelog: "Fixed"},
+	{words: []string{"add", "adds", "added", "feat", "feature", "implement", "introduce", "new", "support"}, towncrier: "feature", keepAChangelog: "Added"},
+	{words: []string{"remove", "removes", "removed", "delete", "drop"}, towncrier: "removal", keepAChangelog: "Removed"},
+	{words: []string{"deprecate", "deprecates"}, towncrier: "removal", keepAChangelog: "Deprecated"},
+	{words: []string{"doc", "docs", "document", "documentation", "readme"}, towncrier: "doc", keepAChangelog: "Changed"},
+	{towncrier: "misc", keepAChangelog: "Changed"},
+}
+
+// guessChangeKind returns the kind of the first of the texts that starts with a known word.
+func guessChangeKind(texts ...string) changeKind {
+	for _, text := range texts {
+		first, _, _ := strings.Cut(strings.TrimSpace(nonWordRegex.ReplaceAllString(strings.ToLower(text), " ")), " ")
+		for _, kind := range changeKinds {
+			for _, word := range kind.words {
+				if first == word {
+					return kind
+				}
+			}
+		}
+	}
+	return changeKinds[len(changeKinds)-1]
+}
+
+// ChangelogFragment returns the path of the changelog fragment of the instance, relative to the root of
+// its worktree, and its content, in the format of the settings.
+func (i *Instance) ChangelogFragment(settings config.Changelog) (string, string, error) {
+	data := ChangelogData{Title: i.Title, Branch: i.Branch, Prompt: i.firstPrompt(), Summary: firstParagraph(i.LastAgentMessage())}
+	tmpl := settings.Template
+	if tmpl == "" {
+		tmpl = "{{.Title}}"
+	}
+	t, err := template.New("changelog").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid changelog template: %w", err)
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, data); err != nil {
+		return "", "", fmt.Errorf("invalid changelog template: %w", err)
+	}
+	entry := strings.Join(strings.Fields(b.String()), " ")
+	if entry == "" {
+		return "", "", fmt.Errorf("the changelog template rendered an empty entry")
+	}
+
+	kind := guessChangeKind(i.Title, data.Prompt)
+	name := TitleFromText(i.Title)
+	switch settings.Format {
+	case config.ChangelogTowncrier:
+		kindName := settings.Type
+		if kindName == "" {
+			kindName = kind.towncrier
+		}
+		dir := settings.Dir
+		if dir == "" {
+			dir = "newsfragments"
+		}
+		// Fragments named with a "+" aren't tied to an issue.
+		return filepath.Join(dir, fmt.Sprintf("+%s.%s.md", name, kindName)), entry + "\n", nil
+	case config.ChangelogKeepAChangelog:
+		section := settings.Type
+		if section == "" {
+			section = kind.keepAChangelog
+		}
+		dir := settings.Dir
+		if dir == "" {
+			dir = "changelog.d"
+		}
+		return filepath.Join(dir, name+".md"), fmt.Sprintf("### %s\n\n- %s\n", section, entry), nil
+	}
+	return "", "", fmt.Errorf("unknown changelog format %q", settings.Format)
+}
+
+// WriteChangelogFragment writes the changelog fragment of the instance into its worktree, if its
+// repository has changelog settings, so it's committed along with its changes. A fragment written before,
+// possibly edited since, is kept. It returns the path of the fragment written, if any.
+func (i *Instance) WriteChangelogFragment() (string, error) {
+	if i.gitWorktree == nil || i.Host != "" {
+		return "", nil
+	}
+	repoConfig, err := config.LoadRepoConfig(i.gitWorktree.GetRepoPath())
+	if err != nil {
+		return "", err
+	}
+	if repoConfig.Changelog.Format == "" {
+		return "", nil
+	}
+	name, content, err := i.ChangelogFragment(repoConfig.Changelog)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(i.gitWorktree.GetWorktreePath(), name)
+	// The kind of a towncrier fragment is in its name, and may be guessed differently from one push to
+	// the next.
+	pattern := path
+	if repoConfig.Changelog.Format == config.ChangelogTowncrier {
+		pattern = filepath.Join(filepath.Dir(path), "+"+TitleFromText(i.Title)+".*.md")
+	}
+	if existing, err := filepath.Glob(pattern); err == nil && len(existing) > 0 {
+		return "", nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create changelog directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return "", fmt.Errorf("failed to write changelog fragment: %w", err)
+	}
+	return name, nil
+}
+
+// firstPrompt returns the first prompt sent to the instance, from its audit log.
+func (i *Instance) firstPrompt() string {
+	events, err := i.AuditLog()
+	if err != nil {
+		return ""
+	}
+	for _, event := range events {
+		if event.Type == AuditPrompt {
+			return event.Detail
+		}
+	}
+	return ""
+}
+
+// firstParagraph returns the first paragraph of the text, cut to maxChangelogSummary characters.
+func firstParagraph(text string) string {
+	paragraph, _, _ := strings.Cut(strings.TrimSpace(text), "\n\n")
+	paragraph = strings.Join(strings.Fields(paragraph), " ")
+	if utf8.RuneCountInString(paragraph) > maxChangelogSummary {
+		paragraph = strings.TrimSpace(textutil.Prefix(paragraph, maxChangelogSummary)) + "..."
+	}
+	return paragraph
+}
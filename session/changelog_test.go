@@ -0,0 +1,84 @@
+package session
+
+import (
+	"claude-squad/config"
+	"claude-squad/session/git"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGuessChangeKind(t *testing.T) {
+	assert.Equal(t, "bugfix", guessChangeKind("Fix login redirect").towncrier)
+	assert.Equal(t, "Added", guessChangeKind("dark-mode", "Add a dark mode toggle").keepAChangelog)
+	assert.Equal(t, "Removed", guessChangeKind("drop python 3.8").keepAChangelog)
+	assert.Equal(t, "misc", guessChangeKind("refactor auth", "").towncrier)
+}
+
+func TestFirstParagraph(t *testing.T) {
+	assert.Equal(t, "Fixed the redirect loop.", firstParagraph("Fixed the\nredirect loop.\n\nDetails follow."))
+	long := firstParagraph(strings.Repeat("é", 400))
+	assert.True(t, utf8.ValidString(long))
+	assert.Equal(t, strings.Repeat("é", maxChangelogSummary)+"...", long)
+}
+
+func TestChangelogFragment(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	instance := &Instance{Title: "fix login", Branch: "me/fix-login", Program: "aider"}
+	instance.Audit(AuditPrompt, "Fix the redirect loop after login")
+
+	name, content, err := instance.ChangelogFragment(config.Changelog{Format: config.ChangelogTowncrier})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("newsfragments", "+fix-login.bugfix.md"), name)
+	assert.Equal(t, "fix login\n", content)
+
+	name, content, err = instance.ChangelogFragment(config.Changelog{
+		Format:   config.ChangelogKeepAChangelog,
+		Dir:      "docs/changes",
+		Template: "{{.Prompt}} ({{.Branch}})",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("docs", "changes", "fix-login.md"), name)
+	assert.Equal(t, "### Fixed\n\n- Fix the redirect loop after login (me/fix-login)\n", content)
+
+	_, content, err = instance.ChangelogFragment(config.Changelog{Format: config.ChangelogKeepAChangelog, Type: "Security"})
+	require.NoError(t, err)
+	assert.Equal(t, "### Security\n\n- fix login\n", content)
+
+	_, _, err = instance.ChangelogFragment(config.Changelog{Format: "news"})
+	assert.ErrorContains(t, err, "unknown changelog format")
+	_, _, err = instance.ChangelogFragment(config.Changelog{Format: config.ChangelogTowncrier, Template: "{{.Summary}}"})
+	assert.ErrorContains(t, err, "empty entry")
+}
+
+func TestWriteChangelogFragment(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	repoPath := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, config.RepoConfigFileName),
+		[]byte(`{"changelog": {"format": "towncrier"}}`), 0644))
+	worktreePath := t.TempDir()
+	instance := &Instance{
+		Title:       "add dark mode",
+		gitWorktree: git.NewGitWorktreeFromStorage(repoPath, worktreePath, "add dark mode", "dark-mode", "", false),
+	}
+
+	name, err := instance.WriteChangelogFragment()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("newsfragments", "+add-dark-mode.feature.md"), name)
+	content, err := os.ReadFile(filepath.Join(worktreePath, name))
+	require.NoError(t, err)
+	assert.Equal(t, "add dark mode\n", string(content))
+
+	// A fragment edited since, even with another type, is kept.
+	require.NoError(t, os.Rename(filepath.Join(worktreePath, name),
+		filepath.Join(worktreePath, "newsfragments", "+add-dark-mode.misc.md")))
+	name, err = instance.WriteChangelogFragment()
+	require.NoError(t, err)
+	assert.Empty(t, name)
+	assert.NoFileExists(t, filepath.Join(worktreePath, "newsfragments", "+add-dark-mode.feature.md"))
+}
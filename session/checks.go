@@ -0,0 +1,183 @@
+package session
+
+import (
+	"claude-squad/config"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"time"
+)
+
+// checksTimeout is how long the check command may run.
+const checksTimeout = 15 * time.Minute
+
+// maxCheckOutput is how much of the end of the output of the check command is kept.
+const maxCheckOutput = 4000
+
+// CheckResult is the outcome of running the check command of the repository on the changes of an
+// instance.
+type CheckResult struct {
+	// Command is the check command that ran.
+	Command string `json:"command"`
+	// Passed is true if the command exited successfully.
+	Passed bool `json:"passed"`
+	// Output is the end of the combined output of the command.
+	Output string `json:"output,omitempty"`
+	// RanAt is when the command finished.
+	RanAt time.Time `json:"ran_at"`
+	// DiffHash identifies the diff the command ran on, so the result no longer counts once it changes.
+	DiffHash string `json:"diff_hash"`
+}
+
+// Checks states, as shown in the instance list.
+const (
+	ChecksNone    = ""
+	ChecksRunning = "running"
+	ChecksPassed  = "passed"
+	ChecksFailed  = "failed"
+	// ChecksStale means the last run passed, but the diff changed since.
+	ChecksStale = "stale"
+)
+
+// RunChecks runs the check command of the repository in the worktree of the instance and returns the
+// result, which is recorded with RecordChecks. The command gets the same environment as the teardown
+// commands. It returns an error if the command couldn't be run at all, not if it failed.
+func (i *Instance) RunChecks() (*CheckResult, error) {
+	if !i.started || i.Paused() {
+		return nil, fmt.Errorf("instance %s isn't running", i.Title)
+	}
+	if i.Host != "" {
+		return nil, fmt.Errorf("checks of remote instances aren't supported")
+	}
+	repoConfig, err := config.LoadRepoConfig(i.gitWorktree.GetRepoPath())
+	if err != nil {
+		return nil, err
+	}
+	if repoConfig.CheckCommand == "" && repoConfig.Untrusted {
+		return nil, fmt.Errorf("the commands in the %s of the repository aren't trusted: run `cs trust` in it", config.RepoConfigFileName)
+	}
+	if repoConfig.CheckCommand == "" {
+		return nil, fmt.Errorf("no check_command in the %s of the repository", config.RepoConfigFileName)
+	}
+
+	stats := i.gitWorktree.Diff()
+	if stats.Error != nil {
+		return nil, fmt.Errorf("failed to get diff: %w", stats.Error)
+	}
+	diffHash := hashDiff(RedactSecrets(stats.Content, i.secrets))
+
+	ctx, cancel := context.WithTimeout(context.Background(), checksTimeout)
+	defer cancel()
+	cmd := shellCommand(ctx, repoConfig.CheckCommand)
+	cmd.Dir = i.gitWorktree.GetWorktreePath()
+	cmd.Env = os.Environ()
+	for key, value := range i.commandEnv() {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+	output, err := cmd.CombinedOutput()
+	result := &CheckResult{
+		Command:  repoConfig.CheckCommand,
+		Passed:   err == nil,
+		Output:   RedactSecrets(tail(string(output), maxCheckOutput), i.secrets),
+		RanAt:    time.Now(),
+		DiffHash: diffHash,
+	}
+	if ctx.Err() != nil {
+		result.Output += fmt.Sprintf("\n\ntimed out after %s", checksTimeout)
+	}
+	return result, nil
+}
+
+// TimeCheckCommand runs the command in dir the way the checks of an instance run, and returns how long
+// it took, e.g. to tell whether it's fit to be the check command of a repository. The error wraps
+// context.DeadlineExceeded if the command ran longer than the checks may.
+func TimeCheckCommand(dir string, command string) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), checksTimeout)
+	defer cancel()
+	cmd := shellCommand(ctx, command)
+	cmd.Dir = dir
+	start := time.Now()
+	output, err := cmd.CombinedOutput()
+	elapsed := time.Since(start)
+	if ctx.Err() != nil {
+		return elapsed, fmt.Errorf("%s timed out after %s: %w", command, checksTimeout, ctx.Err())
+	}
+	if err != nil {
+		return elapsed, fmt.Errorf("%s failed: %w\n%s", command, err, tail(string(output), maxCheckOutput))
+	}
+	return elapsed, nil
+}
+
+// RecordChecks records the result of a run of the check command.
+func (i *Instance) RecordChecks(result *CheckResult) {
+	i.checksRunning = false
+	i.Checks = result
+	outcome := "failed"
+	if result.Passed {
+		outcome = "passed"
+	}
+	i.Audit(AuditChecks, outcome+": "+result.Command)
+}
+
+// SetChecksRunning marks the check command of the instance as running, until RecordChecks.
+func (i *Instance) SetChecksRunning(running bool) {
+	i.checksRunning = running
+}
+
+// ChecksState returns the state of the checks of the instance, one of the Checks constants.
+func (i *Instance) ChecksState() string {
+	switch {
+	case i.checksRunning:
+		return ChecksRunning
+	case i.Checks == nil:
+		return ChecksNone
+	case !i.Checks.Passed:
+		return ChecksFailed
+	case i.diffStats != nil && hashDiff(i.diffStats.Content) != i.Checks.DiffHash:
+		return ChecksStale
+	default:
+		return ChecksPassed
+	}
+}
+
+// CheckPushAllowed returns an error if the repository requires the checks to pass before the changes of
+// the instance are pushed, and they didn't pass on the current diff.
+func (i *Instance) CheckPushAllowed() error {
+	if i.Host != "" || i.gitWorktree == nil {
+		return nil
+	}
+	repoConfig, err := config.LoadRepoConfig(i.gitWorktree.GetRepoPath())
+	if err != nil {
+		return err
+	}
+	if !repoConfig.RequireChecks {
+		return nil
+	}
+	switch i.ChecksState() {
+	case ChecksPassed:
+		return nil
+	case ChecksNone:
+		return fmt.Errorf("run the checks of %s before pushing", i.Title)
+	case ChecksRunning:
+		return fmt.Errorf("the checks of %s are still running", i.Title)
+	case ChecksStale:
+		return fmt.Errorf("the changes of %s changed since the checks passed, run them again", i.Title)
+	default:
+		return fmt.Errorf("the checks of %s failed", i.Title)
+	}
+}
+
+func hashDiff(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// tail returns the last n bytes of s.
+func tail(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[len(s)-n:]
+}
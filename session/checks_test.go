@@ -0,0 +1,86 @@
+package session
+
+import (
+	"claude-squad/config"
+	"claude-squad/session/git"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunChecks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the check command uses sh")
+	}
+	t.Setenv("HOME", t.TempDir())
+	repoPath := t.TempDir()
+	for _, args := range [][]string{
+		{"init"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--allow-empty", "-m", "initial"},
+	} {
+		output, err := exec.Command("git", append([]string{"-C", repoPath}, args...)...).CombinedOutput()
+		require.NoError(t, err, string(output))
+	}
+	head, err := exec.Command("git", "-C", repoPath, "rev-parse", "HEAD").Output()
+	require.NoError(t, err)
+
+	instance := &Instance{
+		Title:     "fix-login",
+		Status:    Ready,
+		CreatedAt: time.Now(),
+		started:   true,
+		gitWorktree: git.NewGitWorktreeFromStorage(repoPath, repoPath, "fix-login", "fix-login",
+			strings.TrimSpace(string(head)), false),
+	}
+	_, err = instance.RunChecks()
+	assert.ErrorContains(t, err, "no check_command")
+	assert.NoError(t, instance.CheckPushAllowed())
+
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, config.RepoConfigFileName),
+		[]byte(`{"check_command": "echo $CS_TITLE; test -f ok", "require_checks": true}`), 0644))
+	assert.ErrorContains(t, instance.CheckPushAllowed(), "run the checks")
+	_, err = instance.RunChecks()
+	assert.ErrorContains(t, err, "cs trust")
+	trustRepoConfig(t, repoPath)
+
+	result, err := instance.RunChecks()
+	require.NoError(t, err)
+	assert.False(t, result.Passed)
+	assert.Equal(t, "fix-login\n", result.Output)
+	instance.RecordChecks(result)
+	assert.Equal(t, ChecksFailed, instance.ChecksState())
+	assert.ErrorContains(t, instance.CheckPushAllowed(), "failed")
+
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "ok"), []byte("ok\n"), 0644))
+	instance.SetChecksRunning(true)
+	assert.Equal(t, ChecksRunning, instance.ChecksState())
+	result, err = instance.RunChecks()
+	require.NoError(t, err)
+	instance.RecordChecks(result)
+	require.NoError(t, instance.UpdateDiffStats())
+	assert.Equal(t, ChecksPassed, instance.ChecksState())
+	assert.NoError(t, instance.CheckPushAllowed())
+
+	// The result no longer counts once the diff changes.
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "ok"), []byte("changed\n"), 0644))
+	require.NoError(t, instance.UpdateDiffStats())
+	assert.Equal(t, ChecksStale, instance.ChecksState())
+	assert.ErrorContains(t, instance.CheckPushAllowed(), "run them again")
+
+	events, err := instance.AuditLog()
+	require.NoError(t, err)
+	var checks []string
+	for _, event := range events {
+		if event.Type == AuditChecks {
+			checks = append(checks, event.Detail)
+		}
+	}
+	assert.Equal(t, []string{"failed: echo $CS_TITLE; test -f ok", "passed: echo $CS_TITLE; test -f ok"}, checks)
+}
@@ -0,0 +1,47 @@
+package claude
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// IsLoggedIn reports whether claude has credentials: an API key or OAuth token in the environment,
+// a credentials file, or an account recorded in its global config after `claude login`. On macOS the
+// credentials themselves are in the keychain, so the account in the config is what's checked.
+func IsLoggedIn() bool {
+	if os.Getenv("ANTHROPIC_API_KEY") != "" || os.Getenv("CLAUDE_CODE_OAUTH_TOKEN") != "" {
+		return true
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		// Don't block instances if we can't tell.
+		return true
+	}
+	configDir := os.Getenv("CLAUDE_CONFIG_DIR")
+	if configDir == "" {
+		configDir = filepath.Join(homeDir, ".claude")
+	}
+	if _, err := os.Stat(filepath.Join(configDir, ".credentials.json")); err == nil {
+		return true
+	}
+
+	for _, path := range []string{filepath.Join(configDir, ".claude.json"), filepath.Join(homeDir, ".claude.json")} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var globalConfig struct {
+			OAuthAccount  json.RawMessage `json:"oauthAccount"`
+			PrimaryAPIKey string          `json:"primaryApiKey"`
+		}
+		if err := json.Unmarshal(data, &globalConfig); err != nil {
+			continue
+		}
+		if len(globalConfig.OAuthAccount) > 0 && string(globalConfig.OAuthAccount) != "null" || globalConfig.PrimaryAPIKey != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -6,9 +6,12 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 )
 
+var projectPathRegex = regexp.MustCompile(`[^a-zA-Z0-9]`)
+
 // ConversationInfo holds basic info about a Claude conversation
 type ConversationInfo struct {
 	SessionID string
@@ -18,14 +21,11 @@ type ConversationInfo struct {
 
 // GetClaudeProjectPath returns the Claude project directory for a given repo path
 func GetClaudeProjectPath(repoPath string) string {
-	// Convert absolute path to Claude's format
-	// /Users/daniel/Documents/GitHub/claude-squad → -Users-daniel-Documents-GitHub-claude-squad
-	cleanPath := strings.ReplaceAll(repoPath, "/", "-")
-	if strings.HasPrefix(cleanPath, "-") {
-		cleanPath = cleanPath[1:]
-	}
-	cleanPath = "-" + cleanPath
-	
+	// Convert absolute path to Claude's format. Claude replaces every character that is not a letter
+	// or digit with a dash:
+	// /Users/daniel/.claude-squad/worktrees/my_task → -Users-daniel--claude-squad-worktrees-my-task
+	cleanPath := projectPathRegex.ReplaceAllString(repoPath, "-")
+
 	homeDir, _ := os.UserHomeDir()
 	return filepath.Join(homeDir, ".claude", "projects", cleanPath)
 }
@@ -0,0 +1,338 @@
+package claude
+
+import (
+	"bufio"
+	"bytes"
+	"claude-squad/textutil"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// FormatMarkdown exports a conversation as Markdown, with the tool calls in collapsed <details> blocks.
+	FormatMarkdown = "markdown"
+	// FormatHTML exports a conversation as a self-contained HTML page.
+	FormatHTML = "html"
+)
+
+// maxToolResultLength caps the output of a tool call in an export, e.g. a whole file that was read.
+const maxToolResultLength = 4000
+
+// toolSummaryKeys are the inputs of the tools that describe a call best, in order of preference.
+var toolSummaryKeys = []string{"description", "command", "file_path", "path", "pattern", "url", "query", "prompt"}
+
+//go:embed export.html
+var exportTemplate string
+
+var exportPage = template.Must(template.New("export").Funcs(template.FuncMap{
+	"markdown": markdownToHTML,
+}).Parse(exportTemplate))
+
+// Turn is a run of consecutive messages of the same role in a conversation. The results of the tool calls
+// of the assistant are part of its turn.
+type Turn struct {
+	// Role is either "user" or "assistant".
+	Role      string
+	Timestamp time.Time
+	Parts     []Part
+}
+
+// Part is either text or a tool call of a turn.
+type Part struct {
+	// Text is the text of the part, empty for tool calls.
+	Text string
+	// Tool is the name of the tool called, empty for text.
+	Tool string
+	// Input is the input of the tool call, as indented JSON.
+	Input string
+	// Result is the output of the tool call, truncated to maxToolResultLength.
+	Result string
+	// IsError is true if the tool call failed.
+	IsError bool
+}
+
+// Summary describes the tool call in a line, e.g. "Bash: go test ./...".
+func (p Part) Summary() string {
+	var input map[string]any
+	if err := json.Unmarshal([]byte(p.Input), &input); err == nil {
+		for _, key := range toolSummaryKeys {
+			if value, ok := input[key].(string); ok && strings.TrimSpace(value) != "" {
+				value = strings.TrimSpace(strings.SplitN(strings.TrimSpace(value), "\n", 2)[0])
+				return p.Tool + ": " + textutil.Truncate(value, 80)
+			}
+		}
+	}
+	return p.Tool
+}
+
+// ExportOptions are the options of Export.
+type ExportOptions struct {
+	// Title is the title of the export, e.g. the title of the instance.
+	Title string
+	// Format is FormatMarkdown or FormatHTML.
+	Format string
+	// Redact, if set, is applied to the text, the tool inputs and the tool results before they're
+	// rendered, e.g. to remove secrets before sharing the transcript.
+	Redact func(string) string
+}
+
+// exportBlock is a block of a structured message content, as far as an export needs it.
+type exportBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text"`
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Input     json.RawMessage `json:"input"`
+	ToolUseID string          `json:"tool_use_id"`
+	Content   json.RawMessage `json:"content"`
+	IsError   bool            `json:"is_error"`
+}
+
+// ReadTranscript returns the turns of a conversation file, with the tool calls paired with their results.
+// Thinking blocks are left out.
+func ReadTranscript(conversationPath string) ([]Turn, error) {
+	file, err := os.Open(conversationPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open conversation: %w", err)
+	}
+	defer file.Close()
+
+	var turns []Turn
+	// calls are the tool calls waiting for their results, by ID, as indexes of turns and parts.
+	calls := make(map[string][2]int)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 32*1024*1024)
+	for scanner.Scan() {
+		var line conversationLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			continue
+		}
+		if line.Type != "user" && line.Type != "assistant" {
+			continue
+		}
+
+		var blocks []exportBlock
+		var s string
+		if err := json.Unmarshal(line.Message.Content, &s); err == nil {
+			blocks = []exportBlock{{Type: "text", Text: s}}
+		} else if err := json.Unmarshal(line.Message.Content, &blocks); err != nil {
+			continue
+		}
+
+		for _, block := range blocks {
+			switch block.Type {
+			case "text":
+				text := strings.TrimSpace(block.Text)
+				if text == "" {
+					continue
+				}
+				turns = appendPart(turns, line.Message.Role, line.Timestamp, Part{Text: text})
+			case "tool_use":
+				var input bytes.Buffer
+				if err := json.Indent(&input, block.Input, "", "  "); err != nil {
+					input.Reset()
+					input.Write(block.Input)
+				}
+				turns = appendPart(turns, line.Message.Role, line.Timestamp, Part{Tool: block.Name, Input: input.String()})
+				calls[block.ID] = [2]int{len(turns) - 1, len(turns[len(turns)-1].Parts) - 1}
+			case "tool_result":
+				call, ok := calls[block.ToolUseID]
+				if !ok {
+					continue
+				}
+				delete(calls, block.ToolUseID)
+				part := &turns[call[0]].Parts[call[1]]
+				part.Result = truncate(resultText(block.Content), maxToolResultLength)
+				part.IsError = block.IsError
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read conversation: %w", err)
+	}
+	return turns, nil
+}
+
+// appendPart appends the part to the last turn if it has the same role, or to a new turn otherwise.
+func appendPart(turns []Turn, role string, timestamp time.Time, part Part) []Turn {
+	if len(turns) == 0 || turns[len(turns)-1].Role != role {
+		turns = append(turns, Turn{Role: role, Timestamp: timestamp})
+	}
+	turns[len(turns)-1].Parts = append(turns[len(turns)-1].Parts, part)
+	return turns
+}
+
+// resultText returns the text of the content of a tool result, which is either a string or a list of
+// blocks.
+func resultText(content json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(content, &s); err == nil {
+		return strings.TrimSpace(s)
+	}
+	var blocks []contentBlock
+	if err := json.Unmarshal(content, &blocks); err != nil {
+		return ""
+	}
+	var parts []string
+	for _, block := range blocks {
+		if block.Type == "text" && block.Text != "" {
+			parts = append(parts, block.Text)
+		}
+	}
+	return strings.TrimSpace(strings.Join(parts, "\n"))
+}
+
+func truncate(s string, max int) string {
+	length := utf8.RuneCountInString(s)
+	if length <= max {
+		return s
+	}
+	return textutil.Prefix(s, max) + fmt.Sprintf("\n… (%d more characters)", length-max)
+}
+
+// Export renders the conversation file in the format of the options.
+func Export(conversationPath string, opts ExportOptions) ([]byte, error) {
+	turns, err := ReadTranscript(conversationPath)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Redact != nil {
+		for t := range turns {
+			for p := range turns[t].Parts {
+				part := &turns[t].Parts[p]
+				part.Text, part.Input, part.Result = opts.Redact(part.Text), opts.Redact(part.Input), opts.Redact(part.Result)
+			}
+		}
+	}
+	switch opts.Format {
+	case FormatMarkdown, "":
+		return []byte(RenderMarkdown(opts.Title, turns)), nil
+	case FormatHTML:
+		return RenderHTML(opts.Title, turns)
+	default:
+		return nil, fmt.Errorf("unknown export format %q: use %s or %s", opts.Format, FormatMarkdown, FormatHTML)
+	}
+}
+
+// RenderMarkdown renders the turns as Markdown. The text of the messages is kept as is, since it's
+// Markdown already, and each tool call is a collapsed <details> block with its input and result.
+func RenderMarkdown(title string, turns []Turn) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n", title)
+	for _, turn := range turns {
+		fmt.Fprintf(&b, "\n## %s", roleName(turn.Role))
+		if !turn.Timestamp.IsZero() {
+			fmt.Fprintf(&b, " · %s", turn.Timestamp.Local().Format("2006-01-02 15:04"))
+		}
+		b.WriteString("\n")
+		for _, part := range turn.Parts {
+			b.WriteString("\n")
+			if part.Tool == "" {
+				b.WriteString(part.Text + "\n")
+				continue
+			}
+			summary := part.Summary()
+			if part.IsError {
+				summary += " (failed)"
+			}
+			fmt.Fprintf(&b, "<details>\n<summary>%s</summary>\n\n", template.HTMLEscapeString(summary))
+			b.WriteString(codeBlock(part.Input, "json"))
+			if part.Result != "" {
+				b.WriteString("\n" + codeBlock(part.Result, ""))
+			}
+			b.WriteString("</details>\n")
+		}
+	}
+	return b.String()
+}
+
+// RenderHTML renders the turns as a page without external resources.
+func RenderHTML(title string, turns []Turn) ([]byte, error) {
+	var b bytes.Buffer
+	data := struct {
+		Title string
+		Turns []Turn
+	}{title, turns}
+	if err := exportPage.Execute(&b, data); err != nil {
+		return nil, fmt.Errorf("failed to render conversation: %w", err)
+	}
+	return b.Bytes(), nil
+}
+
+func roleName(role string) string {
+	if role == "assistant" {
+		return "Assistant"
+	}
+	return "User"
+}
+
+// codeBlock fences the content with more backticks than it contains in a row, so code blocks inside it
+// are preserved.
+func codeBlock(content, lang string) string {
+	fence := "```"
+	for strings.Contains(content, fence) {
+		fence += "`"
+	}
+	return fence + lang + "\n" + strings.TrimRight(content, "\n") + "\n" + fence + "\n"
+}
+
+// markdownToHTML renders the text of a message, keeping its fenced code blocks as preformatted code and
+// the rest as paragraphs with inline code. Other Markdown is left as is.
+func markdownToHTML(text string) template.HTML {
+	var b strings.Builder
+	var paragraph, code []string
+	fence := ""
+	flush := func() {
+		if len(paragraph) > 0 {
+			b.WriteString("<p>" + inlineCode(strings.Join(paragraph, "\n")) + "</p>\n")
+			paragraph = nil
+		}
+	}
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case fence != "" && strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, "`") == "":
+			b.WriteString("<pre><code>" + template.HTMLEscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+			fence, code = "", nil
+		case fence != "":
+			code = append(code, line)
+		case strings.HasPrefix(trimmed, "```"):
+			flush()
+			fence = trimmed[:len(trimmed)-len(strings.TrimLeft(trimmed, "`"))]
+		case trimmed == "":
+			flush()
+		default:
+			paragraph = append(paragraph, line)
+		}
+	}
+	flush()
+	if fence != "" {
+		// An unclosed code block runs to the end of the message.
+		b.WriteString("<pre><code>" + template.HTMLEscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+	}
+	return template.HTML(b.String())
+}
+
+// inlineCode escapes the text and renders the spans between backticks as code.
+func inlineCode(text string) string {
+	spans := strings.Split(text, "`")
+	if len(spans)%2 == 0 {
+		// An unmatched backtick is kept as is.
+		return template.HTMLEscapeString(text)
+	}
+	var b strings.Builder
+	for idx, span := range spans {
+		if idx%2 == 1 {
+			b.WriteString("<code>" + template.HTMLEscapeString(span) + "</code>")
+		} else {
+			b.WriteString(template.HTMLEscapeString(span))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,78 @@
+package claude
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const conversation = `{"type":"summary","summary":"Fix the tests"}
+{"type":"user","timestamp":"2026-10-16T10:00:00Z","message":{"role":"user","content":"Fix the failing test, the token is sk-secret"}}
+{"type":"assistant","timestamp":"2026-10-16T10:00:05Z","message":{"role":"assistant","content":[{"type":"thinking","thinking":"hmm"},{"type":"text","text":"Running the tests."},{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"go test ./...","description":"Run the tests"}}]}}
+{"type":"user","timestamp":"2026-10-16T10:00:09Z","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"--- FAIL: TestLogin\n` + "```" + `","is_error":true}]}}
+{"type":"assistant","timestamp":"2026-10-16T10:00:12Z","message":{"role":"assistant","content":[{"type":"text","text":"Fixed it in ` + "`login.go`" + `:\n\n` + "```go\\nreturn nil, <err>\\n```" + `"}]}}
+`
+
+func writeConversation(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "conversation.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte(conversation), 0644))
+	return path
+}
+
+func TestReadTranscript(t *testing.T) {
+	turns, err := ReadTranscript(writeConversation(t))
+	require.NoError(t, err)
+	require.Len(t, turns, 2)
+	assert.Equal(t, "user", turns[0].Role)
+
+	// The tool result belongs to the call of the assistant, whose turn goes on after it.
+	assistant := turns[1]
+	require.Len(t, assistant.Parts, 3)
+	call := assistant.Parts[1]
+	assert.Equal(t, "Bash: Run the tests", call.Summary())
+	assert.Contains(t, call.Input, `"command": "go test ./..."`)
+	assert.Equal(t, "--- FAIL: TestLogin\n```", call.Result)
+	assert.True(t, call.IsError)
+}
+
+func TestTruncate(t *testing.T) {
+	long := Part{Tool: "Bash", Input: `{"description": "` + strings.Repeat("é", 100) + `"}`}
+	assert.Equal(t, "Bash: "+strings.Repeat("é", 77)+"...", long.Summary())
+
+	// The cut output counts the characters left out.
+	assert.Equal(t, "ééé\n… (2 more characters)", truncate("ééééé", 3))
+	assert.Equal(t, "ééé", truncate("ééé", 3))
+}
+
+func TestExportMarkdown(t *testing.T) {
+	content, err := Export(writeConversation(t), ExportOptions{
+		Title:  "fix-tests",
+		Redact: func(s string) string { return strings.ReplaceAll(s, "sk-secret", "[REDACTED]") },
+	})
+	require.NoError(t, err)
+	markdown := string(content)
+	assert.True(t, strings.HasPrefix(markdown, "# fix-tests\n"))
+	assert.Contains(t, markdown, "the token is [REDACTED]")
+	assert.NotContains(t, markdown, "hmm")
+	assert.Contains(t, markdown, "<details>\n<summary>Bash: Run the tests (failed)</summary>")
+	// The result contains a fence, so it's fenced with more backticks.
+	assert.Contains(t, markdown, "````\n--- FAIL: TestLogin\n```\n````")
+	assert.Contains(t, markdown, "```go\nreturn nil, <err>\n```")
+}
+
+func TestExportHTML(t *testing.T) {
+	content, err := Export(writeConversation(t), ExportOptions{Title: "fix-tests", Format: FormatHTML})
+	require.NoError(t, err)
+	html := string(content)
+	assert.Contains(t, html, "<title>fix-tests – claude-squad conversation</title>")
+	assert.Contains(t, html, `<details class="failed">`)
+	assert.Contains(t, html, "<p>Fixed it in <code>login.go</code>:</p>")
+	assert.Contains(t, html, "<pre><code>return nil, &lt;err&gt;</code></pre>")
+
+	_, err = Export(writeConversation(t), ExportOptions{Format: "pdf"})
+	assert.ErrorContains(t, err, `unknown export format "pdf"`)
+}
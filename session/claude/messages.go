@@ -0,0 +1,125 @@
+package claude
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Message is a single user or assistant message of a conversation.
+type Message struct {
+	// Role is either "user" or "assistant".
+	Role string
+	// Text is the plain text content of the message. Tool calls and results are omitted.
+	Text string
+	// Timestamp is when the message was recorded.
+	Timestamp time.Time
+}
+
+// conversationLine is a line of a Claude conversation JSONL file.
+type conversationLine struct {
+	Type      string    `json:"type"`
+	Timestamp time.Time `json:"timestamp"`
+	Message   struct {
+		Role    string          `json:"role"`
+		Content json.RawMessage `json:"content"`
+	} `json:"message"`
+}
+
+// contentBlock is a block of a structured message content.
+type contentBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// text returns the plain text of the message content, which is either a string or a list of blocks.
+func (l *conversationLine) text() string {
+	var s string
+	if err := json.Unmarshal(l.Message.Content, &s); err == nil {
+		return s
+	}
+
+	var blocks []contentBlock
+	if err := json.Unmarshal(l.Message.Content, &blocks); err != nil {
+		return ""
+	}
+	var parts []string
+	for _, block := range blocks {
+		if block.Type == "text" && block.Text != "" {
+			parts = append(parts, block.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// ReadMessages returns the text messages of a conversation file in the order they were recorded.
+func ReadMessages(conversationPath string) ([]Message, error) {
+	file, err := os.Open(conversationPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open conversation: %w", err)
+	}
+	defer file.Close()
+
+	var messages []Message
+	scanner := bufio.NewScanner(file)
+	// Conversation lines embed whole files and tool output, so they can be very long.
+	scanner.Buffer(make([]byte, 0, 64*1024), 32*1024*1024)
+	for scanner.Scan() {
+		var line conversationLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			continue
+		}
+		if line.Type != "user" && line.Type != "assistant" {
+			continue
+		}
+		text := strings.TrimSpace(line.text())
+		if text == "" {
+			continue
+		}
+		messages = append(messages, Message{
+			Role:      line.Message.Role,
+			Text:      text,
+			Timestamp: line.Timestamp,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read conversation: %w", err)
+	}
+	return messages, nil
+}
+
+// RecentMessages returns the messages with the given role recorded after since across all conversations
+// of the project, oldest first. An empty role matches all messages.
+func RecentMessages(projectPath string, role string, since time.Time) ([]Message, error) {
+	conversations, err := ListConversations(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []Message
+	for _, conversation := range conversations {
+		// Skip conversations that were not written to since the cutoff.
+		if info, err := os.Stat(conversation.Path); err != nil || info.ModTime().Before(since) {
+			continue
+		}
+		all, err := ReadMessages(filepath.Clean(conversation.Path))
+		if err != nil {
+			return nil, err
+		}
+		for _, msg := range all {
+			if (role == "" || msg.Role == role) && msg.Timestamp.After(since) {
+				messages = append(messages, msg)
+			}
+		}
+	}
+
+	sort.SliceStable(messages, func(i, j int) bool {
+		return messages[i].Timestamp.Before(messages[j].Timestamp)
+	})
+	return messages, nil
+}
@@ -0,0 +1,76 @@
+package claude
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Usage is the number of tokens the assistant messages of a conversation used.
+type Usage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+}
+
+// Total returns the number of tokens of every kind.
+func (u Usage) Total() int {
+	return u.InputTokens + u.OutputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens
+}
+
+// Add adds the tokens of other to u.
+func (u *Usage) Add(other Usage) {
+	u.InputTokens += other.InputTokens
+	u.OutputTokens += other.OutputTokens
+	u.CacheCreationInputTokens += other.CacheCreationInputTokens
+	u.CacheReadInputTokens += other.CacheReadInputTokens
+}
+
+// usageLine is the part of a line of a Claude conversation JSONL file with the usage of a message.
+type usageLine struct {
+	Type    string `json:"type"`
+	Message struct {
+		ID    string `json:"id"`
+		Usage *Usage `json:"usage"`
+	} `json:"message"`
+}
+
+// ReadUsage returns the tokens the assistant messages of a conversation file used. A message is written
+// on a line per content block, each with the usage of the whole message, so it's counted once.
+func ReadUsage(conversationPath string) (Usage, error) {
+	file, err := os.Open(conversationPath)
+	if err != nil {
+		return Usage{}, fmt.Errorf("failed to open conversation: %w", err)
+	}
+	defer file.Close()
+
+	var usage Usage
+	messages := make(map[string]Usage)
+	scanner := bufio.NewScanner(file)
+	// Conversation lines embed whole files and tool output, so they can be very long.
+	scanner.Buffer(make([]byte, 0, 64*1024), 32*1024*1024)
+	for scanner.Scan() {
+		var line usageLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			continue
+		}
+		if line.Type != "assistant" || line.Message.Usage == nil {
+			continue
+		}
+		if line.Message.ID == "" {
+			usage.Add(*line.Message.Usage)
+			continue
+		}
+		// The last line of a message has its final output tokens.
+		messages[line.Message.ID] = *line.Message.Usage
+	}
+	if err := scanner.Err(); err != nil {
+		return Usage{}, fmt.Errorf("failed to read conversation: %w", err)
+	}
+	for _, message := range messages {
+		usage.Add(message)
+	}
+	return usage, nil
+}
@@ -0,0 +1,25 @@
+package claude
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadUsage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "conversation.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte(`{"type":"user","message":{"role":"user","content":"Fix the tests"}}
+{"type":"assistant","message":{"id":"msg_1","role":"assistant","usage":{"input_tokens":10,"output_tokens":1,"cache_read_input_tokens":1000}}}
+{"type":"assistant","message":{"id":"msg_1","role":"assistant","usage":{"input_tokens":10,"output_tokens":50,"cache_read_input_tokens":1000}}}
+{"type":"assistant","message":{"id":"msg_2","role":"assistant","usage":{"input_tokens":5,"output_tokens":20,"cache_creation_input_tokens":200}}}
+not json
+`), 0644))
+
+	usage, err := ReadUsage(path)
+	require.NoError(t, err)
+	assert.Equal(t, Usage{InputTokens: 15, OutputTokens: 70, CacheCreationInputTokens: 200, CacheReadInputTokens: 1000}, usage)
+	assert.Equal(t, 1285, usage.Total())
+}
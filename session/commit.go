@@ -0,0 +1,131 @@
+package session
+
+import (
+	"claude-squad/config"
+	"claude-squad/log"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	// CommitReasonPause commits the changes of an instance before it's paused.
+	CommitReasonPause = "pause"
+	// CommitReasonPush commits the changes of an instance before its branch is pushed.
+	CommitReasonPush = "push"
+	// CommitReasonRun commits the changes of a headless run.
+	CommitReasonRun = "run"
+	// CommitReasonStage commits the changes of a finished pipeline stage.
+	CommitReasonStage = "stage"
+)
+
+// CommitData is what the commit message template can use.
+type CommitData struct {
+	Title   string
+	Branch  string
+	Program string
+	// Reason is what the commit is for: "pause", "push", "run", "stage", or the commit mode, "interval",
+	// "idle" or "save", for automatic commits.
+	Reason string
+	// Time is when the commit is made, e.g. "02 Jan 06 15:04 MST".
+	Time string
+	// Files is the number of changed files of the instance.
+	Files int
+}
+
+// CommitMessage returns the message of a commit of the instance for the reason, rendered from the message
+// template of the commit strategy. If there isn't one, or it's invalid, the default message is returned.
+func (i *Instance) CommitMessage(strategy config.CommitStrategy, reason string, defaultMessage string) string {
+	if strategy.MessageTemplate == "" {
+		return defaultMessage
+	}
+	message, err := i.renderCommitMessage(strategy.MessageTemplate, reason, time.Now())
+	if err != nil {
+		log.WarningLog.Printf("using the default commit message: %v", err)
+		return defaultMessage
+	}
+	return message
+}
+
+func (i *Instance) renderCommitMessage(tmpl string, reason string, now time.Time) (string, error) {
+	t, err := template.New("commit").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("invalid commit message template: %w", err)
+	}
+	data := CommitData{
+		Title:   i.Title,
+		Branch:  i.Branch,
+		Program: i.Program,
+		Reason:  reason,
+		Time:    now.Format(time.RFC822),
+	}
+	if stats := i.GetDiffStats(); stats != nil && stats.Error == nil {
+		data.Files = len(stats.ChangedFiles())
+	}
+	var message strings.Builder
+	if err := t.Execute(&message, data); err != nil {
+		return "", fmt.Errorf("invalid commit message template: %w", err)
+	}
+	if strings.TrimSpace(message.String()) == "" {
+		return "", fmt.Errorf("the commit message template rendered an empty message")
+	}
+	return message.String(), nil
+}
+
+// autoCommitDue returns the commit mode of the strategy if it's time to commit the changes of the
+// instance, and an empty string otherwise.
+func (i *Instance) autoCommitDue(strategy config.CommitStrategy, now time.Time) string {
+	mode := strategy.GetMode()
+	switch mode {
+	case config.CommitOnInterval:
+		if i.autoCommitAt.IsZero() {
+			// The interval starts when the instance is first polled.
+			i.autoCommitAt = now
+			return ""
+		}
+		if now.Sub(i.autoCommitAt) >= strategy.GetInterval() {
+			return mode
+		}
+	case config.CommitOnSave:
+		// Without a watcher, e.g. for remote instances or in the daemon, the agent going idle stands in for
+		// the files settling.
+		if i.diffWatcher != nil {
+			lastChange := i.diffWatcher.LastChange()
+			if lastChange.After(i.autoCommitAt) && now.Sub(lastChange) >= strategy.GetQuiet() {
+				return mode
+			}
+			return ""
+		}
+		fallthrough
+	case config.CommitOnIdle:
+		if i.Status == Ready && i.readyAt.After(i.autoCommitAt) && now.Sub(i.readyAt) >= strategy.GetQuiet() {
+			return mode
+		}
+	}
+	return ""
+}
+
+// AutoCommit commits the changes of the running instance if the commit strategy says it's time: every
+// interval, once its agent has been idle for a while, or once its files stopped changing for a while. It
+// returns true if it committed.
+func (i *Instance) AutoCommit(strategy config.CommitStrategy, now time.Time) (bool, error) {
+	if !i.started || i.Paused() || i.Status == Gone {
+		return false, nil
+	}
+	reason := i.autoCommitDue(strategy, now)
+	if reason == "" {
+		return false, nil
+	}
+	// The next commit is only considered after the next interval or change, even if this one fails.
+	i.autoCommitAt = now
+	dirty, err := i.gitWorktree.IsDirty()
+	if err != nil || !dirty {
+		return false, err
+	}
+	defaultMessage := fmt.Sprintf("[claudesquad] update from '%s' on %s (%s)", i.Title, now.Format(time.RFC822), reason)
+	if err := i.CommitWork(i.CommitMessage(strategy, reason, defaultMessage)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
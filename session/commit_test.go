@@ -0,0 +1,64 @@
+package session
+
+import (
+	"claude-squad/config"
+	"claude-squad/session/git"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommitMessage(t *testing.T) {
+	instance := &Instance{
+		Title:     "fix login",
+		Branch:    "me/fix-login",
+		Program:   "claude",
+		diffStats: &git.DiffStats{Added: 2, Content: "diff --git a/a.go b/a.go\n+a\ndiff --git a/b.go b/b.go\n+b"},
+	}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	message, err := instance.renderCommitMessage("wip({{.Branch}}): {{.Reason}} of {{.Title}} by {{.Program}}, {{.Files}} files at {{.Time}}", "idle", now)
+	require.NoError(t, err)
+	assert.Equal(t, "wip(me/fix-login): idle of fix login by claude, 2 files at 01 May 24 12:00 UTC", message)
+
+	_, err = instance.renderCommitMessage("{{.Author}}", "idle", now)
+	assert.Error(t, err)
+	_, err = instance.renderCommitMessage("{{if .Files}}", "idle", now)
+	assert.Error(t, err)
+	_, err = instance.renderCommitMessage("{{if false}}x{{end}}", "idle", now)
+	assert.Error(t, err)
+
+	assert.Equal(t, "default", instance.CommitMessage(config.CommitStrategy{}, CommitReasonPause, "default"))
+	assert.Equal(t, "default", instance.CommitMessage(config.CommitStrategy{MessageTemplate: "{{.Nope}}"}, CommitReasonPause, "default"))
+	assert.Equal(t, "pause fix login", instance.CommitMessage(config.CommitStrategy{MessageTemplate: "{{.Reason}} {{.Title}}"}, CommitReasonPause, "default"))
+}
+
+func TestAutoCommitDue(t *testing.T) {
+	now := time.Now()
+	instance := &Instance{Title: "fix login", Status: Running, started: true}
+
+	assert.Empty(t, instance.autoCommitDue(config.CommitStrategy{}, now))
+
+	interval := config.CommitStrategy{Mode: config.CommitOnInterval, IntervalMinutes: 5}
+	// The first poll starts the interval.
+	assert.Empty(t, instance.autoCommitDue(interval, now))
+	assert.Empty(t, instance.autoCommitDue(interval, now.Add(4*time.Minute)))
+	assert.Equal(t, config.CommitOnInterval, instance.autoCommitDue(interval, now.Add(5*time.Minute)))
+
+	instance.autoCommitAt = now
+	idle := config.CommitStrategy{Mode: config.CommitOnIdle, QuietSeconds: 10}
+	assert.Empty(t, instance.autoCommitDue(idle, now.Add(time.Minute)))
+	instance.Status = Ready
+	instance.readyAt = now.Add(time.Minute)
+	assert.Empty(t, instance.autoCommitDue(idle, now.Add(time.Minute+5*time.Second)))
+	assert.Equal(t, config.CommitOnIdle, instance.autoCommitDue(idle, now.Add(time.Minute+10*time.Second)))
+	// It's only committed once per idle period.
+	instance.autoCommitAt = now.Add(time.Minute + 10*time.Second)
+	assert.Empty(t, instance.autoCommitDue(idle, now.Add(2*time.Minute)))
+
+	// Without a watcher, the save mode commits when the agent is idle.
+	save := config.CommitStrategy{Mode: config.CommitOnSave, QuietSeconds: 10}
+	instance.autoCommitAt = now
+	assert.Equal(t, config.CommitOnSave, instance.autoCommitDue(save, now.Add(2*time.Minute)))
+}
@@ -0,0 +1,464 @@
+//go:build windows
+
+package conpty
+
+import (
+	"bytes"
+	"claude-squad/log"
+	"claude-squad/session/tmux"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+	"unicode/utf16"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/term"
+)
+
+const (
+	defaultWidth  = 120
+	defaultHeight = 40
+)
+
+// console is a program running in a pseudo console. Consoles are looked up by name, since instances are
+// recreated from storage while claude-squad runs.
+type console struct {
+	handle  windows.Handle
+	process windows.Handle
+	// in is written to type into the console.
+	in *os.File
+	// out is read for the output of the console.
+	out    *os.File
+	screen *screen
+	// exited is closed when the program exits.
+	exited chan struct{}
+
+	mu sync.Mutex
+	// attached receives the output while a session is attached.
+	attached io.Writer
+}
+
+var (
+	consolesMu sync.Mutex
+	consoles   = make(map[string]*console)
+)
+
+func lookupConsole(name string) *console {
+	consolesMu.Lock()
+	defer consolesMu.Unlock()
+	return consoles[name]
+}
+
+// CloseAll terminates all the consoles.
+func CloseAll() error {
+	consolesMu.Lock()
+	defer consolesMu.Unlock()
+	for name, c := range consoles {
+		c.close()
+		delete(consoles, name)
+	}
+	return nil
+}
+
+// Session is a pseudo console session, implementing the same operations as a tmux session.
+type Session struct {
+	name    string
+	program string
+	// workDir is where the program is restarted if the console is not running on Restore.
+	workDir       string
+	env           map[string]string
+	width, height int
+	// prevOutputHash is the hash of the content on the last call to HasUpdated.
+	prevOutputHash []byte
+
+	// Initialized by Attach
+	attachCh   chan struct{}
+	detachOnce *sync.Once
+	ctx        context.Context
+	cancel     func()
+}
+
+// NewSession creates a session running program. workDir is the directory to restart the program in
+// when the session is restored, if known.
+func NewSession(name string, program string, workDir string) *Session {
+	return &Session{
+		name:    name,
+		program: program,
+		workDir: workDir,
+		width:   defaultWidth,
+		height:  defaultHeight,
+	}
+}
+
+// SetEnv sets environment variables for the program. It only applies to sessions started afterwards.
+func (s *Session) SetEnv(env map[string]string) {
+	s.env = env
+}
+
+// Start starts the program in a new pseudo console in workDir.
+func (s *Session) Start(workDir string) error {
+	if s.DoesSessionExist() {
+		return fmt.Errorf("console session already exists: %s", s.name)
+	}
+	c, err := startConsole(s.program, workDir, s.env, s.width, s.height)
+	if err != nil {
+		return fmt.Errorf("error starting console session: %w", err)
+	}
+	s.workDir = workDir
+
+	consolesMu.Lock()
+	if previous, ok := consoles[s.name]; ok {
+		previous.close()
+	}
+	consoles[s.name] = c
+	consolesMu.Unlock()
+	return nil
+}
+
+// Restore reconnects to the console of the session. If it isn't running, e.g. after claude-squad was
+// restarted, the program is started again in the worktree.
+func (s *Session) Restore() error {
+	if s.DoesSessionExist() {
+		return nil
+	}
+	if s.workDir == "" {
+		return fmt.Errorf("console session %s is not running", s.name)
+	}
+	log.InfoLog.Printf("restarting console session %s in %s", s.name, s.workDir)
+	return s.Start(s.workDir)
+}
+
+// Close terminates the console.
+func (s *Session) Close() error {
+	consolesMu.Lock()
+	defer consolesMu.Unlock()
+	if c, ok := consoles[s.name]; ok {
+		c.close()
+		delete(consoles, s.name)
+	}
+	return nil
+}
+
+// DoesSessionExist returns true if the program of the session is running.
+func (s *Session) DoesSessionExist() bool {
+	c := lookupConsole(s.name)
+	return c != nil && c.running()
+}
+
+// AttachCommand returns an error: the console belongs to the claude-squad process, so another terminal
+// can't attach to it.
+func (s *Session) AttachCommand() ([]string, error) {
+	return nil, fmt.Errorf("attaching from another terminal is not supported on Windows")
+}
+
+// ProcessID returns the PID of the program running in the console.
+func (s *Session) ProcessID() (int, error) {
+	c := lookupConsole(s.name)
+	if c == nil {
+		return 0, fmt.Errorf("console session %s is not running", s.name)
+	}
+	pid, err := windows.GetProcessId(c.process)
+	if err != nil {
+		return 0, err
+	}
+	return int(pid), nil
+}
+
+// CapturePaneContent returns the visible lines of the console.
+func (s *Session) CapturePaneContent() (string, error) {
+	c := lookupConsole(s.name)
+	if c == nil {
+		return "", fmt.Errorf("console session %s is not running", s.name)
+	}
+	return c.screen.Content(s.height), nil
+}
+
+// CapturePaneContentWithOptions returns the whole history of the console. Line ranges are not supported.
+func (s *Session) CapturePaneContentWithOptions(start, end string) (string, error) {
+	c := lookupConsole(s.name)
+	if c == nil {
+		return "", fmt.Errorf("console session %s is not running", s.name)
+	}
+	return c.screen.Content(0), nil
+}
+
+// HasUpdated checks if content, just captured, changed since the last call. It also returns true if the
+// program shows a permission prompt.
+func (s *Session) HasUpdated(content string) (updated bool, hasPrompt bool) {
+	hasPrompt = tmux.HasPrompt(s.program, content)
+
+	hash := sha256.Sum256([]byte(content))
+	if !bytes.Equal(hash[:], s.prevOutputHash) {
+		s.prevOutputHash = hash[:]
+		return true, hasPrompt
+	}
+	return false, hasPrompt
+}
+
+// SendKeys types the keys into the console.
+func (s *Session) SendKeys(keys string) error {
+	c := lookupConsole(s.name)
+	if c == nil {
+		return fmt.Errorf("console session %s is not running", s.name)
+	}
+	_, err := c.in.Write([]byte(keys))
+	return err
+}
+
+// TapEnter sends an enter keystroke to the console.
+func (s *Session) TapEnter() error {
+	if err := s.SendKeys("\r"); err != nil {
+		return fmt.Errorf("error sending enter keystroke to console: %w", err)
+	}
+	return nil
+}
+
+// SetDetachedSize sets the size of the console.
+func (s *Session) SetDetachedSize(width, height int) error {
+	s.width, s.height = width, height
+	c := lookupConsole(s.name)
+	if c == nil {
+		return nil
+	}
+	return windows.ResizePseudoConsole(c.handle, windows.Coord{X: int16(width), Y: int16(height)})
+}
+
+// Attach forwards stdin to the console and the console output to stdout until Ctrl-Q is pressed or the
+// program exits.
+func (s *Session) Attach() (chan struct{}, error) {
+	c := lookupConsole(s.name)
+	if c == nil || !c.running() {
+		return nil, fmt.Errorf("console session %s is not running", s.name)
+	}
+
+	s.attachCh = make(chan struct{})
+	s.detachOnce = &sync.Once{}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	ctx := s.ctx
+
+	// Redraw the current content before streaming the output.
+	fmt.Fprint(os.Stdout, "\x1b[2J\x1b[H"+c.screen.Content(s.height))
+	c.setAttached(os.Stdout)
+
+	go func() {
+		buf := make([]byte, 32)
+		for {
+			nr, err := os.Stdin.Read(buf)
+			if err != nil {
+				if err == io.EOF {
+					return
+				}
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+			// Check for Ctrl+q (ASCII 17)
+			if nr == 1 && buf[0] == 17 {
+				s.Detach()
+				return
+			}
+			_, _ = c.in.Write(buf[:nr])
+		}
+	}()
+
+	go func() {
+		select {
+		case <-c.exited:
+			fmt.Fprintf(os.Stderr, "\n\033[31mError: Session terminated without detaching.\033[0m\n")
+			s.Detach()
+		case <-ctx.Done():
+		}
+	}()
+
+	go s.monitorWindowSize(ctx, c)
+	return s.attachCh, nil
+}
+
+// Detach stops forwarding stdin and stdout.
+func (s *Session) Detach() {
+	s.detachOnce.Do(func() {
+		if c := lookupConsole(s.name); c != nil {
+			c.setAttached(nil)
+		}
+		s.cancel()
+		close(s.attachCh)
+	})
+}
+
+// monitorWindowSize resizes the console to the terminal while attached. Windows has no SIGWINCH, so the
+// size is polled.
+func (s *Session) monitorWindowSize(ctx context.Context, c *console) {
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+
+	var lastCols, lastRows int
+	for {
+		cols, rows, err := term.GetSize(int(os.Stdout.Fd()))
+		if err == nil && (cols != lastCols || rows != lastRows) {
+			lastCols, lastRows = cols, rows
+			if err := windows.ResizePseudoConsole(c.handle, windows.Coord{X: int16(cols), Y: int16(rows)}); err != nil {
+				log.ErrorLog.Printf("failed to update window size: %v", err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// startConsole creates a pseudo console and starts the program in it.
+func startConsole(program string, workDir string, env map[string]string, width, height int) (*console, error) {
+	var inRead, inWrite, outRead, outWrite windows.Handle
+	if err := windows.CreatePipe(&inRead, &inWrite, nil, 0); err != nil {
+		return nil, fmt.Errorf("failed to create input pipe: %w", err)
+	}
+	if err := windows.CreatePipe(&outRead, &outWrite, nil, 0); err != nil {
+		windows.CloseHandle(inRead)
+		windows.CloseHandle(inWrite)
+		return nil, fmt.Errorf("failed to create output pipe: %w", err)
+	}
+
+	var handle windows.Handle
+	size := windows.Coord{X: int16(width), Y: int16(height)}
+	err := windows.CreatePseudoConsole(size, inRead, outWrite, 0, &handle)
+	if err == nil {
+		var process windows.Handle
+		process, err = createProcess(handle, program, workDir, env)
+		// The pseudo console holds its own references to these ends.
+		windows.CloseHandle(inRead)
+		windows.CloseHandle(outWrite)
+		if err != nil {
+			windows.ClosePseudoConsole(handle)
+			windows.CloseHandle(inWrite)
+			windows.CloseHandle(outRead)
+			return nil, err
+		}
+
+		c := &console{
+			handle:  handle,
+			process: process,
+			in:      os.NewFile(uintptr(inWrite), "conpty-in"),
+			out:     os.NewFile(uintptr(outRead), "conpty-out"),
+			screen:  newScreen(),
+			exited:  make(chan struct{}),
+		}
+		go c.readOutput()
+		go c.waitExit()
+		return c, nil
+	}
+
+	for _, h := range []windows.Handle{inRead, inWrite, outRead, outWrite} {
+		windows.CloseHandle(h)
+	}
+	return nil, fmt.Errorf("failed to create pseudo console: %w", err)
+}
+
+// createProcess starts the program attached to the pseudo console.
+func createProcess(handle windows.Handle, program string, workDir string, env map[string]string) (windows.Handle, error) {
+	attrs, err := windows.NewProcThreadAttributeList(1)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create attribute list: %w", err)
+	}
+	defer attrs.Delete()
+	// The attribute value is the console handle itself, not a pointer to it.
+	if err := attrs.Update(windows.PROC_THREAD_ATTRIBUTE_PSEUDOCONSOLE,
+		*(*unsafe.Pointer)(unsafe.Pointer(&handle)), unsafe.Sizeof(handle)); err != nil {
+		return 0, fmt.Errorf("failed to set pseudo console attribute: %w", err)
+	}
+
+	si := &windows.StartupInfoEx{ProcThreadAttributeList: attrs.List()}
+	si.Cb = uint32(unsafe.Sizeof(*si))
+
+	cmdLine, err := windows.UTF16PtrFromString(commandLine(program))
+	if err != nil {
+		return 0, err
+	}
+	dir, err := windows.UTF16PtrFromString(workDir)
+	if err != nil {
+		return 0, err
+	}
+
+	var pi windows.ProcessInformation
+	flags := uint32(windows.EXTENDED_STARTUPINFO_PRESENT | windows.CREATE_UNICODE_ENVIRONMENT)
+	if err := windows.CreateProcess(nil, cmdLine, nil, nil, false, flags,
+		environmentBlock(mergeEnv(os.Environ(), env)), dir, &si.StartupInfo, &pi); err != nil {
+		return 0, fmt.Errorf("failed to start %s: %w", program, err)
+	}
+	windows.CloseHandle(pi.Thread)
+	return pi.Process, nil
+}
+
+// environmentBlock encodes the environment as a block of null-terminated UTF-16 strings, terminated by
+// an empty string.
+func environmentBlock(env []string) *uint16 {
+	var block []uint16
+	for _, entry := range env {
+		block = append(block, utf16.Encode([]rune(entry))...)
+		block = append(block, 0)
+	}
+	block = append(block, 0)
+	return &block[0]
+}
+
+func (c *console) readOutput() {
+	buf := make([]byte, 4096)
+	for {
+		n, err := c.out.Read(buf)
+		if n > 0 {
+			_, _ = c.screen.Write(buf[:n])
+			c.mu.Lock()
+			if c.attached != nil {
+				_, _ = c.attached.Write(buf[:n])
+			}
+			c.mu.Unlock()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (c *console) waitExit() {
+	_, _ = windows.WaitForSingleObject(c.process, windows.INFINITE)
+	close(c.exited)
+}
+
+func (c *console) running() bool {
+	select {
+	case <-c.exited:
+		return false
+	default:
+		return true
+	}
+}
+
+func (c *console) setAttached(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.attached = w
+}
+
+// close terminates the program and releases the console.
+func (c *console) close() {
+	if c.running() {
+		_ = windows.TerminateProcess(c.process, 1)
+	}
+	windows.ClosePseudoConsole(c.handle)
+	_ = c.in.Close()
+	_ = c.out.Close()
+	// waitExit still uses the process handle until the process is gone.
+	go func() {
+		<-c.exited
+		windows.CloseHandle(c.process)
+	}()
+}
@@ -0,0 +1,41 @@
+package conpty
+
+import (
+	"sort"
+	"strings"
+)
+
+// mergeEnv overrides the KEY=VALUE entries of base with extra. Keys are compared case-insensitively,
+// like Windows does.
+func mergeEnv(base []string, extra map[string]string) []string {
+	merged := make([]string, 0, len(base)+len(extra))
+	for _, entry := range base {
+		key, _, _ := strings.Cut(entry, "=")
+		overridden := false
+		for name := range extra {
+			if strings.EqualFold(key, name) {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			merged = append(merged, entry)
+		}
+	}
+
+	names := make([]string, 0, len(extra))
+	for name := range extra {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		merged = append(merged, name+"="+extra[name])
+	}
+	return merged
+}
+
+// commandLine returns the command line running the program. The program goes through cmd.exe so that
+// arguments and .cmd shims (like the ones npm installs) work.
+func commandLine(program string) string {
+	return "cmd.exe /c " + program
+}
@@ -0,0 +1,15 @@
+package conpty
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeEnv(t *testing.T) {
+	merged := mergeEnv([]string{"Path=C:\\Windows", "API_KEY=old"}, map[string]string{
+		"api_key": "new",
+		"TOKEN":   "secret",
+	})
+	assert.Equal(t, []string{"Path=C:\\Windows", "TOKEN=secret", "api_key=new"}, merged)
+}
@@ -0,0 +1,142 @@
+// Package conpty runs programs in Windows pseudo consoles, as a replacement for tmux which isn't available
+// natively on Windows. Unlike tmux sessions, consoles live in the claude-squad process: they end when it
+// exits and are restarted in their worktree on the next start.
+package conpty
+
+import (
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// maxScreenLines is the number of lines of history kept by a screen.
+const maxScreenLines = 2000
+
+// screen keeps the recent output of a console as lines of text. It is not a terminal emulator: cursor
+// movements are ignored and only the colors (SGR sequences) are kept, which is enough to preview the
+// output of line oriented programs.
+type screen struct {
+	mu    sync.Mutex
+	lines [][]byte
+	// pending holds an escape sequence split across writes.
+	pending []byte
+}
+
+func newScreen() *screen {
+	return &screen{lines: [][]byte{nil}}
+}
+
+// Write appends console output to the screen.
+func (s *screen) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := append(s.pending, p...)
+	s.pending = nil
+	for i := 0; i < len(data); i++ {
+		switch b := data[i]; b {
+		case 0x1b:
+			n, complete := escapeLength(data[i:])
+			if !complete {
+				s.pending = append([]byte(nil), data[i:]...)
+				return len(p), nil
+			}
+			s.handleEscape(data[i : i+n])
+			i += n - 1
+		case '\n':
+			s.newLine()
+		case '\r':
+			// A carriage return not followed by a newline redraws the line.
+			if i+1 < len(data) && data[i+1] != '\n' {
+				s.lines[len(s.lines)-1] = nil
+			}
+		case '\b':
+			line := s.lines[len(s.lines)-1]
+			if len(line) > 0 {
+				_, size := utf8.DecodeLastRune(line)
+				s.lines[len(s.lines)-1] = line[:len(line)-size]
+			}
+		case '\t':
+			s.lines[len(s.lines)-1] = append(s.lines[len(s.lines)-1], b)
+		default:
+			if b >= 0x20 && b != 0x7f {
+				s.lines[len(s.lines)-1] = append(s.lines[len(s.lines)-1], b)
+			}
+		}
+	}
+	return len(p), nil
+}
+
+// escapeLength returns the length of the escape sequence at the start of data, and whether it is
+// complete.
+func escapeLength(data []byte) (int, bool) {
+	if len(data) < 2 {
+		return 0, false
+	}
+	switch data[1] {
+	case '[':
+		// CSI: parameters and intermediates, terminated by a byte in 0x40-0x7e.
+		for i := 2; i < len(data); i++ {
+			if data[i] >= 0x40 && data[i] <= 0x7e {
+				return i + 1, true
+			}
+		}
+		return 0, false
+	case ']':
+		// OSC: terminated by BEL or ST (ESC \).
+		for i := 2; i < len(data); i++ {
+			if data[i] == 0x07 {
+				return i + 1, true
+			}
+			if data[i] == 0x1b && i+1 < len(data) && data[i+1] == '\\' {
+				return i + 2, true
+			}
+		}
+		return 0, false
+	default:
+		return 2, true
+	}
+}
+
+// handleEscape applies a complete escape sequence.
+func (s *screen) handleEscape(seq []byte) {
+	if len(seq) < 3 || seq[1] != '[' {
+		return
+	}
+	last := len(s.lines) - 1
+	switch seq[len(seq)-1] {
+	case 'm':
+		// Keep colors.
+		s.lines[last] = append(s.lines[last], seq...)
+	case 'J':
+		// Erase in display: treat clearing the whole screen as a fresh start.
+		if params := string(seq[2 : len(seq)-1]); params == "2" || params == "3" {
+			s.lines = [][]byte{nil}
+		}
+	case 'K':
+		s.lines[last] = nil
+	}
+}
+
+func (s *screen) newLine() {
+	s.lines = append(s.lines, nil)
+	if len(s.lines) > maxScreenLines {
+		s.lines = s.lines[len(s.lines)-maxScreenLines:]
+	}
+}
+
+// Content returns the last n lines of the screen, or all of them if n <= 0.
+func (s *screen) Content(n int) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lines := s.lines
+	if n > 0 && len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		out[i] = string(line)
+	}
+	return strings.Join(out, "\n")
+}
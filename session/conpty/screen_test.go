@@ -0,0 +1,32 @@
+package conpty
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScreen(t *testing.T) {
+	s := newScreen()
+	_, _ = s.Write([]byte("hello\r\nwor"))
+	_, _ = s.Write([]byte("ld\x1b[31mred\x1b[0m\x1b[?25l\x1b]0;title\x07\n"))
+	assert.Equal(t, "hello\nworld\x1b[31mred\x1b[0m\n", s.Content(0))
+
+	t.Run("escape sequences split across writes", func(t *testing.T) {
+		s := newScreen()
+		_, _ = s.Write([]byte("a\x1b[3"))
+		_, _ = s.Write([]byte("2mb"))
+		assert.Equal(t, "a\x1b[32mb", s.Content(0))
+	})
+
+	t.Run("redraws and clears", func(t *testing.T) {
+		s := newScreen()
+		_, _ = s.Write([]byte("progress 10%\rprogress 20%\nab\bc\n\x1b[2Jfresh"))
+		assert.Equal(t, "fresh", s.Content(0))
+
+		s = newScreen()
+		_, _ = s.Write([]byte("progress 10%\rprogress 20%\nab\bc"))
+		assert.Equal(t, "progress 20%\nac", s.Content(0))
+		assert.Equal(t, "ac", s.Content(1))
+	})
+}
@@ -0,0 +1,126 @@
+package session
+
+import (
+	"claude-squad/config"
+	"claude-squad/session/claude"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ConversationBackupInterval is how often the conversations of the instances are backed up.
+const ConversationBackupInterval = time.Minute
+
+// conversationBackupDir returns the directory the conversations of the instance created at createdAt
+// are backed up to. Like the audit log, it's named after the creation time too, since titles can be
+// reused.
+func conversationBackupDir(title string, createdAt time.Time) (string, error) {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "conversations", fmt.Sprintf("%s-%d", slug(title, 64, "instance"), createdAt.Unix())), nil
+}
+
+// BackupConversations copies the Claude conversations of the worktree of the instance that changed since
+// the last backup to its backup directory, so they outlive the worktree and Claude pruning old projects.
+func (i *Instance) BackupConversations() error {
+	// The conversations of a remote instance are on its host.
+	if !i.started || i.Paused() || i.Host != "" {
+		return nil
+	}
+	conversations, err := claude.ListConversations(i.gitWorktree.GetWorktreePath())
+	if err != nil {
+		return err
+	}
+	if len(conversations) == 0 {
+		return nil
+	}
+	dir, err := conversationBackupDir(i.Title, i.CreatedAt)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create conversation backup directory: %w", err)
+	}
+
+	for _, conversation := range conversations {
+		source, err := os.Stat(conversation.Path)
+		if err != nil {
+			continue
+		}
+		target := filepath.Join(dir, filepath.Base(conversation.Path))
+		if backup, err := os.Stat(target); err == nil && backup.Size() == source.Size() && !source.ModTime().After(backup.ModTime()) {
+			continue
+		}
+		if err := copyConversation(conversation.Path, target, source.ModTime()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreConversations copies the backed up conversations of the instance that Claude no longer has back
+// to the project of its worktree, so they can be resumed.
+func (i *Instance) restoreConversations() error {
+	if i.Host != "" {
+		return nil
+	}
+	dir, err := conversationBackupDir(i.Title, i.CreatedAt)
+	if err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read conversation backups: %w", err)
+	}
+	projectDir := claude.GetClaudeProjectPath(i.gitWorktree.GetWorktreePath())
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".jsonl") {
+			continue
+		}
+		target := filepath.Join(projectDir, entry.Name())
+		if _, err := os.Stat(target); !os.IsNotExist(err) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if err := os.MkdirAll(projectDir, 0755); err != nil {
+			return fmt.Errorf("failed to create Claude project directory: %w", err)
+		}
+		if err := copyConversation(filepath.Join(dir, entry.Name()), target, info.ModTime()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backedUpConversation returns the content of the backup of the conversation of the instance created at
+// createdAt.
+func backedUpConversation(title string, createdAt time.Time, conversation string) ([]byte, error) {
+	dir, err := conversationBackupDir(title, createdAt)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(filepath.Join(dir, conversation+".jsonl"))
+}
+
+// copyConversation copies the conversation file source to target, keeping its modification time so
+// later backups can tell whether it changed.
+func copyConversation(source, target string, modTime time.Time) error {
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return fmt.Errorf("failed to read conversation %s: %w", source, err)
+	}
+	if err := os.WriteFile(target, data, 0600); err != nil {
+		return fmt.Errorf("failed to write conversation %s: %w", target, err)
+	}
+	return os.Chtimes(target, modTime, modTime)
+}
@@ -0,0 +1,46 @@
+package session
+
+import (
+	"claude-squad/session/git"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupConversations(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	workDir := t.TempDir()
+	instance := &Instance{
+		Title:       "Fix Login",
+		Status:      Running,
+		CreatedAt:   time.Unix(1700000000, 0),
+		started:     true,
+		gitWorktree: git.NewGitWorktreeFromStorage("", workDir, "Fix Login", "fix-login", "", false),
+	}
+	source := conversationPath(workDir, "abc")
+	require.NoError(t, os.MkdirAll(filepath.Dir(source), 0755))
+	require.NoError(t, os.WriteFile(source, []byte(`{"type":"user"}`+"\n"), 0644))
+
+	require.NoError(t, instance.BackupConversations())
+	backup, err := backedUpConversation(instance.Title, instance.CreatedAt, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, `{"type":"user"}`+"\n", string(backup))
+
+	// Only conversations that changed are copied again.
+	require.NoError(t, os.WriteFile(source, []byte(`{"type":"user"}`+"\n"+`{"type":"assistant"}`+"\n"), 0644))
+	require.NoError(t, instance.BackupConversations())
+	backup, err = backedUpConversation(instance.Title, instance.CreatedAt, "abc")
+	require.NoError(t, err)
+	assert.Contains(t, string(backup), "assistant")
+
+	// Claude pruned the project.
+	require.NoError(t, os.RemoveAll(filepath.Dir(source)))
+	require.NoError(t, instance.restoreConversations())
+	restored, err := os.ReadFile(source)
+	require.NoError(t, err)
+	assert.Equal(t, string(backup), string(restored))
+}
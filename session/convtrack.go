@@ -0,0 +1,97 @@
+package session
+
+import (
+	"claude-squad/log"
+	"claude-squad/session/claude"
+	"os"
+	"path"
+	"time"
+)
+
+// conversationCheckInterval is how often the Claude project of the worktree of an instance is checked for
+// the conversation its agent writes to.
+const conversationCheckInterval = 5 * time.Second
+
+// isClaude returns true if the program of the instance is claude.
+func (i *Instance) isClaude() bool {
+	return path.Base(programBinary(i.Program)) == "claude"
+}
+
+// watchConversations remembers when the conversations of the Claude project of the worktree were last
+// written, once the agent of the instance is started, so the conversation it writes to afterwards can be
+// told apart from the ones that were there already, e.g. copied over by a claude resume.
+func (i *Instance) watchConversations() {
+	if i.Host != "" || !i.isClaude() {
+		return
+	}
+	i.conversationTimes = conversationTimes(i.gitWorktree.GetWorktreePath())
+	i.conversationCheckedAt = time.Now()
+}
+
+// TrackConversation records the session ID of the Claude conversation the agent of the instance writes
+// to, the one of the project of its worktree that changed last since its agent started, so resuming the
+// instance resumes that conversation. The project is checked every few seconds at most.
+func (i *Instance) TrackConversation() {
+	if !i.started || i.Paused() || i.conversationTimes == nil {
+		return
+	}
+	if time.Since(i.conversationCheckedAt) < conversationCheckInterval {
+		return
+	}
+	i.trackConversation()
+}
+
+func (i *Instance) trackConversation() {
+	if i.conversationTimes == nil {
+		return
+	}
+	i.conversationCheckedAt = time.Now()
+	var latest string
+	var latestTime time.Time
+	for id, modTime := range conversationTimes(i.gitWorktree.GetWorktreePath()) {
+		if !modTime.After(i.conversationTimes[id]) {
+			continue
+		}
+		if modTime.After(latestTime) {
+			latest, latestTime = id, modTime
+		}
+	}
+	if latest != "" && latest != i.ClaudeSessionID {
+		log.InfoLog.Printf("%s writes to the claude conversation %s", i.Title, latest)
+		i.ClaudeSessionID = latest
+	}
+}
+
+// conversationTimes returns when the conversations of the Claude project at path were last written, by
+// session ID.
+func conversationTimes(path string) map[string]time.Time {
+	times := make(map[string]time.Time)
+	conversations, err := claude.ListConversations(path)
+	if err != nil {
+		log.WarningLog.Printf("failed to list the conversations of %s: %v", path, err)
+		return times
+	}
+	for _, conversation := range conversations {
+		if info, err := os.Stat(conversation.Path); err == nil {
+			times[conversation.SessionID] = info.ModTime()
+		}
+	}
+	return times
+}
+
+// resumeProgram returns the program to resume the instance with: claude resumes the conversation the
+// instance last wrote to, if it's still there or was backed up.
+func (i *Instance) resumeProgram() string {
+	if i.ClaudeSessionID == "" || i.Host != "" || !i.isClaude() {
+		return i.Program
+	}
+	// Claude may have pruned the conversations of the project since.
+	if err := i.restoreConversations(); err != nil {
+		log.WarningLog.Printf("failed to restore the conversations of %s: %v", i.Title, err)
+	}
+	if _, err := os.Stat(conversationPath(i.gitWorktree.GetWorktreePath(), i.ClaudeSessionID)); err != nil {
+		log.WarningLog.Printf("the claude conversation %s of %s is gone, starting a new one", i.ClaudeSessionID, i.Title)
+		return i.Program
+	}
+	return i.Program + " --resume " + i.ClaudeSessionID
+}
@@ -0,0 +1,62 @@
+package session
+
+import (
+	"claude-squad/session/git"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrackConversation(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	workDir := t.TempDir()
+	instance := &Instance{
+		Title:       "Fix Login",
+		Program:     "claude",
+		Status:      Running,
+		CreatedAt:   time.Unix(1700000000, 0),
+		started:     true,
+		gitWorktree: git.NewGitWorktreeFromStorage("", workDir, "Fix Login", "fix-login", "", false),
+	}
+	writeConversation := func(id string, modTime time.Time) {
+		path := conversationPath(workDir, id)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(`{"type":"user"}`+"\n"), 0644))
+		require.NoError(t, os.Chtimes(path, modTime, modTime))
+	}
+	track := func() {
+		instance.conversationCheckedAt = time.Time{}
+		instance.TrackConversation()
+	}
+	start := time.Now()
+
+	// The conversations copied over before the agent started aren't the one it writes to.
+	writeConversation("copied", start.Add(-time.Minute))
+	instance.watchConversations()
+	track()
+	assert.Empty(t, instance.ClaudeSessionID)
+
+	writeConversation("new", start.Add(time.Second))
+	track()
+	assert.Equal(t, "new", instance.ClaudeSessionID)
+	writeConversation("copied", start.Add(2*time.Second))
+	track()
+	assert.Equal(t, "copied", instance.ClaudeSessionID)
+
+	// The conversation is kept in the state, and resumed.
+	data := instance.ToInstanceData()
+	assert.Equal(t, "copied", data.ClaudeSessionID)
+	assert.Equal(t, "claude --resume copied", instance.resumeProgram())
+
+	// A conversation that is gone and wasn't backed up can't be resumed.
+	require.NoError(t, os.Remove(conversationPath(workDir, "copied")))
+	assert.Equal(t, "claude", instance.resumeProgram())
+
+	// Other programs don't have conversations.
+	instance.Program = "aider"
+	assert.Equal(t, "aider", instance.resumeProgram())
+}
@@ -0,0 +1,60 @@
+package session
+
+import (
+	"claude-squad/config"
+	"fmt"
+)
+
+// DiffSize is how the diff of an instance compares to the diff budget.
+type DiffSize int
+
+const (
+	// DiffWithinBudget is a diff under the warn limit, or an instance without a diff or a budget.
+	DiffWithinBudget DiffSize = iota
+	// DiffWarned is a diff past the warn limit.
+	DiffWarned
+	// DiffFlagged is a diff past the flag limit.
+	DiffFlagged
+)
+
+// CheckDiffSize returns how the number of changed lines, added and removed, compares to the diff budget.
+func CheckDiffSize(budget config.DiffBudget, changed int) DiffSize {
+	switch {
+	case budget.FlagLines > 0 && changed > budget.FlagLines:
+		return DiffFlagged
+	case budget.WarnLines > 0 && changed > budget.WarnLines:
+		return DiffWarned
+	default:
+		return DiffWithinBudget
+	}
+}
+
+// DiffSize returns how the last computed diff of the instance compares to the diff budget.
+func (i *Instance) DiffSize(budget config.DiffBudget) DiffSize {
+	stats := i.diffStats
+	if stats == nil || stats.Error != nil {
+		return DiffWithinBudget
+	}
+	return CheckDiffSize(budget, stats.Added+stats.Removed)
+}
+
+// CheckDiffBudget sends the split prompt to the instance once its diff is flagged, if the budget asks for
+// it. The prompt is sent again only after the diff went back under the warn limit.
+func (i *Instance) CheckDiffBudget(budget config.DiffBudget) (bool, error) {
+	if !i.started || i.Paused() || i.Status == Gone {
+		return false, nil
+	}
+	size := i.DiffSize(budget)
+	if size == DiffWithinBudget {
+		i.SplitRequested = false
+	}
+	if size != DiffFlagged || !budget.SplitOnFlag || i.SplitRequested {
+		return false, nil
+	}
+	// The prompt is sent once, even if it fails, so a broken session isn't sent it on every tick.
+	i.SplitRequested = true
+	if err := i.SendPrompt(budget.GetSplitPrompt()); err != nil {
+		return true, fmt.Errorf("failed to send the split prompt to instance '%s': %w", i.Title, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,66 @@
+package session
+
+import (
+	"claude-squad/config"
+	"claude-squad/session/git"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckDiffSize(t *testing.T) {
+	budget := config.DiffBudget{WarnLines: 800, FlagLines: 2000}
+	assert.Equal(t, DiffWithinBudget, CheckDiffSize(budget, 800))
+	assert.Equal(t, DiffWarned, CheckDiffSize(budget, 801))
+	assert.Equal(t, DiffFlagged, CheckDiffSize(budget, 2001))
+	// Without limits, no diff is too large.
+	assert.Equal(t, DiffWithinBudget, CheckDiffSize(config.DiffBudget{}, 100000))
+	// Each limit works on its own.
+	assert.Equal(t, DiffFlagged, CheckDiffSize(config.DiffBudget{FlagLines: 10}, 11))
+}
+
+func TestCheckDiffBudget(t *testing.T) {
+	// The prompts are queued while the instance waits for its resources, which shows what was sent.
+	instance := &Instance{Title: "refactor", Status: Running, started: true,
+		tmuxSession:       newTerminal("refactor", "claude", t.TempDir()),
+		ExternalResources: []string{"staging-db"}, WaitingForResources: true}
+	budget := config.DiffBudget{WarnLines: 800, FlagLines: 2000, SplitOnFlag: true, SplitPrompt: "split it"}
+
+	instance.diffStats = &git.DiffStats{Added: 900, Removed: 100}
+	assert.Equal(t, DiffWarned, instance.DiffSize(budget))
+	sent, err := instance.CheckDiffBudget(budget)
+	require.NoError(t, err)
+	assert.False(t, sent)
+	assert.Empty(t, instance.QueuedPrompts)
+
+	// The split prompt is sent once, when the diff is flagged.
+	instance.diffStats = &git.DiffStats{Added: 1800, Removed: 300}
+	sent, err = instance.CheckDiffBudget(budget)
+	require.NoError(t, err)
+	assert.True(t, sent)
+	assert.Equal(t, []string{"split it"}, instance.QueuedPrompts)
+	sent, err = instance.CheckDiffBudget(budget)
+	require.NoError(t, err)
+	assert.False(t, sent)
+	assert.True(t, instance.ToInstanceData().SplitRequested)
+
+	// It's sent again once the diff went back under the warn limit and grew past the flag limit again.
+	instance.diffStats = &git.DiffStats{Added: 10}
+	_, err = instance.CheckDiffBudget(budget)
+	require.NoError(t, err)
+	assert.False(t, instance.SplitRequested)
+	instance.diffStats = &git.DiffStats{Added: 2500}
+	sent, err = instance.CheckDiffBudget(budget)
+	require.NoError(t, err)
+	assert.True(t, sent)
+	assert.Len(t, instance.QueuedPrompts, 2)
+
+	// Without split_on_flag, a flagged instance is only marked.
+	budget.SplitOnFlag = false
+	instance.SplitRequested = false
+	sent, err = instance.CheckDiffBudget(budget)
+	require.NoError(t, err)
+	assert.False(t, sent)
+	assert.Equal(t, DiffFlagged, instance.DiffSize(budget))
+}
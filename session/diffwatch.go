@@ -0,0 +1,71 @@
+package session
+
+import (
+	"claude-squad/config"
+	"claude-squad/log"
+	"time"
+)
+
+// diffStatsMaxAge is how often the diff stats are recomputed even if no file changed, in case the
+// watcher missed changes, e.g. because the system ran out of inotify watches.
+const diffStatsMaxAge = 30 * time.Second
+
+// remoteDiffInterval is how often the diff stats of a remote instance are recomputed, since its worktree
+// can't be watched and every diff is a round trip to its host.
+const remoteDiffInterval = 5 * time.Second
+
+// RefreshDiffStats updates the diff stats if the files of the worktree changed since they were last
+// computed. The watcher is started on the first call; if it can't be, they are recomputed every time.
+func (i *Instance) RefreshDiffStats() error {
+	if !i.started || i.Paused() {
+		return i.UpdateDiffStats()
+	}
+	if i.Host != "" {
+		if time.Since(i.diffStatsAt) < remoteDiffInterval {
+			return nil
+		}
+		return i.UpdateDiffStats()
+	}
+
+	if i.diffWatcher == nil && !i.diffPolling {
+		watcher, err := i.gitWorktree.NewWatcher()
+		if err != nil {
+			log.WarningLog.Printf("could not watch worktree of %s, polling its diff: %v", i.Title, err)
+			i.diffPolling = true
+		}
+		i.diffWatcher = watcher
+	}
+	if i.diffWatcher == nil {
+		return i.UpdateDiffStats()
+	}
+
+	now := time.Now()
+	if !i.diffWatcher.Changed(now) && now.Sub(i.diffStatsAt) < diffStatsMaxAge {
+		return nil
+	}
+	return i.UpdateDiffStats()
+}
+
+// loadDiffPaths limits the diff and the watcher of the worktree to the diff paths of the repository, if
+// it has any. The diffs of remote instances aren't limited.
+func (i *Instance) loadDiffPaths() {
+	if i.Host != "" || i.gitWorktree == nil {
+		return
+	}
+	repoConfig, err := config.LoadRepoConfig(i.gitWorktree.GetRepoPath())
+	if err != nil {
+		log.WarningLog.Printf("could not load the diff paths of %s: %v", i.Title, err)
+		return
+	}
+	i.gitWorktree.SetDiffPaths(NormalizeScopes(repoConfig.DiffPaths))
+}
+
+func (i *Instance) closeDiffWatcher() {
+	if i.diffWatcher == nil {
+		return
+	}
+	if err := i.diffWatcher.Close(); err != nil {
+		log.WarningLog.Printf("could not close worktree watcher of %s: %v", i.Title, err)
+	}
+	i.diffWatcher = nil
+}
@@ -0,0 +1,134 @@
+package session
+
+import (
+	"claude-squad/session/git"
+	"claude-squad/session/tmux"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// OrphanTerminal is a terminal session of claude-squad that no instance owns, e.g. one left behind by a
+// crash, or by an instance removed from the state by hand.
+type OrphanTerminal struct {
+	Name string
+	// Path is the directory the session was started in, the worktree of its instance.
+	Path string
+}
+
+// FindOrphanTerminals returns the terminal sessions with the prefix of claude-squad that none of the
+// instances own. The sessions of remote instances aren't looked for.
+func FindOrphanTerminals(instances []*Instance) ([]OrphanTerminal, error) {
+	owned := make(map[string]bool)
+	for _, instance := range instances {
+		if instance.Host == "" {
+			owned[instance.TerminalName()] = true
+		}
+	}
+	return findOrphanTerminals(owned)
+}
+
+// FindStoredOrphanTerminals is FindOrphanTerminals for the stored instances, without loading them.
+func FindStoredOrphanTerminals(instances []InstanceData) ([]OrphanTerminal, error) {
+	owned := make(map[string]bool)
+	for _, data := range instances {
+		if data.Host != "" {
+			continue
+		}
+		if data.TerminalName != "" {
+			owned[data.TerminalName] = true
+		} else {
+			owned[tmux.SessionName(tmux.TmuxPrefix, data.Title)] = true
+		}
+	}
+	return findOrphanTerminals(owned)
+}
+
+// FindOrphanWorktrees returns the directories in the worktree directory that none of the stored instances
+// has as its worktree, e.g. left behind by a crash.
+func FindOrphanWorktrees(instances []InstanceData) ([]string, error) {
+	dirs, err := git.WorktreeDirs()
+	if err != nil {
+		return nil, err
+	}
+	owned := make(map[string]bool)
+	for _, data := range instances {
+		if data.Host == "" {
+			owned[filepath.Clean(data.Worktree.WorktreePath)] = true
+		}
+	}
+	var orphans []string
+	for _, dir := range dirs {
+		if !owned[filepath.Clean(dir)] {
+			orphans = append(orphans, dir)
+		}
+	}
+	return orphans, nil
+}
+
+func findOrphanTerminals(owned map[string]bool) ([]OrphanTerminal, error) {
+	terminals, err := listTerminals(terminalPrefixes())
+	if err != nil {
+		return nil, err
+	}
+	var orphans []OrphanTerminal
+	for _, terminal := range terminals {
+		if !owned[terminal.Name] {
+			orphans = append(orphans, terminal)
+		}
+	}
+	return orphans, nil
+}
+
+// Kill kills the orphan terminal session, and the program in it.
+func (o OrphanTerminal) Kill() error {
+	return killTerminal(o.Name)
+}
+
+// Title returns the title of an instance adopting the orphan terminal session: its name without the
+// prefix.
+func (o OrphanTerminal) Title() string {
+	for _, prefix := range terminalPrefixes() {
+		if title, ok := strings.CutPrefix(o.Name, prefix); ok && title != "" {
+			return title
+		}
+	}
+	return o.Name
+}
+
+// Adopt returns a running instance of the orphan terminal session, if it runs in a worktree on a branch.
+// program is assumed to be what runs in it.
+func (o OrphanTerminal) Adopt(program string) (*Instance, error) {
+	title := o.Title()
+	worktree, err := git.NewGitWorktreeFromPath(o.Path, title)
+	if err != nil {
+		return nil, fmt.Errorf("cannot adopt %s: %w", o.Name, err)
+	}
+	now := time.Now()
+	data := InstanceData{
+		Title:        title,
+		Path:         worktree.GetRepoPath(),
+		Branch:       worktree.GetBranchName(),
+		Status:       Running,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		Program:      program,
+		TerminalName: o.Name,
+		Worktree: GitWorktreeData{
+			RepoPath:      worktree.GetRepoPath(),
+			WorktreePath:  worktree.GetWorktreePath(),
+			SessionName:   title,
+			BranchName:    worktree.GetBranchName(),
+			BaseCommitSHA: worktree.GetBaseCommitSHA(),
+			// The branch may have been created by hand, so it's kept when the instance is killed.
+			ExistingBranch: true,
+		},
+	}
+	instance, err := FromInstanceData(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot adopt %s: %w", o.Name, err)
+	}
+	instance.Audit(AuditCreated, "adopted terminal session "+o.Name)
+	return instance, nil
+}
@@ -0,0 +1,33 @@
+package session
+
+import (
+	"claude-squad/session/tmux"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTerminalName(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	require.NoError(t, os.MkdirAll(filepath.Join(home, ".claude-squad"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".claude-squad", "config.json"), []byte(`{"tmux_prefix": "cs-work_"}`), 0644))
+
+	// Instances saved before the names were stored keep the sessions named with the default prefix.
+	instance := &Instance{Title: "fix login"}
+	assert.Equal(t, tmux.TmuxPrefix+"fixlogin", instance.TerminalName())
+	instance.terminalName = instance.freeTerminalName()
+	assert.Equal(t, "cs-work_fixlogin", instance.TerminalName())
+
+	restored, err := FromInstanceData(InstanceData{Title: "fix login", Status: Paused, TerminalName: "cs-work_fixlogin_2"})
+	require.NoError(t, err)
+	assert.Equal(t, "cs-work_fixlogin_2", restored.TerminalName())
+	assert.Equal(t, "cs-work_fixlogin_2", restored.ToInstanceData().TerminalName)
+
+	assert.Equal(t, []string{"cs-work_", tmux.TmuxPrefix}, terminalPrefixes())
+	assert.Equal(t, "fixlogin_2", OrphanTerminal{Name: "cs-work_fixlogin_2"}.Title())
+	assert.Equal(t, "old", OrphanTerminal{Name: tmux.TmuxPrefix + "old"}.Title())
+}
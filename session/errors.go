@@ -0,0 +1,213 @@
+package session
+
+import (
+	"claude-squad/log"
+	"claude-squad/telemetry"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// maxErrorHistory is how many errors an instance keeps. Older ones are dropped.
+const maxErrorHistory = 20
+
+// ErrorOp is the operation of an instance an error happened in.
+type ErrorOp string
+
+const (
+	// ErrorOpWorktree is setting up the worktree of the instance, when it's resumed or recreated.
+	ErrorOpWorktree ErrorOp = "worktree setup"
+	// ErrorOpTmux is restoring or starting the terminal session of the instance.
+	ErrorOpTmux ErrorOp = "tmux restore"
+	// ErrorOpCleanup is pausing or killing the instance.
+	ErrorOpCleanup ErrorOp = "cleanup"
+	// ErrorOpDiff is computing the diff of the worktree.
+	ErrorOpDiff ErrorOp = "diff"
+	// ErrorOpPush is pushing the branch of the instance.
+	ErrorOpPush ErrorOp = "push"
+	// ErrorOpCommit is committing the changes of the instance automatically, per the commit strategy.
+	ErrorOpCommit ErrorOp = "auto-commit"
+	// ErrorOpRebase is rebasing the branch of a paused instance on the upstream base branch.
+	ErrorOpRebase ErrorOp = "rebase"
+	// ErrorOpProgram is the program of the instance exiting with a non-zero status, if its ready rule
+	// keeps it.
+	ErrorOpProgram ErrorOp = "program"
+)
+
+// ErrRestoreFailed is returned when the terminal session of a stored instance couldn't be restored. The
+// instance is kept, with its worktree and branch, so the restore can be retried.
+var ErrRestoreFailed = errors.New("failed to restore existing session")
+
+// ErrorRecord is an error in the history of an instance.
+type ErrorRecord struct {
+	Time    time.Time `json:"time"`
+	Op      ErrorOp   `json:"op"`
+	Message string    `json:"message"`
+	// Count is how many times the error happened in a row, e.g. a diff failing on every tick.
+	Count int `json:"count"`
+	// Resolved is true once a retry of the operation succeeded or the error was dismissed.
+	Resolved bool `json:"resolved,omitempty"`
+}
+
+// String returns the error as one line, e.g. "15:04:05 tmux restore: error opening PTY (3x)".
+func (r ErrorRecord) String() string {
+	s := fmt.Sprintf("%s %s: %s", r.Time.Format("15:04:05"), r.Op, r.Message)
+	if r.Count > 1 {
+		s += fmt.Sprintf(" (%dx)", r.Count)
+	}
+	return s
+}
+
+// RecordError adds the error of the operation to the history of the instance, if it isn't nil. An error
+// repeating the unresolved last one is counted instead of added again.
+func (i *Instance) RecordError(op ErrorOp, err error) {
+	if err == nil {
+		return
+	}
+	i.errorsMu.Lock()
+	defer i.errorsMu.Unlock()
+	message := RedactSecrets(err.Error(), i.secrets)
+	if n := len(i.Errors); n > 0 {
+		last := &i.Errors[n-1]
+		if !last.Resolved && last.Op == op && last.Message == message {
+			last.Count++
+			last.Time = time.Now()
+			return
+		}
+	}
+	log.ErrorLog.Printf("%s of %s failed: %s", op, i.Title, message)
+	telemetry.Error(string(op))
+	i.Errors = append(i.Errors, ErrorRecord{Time: time.Now(), Op: op, Message: message, Count: 1})
+	if len(i.Errors) > maxErrorHistory {
+		i.Errors = i.Errors[len(i.Errors)-maxErrorHistory:]
+	}
+}
+
+// ResolveErrors marks the errors of the operation as resolved, or all of them if op is empty.
+func (i *Instance) ResolveErrors(op ErrorOp) {
+	i.errorsMu.Lock()
+	defer i.errorsMu.Unlock()
+	for idx := range i.Errors {
+		if op == "" || i.Errors[idx].Op == op {
+			i.Errors[idx].Resolved = true
+		}
+	}
+}
+
+// ErrorHistory returns a copy of the errors of the instance, oldest first.
+func (i *Instance) ErrorHistory() []ErrorRecord {
+	i.errorsMu.Lock()
+	defer i.errorsMu.Unlock()
+	return append([]ErrorRecord(nil), i.Errors...)
+}
+
+// UnresolvedErrors returns how many errors of the instance aren't resolved, for the badge in the list.
+func (i *Instance) UnresolvedErrors() int {
+	i.errorsMu.Lock()
+	defer i.errorsMu.Unlock()
+	n := 0
+	for _, record := range i.Errors {
+		if !record.Resolved {
+			n++
+		}
+	}
+	return n
+}
+
+// HasUnresolved returns true if an error of the operation isn't resolved.
+func (i *Instance) HasUnresolved(op ErrorOp) bool {
+	i.errorsMu.Lock()
+	defer i.errorsMu.Unlock()
+	for _, record := range i.Errors {
+		if record.Op == op && !record.Resolved {
+			return true
+		}
+	}
+	return false
+}
+
+// WorktreeMissing returns true if the instance should have a worktree but it's gone.
+func (i *Instance) WorktreeMissing() bool {
+	return i.started && !i.Paused() && i.Host == "" && i.gitWorktree != nil && !i.gitWorktree.Exists()
+}
+
+// RetryWorktreeSetup sets up the worktree of the instance again from its branch: by resuming it if it's
+// paused, and in place otherwise.
+func (i *Instance) RetryWorktreeSetup() error {
+	if i.Paused() {
+		if err := i.Resume(); err != nil {
+			return err
+		}
+		i.ResolveErrors(ErrorOpWorktree)
+		return nil
+	}
+	if !i.WorktreeMissing() {
+		i.ResolveErrors(ErrorOpWorktree)
+		return nil
+	}
+	if checked, err := i.gitWorktree.IsBranchCheckedOut(); err != nil {
+		return fmt.Errorf("failed to check if branch is checked out: %w", err)
+	} else if checked {
+		return fmt.Errorf("cannot set up the worktree: branch %s is checked out, please switch to a different branch", i.Branch)
+	}
+	setupStart := time.Now()
+	if err := i.gitWorktree.Setup(); err != nil {
+		err = fmt.Errorf("failed to setup git worktree: %w", err)
+		i.RecordError(ErrorOpWorktree, err)
+		return err
+	}
+	i.Audit(AuditWorktree, time.Since(setupStart).Round(time.Millisecond).String())
+	i.ResolveErrors(ErrorOpWorktree)
+	return nil
+}
+
+// RetryTmuxRestore reconnects to the terminal session of the instance if it still exists, and recreates
+// it from the worktree, resuming the conversation, if it's gone.
+func (i *Instance) RetryTmuxRestore() error {
+	if i.Paused() {
+		return fmt.Errorf("%s is paused, resume it instead", i.Title)
+	}
+	if i.tmuxSession != nil && i.TmuxAlive() {
+		if err := i.tmuxSession.Restore(); err != nil {
+			err = fmt.Errorf("%w: %w", ErrRestoreFailed, err)
+			i.RecordError(ErrorOpTmux, err)
+			return err
+		}
+		i.SetStatus(Running)
+	} else if err := i.Recreate(true); err != nil {
+		return err
+	}
+	i.ResolveErrors(ErrorOpTmux)
+	return nil
+}
+
+// ForceCleanup removes whatever is left of the instance, ignoring what's in the way: the services, the
+// terminal session and its processes, and the worktree, even if it's locked or git doesn't know it
+// anymore. The teardown commands aren't run, since they may be what's failing. It returns what it couldn't
+// remove, but the instance shouldn't be used afterwards either way.
+func (i *Instance) ForceCleanup() error {
+	i.Audit(AuditKill, "force cleanup")
+	i.recordHistory(HistoryKilled)
+	i.closeDiffWatcher()
+	i.stopWarmup()
+	i.removeHookFiles()
+
+	var errs []error
+	if err := i.removeServices(); err != nil {
+		errs = append(errs, err)
+	}
+	if i.tmuxSession != nil {
+		pids := i.snapshotProcessTree()
+		if err := i.tmuxSession.Close(); err != nil && i.TmuxAlive() {
+			errs = append(errs, fmt.Errorf("failed to close tmux session: %w", err))
+		}
+		i.terminateInBackground(pids, killGracePeriod)
+	}
+	if i.gitWorktree != nil {
+		if err := i.gitWorktree.ForceCleanup(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to clean up git worktree: %w", err))
+		}
+	}
+	i.removeAuditLog()
+	return errors.Join(errs...)
+}
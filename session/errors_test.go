@@ -0,0 +1,53 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordError(t *testing.T) {
+	instance := &Instance{Title: "a", secrets: map[string]string{"API_KEY": "hunter22"}}
+	instance.RecordError(ErrorOpDiff, nil)
+	assert.Empty(t, instance.ErrorHistory())
+
+	// Repeats of the last error are counted, and secrets never make it into the history.
+	instance.RecordError(ErrorOpDiff, errors.New("bad object"))
+	instance.RecordError(ErrorOpDiff, errors.New("bad object"))
+	instance.RecordError(ErrorOpTmux, errors.New("error opening PTY with hunter22"))
+	history := instance.ErrorHistory()
+	require.Len(t, history, 2)
+	assert.Equal(t, 2, history[0].Count)
+	assert.NotContains(t, history[1].Message, "hunter22")
+	assert.Equal(t, 2, instance.UnresolvedErrors())
+	assert.True(t, instance.HasUnresolved(ErrorOpTmux))
+
+	instance.ResolveErrors(ErrorOpTmux)
+	assert.False(t, instance.HasUnresolved(ErrorOpTmux))
+	assert.Equal(t, 1, instance.UnresolvedErrors())
+
+	// Once resolved, the same error is recorded again instead of counted.
+	instance.ResolveErrors("")
+	instance.RecordError(ErrorOpDiff, errors.New("bad object"))
+	assert.Len(t, instance.ErrorHistory(), 3)
+	assert.Equal(t, 1, instance.UnresolvedErrors())
+
+	for n := 0; n < 2*maxErrorHistory; n++ {
+		instance.RecordError(ErrorOpPush, fmt.Errorf("rejected %d", n))
+	}
+	history = instance.ErrorHistory()
+	require.Len(t, history, maxErrorHistory)
+	assert.Equal(t, fmt.Sprintf("rejected %d", 2*maxErrorHistory-1), history[len(history)-1].Message)
+}
+
+func TestErrorHistoryIsStored(t *testing.T) {
+	instance := &Instance{Title: "a"}
+	instance.RecordError(ErrorOpCleanup, errors.New("worktree is locked"))
+	data := instance.ToInstanceData()
+	require.Len(t, data.Errors, 1)
+	assert.Equal(t, ErrorOpCleanup, data.Errors[0].Op)
+	assert.Contains(t, data.Errors[0].String(), "cleanup: worktree is locked")
+}
@@ -0,0 +1,49 @@
+package session
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ConversationPath returns the path of the last Claude conversation of the instance, to export it. The
+// conversation of a paused instance is still there, since Claude keeps it by the path of the worktree.
+func (i *Instance) ConversationPath() (string, error) {
+	if !i.started || i.gitWorktree == nil {
+		return "", fmt.Errorf("instance %s has not been started", i.Title)
+	}
+	if i.Host != "" {
+		return "", fmt.Errorf("the conversations of %s are on %s", i.Title, i.Host)
+	}
+	return findConversation(i.Title, i.CreatedAt, i.gitWorktree.GetWorktreePath(), latestConversation(i.gitWorktree.GetWorktreePath()))
+}
+
+// ConversationPath returns the path of the last Claude conversation of the archived instance.
+func (a ArchivedInstance) ConversationPath() (string, error) {
+	return findConversation(a.Title, a.CreatedAt, a.Worktree.WorktreePath, a.Conversation)
+}
+
+// findConversation returns the path of the conversation of the project at path, or of its backup if
+// Claude no longer has it.
+func findConversation(title string, createdAt time.Time, path string, conversation string) (string, error) {
+	if conversation == "" {
+		return "", fmt.Errorf("%s has no Claude conversation", title)
+	}
+	if candidate := conversationPath(path, conversation); isFile(candidate) {
+		return candidate, nil
+	}
+	dir, err := conversationBackupDir(title, createdAt)
+	if err != nil {
+		return "", err
+	}
+	if candidate := filepath.Join(dir, conversation+".jsonl"); isFile(candidate) {
+		return candidate, nil
+	}
+	return "", fmt.Errorf("the conversation %s of %s is gone", conversation, title)
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,96 @@
+package session
+
+import (
+	"claude-squad/config"
+	"claude-squad/log"
+	"claude-squad/session/remote"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// commandPlaceholder is replaced with the attach command in the external_terminal setting.
+const commandPlaceholder = "{{command}}"
+
+// linuxTerminals are the terminal emulators tried in turn when no terminal is configured, with the
+// arguments that precede the command to run.
+var linuxTerminals = [][]string{
+	{"x-terminal-emulator", "-e"},
+	{"gnome-terminal", "--"},
+	{"konsole", "-e"},
+	{"alacritty", "-e"},
+	{"kitty"},
+	{"wezterm", "start", "--"},
+	{"xterm", "-e"},
+}
+
+// OpenInExternalTerminal opens a new terminal window attached to the instance, so it can be used side
+// by side with the app. The terminal is the external_terminal setting, or else one found for the platform.
+func (i *Instance) OpenInExternalTerminal() error {
+	if !i.started || i.Status == Paused {
+		return fmt.Errorf("cannot open an instance that is paused or not started in a terminal")
+	}
+	attach, err := i.tmuxSession.AttachCommand()
+	if err != nil {
+		return err
+	}
+	cmd, err := externalTerminalCommand(config.LoadConfig().ExternalTerminal, attach, runtime.GOOS)
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to open terminal: %w", err)
+	}
+	// Reap the launcher, which may exit right away or when the window is closed.
+	go func() {
+		if err := cmd.Wait(); err != nil {
+			log.WarningLog.Printf("terminal for %s exited: %v", i.Title, err)
+		}
+	}()
+	return nil
+}
+
+// externalTerminalCommand returns the command that runs attach in a new terminal window. A configured
+// terminal is a shell command line in which {{command}} is replaced with the attach command, or which
+// the attach command is appended to.
+func externalTerminalCommand(terminal string, attach []string, goos string) (*exec.Cmd, error) {
+	quoted := make([]string, len(attach))
+	for idx, arg := range attach {
+		quoted[idx] = remote.QuoteArg(arg)
+	}
+	command := strings.Join(quoted, " ")
+
+	if terminal != "" {
+		if !strings.Contains(terminal, commandPlaceholder) {
+			terminal += " " + commandPlaceholder
+		}
+		return exec.Command("sh", "-c", strings.ReplaceAll(terminal, commandPlaceholder, command)), nil
+	}
+
+	switch goos {
+	case "darwin":
+		if os.Getenv("TERM_PROGRAM") == "iTerm.app" {
+			return exec.Command("osascript",
+				"-e", fmt.Sprintf(`tell application "iTerm2" to create window with default profile command %q`, command),
+				"-e", `tell application "iTerm2" to activate`), nil
+		}
+		return exec.Command("osascript",
+			"-e", fmt.Sprintf(`tell application "Terminal" to do script %q`, command),
+			"-e", `tell application "Terminal" to activate`), nil
+	case "windows":
+		return nil, fmt.Errorf("no terminal found: set external_terminal in the config (see `cs debug`)")
+	}
+
+	if terminal := os.Getenv("TERMINAL"); terminal != "" {
+		return exec.Command(terminal, append([]string{"-e"}, attach...)...), nil
+	}
+	for _, candidate := range linuxTerminals {
+		if _, err := lookPath(candidate[0]); err == nil {
+			args := append(append([]string{}, candidate[1:]...), attach...)
+			return exec.Command(candidate[0], args...), nil
+		}
+	}
+	return nil, fmt.Errorf("no terminal found: set external_terminal in the config (see `cs debug`), e.g. \"alacritty -e {{command}}\"")
+}
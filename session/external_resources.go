@@ -0,0 +1,113 @@
+package session
+
+import (
+	"claude-squad/config"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CheckExternalResources returns an error if any of the names isn't a declared external resource.
+func CheckExternalResources(declared []config.ExternalResource, names []string) error {
+	for _, name := range names {
+		if _, ok := findExternalResource(declared, name); !ok {
+			return fmt.Errorf("unknown external resource %q: declare it in external_resources", name)
+		}
+	}
+	return nil
+}
+
+func findExternalResource(declared []config.ExternalResource, name string) (config.ExternalResource, bool) {
+	for _, resource := range declared {
+		if resource.Name == name {
+			return resource, true
+		}
+	}
+	return config.ExternalResource{}, false
+}
+
+// HoldsResources returns true if the instance uses its external resources: it requires some, isn't
+// paused and isn't waiting for them.
+func (i *Instance) HoldsResources() bool {
+	return len(i.ExternalResources) > 0 && !i.Paused() && !i.WaitingForResources
+}
+
+// ResourceHolders returns the instances using each external resource.
+func ResourceHolders(instances []*Instance) map[string][]*Instance {
+	holders := make(map[string][]*Instance)
+	for _, instance := range instances {
+		if !instance.HoldsResources() {
+			continue
+		}
+		for _, name := range instance.ExternalResources {
+			holders[name] = append(holders[name], instance)
+		}
+	}
+	return holders
+}
+
+// ClaimResources makes the instance use its external resources if none of them is at capacity, and
+// sends it the prompts queued while it waited for them. It returns true if the instance holds its
+// resources. Resources that aren't declared anymore don't hold the instance back.
+func (i *Instance) ClaimResources(declared []config.ExternalResource, instances []*Instance) (bool, error) {
+	if !i.WaitingForResources {
+		return true, nil
+	}
+	if i.Paused() {
+		return false, nil
+	}
+	holders := ResourceHolders(instances)
+	for _, name := range i.ExternalResources {
+		resource, ok := findExternalResource(declared, name)
+		if ok && len(holders[name]) >= resource.GetCapacity() {
+			return false, nil
+		}
+	}
+
+	i.WaitingForResources = false
+	i.Audit(AuditResources, "claimed "+strings.Join(i.ExternalResources, ", "))
+	return true, i.sendQueuedPrompts()
+}
+
+// sendQueuedPrompts sends the prompts held back while the instance waited.
+func (i *Instance) sendQueuedPrompts() error {
+	prompts := i.QueuedPrompts
+	i.QueuedPrompts = nil
+	for k, prompt := range prompts {
+		if err := i.SendPrompt(prompt); err != nil {
+			// Keep the prompts that weren't sent, to be sent by hand.
+			i.QueuedPrompts = prompts[k:]
+			return fmt.Errorf("could not send the queued prompt to %s: %w", i.Title, err)
+		}
+	}
+	return nil
+}
+
+// DispatchWaiting gives the instances waiting for external resources the ones that are free, in the
+// order the instances were created, so each resource is used by at most its capacity of instances. It
+// returns the instances that got their resources.
+func DispatchWaiting(declared []config.ExternalResource, instances []*Instance) ([]*Instance, error) {
+	var waiting []*Instance
+	for _, instance := range instances {
+		if instance.Started() && instance.WaitingForResources && !instance.Paused() {
+			waiting = append(waiting, instance)
+		}
+	}
+	sort.SliceStable(waiting, func(a, b int) bool {
+		return waiting[a].CreatedAt.Before(waiting[b].CreatedAt)
+	})
+
+	var dispatched []*Instance
+	var errs []error
+	for _, instance := range waiting {
+		claimed, err := instance.ClaimResources(declared, instances)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if claimed {
+			dispatched = append(dispatched, instance)
+		}
+	}
+	return dispatched, errors.Join(errs...)
+}
@@ -0,0 +1,86 @@
+package session
+
+import (
+	"claude-squad/config"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckExternalResources(t *testing.T) {
+	declared := []config.ExternalResource{{Name: "staging-db"}, {Name: "android-emulator", Capacity: 2}}
+	assert.NoError(t, CheckExternalResources(declared, nil))
+	assert.NoError(t, CheckExternalResources(declared, []string{"staging-db", "android-emulator"}))
+	assert.ErrorContains(t, CheckExternalResources(declared, []string{"staging-db", "prod-db"}),
+		`unknown external resource "prod-db"`)
+}
+
+func TestDispatchWaiting(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	declared := []config.ExternalResource{{Name: "staging-db"}, {Name: "android-emulator", Capacity: 2}}
+	now := time.Now()
+	waiting := func(title string, age time.Duration, resources ...string) *Instance {
+		return &Instance{Title: title, Status: Running, started: true, CreatedAt: now.Add(-age),
+			ExternalResources: resources, WaitingForResources: true}
+	}
+
+	t.Run("oldest first up to the capacity", func(t *testing.T) {
+		newer := waiting("newer", time.Minute, "staging-db")
+		older := waiting("older", time.Hour, "staging-db")
+		instances := []*Instance{newer, older}
+
+		dispatched, err := DispatchWaiting(declared, instances)
+		require.NoError(t, err)
+		assert.Equal(t, []*Instance{older}, dispatched)
+		assert.True(t, older.HoldsResources())
+		assert.True(t, newer.WaitingForResources)
+
+		// The resource is released when the instance holding it is paused.
+		older.Status = Paused
+		dispatched, err = DispatchWaiting(declared, instances)
+		require.NoError(t, err)
+		assert.Equal(t, []*Instance{newer}, dispatched)
+	})
+
+	t.Run("capacity", func(t *testing.T) {
+		instances := []*Instance{
+			waiting("first", 3*time.Minute, "android-emulator"),
+			waiting("second", 2*time.Minute, "android-emulator"),
+			waiting("third", time.Minute, "android-emulator"),
+		}
+		dispatched, err := DispatchWaiting(declared, instances)
+		require.NoError(t, err)
+		assert.Equal(t, instances[:2], dispatched)
+	})
+
+	t.Run("all the resources have to be free", func(t *testing.T) {
+		holder := waiting("holder", time.Hour, "staging-db")
+		both := waiting("both", time.Minute, "staging-db", "android-emulator")
+		emulator := waiting("emulator", time.Second, "android-emulator")
+		instances := []*Instance{holder, both, emulator}
+
+		dispatched, err := DispatchWaiting(declared, instances)
+		require.NoError(t, err)
+		assert.Equal(t, []*Instance{holder, emulator}, dispatched)
+		assert.True(t, both.WaitingForResources)
+	})
+
+	t.Run("paused instances keep waiting", func(t *testing.T) {
+		paused := waiting("paused", time.Hour, "staging-db")
+		paused.Status = Paused
+		dispatched, err := DispatchWaiting(declared, []*Instance{paused})
+		require.NoError(t, err)
+		assert.Empty(t, dispatched)
+		assert.True(t, paused.WaitingForResources)
+	})
+}
+
+func TestSendPromptQueuesWhileWaitingForResources(t *testing.T) {
+	instance := &Instance{Title: "waiting", Status: Running, started: true,
+		tmuxSession:       newTerminal("waiting", "claude", t.TempDir()),
+		ExternalResources: []string{"staging-db"}, WaitingForResources: true}
+	require.NoError(t, instance.SendPrompt("run the migrations"))
+	assert.Equal(t, []string{"run the migrations"}, instance.QueuedPrompts)
+}
@@ -0,0 +1,32 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExternalTerminalCommand(t *testing.T) {
+	attach := []string{"tmux", "attach-session", "-t", "=claudesquad_it's"}
+
+	cmd, err := externalTerminalCommand("alacritty -e {{command}}", attach, "linux")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sh", "-c", `alacritty -e tmux attach-session -t '=claudesquad_it'\''s'`}, cmd.Args)
+
+	// The command is appended if there's no placeholder.
+	cmd, err = externalTerminalCommand("kitty", attach[:2], "linux")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sh", "-c", "kitty tmux attach-session"}, cmd.Args)
+
+	t.Setenv("TERMINAL", "foot")
+	cmd, err = externalTerminalCommand("", attach[:2], "linux")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"foot", "-e", "tmux", "attach-session"}, cmd.Args)
+
+	t.Setenv("TERM_PROGRAM", "Apple_Terminal")
+	cmd, err = externalTerminalCommand("", attach[:2], "darwin")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"osascript", "-e", `tell application "Terminal" to do script "tmux attach-session"`,
+		"-e", `tell application "Terminal" to activate`}, cmd.Args)
+}
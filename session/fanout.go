@@ -0,0 +1,66 @@
+package session
+
+import (
+	"claude-squad/config"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// maxTitleLength is the maximum length of an instance title.
+const maxTitleLength = 32
+
+var nonWordRegex = regexp.MustCompile(`[^a-z0-9]+`)
+
+// FanOutGroupName derives a fan-out group name from the first words of the prompt. The name leaves
+// room for the "-N" suffix of the instance titles.
+func FanOutGroupName(prompt string) string {
+	return slug(prompt, maxTitleLength-3, "fanout")
+}
+
+// TitleFromText derives an instance title from the first words of a text, e.g. a task description.
+func TitleFromText(text string) string {
+	return slug(text, maxTitleLength, "instance")
+}
+
+// slug lowercases the text and joins its words with dashes, cutting it to limit characters.
+func slug(text string, limit int, fallback string) string {
+	name := strings.Trim(nonWordRegex.ReplaceAllString(strings.ToLower(text), "-"), "-")
+	if len(name) > limit {
+		name = strings.Trim(name[:limit], "-")
+	}
+	if name == "" {
+		name = fallback
+	}
+	return name
+}
+
+// FanOutProviderOptions returns the options of the instances of a fan-out group, one per provider, running
+// the program of its provider with its environment.
+func FanOutProviderOptions(group string, path string, providers []config.Provider) []InstanceOptions {
+	opts := FanOutOptions(group, path, len(providers), "", nil)
+	for i, provider := range providers {
+		opts[i].Program = provider.Program
+		opts[i].Provider = provider.Name
+	}
+	return opts
+}
+
+// FanOutOptions returns the options of the instances of a fan-out group. The instances are titled after
+// the group and run the programs in turn, or defaultProgram if programs is empty.
+func FanOutOptions(group string, path string, count int, defaultProgram string, programs []string) []InstanceOptions {
+	opts := make([]InstanceOptions, 0, count)
+	for i := 0; i < count; i++ {
+		program := defaultProgram
+		if len(programs) > 0 {
+			program = programs[i%len(programs)]
+		}
+		opts = append(opts, InstanceOptions{
+			Title:   fmt.Sprintf("%s-%d", group, i+1),
+			Path:    path,
+			Program: program,
+			Group:   group,
+		})
+	}
+	return opts
+}
@@ -0,0 +1,31 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFanOutGroupName(t *testing.T) {
+	assert.Equal(t, "fix-the-flaky-tests", FanOutGroupName("Fix the flaky tests!"))
+	assert.Equal(t, "fanout", FanOutGroupName("???"))
+
+	name := FanOutGroupName("Refactor the storage layer so that it writes each instance separately")
+	assert.LessOrEqual(t, len(name), maxTitleLength-3)
+	assert.NotEqual(t, '-', name[len(name)-1])
+}
+
+func TestFanOutOptions(t *testing.T) {
+	opts := FanOutOptions("group", ".", 3, "claude", []string{"claude", "aider"})
+	assert.Len(t, opts, 3)
+	assert.Equal(t, "group-1", opts[0].Title)
+	assert.Equal(t, "claude", opts[0].Program)
+	assert.Equal(t, "aider", opts[1].Program)
+	assert.Equal(t, "claude", opts[2].Program)
+	for _, o := range opts {
+		assert.Equal(t, "group", o.Group)
+	}
+
+	opts = FanOutOptions("group", ".", 2, "claude", nil)
+	assert.Equal(t, "claude", opts[1].Program)
+}
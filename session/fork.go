@@ -0,0 +1,75 @@
+package session
+
+import (
+	"claude-squad/log"
+	"fmt"
+	"os"
+)
+
+// checkForkable returns an error if the instance the options fork can't be forked.
+func checkForkable(opts InstanceOptions) error {
+	switch {
+	case opts.Parent != nil || opts.Branch != "":
+		return fmt.Errorf("cannot fork an instance onto another branch")
+	case !opts.Fork.started || opts.Fork.Paused():
+		return fmt.Errorf("cannot fork instance '%s' that is not running", opts.Fork.Title)
+	case opts.Fork.Host != "" || opts.Host != "":
+		return fmt.Errorf("forking remote instances isn't supported")
+	}
+	return nil
+}
+
+// ForkOptions returns the options of a new instance titled title that forks the instance: it runs the
+// same program with the same settings on a new branch starting where the branch of the instance is.
+func (i *Instance) ForkOptions(title string) InstanceOptions {
+	return InstanceOptions{
+		Title:    title,
+		Path:     i.Path,
+		Program:  i.Program,
+		Scopes:   i.Scopes,
+		Teardown: i.Teardown,
+		Fork:     i,
+
+		ExternalResources: i.ExternalResources,
+		Provider:          i.Provider,
+	}
+}
+
+// forkProgram returns the program to start the instance with. A fork of a claude instance resumes a copy
+// of the conversation of the instance it forks, written to the project of its own worktree.
+func (i *Instance) forkProgram() string {
+	if i.forkOf == nil || !i.isClaude() {
+		return i.Program
+	}
+	source := i.forkOf.gitWorktree.GetWorktreePath()
+	conversation := i.forkOf.ClaudeSessionID
+	if conversation == "" {
+		conversation = latestConversation(source)
+	}
+	if conversation == "" {
+		return i.Program
+	}
+	content, err := os.ReadFile(conversationPath(source, conversation))
+	if err == nil {
+		err = writeConversation(i.gitWorktree.GetWorktreePath(), conversation, string(content), source)
+	}
+	if err != nil {
+		log.WarningLog.Printf("failed to copy the conversation of %s to its fork %s: %v", i.forkOf.Title, i.Title, err)
+		return i.Program
+	}
+	i.ClaudeSessionID = conversation
+	return i.Program + " --resume " + conversation
+}
+
+// copyForkedChanges copies the uncommitted changes of the instance this one forks to its worktree.
+func (i *Instance) copyForkedChanges() error {
+	if i.forkOf == nil {
+		return nil
+	}
+	if err := i.gitWorktree.CopyChangesFrom(i.forkOf.gitWorktree); err != nil {
+		return fmt.Errorf("failed to fork instance '%s': %w", i.forkOf.Title, err)
+	}
+	i.Audit(AuditCreated, fmt.Sprintf("forked from %s on branch %s", i.forkOf.Title, i.forkOf.Branch))
+	i.forkOf = nil
+	return nil
+}
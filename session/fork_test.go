@@ -0,0 +1,38 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForkOptions(t *testing.T) {
+	source := &Instance{
+		Title:    "fix-login",
+		Path:     t.TempDir(),
+		Program:  "claude",
+		Status:   Running,
+		Scopes:   []string{"services/auth"},
+		Teardown: []string{"make stop"},
+	}
+	opts := source.ForkOptions("fix-login-fork")
+	assert.Equal(t, "fix-login-fork", opts.Title)
+	assert.Equal(t, source.Path, opts.Path)
+	assert.Equal(t, source.Scopes, opts.Scopes)
+	assert.Equal(t, source.Teardown, opts.Teardown)
+
+	// Only running instances can be forked.
+	_, err := NewInstance(opts)
+	assert.ErrorContains(t, err, "not running")
+
+	source.started = true
+	forked, err := NewInstance(opts)
+	require.NoError(t, err)
+	assert.Same(t, source, forked.forkOf)
+	assert.Empty(t, forked.Parent)
+
+	source.Host = "build-box"
+	_, err = NewInstance(opts)
+	assert.ErrorContains(t, err, "remote")
+}
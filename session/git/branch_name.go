@@ -0,0 +1,116 @@
+package git
+
+import (
+	"claude-squad/config"
+	"fmt"
+	"os/exec"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// defaultBranchTemplate names branches after the title of the instance, following the branch prefix.
+const defaultBranchTemplate = "{{prefix}}{{slug title}}"
+
+// maxBranchSuffix is the highest suffix tried to make a branch name unique.
+const maxBranchSuffix = 99
+
+// RenderBranchName renders a branch name template for an instance title. Templates can use {{title}},
+// {{slug title}}, {{user}}, {{date}} (YYYY-MM-DD) and {{prefix}}, the configured branch prefix. An empty
+// template is the default naming: the prefix followed by the slug of the title.
+func RenderBranchName(tmpl string, prefix string, title string, now time.Time) (string, error) {
+	if tmpl == "" {
+		tmpl = defaultBranchTemplate
+	}
+	t, err := template.New("branch").Funcs(template.FuncMap{
+		"title":  func() string { return title },
+		"slug":   sanitizeBranchName,
+		"user":   config.Username,
+		"date":   func() string { return now.Format("2006-01-02") },
+		"prefix": func() string { return prefix },
+	}).Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("invalid branch template %q: %w", tmpl, err)
+	}
+
+	var name strings.Builder
+	if err := t.Execute(&name, nil); err != nil {
+		return "", fmt.Errorf("invalid branch template %q: %w", tmpl, err)
+	}
+	if err := ValidateBranchName(name.String()); err != nil {
+		return "", fmt.Errorf("branch template %q: %w", tmpl, err)
+	}
+	return name.String(), nil
+}
+
+// ValidateBranchName returns an error if name isn't a valid branch name.
+func ValidateBranchName(name string) error {
+	if name == "" {
+		return fmt.Errorf("branch name is empty")
+	}
+	if err := exec.Command("git", "check-ref-format", "--branch", name).Run(); err != nil {
+		return fmt.Errorf("%q is not a valid branch name", name)
+	}
+	return nil
+}
+
+// BranchExists returns true if the repository has a local branch named name, or a remote one.
+func BranchExists(repoPath string, name string) (bool, error) {
+	return branchExists("", repoPath, name)
+}
+
+// branchExists is BranchExists for a repository on host, or a local one if host is empty.
+func branchExists(host string, repoPath string, name string) (bool, error) {
+	output, err := gitCommand(host, repoPath, "for-each-ref", "--format=%(refname)",
+		"refs/heads/"+name, "refs/remotes/*/"+name).Output()
+	if err != nil {
+		return false, fmt.Errorf("failed to list branches: %w", err)
+	}
+	return strings.TrimSpace(string(output)) != "", nil
+}
+
+// uniqueBranchName returns name, suffixed with -2, -3... if a local or remote branch already has it.
+func uniqueBranchName(host string, repoPath string, name string) (string, error) {
+	for suffix := 1; suffix <= maxBranchSuffix; suffix++ {
+		candidate := name
+		if suffix > 1 {
+			candidate = fmt.Sprintf("%s-%d", name, suffix)
+		}
+		exists, err := branchExists(host, repoPath, candidate)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("branch %s and its suffixed variants up to -%d already exist", name, maxBranchSuffix)
+}
+
+// ListAvailableBranches returns the local branches of the repository that aren't checked out in a
+// worktree, most recently committed to first.
+func ListAvailableBranches(repoPath string) ([]string, error) {
+	output, err := exec.Command("git", "-C", repoPath, "for-each-ref", "--sort=-committerdate",
+		"--format=%(refname:short)", "refs/heads").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list branches: %w", err)
+	}
+	worktrees, err := exec.Command("git", "-C", repoPath, "worktree", "list", "--porcelain").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list worktrees: %w", err)
+	}
+	checkedOut := make(map[string]bool)
+	for _, line := range strings.Split(string(worktrees), "\n") {
+		if branch, ok := strings.CutPrefix(line, "branch refs/heads/"); ok {
+			checkedOut[branch] = true
+		}
+	}
+
+	var branches []string
+	for _, branch := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if branch != "" && !checkedOut[branch] {
+			branches = append(branches, branch)
+		}
+	}
+	return branches, nil
+}
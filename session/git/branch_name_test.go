@@ -0,0 +1,64 @@
+package git
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderBranchName(t *testing.T) {
+	now := time.Date(2025, 3, 14, 9, 26, 53, 0, time.UTC)
+
+	name, err := RenderBranchName("", "alice/", "Fix Login Bug", now)
+	require.NoError(t, err)
+	assert.Equal(t, "alice/fix-login-bug", name)
+
+	name, err = RenderBranchName("agents/{{date}}/{{slug title}}", "alice/", "Fix Login Bug", now)
+	require.NoError(t, err)
+	assert.Equal(t, "agents/2025-03-14/fix-login-bug", name)
+
+	_, err = RenderBranchName("{{slug title", "alice/", "Fix Login Bug", now)
+	assert.ErrorContains(t, err, "invalid branch template")
+	_, err = RenderBranchName("{{branch}}", "alice/", "Fix Login Bug", now)
+	assert.ErrorContains(t, err, "invalid branch template")
+	// The raw title has spaces.
+	_, err = RenderBranchName("{{prefix}}{{title}}", "alice/", "Fix Login Bug", now)
+	assert.ErrorContains(t, err, "not a valid branch name")
+}
+
+func TestBranchCollisionsAndReuse(t *testing.T) {
+	s := newStackTest(t)
+
+	// The parent's branch is taken, so the new branch is suffixed.
+	tree, branch, err := NewGitWorktree(s.repoPath, "parent")
+	require.NoError(t, err)
+	assert.Equal(t, "test/parent-2", branch)
+	assert.Equal(t, "test/parent-2", tree.GetBranchName())
+
+	s.git(s.repoPath, "branch", "feature")
+	s.commitFile(s.repoPath, "main-1", "main")
+	branches, err := ListAvailableBranches(s.repoPath)
+	require.NoError(t, err)
+	// The branches checked out in the repository and the worktrees aren't available.
+	assert.Equal(t, []string{"feature"}, branches)
+
+	_, err = NewGitWorktreeFromBranch(s.repoPath, "missing", "missing")
+	assert.ErrorContains(t, err, "branch missing does not exist")
+
+	tree, err = NewGitWorktreeFromBranch(s.repoPath, "feature work", "feature")
+	require.NoError(t, err)
+	assert.True(t, tree.IsExistingBranch())
+	require.NoError(t, tree.Setup())
+	assert.Equal(t, s.git(s.repoPath, "rev-parse", "feature"), tree.GetBaseCommitSHA())
+	assert.NoFileExists(t, filepath.Join(tree.GetWorktreePath(), "main-1"))
+
+	// The reused branch outlives the worktree.
+	require.NoError(t, tree.Cleanup())
+	assert.NoDirExists(t, tree.GetWorktreePath())
+	exists, err := BranchExists(s.repoPath, "feature")
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
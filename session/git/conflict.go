@@ -0,0 +1,163 @@
+package git
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxConflictLines caps the conflicted lines collected from the files, to keep the prompt reasonable.
+const maxConflictLines = 400
+
+// ConflictError is returned when a rebase stops on conflicts. The rebase is left in progress so the
+// conflicts can be resolved in the worktree.
+type ConflictError struct {
+	// Files are the conflicted files, relative to the worktree.
+	Files []string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("conflicts in %s", strings.Join(e.Files, ", "))
+}
+
+// IsRebaseInProgress returns true if a rebase stopped in the worktree.
+func (g *GitWorktree) IsRebaseInProgress() bool {
+	for _, dir := range []string{"rebase-merge", "rebase-apply"} {
+		output, err := g.runGitCommand(g.worktreePath, "rev-parse", "--git-path", dir)
+		if err != nil {
+			continue
+		}
+		path := strings.TrimSpace(output)
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(g.worktreePath, path)
+		}
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// ConflictedFiles returns the files with unresolved conflicts according to the index.
+func (g *GitWorktree) ConflictedFiles() ([]string, error) {
+	return g.conflictedFiles(g.worktreePath)
+}
+
+// conflictedFiles returns the files with unresolved conflicts in the worktree at path.
+func (g *GitWorktree) conflictedFiles(path string) ([]string, error) {
+	output, err := g.runGitCommand(path, "diff", "--name-only", "--diff-filter=U")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list conflicted files: %w", err)
+	}
+	var files []string
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
+// ConflictHunks returns the conflicted hunks of files, between their conflict markers, prefixed with
+// the file name and line number.
+func (g *GitWorktree) ConflictHunks(files []string) (string, error) {
+	var b strings.Builder
+	total := 0
+	for _, file := range files {
+		f, err := os.Open(filepath.Join(g.worktreePath, file))
+		if err != nil {
+			return "", fmt.Errorf("failed to read conflicted file %s: %w", file, err)
+		}
+		scanner := bufio.NewScanner(f)
+		inHunk := false
+		for lineNum := 1; scanner.Scan(); lineNum++ {
+			line := scanner.Text()
+			if strings.HasPrefix(line, "<<<<<<<") {
+				inHunk = true
+				fmt.Fprintf(&b, "%s:%d\n", file, lineNum)
+			}
+			if !inHunk {
+				continue
+			}
+			if total >= maxConflictLines {
+				break
+			}
+			b.WriteString(line + "\n")
+			total++
+			if strings.HasPrefix(line, ">>>>>>>") {
+				inHunk = false
+				b.WriteString("\n")
+			}
+		}
+		f.Close()
+		if err := scanner.Err(); err != nil {
+			return "", fmt.Errorf("failed to read conflicted file %s: %w", file, err)
+		}
+		if total >= maxConflictLines {
+			b.WriteString("... (more conflicts truncated)\n")
+			break
+		}
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// hasConflictMarkers returns true if the file still contains conflict markers.
+func (g *GitWorktree) hasConflictMarkers(file string) (bool, error) {
+	data, err := os.ReadFile(filepath.Join(g.worktreePath, file))
+	if err != nil {
+		if os.IsNotExist(err) {
+			// Resolved by deleting the file.
+			return false, nil
+		}
+		return false, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "<<<<<<<") || strings.HasPrefix(line, ">>>>>>>") {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ContinueRebase verifies that the conflicts of a stopped rebase are resolved, stages the resolutions,
+// and continues the rebase. It returns a ConflictError if the next commits conflict too.
+func (g *GitWorktree) ContinueRebase(branch string) error {
+	files, err := g.ConflictedFiles()
+	if err != nil {
+		return err
+	}
+	var unresolved []string
+	for _, file := range files {
+		conflicted, err := g.hasConflictMarkers(file)
+		if err != nil {
+			return fmt.Errorf("failed to check %s for conflict markers: %w", file, err)
+		}
+		if conflicted {
+			unresolved = append(unresolved, file)
+		}
+	}
+	if len(unresolved) > 0 {
+		return fmt.Errorf("conflicts are not resolved yet in %s", strings.Join(unresolved, ", "))
+	}
+
+	if _, err := g.runGitCommand(g.worktreePath, "add", "-A"); err != nil {
+		return fmt.Errorf("failed to stage resolved conflicts: %w", err)
+	}
+	if _, err := g.runGitCommand(g.worktreePath, "-c", "core.editor=true", "rebase", "--continue"); err != nil {
+		if files, filesErr := g.ConflictedFiles(); filesErr == nil && len(files) > 0 {
+			return &ConflictError{Files: files}
+		}
+		return fmt.Errorf("failed to continue rebase on %s: %w", branch, err)
+	}
+	return g.updateBaseCommit(branch)
+}
+
+// AbortRebase aborts a stopped rebase, leaving the worktree as it was before.
+func (g *GitWorktree) AbortRebase() error {
+	if _, err := g.runGitCommand(g.worktreePath, "rebase", "--abort"); err != nil {
+		return fmt.Errorf("failed to abort rebase: %w", err)
+	}
+	return nil
+}
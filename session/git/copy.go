@@ -0,0 +1,231 @@
+package git
+
+import (
+	"claude-squad/log"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// envFilePatterns are the names of the dotenv-style files copy_env_files copies from the repository root.
+var envFilePatterns = []string{".env", ".env.*", "*.env"}
+
+// isGlob returns true if the copy_on_create entry is a pattern rather than a path.
+func isGlob(entry string) bool {
+	return strings.ContainsAny(entry, "*?[")
+}
+
+// matchPath returns true if the slash-separated path matches the pattern, where "**" matches any number of
+// directories and the other segments match like path.Match.
+func matchPath(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for skip := 0; skip <= len(name); skip++ {
+				if matchSegments(pattern[1:], name[skip:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// excluded returns true if the path, or one of its directories, matches one of the exclusion patterns.
+func excluded(excludes []string, name string) bool {
+	for _, exclude := range excludes {
+		for dir := name; dir != "."; dir = path.Dir(dir) {
+			if matchPath(exclude, dir) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// globRoot returns the directory of the pattern before its first segment with a wildcard, which is all
+// that needs to be walked to find its matches.
+func globRoot(pattern string) string {
+	segments := strings.Split(pattern, "/")
+	var root []string
+	for _, segment := range segments[:len(segments)-1] {
+		if isGlob(segment) || segment == "**" {
+			break
+		}
+		root = append(root, segment)
+	}
+	if len(root) == 0 {
+		return "."
+	}
+	return strings.Join(root, "/")
+}
+
+// copyOnCreateFiles returns the files of the repository the copy_on_create entries select, as paths
+// relative to its root: paths of files as they are, the untracked files in directories and matching
+// patterns, since the worktree has its own copy of the tracked ones, minus the files matching the entries
+// starting with "!". With envFiles, the dotenv-style files in the root that git ignores are added.
+func (g *GitWorktree) copyOnCreateFiles(entries []string, envFiles bool) []string {
+	var includes, excludes []string
+	for _, entry := range entries {
+		entry = filepath.ToSlash(strings.TrimSpace(entry))
+		if exclude, ok := strings.CutPrefix(entry, "!"); ok {
+			excludes = append(excludes, strings.Trim(path.Clean(exclude), "/"))
+		} else if entry != "" {
+			includes = append(includes, entry)
+		}
+	}
+
+	selected := make(map[string]bool)
+	var walkRoots []string
+	var patterns []string
+	for _, include := range includes {
+		cleaned := path.Clean(include)
+		if path.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+			log.ErrorLog.Printf("Skipping copy_on_create %s: it must be a path inside the repository", include)
+			continue
+		}
+		if isGlob(cleaned) {
+			walkRoots = append(walkRoots, globRoot(cleaned))
+			patterns = append(patterns, cleaned)
+			continue
+		}
+		info, err := os.Stat(filepath.Join(g.repoPath, filepath.FromSlash(cleaned)))
+		if os.IsNotExist(err) {
+			log.InfoLog.Printf("Skipping %s (not found in source repository)", include)
+			continue
+		} else if err != nil {
+			log.ErrorLog.Printf("Error checking file %s: %v", include, err)
+			continue
+		}
+		if info.IsDir() {
+			walkRoots = append(walkRoots, cleaned)
+			patterns = append(patterns, strings.TrimPrefix(cleaned+"/**", "./"))
+			continue
+		}
+		selected[cleaned] = true
+	}
+
+	if envFiles {
+		for _, file := range g.ignoredEnvFiles() {
+			selected[file] = true
+		}
+	}
+
+	if len(walkRoots) > 0 {
+		tracked := g.trackedFiles(walkRoots)
+		for _, root := range walkRoots {
+			for _, file := range g.walkFiles(root) {
+				if tracked[file] {
+					continue
+				}
+				for _, pattern := range patterns {
+					if matchPath(pattern, file) {
+						selected[file] = true
+						break
+					}
+				}
+			}
+		}
+	}
+
+	files := make([]string, 0, len(selected))
+	for file := range selected {
+		if !excluded(excludes, file) {
+			files = append(files, file)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// walkFiles returns the files under the directory of the repository, as slash-separated paths relative to
+// its root. The .git directories are skipped, and symlinks aren't followed.
+func (g *GitWorktree) walkFiles(dir string) []string {
+	var files []string
+	root := filepath.Join(g.repoPath, filepath.FromSlash(dir))
+	err := filepath.WalkDir(root, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if entry.IsDir() && entry.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(g.repoPath, p)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		log.ErrorLog.Printf("Failed to list the files of %s to copy: %v", dir, err)
+	}
+	return files
+}
+
+// trackedFiles returns the files tracked by git under the directories, none if the repository isn't one.
+func (g *GitWorktree) trackedFiles(dirs []string) map[string]bool {
+	tracked := make(map[string]bool)
+	output, err := g.runGitCommand(g.repoPath, append([]string{"ls-files", "-z", "--"}, dirs...)...)
+	if err != nil {
+		return tracked
+	}
+	for _, file := range strings.Split(output, "\x00") {
+		if file != "" {
+			tracked[file] = true
+		}
+	}
+	return tracked
+}
+
+// ignoredEnvFiles returns the dotenv-style files in the root of the repository that git ignores, such as
+// .env or .env.local.
+func (g *GitWorktree) ignoredEnvFiles() []string {
+	var candidates []string
+	for _, pattern := range envFilePatterns {
+		matches, err := filepath.Glob(filepath.Join(g.repoPath, pattern))
+		if err != nil {
+			continue
+		}
+		for _, match := range matches {
+			if info, err := os.Stat(match); err == nil && info.Mode().IsRegular() {
+				candidates = append(candidates, filepath.Base(match))
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	// check-ignore prints the ignored files among its arguments, and fails if there are none.
+	output, err := g.runGitCommand(g.repoPath, append([]string{"check-ignore", "--"}, candidates...)...)
+	if err != nil {
+		return nil
+	}
+	var ignored []string
+	for _, file := range strings.Split(output, "\n") {
+		if file = strings.TrimSpace(file); file != "" {
+			ignored = append(ignored, file)
+		}
+	}
+	return ignored
+}
@@ -0,0 +1,140 @@
+package git
+
+import (
+	"claude-squad/config"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchPath(t *testing.T) {
+	assert.True(t, matchPath("config/*.local.json", "config/db.local.json"))
+	assert.False(t, matchPath("config/*.local.json", "config/nested/db.local.json"))
+	assert.True(t, matchPath("**/.env", ".env"))
+	assert.True(t, matchPath("**/.env", "services/api/.env"))
+	assert.True(t, matchPath("fixtures/**", "fixtures/a/b.bin"))
+	assert.False(t, matchPath("fixtures/**", "other/a.bin"))
+}
+
+func TestCopyOnCreateFiles(t *testing.T) {
+	repoPath := t.TempDir()
+	write := func(name, content string) {
+		path := filepath.Join(repoPath, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	run := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = repoPath
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+	}
+	write(".gitignore", ".env*\nconfig/*.local.json\nfixtures/\n")
+	write("config/app.json", "{}")
+	run("init")
+	run("add", ".")
+	run("commit", "-m", "initial")
+
+	write(".env", "A=1")
+	write(".env.local", "B=2")
+	write("notes.env.txt", "not a dotenv file")
+	write("config/db.local.json", "{}")
+	write("config/app.local.json", "{}")
+	write("fixtures/small.bin", "small")
+	write("fixtures/cache/large.bin", "large")
+
+	g := &GitWorktree{repoPath: repoPath}
+	files := g.copyOnCreateFiles([]string{"config/*.json", "fixtures", "!fixtures/cache", "!config/app.local.json"}, true)
+	// The tracked config/app.json is in the worktree already.
+	assert.Equal(t, []string{".env", ".env.local", "config/db.local.json", "fixtures/small.bin"}, files)
+
+	// Paths of files are copied as they are, tracked or not.
+	assert.Equal(t, []string{"config/app.json"}, g.copyOnCreateFiles([]string{"config/app.json", "missing.txt"}, false))
+	assert.Empty(t, g.copyOnCreateFiles([]string{"../outside"}, false))
+}
+
+func TestCopiedFilesExcluded(t *testing.T) {
+	s := newStackTest(t)
+	require.NoError(t, os.WriteFile(filepath.Join(s.repoPath, ".env"), []byte("TOKEN=secret"), 0600))
+	require.NoError(t, config.SaveConfig(&config.Config{BranchPrefix: "test/", CopyOnCreate: []string{".env"}}))
+
+	tree, _, err := NewGitWorktree(s.repoPath, "secrets")
+	require.NoError(t, err)
+	require.NoError(t, tree.Setup())
+	worktreePath := tree.GetWorktreePath()
+	assert.FileExists(t, filepath.Join(worktreePath, ".env"))
+	assert.Equal(t, []string{".env"}, tree.CopiedFiles())
+
+	// The copy is ignored, so committing everything leaves it out.
+	require.NoError(t, os.WriteFile(filepath.Join(worktreePath, "app.go"), []byte("package app"), 0644))
+	s.git(worktreePath, "add", "-A")
+	assert.Equal(t, "A  app.go", s.git(worktreePath, "status", "--porcelain"))
+	assert.Equal(t, []string{"app.go"}, tree.Diff().ChangedFiles())
+
+	// Even staged by force, it's left out of the diff.
+	s.git(worktreePath, "add", "-f", ".env")
+	assert.Equal(t, []string{"app.go"}, tree.Diff().ChangedFiles())
+	restored := NewGitWorktreeFromStorage(s.repoPath, worktreePath, "secrets", tree.GetBranchName(), tree.GetBaseCommitSHA(), false)
+	restored.SetCopiedFiles(tree.CopiedFiles())
+	stats := restored.Diff()
+	require.NoError(t, stats.Error)
+	assert.Equal(t, []string{"app.go"}, stats.ChangedFiles())
+}
+
+func TestCopiedFilesExcludeEntries(t *testing.T) {
+	s := newStackTest(t)
+	s.commitFile(s.repoPath, ".env.example", "TOKEN=")
+	require.NoError(t, os.WriteFile(filepath.Join(s.repoPath, ".env"), []byte("TOKEN=secret"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(s.repoPath, ".env.example"), []byte("TOKEN=local"), 0600))
+	require.NoError(t, config.SaveConfig(&config.Config{BranchPrefix: "test/", CopyOnCreate: []string{".env", ".env.example"}}))
+	excludePath := filepath.Join(s.repoPath, ".git", "info", "exclude")
+	excluded := func() int {
+		data, err := os.ReadFile(excludePath)
+		require.NoError(t, err)
+		return strings.Count(string(data), "/.env\n")
+	}
+
+	first, _, err := NewGitWorktree(s.repoPath, "first")
+	require.NoError(t, err)
+	require.NoError(t, first.Setup())
+	second, _, err := NewGitWorktree(s.repoPath, "second")
+	require.NoError(t, err)
+	require.NoError(t, second.Setup())
+
+	// The file tracked in the base commit is copied, but not excluded, so its edits show in the diff.
+	assert.Equal(t, []string{".env"}, first.CopiedFiles())
+	assert.Equal(t, []string{".env.example"}, first.Diff().ChangedFiles())
+	assert.Equal(t, 2, excluded())
+
+	// The entries go with the last worktree that copied the file, so the repository doesn't keep ignoring
+	// it.
+	require.NoError(t, first.Cleanup())
+	assert.Equal(t, 1, excluded())
+	assert.Empty(t, s.git(second.GetWorktreePath(), "status", "--porcelain", "--", ".env"))
+	require.NoError(t, second.Cleanup())
+	assert.Equal(t, 0, excluded())
+	assert.Equal(t, "?? .env", s.git(s.repoPath, "status", "--porcelain", "--", ".env"))
+}
+
+func TestRemoveExcludeBlock(t *testing.T) {
+	data := "# git ls-files --others --exclude-from=.git/info/exclude\n" +
+		"# Files copied into the worktree /w/a by claude-squad\n/.env\n" +
+		"# Files copied into the worktree /w/b by claude-squad\n/.env\n" +
+		"*.log\n"
+	assert.Equal(t, "# git ls-files --others --exclude-from=.git/info/exclude\n"+
+		"# Files copied into the worktree /w/b by claude-squad\n/.env\n"+
+		"*.log\n", removeExcludeBlock(data, "Files copied into the worktree /w/a by claude-squad"))
+	assert.Equal(t, data, removeExcludeBlock(data, "Files copied into the worktree /w/c by claude-squad"))
+}
+
+func TestDiffFileset(t *testing.T) {
+	assert.Equal(t, "", diffFileset(nil, nil))
+	assert.Equal(t, `root:"api" | root:"web"`, diffFileset([]string{"api", "web"}, nil))
+	assert.Equal(t, `(all()) ~ (root:".env")`, diffFileset(nil, []string{".env"}))
+	assert.Equal(t, `(root:"api") ~ (root:".env" | root:"api/.env")`, diffFileset([]string{"api"}, []string{".env", "api/.env"}))
+}
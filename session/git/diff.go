@@ -21,18 +21,27 @@ func (d *DiffStats) IsEmpty() bool {
 	return d.Added == 0 && d.Removed == 0 && d.Content == ""
 }
 
+// ChangedFiles returns the paths of the files changed in the diff, in diff order.
+func (d *DiffStats) ChangedFiles() []string {
+	var files []string
+	for _, line := range strings.Split(d.Content, "\n") {
+		if !strings.HasPrefix(line, "diff --git ") {
+			continue
+		}
+		// The header has the form "diff --git a/<path> b/<path>". Use the destination path so that
+		// renames are attributed to the new location.
+		if idx := strings.LastIndex(line, " b/"); idx >= 0 {
+			files = append(files, strings.Trim(line[idx+len(" b/"):], `"`))
+		}
+	}
+	return files
+}
+
 // Diff returns the git diff between the worktree and the base branch along with statistics
 func (g *GitWorktree) Diff() *DiffStats {
 	stats := &DiffStats{}
 
-	// -N stages untracked files (intent to add), including them in the diff
-	_, err := g.runGitCommand(g.worktreePath, "add", "-N", ".")
-	if err != nil {
-		stats.Error = err
-		return stats
-	}
-
-	content, err := g.runGitCommand(g.worktreePath, "--no-pager", "diff", g.GetBaseCommitSHA())
+	content, err := g.vcs().Diff(g.worktreePath, g.GetBaseCommitSHA(), g.diffPaths, g.copiedFiles)
 	if err != nil {
 		stats.Error = err
 		return stats
@@ -49,3 +58,28 @@ func (g *GitWorktree) Diff() *DiffStats {
 
 	return stats
 }
+
+// SetDiffPaths limits the diff and the watcher of the worktree to the paths, relative to its root, e.g.
+// the areas of a monorepo agents work in, so changes elsewhere aren't scanned.
+func (g *GitWorktree) SetDiffPaths(paths []string) {
+	g.diffPaths = paths
+}
+
+// CopiedFiles returns the files copy_on_create copied into the worktree, relative to its root.
+func (g *GitWorktree) CopiedFiles() []string {
+	return g.copiedFiles
+}
+
+// SetCopiedFiles sets the files copy_on_create copied into the worktree, for worktrees loaded from
+// storage, so they're still left out of the diff.
+func (g *GitWorktree) SetCopiedFiles(files []string) {
+	g.copiedFiles = files
+}
+
+// diffPathspec returns the paths the diff is limited to, or the whole worktree.
+func (g *GitWorktree) diffPathspec() []string {
+	if len(g.diffPaths) == 0 {
+		return []string{"."}
+	}
+	return g.diffPaths
+}
@@ -0,0 +1,60 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangedFiles(t *testing.T) {
+	stats := &DiffStats{Content: `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -1 +1 @@
+-package old
++package main
+diff --git a/old/name.go b/new/name.go
+similarity index 100%
+rename from old/name.go
+rename to new/name.go
+diff --git a/docs/guide.md b/docs/guide.md
+new file mode 100644
+`}
+
+	assert.Equal(t, []string{"main.go", "new/name.go", "docs/guide.md"}, stats.ChangedFiles())
+	assert.Empty(t, (&DiffStats{}).ChangedFiles())
+}
+
+func TestDiffPaths(t *testing.T) {
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--allow-empty", "-m", "initial"},
+	} {
+		output, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+		require.NoError(t, err, string(output))
+	}
+	head, err := exec.Command("git", "-C", dir, "rev-parse", "HEAD").Output()
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "services", "auth"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "services", "auth", "auth.go"), []byte("package auth\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("# repo\n"), 0644))
+
+	g := &GitWorktree{worktreePath: dir, baseCommitSHA: strings.TrimSpace(string(head))}
+	stats := g.Diff()
+	require.NoError(t, stats.Error)
+	assert.ElementsMatch(t, []string{"README.md", "services/auth/auth.go"}, stats.ChangedFiles())
+
+	// Paths that don't exist yet are fine.
+	g.SetDiffPaths([]string{"services/auth", "web"})
+	stats = g.Diff()
+	require.NoError(t, stats.Error)
+	assert.Equal(t, []string{"services/auth/auth.go"}, stats.ChangedFiles())
+	assert.Equal(t, 1, stats.Added)
+}
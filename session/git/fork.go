@@ -0,0 +1,64 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// CopyChangesFrom copies the uncommitted changes of the worktree source, untracked files included, to
+// this worktree, whose branch is expected to start at the tip of the branch of source.
+func (g *GitWorktree) CopyChangesFrom(source *GitWorktree) error {
+	if g.host != "" || source.host != "" {
+		return fmt.Errorf("copying changes between remote worktrees isn't supported")
+	}
+	// The combined output of runGitCommand would mix warnings into the patch.
+	patch, err := gitCommand("", source.worktreePath, "diff", "--binary", "HEAD").Output()
+	if err != nil {
+		return fmt.Errorf("failed to get the changes of %s: %w", source.branchName, err)
+	}
+	if len(patch) > 0 {
+		patchFile, err := os.CreateTemp("", "claudesquad-fork-*.patch")
+		if err != nil {
+			return fmt.Errorf("failed to create patch file: %w", err)
+		}
+		defer os.Remove(patchFile.Name())
+		_, err = patchFile.Write(patch)
+		if closeErr := patchFile.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write patch file: %w", err)
+		}
+		if _, err := g.runGitCommand(g.worktreePath, "apply", "--binary", "--whitespace=nowarn", patchFile.Name()); err != nil {
+			return fmt.Errorf("failed to apply the changes of %s: %w", source.branchName, err)
+		}
+	}
+
+	untracked, err := gitCommand("", source.worktreePath, "ls-files", "--others", "--exclude-standard", "-z").Output()
+	if err != nil {
+		return fmt.Errorf("failed to list the untracked files of %s: %w", source.branchName, err)
+	}
+	for _, file := range strings.Split(string(untracked), "\x00") {
+		if file == "" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(source.worktreePath, file))
+		if err != nil {
+			return fmt.Errorf("failed to read untracked file %s: %w", file, err)
+		}
+		info, err := os.Stat(filepath.Join(source.worktreePath, file))
+		if err != nil {
+			return fmt.Errorf("failed to read untracked file %s: %w", file, err)
+		}
+		target := filepath.Join(g.worktreePath, file)
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", file, err)
+		}
+		if err := os.WriteFile(target, data, info.Mode().Perm()); err != nil {
+			return fmt.Errorf("failed to copy untracked file %s: %w", file, err)
+		}
+	}
+	return nil
+}
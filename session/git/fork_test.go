@@ -0,0 +1,30 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopyChangesFrom(t *testing.T) {
+	s := newStackTest(t)
+	parentPath, childPath := s.parent.GetWorktreePath(), s.child.GetWorktreePath()
+	require.NoError(t, os.WriteFile(filepath.Join(parentPath, "README"), []byte("changed readme"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(parentPath, "notes"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(parentPath, "notes", "plan.md"), []byte("plan"), 0644))
+
+	require.NoError(t, s.child.CopyChangesFrom(s.parent))
+
+	readme, err := os.ReadFile(filepath.Join(childPath, "README"))
+	require.NoError(t, err)
+	assert.Equal(t, "changed readme", string(readme))
+	plan, err := os.ReadFile(filepath.Join(childPath, "notes", "plan.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "plan", string(plan))
+	// The changes stay uncommitted in both worktrees.
+	assert.Contains(t, s.git(parentPath, "status", "--porcelain"), "README")
+	assert.Contains(t, s.git(childPath, "status", "--porcelain"), "README")
+}
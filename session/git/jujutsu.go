@@ -0,0 +1,135 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// jjWorkspace makes working copies with the workspaces of Jujutsu, in repositories colocated with jj. A
+// workspace is named after the directory of its working copy. Its working-copy commit sits on top of the
+// branch of the instance, a bookmark, which commits move forward.
+type jjWorkspace struct{}
+
+func (w jjWorkspace) Name() string {
+	return VCSJujutsu
+}
+
+// run runs jj with args in the repository or the workspace at path.
+func (w jjWorkspace) run(path string, args ...string) (string, error) {
+	cmd := exec.Command("jj", append([]string{"--repository", path, "--no-pager", "--color", "never"}, args...)...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("jj command failed: %s (%w)", output, err)
+	}
+	return string(output), nil
+}
+
+// workspaceName returns the name of the workspace of the working copy at path.
+func workspaceName(path string) string {
+	return filepath.Base(path)
+}
+
+func (w jjWorkspace) AddNew(repoPath, path, branch, base string) error {
+	if _, err := w.run(repoPath, "bookmark", "create", branch, "--revision", base); err != nil {
+		return fmt.Errorf("failed to create bookmark %s: %w", branch, err)
+	}
+	if err := w.AddExisting(repoPath, path, branch); err != nil {
+		if _, deleteErr := w.run(repoPath, "bookmark", "delete", branch); deleteErr != nil {
+			err = fmt.Errorf("%w (cleanup error: %v)", err, deleteErr)
+		}
+		return err
+	}
+	return nil
+}
+
+func (w jjWorkspace) AddExisting(repoPath, path, branch string) error {
+	if _, err := w.run(repoPath, "workspace", "add", "--name", workspaceName(path), "--revision", branch, path); err != nil {
+		return fmt.Errorf("failed to create workspace from bookmark %s: %w", branch, err)
+	}
+	return nil
+}
+
+// Remove forgets the workspace, which keeps its working-copy commit out of the log, and deletes its
+// directory. jj doesn't lock workspaces, so force doesn't change anything.
+func (w jjWorkspace) Remove(repoPath, path string, force bool) error {
+	if _, err := w.run(repoPath, "workspace", "forget", workspaceName(path)); err != nil {
+		return fmt.Errorf("failed to forget workspace %s: %w", workspaceName(path), err)
+	}
+	if err := os.RemoveAll(path); err != nil {
+		return fmt.Errorf("failed to delete workspace %s: %w", path, err)
+	}
+	return nil
+}
+
+// Prune does nothing, since Remove forgets the workspaces as it deletes them.
+func (w jjWorkspace) Prune(repoPath string) error {
+	return nil
+}
+
+// Diff snapshots the working copy, as every jj command does, and diffs it in the git format, which jj
+// writes untracked files in too.
+func (w jjWorkspace) Diff(path, base string, paths, excludes []string) (string, error) {
+	args := []string{"diff", "--git", "--from", base, "--to", "@"}
+	if fileset := diffFileset(paths, excludes); fileset != "" {
+		args = append(args, fileset)
+	}
+	return w.run(path, args...)
+}
+
+// diffFileset returns the fileset of the paths minus the excluded files, or an empty string for all the
+// files. Paths are quoted so they're taken literally.
+func diffFileset(paths, excludes []string) string {
+	quote := func(paths []string) string {
+		quoted := make([]string, len(paths))
+		for i, p := range paths {
+			quoted[i] = fmt.Sprintf("root:%q", p)
+		}
+		return strings.Join(quoted, " | ")
+	}
+	fileset := quote(paths)
+	if len(excludes) == 0 {
+		return fileset
+	}
+	if fileset == "" {
+		fileset = "all()"
+	}
+	return fmt.Sprintf("(%s) ~ (%s)", fileset, quote(excludes))
+}
+
+func (w jjWorkspace) IsDirty(path string) (bool, error) {
+	output, err := w.run(path, "diff", "--summary")
+	if err != nil {
+		return false, fmt.Errorf("failed to check workspace status: %w", err)
+	}
+	return strings.TrimSpace(output) != "", nil
+}
+
+// Commit commits the working-copy commit with the message, starting a new one on top of it, and moves
+// the bookmark of the branch to it, which exports it to git.
+func (w jjWorkspace) Commit(path, branch, message string) error {
+	if _, err := w.run(path, "commit", "--message", message); err != nil {
+		return fmt.Errorf("failed to commit changes: %w", err)
+	}
+	if _, err := w.run(path, "bookmark", "set", branch, "--revision", "@-"); err != nil {
+		return fmt.Errorf("failed to move bookmark %s: %w", branch, err)
+	}
+	return nil
+}
+
+// Snapshot returns the working-copy commit, which every jj command snapshots the changes of the workspace
+// into, so it has a description of its own rather than the message. It's a git commit too, since the
+// repository is colocated.
+func (w jjWorkspace) Snapshot(path, message string) (string, error) {
+	dirty, err := w.IsDirty(path)
+	if err != nil || !dirty {
+		return "", err
+	}
+	output, err := w.run(path, "log", "--no-graph", "--revisions", "@", "--template", "commit_id")
+	if err != nil {
+		return "", fmt.Errorf("failed to find the working-copy commit: %w", err)
+	}
+	return strings.TrimSpace(output), nil
+}
@@ -0,0 +1,125 @@
+package git
+
+import (
+	"claude-squad/config"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeJJ puts a jj on the PATH that logs its arguments, creates the directories of the workspaces it adds,
+// prints a diff and the working-copy commit from $FAKE_JJ_COMMIT, and returns the path of the log.
+func fakeJJ(t *testing.T) string {
+	bin := t.TempDir()
+	logPath := filepath.Join(bin, "jj.log")
+	script := `#!/bin/sh
+echo "$@" >> "` + logPath + `"
+case "$*" in
+*"workspace add"*) for last; do :; done; mkdir -p "$last" ;;
+*"diff --git"*) printf 'diff --git a/app.go b/app.go\n--- a/app.go\n+++ b/app.go\n@@ -1 +1,2 @@\n-old\n+new\n+more\n' ;;
+*"diff --summary"*) printf 'M app.go\n' ;;
+*"log --no-graph"*) printf '%s\n' "$FAKE_JJ_COMMIT" ;;
+esac
+`
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "jj"), []byte(script), 0755))
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return logPath
+}
+
+func TestJujutsuWorkspace(t *testing.T) {
+	s := newStackTest(t)
+	logPath := fakeJJ(t)
+
+	// A repository that isn't colocated with jj gets git worktrees.
+	tree, _, err := NewGitWorktree(s.repoPath, "plain")
+	require.NoError(t, err)
+	assert.Equal(t, "", tree.VCS())
+
+	require.NoError(t, os.Mkdir(filepath.Join(s.repoPath, ".jj"), 0755))
+	tree, branch, err := NewGitWorktree(s.repoPath, "fix login")
+	require.NoError(t, err)
+	assert.Equal(t, VCSJujutsu, tree.VCS())
+	require.NoError(t, tree.Setup())
+	assert.DirExists(t, tree.GetWorktreePath())
+
+	stats := tree.Diff()
+	require.NoError(t, stats.Error)
+	assert.Equal(t, 2, stats.Added)
+	assert.Equal(t, 1, stats.Removed)
+	assert.Equal(t, []string{"app.go"}, stats.ChangedFiles())
+
+	require.NoError(t, tree.Cleanup())
+	assert.NoDirExists(t, tree.GetWorktreePath())
+
+	name := filepath.Base(tree.GetWorktreePath())
+	data, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	log := string(data)
+	assert.Contains(t, log, "bookmark create "+branch+" --revision "+tree.GetBaseCommitSHA())
+	assert.Contains(t, log, "workspace add --name "+name+" --revision "+branch+" "+tree.GetWorktreePath())
+	assert.Contains(t, log, "diff --git --from "+tree.GetBaseCommitSHA()+" --to @")
+	assert.Contains(t, log, "workspace forget "+name)
+
+	// The backend is kept with the instance, since a paused one has no working copy to tell.
+	restored := NewGitWorktreeFromStorage(s.repoPath, tree.GetWorktreePath(), "fix login", branch, tree.GetBaseCommitSHA(), false)
+	restored.SetVCS(tree.VCS())
+	assert.Equal(t, VCSJujutsu, restored.VCS())
+
+	// The vcs setting overrides the detection.
+	require.NoError(t, config.SaveConfig(&config.Config{VCS: VCSGit}))
+	tree, _, err = NewGitWorktree(s.repoPath, "forced")
+	require.NoError(t, err)
+	assert.Equal(t, "", tree.VCS())
+	require.NoError(t, os.Remove(filepath.Join(s.repoPath, ".jj")))
+	require.NoError(t, config.SaveConfig(&config.Config{VCS: VCSJujutsu}))
+	_, _, err = NewGitWorktree(s.repoPath, "forced")
+	assert.ErrorContains(t, err, "isn't a jj repository")
+}
+
+func TestJujutsuPushAndBackup(t *testing.T) {
+	s := newStackTest(t)
+	logPath := fakeJJ(t)
+	require.NoError(t, os.Mkdir(filepath.Join(s.repoPath, ".jj"), 0755))
+	tree, branch, err := NewGitWorktree(s.repoPath, "fix login")
+	require.NoError(t, err)
+	require.NoError(t, tree.Setup())
+	// jj exports the bookmark to git, which the fake doesn't.
+	s.git(s.repoPath, "branch", branch, tree.GetBaseCommitSHA())
+
+	// gh runs where it's run from, and fails to sync a branch origin doesn't have yet.
+	bin := t.TempDir()
+	ghLog := filepath.Join(bin, "gh.log")
+	script := `#!/bin/sh
+echo "$PWD $*" >> "` + ghLog + `"
+case "$*" in
+*--source*) exit 1 ;;
+esac
+`
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "gh"), []byte(script), 0755))
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	origin := filepath.Join(t.TempDir(), "origin.git")
+	s.git(s.repoPath, "init", "--bare", origin)
+	s.git(s.repoPath, "remote", "add", "origin", origin)
+
+	// The workspace has no .git, so the branch is pushed from the repository.
+	require.NoError(t, tree.PushChanges("fix the login", false))
+	assert.Equal(t, tree.GetBaseCommitSHA(), s.git(origin, "rev-parse", branch))
+	data, err := os.ReadFile(ghLog)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), s.repoPath+" repo sync -b "+branch)
+	assert.NotContains(t, string(data), tree.GetWorktreePath())
+
+	// The backup keeps the working-copy commit jj snapshotted the changes into.
+	workingCopy := s.git(s.parent.GetWorktreePath(), "rev-parse", "HEAD")
+	t.Setenv("FAKE_JJ_COMMIT", workingCopy)
+	ref, err := tree.Backup()
+	require.NoError(t, err)
+	assert.Equal(t, workingCopy, s.git(s.repoPath, "rev-parse", ref))
+	data, err = os.ReadFile(logPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "commit --message fix the login")
+	assert.Contains(t, string(data), "log --no-graph --revisions @ --template commit_id")
+}
@@ -0,0 +1,226 @@
+package git
+
+import (
+	"claude-squad/config"
+	"claude-squad/log"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// linkConfiguredDirs symlinks the directories of link_on_create, like node_modules, from the repository
+// into the worktree, so they're shared instead of copied or rebuilt. Directories tracked by git aren't
+// linked, since the worktree has its own copy, and the links are excluded from git so they're never
+// committed.
+func (g *GitWorktree) linkConfiguredDirs() error {
+	cfg := config.LoadConfig()
+	if len(cfg.LinkOnCreate) == 0 {
+		return nil
+	}
+
+	var linked []string
+	for _, dirPath := range cfg.LinkOnCreate {
+		dirPath = filepath.Clean(dirPath)
+		if filepath.IsAbs(dirPath) || dirPath == "." || strings.HasPrefix(dirPath, "..") {
+			log.ErrorLog.Printf("Skipping link_on_create %s: it must be a path inside the repository", dirPath)
+			continue
+		}
+		srcPath := filepath.Join(g.repoPath, dirPath)
+		dstPath := filepath.Join(g.worktreePath, dirPath)
+
+		if info, err := os.Stat(srcPath); os.IsNotExist(err) {
+			log.InfoLog.Printf("Skipping %s (not found in source repository)", dirPath)
+			continue
+		} else if err != nil {
+			log.ErrorLog.Printf("Error checking directory %s: %v", dirPath, err)
+			continue
+		} else if !info.IsDir() {
+			log.ErrorLog.Printf("Skipping link_on_create %s: it's not a directory, use copy_on_create", dirPath)
+			continue
+		}
+		if tracked, err := g.runGitCommand(g.repoPath, "ls-files", "--", filepath.ToSlash(dirPath)); err != nil || tracked != "" {
+			log.ErrorLog.Printf("Skipping link_on_create %s: it's tracked by git", dirPath)
+			continue
+		}
+		if _, err := os.Lstat(dstPath); err == nil {
+			log.InfoLog.Printf("Skipping link_on_create %s: it already exists in the worktree", dirPath)
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+			log.ErrorLog.Printf("Failed to create directory for %s: %v", dirPath, err)
+			continue
+		}
+		if err := os.Symlink(srcPath, dstPath); err != nil {
+			log.ErrorLog.Printf("Failed to link %s: %v", dirPath, err)
+			continue
+		}
+		linked = append(linked, dirPath)
+		log.InfoLog.Printf("Linked %s into worktree", dirPath)
+	}
+	return g.excludeLinks(linked)
+}
+
+// excludeLinks adds the links to the exclude file of the repository. Ignore rules with a trailing slash,
+// like "node_modules/", only match directories, not the links to them.
+func (g *GitWorktree) excludeLinks(links []string) error {
+	return g.excludePaths(links, "Directories linked into worktrees by claude-squad")
+}
+
+// excludePaths adds the paths, relative to the root of the worktree, to the exclude file of the
+// repository under the comment, unless they're already in it.
+func (g *GitWorktree) excludePaths(paths []string, comment string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	excludePath, data, err := g.readExcludeFile()
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool)
+	for _, line := range strings.Split(data, "\n") {
+		existing[strings.TrimSpace(line)] = true
+	}
+
+	var added []string
+	for _, path := range paths {
+		pattern := "/" + filepath.ToSlash(path)
+		if !existing[pattern] {
+			added = append(added, pattern)
+		}
+	}
+	if len(added) == 0 {
+		return nil
+	}
+	return writeExcludeFile(excludePath, data, added, comment)
+}
+
+// copiedFilesComment is the comment above the exclude entries of the files copied into the worktree.
+func (g *GitWorktree) copiedFilesComment() string {
+	return "Files copied into the worktree " + g.worktreePath + " by claude-squad"
+}
+
+// excludeCopiedFiles adds the files copied into the worktree to the exclude file of the repository, which
+// all the worktrees share, under a comment naming the worktree. Each worktree has entries of its own, even
+// for files another one excluded already, so they're removed with it without affecting the others.
+func (g *GitWorktree) excludeCopiedFiles(files []string) error {
+	excludePath, data, err := g.readExcludeFile()
+	if err != nil {
+		return err
+	}
+	// A worktree set up again, e.g. on resume, replaces its entries.
+	data = removeExcludeBlock(data, g.copiedFilesComment())
+	var patterns []string
+	for _, file := range files {
+		patterns = append(patterns, "/"+filepath.ToSlash(file))
+	}
+	return writeExcludeFile(excludePath, data, patterns, g.copiedFilesComment())
+}
+
+// unexcludeCopiedFiles removes the exclude entries of the files copied into the worktree when it's
+// removed. The files other worktrees copied stay excluded by their own entries.
+func (g *GitWorktree) unexcludeCopiedFiles() {
+	if g.host != "" {
+		return
+	}
+	excludePath, data, err := g.readExcludeFile()
+	if err != nil {
+		log.ErrorLog.Printf("Failed to remove the copied files of %s from the exclude file: %v", g.worktreePath, err)
+		return
+	}
+	if removed := removeExcludeBlock(data, g.copiedFilesComment()); removed != data {
+		if err := writeExcludeFile(excludePath, removed, nil, ""); err != nil {
+			log.ErrorLog.Printf("Failed to remove the copied files of %s from the exclude file: %v", g.worktreePath, err)
+		}
+	}
+}
+
+// readExcludeFile returns the path of the exclude file of the repository and its content, empty if it
+// doesn't exist.
+func (g *GitWorktree) readExcludeFile() (string, string, error) {
+	// The repository is asked, since the workspaces of jj aren't git worktrees.
+	commonDir, err := g.runGitCommand(g.repoPath, "rev-parse", "--path-format=absolute", "--git-common-dir")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to find the git directory: %w", err)
+	}
+	excludePath := filepath.Join(strings.TrimSpace(commonDir), "info", "exclude")
+	data, err := os.ReadFile(excludePath)
+	if err != nil && !os.IsNotExist(err) {
+		return "", "", fmt.Errorf("failed to read %s: %w", excludePath, err)
+	}
+	return excludePath, string(data), nil
+}
+
+// writeExcludeFile writes the exclude file at excludePath with data, and the patterns under the comment
+// appended if there are any.
+func writeExcludeFile(excludePath string, data string, patterns []string, comment string) error {
+	if len(patterns) > 0 {
+		if len(data) > 0 && !strings.HasSuffix(data, "\n") {
+			data += "\n"
+		}
+		data += "# " + comment + "\n" + strings.Join(patterns, "\n") + "\n"
+	}
+	if err := os.MkdirAll(filepath.Dir(excludePath), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(excludePath), err)
+	}
+	if err := os.WriteFile(excludePath, []byte(data), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", excludePath, err)
+	}
+	return nil
+}
+
+// removeExcludeBlock removes the comment from the exclude file content data, along with the patterns
+// under it, up to the next comment or blank line.
+func removeExcludeBlock(data string, comment string) string {
+	lines := strings.SplitAfter(data, "\n")
+	var kept []string
+	inBlock := false
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "# "+comment:
+			inBlock = true
+			continue
+		case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+			inBlock = false
+		}
+		if !inBlock {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "")
+}
+
+// LinkedDirs returns the directories of link_on_create that are linked into the worktree, relative to
+// its root.
+func (g *GitWorktree) LinkedDirs() []string {
+	if g.host != "" {
+		return nil
+	}
+	var linked []string
+	for _, dirPath := range config.LoadConfig().LinkOnCreate {
+		dirPath = filepath.Clean(dirPath)
+		if g.isLink(dirPath) {
+			linked = append(linked, filepath.ToSlash(dirPath))
+		}
+	}
+	return linked
+}
+
+// isLink returns true if the path, relative to the root of the worktree, is a link to the same path in
+// the repository.
+func (g *GitWorktree) isLink(dirPath string) bool {
+	target, err := os.Readlink(filepath.Join(g.worktreePath, dirPath))
+	return err == nil && target == filepath.Join(g.repoPath, dirPath)
+}
+
+// unlinkConfiguredDirs removes the links of link_on_create from the worktree before it's removed, so
+// nothing removing the worktree can follow them into the shared directories.
+func (g *GitWorktree) unlinkConfiguredDirs() {
+	for _, dirPath := range g.LinkedDirs() {
+		if err := os.Remove(filepath.Join(g.worktreePath, filepath.FromSlash(dirPath))); err != nil {
+			log.ErrorLog.Printf("Failed to unlink %s: %v", dirPath, err)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package git
+
+import (
+	"claude-squad/config"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinkConfiguredDirs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need privileges on Windows")
+	}
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+	repoPath := filepath.Join(tempDir, "repo")
+	worktreePath := filepath.Join(tempDir, "worktree")
+	git := func(dir string, args ...string) string {
+		output, err := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...).CombinedOutput()
+		require.NoError(t, err, string(output))
+		return string(output)
+	}
+
+	require.NoError(t, os.MkdirAll(filepath.Join(repoPath, "node_modules", "left-pad"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(repoPath, "vendor"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, ".gitignore"), []byte("node_modules/\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "vendor", "lib.go"), []byte("package lib\n"), 0644))
+	git(tempDir, "init", repoPath)
+	git(repoPath, "add", ".")
+	git(repoPath, "commit", "-m", "initial")
+	git(repoPath, "worktree", "add", "-b", "test", worktreePath)
+
+	require.NoError(t, config.SaveConfig(&config.Config{
+		DefaultProgram: "claude",
+		LinkOnCreate:   []string{"node_modules", "vendor", "missing", "../outside"},
+	}))
+	g := &GitWorktree{repoPath: repoPath, worktreePath: worktreePath}
+	require.NoError(t, g.linkConfiguredDirs())
+
+	// Only the untracked directory that exists is linked.
+	target, err := os.Readlink(filepath.Join(worktreePath, "node_modules"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(repoPath, "node_modules"), target)
+	info, err := os.Lstat(filepath.Join(worktreePath, "vendor"))
+	require.NoError(t, err)
+	assert.True(t, info.IsDir())
+	assert.NoFileExists(t, filepath.Join(worktreePath, "missing"))
+	assert.Equal(t, []string{"node_modules"}, g.LinkedDirs())
+
+	// The link is never committed, and it's only excluded once.
+	assert.Empty(t, strings.TrimSpace(git(worktreePath, "status", "--porcelain")))
+	require.NoError(t, g.excludeLinks([]string{"node_modules"}))
+	exclude, err := os.ReadFile(filepath.Join(repoPath, ".git", "info", "exclude"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(exclude), "/node_modules\n"))
+
+	// Removing the worktree leaves the shared directory alone.
+	require.NoError(t, g.Remove())
+	assert.DirExists(t, filepath.Join(repoPath, "node_modules", "left-pad"))
+}
@@ -0,0 +1,100 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"claude-squad/config"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// SetProgress sets the function called with the progress of the slow steps of the setup of the worktree,
+// e.g. "submodules: Receiving objects: 45% (90/200)".
+func (g *GitWorktree) SetProgress(progress func(string)) {
+	g.progress = progress
+}
+
+func (g *GitWorktree) reportProgress(message string) {
+	if g.progress != nil {
+		g.progress(message)
+	}
+}
+
+// setupModules checks out the submodules of the worktree and pulls its Git LFS objects, if init_submodules
+// and pull_lfs are set and the repository has any, since worktrees come up without them otherwise.
+func (g *GitWorktree) setupModules() error {
+	cfg := config.LoadConfig()
+	if cfg.InitSubmodules && g.hasSubmodules() {
+		if err := g.runWithProgress("submodules", "submodule", "update", "--init", "--recursive", "--progress"); err != nil {
+			return fmt.Errorf("failed to check out the submodules: %w", err)
+		}
+	}
+	if cfg.PullLFS && g.hasLFSFiles() {
+		if _, err := g.runGitCommand(g.worktreePath, "lfs", "version"); err != nil {
+			return fmt.Errorf("failed to pull the LFS objects: git lfs isn't installed")
+		}
+		if err := g.runWithProgress("lfs", "lfs", "pull"); err != nil {
+			return fmt.Errorf("failed to pull the LFS objects: %w", err)
+		}
+	}
+	return nil
+}
+
+// hasSubmodules returns true if the worktree has submodules.
+func (g *GitWorktree) hasSubmodules() bool {
+	output, err := g.runGitCommand(g.worktreePath, "ls-files", "--", ".gitmodules")
+	return err == nil && strings.TrimSpace(output) != ""
+}
+
+// hasLFSFiles returns true if files of the worktree are stored with Git LFS.
+func (g *GitWorktree) hasLFSFiles() bool {
+	output, err := g.runGitCommand(g.worktreePath, "ls-files", "--", ":(attr:filter=lfs)")
+	return err == nil && strings.TrimSpace(output) != ""
+}
+
+// runWithProgress runs git with args in the worktree, reporting each line of its output, prefixed by
+// step, as it's printed. Progress lines that overwrite themselves with carriage returns are reported
+// each time.
+func (g *GitWorktree) runWithProgress(step string, args ...string) error {
+	cmd := gitCommand(g.host, g.worktreePath, args...)
+	var output bytes.Buffer
+	reader, writer := io.Pipe()
+	cmd.Stdout = io.MultiWriter(&output, writer)
+	cmd.Stderr = cmd.Stdout
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		scanner := bufio.NewScanner(reader)
+		scanner.Split(scanProgressLines)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				g.reportProgress(step + ": " + line)
+			}
+		}
+		// Keep draining, so git isn't blocked on a line too long for the scanner.
+		_, _ = io.Copy(io.Discard, reader)
+	}()
+
+	err := cmd.Run()
+	writer.Close()
+	wg.Wait()
+	if err != nil {
+		return fmt.Errorf("git command failed: %s (%w)", output.String(), err)
+	}
+	return nil
+}
+
+// scanProgressLines splits output into lines ended by newlines or carriage returns.
+func scanProgressLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
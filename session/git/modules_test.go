@@ -0,0 +1,88 @@
+package git
+
+import (
+	"bufio"
+	"claude-squad/config"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetupModules(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+	// Submodules are cloned from local paths here.
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "protocol.file.allow")
+	t.Setenv("GIT_CONFIG_VALUE_0", "always")
+	git := func(dir string, args ...string) string {
+		output, err := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...).CombinedOutput()
+		require.NoError(t, err, string(output))
+		return string(output)
+	}
+
+	libPath := filepath.Join(tempDir, "lib")
+	git(tempDir, "init", libPath)
+	require.NoError(t, os.WriteFile(filepath.Join(libPath, "lib.go"), []byte("package lib\n"), 0644))
+	git(libPath, "add", ".")
+	git(libPath, "commit", "-m", "initial")
+
+	repoPath := filepath.Join(tempDir, "repo")
+	git(tempDir, "init", repoPath)
+	git(repoPath, "submodule", "add", libPath, "lib")
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, ".gitattributes"), []byte("*.bin filter=lfs diff=lfs merge=lfs -text\n"), 0644))
+	git(repoPath, "add", ".")
+	git(repoPath, "commit", "-m", "initial")
+
+	newWorktree := func() *GitWorktree {
+		g, _, err := NewGitWorktree(repoPath, "fix-login")
+		require.NoError(t, err)
+		return g
+	}
+
+	// Worktrees come up without the content of their submodules by default.
+	require.NoError(t, config.SaveConfig(&config.Config{BranchPrefix: "test/"}))
+	g := newWorktree()
+	require.NoError(t, g.Setup())
+	assert.NoFileExists(t, filepath.Join(g.GetWorktreePath(), "lib", "lib.go"))
+	require.NoError(t, g.Cleanup())
+
+	require.NoError(t, config.SaveConfig(&config.Config{BranchPrefix: "test/", InitSubmodules: true, PullLFS: true}))
+	g = newWorktree()
+	var progress []string
+	g.SetProgress(func(message string) { progress = append(progress, message) })
+	require.NoError(t, g.Setup())
+	assert.FileExists(t, filepath.Join(g.GetWorktreePath(), "lib", "lib.go"))
+	require.NotEmpty(t, progress)
+	assert.True(t, strings.HasPrefix(progress[0], "submodules: "))
+	require.NoError(t, g.Cleanup())
+
+	// Files stored with LFS can't be pulled without git lfs.
+	if exec.Command("git", "lfs", "version").Run() == nil {
+		return
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "model.bin"), []byte("weights"), 0644))
+	git(repoPath, "add", ".")
+	git(repoPath, "commit", "-m", "model")
+	g = newWorktree()
+	assert.ErrorContains(t, g.Setup(), "git lfs isn't installed")
+}
+
+func TestScanProgressLines(t *testing.T) {
+	scanner := bufio.NewScanner(strings.NewReader("Receiving objects:  50% (1/2)\rReceiving objects: 100% (2/2), done.\nSubmodule path 'lib': checked out"))
+	scanner.Split(scanProgressLines)
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	assert.Equal(t, []string{
+		"Receiving objects:  50% (1/2)",
+		"Receiving objects: 100% (2/2), done.",
+		"Submodule path 'lib': checked out",
+	}, lines)
+}
@@ -0,0 +1,78 @@
+package git
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// FetchRemoteBranch fetches a branch of a remote, e.g. the branch of a colleague's pull request, into the
+// local branch of the same name in the repository at repoPath, and returns the name of that branch. ref is
+// the branch prefixed by its remote, e.g. "origin/fix-login", or just the branch, which is then fetched
+// from origin. A local branch that's behind the remote one is fast-forwarded; one that has diverged from
+// it isn't touched, and fetching fails.
+func FetchRemoteBranch(repoPath string, ref string) (string, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return "", fmt.Errorf("no remote branch given")
+	}
+	remote, branch, err := splitRemoteRef(repoPath, ref)
+	if err != nil {
+		return "", err
+	}
+	// The remote-tracking branch is updated too, so the instance's branch can be compared to it.
+	cmd := exec.Command("git", "-C", repoPath, "fetch", "--quiet", remote,
+		fmt.Sprintf("+refs/heads/%s:refs/remotes/%s/%s", branch, remote, branch))
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("failed to fetch branch %s from %s: %s (%w)", branch, remote, output, err)
+	}
+	tracking := remote + "/" + branch
+
+	exists, err := exec.Command("git", "-C", repoPath, "for-each-ref", "--format=%(refname)",
+		"refs/heads/"+branch).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to list branches: %w", err)
+	}
+	if strings.TrimSpace(string(exists)) == "" {
+		cmd = exec.Command("git", "-C", repoPath, "branch", "--track", branch, tracking)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return "", fmt.Errorf("failed to create branch %s from %s: %s (%w)", branch, tracking, output, err)
+		}
+		return branch, nil
+	}
+
+	if err := exec.Command("git", "-C", repoPath, "merge-base", "--is-ancestor", branch, tracking).Run(); err != nil {
+		return "", fmt.Errorf("local branch %s has diverged from %s: pick it with the branch picker, or delete it to start over from %s",
+			branch, tracking, tracking)
+	}
+	// update-ref doesn't refuse to move a branch checked out elsewhere, so that's checked first.
+	worktrees, err := exec.Command("git", "-C", repoPath, "worktree", "list", "--porcelain").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to list worktrees: %w", err)
+	}
+	for _, line := range strings.Split(string(worktrees), "\n") {
+		if line == "branch refs/heads/"+branch {
+			return "", fmt.Errorf("branch %s is already checked out", branch)
+		}
+	}
+	cmd = exec.Command("git", "-C", repoPath, "update-ref", "refs/heads/"+branch, tracking)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("failed to fast-forward branch %s to %s: %s (%w)", branch, tracking, output, err)
+	}
+	return branch, nil
+}
+
+// splitRemoteRef splits ref into its remote and its branch, if it starts with the name of a remote of the
+// repository, and returns origin and ref otherwise.
+func splitRemoteRef(repoPath string, ref string) (string, string, error) {
+	output, err := exec.Command("git", "-C", repoPath, "remote").Output()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to list remotes: %w", err)
+	}
+	for _, remote := range strings.Fields(string(output)) {
+		if branch, ok := strings.CutPrefix(ref, remote+"/"); ok && branch != "" {
+			return remote, branch, nil
+		}
+	}
+	return "origin", ref, nil
+}
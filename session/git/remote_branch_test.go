@@ -0,0 +1,47 @@
+package git
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchRemoteBranch(t *testing.T) {
+	s := newStackTest(t)
+	origin := filepath.Join(t.TempDir(), "origin.git")
+	s.git(s.repoPath, "init", "--bare", origin)
+	s.git(s.repoPath, "remote", "add", "upstream", origin)
+	s.git(s.repoPath, "push", "upstream", "HEAD:refs/heads/main")
+
+	// A colleague pushes a branch for review.
+	clone := filepath.Join(t.TempDir(), "clone")
+	s.git(s.repoPath, "clone", "--quiet", "--branch", "main", origin, clone)
+	s.git(clone, "checkout", "-b", "fix-login")
+	s.commitFile(clone, "login", "fix")
+	s.git(clone, "push", "origin", "fix-login")
+
+	branch, err := FetchRemoteBranch(s.repoPath, "upstream/fix-login")
+	require.NoError(t, err)
+	assert.Equal(t, "fix-login", branch)
+	assert.Equal(t, s.git(clone, "rev-parse", "HEAD"), s.git(s.repoPath, "rev-parse", "fix-login"))
+	assert.Equal(t, "upstream/fix-login", s.git(s.repoPath, "rev-parse", "--abbrev-ref", "fix-login@{upstream}"))
+
+	// Fetching it again fast-forwards the local branch.
+	s.commitFile(clone, "login-2", "fix")
+	s.git(clone, "push", "origin", "fix-login")
+	_, err = FetchRemoteBranch(s.repoPath, "upstream/fix-login")
+	require.NoError(t, err)
+	assert.Equal(t, s.git(clone, "rev-parse", "HEAD"), s.git(s.repoPath, "rev-parse", "fix-login"))
+
+	// A local branch that has diverged is left alone.
+	s.git(s.repoPath, "commit", "--allow-empty", "-m", "local")
+	s.git(s.repoPath, "branch", "-f", "fix-login", "HEAD")
+	_, err = FetchRemoteBranch(s.repoPath, "upstream/fix-login")
+	assert.ErrorContains(t, err, "diverged")
+
+	// A branch without a known remote prefix is fetched from origin, which doesn't exist here.
+	_, err = FetchRemoteBranch(s.repoPath, "fix-login")
+	assert.ErrorContains(t, err, "from origin")
+}
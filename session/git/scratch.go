@@ -0,0 +1,32 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ScratchDirName is the directory created in every worktree for the temporary output of the agent, like
+// logs and build artifacts. It's excluded from git, so it's neither in the diff nor committed.
+const ScratchDirName = ".claude-squad-scratch"
+
+// ScratchDir returns the path of the scratch directory of the worktree, or an empty string if it's on a
+// remote host.
+func (g *GitWorktree) ScratchDir() string {
+	if g.host != "" {
+		return ""
+	}
+	return filepath.Join(g.worktreePath, ScratchDirName)
+}
+
+// setupScratchDir creates the scratch directory of the worktree and excludes it from git.
+func (g *GitWorktree) setupScratchDir() error {
+	scratchDir := g.ScratchDir()
+	if scratchDir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(scratchDir, 0755); err != nil {
+		return fmt.Errorf("failed to create the scratch directory: %w", err)
+	}
+	return g.excludePaths([]string{ScratchDirName + "/"}, "Scratch directories of claude-squad worktrees")
+}
@@ -0,0 +1,54 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetupScratchDir(t *testing.T) {
+	tempDir := t.TempDir()
+	repoPath := filepath.Join(tempDir, "repo")
+	worktreePath := filepath.Join(tempDir, "worktree")
+	git := func(dir string, args ...string) string {
+		output, err := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...).CombinedOutput()
+		require.NoError(t, err, string(output))
+		return string(output)
+	}
+
+	git(tempDir, "init", repoPath)
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "main.go"), []byte("package main\n"), 0644))
+	git(repoPath, "add", ".")
+	git(repoPath, "commit", "-m", "initial")
+	git(repoPath, "worktree", "add", "-b", "test", worktreePath)
+	baseCommit := strings.TrimSpace(git(repoPath, "rev-parse", "HEAD"))
+
+	g := &GitWorktree{repoPath: repoPath, worktreePath: worktreePath, baseCommitSHA: baseCommit}
+	require.NoError(t, g.setupScratchDir())
+	assert.DirExists(t, filepath.Join(worktreePath, ScratchDirName))
+	assert.Equal(t, filepath.Join(worktreePath, ScratchDirName), g.ScratchDir())
+
+	// What the agent writes to the scratch directory is neither in the diff nor committed.
+	require.NoError(t, os.WriteFile(filepath.Join(worktreePath, ScratchDirName, "build.log"), []byte("ok\nok\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(worktreePath, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644))
+	stats := g.Diff()
+	require.NoError(t, stats.Error)
+	assert.Equal(t, 2, stats.Added)
+	assert.NotContains(t, stats.Content, "build.log")
+	git(worktreePath, "add", "-A")
+	assert.Equal(t, "main.go", strings.TrimSpace(git(worktreePath, "diff", "--cached", "--name-only")))
+
+	// The directory is only excluded once, however many worktrees are set up.
+	require.NoError(t, g.setupScratchDir())
+	exclude, err := os.ReadFile(filepath.Join(repoPath, ".git", "info", "exclude"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(exclude), "/"+ScratchDirName+"/\n"))
+
+	// A remote worktree has no scratch directory on this machine.
+	assert.Empty(t, (&GitWorktree{host: "build-box", worktreePath: worktreePath}).ScratchDir())
+}
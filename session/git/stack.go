@@ -0,0 +1,58 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// SetBaseBranch makes Setup create the worktree's branch from the tip of branch instead of HEAD, to
+// stack it on the branch of another instance.
+func (g *GitWorktree) SetBaseBranch(branch string) {
+	g.baseBranch = branch
+}
+
+// IsBehind returns true if branch has commits that the worktree's branch doesn't contain, meaning the
+// worktree needs to be restacked on it.
+func (g *GitWorktree) IsBehind(branch string) (bool, error) {
+	// The branch is gone if its instance was killed, there's nothing to restack on anymore.
+	if _, err := g.runGitCommand(g.repoPath, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch); err != nil {
+		return false, nil
+	}
+	_, err := g.runGitCommand(g.repoPath, "merge-base", "--is-ancestor", branch, g.branchName)
+	if err == nil {
+		return false, nil
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return true, nil
+	}
+	return false, fmt.Errorf("failed to compare %s with %s: %w", g.branchName, branch, err)
+}
+
+// Rebase rebases the worktree's branch on the tip of branch. Uncommitted changes are stashed and
+// reapplied. If the rebase stops on conflicts, it is left in progress and a ConflictError lists the
+// conflicted files; any other failure aborts it, leaving the worktree as it was.
+func (g *GitWorktree) Rebase(branch string) error {
+	if _, err := g.runGitCommand(g.worktreePath, "rebase", "--autostash", branch); err != nil {
+		if files, filesErr := g.ConflictedFiles(); filesErr == nil && len(files) > 0 {
+			return &ConflictError{Files: files}
+		}
+		if abortErr := g.AbortRebase(); abortErr != nil {
+			return fmt.Errorf("failed to rebase on %s: %w (%v)", branch, err, abortErr)
+		}
+		return fmt.Errorf("failed to rebase on %s: %w", branch, err)
+	}
+	return g.updateBaseCommit(branch)
+}
+
+// updateBaseCommit makes the diff of the worktree relative to the tip of branch.
+func (g *GitWorktree) updateBaseCommit(branch string) error {
+	output, err := g.runGitCommand(g.repoPath, "rev-parse", branch)
+	if err != nil {
+		return fmt.Errorf("failed to get the commit of %s: %w", branch, err)
+	}
+	g.baseCommitSHA = strings.TrimSpace(output)
+	return nil
+}
@@ -0,0 +1,124 @@
+package git
+
+import (
+	"claude-squad/config"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stackTest struct {
+	t        *testing.T
+	repoPath string
+	parent   *GitWorktree
+	child    *GitWorktree
+}
+
+// newStackTest creates a repository with a parent worktree that has one commit, and a child worktree
+// stacked on it.
+func newStackTest(t *testing.T) *stackTest {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+	require.NoError(t, config.SaveConfig(&config.Config{BranchPrefix: "test/"}))
+
+	s := &stackTest{t: t, repoPath: filepath.Join(tempDir, "repo")}
+	require.NoError(t, os.MkdirAll(s.repoPath, 0755))
+	s.git(s.repoPath, "init")
+	s.commitFile(s.repoPath, "README", "readme")
+
+	var err error
+	s.parent, _, err = NewGitWorktree(s.repoPath, "parent")
+	require.NoError(t, err)
+	require.NoError(t, s.parent.Setup())
+	s.commitFile(s.parent.GetWorktreePath(), "parent-1", "parent")
+
+	s.child, _, err = NewGitWorktree(s.repoPath, "child")
+	require.NoError(t, err)
+	s.child.SetBaseBranch(s.parent.GetBranchName())
+	require.NoError(t, s.child.Setup())
+	return s
+}
+
+func (s *stackTest) git(dir string, args ...string) string {
+	output, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+	require.NoError(s.t, err, string(output))
+	return strings.TrimSpace(string(output))
+}
+
+func (s *stackTest) commitFile(dir, name, content string) {
+	require.NoError(s.t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	s.git(dir, "add", name)
+	s.git(dir, "commit", "-m", name)
+}
+
+func TestStackedWorktree(t *testing.T) {
+	s := newStackTest(t)
+	parent, child := s.parent, s.child
+	assert.Equal(t, s.git(s.repoPath, "rev-parse", parent.GetBranchName()), child.GetBaseCommitSHA())
+	assert.FileExists(t, filepath.Join(child.GetWorktreePath(), "parent-1"))
+
+	behind, err := child.IsBehind(parent.GetBranchName())
+	require.NoError(t, err)
+	assert.False(t, behind)
+
+	// The parent moves on while the child has committed and uncommitted work.
+	s.commitFile(parent.GetWorktreePath(), "parent-2", "parent")
+	s.commitFile(child.GetWorktreePath(), "child-1", "child")
+	require.NoError(t, os.WriteFile(filepath.Join(child.GetWorktreePath(), "wip"), []byte("wip"), 0644))
+
+	behind, err = child.IsBehind(parent.GetBranchName())
+	require.NoError(t, err)
+	assert.True(t, behind)
+
+	require.NoError(t, child.Rebase(parent.GetBranchName()))
+	behind, err = child.IsBehind(parent.GetBranchName())
+	require.NoError(t, err)
+	assert.False(t, behind)
+	assert.Equal(t, s.git(s.repoPath, "rev-parse", parent.GetBranchName()), child.GetBaseCommitSHA())
+	assert.FileExists(t, filepath.Join(child.GetWorktreePath(), "parent-2"))
+	assert.FileExists(t, filepath.Join(child.GetWorktreePath(), "child-1"))
+	assert.FileExists(t, filepath.Join(child.GetWorktreePath(), "wip"))
+}
+
+func TestRebaseConflicts(t *testing.T) {
+	s := newStackTest(t)
+	parent, child := s.parent, s.child
+
+	s.commitFile(parent.GetWorktreePath(), "shared", "from parent\n")
+	s.commitFile(child.GetWorktreePath(), "shared", "from child\n")
+
+	err := child.Rebase(parent.GetBranchName())
+	var conflictErr *ConflictError
+	require.ErrorAs(t, err, &conflictErr)
+	assert.Equal(t, []string{"shared"}, conflictErr.Files)
+	assert.True(t, child.IsRebaseInProgress())
+
+	hunks, err := child.ConflictHunks(conflictErr.Files)
+	require.NoError(t, err)
+	assert.Contains(t, hunks, "shared:1")
+	assert.Contains(t, hunks, "from parent")
+	assert.Contains(t, hunks, "from child")
+
+	// Unresolved conflicts are not continued.
+	err = child.ContinueRebase(parent.GetBranchName())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not resolved yet in shared")
+
+	require.NoError(t, os.WriteFile(filepath.Join(child.GetWorktreePath(), "shared"), []byte("from both\n"), 0644))
+	require.NoError(t, child.ContinueRebase(parent.GetBranchName()))
+	assert.False(t, child.IsRebaseInProgress())
+	assert.Equal(t, s.git(s.repoPath, "rev-parse", parent.GetBranchName()), child.GetBaseCommitSHA())
+
+	content, err := os.ReadFile(filepath.Join(child.GetWorktreePath(), "shared"))
+	require.NoError(t, err)
+	assert.Equal(t, "from both\n", string(content))
+}
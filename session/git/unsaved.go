@@ -0,0 +1,166 @@
+package git
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BackupRefPrefix is where the backups of the work of killed instances are kept in their repository.
+const BackupRefPrefix = "refs/claude-squad/backups/"
+
+// UnsavedWork is the work on a worktree and its branch that removing them would lose.
+type UnsavedWork struct {
+	// Unmerged is the number of commits of the branch the base branch doesn't contain.
+	Unmerged int
+	// Unpushed is the number of commits of the branch no remote branch contains.
+	Unpushed int
+	// Dirty is true if the worktree has uncommitted changes.
+	Dirty bool
+}
+
+// Any returns true if there's any unsaved work.
+func (u UnsavedWork) Any() bool {
+	return u.Unmerged > 0 || u.Unpushed > 0 || u.Dirty
+}
+
+// String describes the unsaved work, e.g. "2 unmerged commits, 1 unpushed commit, uncommitted changes".
+func (u UnsavedWork) String() string {
+	var parts []string
+	if u.Unmerged > 0 {
+		parts = append(parts, pluralize(u.Unmerged, "unmerged commit"))
+	}
+	if u.Unpushed > 0 {
+		parts = append(parts, pluralize(u.Unpushed, "unpushed commit"))
+	}
+	if u.Dirty {
+		parts = append(parts, "uncommitted changes")
+	}
+	return strings.Join(parts, ", ")
+}
+
+func pluralize(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// UnsavedWork returns the work Cleanup would lose: the commits of the branch that aren't merged into the
+// base branch or pushed, and the uncommitted changes of the worktree. The commits of a branch that existed
+// before the worktree don't count, since Cleanup keeps it.
+func (g *GitWorktree) UnsavedWork() (UnsavedWork, error) {
+	var work UnsavedWork
+	if g.Exists() {
+		dirty, err := g.IsDirty()
+		if err != nil {
+			return work, err
+		}
+		work.Dirty = dirty
+	}
+	if g.existingBranch {
+		return work, nil
+	}
+	if _, err := g.runGitCommand(g.repoPath, "rev-parse", "--verify", "--quiet", "refs/heads/"+g.branchName); err != nil {
+		// The branch is already gone.
+		return work, nil
+	}
+
+	var err error
+	if targets := g.mergeTargets(); len(targets) > 0 {
+		work.Unmerged, err = g.countCommits(append([]string{g.branchName, "--not"}, targets...)...)
+	} else {
+		work.Unmerged, err = g.countCommits(g.revisionRange())
+	}
+	if err != nil {
+		return work, err
+	}
+
+	// A repository without remotes has nowhere to push to, which the unmerged commits already tell.
+	remotes, err := g.runGitCommand(g.repoPath, "remote")
+	if err != nil {
+		return work, fmt.Errorf("failed to list remotes: %w", err)
+	}
+	if strings.TrimSpace(remotes) != "" {
+		if work.Unpushed, err = g.countCommits(g.branchName, "--not", "--remotes"); err != nil {
+			return work, err
+		}
+	}
+	return work, nil
+}
+
+// mergeTargets returns the branches the worktree's branch is eventually merged into: the upstream base
+// branch and the local default branch, if they exist.
+func (g *GitWorktree) mergeTargets() []string {
+	var targets []string
+	locals := []string{"main", "master"}
+	if upstream, err := g.UpstreamBaseBranch(); err == nil {
+		targets = append(targets, upstream)
+		locals = append([]string{strings.TrimPrefix(upstream, "origin/")}, locals...)
+	}
+	for _, branch := range locals {
+		if branch == g.branchName {
+			continue
+		}
+		if _, err := g.runGitCommand(g.repoPath, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch); err == nil {
+			targets = append(targets, branch)
+			break
+		}
+	}
+	return targets
+}
+
+// IsMerged returns true if the branch has commits of its own and the branches it's eventually merged into
+// contain all of them. Squash and rebase merges rewrite the commits, so they aren't recognized.
+func (g *GitWorktree) IsMerged() (bool, error) {
+	if g.baseCommitSHA == "" {
+		return false, nil
+	}
+	if _, err := g.runGitCommand(g.repoPath, "rev-parse", "--verify", "--quiet", "refs/heads/"+g.branchName); err != nil {
+		return false, nil
+	}
+	targets := g.mergeTargets()
+	if len(targets) == 0 {
+		return false, nil
+	}
+	commits, err := g.countCommits(g.revisionRange())
+	if err != nil || commits == 0 {
+		return false, err
+	}
+	unmerged, err := g.countCommits(append([]string{g.branchName, "--not"}, targets...)...)
+	if err != nil {
+		return false, err
+	}
+	return unmerged == 0, nil
+}
+
+// countCommits returns the number of commits rev-list lists with the arguments.
+func (g *GitWorktree) countCommits(args ...string) (int, error) {
+	output, err := g.runGitCommand(g.repoPath, append([]string{"rev-list", "--count"}, args...)...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count the commits of %s: %w", g.branchName, err)
+	}
+	return strconv.Atoi(strings.TrimSpace(output))
+}
+
+// Backup keeps the work of the worktree's branch in a ref under BackupRefPrefix, named after the branch and
+// the time, so it survives Cleanup. The uncommitted changes of the worktree, untracked files included, are
+// kept in a commit on top of the branch without changing it. Returns the name of the ref.
+func (g *GitWorktree) Backup() (string, error) {
+	commit := g.branchName
+	if g.Exists() {
+		snapshot, err := g.vcs().Snapshot(g.worktreePath, "claude-squad backup of "+g.branchName)
+		if err != nil {
+			return "", fmt.Errorf("failed to back up the uncommitted changes: %w", err)
+		}
+		if snapshot != "" {
+			commit = snapshot
+		}
+	}
+	ref := BackupRefPrefix + g.branchName + "/" + time.Now().Format("20060102-150405")
+	if _, err := g.runGitCommand(g.repoPath, "update-ref", ref, commit); err != nil {
+		return "", fmt.Errorf("failed to create backup ref %s: %w", ref, err)
+	}
+	return ref, nil
+}
@@ -0,0 +1,76 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnsavedWork(t *testing.T) {
+	s := newStackTest(t)
+	parent := s.parent
+
+	work, err := parent.UnsavedWork()
+	require.NoError(t, err)
+	assert.Equal(t, UnsavedWork{Unmerged: 1}, work)
+	assert.Equal(t, "1 unmerged commit", work.String())
+
+	require.NoError(t, os.WriteFile(filepath.Join(parent.GetWorktreePath(), "wip"), []byte("wip"), 0644))
+	work, err = parent.UnsavedWork()
+	require.NoError(t, err)
+	assert.True(t, work.Dirty)
+
+	// Commits count as unpushed once there's a remote, until they're pushed.
+	origin := filepath.Join(t.TempDir(), "origin.git")
+	s.git(s.repoPath, "init", "--bare", origin)
+	s.git(s.repoPath, "remote", "add", "origin", origin)
+	s.git(s.repoPath, "push", "origin", "HEAD:refs/heads/main")
+	work, err = parent.UnsavedWork()
+	require.NoError(t, err)
+	assert.Equal(t, UnsavedWork{Unmerged: 1, Unpushed: 1, Dirty: true}, work)
+	assert.Equal(t, "1 unmerged commit, 1 unpushed commit, uncommitted changes", work.String())
+
+	s.git(s.repoPath, "push", "origin", parent.GetBranchName())
+	work, err = parent.UnsavedWork()
+	require.NoError(t, err)
+	assert.Equal(t, UnsavedWork{Unmerged: 1, Dirty: true}, work)
+}
+
+func TestBackup(t *testing.T) {
+	s := newStackTest(t)
+	parent := s.parent
+	require.NoError(t, os.WriteFile(filepath.Join(parent.GetWorktreePath(), "wip"), []byte("wip"), 0644))
+
+	ref, err := parent.Backup()
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(ref, BackupRefPrefix+parent.GetBranchName()+"/"))
+
+	// The branch is left alone, and the backup survives the cleanup.
+	assert.Equal(t, "parent-1", s.git(s.repoPath, "log", "-1", "--format=%s", parent.GetBranchName()))
+	require.NoError(t, parent.Cleanup())
+	assert.Equal(t, "wip", s.git(s.repoPath, "show", ref+":wip"))
+	assert.Equal(t, "parent", s.git(s.repoPath, "show", ref+":parent-1"))
+}
+
+func TestIsMerged(t *testing.T) {
+	s := newStackTest(t)
+	parent := s.parent
+
+	merged, err := parent.IsMerged()
+	require.NoError(t, err)
+	assert.False(t, merged)
+
+	// A branch without commits of its own wasn't merged, it just has nothing to merge.
+	merged, err = s.child.IsMerged()
+	require.NoError(t, err)
+	assert.False(t, merged)
+
+	s.git(s.repoPath, "merge", "--no-edit", parent.GetBranchName())
+	merged, err = parent.IsMerged()
+	require.NoError(t, err)
+	assert.True(t, merged)
+}
@@ -0,0 +1,86 @@
+package git
+
+import (
+	"claude-squad/log"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// FetchOrigin fetches the branches of the origin remote of the repository, so the worktree's branch can
+// be compared with them.
+func (g *GitWorktree) FetchOrigin() error {
+	if _, err := g.runGitCommand(g.repoPath, "fetch", "--quiet", "origin"); err != nil {
+		return fmt.Errorf("failed to fetch origin: %w", err)
+	}
+	return nil
+}
+
+// UpstreamBaseBranch returns the remote-tracking branch of the default branch of origin, e.g.
+// "origin/main", which the worktree's branch is eventually merged into.
+func (g *GitWorktree) UpstreamBaseBranch() (string, error) {
+	if output, err := g.runGitCommand(g.repoPath, "symbolic-ref", "--quiet", "--short", "refs/remotes/origin/HEAD"); err == nil {
+		return strings.TrimSpace(output), nil
+	}
+	// origin/HEAD is only set by clone, so fall back to the usual names.
+	for _, branch := range []string{"origin/main", "origin/master"} {
+		if _, err := g.runGitCommand(g.repoPath, "rev-parse", "--verify", "--quiet", "refs/remotes/"+branch); err == nil {
+			return branch, nil
+		}
+	}
+	return "", fmt.Errorf("could not find the default branch of origin")
+}
+
+// AheadBehind returns the number of commits of the worktree's branch that ref doesn't contain, and the
+// number of commits of ref the worktree's branch isn't based on.
+func (g *GitWorktree) AheadBehind(ref string) (ahead int, behind int, err error) {
+	output, err := g.runGitCommand(g.repoPath, "rev-list", "--left-right", "--count", g.branchName+"..."+ref)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to compare %s with %s: %w", g.branchName, ref, err)
+	}
+	counts := strings.Fields(output)
+	if len(counts) != 2 {
+		return 0, 0, fmt.Errorf("unexpected output comparing %s with %s: %q", g.branchName, ref, output)
+	}
+	if ahead, err = strconv.Atoi(counts[0]); err != nil {
+		return 0, 0, err
+	}
+	if behind, err = strconv.Atoi(counts[1]); err != nil {
+		return 0, 0, err
+	}
+	return ahead, behind, nil
+}
+
+// RebaseBranch rebases the worktree's branch on the tip of ref while the worktree is removed, e.g. when
+// its instance is paused, in a temporary worktree. If the rebase stops on conflicts, it's aborted, leaving
+// the branch as it was, and a ConflictError lists the conflicted files.
+func (g *GitWorktree) RebaseBranch(ref string) error {
+	dir, err := os.MkdirTemp("", "claude-squad-rebase-")
+	if err != nil {
+		return fmt.Errorf("failed to create a temporary worktree: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "worktree")
+	if _, err := g.runGitCommand(g.repoPath, "worktree", "add", "--quiet", path, g.branchName); err != nil {
+		return fmt.Errorf("failed to check out %s: %w", g.branchName, err)
+	}
+	defer func() {
+		if _, err := g.runGitCommand(g.repoPath, "worktree", "remove", "--force", path); err != nil {
+			log.WarningLog.Print(err)
+		}
+	}()
+
+	if _, err := g.runGitCommand(path, "rebase", ref); err != nil {
+		files, _ := g.conflictedFiles(path)
+		if _, abortErr := g.runGitCommand(path, "rebase", "--abort"); abortErr != nil {
+			return fmt.Errorf("failed to rebase %s on %s: %w (%v)", g.branchName, ref, err, abortErr)
+		}
+		if len(files) > 0 {
+			return &ConflictError{Files: files}
+		}
+		return fmt.Errorf("failed to rebase %s on %s: %w", g.branchName, ref, err)
+	}
+	return g.updateBaseCommit(ref)
+}
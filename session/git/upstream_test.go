@@ -0,0 +1,73 @@
+package git
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAheadBehindUpstream(t *testing.T) {
+	s := newStackTest(t)
+	origin := filepath.Join(t.TempDir(), "origin.git")
+	s.git(s.repoPath, "init", "--bare", origin)
+	s.git(s.repoPath, "remote", "add", "origin", origin)
+	s.git(s.repoPath, "push", "origin", "HEAD:refs/heads/main")
+
+	upstream, err := s.parent.UpstreamBaseBranch()
+	require.NoError(t, err)
+	assert.Equal(t, "origin/main", upstream)
+
+	// Someone else pushes to main.
+	clone := filepath.Join(t.TempDir(), "clone")
+	s.git(s.repoPath, "clone", "--quiet", "--branch", "main", origin, clone)
+	s.commitFile(clone, "upstream-1", "upstream")
+	s.commitFile(clone, "upstream-2", "upstream")
+	s.git(clone, "push", "origin", "main")
+
+	ahead, behind, err := s.parent.AheadBehind(upstream)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 0}, []int{ahead, behind})
+
+	require.NoError(t, s.parent.FetchOrigin())
+	ahead, behind, err = s.parent.AheadBehind(upstream)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, []int{ahead, behind})
+}
+
+func TestRebaseBranch(t *testing.T) {
+	s := newStackTest(t)
+	origin := filepath.Join(t.TempDir(), "origin.git")
+	s.git(s.repoPath, "init", "--bare", origin)
+	s.git(s.repoPath, "remote", "add", "origin", origin)
+	s.git(s.repoPath, "push", "origin", "HEAD:refs/heads/main")
+	clone := filepath.Join(t.TempDir(), "clone")
+	s.git(s.repoPath, "clone", "--quiet", "--branch", "main", origin, clone)
+
+	// The instance is paused, so its worktree is gone.
+	require.NoError(t, s.parent.Remove())
+	s.commitFile(clone, "upstream-1", "upstream")
+	s.git(clone, "push", "origin", "main")
+	require.NoError(t, s.parent.FetchOrigin())
+
+	require.NoError(t, s.parent.RebaseBranch("origin/main"))
+	ahead, behind, err := s.parent.AheadBehind("origin/main")
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 0}, []int{ahead, behind})
+	assert.Equal(t, s.git(s.repoPath, "rev-parse", "origin/main"), s.parent.GetBaseCommitSHA())
+
+	t.Run("conflicts leave the branch as it was", func(t *testing.T) {
+		s.commitFile(clone, "parent-1", "conflicting")
+		s.git(clone, "push", "origin", "main")
+		require.NoError(t, s.parent.FetchOrigin())
+		before := s.git(s.repoPath, "rev-parse", s.parent.GetBranchName())
+
+		err := s.parent.RebaseBranch("origin/main")
+		var conflictErr *ConflictError
+		require.ErrorAs(t, err, &conflictErr)
+		assert.Equal(t, []string{"parent-1"}, conflictErr.Files)
+		assert.Equal(t, before, s.git(s.repoPath, "rev-parse", s.parent.GetBranchName()))
+		assert.NotContains(t, s.git(s.repoPath, "worktree", "list"), "claude-squad-rebase-")
+	})
+}
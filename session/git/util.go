@@ -35,8 +35,8 @@ func sanitizeBranchName(s string) string {
 	return s
 }
 
-// checkGHCLI checks if GitHub CLI is installed and configured
-func checkGHCLI() error {
+// CheckGHCLI checks if GitHub CLI is installed and configured
+func CheckGHCLI() error {
 	// Check if gh is installed
 	if _, err := exec.LookPath("gh"); err != nil {
 		return fmt.Errorf("GitHub CLI (gh) is not installed. Please install it first")
@@ -67,7 +67,8 @@ func IsGitRepo(path string) bool {
 	}
 }
 
-func findGitRepoRoot(path string) (string, error) {
+// FindRepoRoot returns the root of the repository that path is in.
+func FindRepoRoot(path string) (string, error) {
 	currentPath := path
 	for {
 		_, err := git.PlainOpen(currentPath)
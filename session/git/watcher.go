@@ -0,0 +1,237 @@
+package git
+
+import (
+	"claude-squad/log"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce is how long the worktree must be quiet after a change before the diff is recomputed, so
+// a burst of writes, like a formatter or a build, is only diffed once.
+const watchDebounce = 300 * time.Millisecond
+
+// Watcher watches the files of a worktree to tell when its diff needs to be recomputed. Directories
+// ignored by git, like node_modules, and the .git directory are not watched, nor the directories outside
+// the diff paths of the worktree, if it has any.
+type Watcher struct {
+	root     string
+	worktree *GitWorktree
+	watcher  *fsnotify.Watcher
+	// ignored are the absolute paths of the directories ignored by git, listed on start and added to as
+	// ignored directories are created.
+	ignored map[string]bool
+	// scopes are the absolute paths of the directories watched, the root or the diff paths.
+	scopes []string
+
+	mu sync.Mutex
+	// changed is true if files changed since the last call to Changed.
+	changed bool
+	// lastChange is when the last change happened.
+	lastChange time.Time
+}
+
+// NewWatcher starts watching the worktree.
+func (g *GitWorktree) NewWatcher() (*Watcher, error) {
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{
+		root:     g.worktreePath,
+		worktree: g,
+		watcher:  fsWatcher,
+		ignored:  g.ignoredDirs(),
+		scopes:   []string{g.worktreePath},
+		// Compute the diff once on start.
+		changed: true,
+	}
+	if len(g.diffPaths) > 0 {
+		w.scopes = nil
+		for _, path := range g.diffPaths {
+			w.scopes = append(w.scopes, filepath.Join(g.worktreePath, filepath.FromSlash(path)))
+		}
+	}
+	for _, scope := range w.scopes {
+		if err := w.watchScope(scope); err != nil {
+			fsWatcher.Close()
+			return nil, err
+		}
+	}
+	go w.run()
+	return w, nil
+}
+
+// ignoredDirs returns the absolute paths of the directories ignored by git.
+func (g *GitWorktree) ignoredDirs() map[string]bool {
+	ignored := map[string]bool{filepath.Join(g.worktreePath, ".git"): true}
+	args := append([]string{"ls-files", "--others", "--ignored", "--exclude-standard", "--directory", "--"}, g.diffPathspec()...)
+	output, err := g.runGitCommand(g.worktreePath, args...)
+	if err != nil {
+		log.WarningLog.Printf("could not list ignored directories of %s: %v", g.worktreePath, err)
+		return ignored
+	}
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasSuffix(line, "/") {
+			ignored[filepath.Join(g.worktreePath, filepath.FromSlash(strings.TrimSuffix(line, "/")))] = true
+		}
+	}
+	return ignored
+}
+
+// isIgnoredDir returns true if git ignores the directory at path, e.g. a node_modules the agent just
+// created.
+func (g *GitWorktree) isIgnoredDir(path string) bool {
+	// check-ignore exits with 1 if the path isn't ignored.
+	_, err := g.runGitCommand(g.worktreePath, "check-ignore", "-q", "--", path)
+	return err == nil
+}
+
+// watchScope watches the directory scope and its subdirectories. If it doesn't exist yet, its closest
+// parent that does is watched instead, until it's created.
+func (w *Watcher) watchScope(scope string) error {
+	dir := scope
+	for {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			break
+		}
+		if dir == w.root || dir == filepath.Dir(dir) {
+			return nil
+		}
+		dir = filepath.Dir(dir)
+	}
+	if dir == scope {
+		return w.addTree(scope)
+	}
+	return w.watcher.Add(dir)
+}
+
+// addTree watches dir and its subdirectories.
+func (w *Watcher) addTree(dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// The directory may have been removed in the meantime.
+			return nil
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if w.ignored[path] {
+			return filepath.SkipDir
+		}
+		return w.watcher.Add(path)
+	})
+}
+
+func (w *Watcher) run() {
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if w.isIgnored(event.Name) {
+				continue
+			}
+			if !w.inScope(event.Name) {
+				if event.Has(fsnotify.Create) {
+					// A parent of a scope that doesn't exist yet was created.
+					w.watchScopesIn(event.Name)
+				}
+				continue
+			}
+			if event.Has(fsnotify.Create) && w.ignoreNewDir(event.Name) {
+				continue
+			}
+			if event.Has(fsnotify.Create) {
+				// fsnotify isn't recursive, watch new directories too.
+				_ = w.addTree(event.Name)
+			}
+			w.MarkChanged()
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			// Events may have been dropped, recompute to be safe.
+			log.WarningLog.Printf("worktree watcher error for %s: %v", w.root, err)
+			w.MarkChanged()
+		}
+	}
+}
+
+// inScope returns true if path is in one of the scopes.
+func (w *Watcher) inScope(path string) bool {
+	for _, scope := range w.scopes {
+		if path == scope || strings.HasPrefix(path, scope+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// watchScopesIn watches the scopes in dir, which was just created.
+func (w *Watcher) watchScopesIn(dir string) {
+	for _, scope := range w.scopes {
+		if strings.HasPrefix(scope, dir+string(filepath.Separator)) {
+			_ = w.watchScope(scope)
+		}
+	}
+}
+
+// ignoreNewDir returns true if path is a directory that was just created and that git ignores, like
+// node_modules or target. It's recorded as ignored so it and its subdirectories aren't watched.
+func (w *Watcher) ignoreNewDir(path string) bool {
+	if info, err := os.Stat(path); err != nil || !info.IsDir() || !w.worktree.isIgnoredDir(path) {
+		return false
+	}
+	w.ignored[path] = true
+	return true
+}
+
+// isIgnored returns true if path is in an ignored directory.
+func (w *Watcher) isIgnored(path string) bool {
+	for dir := path; dir != w.root && dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		if w.ignored[dir] {
+			return true
+		}
+	}
+	return false
+}
+
+// MarkChanged forces the next call to Changed to return true once the debounce elapsed, e.g. after the
+// base commit of the diff changed.
+func (w *Watcher) MarkChanged() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.changed = true
+	w.lastChange = time.Now()
+}
+
+// Changed returns true if files changed since the last call that returned true, and no change happened
+// for the debounce period.
+func (w *Watcher) Changed(now time.Time) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.changed || now.Sub(w.lastChange) < watchDebounce {
+		return false
+	}
+	w.changed = false
+	return true
+}
+
+// LastChange returns when files last changed, or the zero time if they haven't since the watcher started.
+func (w *Watcher) LastChange() time.Time {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.lastChange
+}
+
+// Close stops watching.
+func (w *Watcher) Close() error {
+	return w.watcher.Close()
+}
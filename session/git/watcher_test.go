@@ -0,0 +1,98 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatcher(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, exec.Command("git", "-C", dir, "init").Run())
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".gitignore"), []byte("build/\n"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "build"), 0755))
+
+	g := &GitWorktree{worktreePath: dir}
+	w, err := g.NewWatcher()
+	require.NoError(t, err)
+	defer w.Close()
+
+	// changedSoon waits for the events and the debounce.
+	changedSoon := func() bool {
+		deadline := time.Now().Add(2 * time.Second)
+		for time.Now().Before(deadline) {
+			if w.Changed(time.Now()) {
+				return true
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		return false
+	}
+
+	// The diff is computed once on start.
+	assert.True(t, w.Changed(time.Now()))
+	assert.False(t, w.Changed(time.Now()))
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main"), 0644))
+	assert.True(t, changedSoon())
+
+	// Changes in ignored directories don't count.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "build", "out"), []byte("binary"), 0644))
+	assert.False(t, changedSoon())
+
+	// New directories are watched too.
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "pkg"), 0755))
+	assert.True(t, changedSoon())
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pkg", "pkg.go"), []byte("package pkg"), 0644))
+	assert.True(t, changedSoon())
+
+	// Ignored directories created afterwards aren't watched.
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "pkg", "build", "cache"), 0755))
+	assert.False(t, changedSoon())
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pkg", "build", "cache", "out"), []byte("binary"), 0644))
+	assert.False(t, changedSoon())
+}
+
+func TestWatcherDiffPaths(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, exec.Command("git", "-C", dir, "init").Run())
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "docs"), 0755))
+
+	g := &GitWorktree{worktreePath: dir}
+	g.SetDiffPaths([]string{"docs", "services/auth"})
+	w, err := g.NewWatcher()
+	require.NoError(t, err)
+	defer w.Close()
+
+	changedSoon := func() bool {
+		deadline := time.Now().Add(2 * time.Second)
+		for time.Now().Before(deadline) {
+			if w.Changed(time.Now()) {
+				return true
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		return false
+	}
+	assert.True(t, w.Changed(time.Now()))
+
+	// Changes outside the diff paths don't count.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main"), 0644))
+	assert.False(t, changedSoon())
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "docs", "guide.md"), []byte("# guide"), 0644))
+	assert.True(t, changedSoon())
+
+	// Diff paths created later are watched once they exist.
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "services"), 0755))
+	assert.False(t, changedSoon())
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "services", "auth"), 0755))
+	assert.True(t, changedSoon())
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "services", "auth", "auth.go"), []byte("package auth"), 0644))
+	assert.True(t, changedSoon())
+}
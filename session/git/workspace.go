@@ -0,0 +1,217 @@
+package git
+
+import (
+	"claude-squad/config"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Workspace is the version control backend the working copies of instances are made with: git worktrees,
+// or the workspaces of Jujutsu in repositories colocated with jj, which it doesn't track as git worktrees.
+// The branches of instances are git branches either way, since jj exports its bookmarks to git in
+// colocated repositories, so everything else works on the repository with git.
+type Workspace interface {
+	// Name is the name of the backend, as the vcs setting picks it.
+	Name() string
+	// AddNew creates the working copy at path on a new branch starting at the commit base.
+	AddNew(repoPath, path, branch, base string) error
+	// AddExisting creates the working copy at path on branch, which exists already.
+	AddExisting(repoPath, path, branch string) error
+	// Remove removes the working copy at path, keeping its branch. If force is true, it's removed even if
+	// it's locked.
+	Remove(repoPath, path string, force bool) error
+	// Prune forgets the working copies deleted from disk.
+	Prune(repoPath string) error
+	// Diff returns the diff of the working copy at path against the commit base, untracked files
+	// included, limited to the paths relative to its root, or of all of it if there are none, and leaving
+	// out the files of excludes, which are relative to its root too.
+	Diff(path, base string, paths, excludes []string) (string, error)
+	// IsDirty returns true if the working copy at path has changes that aren't committed.
+	IsDirty(path string) (bool, error)
+	// Commit commits the changes of the working copy at path onto branch.
+	Commit(path, branch, message string) error
+	// Snapshot returns a commit with the uncommitted changes of the working copy at path, untracked files
+	// included, on top of its branch, without changing either, or an empty string if there are none.
+	Snapshot(path, message string) (string, error)
+}
+
+const (
+	// VCSGit makes the working copies of instances git worktrees.
+	VCSGit = "git"
+	// VCSJujutsu makes the working copies of instances jj workspaces.
+	VCSJujutsu = "jj"
+)
+
+// detectWorkspace returns the backend of the working copies of the repository at repoPath: the one the vcs
+// setting names, or else jj workspaces if the repository is colocated with jj and jj is installed, and
+// git worktrees otherwise.
+func detectWorkspace(repoPath string) (Workspace, error) {
+	switch vcs := config.LoadConfig().VCS; vcs {
+	case VCSGit:
+		return nil, nil
+	case VCSJujutsu:
+		if !isJujutsuRepo(repoPath) {
+			return nil, fmt.Errorf("vcs is set to jj, but %s isn't a jj repository: run `jj git init --colocate` in it", repoPath)
+		}
+		return jjWorkspace{}, nil
+	case "", "auto":
+		if isJujutsuRepo(repoPath) {
+			if _, err := exec.LookPath("jj"); err == nil {
+				return jjWorkspace{}, nil
+			}
+		}
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown vcs %q, pick one of auto, git or jj", vcs)
+	}
+}
+
+// isJujutsuRepo returns true if the repository at repoPath is colocated with jj.
+func isJujutsuRepo(repoPath string) bool {
+	info, err := os.Stat(filepath.Join(repoPath, ".jj"))
+	return err == nil && info.IsDir()
+}
+
+// workspaceByName returns the backend named name, as it's stored with the instances. Empty is git.
+func workspaceByName(name string) Workspace {
+	if name == VCSJujutsu {
+		return jjWorkspace{}
+	}
+	return nil
+}
+
+// vcs returns the backend of the working copy, git worktrees unless it's set.
+func (g *GitWorktree) vcs() Workspace {
+	if g.workspace == nil {
+		return gitWorkspace{host: g.host}
+	}
+	return g.workspace
+}
+
+// VCS returns the name of the backend of the working copy, for storage.
+func (g *GitWorktree) VCS() string {
+	if g.workspace == nil {
+		return ""
+	}
+	return g.workspace.Name()
+}
+
+// SetVCS sets the backend of the working copy by its name, for worktrees loaded from storage.
+func (g *GitWorktree) SetVCS(name string) {
+	g.workspace = workspaceByName(name)
+}
+
+// gitWorkspace makes working copies with git worktrees, on host over ssh if it's set.
+type gitWorkspace struct {
+	host string
+}
+
+func (w gitWorkspace) Name() string {
+	return VCSGit
+}
+
+func (w gitWorkspace) run(path string, args ...string) (string, error) {
+	output, err := gitCommand(w.host, path, args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git command failed: %s (%w)", output, err)
+	}
+	return string(output), nil
+}
+
+func (w gitWorkspace) AddNew(repoPath, path, branch, base string) error {
+	if _, err := w.run(repoPath, "worktree", "add", "-b", branch, path, base); err != nil {
+		return fmt.Errorf("failed to create worktree from commit %s: %w", base, err)
+	}
+	return nil
+}
+
+func (w gitWorkspace) AddExisting(repoPath, path, branch string) error {
+	if _, err := w.run(repoPath, "worktree", "add", path, branch); err != nil {
+		return fmt.Errorf("failed to create worktree from branch %s: %w", branch, err)
+	}
+	return nil
+}
+
+func (w gitWorkspace) Remove(repoPath, path string, force bool) error {
+	args := []string{"worktree", "remove", "-f", path}
+	if force {
+		// Forcing twice removes locked worktrees too.
+		args = []string{"worktree", "remove", "-f", "-f", path}
+	}
+	_, err := w.run(repoPath, args...)
+	return err
+}
+
+func (w gitWorkspace) Prune(repoPath string) error {
+	if _, err := w.run(repoPath, "worktree", "prune"); err != nil {
+		return fmt.Errorf("failed to prune worktrees: %w", err)
+	}
+	return nil
+}
+
+func (w gitWorkspace) Diff(path, base string, paths, excludes []string) (string, error) {
+	pathspec := paths
+	if len(pathspec) == 0 {
+		pathspec = []string{"."}
+	}
+	var excludeSpec []string
+	for _, p := range excludes {
+		// The excluded files are taken literally, from the root, even if the diff is limited to a directory.
+		excludeSpec = append(excludeSpec, ":(top,literal,exclude)"+p)
+	}
+	// -N stages untracked files (intent to add), including them in the diff. git add fails on the paths
+	// that don't exist, e.g. an area the agent hasn't created yet, so they're left out.
+	existing := pathspec
+	if len(paths) > 0 && w.host == "" {
+		existing = nil
+		for _, p := range paths {
+			if _, err := os.Stat(filepath.Join(path, filepath.FromSlash(p))); err == nil {
+				existing = append(existing, p)
+			}
+		}
+	}
+	if len(existing) > 0 {
+		if _, err := w.run(path, append([]string{"add", "-N", "--"}, existing...)...); err != nil {
+			return "", err
+		}
+	}
+	args := append([]string{"--no-pager", "diff", base, "--"}, pathspec...)
+	return w.run(path, append(args, excludeSpec...)...)
+}
+
+func (w gitWorkspace) IsDirty(path string) (bool, error) {
+	output, err := w.run(path, "status", "--porcelain")
+	if err != nil {
+		return false, fmt.Errorf("failed to check worktree status: %w", err)
+	}
+	return len(output) > 0, nil
+}
+
+// Commit stages all the changes and commits them with the identity and the signing of the commit strategy.
+func (w gitWorkspace) Commit(path, branch, message string) error {
+	if _, err := w.run(path, "add", "."); err != nil {
+		return fmt.Errorf("failed to stage changes: %w", err)
+	}
+	global, commitArgs := config.LoadConfig().CommitStrategy.GitArgs()
+	args := append(global, "commit", "-m", message, "--no-verify")
+	if _, err := w.run(path, append(args, commitArgs...)...); err != nil {
+		return fmt.Errorf("failed to commit changes: %w", err)
+	}
+	return nil
+}
+
+func (w gitWorkspace) Snapshot(path, message string) (string, error) {
+	// Staging everything lets stash create, which leaves the worktree alone, include the untracked files.
+	if _, err := w.run(path, "add", "-A"); err != nil {
+		return "", fmt.Errorf("failed to stage the changes: %w", err)
+	}
+	output, err := w.run(path, "stash", "create", message)
+	if err != nil {
+		return "", fmt.Errorf("failed to snapshot the changes: %w", err)
+	}
+	// stash create prints nothing if there are no changes.
+	return strings.TrimSpace(output), nil
+}
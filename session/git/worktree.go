@@ -4,7 +4,9 @@ import (
 	"claude-squad/config"
 	"claude-squad/log"
 	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -17,6 +19,27 @@ func getWorktreeDirectory() (string, error) {
 	return filepath.Join(configDir, "worktrees"), nil
 }
 
+// WorktreeDirs returns the directories in the directory the worktrees of the instances are created in.
+func WorktreeDirs() ([]string, error) {
+	worktreeDir, err := getWorktreeDirectory()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(worktreeDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read worktree directory: %w", err)
+	}
+	var dirs []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			dirs = append(dirs, filepath.Join(worktreeDir, entry.Name()))
+		}
+	}
+	return dirs, nil
+}
+
 // GitWorktree manages git worktree operations for a session
 type GitWorktree struct {
 	// Path to the repository
@@ -29,24 +52,113 @@ type GitWorktree struct {
 	branchName string
 	// Base commit hash for the worktree
 	baseCommitSHA string
+	// Branch the worktree is created from instead of HEAD, if any
+	baseBranch string
+	// Whether the branch existed before the session. It's checked out as is, and kept on cleanup.
+	existingBranch bool
+	// Machine the repository and the worktree are on over ssh, if they're remote
+	host string
+	// Paths, relative to the root, the diff and the watcher are limited to. Empty means the whole worktree.
+	diffPaths []string
+	// Called with the progress of the slow steps of the setup, like checking out submodules, if set
+	progress func(string)
+	// Backend the working copy is made with, git worktrees if it's nil
+	workspace Workspace
+	// Files copy_on_create copied into the worktree, relative to its root. They're excluded from git and
+	// left out of the diff, since they're usually secrets like .env.
+	copiedFiles []string
 }
 
-func NewGitWorktreeFromStorage(repoPath string, worktreePath string, sessionName string, branchName string, baseCommitSHA string) *GitWorktree {
+func NewGitWorktreeFromStorage(repoPath string, worktreePath string, sessionName string, branchName string, baseCommitSHA string, existingBranch bool) *GitWorktree {
 	return &GitWorktree{
-		repoPath:      repoPath,
-		worktreePath:  worktreePath,
-		sessionName:   sessionName,
-		branchName:    branchName,
-		baseCommitSHA: baseCommitSHA,
+		repoPath:       repoPath,
+		worktreePath:   worktreePath,
+		sessionName:    sessionName,
+		branchName:     branchName,
+		baseCommitSHA:  baseCommitSHA,
+		existingBranch: existingBranch,
+	}
+}
+
+// NewGitWorktreeFromPath returns the GitWorktree of the existing worktree at worktreePath, e.g. to adopt
+// the terminal session of an instance that's missing from the state. Its base commit is where its branch
+// forked from the HEAD of the repository.
+func NewGitWorktreeFromPath(worktreePath string, sessionName string) (*GitWorktree, error) {
+	g := &GitWorktree{worktreePath: worktreePath, sessionName: sessionName}
+	commonDir, err := g.runGitCommand(worktreePath, "rev-parse", "--path-format=absolute", "--git-common-dir")
+	if err != nil {
+		return nil, fmt.Errorf("%s isn't a git worktree: %w", worktreePath, err)
+	}
+	// The common directory is the .git directory of the repository, or the repository itself if it's bare.
+	g.repoPath = strings.TrimSpace(commonDir)
+	if filepath.Base(g.repoPath) == ".git" {
+		g.repoPath = filepath.Dir(g.repoPath)
 	}
+	if filepath.Clean(g.repoPath) == filepath.Clean(worktreePath) {
+		return nil, fmt.Errorf("%s is the repository itself, not a worktree of it", worktreePath)
+	}
+	branch, err := g.runGitCommand(worktreePath, "symbolic-ref", "--short", "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("%s isn't on a branch: %w", worktreePath, err)
+	}
+	g.branchName = strings.TrimSpace(branch)
+	base, err := g.runGitCommand(worktreePath, "merge-base", "HEAD", g.repoHead())
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the base commit of %s: %w", g.branchName, err)
+	}
+	g.baseCommitSHA = strings.TrimSpace(base)
+	return g, nil
+}
+
+// repoHead returns the commit of the HEAD of the repository, or "HEAD" if it can't be resolved.
+func (g *GitWorktree) repoHead() string {
+	head, err := g.runGitCommand(g.repoPath, "rev-parse", "HEAD")
+	if err != nil {
+		return "HEAD"
+	}
+	return strings.TrimSpace(head)
 }
 
-// NewGitWorktree creates a new GitWorktree instance
+// NewGitWorktree creates a new GitWorktree instance on a new branch, named after the configured branch
+// template and suffixed if a branch already has the name.
 func NewGitWorktree(repoPath string, sessionName string) (tree *GitWorktree, branchname string, err error) {
 	cfg := config.LoadConfig()
-	sanitizedName := sanitizeBranchName(sessionName)
-	branchName := fmt.Sprintf("%s%s", cfg.BranchPrefix, sanitizedName)
+	branchName, err := RenderBranchName(cfg.BranchTemplate, cfg.BranchPrefix, sessionName, time.Now())
+	if err != nil {
+		return nil, "", err
+	}
 
+	tree, err = newGitWorktree(repoPath, sessionName)
+	if err != nil {
+		return nil, "", err
+	}
+	if tree.branchName, err = uniqueBranchName("", tree.repoPath, branchName); err != nil {
+		return nil, "", err
+	}
+	return tree, tree.branchName, nil
+}
+
+// NewGitWorktreeFromBranch creates a new GitWorktree instance that checks out an existing branch instead
+// of creating one. The branch is kept when the worktree is cleaned up.
+func NewGitWorktreeFromBranch(repoPath string, sessionName string, branchName string) (*GitWorktree, error) {
+	tree, err := newGitWorktree(repoPath, sessionName)
+	if err != nil {
+		return nil, err
+	}
+	exists, err := BranchExists(tree.repoPath, branchName)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("branch %s does not exist", branchName)
+	}
+	tree.branchName = branchName
+	tree.existingBranch = true
+	return tree, nil
+}
+
+// newGitWorktree creates a GitWorktree instance for the repository at repoPath, without a branch.
+func newGitWorktree(repoPath string, sessionName string) (*GitWorktree, error) {
 	// Convert repoPath to absolute path
 	absPath, err := filepath.Abs(repoPath)
 	if err != nil {
@@ -55,25 +167,40 @@ func NewGitWorktree(repoPath string, sessionName string) (tree *GitWorktree, bra
 		absPath = repoPath
 	}
 
-	repoPath, err = findGitRepoRoot(absPath)
+	repoPath, err = FindRepoRoot(absPath)
 	if err != nil {
-		return nil, "", err
+		return nil, err
 	}
 
 	worktreeDir, err := getWorktreeDirectory()
 	if err != nil {
-		return nil, "", err
+		return nil, err
 	}
 
-	worktreePath := filepath.Join(worktreeDir, sanitizedName)
+	// Branch names may contain slashes, which would nest the worktree in subdirectories.
+	worktreePath := filepath.Join(worktreeDir, strings.ReplaceAll(sanitizeBranchName(sessionName), "/", "-"))
 	worktreePath = worktreePath + "_" + fmt.Sprintf("%x", time.Now().UnixNano())
 
+	workspace, err := detectWorkspace(repoPath)
+	if err != nil {
+		return nil, err
+	}
 	return &GitWorktree{
 		repoPath:     repoPath,
 		sessionName:  sessionName,
-		branchName:   branchName,
 		worktreePath: worktreePath,
-	}, branchName, nil
+		workspace:    workspace,
+	}, nil
+}
+
+// NewWorktreeLocation returns the root of the repository at repoPath and a new worktree path for a
+// session, for a worktree created from data received from elsewhere.
+func NewWorktreeLocation(repoPath string, sessionName string) (repoRoot string, worktreePath string, err error) {
+	tree, err := newGitWorktree(repoPath, sessionName)
+	if err != nil {
+		return "", "", err
+	}
+	return tree.repoPath, tree.worktreePath, nil
 }
 
 // GetWorktreePath returns the path to the worktree
@@ -91,9 +218,15 @@ func (g *GitWorktree) GetRepoPath() string {
 	return g.repoPath
 }
 
-// GetRepoName returns the name of the repository (last part of the repoPath).
+// GetRepoName returns the name of the repository (last part of the repoPath), without the .git suffix
+// of bare repositories.
 func (g *GitWorktree) GetRepoName() string {
-	return filepath.Base(g.repoPath)
+	return strings.TrimSuffix(filepath.Base(g.repoPath), ".git")
+}
+
+// IsExistingBranch returns true if the worktree checks out a branch that existed before the session.
+func (g *GitWorktree) IsExistingBranch() bool {
+	return g.existingBranch
 }
 
 // GetBaseCommitSHA returns the base commit SHA for the worktree
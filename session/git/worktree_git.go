@@ -4,13 +4,14 @@ import (
 	"claude-squad/log"
 	"fmt"
 	"os/exec"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // runGitCommand executes a git command and returns any error
 func (g *GitWorktree) runGitCommand(path string, args ...string) (string, error) {
-	baseArgs := []string{"-C", path}
-	cmd := exec.Command("git", append(baseArgs, args...)...)
+	cmd := gitCommand(g.host, path, args...)
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -22,7 +23,10 @@ func (g *GitWorktree) runGitCommand(path string, args ...string) (string, error)
 
 // PushChanges commits and pushes changes in the worktree to the remote branch
 func (g *GitWorktree) PushChanges(commitMessage string, open bool) error {
-	if err := checkGHCLI(); err != nil {
+	if g.host != "" {
+		return g.pushRemoteChanges(commitMessage)
+	}
+	if err := CheckGHCLI(); err != nil {
 		return err
 	}
 
@@ -33,26 +37,20 @@ func (g *GitWorktree) PushChanges(commitMessage string, open bool) error {
 	}
 
 	if isDirty {
-		// Stage all changes
-		if _, err := g.runGitCommand(g.worktreePath, "add", "."); err != nil {
+		if err := g.vcs().Commit(g.worktreePath, g.branchName, commitMessage); err != nil {
 			log.ErrorLog.Print(err)
-			return fmt.Errorf("failed to stage changes: %w", err)
-		}
-
-		// Create commit
-		if _, err := g.runGitCommand(g.worktreePath, "commit", "-m", commitMessage, "--no-verify"); err != nil {
-			log.ErrorLog.Print(err)
-			return fmt.Errorf("failed to commit changes: %w", err)
+			return err
 		}
 	}
 
-	// First push the branch to remote to ensure it exists
+	// First push the branch to remote to ensure it exists. The branch is pushed from the repository, since
+	// the workspaces of jj aren't git worktrees.
 	pushCmd := exec.Command("gh", "repo", "sync", "--source", "-b", g.branchName)
-	pushCmd.Dir = g.worktreePath
+	pushCmd.Dir = g.repoPath
 	if err := pushCmd.Run(); err != nil {
 		// If sync fails, try creating the branch on remote first
 		gitPushCmd := exec.Command("git", "push", "-u", "origin", g.branchName)
-		gitPushCmd.Dir = g.worktreePath
+		gitPushCmd.Dir = g.repoPath
 		if pushOutput, pushErr := gitPushCmd.CombinedOutput(); pushErr != nil {
 			log.ErrorLog.Print(pushErr)
 			return fmt.Errorf("failed to push branch: %s (%w)", pushOutput, pushErr)
@@ -61,7 +59,7 @@ func (g *GitWorktree) PushChanges(commitMessage string, open bool) error {
 
 	// Now sync with remote
 	syncCmd := exec.Command("gh", "repo", "sync", "-b", g.branchName)
-	syncCmd.Dir = g.worktreePath
+	syncCmd.Dir = g.repoPath
 	if output, err := syncCmd.CombinedOutput(); err != nil {
 		log.ErrorLog.Print(err)
 		return fmt.Errorf("failed to sync changes: %s (%w)", output, err)
@@ -78,6 +76,24 @@ func (g *GitWorktree) PushChanges(commitMessage string, open bool) error {
 	return nil
 }
 
+// PushBranch pushes the branch to origin from the repository, so it works without the worktree.
+func (g *GitWorktree) PushBranch() error {
+	if _, err := g.runGitCommand(g.repoPath, "push", "-u", "origin", g.branchName); err != nil {
+		return fmt.Errorf("failed to push branch %s: %w", g.branchName, err)
+	}
+	return nil
+}
+
+// FetchBranch fetches a branch from origin into the local branch of the same name in the repository
+// at repoPath.
+func FetchBranch(repoPath string, branchName string) error {
+	cmd := exec.Command("git", "-C", repoPath, "fetch", "origin", branchName+":"+branchName)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to fetch branch %s: %s (%w)", branchName, output, err)
+	}
+	return nil
+}
+
 // CommitChanges commits changes locally without pushing to remote
 func (g *GitWorktree) CommitChanges(commitMessage string) error {
 	// Check if there are any changes to commit
@@ -87,33 +103,34 @@ func (g *GitWorktree) CommitChanges(commitMessage string) error {
 	}
 
 	if isDirty {
-		// Stage all changes
-		if _, err := g.runGitCommand(g.worktreePath, "add", "."); err != nil {
-			log.ErrorLog.Print(err)
-			return fmt.Errorf("failed to stage changes: %w", err)
-		}
-
 		// Create commit (local only)
-		if _, err := g.runGitCommand(g.worktreePath, "commit", "-m", commitMessage, "--no-verify"); err != nil {
+		if err := g.vcs().Commit(g.worktreePath, g.branchName, commitMessage); err != nil {
 			log.ErrorLog.Print(err)
-			return fmt.Errorf("failed to commit changes: %w", err)
+			return err
 		}
 	}
 
 	return nil
 }
 
+// IsBare returns true if the repository is bare, e.g. a clone made with --bare that the worktrees of all
+// instances hang off.
+func (g *GitWorktree) IsBare() bool {
+	output, err := g.runGitCommand(g.repoPath, "rev-parse", "--is-bare-repository")
+	return err == nil && strings.TrimSpace(output) == "true"
+}
+
 // IsDirty checks if the worktree has uncommitted changes
 func (g *GitWorktree) IsDirty() (bool, error) {
-	output, err := g.runGitCommand(g.worktreePath, "status", "--porcelain")
-	if err != nil {
-		return false, fmt.Errorf("failed to check worktree status: %w", err)
-	}
-	return len(output) > 0, nil
+	return g.vcs().IsDirty(g.worktreePath)
 }
 
 // IsBranchCheckedOut checks if the instance branch is currently checked out
 func (g *GitWorktree) IsBranchCheckedOut() (bool, error) {
+	// A bare repository has no checkout, though it has a HEAD.
+	if g.IsBare() {
+		return false, nil
+	}
 	output, err := g.runGitCommand(g.repoPath, "branch", "--show-current")
 	if err != nil {
 		return false, fmt.Errorf("failed to get current branch: %w", err)
@@ -124,14 +141,65 @@ func (g *GitWorktree) IsBranchCheckedOut() (bool, error) {
 // OpenBranchURL opens the branch URL in the default browser
 func (g *GitWorktree) OpenBranchURL() error {
 	// Check if GitHub CLI is available
-	if err := checkGHCLI(); err != nil {
+	if err := CheckGHCLI(); err != nil {
 		return err
 	}
 
 	cmd := exec.Command("gh", "browse", "--branch", g.branchName)
-	cmd.Dir = g.worktreePath
+	cmd.Dir = g.repoPath
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("failed to open branch URL: %w", err)
 	}
 	return nil
 }
+
+// revisionRange returns the range of commits made on the instance branch.
+func (g *GitWorktree) revisionRange() string {
+	if g.baseCommitSHA == "" {
+		return g.branchName
+	}
+	return fmt.Sprintf("%s..%s", g.baseCommitSHA, g.branchName)
+}
+
+// CommitsSince returns the one-line summaries of the commits on the branch made after since,
+// newest first.
+func (g *GitWorktree) CommitsSince(since time.Time) ([]string, error) {
+	output, err := g.runGitCommand(g.repoPath, "log", "--since="+since.Format(time.RFC3339),
+		"--format=%h %s", g.revisionRange())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list commits: %w", err)
+	}
+
+	var commits []string
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			commits = append(commits, line)
+		}
+	}
+	return commits, nil
+}
+
+// ChangesSince returns the number of lines added and removed by the commits on the branch made after
+// since.
+func (g *GitWorktree) ChangesSince(since time.Time) (added int, removed int, err error) {
+	output, err := g.runGitCommand(g.repoPath, "log", "--since="+since.Format(time.RFC3339),
+		"--numstat", "--format=", g.revisionRange())
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get commit stats: %w", err)
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		// Binary files are reported as "-".
+		if n, err := strconv.Atoi(fields[0]); err == nil {
+			added += n
+		}
+		if n, err := strconv.Atoi(fields[1]); err == nil {
+			removed += n
+		}
+	}
+	return added, removed, nil
+}
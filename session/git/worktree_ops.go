@@ -8,14 +8,33 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
 )
 
-// Setup creates a new worktree for the session
+// Setup creates a new worktree for the session and its scratch directory, then checks out its submodules
+// and pulls its LFS objects if init_submodules and pull_lfs are set.
 func (g *GitWorktree) Setup() error {
+	if err := g.setup(); err != nil {
+		return err
+	}
+	if err := g.setupScratchDir(); err != nil {
+		log.ErrorLog.Printf("Failed to set up the scratch directory: %v", err)
+	}
+	return g.setupModules()
+}
+
+func (g *GitWorktree) setup() error {
+	if g.host != "" {
+		return g.setupRemote()
+	}
+	if g.existingBranch {
+		return g.SetupFromExistingBranch()
+	}
+
 	// Check if branch exists first
 	repo, err := git.PlainOpen(g.repoPath)
 	if err != nil {
@@ -41,11 +60,20 @@ func (g *GitWorktree) SetupFromExistingBranch() error {
 	}
 
 	// Clean up any existing worktree first
-	_, _ = g.runGitCommand(g.repoPath, "worktree", "remove", "-f", g.worktreePath) // Ignore error if worktree doesn't exist
+	_ = g.vcs().Remove(g.repoPath, g.worktreePath, false) // Ignore error if worktree doesn't exist
 
 	// Create a new worktree from the existing branch
-	if _, err := g.runGitCommand(g.repoPath, "worktree", "add", g.worktreePath, g.branchName); err != nil {
-		return fmt.Errorf("failed to create worktree from branch %s: %w", g.branchName, err)
+	if err := g.vcs().AddExisting(g.repoPath, g.worktreePath, g.branchName); err != nil {
+		return err
+	}
+
+	// A reused branch is diffed against where it forked from HEAD.
+	if g.baseCommitSHA == "" {
+		output, err := g.runGitCommand(g.repoPath, "merge-base", "HEAD", g.branchName)
+		if err != nil {
+			return fmt.Errorf("failed to find where branch %s forked: %w", g.branchName, err)
+		}
+		g.baseCommitSHA = strings.TrimSpace(output)
 	}
 
 	// Copy configured files after worktree is created
@@ -53,6 +81,9 @@ func (g *GitWorktree) SetupFromExistingBranch() error {
 		log.ErrorLog.Printf("Failed to copy configured files: %v", err)
 		// Don't fail the entire setup just because file copying failed
 	}
+	if err := g.linkConfiguredDirs(); err != nil {
+		log.ErrorLog.Printf("Failed to link configured directories: %v", err)
+	}
 
 	return nil
 }
@@ -66,7 +97,7 @@ func (g *GitWorktree) SetupNewWorktree() error {
 	}
 
 	// Clean up any existing worktree first
-	_, _ = g.runGitCommand(g.repoPath, "worktree", "remove", "-f", g.worktreePath) // Ignore error if worktree doesn't exist
+	_ = g.vcs().Remove(g.repoPath, g.worktreePath, false) // Ignore error if worktree doesn't exist
 
 	// Open the repository
 	repo, err := git.PlainOpen(g.repoPath)
@@ -79,8 +110,15 @@ func (g *GitWorktree) SetupNewWorktree() error {
 		return fmt.Errorf("failed to cleanup existing branch: %w", err)
 	}
 
-	output, err := g.runGitCommand(g.repoPath, "rev-parse", "HEAD")
+	base := "HEAD"
+	if g.baseBranch != "" {
+		base = g.baseBranch
+	}
+	output, err := g.runGitCommand(g.repoPath, "rev-parse", base)
 	if err != nil {
+		if g.baseBranch != "" {
+			return fmt.Errorf("failed to get the commit of base branch %s: %w", g.baseBranch, err)
+		}
 		if strings.Contains(err.Error(), "fatal: ambiguous argument 'HEAD'") ||
 			strings.Contains(err.Error(), "fatal: not a valid object name") ||
 			strings.Contains(err.Error(), "fatal: HEAD: not a valid object name") {
@@ -95,8 +133,8 @@ func (g *GitWorktree) SetupNewWorktree() error {
 	// Otherwise, we'll inherit uncommitted changes from the previous worktree.
 	// This way, we can start the worktree with a clean slate.
 	// TODO: we might want to give an option to use main/master instead of the current branch.
-	if _, err := g.runGitCommand(g.repoPath, "worktree", "add", "-b", g.branchName, g.worktreePath, headCommit); err != nil {
-		return fmt.Errorf("failed to create worktree from commit %s: %w", headCommit, err)
+	if err := g.vcs().AddNew(g.repoPath, g.worktreePath, g.branchName, headCommit); err != nil {
+		return err
 	}
 
 	// Copy configured files after worktree is created
@@ -104,18 +142,25 @@ func (g *GitWorktree) SetupNewWorktree() error {
 		log.ErrorLog.Printf("Failed to copy configured files: %v", err)
 		// Don't fail the entire setup just because file copying failed
 	}
+	if err := g.linkConfiguredDirs(); err != nil {
+		log.ErrorLog.Printf("Failed to link configured directories: %v", err)
+	}
 
 	return nil
 }
 
 // Cleanup removes the worktree and associated branch
 func (g *GitWorktree) Cleanup() error {
+	if g.host != "" {
+		return g.cleanupRemote()
+	}
+	g.unexcludeCopiedFiles()
 	var errs []error
 
 	// Check if worktree path exists before attempting removal
 	if _, err := os.Stat(g.worktreePath); err == nil {
-		// Remove the worktree using git command
-		if _, err := g.runGitCommand(g.repoPath, "worktree", "remove", "-f", g.worktreePath); err != nil {
+		g.unlinkConfiguredDirs()
+		if err := g.vcs().Remove(g.repoPath, g.worktreePath, false); err != nil {
 			errs = append(errs, err)
 		}
 	} else if !os.IsNotExist(err) {
@@ -132,13 +177,15 @@ func (g *GitWorktree) Cleanup() error {
 
 	branchRef := plumbing.NewBranchReferenceName(g.branchName)
 
-	// Check if branch exists before attempting removal
-	if _, err := repo.Reference(branchRef, false); err == nil {
-		if err := repo.Storer.RemoveReference(branchRef); err != nil {
-			errs = append(errs, fmt.Errorf("failed to remove branch %s: %w", g.branchName, err))
+	// Check if branch exists before attempting removal. Branches that existed before the session are kept.
+	if !g.existingBranch {
+		if _, err := repo.Reference(branchRef, false); err == nil {
+			if err := repo.Storer.RemoveReference(branchRef); err != nil {
+				errs = append(errs, fmt.Errorf("failed to remove branch %s: %w", g.branchName, err))
+			}
+		} else if err != plumbing.ErrReferenceNotFound {
+			errs = append(errs, fmt.Errorf("error checking branch %s existence: %w", g.branchName, err))
 		}
-	} else if err != plumbing.ErrReferenceNotFound {
-		errs = append(errs, fmt.Errorf("error checking branch %s existence: %w", g.branchName, err))
 	}
 
 	// Prune the worktree to clean up any remaining references
@@ -155,24 +202,41 @@ func (g *GitWorktree) Cleanup() error {
 
 // Remove removes the worktree but keeps the branch
 func (g *GitWorktree) Remove() error {
-	// Remove the worktree using git command
-	if _, err := g.runGitCommand(g.repoPath, "worktree", "remove", "-f", g.worktreePath); err != nil {
+	g.unlinkConfiguredDirs()
+	g.unexcludeCopiedFiles()
+	if err := g.vcs().Remove(g.repoPath, g.worktreePath, false); err != nil {
 		return fmt.Errorf("failed to remove worktree: %w", err)
 	}
 
 	return nil
 }
 
+// ForceCleanup removes the worktree and its branch like Cleanup, even if the worktree is locked or git
+// can't remove it, e.g. because its administrative files are gone. Whatever is left of it is deleted
+// from disk and pruned.
+func (g *GitWorktree) ForceCleanup() error {
+	if g.host != "" {
+		return g.cleanupRemote()
+	}
+	if _, err := os.Stat(g.worktreePath); err == nil {
+		g.unlinkConfiguredDirs()
+		if err := g.vcs().Remove(g.repoPath, g.worktreePath, true); err != nil {
+			log.WarningLog.Printf("couldn't remove worktree %s, deleting it: %v", g.worktreePath, err)
+			if err := os.RemoveAll(g.worktreePath); err != nil {
+				return fmt.Errorf("failed to delete worktree %s: %w", g.worktreePath, err)
+			}
+		}
+	}
+	return g.Cleanup()
+}
+
 // Prune removes all working tree administrative files and directories
 func (g *GitWorktree) Prune() error {
-	if _, err := g.runGitCommand(g.repoPath, "worktree", "prune"); err != nil {
-		return fmt.Errorf("failed to prune worktrees: %w", err)
-	}
-	return nil
+	return g.vcs().Prune(g.repoPath)
 }
 
-// CleanupWorktrees removes all worktrees and their associated branches
-func CleanupWorktrees() error {
+// CleanupWorktrees removes all worktrees and their associated branches, except the branches in keep.
+func CleanupWorktrees(keep []string) error {
 	worktreesDir, err := getWorktreeDirectory()
 	if err != nil {
 		return fmt.Errorf("failed to get worktree directory: %w", err)
@@ -214,6 +278,9 @@ func CleanupWorktrees() error {
 			// Delete the branch associated with this worktree if found
 			for path, branch := range worktreeBranches {
 				if strings.Contains(path, entry.Name()) {
+					if slices.Contains(keep, branch) {
+						break
+					}
 					// Delete the branch
 					deleteCmd := exec.Command("git", "branch", "-D", branch)
 					if err := deleteCmd.Run(); err != nil {
@@ -239,29 +306,22 @@ func CleanupWorktrees() error {
 	return nil
 }
 
-// copyConfiguredFiles copies files specified in the configuration from the repo to the worktree
+// copyConfiguredFiles copies files specified in the configuration from the repo to the worktree: the
+// files, directories and patterns of copy_on_create, and the ignored dotenv files with copy_env_files.
 func (g *GitWorktree) copyConfiguredFiles() error {
 	cfg := config.LoadConfig()
-	if len(cfg.CopyOnCreate) == 0 {
+	if len(cfg.CopyOnCreate) == 0 && !cfg.CopyEnvFiles {
 		// No files to copy
 		return nil
 	}
 
 	log.InfoLog.Printf("Copying configured files to worktree...")
 
-	for _, filePath := range cfg.CopyOnCreate {
+	var copied []string
+	for _, filePath := range g.copyOnCreateFiles(cfg.CopyOnCreate, cfg.CopyEnvFiles) {
 		// Construct source and destination paths
-		srcPath := filepath.Join(g.repoPath, filePath)
-		dstPath := filepath.Join(g.worktreePath, filePath)
-
-		// Check if source file exists
-		if _, err := os.Stat(srcPath); os.IsNotExist(err) {
-			log.InfoLog.Printf("Skipping %s (not found in source repository)", filePath)
-			continue
-		} else if err != nil {
-			log.ErrorLog.Printf("Error checking file %s: %v", filePath, err)
-			continue
-		}
+		srcPath := filepath.Join(g.repoPath, filepath.FromSlash(filePath))
+		dstPath := filepath.Join(g.worktreePath, filepath.FromSlash(filePath))
 
 		// Create destination directory if needed
 		dstDir := filepath.Dir(dstPath)
@@ -277,11 +337,45 @@ func (g *GitWorktree) copyConfiguredFiles() error {
 		}
 
 		log.InfoLog.Printf("Copied %s to worktree", filePath)
+		copied = append(copied, filePath)
 	}
 
+	// The copies are usually secrets, so they're excluded to keep an agent from committing them. Files
+	// tracked in the base commit, like a committed .env.example, are left alone, so their edits show.
+	tracked := g.trackedInBase(copied)
+	g.copiedFiles = nil
+	for _, filePath := range copied {
+		if !tracked[filePath] {
+			g.copiedFiles = append(g.copiedFiles, filePath)
+		}
+	}
+	if err := g.excludeCopiedFiles(g.copiedFiles); err != nil {
+		log.ErrorLog.Printf("Failed to exclude the copied files from git: %v", err)
+	}
 	return nil
 }
 
+// trackedInBase returns which of the files, relative to the root of the worktree, are tracked in the
+// base commit.
+func (g *GitWorktree) trackedInBase(files []string) map[string]bool {
+	tracked := make(map[string]bool)
+	if len(files) == 0 {
+		return tracked
+	}
+	args := append([]string{"ls-tree", "-r", "-z", "--name-only", "--full-tree", g.GetBaseCommitSHA(), "--"}, files...)
+	output, err := g.runGitCommand(g.repoPath, args...)
+	if err != nil {
+		log.ErrorLog.Printf("Failed to list the copied files tracked in the base commit: %v", err)
+		return tracked
+	}
+	for _, name := range strings.Split(output, "\x00") {
+		if name != "" {
+			tracked[name] = true
+		}
+	}
+	return tracked
+}
+
 // copyFile copies a single file from src to dst
 func copyFile(src, dst string) error {
 	// Open source file
@@ -173,4 +173,87 @@ func TestCopyConfiguredFiles(t *testing.T) {
 		err := g.copyConfiguredFiles()
 		assert.NoError(t, err)
 	})
-}
\ No newline at end of file
+}
+func TestForceCleanup(t *testing.T) {
+	s := newStackTest(t)
+
+	// A locked worktree, and one whose administrative files are gone, so git doesn't know it anymore.
+	s.git(s.repoPath, "worktree", "lock", s.parent.GetWorktreePath())
+	require.NoError(t, os.Remove(filepath.Join(s.child.GetWorktreePath(), ".git")))
+
+	for _, g := range []*GitWorktree{s.parent, s.child} {
+		require.NoError(t, g.ForceCleanup())
+		assert.NoDirExists(t, g.GetWorktreePath())
+		assert.NotContains(t, s.git(s.repoPath, "branch", "--list"), g.GetBranchName())
+	}
+	assert.NotContains(t, s.git(s.repoPath, "worktree", "list"), "child")
+}
+
+func TestCommitChangesIdentity(t *testing.T) {
+	s := newStackTest(t)
+	require.NoError(t, config.SaveConfig(&config.Config{BranchPrefix: "test/", CommitStrategy: config.CommitStrategy{
+		AuthorName:  "Squad Agent",
+		AuthorEmail: "agent@example.com",
+	}}))
+	worktreePath := s.parent.GetWorktreePath()
+	require.NoError(t, os.WriteFile(filepath.Join(worktreePath, "agent"), []byte("agent"), 0644))
+
+	require.NoError(t, s.parent.CommitChanges("agent work"))
+	assert.Equal(t, "Squad Agent <agent@example.com>: agent work", s.git(worktreePath, "log", "-1", "--format=%an <%ae>: %s"))
+	dirty, err := s.parent.IsDirty()
+	require.NoError(t, err)
+	assert.False(t, dirty)
+}
+
+func TestBareRepository(t *testing.T) {
+	s := newStackTest(t)
+	t.Setenv("HOME", t.TempDir())
+	s.commitFile(s.repoPath, config.RepoConfigFileName, `{"check_command": "make test"}`)
+	barePath := filepath.Join(filepath.Dir(s.repoPath), "project.git")
+	s.git(s.repoPath, "clone", "--quiet", "--bare", s.repoPath, barePath)
+
+	trusted, err := config.TrustRepoConfig(barePath, func(repoConfig *config.RepoConfig) bool {
+		return repoConfig.CheckCommand == "make test"
+	})
+	require.NoError(t, err)
+	assert.True(t, trusted)
+	repoConfig, err := config.LoadRepoConfig(barePath)
+	require.NoError(t, err)
+	assert.Equal(t, "make test", repoConfig.CheckCommand)
+
+	g, branch, err := NewGitWorktree(barePath, "bare")
+	require.NoError(t, err)
+	require.NoError(t, g.Setup())
+	assert.Equal(t, barePath, g.GetRepoPath())
+	assert.Equal(t, "project", g.GetRepoName())
+	assert.True(t, g.IsBare())
+	assert.FileExists(t, filepath.Join(g.GetWorktreePath(), "README"))
+	assert.Equal(t, s.git(barePath, "rev-parse", "HEAD"), g.GetBaseCommitSHA())
+
+	// The HEAD of a bare repository isn't a checkout, even of the branch of the instance.
+	s.git(barePath, "symbolic-ref", "HEAD", "refs/heads/"+branch)
+	checkedOut, err := g.IsBranchCheckedOut()
+	require.NoError(t, err)
+	assert.False(t, checkedOut)
+
+	require.NoError(t, g.Cleanup())
+	assert.NoDirExists(t, g.GetWorktreePath())
+	assert.False(t, s.parent.IsBare())
+}
+
+func TestNewGitWorktreeFromPath(t *testing.T) {
+	s := newStackTest(t)
+
+	g, err := NewGitWorktreeFromPath(s.parent.GetWorktreePath(), "adopted")
+	require.NoError(t, err)
+	assert.Equal(t, s.repoPath, g.GetRepoPath())
+	assert.Equal(t, s.parent.GetWorktreePath(), g.GetWorktreePath())
+	assert.Equal(t, s.parent.GetBranchName(), g.GetBranchName())
+	// The branch forked from the HEAD of the repository, before the commit of the parent.
+	assert.Equal(t, s.git(s.repoPath, "rev-parse", "HEAD"), g.GetBaseCommitSHA())
+
+	_, err = NewGitWorktreeFromPath(s.repoPath, "repo")
+	assert.Error(t, err)
+	_, err = NewGitWorktreeFromPath(t.TempDir(), "elsewhere")
+	assert.Error(t, err)
+}
@@ -0,0 +1,148 @@
+package git
+
+import (
+	"claude-squad/config"
+	"claude-squad/session/remote"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+)
+
+// remoteWorktreeDirectory is where the worktrees of remote sessions are created on the remote host. It's
+// expanded by the remote shell.
+const remoteWorktreeDirectory = "~/.claude-squad/worktrees"
+
+// gitCommand returns the git command with args run in path, over ssh if host isn't empty.
+func gitCommand(host string, path string, args ...string) *exec.Cmd {
+	args = append([]string{"-C", path}, args...)
+	if host != "" {
+		return remote.Command(host, "git", args...)
+	}
+	return exec.Command("git", args...)
+}
+
+// NewRemoteGitWorktree creates a GitWorktree instance for the repository at repoPath on host. It checks out
+// branchName if it's set and creates a new branch otherwise, like NewGitWorktreeFromBranch and NewGitWorktree.
+func NewRemoteGitWorktree(host string, repoPath string, sessionName string, branchName string) (*GitWorktree, string, error) {
+	output, err := gitCommand(host, repoPath, "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to find Git repository root from path %s on %s: %w", repoPath, host, err)
+	}
+	name := strings.ReplaceAll(sanitizeBranchName(sessionName), "/", "-") + "_" + fmt.Sprintf("%x", time.Now().UnixNano())
+	tree := &GitWorktree{
+		host:         host,
+		repoPath:     strings.TrimSpace(string(output)),
+		sessionName:  sessionName,
+		worktreePath: path.Join(remoteWorktreeDirectory, name),
+	}
+
+	if branchName != "" {
+		exists, err := branchExists(host, tree.repoPath, branchName)
+		if err != nil {
+			return nil, "", err
+		}
+		if !exists {
+			return nil, "", fmt.Errorf("branch %s does not exist", branchName)
+		}
+		tree.branchName = branchName
+		tree.existingBranch = true
+		return tree, branchName, nil
+	}
+
+	cfg := config.LoadConfig()
+	branchName, err = RenderBranchName(cfg.BranchTemplate, cfg.BranchPrefix, sessionName, time.Now())
+	if err != nil {
+		return nil, "", err
+	}
+	if tree.branchName, err = uniqueBranchName(host, tree.repoPath, branchName); err != nil {
+		return nil, "", err
+	}
+	return tree, tree.branchName, nil
+}
+
+// SetHost sets the machine the repository and the worktree are on, for worktrees loaded from storage.
+func (g *GitWorktree) SetHost(host string) {
+	g.host = host
+}
+
+// Exists returns true if the worktree directory exists.
+func (g *GitWorktree) Exists() bool {
+	if g.host != "" {
+		return remote.Command(g.host, "test", "-d", g.worktreePath).Run() == nil
+	}
+	_, err := os.Stat(g.worktreePath)
+	return err == nil
+}
+
+// setupRemote creates the worktree on the remote host with git alone, since go-git can't open the
+// repository there. Configured files aren't copied to remote worktrees.
+func (g *GitWorktree) setupRemote() error {
+	// Clean up any existing worktree first
+	_, _ = g.runGitCommand(g.repoPath, "worktree", "remove", "-f", g.worktreePath) // Ignore error if worktree doesn't exist
+
+	if _, err := g.runGitCommand(g.repoPath, "rev-parse", "--verify", "--quiet", "refs/heads/"+g.branchName); err == nil {
+		if _, err := g.runGitCommand(g.repoPath, "worktree", "add", g.worktreePath, g.branchName); err != nil {
+			return fmt.Errorf("failed to create worktree from branch %s: %w", g.branchName, err)
+		}
+		if g.baseCommitSHA == "" {
+			output, err := g.runGitCommand(g.repoPath, "merge-base", "HEAD", g.branchName)
+			if err != nil {
+				return fmt.Errorf("failed to find where branch %s forked: %w", g.branchName, err)
+			}
+			g.baseCommitSHA = strings.TrimSpace(output)
+		}
+		return nil
+	}
+
+	base := "HEAD"
+	if g.baseBranch != "" {
+		base = g.baseBranch
+	}
+	output, err := g.runGitCommand(g.repoPath, "rev-parse", base)
+	if err != nil {
+		return fmt.Errorf("failed to get the commit of %s: %w", base, err)
+	}
+	headCommit := strings.TrimSpace(output)
+	if _, err := g.runGitCommand(g.repoPath, "worktree", "add", "-b", g.branchName, g.worktreePath, headCommit); err != nil {
+		return fmt.Errorf("failed to create worktree from commit %s: %w", headCommit, err)
+	}
+	g.baseCommitSHA = headCommit
+	return nil
+}
+
+// cleanupRemote removes the worktree and the branch on the remote host.
+func (g *GitWorktree) cleanupRemote() error {
+	var errs []error
+	if g.Exists() {
+		if _, err := g.runGitCommand(g.repoPath, "worktree", "remove", "-f", g.worktreePath); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	// Branches that existed before the session are kept.
+	if !g.existingBranch {
+		if _, err := g.runGitCommand(g.repoPath, "rev-parse", "--verify", "--quiet", "refs/heads/"+g.branchName); err == nil {
+			if _, err := g.runGitCommand(g.repoPath, "branch", "-D", g.branchName); err != nil {
+				errs = append(errs, fmt.Errorf("failed to remove branch %s: %w", g.branchName, err))
+			}
+		}
+	}
+	if err := g.Prune(); err != nil {
+		errs = append(errs, err)
+	}
+	return g.combineErrors(errs)
+}
+
+// pushRemoteChanges commits the changes in the remote worktree and pushes the branch to origin from
+// there. The gh CLI isn't needed on the remote host.
+func (g *GitWorktree) pushRemoteChanges(commitMessage string) error {
+	if err := g.CommitChanges(commitMessage); err != nil {
+		return err
+	}
+	if _, err := g.runGitCommand(g.worktreePath, "push", "-u", "origin", g.branchName); err != nil {
+		return fmt.Errorf("failed to push branch: %w", err)
+	}
+	return nil
+}
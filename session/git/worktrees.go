@@ -0,0 +1,46 @@
+package git
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Worktree is a linked worktree of a repository, as git lists it.
+type Worktree struct {
+	Path string
+	// Branch is the branch checked out in the worktree, empty if its HEAD is detached.
+	Branch string
+}
+
+// ListWorktrees returns the linked worktrees of the repository at repoPath, without the main one.
+func ListWorktrees(repoPath string) ([]Worktree, error) {
+	output, err := exec.Command("git", "-C", repoPath, "worktree", "list", "--porcelain").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list worktrees: %w", err)
+	}
+	return parseWorktrees(string(output)), nil
+}
+
+// parseWorktrees parses the output of `git worktree list --porcelain`, whose first entry is the main
+// worktree, or the repository itself if it's bare.
+func parseWorktrees(output string) []Worktree {
+	var worktrees []Worktree
+	for i, entry := range strings.Split(strings.TrimSpace(output), "\n\n") {
+		if i == 0 {
+			continue
+		}
+		var worktree Worktree
+		for _, line := range strings.Split(entry, "\n") {
+			if path, ok := strings.CutPrefix(line, "worktree "); ok {
+				worktree.Path = path
+			} else if branch, ok := strings.CutPrefix(line, "branch refs/heads/"); ok {
+				worktree.Branch = branch
+			}
+		}
+		if worktree.Path != "" {
+			worktrees = append(worktrees, worktree)
+		}
+	}
+	return worktrees
+}
@@ -0,0 +1,28 @@
+package git
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListWorktrees(t *testing.T) {
+	s := newStackTest(t)
+	detached := filepath.Join(t.TempDir(), "detached")
+	s.git(s.repoPath, "worktree", "add", "--detach", detached)
+
+	worktrees, err := ListWorktrees(s.repoPath)
+	require.NoError(t, err)
+	branches := make(map[string]string)
+	for _, worktree := range worktrees {
+		branches[worktree.Path] = worktree.Branch
+	}
+	// The main worktree isn't listed, and a detached worktree has no branch.
+	assert.Equal(t, map[string]string{
+		s.parent.GetWorktreePath(): s.parent.GetBranchName(),
+		s.child.GetWorktreePath():  s.child.GetBranchName(),
+		detached:                   "",
+	}, branches)
+}
@@ -0,0 +1,106 @@
+package session
+
+import (
+	"claude-squad/config"
+	"claude-squad/textutil"
+	"fmt"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+)
+
+// maxHandoffContent caps the characters of the content handed off, e.g. a large diff, so the prompt stays
+// usable.
+const maxHandoffContent = 50000
+
+// HandoffData is what the prompt of a handoff template can use.
+type HandoffData struct {
+	// From is the title of the instance handing off.
+	From string
+	// To is the title of the instance handed off to.
+	To string
+	// Branch is the branch of the instance handing off.
+	Branch string
+	// Content is what's handed off, e.g. the diff.
+	Content string
+}
+
+// HandoffContent returns the content of the instance to hand off: its diff, the last message of its agent,
+// or both summarized.
+func (i *Instance) HandoffContent(content string) (string, error) {
+	var text string
+	switch content {
+	case config.HandoffContentDiff:
+		if i.diffStats == nil || i.diffStats.IsEmpty() {
+			return "", fmt.Errorf("'%s' has no changes to hand off", i.Title)
+		}
+		text = i.diffStats.Content
+	case config.HandoffContentMessage:
+		text = i.LastAgentMessage()
+		if text == "" {
+			return "", fmt.Errorf("'%s' has no message to hand off", i.Title)
+		}
+	case config.HandoffContentSummary:
+		text = i.StageSummary()
+		if text == "" {
+			return "", fmt.Errorf("'%s' has nothing to hand off", i.Title)
+		}
+	default:
+		return "", fmt.Errorf("unknown handoff content %q", content)
+	}
+	if utf8.RuneCountInString(text) > maxHandoffContent {
+		// Cut after the last whole line, so neither a line of the diff nor a character is split.
+		text = textutil.Prefix(text, maxHandoffContent)
+		if end := strings.LastIndexByte(text, '\n'); end > 0 {
+			text = text[:end]
+		}
+		text += "\n... (cut)"
+	}
+	return text, nil
+}
+
+// HandoffPrompt renders the prompt of the template handing the content of the instance off to the other
+// one.
+func (i *Instance) HandoffPrompt(to *Instance, tmpl config.HandoffTemplate) (string, error) {
+	if i == to {
+		return "", fmt.Errorf("'%s' can't hand off to itself", i.Title)
+	}
+	content, err := i.HandoffContent(tmpl.Content)
+	if err != nil {
+		return "", err
+	}
+	t, err := template.New(tmpl.Name).Option("missingkey=error").Parse(tmpl.Prompt)
+	if err != nil {
+		return "", fmt.Errorf("invalid handoff template %q: %w", tmpl.Name, err)
+	}
+	var prompt strings.Builder
+	data := HandoffData{From: i.Title, To: to.Title, Branch: i.Branch, Content: content}
+	if err := t.Execute(&prompt, data); err != nil {
+		return "", fmt.Errorf("invalid handoff template %q: %w", tmpl.Name, err)
+	}
+	return prompt.String(), nil
+}
+
+// HandOff sends the prompt, e.g. rendered by HandoffPrompt, to the other instance, and records the handoff
+// in the audit logs of both.
+func (i *Instance) HandOff(to *Instance, templateName string, prompt string) error {
+	if !to.Started() || to.Paused() {
+		return fmt.Errorf("'%s' is not running", to.Title)
+	}
+	if err := to.SendPrompt(prompt); err != nil {
+		return err
+	}
+	i.Audit(AuditHandoff, fmt.Sprintf("%s to %s", templateName, to.Title))
+	to.Audit(AuditHandoff, fmt.Sprintf("%s from %s", templateName, i.Title))
+	return nil
+}
+
+// FindHandoffTemplate returns the template with the name.
+func FindHandoffTemplate(templates []config.HandoffTemplate, name string) (config.HandoffTemplate, error) {
+	for _, tmpl := range templates {
+		if tmpl.Name == name {
+			return tmpl, nil
+		}
+	}
+	return config.HandoffTemplate{}, fmt.Errorf("no handoff template %q", name)
+}
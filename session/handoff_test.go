@@ -0,0 +1,61 @@
+package session
+
+import (
+	"claude-squad/config"
+	"claude-squad/session/git"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandoffPrompt(t *testing.T) {
+	from := &Instance{
+		Title:     "dark-mode",
+		Branch:    "me/dark-mode",
+		Program:   "aider",
+		diffStats: &git.DiffStats{Added: 1, Content: "diff --git a/theme.go b/theme.go\n+dark"},
+	}
+	to := &Instance{Title: "reviewer"}
+	templates := (&config.Config{}).GetHandoffTemplates()
+
+	review, err := FindHandoffTemplate(templates, "review")
+	require.NoError(t, err)
+	prompt, err := from.HandoffPrompt(to, review)
+	require.NoError(t, err)
+	assert.Contains(t, prompt, "branch me/dark-mode")
+	assert.Contains(t, prompt, "```diff\ndiff --git a/theme.go b/theme.go\n+dark\n```")
+
+	custom := config.HandoffTemplate{Name: "tests", Content: config.HandoffContentDiff, Prompt: "{{.To}}, test what {{.From}} did:\n{{.Content}}"}
+	prompt, err = from.HandoffPrompt(to, custom)
+	require.NoError(t, err)
+	assert.Equal(t, "reviewer, test what dark-mode did:\ndiff --git a/theme.go b/theme.go\n+dark", prompt)
+
+	// Only claude has a last message, and an instance can't hand off to itself.
+	relay, err := FindHandoffTemplate(templates, "relay")
+	require.NoError(t, err)
+	_, err = from.HandoffPrompt(to, relay)
+	assert.EqualError(t, err, "'dark-mode' has no message to hand off")
+	_, err = from.HandoffPrompt(from, review)
+	assert.Error(t, err)
+	_, err = from.HandoffPrompt(to, config.HandoffTemplate{Name: "bad", Content: config.HandoffContentDiff, Prompt: "{{.Nope}}"})
+	assert.ErrorContains(t, err, `invalid handoff template "bad"`)
+
+	from.diffStats = &git.DiffStats{}
+	_, err = from.HandoffPrompt(to, review)
+	assert.EqualError(t, err, "'dark-mode' has no changes to hand off")
+	_, err = FindHandoffTemplate(templates, "missing")
+	assert.Error(t, err)
+}
+
+func TestHandoffContentIsCut(t *testing.T) {
+	line := "+" + strings.Repeat("é", 99) + "\n"
+	from := &Instance{Title: "dark-mode", diffStats: &git.DiffStats{Added: 1000, Content: strings.Repeat(line, 1000)}}
+	content, err := from.HandoffContent(config.HandoffContentDiff)
+	require.NoError(t, err)
+	assert.True(t, utf8.ValidString(content))
+	// The last whole line before the limit is kept.
+	assert.Equal(t, strings.Repeat(line, maxHandoffContent/utf8.RuneCountInString(line))+"... (cut)", content)
+}
@@ -0,0 +1,205 @@
+package session
+
+import (
+	"bufio"
+	"claude-squad/config"
+	"claude-squad/log"
+	"claude-squad/session/claude"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// HistoryOutcome is how an instance in the history ended.
+type HistoryOutcome string
+
+const (
+	// HistoryKilled is an instance killed with work that wasn't merged, or no work at all.
+	HistoryKilled HistoryOutcome = "killed"
+	// HistoryMerged is an instance killed once its branch was merged.
+	HistoryMerged HistoryOutcome = "merged"
+	// HistoryArchived is an instance archived, which can be resurrected.
+	HistoryArchived HistoryOutcome = "archived"
+)
+
+// HistoryEntry is an instance that was killed or archived, as it was when it ended.
+type HistoryEntry struct {
+	Title     string    `json:"title"`
+	Branch    string    `json:"branch"`
+	Program   string    `json:"program"`
+	Provider  string    `json:"provider,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	EndedAt   time.Time `json:"ended_at"`
+	// Added, Removed and Files are the final diff stats of the instance.
+	Added   int `json:"added"`
+	Removed int `json:"removed"`
+	Files   int `json:"files"`
+	// Tokens are the tokens its Claude conversations used. The conversations of a remote instance are on
+	// its host, so they aren't counted.
+	Tokens  claude.Usage   `json:"tokens"`
+	Outcome HistoryOutcome `json:"outcome"`
+}
+
+// Duration returns how long the instance existed.
+func (e HistoryEntry) Duration() time.Duration {
+	return e.EndedAt.Sub(e.CreatedAt)
+}
+
+func getHistoryPath() (string, error) {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "history.jsonl"), nil
+}
+
+// RecordHistory appends the entry to the history. The history is append-only JSON lines, like the audit
+// log, so the daemon and the app can both write to it.
+func RecordHistory(entry HistoryEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal history entry: %w", err)
+	}
+	path, err := getHistoryPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create the config directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open history: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	return nil
+}
+
+// LoadHistory returns the entries of the history, oldest first.
+func LoadHistory() ([]HistoryEntry, error) {
+	path, err := getHistoryPath()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open history: %w", err)
+	}
+	defer f.Close()
+
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// Skip lines cut by a crash.
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	return entries, nil
+}
+
+// recordHistory adds the instance to the history as it ends, with its last diff stats. Failures are
+// logged, not returned, so they never get in the way of killing or archiving it.
+func (i *Instance) recordHistory(outcome HistoryOutcome) {
+	entry := HistoryEntry{
+		Title:     i.Title,
+		Branch:    i.Branch,
+		Program:   i.Program,
+		Provider:  i.Provider,
+		CreatedAt: i.CreatedAt,
+		EndedAt:   time.Now(),
+		Outcome:   outcome,
+	}
+	if stats := i.GetDiffStats(); stats != nil && stats.Error == nil {
+		entry.Added, entry.Removed, entry.Files = stats.Added, stats.Removed, len(stats.ChangedFiles())
+	}
+	if i.gitWorktree != nil {
+		if outcome == HistoryKilled {
+			if merged, err := i.gitWorktree.IsMerged(); err != nil {
+				log.WarningLog.Printf("could not tell if the branch of %s was merged: %v", i.Title, err)
+			} else if merged {
+				entry.Outcome = HistoryMerged
+			}
+		}
+		if i.Host == "" {
+			entry.Tokens = conversationUsage(i.gitWorktree.GetWorktreePath())
+		}
+	}
+	if err := RecordHistory(entry); err != nil {
+		log.ErrorLog.Printf("could not record %s in the history: %v", i.Title, err)
+	}
+}
+
+// conversationUsage returns the tokens all the Claude conversations of the project at path used.
+func conversationUsage(path string) claude.Usage {
+	var usage claude.Usage
+	conversations, err := claude.ListConversations(path)
+	if err != nil {
+		log.WarningLog.Printf("failed to list the conversations of %s: %v", path, err)
+		return usage
+	}
+	for _, conversation := range conversations {
+		read, err := claude.ReadUsage(conversation.Path)
+		if err != nil {
+			log.WarningLog.Printf("failed to read the token usage of %s: %v", conversation.Path, err)
+			continue
+		}
+		usage.Add(read)
+	}
+	return usage
+}
+
+// FormatHistory formats the entries as a table, one line each, followed by their totals.
+func FormatHistory(entries []HistoryEntry) string {
+	if len(entries) == 0 {
+		return "No sessions in the history."
+	}
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ENDED\tTITLE\tBRANCH\tOUTCOME\tDURATION\tCHANGES\tTOKENS")
+	var total HistoryEntry
+	var duration time.Duration
+	outcomes := make(map[HistoryOutcome]int)
+	for _, entry := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t+%d -%d (%d files)\t%s\n", entry.EndedAt.Local().Format("2006-01-02 15:04"),
+			entry.Title, entry.Branch, entry.Outcome, FormatBudget(entry.Duration()), entry.Added, entry.Removed,
+			entry.Files, formatTokens(entry.Tokens.Total()))
+		total.Added += entry.Added
+		total.Removed += entry.Removed
+		total.Tokens.Add(entry.Tokens)
+		duration += entry.Duration()
+		outcomes[entry.Outcome]++
+	}
+	w.Flush()
+	fmt.Fprintf(&b, "\n%d sessions: %d merged, %d killed, %d archived. %s in total, +%d -%d, %s tokens.",
+		len(entries), outcomes[HistoryMerged], outcomes[HistoryKilled], outcomes[HistoryArchived],
+		FormatBudget(duration), total.Added, total.Removed, formatTokens(total.Tokens.Total()))
+	return b.String()
+}
+
+// formatTokens formats a number of tokens, e.g. "850", "12.5k" or "3.2M".
+func formatTokens(tokens int) string {
+	switch {
+	case tokens >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(tokens)/1_000_000)
+	case tokens >= 1000:
+		return fmt.Sprintf("%.1fk", float64(tokens)/1000)
+	default:
+		return fmt.Sprint(tokens)
+	}
+}
@@ -0,0 +1,55 @@
+package session
+
+import (
+	"claude-squad/config"
+	"claude-squad/session/claude"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistory(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	entries, err := LoadHistory()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+	assert.Equal(t, "No sessions in the history.", FormatHistory(entries))
+
+	created := time.Date(2026, 10, 16, 9, 0, 0, 0, time.Local)
+	require.NoError(t, RecordHistory(HistoryEntry{Title: "fix login", Branch: "me/fix-login", CreatedAt: created,
+		EndedAt: created.Add(95 * time.Minute), Added: 40, Removed: 2, Files: 3,
+		Tokens: claude.Usage{InputTokens: 1000, OutputTokens: 500}, Outcome: HistoryMerged}))
+	require.NoError(t, RecordHistory(HistoryEntry{Title: "spike", Branch: "me/spike", CreatedAt: created,
+		EndedAt: created.Add(20 * time.Minute), Outcome: HistoryKilled}))
+
+	// Lines cut by a crash are skipped.
+	configDir, err := config.GetConfigDir()
+	require.NoError(t, err)
+	f, err := os.OpenFile(filepath.Join(configDir, "history.jsonl"), os.O_APPEND|os.O_WRONLY, 0600)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"title": "cut`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	entries, err = LoadHistory()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "fix login", entries[0].Title)
+	assert.Equal(t, 95*time.Minute, entries[0].Duration())
+	assert.Equal(t, 1500, entries[0].Tokens.Total())
+
+	assert.Equal(t, "ENDED             TITLE      BRANCH        OUTCOME  DURATION  CHANGES           TOKENS\n"+
+		"2026-10-16 10:35  fix login  me/fix-login  merged   1h35m     +40 -2 (3 files)  1.5k\n"+
+		"2026-10-16 09:20  spike      me/spike      killed   20m       +0 -0 (0 files)   0\n"+
+		"\n2 sessions: 1 merged, 1 killed, 0 archived. 1h55m in total, +40 -2, 1.5k tokens.", FormatHistory(entries))
+}
+
+func TestFormatTokens(t *testing.T) {
+	assert.Equal(t, "850", formatTokens(850))
+	assert.Equal(t, "12.5k", formatTokens(12_500))
+	assert.Equal(t, "3.2M", formatTokens(3_210_000))
+}
@@ -0,0 +1,190 @@
+package session
+
+import (
+	"bytes"
+	"claude-squad/config"
+	"claude-squad/log"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HookState is what claude is doing according to the events its hooks reported.
+type HookState int
+
+const (
+	// HookUnknown is the state until the first event, the status is then read from the pane.
+	HookUnknown HookState = iota
+	// HookWorking is when claude works on a prompt.
+	HookWorking
+	// HookWaiting is when claude waits for the permission to use a tool.
+	HookWaiting
+	// HookDone is when claude finished its turn and waits for the next prompt.
+	HookDone
+)
+
+// hookEvents are the Claude Code hook events the state is derived from.
+var hookEvents = []string{"SessionStart", "UserPromptSubmit", "PreToolUse", "PostToolUse", "Notification", "Stop"}
+
+// HookEvent is an event Claude Code reported through a hook.
+type HookEvent struct {
+	Time time.Time `json:"time"`
+	Name string    `json:"hook_event_name"`
+	// Message is the text of a notification, e.g. "Claude needs your permission to use Bash".
+	Message string `json:"message,omitempty"`
+}
+
+// hookPaths returns the paths of the hook settings of the instance created at createdAt and of the file
+// its hooks record their events in.
+func hookPaths(title string, createdAt time.Time) (settingsPath string, eventsPath string, err error) {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return "", "", err
+	}
+	name := filepath.Join(configDir, "hooks", fmt.Sprintf("%s-%d", slug(title, 64, "instance"), createdAt.Unix()))
+	return name + ".settings.json", name + ".jsonl", nil
+}
+
+// RecordHookEvent appends the event Claude Code passed to a hook on stdin to the events file. It's run by
+// the hook command of the instance.
+func RecordHookEvent(eventsPath string, input []byte) error {
+	var event HookEvent
+	if err := json.Unmarshal(input, &event); err != nil {
+		return fmt.Errorf("failed to parse hook event: %w", err)
+	}
+	event.Time = time.Now()
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(eventsPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open hook events: %w", err)
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// hookSettings returns Claude Code settings that run command on the events the state is derived from.
+func hookSettings(command string) ([]byte, error) {
+	type hook struct {
+		Type    string `json:"type"`
+		Command string `json:"command"`
+	}
+	type matcher struct {
+		Matcher string `json:"matcher,omitempty"`
+		Hooks   []hook `json:"hooks"`
+	}
+	hooks := make(map[string][]matcher)
+	for _, event := range hookEvents {
+		m := matcher{Hooks: []hook{{Type: "command", Command: command}}}
+		if event == "PreToolUse" || event == "PostToolUse" {
+			m.Matcher = "*"
+		}
+		hooks[event] = []matcher{m}
+	}
+	return json.MarshalIndent(map[string]any{"hooks": hooks}, "", "  ")
+}
+
+// withHooks returns program with the settings whose hooks report the events of the instance, if the
+// hooks are enabled and program is claude.
+func (i *Instance) withHooks(program string) string {
+	if i.Host != "" || path.Base(programBinary(program)) != "claude" || !config.LoadConfig().ClaudeHooks {
+		return program
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		log.WarningLog.Printf("failed to find the executable for the hooks of %s: %v", i.Title, err)
+		return program
+	}
+	settingsPath, eventsPath, err := hookPaths(i.Title, i.CreatedAt)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(settingsPath), 0700)
+	}
+	var settings []byte
+	if err == nil {
+		settings, err = hookSettings(strconv.Quote(executable) + " hook " + strconv.Quote(eventsPath))
+	}
+	if err == nil {
+		err = os.WriteFile(settingsPath, settings, 0600)
+	}
+	if err != nil {
+		log.WarningLog.Printf("failed to write the hook settings of %s: %v", i.Title, err)
+		return program
+	}
+	i.hookEventsPath = eventsPath
+	return program + " --settings " + strconv.Quote(settingsPath)
+}
+
+// updateHookState reads the events the hooks recorded since the last update, and returns the state they
+// leave claude in.
+func (i *Instance) updateHookState() HookState {
+	if i.hookEventsPath == "" {
+		return HookUnknown
+	}
+	f, err := os.Open(i.hookEventsPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.WarningLog.Printf("failed to open the hook events of %s: %v", i.Title, err)
+		}
+		return i.hookState
+	}
+	defer f.Close()
+	if _, err := f.Seek(i.hookOffset, io.SeekStart); err != nil {
+		return i.hookState
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return i.hookState
+	}
+	// A hook may be writing the last line.
+	end := bytes.LastIndexByte(data, '\n')
+	if end < 0 {
+		return i.hookState
+	}
+	i.hookOffset += int64(end + 1)
+	for _, line := range bytes.Split(data[:end], []byte("\n")) {
+		var event HookEvent
+		if err := json.Unmarshal(line, &event); err != nil {
+			continue
+		}
+		i.hookState = hookStateAfter(event)
+	}
+	return i.hookState
+}
+
+// hookStateAfter returns the state of claude after the event.
+func hookStateAfter(event HookEvent) HookState {
+	switch event.Name {
+	case "UserPromptSubmit", "PreToolUse", "PostToolUse":
+		return HookWorking
+	case "Notification":
+		if strings.Contains(event.Message, "permission") {
+			return HookWaiting
+		}
+		return HookDone
+	default:
+		// SessionStart and Stop.
+		return HookDone
+	}
+}
+
+// removeHookFiles removes the hook settings and the recorded events of the instance.
+func (i *Instance) removeHookFiles() {
+	settingsPath, eventsPath, err := hookPaths(i.Title, i.CreatedAt)
+	if err != nil {
+		return
+	}
+	for _, file := range []string{settingsPath, eventsPath} {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			log.WarningLog.Printf("failed to remove %s: %v", file, err)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package session
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHookState(t *testing.T) {
+	eventsPath := filepath.Join(t.TempDir(), "events.jsonl")
+	instance := &Instance{Title: "fix-login", hookEventsPath: eventsPath}
+	record := func(event string) {
+		require.NoError(t, RecordHookEvent(eventsPath, []byte(event)))
+	}
+
+	// No events yet, the pane tells the status.
+	assert.Equal(t, HookUnknown, instance.updateHookState())
+
+	record(`{"session_id":"abc","hook_event_name":"SessionStart","source":"startup"}`)
+	assert.Equal(t, HookDone, instance.updateHookState())
+
+	record(`{"session_id":"abc","hook_event_name":"UserPromptSubmit","prompt":"fix the login"}`)
+	record(`{"session_id":"abc","hook_event_name":"PreToolUse","tool_name":"Bash"}`)
+	assert.Equal(t, HookWorking, instance.updateHookState())
+
+	record(`{"session_id":"abc","hook_event_name":"Notification","message":"Claude needs your permission to use Bash"}`)
+	assert.Equal(t, HookWaiting, instance.updateHookState())
+
+	record(`{"session_id":"abc","hook_event_name":"PostToolUse","tool_name":"Bash"}`)
+	record(`{"session_id":"abc","hook_event_name":"Stop"}`)
+	assert.Equal(t, HookDone, instance.updateHookState())
+
+	// A line being written isn't read until it's complete.
+	f, err := os.OpenFile(eventsPath, os.O_APPEND|os.O_WRONLY, 0600)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"hook_event_name":"UserPrompt`)
+	require.NoError(t, err)
+	assert.Equal(t, HookDone, instance.updateHookState())
+	_, err = f.WriteString(`Submit"}` + "\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	assert.Equal(t, HookWorking, instance.updateHookState())
+
+	assert.Error(t, RecordHookEvent(eventsPath, []byte("not json")))
+}
+
+func TestHookSettings(t *testing.T) {
+	data, err := hookSettings(`"/usr/local/bin/cs" hook "/tmp/events.jsonl"`)
+	require.NoError(t, err)
+	var settings struct {
+		Hooks map[string][]struct {
+			Matcher string `json:"matcher"`
+			Hooks   []struct {
+				Type    string `json:"type"`
+				Command string `json:"command"`
+			} `json:"hooks"`
+		} `json:"hooks"`
+	}
+	require.NoError(t, json.Unmarshal(data, &settings))
+	assert.Len(t, settings.Hooks, len(hookEvents))
+	assert.Equal(t, "*", settings.Hooks["PreToolUse"][0].Matcher)
+	assert.Equal(t, "command", settings.Hooks["Stop"][0].Hooks[0].Type)
+	assert.Equal(t, `"/usr/local/bin/cs" hook "/tmp/events.jsonl"`, settings.Hooks["Stop"][0].Hooks[0].Command)
+}
@@ -1,15 +1,22 @@
 package session
 
 import (
+	"claude-squad/config"
 	"claude-squad/log"
+	"claude-squad/session/claude"
 	"claude-squad/session/git"
-	"claude-squad/session/tmux"
+	"claude-squad/tracing"
+	"context"
+	"errors"
 	"io"
 	"path/filepath"
+	"runtime"
 
 	"fmt"
+	"maps"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/atotto/clipboard"
@@ -26,8 +33,40 @@ const (
 	Loading
 	// Paused is if the instance is paused (worktree removed but branch preserved).
 	Paused
+	// NeedsAuth is if claude is waiting for the user to log in again.
+	NeedsAuth
+	// NeedsAttention is if the program waits on a prompt auto-yes doesn't answer.
+	NeedsAttention
+	// Gone is if the terminal session of the instance is gone, e.g. because the machine rebooted.
+	Gone
+	// NeedsApproval is if auto-yes holds a permission prompt its policy doesn't approve, for the user to
+	// approve or deny.
+	NeedsApproval
 )
 
+func (s Status) String() string {
+	switch s {
+	case Running:
+		return "running"
+	case Ready:
+		return "ready"
+	case Loading:
+		return "loading"
+	case Paused:
+		return "paused"
+	case NeedsAuth:
+		return "needs auth"
+	case NeedsAttention:
+		return "needs attention"
+	case Gone:
+		return "session gone"
+	case NeedsApproval:
+		return "needs approval"
+	default:
+		return "unknown"
+	}
+}
+
 // Instance is a running instance of claude code.
 type Instance struct {
 	// Title is the title of the instance.
@@ -40,6 +79,9 @@ type Instance struct {
 	Status Status
 	// Program is the program to run in the instance.
 	Program string
+	// Provider is the name of the configured provider whose program and environment the instance runs, if
+	// it was created for one.
+	Provider string
 	// Height is the height of the instance.
 	Height int
 	// Width is the width of the instance.
@@ -54,15 +96,139 @@ type Instance struct {
 	Prompt string
 	// ClaudeResume indicates if this instance should start with claude --resume
 	ClaudeResume bool
+	// ClaudeSessionID is the session ID of the Claude conversation the agent of the instance last wrote
+	// to, which it resumes when the instance is resumed.
+	ClaudeSessionID string
+	// Group is the name of the fan-out comparison group the instance belongs to, if any.
+	Group string
+	// Scopes are the directories, relative to the repository root, the instance owns. Empty means the
+	// whole repository.
+	Scopes []string
+	// Notes are free-form notes about the instance, e.g. what to look at before merging its branch.
+	Notes string
+	// Todos are the checklist of the instance, see OpenTodos.
+	Todos []Todo
+	// Parent is the title of the instance this one is stacked on, if any.
+	Parent string
+	// ParentBranch is the branch of the parent instance, which the branch of this instance is based on.
+	ParentBranch string
+	// TouchedFiles are the files the instance changed over its lifetime, relative to the repository root.
+	TouchedFiles []string
+	// ServiceProject is the docker compose project of the instance's services, if they were started.
+	ServiceProject string
+	// ServicePorts are the host ports of the running services, by environment variable.
+	ServicePorts map[string]int
+	// Teardown are the commands of the instance's template run before its worktree is removed.
+	Teardown []string
+	// Host is the machine the worktree and the terminal session are on over ssh, if the instance is remote.
+	Host string
+	// Pipeline is the stage of a pipeline run the instance runs, if any.
+	Pipeline *PipelineStage
+	// Checks is the result of the last run of the check command of the repository, if any.
+	Checks *CheckResult
+	// Suspended is true if the instance was paused by Suspend, to be resumed by ResumeSuspended.
+	Suspended bool
+	// Errors is the history of the errors of the git and tmux operations of the instance, oldest first.
+	Errors []ErrorRecord
+	// ExternalResources are the names of the external resources the instance uses in turn with the other
+	// instances requiring them.
+	ExternalResources []string
+	// WaitingForResources is true while the instance waits for its external resources to be free. Its
+	// prompts are queued meanwhile.
+	WaitingForResources bool
+	// QueuedPrompts are the prompts held back while the instance waits for its external resources or for
+	// a running slot.
+	QueuedPrompts []string
+	// QueuedAt is when the instance was queued for a running slot, see max_running_instances. A queued
+	// instance is paused until a slot is free. It's zero if the instance isn't queued.
+	QueuedAt time.Time
+	// TimeBudget is how long the instance may run each time it starts or resumes, before it's asked to
+	// wrap up and then paused. Zero is no limit.
+	TimeBudget time.Duration
+	// Deadline is when the time budget of the instance runs out, zero if it has none.
+	Deadline time.Time
+	// WrappedUp is true once the wrap-up prompt was sent, as the time budget is nearly exhausted.
+	WrappedUp bool
+	// SplitRequested is true once the split prompt was sent, as the diff of the instance is past the flag
+	// limit of the diff budget.
+	SplitRequested bool
 
 	// DiffStats stores the current git diff statistics
 	diffStats *git.DiffStats
+	// secrets are the environment variables injected from the secret env files. They are redacted from
+	// the preview and the diff.
+	secrets map[string]string
+	// providerEnv is the environment of the provider of the instance, see loadSecrets.
+	providerEnv map[string]string
+	// scopeAnnounced is true once the scope and linked directories instructions have been sent to the
+	// program.
+	scopeAnnounced bool
+	// reviewComments are the comments on the diff waiting to be sent to the program.
+	reviewComments []ReviewComment
+	// checksRunning is true while the check command runs.
+	checksRunning bool
+	// warmup tracks the warmup commands of the worktree, if they were started.
+	warmup *warmup
+	// actor is the automation the audit events are recorded as caused by, if any.
+	actor string
+	// errorsMu guards Errors, which are recorded from the commands of the app too.
+	errorsMu sync.Mutex
+	// setupProgress is the progress of the setup of the worktree while the instance is Loading.
+	setupProgress   string
+	setupProgressMu sync.Mutex
+	// resources is the last resource usage sample of the process tree.
+	resources *resourceSample
+	// behindParent is true if the parent branch has commits this instance's branch is not based on.
+	behindParent bool
+	// upstream is how far the branch is from the upstream base branch, if it was checked.
+	upstream *UpstreamStatus
+	// forkOf is the instance this one forks until it's started, if any.
+	forkOf *Instance
+	// hookEventsPath is the file the hooks of claude record their events in, if they're enabled.
+	hookEventsPath string
+	// hookOffset is how much of the hook events file was read, and hookState the state it left claude in.
+	hookOffset int64
+	hookState  HookState
+	// conflicts are the files of a stopped restack whose conflicts were handed off to the agent.
+	conflicts []string
+	// rateLimitRetriedAt is when the agent was last asked to retry after a rate limit.
+	rateLimitRetriedAt time.Time
+	// diffWatcher tells when the files of the worktree changed, so the diff is only recomputed then.
+	diffWatcher *git.Watcher
+	// diffStatsAt is when the diff stats were last computed.
+	diffStatsAt time.Time
+	// diffPolling is true if the worktree couldn't be watched.
+	diffPolling bool
+	// readyAt is when the status last changed to Ready.
+	readyAt time.Time
+	// quietSince is when the pane last changed, for the ready detection.
+	quietSince time.Time
+	// heldPrompt is the kind of the prompt held for approval while the status is NeedsApproval.
+	heldPrompt PromptKind
+	// exited is true once the exit of the program, kept by its ready rule, was recorded.
+	exited bool
+	// autoCommitAt is when the commit strategy last committed, or considered committing, the changes.
+	autoCommitAt time.Time
+	// terminalName is the name of the terminal session of the instance, picked when it's created.
+	terminalName string
+	// paneLogTail are the last lines appended to the output log, nil until they're read from it.
+	paneLogTail []string
+	// paneLogMu guards paneLogTail and the output log, which are appended to in the background too.
+	paneLogMu sync.Mutex
+	// conversationTimes are when the conversations of the Claude project of the worktree were last
+	// written when the agent started, nil if the conversations aren't tracked, and conversationCheckedAt
+	// is when they were last checked.
+	conversationTimes     map[string]time.Time
+	conversationCheckedAt time.Time
+	// pausedScreen is the screen of the instance when it was paused, shown instead of its preview until
+	// it's resumed.
+	pausedScreen string
 
 	// The below fields are initialized upon calling Start().
 
 	started bool
-	// tmuxSession is the tmux session for the instance.
-	tmuxSession *tmux.TmuxSession
+	// tmuxSession is the terminal session for the instance. It is a tmux session, except on Windows.
+	tmuxSession Terminal
 	// gitWorktree is the git worktree for the instance.
 	gitWorktree *git.GitWorktree
 }
@@ -70,26 +236,56 @@ type Instance struct {
 // ToInstanceData converts an Instance to its serializable form
 func (i *Instance) ToInstanceData() InstanceData {
 	data := InstanceData{
-		Title:     i.Title,
-		Path:      i.Path,
-		Branch:    i.Branch,
-		Status:    i.Status,
-		Height:    i.Height,
-		Width:     i.Width,
-		CreatedAt: i.CreatedAt,
-		UpdatedAt: time.Now(),
-		Program:   i.Program,
-		AutoYes:   i.AutoYes,
+		Title:          i.Title,
+		Path:           i.Path,
+		Branch:         i.Branch,
+		Status:         i.Status,
+		Height:         i.Height,
+		Width:          i.Width,
+		CreatedAt:      i.CreatedAt,
+		UpdatedAt:      time.Now(),
+		Program:        i.Program,
+		AutoYes:        i.AutoYes,
+		Group:          i.Group,
+		Scopes:         i.Scopes,
+		Notes:          i.Notes,
+		Todos:          i.Todos,
+		Parent:         i.Parent,
+		ParentBranch:   i.ParentBranch,
+		TouchedFiles:   i.TouchedFiles,
+		ServiceProject: i.ServiceProject,
+		ServicePorts:   i.ServicePorts,
+		Teardown:       i.Teardown,
+		Host:           i.Host,
+		Pipeline:       i.Pipeline,
+		Checks:         i.Checks,
+		Suspended:      i.Suspended,
+		Errors:         i.ErrorHistory(),
+		TerminalName:   i.terminalName,
+
+		ExternalResources:   i.ExternalResources,
+		WaitingForResources: i.WaitingForResources,
+		QueuedPrompts:       i.QueuedPrompts,
+		QueuedAt:            i.QueuedAt,
+		ClaudeSessionID:     i.ClaudeSessionID,
+		TimeBudget:          i.TimeBudget,
+		Deadline:            i.Deadline,
+		WrappedUp:           i.WrappedUp,
+		SplitRequested:      i.SplitRequested,
+		Provider:            i.Provider,
 	}
 
 	// Only include worktree data if gitWorktree is initialized
 	if i.gitWorktree != nil {
 		data.Worktree = GitWorktreeData{
-			RepoPath:      i.gitWorktree.GetRepoPath(),
-			WorktreePath:  i.gitWorktree.GetWorktreePath(),
-			SessionName:   i.Title,
-			BranchName:    i.gitWorktree.GetBranchName(),
-			BaseCommitSHA: i.gitWorktree.GetBaseCommitSHA(),
+			RepoPath:       i.gitWorktree.GetRepoPath(),
+			WorktreePath:   i.gitWorktree.GetWorktreePath(),
+			SessionName:    i.Title,
+			BranchName:     i.gitWorktree.GetBranchName(),
+			BaseCommitSHA:  i.gitWorktree.GetBaseCommitSHA(),
+			ExistingBranch: i.gitWorktree.IsExistingBranch(),
+			VCS:            i.gitWorktree.VCS(),
+			CopiedFiles:    i.gitWorktree.CopiedFiles(),
 		}
 	}
 
@@ -108,21 +304,49 @@ func (i *Instance) ToInstanceData() InstanceData {
 // FromInstanceData creates a new Instance from serialized data
 func FromInstanceData(data InstanceData) (*Instance, error) {
 	instance := &Instance{
-		Title:     data.Title,
-		Path:      data.Path,
-		Branch:    data.Branch,
-		Status:    data.Status,
-		Height:    data.Height,
-		Width:     data.Width,
-		CreatedAt: data.CreatedAt,
-		UpdatedAt: data.UpdatedAt,
-		Program:   data.Program,
+		Title:          data.Title,
+		Path:           data.Path,
+		Branch:         data.Branch,
+		Status:         data.Status,
+		Height:         data.Height,
+		Width:          data.Width,
+		CreatedAt:      data.CreatedAt,
+		UpdatedAt:      data.UpdatedAt,
+		Program:        data.Program,
+		Group:          data.Group,
+		Scopes:         data.Scopes,
+		Notes:          data.Notes,
+		Todos:          data.Todos,
+		Parent:         data.Parent,
+		ParentBranch:   data.ParentBranch,
+		TouchedFiles:   data.TouchedFiles,
+		ServiceProject: data.ServiceProject,
+		ServicePorts:   data.ServicePorts,
+		Teardown:       data.Teardown,
+		Host:           data.Host,
+		Pipeline:       data.Pipeline,
+		Checks:         data.Checks,
+		Suspended:      data.Suspended,
+		Errors:         data.Errors,
+		terminalName:   data.TerminalName,
+
+		ExternalResources:   data.ExternalResources,
+		WaitingForResources: data.WaitingForResources,
+		QueuedPrompts:       data.QueuedPrompts,
+		QueuedAt:            data.QueuedAt,
+		ClaudeSessionID:     data.ClaudeSessionID,
+		TimeBudget:          data.TimeBudget,
+		Deadline:            data.Deadline,
+		WrappedUp:           data.WrappedUp,
+		SplitRequested:      data.SplitRequested,
+		Provider:            data.Provider,
 		gitWorktree: git.NewGitWorktreeFromStorage(
 			data.Worktree.RepoPath,
 			data.Worktree.WorktreePath,
 			data.Worktree.SessionName,
 			data.Worktree.BranchName,
 			data.Worktree.BaseCommitSHA,
+			data.Worktree.ExistingBranch,
 		),
 		diffStats: &git.DiffStats{
 			Added:   data.DiffStats.Added,
@@ -131,11 +355,18 @@ func FromInstanceData(data InstanceData) (*Instance, error) {
 		},
 	}
 
+	instance.gitWorktree.SetHost(data.Host)
+	instance.gitWorktree.SetVCS(data.Worktree.VCS)
+	instance.gitWorktree.SetCopiedFiles(data.Worktree.CopiedFiles)
+
 	if instance.Paused() {
 		instance.started = true
-		instance.tmuxSession = tmux.NewTmuxSession(instance.Title, instance.Program)
+		instance.tmuxSession = instance.makeTerminal(instance.Program)
 	} else {
-		if err := instance.Start(false); err != nil {
+		if err := instance.Start(false); errors.Is(err, ErrRestoreFailed) {
+			// Keep the instance, so the restore can be retried without losing its worktree.
+			log.ErrorLog.Printf("%s: %v", instance.Title, err)
+		} else if err != nil {
 			return nil, err
 		}
 	}
@@ -151,8 +382,38 @@ type InstanceOptions struct {
 	Path string
 	// Program is the program to run in the instance (e.g. "claude", "aider --model ollama_chat/gemma3:1b")
 	Program string
+	// Provider is the name of the configured provider the instance runs, if any. Its program replaces
+	// Program, and its environment is set for it.
+	Provider string
 	// If AutoYes is true, then
 	AutoYes bool
+	// Group is the fan-out comparison group of the instance, if any.
+	Group string
+	// Scopes are the directories the instance owns, if any.
+	Scopes []string
+	// Parent is the instance to stack the new instance on, if any. Its branch is the base of the new
+	// instance's branch instead of HEAD.
+	Parent *Instance
+	// Branch is an existing branch to check out in the instance instead of creating a new one, if any.
+	// It's kept when the instance is killed.
+	Branch string
+	// Teardown are commands to run in the worktree before it's removed, in addition to the configured ones.
+	Teardown []string
+	// Host is the machine to create the instance on over ssh, if any. Path is then a path on that machine.
+	Host string
+	// Pipeline is the stage of a pipeline run the instance runs, if any.
+	Pipeline *PipelineStage
+	// Fork is the instance the new instance forks, if any. Its branch starts at the tip of the branch of
+	// Fork, with the uncommitted changes of Fork, and claude resumes a copy of its last conversation.
+	Fork *Instance
+	// Actor is the automation creating the instance, e.g. "slack:U0123", recorded in its audit log. Empty
+	// for a human.
+	Actor string
+	// ExternalResources are the names of the external resources the instance requires, if any.
+	ExternalResources []string
+	// TimeBudget is how long the instance may run before it's wrapped up and paused. Zero is the
+	// default time budget of the config.
+	TimeBudget time.Duration
 }
 
 func NewInstance(opts InstanceOptions) (*Instance, error) {
@@ -163,8 +424,15 @@ func NewInstance(opts InstanceOptions) (*Instance, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to get absolute path: %w", err)
 	}
+	if opts.Host != "" {
+		if runtime.GOOS == "windows" {
+			return nil, fmt.Errorf("remote instances aren't supported on Windows")
+		}
+		// The path is on the remote host.
+		absPath = opts.Path
+	}
 
-	return &Instance{
+	instance := &Instance{
 		Title:     opts.Title,
 		Status:    Ready,
 		Path:      absPath,
@@ -174,7 +442,47 @@ func NewInstance(opts InstanceOptions) (*Instance, error) {
 		CreatedAt: t,
 		UpdatedAt: t,
 		AutoYes:   false,
-	}, nil
+		Group:     opts.Group,
+		Scopes:    NormalizeScopes(opts.Scopes),
+		Branch:    opts.Branch,
+		Teardown:  opts.Teardown,
+		Host:      opts.Host,
+		Pipeline:  opts.Pipeline,
+
+		TimeBudget:        opts.TimeBudget,
+		ExternalResources: opts.ExternalResources,
+		// The prompts wait for the resources until they are claimed.
+		WaitingForResources: len(opts.ExternalResources) > 0,
+	}
+	cfg := config.LoadConfig()
+	if instance.TimeBudget == 0 {
+		instance.TimeBudget = cfg.TimeBudget.GetDefault()
+	}
+	if opts.Provider != "" {
+		provider, ok := FindProvider(cfg.Providers, opts.Provider, "")
+		if !ok {
+			return nil, fmt.Errorf("provider %s isn't configured", opts.Provider)
+		}
+		instance.Provider = provider.Name
+		instance.Program = provider.Program
+	}
+	if opts.Parent != nil {
+		if opts.Branch != "" {
+			return nil, fmt.Errorf("cannot stack an instance on an existing branch")
+		}
+		if opts.Parent.Host != opts.Host {
+			return nil, fmt.Errorf("cannot stack an instance on an instance on another machine")
+		}
+		instance.Parent = opts.Parent.Title
+		instance.ParentBranch = opts.Parent.Branch
+	}
+	if opts.Fork != nil {
+		if err := checkForkable(opts); err != nil {
+			return nil, err
+		}
+		instance.forkOf = opts.Fork
+	}
+	return instance, nil
 }
 
 func (i *Instance) RepoName() (string, error) {
@@ -185,28 +493,63 @@ func (i *Instance) RepoName() (string, error) {
 }
 
 func (i *Instance) SetStatus(status Status) {
+	// Pausing is recorded by Pause, along with its commit. The program going back and forth between
+	// running and ready isn't recorded, since it does on every prompt and every pause in its output.
+	churn := (status == Running || status == Ready) && (i.Status == Running || i.Status == Ready)
+	if i.started && status != i.Status && status != Paused && !churn {
+		i.Audit(AuditStatus, status.String())
+	}
+	if status == Ready && i.Status != Ready {
+		i.readyAt = time.Now()
+	}
 	i.Status = status
 }
 
+// ReadySince returns when the status of the instance last changed to Ready, or the zero time if it
+// hasn't since it was loaded.
+func (i *Instance) ReadySince() time.Time {
+	return i.readyAt
+}
+
+// SetupProgress returns the progress of the setup of the worktree while the instance is Loading, e.g.
+// "submodules: Receiving objects: 45% (90/200)", or "" if there's none to report.
+func (i *Instance) SetupProgress() string {
+	i.setupProgressMu.Lock()
+	defer i.setupProgressMu.Unlock()
+	return i.setupProgress
+}
+
+func (i *Instance) setSetupProgress(progress string) {
+	i.setupProgressMu.Lock()
+	defer i.setupProgressMu.Unlock()
+	i.setupProgress = progress
+}
+
 // firstTimeSetup is true if this is a new instance. Otherwise, it's one loaded from storage.
 func (i *Instance) Start(firstTimeSetup bool) error {
+	ctx, span := i.startSpan(context.Background(), "instance.start", tracing.Bool("instance.new", firstTimeSetup))
+	err := i.start(ctx, firstTimeSetup)
+	span.End(err)
+	return err
+}
+
+func (i *Instance) start(ctx context.Context, firstTimeSetup bool) error {
 	if i.Title == "" {
 		return fmt.Errorf("instance title cannot be empty")
 	}
 
-	// Don't modify the program for ClaudeResume - we'll handle it differently
-	tmuxSession := tmux.NewTmuxSession(i.Title, i.Program)
-	i.tmuxSession = tmuxSession
-
 	if firstTimeSetup {
-		gitWorktree, branchName, err := git.NewGitWorktree(i.Path, i.Title)
-		if err != nil {
-			return fmt.Errorf("failed to create git worktree: %w", err)
+		if err := i.newWorktree(); err != nil {
+			return err
 		}
-		i.gitWorktree = gitWorktree
-		i.Branch = branchName
 	}
 
+	i.loadDiffPaths()
+
+	// Don't modify the program for ClaudeResume - we'll handle it differently
+	tmuxSession := i.makeTerminal(i.forkProgram())
+	i.tmuxSession = tmuxSession
+
 	// Setup error handler to cleanup resources on any error
 	var setupErr error
 	defer func() {
@@ -219,22 +562,57 @@ func (i *Instance) Start(firstTimeSetup bool) error {
 		}
 	}()
 
+	if err := i.loadSecrets(); err != nil {
+		setupErr = err
+		return setupErr
+	}
+
 	if !firstTimeSetup {
 		// Reuse existing session
-		if err := tmuxSession.Restore(); err != nil {
-			setupErr = fmt.Errorf("failed to restore existing session: %w", err)
-			return setupErr
+		_, restoreSpan := tracing.Start(ctx, "tmux.restore")
+		err := tmuxSession.Restore()
+		restoreSpan.End(err)
+		if err != nil {
+			// The worktree and the branch are kept instead of being cleaned up, so the restore can be retried.
+			err = fmt.Errorf("%w: %w", ErrRestoreFailed, err)
+			i.RecordError(ErrorOpTmux, err)
+			i.SetStatus(Gone)
+			return err
 		}
 	} else {
 		// Setup git worktree first
-		if err := i.gitWorktree.Setup(); err != nil {
+		setupStart := time.Now()
+		if err := i.setupWorktree(ctx); err != nil {
 			setupErr = fmt.Errorf("failed to setup git worktree: %w", err)
 			return setupErr
 		}
+		i.Audit(AuditWorktree, time.Since(setupStart).Round(time.Millisecond).String())
+
+		if err := i.copyForkedChanges(); err != nil {
+			if cleanupErr := i.gitWorktree.Cleanup(); cleanupErr != nil {
+				err = fmt.Errorf("%v (cleanup error: %v)", err, cleanupErr)
+			}
+			setupErr = err
+			return setupErr
+		}
+
+		if err := i.startServices(); err != nil {
+			if cleanupErr := i.removeServices(); cleanupErr != nil {
+				err = fmt.Errorf("%v (cleanup error: %v)", err, cleanupErr)
+			}
+			if cleanupErr := i.gitWorktree.Cleanup(); cleanupErr != nil {
+				err = fmt.Errorf("%v (cleanup error: %v)", err, cleanupErr)
+			}
+			setupErr = err
+			return setupErr
+		}
 
 		// Create new session
-		if err := i.tmuxSession.Start(i.gitWorktree.GetWorktreePath()); err != nil {
-			// Cleanup git worktree if tmux session creation fails
+		if err := i.startTerminal(ctx); err != nil {
+			// Cleanup services and git worktree if tmux session creation fails
+			if cleanupErr := i.removeServices(); cleanupErr != nil {
+				err = fmt.Errorf("%v (cleanup error: %v)", err, cleanupErr)
+			}
 			if cleanupErr := i.gitWorktree.Cleanup(); cleanupErr != nil {
 				err = fmt.Errorf("%v (cleanup error: %v)", err, cleanupErr)
 			}
@@ -244,7 +622,7 @@ func (i *Instance) Start(firstTimeSetup bool) error {
 	}
 
 	// If ClaudeResume is set, prepare conversations before starting
-	if i.ClaudeResume && strings.Contains(i.Program, "claude") && firstTimeSetup {
+	if i.ClaudeResume && strings.Contains(i.Program, "claude") && firstTimeSetup && i.Host == "" {
 		// Copy Claude conversations from the original project to the worktree
 		// Do this BEFORE Claude starts so they're available immediately
 		if err := prepareClaudeConversations(i.Path, i.gitWorktree.GetWorktreePath()); err != nil {
@@ -253,10 +631,49 @@ func (i *Instance) Start(firstTimeSetup bool) error {
 			log.InfoLog.Printf("Successfully prepared Claude conversations for worktree")
 		}
 	}
-	
+	i.watchConversations()
+
 	i.SetStatus(Running)
-	
 
+	if firstTimeSetup {
+		i.startTimeBudget(time.Now())
+		i.Audit(AuditCreated, fmt.Sprintf("branch %s, program %s", i.Branch, i.Program))
+		i.startWarmup()
+	}
+
+	return nil
+}
+
+// newWorktree checks the program and names the worktree and the branch of a new instance, without
+// creating them yet.
+func (i *Instance) newWorktree() error {
+	// The program runs on the remote host, so it can't be checked here.
+	if i.Host == "" {
+		if err := Preflight(i.Program); err != nil {
+			return err
+		}
+	}
+	var gitWorktree *git.GitWorktree
+	var err error
+	branchName := i.Branch
+	if i.Host != "" {
+		gitWorktree, branchName, err = git.NewRemoteGitWorktree(i.Host, i.Path, i.Title, branchName)
+	} else if branchName != "" {
+		gitWorktree, err = git.NewGitWorktreeFromBranch(i.Path, i.Title, branchName)
+	} else {
+		gitWorktree, branchName, err = git.NewGitWorktree(i.Path, i.Title)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create git worktree: %w", err)
+	}
+	if i.ParentBranch != "" {
+		gitWorktree.SetBaseBranch(i.ParentBranch)
+	} else if i.forkOf != nil {
+		gitWorktree.SetBaseBranch(i.forkOf.Branch)
+	}
+	i.gitWorktree = gitWorktree
+	i.Branch = branchName
+	i.terminalName = i.freeTerminalName()
 	return nil
 }
 
@@ -267,14 +684,43 @@ func (i *Instance) Kill() error {
 		return nil
 	}
 
+	_, span := i.startSpan(context.Background(), "instance.kill")
 	var errs []error
 
+	i.Audit(AuditKill, "")
+	i.closeDiffWatcher()
+	i.stopWarmup()
+	i.removeHookFiles()
+
+	// Take the final diff for the history while the worktree is still there. A paused instance kept its
+	// last one.
+	if err := i.UpdateDiffStats(); err != nil {
+		log.WarningLog.Printf("failed to update the diff of %s before killing it: %v", i.Title, err)
+	}
+	i.recordHistory(HistoryKilled)
+
+	// Run the teardown commands while the worktree and the services are still there
+	if i.gitWorktree != nil {
+		if err := i.runTeardown("kill"); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// Remove the services first since they may have the worktree mounted
+	if err := i.removeServices(); err != nil {
+		errs = append(errs, err)
+	}
+
 	// Always try to cleanup both resources, even if one fails
 	// Clean up tmux session first since it's using the git worktree
 	if i.tmuxSession != nil {
+		i.finishPaneLog()
+		pids := i.snapshotProcessTree()
 		if err := i.tmuxSession.Close(); err != nil {
 			errs = append(errs, fmt.Errorf("failed to close tmux session: %w", err))
 		}
+		// Processes the program spawned, like dev servers, outlive the session otherwise
+		i.terminateInBackground(pids, killGracePeriod)
 	}
 
 	// Then clean up git worktree
@@ -284,7 +730,13 @@ func (i *Instance) Kill() error {
 		}
 	}
 
-	return i.combineErrors(errs)
+	err := i.combineErrors(errs)
+	if err == nil {
+		i.removeAuditLog()
+	}
+	span.End(err)
+	i.RecordError(ErrorOpCleanup, err)
+	return err
 }
 
 // combineErrors combines multiple errors into a single error
@@ -315,14 +767,123 @@ func (i *Instance) Preview() (string, error) {
 	if !i.started || i.Status == Paused {
 		return "", nil
 	}
+	content, err := i.tmuxSession.CapturePaneContent()
+	if err != nil {
+		return "", err
+	}
+	return RedactSecrets(content, i.secrets), nil
+}
+
+// Scrollback returns the content of the pane of the instance with its whole history, for watching it.
+func (i *Instance) Scrollback() (string, error) {
+	if !i.started || i.Status == Paused {
+		return "", nil
+	}
+	content, err := i.tmuxSession.CapturePaneContentWithOptions("-", "-")
+	if err != nil {
+		return "", err
+	}
+	return RedactSecrets(content, i.secrets), nil
+}
+
+// PausedScreen returns the screen of the instance when it was paused, if it's paused and it was captured.
+func (i *Instance) PausedScreen() string {
+	if i.Status != Paused {
+		return ""
+	}
+	return i.pausedScreen
+}
+
+// capturePausedScreen keeps the screen of the instance before its session is closed on pause.
+func (i *Instance) capturePausedScreen() {
+	if i.tmuxSession == nil || !i.TmuxAlive() {
+		return
+	}
+	content, err := i.tmuxSession.CapturePaneContent()
+	if err != nil {
+		log.WarningLog.Printf("could not capture the screen of %s: %v", i.Title, err)
+		return
+	}
+	i.pausedScreen = RedactSecrets(content, i.secrets)
+}
+
+// loadSecrets reads the configured secret env files from the repository root and the environment of the
+// provider of the instance, and passes their variables to the tmux session.
+func (i *Instance) loadSecrets() error {
+	cfg := config.LoadConfig()
+	// The instance keeps running, without the environment of its provider, rather than being cleaned up.
+	providerEnv, providerSecrets, err := i.loadProviderEnv(cfg.Providers)
+	if err != nil {
+		log.WarningLog.Print(err)
+	}
+	i.providerEnv = providerEnv
+	files := cfg.SecretEnvFiles
+	// The env files of a remote instance are on its host.
+	if len(files) == 0 || i.Host != "" {
+		i.secrets = providerSecrets
+		i.tmuxSession.SetEnv(i.sessionEnv())
+		return nil
+	}
+	secrets, err := LoadSecretEnv(i.gitWorktree.GetRepoPath(), files)
+	if err != nil {
+		return err
+	}
+	maps.Copy(secrets, providerSecrets)
+	i.secrets = secrets
+	i.tmuxSession.SetEnv(i.sessionEnv())
+	return nil
+}
+
+// sessionEnv returns the environment variables of the terminal session of the instance, before its
+// services are started: its secrets, the environment of its provider and its scratch directory.
+func (i *Instance) sessionEnv() map[string]string {
+	env := maps.Clone(i.secrets)
+	if env == nil {
+		env = make(map[string]string)
+	}
+	maps.Copy(env, i.providerEnv)
+	if i.gitWorktree != nil {
+		if scratchDir := i.gitWorktree.ScratchDir(); scratchDir != "" {
+			env["CS_SCRATCH_DIR"] = scratchDir
+		}
+	}
+	return env
+}
+
+// CapturePane returns the visible content of the pane of the instance. It's captured once per tick and
+// passed to the checks of the tick.
+func (i *Instance) CapturePane() (string, error) {
+	if !i.started || i.Paused() {
+		return "", fmt.Errorf("instance '%s' isn't running", i.Title)
+	}
 	return i.tmuxSession.CapturePaneContent()
 }
 
-func (i *Instance) HasUpdated() (updated bool, hasPrompt bool) {
+// HasUpdated returns true if the program of the instance is working: its pane content changed since the
+// last call, or it didn't but the detector of the program tells it's still working. hasPrompt is true if
+// it shows a permission prompt.
+func (i *Instance) HasUpdated(content string, detectors []config.ReadyDetector) (updated bool, hasPrompt bool) {
 	if !i.started {
 		return false, false
 	}
-	return i.tmuxSession.HasUpdated()
+	updated, hasPrompt = i.tmuxSession.HasUpdated(content)
+	now := time.Now()
+	if updated || i.quietSince.IsZero() {
+		i.quietSince = now
+	}
+	// The hooks of claude tell what it's doing more reliably than its pane.
+	switch i.updateHookState() {
+	case HookWorking:
+		return true, false
+	case HookWaiting:
+		return false, true
+	case HookDone:
+		return false, false
+	}
+	if !updated && !hasPrompt {
+		updated = i.stillWorking(detectors, content, now)
+	}
+	return updated, hasPrompt
 }
 
 // TapEnter sends an enter key press to the tmux session if AutoYes is enabled.
@@ -332,7 +893,9 @@ func (i *Instance) TapEnter() {
 	}
 	if err := i.tmuxSession.TapEnter(); err != nil {
 		log.ErrorLog.Printf("error tapping enter: %v", err)
+		return
 	}
+	i.Audit(AuditAutoConfirm, "")
 }
 
 func (i *Instance) Attach() (chan struct{}, error) {
@@ -383,6 +946,16 @@ func (i *Instance) TmuxAlive() bool {
 
 // Pause stops the tmux session and removes the worktree, preserving the branch
 func (i *Instance) Pause() error {
+	_, span := i.startSpan(context.Background(), "instance.pause")
+	err := i.pause("pause")
+	span.End(err)
+	i.RecordError(ErrorOpCleanup, err)
+	return err
+}
+
+// pause stops the tmux session and removes the worktree, preserving the branch. The reason is passed to
+// the teardown commands.
+func (i *Instance) pause(reason string) error {
 	if !i.started {
 		return fmt.Errorf("cannot pause instance that has not been started")
 	}
@@ -398,25 +971,52 @@ func (i *Instance) Pause() error {
 		log.ErrorLog.Print(err)
 	} else if dirty {
 		// Commit changes locally (without pushing to GitHub)
-		commitMsg := fmt.Sprintf("[claudesquad] update from '%s' on %s (paused)", i.Title, time.Now().Format(time.RFC822))
+		commitMsg := i.CommitMessage(config.LoadConfig().CommitStrategy, CommitReasonPause,
+			fmt.Sprintf("[claudesquad] update from '%s' on %s (paused)", i.Title, time.Now().Format(time.RFC822)))
 		if err := i.gitWorktree.CommitChanges(commitMsg); err != nil {
 			errs = append(errs, fmt.Errorf("failed to commit changes: %w", err))
 			log.ErrorLog.Print(err)
 			// Return early if we can't commit changes to avoid corrupted state
 			return i.combineErrors(errs)
 		}
+		i.Audit(AuditCommit, commitMsg)
 	}
 
+	// A failing teardown command shouldn't keep the instance from pausing
+	if err := i.runTeardown(reason); err != nil {
+		log.WarningLog.Print(err)
+	}
+
+	i.closeDiffWatcher()
+	i.stopWarmup()
+	i.trackConversation()
+
+	// Keep the final diff and screen, to show what the agent was doing while the instance is paused
+	if err := i.UpdateDiffStats(); err != nil {
+		log.WarningLog.Print(err)
+	}
+	i.capturePausedScreen()
+
 	// Close tmux session first since it's using the git worktree
-	if err := i.tmuxSession.Close(); err != nil {
+	i.finishPaneLog()
+	pids := i.snapshotProcessTree()
+	if err := i.tmuxSession.Close(); err != nil && i.TmuxAlive() {
 		errs = append(errs, fmt.Errorf("failed to close tmux session: %w", err))
 		log.ErrorLog.Print(err)
 		// Return early if we can't close tmux to avoid corrupted state
 		return i.combineErrors(errs)
 	}
+	// Processes the program spawned, like dev servers, outlive the session otherwise
+	i.terminateInBackground(pids, killGracePeriod)
+
+	if err := i.stopServices(); err != nil {
+		errs = append(errs, err)
+		log.ErrorLog.Print(err)
+		return i.combineErrors(errs)
+	}
 
 	// Check if worktree exists before trying to remove it
-	if _, err := os.Stat(i.gitWorktree.GetWorktreePath()); err == nil {
+	if i.gitWorktree.Exists() {
 		// Remove worktree but keep branch
 		if err := i.gitWorktree.Remove(); err != nil {
 			errs = append(errs, fmt.Errorf("failed to remove git worktree: %w", err))
@@ -438,12 +1038,20 @@ func (i *Instance) Pause() error {
 	}
 
 	i.SetStatus(Paused)
+	i.Audit(AuditPause, "")
 	_ = clipboard.WriteAll(i.gitWorktree.GetBranchName())
 	return nil
 }
 
 // Resume recreates the worktree and restarts the tmux session
 func (i *Instance) Resume() error {
+	ctx, span := i.startSpan(context.Background(), "instance.resume")
+	err := i.resume(ctx)
+	span.End(err)
+	return err
+}
+
+func (i *Instance) resume(ctx context.Context) error {
 	if !i.started {
 		return fmt.Errorf("cannot resume instance that has not been started")
 	}
@@ -459,24 +1067,58 @@ func (i *Instance) Resume() error {
 		return fmt.Errorf("cannot resume: branch is checked out, please switch to a different branch")
 	}
 
+	// Claude resumes the conversation the instance last wrote to
+	if program := i.resumeProgram(); program != i.Program {
+		i.tmuxSession = i.makeTerminal(program)
+	}
+
+	// Reload the secrets in case they changed while the instance was paused
+	if err := i.loadSecrets(); err != nil {
+		log.ErrorLog.Print(err)
+		return err
+	}
+
 	// Setup git worktree
-	if err := i.gitWorktree.Setup(); err != nil {
+	setupStart := time.Now()
+	if err := i.setupWorktree(ctx); err != nil {
+		err = fmt.Errorf("failed to setup git worktree: %w", err)
+		i.RecordError(ErrorOpWorktree, err)
+		return err
+	}
+	i.Audit(AuditWorktree, time.Since(setupStart).Round(time.Millisecond).String())
+
+	if err := i.startServices(); err != nil {
 		log.ErrorLog.Print(err)
-		return fmt.Errorf("failed to setup git worktree: %w", err)
+		return err
 	}
 
 	// Create new tmux session
-	if err := i.tmuxSession.Start(i.gitWorktree.GetWorktreePath()); err != nil {
+	if err := i.startTerminal(ctx); err != nil {
 		log.ErrorLog.Print(err)
-		// Cleanup git worktree if tmux session creation fails
+		// Stop the services and cleanup git worktree if tmux session creation fails
+		if cleanupErr := i.stopServices(); cleanupErr != nil {
+			err = fmt.Errorf("%v (cleanup error: %v)", err, cleanupErr)
+			log.ErrorLog.Print(err)
+		}
 		if cleanupErr := i.gitWorktree.Cleanup(); cleanupErr != nil {
 			err = fmt.Errorf("%v (cleanup error: %v)", err, cleanupErr)
 			log.ErrorLog.Print(err)
 		}
-		return fmt.Errorf("failed to start new session: %w", err)
+		err = fmt.Errorf("failed to start new session: %w", err)
+		i.RecordError(ErrorOpTmux, err)
+		return err
 	}
 
+	i.Audit(AuditResume, "")
+	i.watchConversations()
+	i.Suspended = false
+	i.QueuedAt = time.Time{}
+	i.pausedScreen = ""
+	// The resources were released on pause, so they have to be claimed again.
+	i.WaitingForResources = len(i.ExternalResources) > 0
 	i.SetStatus(Running)
+	i.startTimeBudget(time.Now())
+	i.startWarmup()
 	return nil
 }
 
@@ -492,7 +1134,10 @@ func (i *Instance) UpdateDiffStats() error {
 		return nil
 	}
 
+	_, span := i.startSpan(context.Background(), "instance.diff")
 	stats := i.gitWorktree.Diff()
+	span.SetAttributes(tracing.Int("diff.added", stats.Added), tracing.Int("diff.removed", stats.Removed))
+	span.End(stats.Error)
 	if stats.Error != nil {
 		if strings.Contains(stats.Error.Error(), "base commit SHA not set") {
 			// Worktree is not fully set up yet, not an error
@@ -502,7 +1147,10 @@ func (i *Instance) UpdateDiffStats() error {
 		return fmt.Errorf("failed to get diff stats: %w", stats.Error)
 	}
 
+	stats.Content = RedactSecrets(stats.Content, i.secrets)
 	i.diffStats = stats
+	i.diffStatsAt = time.Now()
+	i.recordTouchedFiles(stats.ChangedFiles())
 	return nil
 }
 
@@ -513,9 +1161,8 @@ func (i *Instance) GetDiffStats() *git.DiffStats {
 
 // prepareClaudeConversations creates the Claude directory and copies conversations before Claude starts
 func prepareClaudeConversations(sourceProjectPath, targetProjectPath string) error {
-	// Get the source Claude directory (simple conversion for regular projects)
-	sourceClaudePath := filepath.Join(os.Getenv("HOME"), ".claude", "projects", 
-		"-" + strings.ReplaceAll(sourceProjectPath, "/", "-")[1:])
+	// Get the source Claude directory
+	sourceClaudePath := getClaudeProjectPath(sourceProjectPath)
 	
 	// Check if source directory exists
 	if _, err := os.Stat(sourceClaudePath); os.IsNotExist(err) {
@@ -663,32 +1310,10 @@ func copyClaudeConversations(sourceProjectPath, targetProjectPath string) error
 	return nil
 }
 
-// getClaudeProjectPath converts a project path to Claude's storage format
+// getClaudeProjectPath converts a project path to Claude's storage format. It handles both Unix and
+// Windows paths.
 func getClaudeProjectPath(projectPath string) string {
-	// Convert absolute path to Claude's format
-	// Claude replaces ALL special characters with dashes, including dots and underscores
-	cleanPath := projectPath
-	
-	// Replace forward slashes with dashes
-	cleanPath = strings.ReplaceAll(cleanPath, "/", "-")
-	
-	// Replace dots with dashes (e.g., .claude-squad becomes -claude-squad)
-	cleanPath = strings.ReplaceAll(cleanPath, ".", "-")
-	
-	// Replace underscores with dashes in the final component
-	parts := strings.Split(cleanPath, "-")
-	if len(parts) > 0 {
-		parts[len(parts)-1] = strings.ReplaceAll(parts[len(parts)-1], "_", "-")
-	}
-	cleanPath = strings.Join(parts, "-")
-	
-	// Ensure we start with a dash
-	if !strings.HasPrefix(cleanPath, "-") {
-		cleanPath = "-" + cleanPath
-	}
-	
-	homeDir, _ := os.UserHomeDir()
-	return filepath.Join(homeDir, ".claude", "projects", cleanPath)
+	return claude.GetClaudeProjectPath(projectPath)
 }
 
 // copyFile copies a file from source to destination
@@ -730,14 +1355,45 @@ func copyAndUpdateConversation(src, dst, oldCwd, newCwd string) error {
 	return nil
 }
 
+// SendInput types keys into the terminal of the instance as they are, e.g. the answer to a question or
+// the escape sequence of an arrow key. Unlike SendPrompt, they aren't queued, and enter isn't pressed.
+func (i *Instance) SendInput(keys string) error {
+	if !i.started || i.tmuxSession == nil {
+		return fmt.Errorf("instance not started")
+	}
+	if i.Paused() || i.Queued() {
+		return fmt.Errorf("%s isn't running", i.Title)
+	}
+	if err := i.tmuxSession.SendKeys(keys); err != nil {
+		return fmt.Errorf("error sending keys to tmux session: %w", err)
+	}
+	return nil
+}
+
 // SendPrompt sends a prompt to the tmux session
 func (i *Instance) SendPrompt(prompt string) error {
+	_, span := i.startSpan(context.Background(), "instance.send_prompt", tracing.Int("prompt.length", len(prompt)))
+	err := i.sendPrompt(prompt)
+	span.End(err)
+	return err
+}
+
+func (i *Instance) sendPrompt(prompt string) error {
 	if !i.started {
 		return fmt.Errorf("instance not started")
 	}
 	if i.tmuxSession == nil {
 		return fmt.Errorf("tmux session not initialized")
 	}
+	if i.WaitingForResources || i.Queued() {
+		i.QueuedPrompts = append(i.QueuedPrompts, prompt)
+		return nil
+	}
+	if !i.scopeAnnounced {
+		if instructions := i.promptInstructions(); instructions != "" {
+			prompt = instructions + " " + prompt
+		}
+	}
 	if err := i.tmuxSession.SendKeys(prompt); err != nil {
 		return fmt.Errorf("error sending keys to tmux session: %w", err)
 	}
@@ -747,6 +1403,8 @@ func (i *Instance) SendPrompt(prompt string) error {
 	if err := i.tmuxSession.TapEnter(); err != nil {
 		return fmt.Errorf("error tapping enter: %w", err)
 	}
+	i.scopeAnnounced = true
+	i.Audit(AuditPrompt, prompt)
 
 	return nil
 }
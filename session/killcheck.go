@@ -0,0 +1,60 @@
+package session
+
+import (
+	"claude-squad/config"
+	"claude-squad/log"
+	"claude-squad/session/git"
+	"fmt"
+)
+
+// UnsavedWorkError is returned by CheckKill when killing the instance would lose work.
+type UnsavedWorkError struct {
+	Title string
+	Work  git.UnsavedWork
+}
+
+func (e *UnsavedWorkError) Error() string {
+	return fmt.Sprintf("instance '%s' has %s", e.Title, e.Work)
+}
+
+// UnsavedWork returns the work killing the instance would lose: the commits of its branch that aren't
+// merged or pushed, and the uncommitted changes of its worktree.
+func (i *Instance) UnsavedWork() (git.UnsavedWork, error) {
+	if !i.started || i.gitWorktree == nil {
+		return git.UnsavedWork{}, nil
+	}
+	return i.gitWorktree.UnsavedWork()
+}
+
+// CheckKill is called before the instance is killed. Unless force is set or skip_kill_checks is, it
+// returns an UnsavedWorkError if killing the instance would lose work. With kill_backup_refs, the
+// unsaved work is backed up to a ref first, and the instance isn't killable if that fails.
+func (i *Instance) CheckKill(force bool) error {
+	cfg := config.LoadConfig()
+	if cfg.SkipKillChecks && !cfg.KillBackupRefs {
+		return nil
+	}
+	work, err := i.UnsavedWork()
+	if err != nil {
+		if force && !cfg.KillBackupRefs {
+			log.WarningLog.Printf("failed to check the work of %s before killing it: %v", i.Title, err)
+			return nil
+		}
+		return fmt.Errorf("failed to check the work of %s before killing it: %w", i.Title, err)
+	}
+	if !work.Any() {
+		return nil
+	}
+	if !force && !cfg.SkipKillChecks {
+		return &UnsavedWorkError{Title: i.Title, Work: work}
+	}
+	if cfg.KillBackupRefs {
+		ref, err := i.gitWorktree.Backup()
+		if err != nil {
+			return fmt.Errorf("failed to back up the work of %s: %w", i.Title, err)
+		}
+		log.InfoLog.Printf("backed up the work of %s (%s) to %s", i.Title, work, ref)
+		i.Audit(AuditBackup, ref)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package session
+
+import (
+	"claude-squad/config"
+	"claude-squad/session/git"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckKill(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+	require.NoError(t, config.SaveConfig(&config.Config{BranchPrefix: "test/"}))
+
+	repoPath := t.TempDir()
+	runGit := func(dir string, args ...string) string {
+		output, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+		require.NoError(t, err, string(output))
+		return strings.TrimSpace(string(output))
+	}
+	runGit(repoPath, "init")
+	runGit(repoPath, "commit", "--allow-empty", "-m", "initial")
+	worktree, _, err := git.NewGitWorktree(repoPath, "fix-login")
+	require.NoError(t, err)
+	require.NoError(t, worktree.Setup())
+	instance := &Instance{Title: "fix-login", started: true, gitWorktree: worktree}
+
+	// Nothing to lose yet.
+	assert.NoError(t, instance.CheckKill(false))
+
+	require.NoError(t, os.WriteFile(filepath.Join(worktree.GetWorktreePath(), "login.go"), []byte("package login"), 0644))
+	var unsaved *UnsavedWorkError
+	require.True(t, errors.As(instance.CheckKill(false), &unsaved))
+	assert.Equal(t, "instance 'fix-login' has uncommitted changes", unsaved.Error())
+	assert.NoError(t, instance.CheckKill(true))
+	assert.Empty(t, runGit(repoPath, "for-each-ref", git.BackupRefPrefix))
+
+	require.NoError(t, config.SaveConfig(&config.Config{BranchPrefix: "test/", SkipKillChecks: true}))
+	assert.NoError(t, instance.CheckKill(false))
+
+	// Backups are made even when the checks are skipped.
+	require.NoError(t, config.SaveConfig(&config.Config{BranchPrefix: "test/", SkipKillChecks: true, KillBackupRefs: true}))
+	require.NoError(t, instance.CheckKill(false))
+	ref := runGit(repoPath, "for-each-ref", "--format=%(refname)", git.BackupRefPrefix)
+	assert.True(t, strings.HasPrefix(ref, git.BackupRefPrefix+worktree.GetBranchName()+"/"))
+	assert.Equal(t, "package login", runGit(repoPath, "show", ref+":login.go"))
+}
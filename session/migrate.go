@@ -0,0 +1,141 @@
+package session
+
+import (
+	"bytes"
+	"claude-squad/config"
+	"claude-squad/session/claude"
+	"claude-squad/session/git"
+	"claude-squad/session/remote"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// runSSH runs the command on host over ssh, with the options of the connections to remote hosts, with
+// stdin as its input, and returns its output. It's replaced in tests.
+var runSSH = func(host string, command string, stdin []byte) (string, error) {
+	cmd := remote.Script(host, command)
+	cmd.Stdin = bytes.NewReader(stdin)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("ssh %s failed: %s (%w)", host, strings.TrimSpace(string(output)), err)
+	}
+	return string(output), nil
+}
+
+// PushForMigration commits the changes of the instance and pushes its branch to origin, which the branch
+// goes through to the other machine. It's done before the instance is archived, so a failed push leaves
+// the instance as it was.
+func (i *Instance) PushForMigration() error {
+	if !i.started {
+		return fmt.Errorf("cannot migrate instance that has not been started")
+	}
+	// A paused instance committed its changes when it was paused.
+	if !i.Paused() {
+		commitMsg := i.CommitMessage(config.LoadConfig().CommitStrategy, CommitReasonPush,
+			fmt.Sprintf("[claudesquad] update from '%s' on %s (migrated)", i.Title, time.Now().Format(time.RFC822)))
+		if err := i.CommitWork(commitMsg); err != nil {
+			return fmt.Errorf("failed to commit changes: %w", err)
+		}
+	}
+	return i.gitWorktree.PushBranch()
+}
+
+// NewMigrationBundle bundles an archived instance with its conversation, to be sent to another machine.
+// Its branch was pushed by PushForMigration.
+func NewMigrationBundle(archived ArchivedInstance) (InstanceBundle, error) {
+	return newInstanceBundle(archived)
+}
+
+// SendMigration sends the bundle over ssh to claude-squad on host, which receives it in the repository
+// at remotePath. command is how claude-squad is run there. It returns what the remote printed.
+func SendMigration(bundle InstanceBundle, host string, command string, remotePath string) (string, error) {
+	data, err := json.Marshal(bundle)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal migration bundle: %w", err)
+	}
+	return runSSH(host, receiveMigrationCommand(command, remotePath), data)
+}
+
+// receiveMigrationCommand returns the shell command that receives a migration in the repository at path
+// on the remote. A leading "~/" is left for the remote shell to expand.
+func receiveMigrationCommand(command string, path string) string {
+	return command + " receive-migration --path " + remote.Quote(path)
+}
+
+// ReceiveMigration creates an instance from a bundle sent from another machine in the repository at
+// repoPath: it fetches the branch, checks it out in a new worktree and resumes the conversation there.
+func ReceiveMigration(bundle InstanceBundle, repoPath string) (*Instance, error) {
+	archived := bundle.Instance
+	if err := git.FetchBranch(repoPath, archived.Worktree.BranchName); err != nil {
+		return nil, err
+	}
+	repoRoot, worktreePath, err := git.NewWorktreeLocation(repoPath, archived.Worktree.SessionName)
+	if err != nil {
+		return nil, err
+	}
+
+	previousPath := archived.Worktree.WorktreePath
+	archived.Path = repoRoot
+	archived.Worktree.RepoPath = repoRoot
+	archived.Worktree.WorktreePath = worktreePath
+	archived.ServiceProject = ""
+	archived.ServicePorts = nil
+	archived.BundleURL = ""
+	// The instance runs on the receiving machine, even if it ran on a remote host here.
+	archived.Host = ""
+
+	if archived.Conversation != "" && bundle.ConversationLog != "" {
+		if err := writeConversation(worktreePath, archived.Conversation, bundle.ConversationLog, previousPath); err != nil {
+			return nil, err
+		}
+	} else {
+		archived.Conversation = ""
+	}
+	return ResurrectInstance(archived)
+}
+
+// conversationPath returns the path of a Claude conversation of the project at path.
+func conversationPath(path string, conversation string) string {
+	return filepath.Join(claude.GetClaudeProjectPath(path), conversation+".jsonl")
+}
+
+// writeConversation writes a Claude conversation recorded in previousPath to the project at path, so
+// Claude can resume it there.
+func writeConversation(path string, conversation string, content string, previousPath string) error {
+	target := conversationPath(path, conversation)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create Claude project directory: %w", err)
+	}
+	// The paths are JSON strings in the conversation, escaped like paths with quotes or backslashes are.
+	previousCwd, err := json.Marshal(previousPath)
+	if err != nil {
+		return fmt.Errorf("failed to marshal path %s: %w", previousPath, err)
+	}
+	cwd, err := json.Marshal(path)
+	if err != nil {
+		return fmt.Errorf("failed to marshal path %s: %w", path, err)
+	}
+	content = strings.ReplaceAll(content, `"cwd":`+string(previousCwd), `"cwd":`+string(cwd))
+	if err := os.WriteFile(target, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write conversation %s: %w", conversation, err)
+	}
+	return nil
+}
+
+// RemoteRepoPath returns the default path of the repository at localPath on another machine: the same
+// path relative to the home directory, or the same absolute path if it's outside of it.
+func RemoteRepoPath(localPath string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return localPath
+	}
+	rel, err := filepath.Rel(home, localPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return localPath
+	}
+	return "~/" + filepath.ToSlash(rel)
+}
@@ -0,0 +1,62 @@
+package session
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendMigration(t *testing.T) {
+	original := runSSH
+	defer func() { runSSH = original }()
+
+	var gotHost, gotCommand string
+	var gotBundle InstanceBundle
+	runSSH = func(host string, command string, stdin []byte) (string, error) {
+		gotHost, gotCommand = host, command
+		return "received\n", json.Unmarshal(stdin, &gotBundle)
+	}
+
+	bundle := InstanceBundle{
+		Instance:        ArchivedInstance{InstanceData: InstanceData{Title: "fix login"}, Conversation: "abc"},
+		ConversationLog: `{"cwd":"/a"}`,
+	}
+	output, err := SendMigration(bundle, "dev@box", "cs", "~/src/my app")
+	require.NoError(t, err)
+	assert.Equal(t, "received\n", output)
+	assert.Equal(t, "dev@box", gotHost)
+	assert.Equal(t, "cs receive-migration --path ~/'src/my app'", gotCommand)
+	assert.Equal(t, bundle.Instance.Title, gotBundle.Instance.Title)
+	assert.Equal(t, bundle.ConversationLog, gotBundle.ConversationLog)
+}
+
+func TestRemoteRepoPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	assert.Equal(t, "~/src/app", RemoteRepoPath(filepath.Join(home, "src", "app")))
+	assert.Equal(t, "/srv/app", RemoteRepoPath("/srv/app"))
+}
+
+func TestWriteConversation(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	content := `{"cwd":"/old/worktree","type":"user"}` + "\n"
+	require.NoError(t, writeConversation("/new/worktree", "abc", content, "/old/worktree"))
+
+	written, err := os.ReadFile(conversationPath("/new/worktree", "abc"))
+	require.NoError(t, err)
+	assert.Equal(t, `{"cwd":"/new/worktree","type":"user"}`+"\n", string(written))
+	assert.Equal(t, "abc", latestConversation("/new/worktree"))
+
+	// Paths are escaped like they are in the JSON of the conversation.
+	content = `{"cwd":"/old/\"quoted\" worktree","type":"user"}` + "\n"
+	require.NoError(t, writeConversation(`/new/"quoted" worktree`, "abc", content, `/old/"quoted" worktree`))
+	written, err = os.ReadFile(conversationPath(`/new/"quoted" worktree`, "abc"))
+	require.NoError(t, err)
+	assert.Equal(t, `{"cwd":"/new/\"quoted\" worktree","type":"user"}`+"\n", string(written))
+}
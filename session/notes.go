@@ -0,0 +1,78 @@
+package session
+
+import (
+	"strings"
+)
+
+// Todo is an item of the checklist of an instance, e.g. something to verify before merging its branch.
+type Todo struct {
+	Text string `json:"text"`
+	Done bool   `json:"done,omitempty"`
+}
+
+const (
+	todoPrefix     = "- [ ] "
+	todoDonePrefix = "- [x] "
+)
+
+// FormatNotes renders the notes of an instance followed by its TODOs as a markdown checklist, to be edited
+// as a single text.
+func FormatNotes(notes string, todos []Todo) string {
+	var b strings.Builder
+	b.WriteString(notes)
+	if notes != "" && len(todos) > 0 {
+		b.WriteString("\n\n")
+	}
+	for idx, todo := range todos {
+		if idx > 0 {
+			b.WriteString("\n")
+		}
+		if todo.Done {
+			b.WriteString(todoDonePrefix)
+		} else {
+			b.WriteString(todoPrefix)
+		}
+		b.WriteString(todo.Text)
+	}
+	return b.String()
+}
+
+// ParseNotes splits text written like FormatNotes renders it into notes and TODOs: lines starting with
+// "- [ ]" or "- [x]" are TODOs, wherever they are, and the other lines are the notes.
+func ParseNotes(text string) (string, []Todo) {
+	var notes []string
+	var todos []Todo
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		lower := strings.ToLower(trimmed)
+		switch {
+		case strings.HasPrefix(lower, "- [ ]"):
+			if text := strings.TrimSpace(trimmed[len("- [ ]"):]); text != "" {
+				todos = append(todos, Todo{Text: text})
+			}
+		case strings.HasPrefix(lower, "- [x]"):
+			if text := strings.TrimSpace(trimmed[len("- [x]"):]); text != "" {
+				todos = append(todos, Todo{Text: text, Done: true})
+			}
+		default:
+			notes = append(notes, strings.TrimRight(line, " \t"))
+		}
+	}
+	return strings.TrimSpace(strings.Join(notes, "\n")), todos
+}
+
+// SetNotes sets the notes and TODOs of the instance from text written like FormatNotes renders it.
+func (i *Instance) SetNotes(text string) {
+	i.Notes, i.Todos = ParseNotes(text)
+}
+
+// OpenTodos returns the number of TODOs of the instance that aren't done.
+func (i *Instance) OpenTodos() int {
+	open := 0
+	for _, todo := range i.Todos {
+		if !todo.Done {
+			open++
+		}
+	}
+	return open
+}
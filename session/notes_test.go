@@ -0,0 +1,33 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNotes(t *testing.T) {
+	notes, todos := ParseNotes("Uses the new session API.\n- [ ] check the migration\n  - [X] run the tests \n\nAsk about the cache.\n- [ ]")
+	assert.Equal(t, "Uses the new session API.\n\nAsk about the cache.", notes)
+	assert.Equal(t, []Todo{{Text: "check the migration"}, {Text: "run the tests", Done: true}}, todos)
+
+	// Formatting the notes and parsing them back gives the same notes.
+	notes, todos = ParseNotes(FormatNotes(notes, todos))
+	assert.Equal(t, "Uses the new session API.\n\nAsk about the cache.", notes)
+	assert.Equal(t, []Todo{{Text: "check the migration"}, {Text: "run the tests", Done: true}}, todos)
+}
+
+func TestOpenTodos(t *testing.T) {
+	instance := &Instance{Title: "notes", Status: Paused}
+	assert.Zero(t, instance.OpenTodos())
+
+	instance.SetNotes("- [ ] check the migration\n- [x] run the tests\n- [ ] update the docs")
+	assert.Empty(t, instance.Notes)
+	assert.Equal(t, 2, instance.OpenTodos())
+
+	data := instance.ToInstanceData()
+	assert.Equal(t, instance.Todos, data.Todos)
+	restored, err := FromInstanceData(data)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, restored.OpenTodos())
+}
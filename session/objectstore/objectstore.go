@@ -0,0 +1,98 @@
+package objectstore
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// runCommand runs a command line tool with stdin as its input and returns its output. It's replaced in
+// tests.
+var runCommand = func(stdin []byte, name string, args ...string) ([]byte, error) {
+	if _, err := exec.LookPath(name); err != nil {
+		return nil, fmt.Errorf("%s is not on PATH: it's needed to use object storage", name)
+	}
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s %s failed: %s (%w)", name, strings.Join(args, " "), strings.TrimSpace(stderr.String()), err)
+	}
+	return output, nil
+}
+
+// Store is a bucket of S3 or Google Cloud Storage, used through the aws and gcloud command line tools so
+// their credentials and configuration apply.
+type Store struct {
+	// url is the bucket and the prefix of the objects, e.g. "s3://bucket/prefix".
+	url string
+	// storageClass is the storage class of the uploaded objects, if any.
+	storageClass string
+}
+
+// New returns the store at url, "s3://bucket/prefix" or "gs://bucket/prefix". Uploaded objects get the
+// storage class, if any, e.g. "STANDARD_IA" or "NEARLINE".
+func New(url string, storageClass string) (*Store, error) {
+	if _, err := scheme(url); err != nil {
+		return nil, err
+	}
+	return &Store{url: strings.TrimRight(url, "/"), storageClass: storageClass}, nil
+}
+
+// scheme returns the scheme of an object storage URL.
+func scheme(url string) (string, error) {
+	scheme, rest, ok := strings.Cut(url, "://")
+	if !ok || (scheme != "s3" && scheme != "gs") || strings.Trim(rest, "/") == "" {
+		return "", fmt.Errorf("invalid object storage URL %q: expected s3://bucket/prefix or gs://bucket/prefix", url)
+	}
+	return scheme, nil
+}
+
+// Upload uploads data to the object key under the prefix of the store, and returns its URL.
+func (s *Store) Upload(key string, data []byte) (string, error) {
+	url := s.url + "/" + key
+	var args []string
+	if strings.HasPrefix(url, "s3://") {
+		args = []string{"s3", "cp", "-", url}
+		if s.storageClass != "" {
+			args = append(args, "--storage-class", s.storageClass)
+		}
+		_, err := runCommand(data, "aws", args...)
+		return url, err
+	}
+	args = []string{"storage", "cp", "-", url}
+	if s.storageClass != "" {
+		args = append(args, "--storage-class="+s.storageClass)
+	}
+	_, err := runCommand(data, "gcloud", args...)
+	return url, err
+}
+
+// Download returns the content of the object at url.
+func Download(url string) ([]byte, error) {
+	s, err := scheme(url)
+	if err != nil {
+		return nil, err
+	}
+	if s == "s3" {
+		return runCommand(nil, "aws", "s3", "cp", url, "-")
+	}
+	return runCommand(nil, "gcloud", "storage", "cat", url)
+}
+
+// Delete deletes the object at url.
+func Delete(url string) error {
+	s, err := scheme(url)
+	if err != nil {
+		return err
+	}
+	if s == "s3" {
+		_, err = runCommand(nil, "aws", "s3", "rm", url)
+	} else {
+		_, err = runCommand(nil, "gcloud", "storage", "rm", url)
+	}
+	return err
+}
@@ -0,0 +1,73 @@
+package objectstore
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	original := runCommand
+	defer func() { runCommand = original }()
+
+	var commands []string
+	var uploaded string
+	runCommand = func(stdin []byte, name string, args ...string) ([]byte, error) {
+		commands = append(commands, name+" "+strings.Join(args, " "))
+		if stdin != nil {
+			uploaded = string(stdin)
+		}
+		return []byte("content"), nil
+	}
+
+	tests := []struct {
+		url          string
+		storageClass string
+		expected     []string
+	}{
+		{
+			url:          "s3://bucket/squad/",
+			storageClass: "STANDARD_IA",
+			expected: []string{
+				"aws s3 cp - s3://bucket/squad/archives/a.json --storage-class STANDARD_IA",
+				"aws s3 cp s3://bucket/squad/archives/a.json -",
+				"aws s3 rm s3://bucket/squad/archives/a.json",
+			},
+		},
+		{
+			url: "gs://bucket",
+			expected: []string{
+				"gcloud storage cp - gs://bucket/archives/a.json",
+				"gcloud storage cat gs://bucket/archives/a.json",
+				"gcloud storage rm gs://bucket/archives/a.json",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			commands = nil
+			store, err := New(tt.url, tt.storageClass)
+			require.NoError(t, err)
+
+			url, err := store.Upload("archives/a.json", []byte("data"))
+			require.NoError(t, err)
+			assert.Equal(t, "data", uploaded)
+			content, err := Download(url)
+			require.NoError(t, err)
+			assert.Equal(t, "content", string(content))
+			require.NoError(t, Delete(url))
+
+			assert.Equal(t, tt.expected, commands)
+		})
+	}
+}
+
+func TestNewInvalidURL(t *testing.T) {
+	for _, url := range []string{"", "bucket/prefix", "https://bucket", "s3://"} {
+		_, err := New(url, "")
+		assert.Error(t, err, url)
+	}
+}
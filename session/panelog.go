@@ -0,0 +1,334 @@
+package session
+
+import (
+	"bufio"
+	"claude-squad/config"
+	"claude-squad/log"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PaneLogInterval is how often the output of the instances is appended to their logs.
+const PaneLogInterval = 15 * time.Second
+
+const (
+	// paneLogCaptureLines is the number of lines of history captured at each append. Output scrolling
+	// faster than that between two appends is partly lost.
+	paneLogCaptureLines = 5000
+	// paneLogAnchorLines is the number of last logged lines looked for in the history, to tell where the
+	// output not logged yet starts.
+	paneLogAnchorLines = 5
+	paneLogPrefix      = "pane-"
+	paneLogSuffix      = ".log"
+	paneLogGzipSuffix  = ".log.gz"
+)
+
+// ansiEscapeRegex matches the escape sequences of colors, cursor moves and window titles, which the logs
+// are stripped of.
+var ansiEscapeRegex = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[@-Z\\-_]`)
+
+// paneLogDir returns the directory the output logs of the instance created at createdAt are in. Like the
+// audit log, it's named after the creation time too, since titles can be reused.
+func paneLogDir(title string, createdAt time.Time) (string, error) {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "panelogs", fmt.Sprintf("%s-%d", slug(title, 64, "instance"), createdAt.Unix())), nil
+}
+
+// AppendPaneLog appends the output of the instance that scrolled off its screen since the last append to
+// its log, rotating the log first if it's too big or old.
+func (i *Instance) AppendPaneLog(cfg config.PaneLog, now time.Time) error {
+	if !cfg.Enabled {
+		return nil
+	}
+	content, err := i.CapturePaneHistory()
+	if err != nil || content == "" {
+		return err
+	}
+	return i.AppendPaneOutput(cfg, content, now)
+}
+
+// CapturePaneHistory returns the output of the instance that scrolled off its screen, to be appended to
+// its log with AppendPaneOutput, or an empty string if it isn't running.
+func (i *Instance) CapturePaneHistory() (string, error) {
+	if !i.started || i.Paused() || i.tmuxSession == nil {
+		return "", nil
+	}
+	// The visible screen is left out, since the program may still redraw it.
+	return i.tmuxSession.CapturePaneContentWithOptions(fmt.Sprintf("-%d", paneLogCaptureLines), "-1")
+}
+
+// AppendPaneOutput appends the captured output not logged yet to the log of the instance. It can be
+// called in the background, while the instance is paused or killed.
+func (i *Instance) AppendPaneOutput(cfg config.PaneLog, content string, now time.Time) error {
+	i.paneLogMu.Lock()
+	defer i.paneLogMu.Unlock()
+	return i.appendPaneLog(cfg, content, now)
+}
+
+// finishPaneLog appends the output of the instance not logged yet, its screen included, before its
+// session is closed.
+func (i *Instance) finishPaneLog() {
+	cfg := config.LoadConfig().PaneLog
+	if !cfg.Enabled || i.tmuxSession == nil || !i.TmuxAlive() {
+		return
+	}
+	content, err := i.tmuxSession.CapturePaneContentWithOptions(fmt.Sprintf("-%d", paneLogCaptureLines), "-")
+	if err == nil {
+		err = i.AppendPaneOutput(cfg, content, time.Now())
+	}
+	if err != nil {
+		log.WarningLog.Printf("could not log the output of %s: %v", i.Title, err)
+	}
+}
+
+func (i *Instance) appendPaneLog(cfg config.PaneLog, content string, now time.Time) error {
+	dir, err := paneLogDir(i.Title, i.CreatedAt)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create the output log directory: %w", err)
+	}
+	path, err := rotatePaneLog(dir, cfg, now)
+	if err != nil {
+		return err
+	}
+	if i.paneLogTail == nil {
+		i.paneLogTail = readPaneLogTail(dir)
+	}
+
+	lines := strings.Split(strings.TrimRight(ansiEscapeRegex.ReplaceAllString(content, ""), "\n "), "\n")
+	lines = newPaneLines(i.paneLogTail, lines)
+	if len(lines) == 0 {
+		return nil
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open the output log: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(RedactSecrets(strings.Join(lines, "\n"), i.secrets) + "\n"); err != nil {
+		return fmt.Errorf("failed to write the output log: %w", err)
+	}
+	i.paneLogTail = append(i.paneLogTail, lines...)
+	if len(i.paneLogTail) > paneLogAnchorLines {
+		i.paneLogTail = i.paneLogTail[len(i.paneLogTail)-paneLogAnchorLines:]
+	}
+	return nil
+}
+
+// newPaneLines returns the lines of the captured output that come after the last logged ones. If they
+// can't be found, e.g. because more output than was captured scrolled by, all of it is new.
+func newPaneLines(logged, captured []string) []string {
+	if len(logged) == 0 {
+		return captured
+	}
+	for end := len(captured); end >= len(logged); end-- {
+		match := true
+		for k := range logged {
+			if captured[end-len(logged)+k] != logged[k] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return captured[end:]
+		}
+	}
+	return captured
+}
+
+// rotatePaneLog compresses the current log of the directory if it's too big or old, removing the oldest
+// compressed logs over the limit, and returns the path of the log to append to.
+func rotatePaneLog(dir string, cfg config.PaneLog, now time.Time) (string, error) {
+	current, started, err := currentPaneLog(dir)
+	if err != nil {
+		return "", err
+	}
+	if current == "" {
+		return filepath.Join(dir, fmt.Sprintf("%s%d%s", paneLogPrefix, now.Unix(), paneLogSuffix)), nil
+	}
+	info, err := os.Stat(current)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the output log: %w", err)
+	}
+	if info.Size() < cfg.GetMaxSize() && now.Sub(started) < cfg.GetMaxAge() {
+		return current, nil
+	}
+
+	if err := compressFile(current, strings.TrimSuffix(current, paneLogSuffix)+paneLogGzipSuffix); err != nil {
+		return "", err
+	}
+	if err := os.Remove(current); err != nil {
+		return "", fmt.Errorf("failed to remove the rotated output log: %w", err)
+	}
+	rotated, err := PaneLogFiles(dir)
+	if err != nil {
+		return "", err
+	}
+	var compressed []string
+	for _, path := range rotated {
+		if strings.HasSuffix(path, paneLogGzipSuffix) {
+			compressed = append(compressed, path)
+		}
+	}
+	for len(compressed) > cfg.GetKeep() {
+		if err := os.Remove(compressed[0]); err != nil {
+			return "", fmt.Errorf("failed to remove an old output log: %w", err)
+		}
+		compressed = compressed[1:]
+	}
+	return filepath.Join(dir, fmt.Sprintf("%s%d%s", paneLogPrefix, now.Unix(), paneLogSuffix)), nil
+}
+
+// currentPaneLog returns the uncompressed log of the directory and when it was started, if there is one.
+func currentPaneLog(dir string) (string, time.Time, error) {
+	files, err := PaneLogFiles(dir)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	for _, path := range files {
+		if strings.HasSuffix(path, paneLogGzipSuffix) {
+			continue
+		}
+		started, _ := paneLogStart(path)
+		return path, started, nil
+	}
+	return "", time.Time{}, nil
+}
+
+// paneLogStart returns when the log was started, from its name.
+func paneLogStart(path string) (time.Time, bool) {
+	name := strings.TrimPrefix(filepath.Base(path), paneLogPrefix)
+	name = strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), paneLogSuffix)
+	unix, err := strconv.ParseInt(name, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(unix, 0), true
+}
+
+// PaneLogFiles returns the output logs in the directory, oldest first: the compressed ones, then the
+// current one.
+func PaneLogFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read the output logs: %w", err)
+	}
+	var files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, paneLogPrefix) && (strings.HasSuffix(name, paneLogSuffix) || strings.HasSuffix(name, paneLogGzipSuffix)) {
+			files = append(files, filepath.Join(dir, name))
+		}
+	}
+	sort.Slice(files, func(a, b int) bool {
+		startA, _ := paneLogStart(files[a])
+		startB, _ := paneLogStart(files[b])
+		return startA.Before(startB)
+	})
+	return files, nil
+}
+
+// readPaneLogTail returns the last lines of the current log of the directory, so a new process carries
+// on where the log ends.
+func readPaneLogTail(dir string) []string {
+	tail := []string{}
+	current, _, err := currentPaneLog(dir)
+	if err != nil || current == "" {
+		return tail
+	}
+	f, err := os.Open(current)
+	if err != nil {
+		return tail
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		tail = append(tail, scanner.Text())
+		if len(tail) > paneLogAnchorLines {
+			tail = tail[1:]
+		}
+	}
+	return tail
+}
+
+func compressFile(source, target string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return fmt.Errorf("failed to open the output log: %w", err)
+	}
+	defer in.Close()
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create the compressed output log: %w", err)
+	}
+	gz := gzip.NewWriter(out)
+	if _, err := io.Copy(gz, in); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to compress the output log: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to compress the output log: %w", err)
+	}
+	return out.Close()
+}
+
+// WritePaneLog writes the whole logged output of the instance created at createdAt to w, oldest first,
+// decompressing the rotated logs.
+func WritePaneLog(w io.Writer, title string, createdAt time.Time) error {
+	dir, err := paneLogDir(title, createdAt)
+	if err != nil {
+		return err
+	}
+	files, err := PaneLogFiles(dir)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no output was logged for %s: set pane_log.enabled in the config to log it", title)
+	}
+	for _, path := range files {
+		if err := copyPaneLog(w, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyPaneLog(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open the output log: %w", err)
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if strings.HasSuffix(path, paneLogGzipSuffix) {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("failed to decompress %s: %w", filepath.Base(path), err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		return fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package session
+
+import (
+	"bytes"
+	"claude-squad/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPaneLines(t *testing.T) {
+	captured := []string{"a", "b", "c", "d"}
+	assert.Equal(t, captured, newPaneLines(nil, captured))
+	assert.Equal(t, []string{"c", "d"}, newPaneLines([]string{"a", "b"}, captured))
+	assert.Empty(t, newPaneLines([]string{"c", "d"}, captured))
+	// The logged lines scrolled out of the captured history.
+	assert.Equal(t, captured, newPaneLines([]string{"x", "y"}, captured))
+}
+
+func TestAppendPaneLog(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	instance := &Instance{Title: "fix-login", CreatedAt: time.Unix(1700000000, 0)}
+	cfg := config.PaneLog{Enabled: true, MaxAgeHours: 1, Keep: 1}
+	start := time.Unix(1700000000, 0)
+
+	require.NoError(t, instance.appendPaneLog(cfg, "\x1b[32m$ go test\x1b[0m\nok\n", start))
+	// Only what scrolled by since is appended.
+	require.NoError(t, instance.appendPaneLog(cfg, "$ go test\nok\n$ git status\nclean\n", start.Add(time.Minute)))
+
+	var out bytes.Buffer
+	require.NoError(t, WritePaneLog(&out, instance.Title, instance.CreatedAt))
+	assert.Equal(t, "$ go test\nok\n$ git status\nclean\n", out.String())
+
+	// The log is rotated once it's too old, and the rotated logs over the limit are removed.
+	for k, line := range []string{"first", "second", "third"} {
+		now := start.Add(time.Duration(k+1) * 2 * time.Hour)
+		require.NoError(t, instance.appendPaneLog(cfg, line, now))
+	}
+	dir, err := paneLogDir(instance.Title, instance.CreatedAt)
+	require.NoError(t, err)
+	files, err := PaneLogFiles(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	assert.True(t, strings.HasSuffix(files[0], ".log.gz"))
+
+	out.Reset()
+	require.NoError(t, WritePaneLog(&out, instance.Title, instance.CreatedAt))
+	assert.Equal(t, "second\nthird\n", out.String())
+}
+
+func TestWritePaneLogWithoutLogs(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	err := WritePaneLog(&bytes.Buffer{}, "fix-login", time.Now())
+	assert.ErrorContains(t, err, "no output was logged")
+}
+
+// paneTerminal is a running session showing content.
+type paneTerminal struct {
+	Terminal
+	content string
+}
+
+func (p paneTerminal) DoesSessionExist() bool { return true }
+
+func (p paneTerminal) CapturePaneContentWithOptions(start, end string) (string, error) {
+	return p.content, nil
+}
+
+func TestAppendPaneOutputWhileFinishing(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	require.NoError(t, os.MkdirAll(filepath.Join(home, ".claude-squad"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".claude-squad", "config.json"), []byte(`{"pane_log": {"enabled": true}}`), 0644))
+	instance := &Instance{Title: "fix-login", CreatedAt: time.Unix(1700000000, 0),
+		tmuxSession: paneTerminal{content: "$ go test\nok\n$ exit"}}
+	cfg := config.PaneLog{Enabled: true}
+
+	// The app appends in the background while the instance is paused or killed.
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for k := 0; k < 20; k++ {
+			assert.NoError(t, instance.AppendPaneOutput(cfg, "$ go test\nok", time.Now()))
+		}
+	}()
+	instance.finishPaneLog()
+	wg.Wait()
+
+	var out bytes.Buffer
+	require.NoError(t, WritePaneLog(&out, instance.Title, instance.CreatedAt))
+	assert.Contains(t, out.String(), "$ exit\n")
+	for _, line := range strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n") {
+		assert.Contains(t, []string{"$ go test", "ok", "$ exit"}, line)
+	}
+}
@@ -0,0 +1,85 @@
+package session
+
+import (
+	"claude-squad/config"
+	"claude-squad/log"
+	"claude-squad/session/claude"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+// stageSettleTime is how long the instance of a pipeline stage has to be idle with a diff before the
+// stage is considered done, so a pause in the agent's output doesn't start the next stage.
+const stageSettleTime = 15 * time.Second
+
+// maxSummaryFiles is how many changed files the summary of a stage lists.
+const maxSummaryFiles = 20
+
+// PipelineStage is the position of an instance in a pipeline run.
+type PipelineStage struct {
+	// Pipeline is the name of the pipeline.
+	Pipeline string `json:"pipeline"`
+	// Stage is the index of the stage the instance runs.
+	Stage int `json:"stage"`
+	// Task is the task the pipeline was started with. Every stage gets it.
+	Task string `json:"task,omitempty"`
+	// Done is true once the stage finished and the next one, if any, was started.
+	Done bool `json:"done,omitempty"`
+}
+
+// StageFinished returns true if the instance runs a pipeline stage that isn't done yet, and its agent has
+// been idle with changes for a while.
+func (i *Instance) StageFinished(now time.Time) bool {
+	if i.Pipeline == nil || i.Pipeline.Done || !i.started || i.Paused() || i.Status != Ready {
+		return false
+	}
+	if i.diffStats == nil || i.diffStats.IsEmpty() {
+		return false
+	}
+	return now.Sub(i.readyAt) >= stageSettleTime
+}
+
+// StageSummary summarizes the work of the instance for the next stage of its pipeline: the last message
+// of claude, if it's the program, and the files it changed.
+func (i *Instance) StageSummary() string {
+	var parts []string
+	if message := i.LastAgentMessage(); message != "" {
+		parts = append(parts, message)
+	}
+	if i.diffStats != nil && !i.diffStats.IsEmpty() {
+		files := i.diffStats.ChangedFiles()
+		if len(files) > maxSummaryFiles {
+			files = append(files[:maxSummaryFiles], fmt.Sprintf("%d more files", len(files)-maxSummaryFiles))
+		}
+		parts = append(parts, fmt.Sprintf("It changed %s (+%d -%d).", strings.Join(files, ", "),
+			i.diffStats.Added, i.diffStats.Removed))
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// LastAgentMessage returns the last message of the agent of the instance, with its secrets redacted, if
+// its program is claude. It's empty if there's none.
+func (i *Instance) LastAgentMessage() string {
+	if i.gitWorktree == nil || path.Base(programBinary(i.Program)) != "claude" {
+		return ""
+	}
+	messages, err := claude.RecentMessages(i.gitWorktree.GetWorktreePath(), "assistant", i.CreatedAt)
+	if err != nil {
+		log.WarningLog.Printf("could not read the conversation of %s: %v", i.Title, err)
+		return ""
+	}
+	if len(messages) == 0 {
+		return ""
+	}
+	return RedactSecrets(messages[len(messages)-1].Text, i.secrets)
+}
+
+// FinishStage marks the pipeline stage of the instance done and commits its changes, so the next stage,
+// whose branch is based on this one, starts from them.
+func (i *Instance) FinishStage() error {
+	i.Pipeline.Done = true
+	return i.CommitWork(i.CommitMessage(config.LoadConfig().CommitStrategy, CommitReasonStage,
+		fmt.Sprintf("[claudesquad] stage %d of pipeline %s from '%s'", i.Pipeline.Stage+1, i.Pipeline.Pipeline, i.Title)))
+}
@@ -0,0 +1,53 @@
+package session
+
+import (
+	"claude-squad/session/git"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStageFinished(t *testing.T) {
+	now := time.Now()
+	instance := &Instance{
+		Title:     "implement dark mode",
+		Status:    Ready,
+		Program:   "claude",
+		CreatedAt: now.Add(-time.Hour),
+		Pipeline:  &PipelineStage{Pipeline: "feature", Task: "dark mode"},
+		started:   true,
+		readyAt:   now.Add(-time.Minute),
+		diffStats: &git.DiffStats{Added: 1, Content: "diff --git a/theme.go b/theme.go\n+dark"},
+	}
+	assert.True(t, instance.StageFinished(now))
+
+	// The agent only just stopped.
+	assert.False(t, instance.StageFinished(instance.readyAt.Add(time.Second)))
+	instance.Status = Running
+	assert.False(t, instance.StageFinished(now))
+	instance.Status = Ready
+	instance.diffStats = &git.DiffStats{}
+	assert.False(t, instance.StageFinished(now))
+	instance.diffStats = &git.DiffStats{Added: 1, Content: "+dark"}
+	instance.Pipeline.Done = true
+	assert.False(t, instance.StageFinished(now))
+}
+
+func TestStageSummary(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	worktree := "/tmp/worktrees/implement-dark-mode"
+	require.NoError(t, writeConversation(worktree, "abc",
+		`{"type":"assistant","timestamp":"2030-01-01T00:00:00Z","message":{"role":"assistant","content":"Added a theme toggle."}}`, worktree))
+
+	instance := &Instance{
+		Title:       "implement dark mode",
+		Program:     "claude",
+		CreatedAt:   time.Now().Add(-time.Hour),
+		gitWorktree: git.NewGitWorktreeFromStorage("/tmp/repo", worktree, "implement dark mode", "dark-mode", "", false),
+		diffStats: &git.DiffStats{Added: 3, Removed: 1,
+			Content: "diff --git a/theme.go b/theme.go\n+a\ndiff --git a/app.go b/app.go\n+b"},
+	}
+	assert.Equal(t, "Added a theme toggle.\n\nIt changed theme.go, app.go (+3 -1).", instance.StageSummary())
+}
@@ -0,0 +1,42 @@
+package session
+
+import (
+	"claude-squad/session/claude"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// lookPath is exec.LookPath, replaced in tests.
+var lookPath = exec.LookPath
+
+// Preflight checks that an instance running program can start: the program is installed, and claude is
+// logged in. It fails fast with an actionable message instead of leaving a dead session. gh isn't
+// checked, since it's only needed to push, which checks it.
+func Preflight(program string) error {
+	binary := programBinary(program)
+	if binary == "" {
+		return fmt.Errorf("no program to run: set default_program in the config (see `cs debug`) or pass --program")
+	}
+	if _, err := lookPath(binary); err != nil {
+		return fmt.Errorf("program %q not found on PATH: install it, or set default_program in the config (see `cs debug`) to its full path", binary)
+	}
+
+	if filepath.Base(binary) == "claude" && !claude.IsLoggedIn() {
+		return fmt.Errorf("claude is not logged in: run `claude` and log in with /login, or set ANTHROPIC_API_KEY")
+	}
+	return nil
+}
+
+// programBinary returns the executable of a program command line, e.g. "aider" for
+// "OLLAMA_HOST=x aider --model m". Leading environment variable assignments are skipped.
+func programBinary(program string) string {
+	for _, field := range strings.Fields(program) {
+		if strings.Contains(field, "=") && !strings.ContainsAny(field, `/\`) {
+			continue
+		}
+		return strings.Trim(field, `"'`)
+	}
+	return ""
+}
@@ -0,0 +1,51 @@
+package session
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgramBinary(t *testing.T) {
+	assert.Equal(t, "claude", programBinary("claude"))
+	assert.Equal(t, "aider", programBinary("aider --model ollama_chat/gemma3:1b"))
+	assert.Equal(t, "aider", programBinary("OLLAMA_HOST=localhost aider"))
+	assert.Equal(t, "/usr/local/bin/claude", programBinary("/usr/local/bin/claude --verbose"))
+	assert.Equal(t, "", programBinary("  "))
+}
+
+func TestPreflight(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	t.Setenv("CLAUDE_CODE_OAUTH_TOKEN", "")
+	t.Setenv("CLAUDE_CONFIG_DIR", "")
+
+	origLookPath := lookPath
+	defer func() { lookPath = origLookPath }()
+	lookPath = func(file string) (string, error) {
+		// gh isn't installed, which doesn't keep sessions from starting.
+		if file == "missing" || file == "gh" {
+			return "", errors.New("not found")
+		}
+		return "/bin/" + file, nil
+	}
+
+	err := Preflight("missing --flag")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `program "missing" not found on PATH`)
+
+	err = Preflight("claude")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "claude is not logged in")
+
+	// Logged in with `claude login`.
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".claude.json"),
+		[]byte(`{"oauthAccount": {"emailAddress": "me@example.com"}}`), 0600))
+	assert.NoError(t, Preflight("claude"))
+	assert.NoError(t, Preflight("aider --model gpt-4o"))
+}
@@ -0,0 +1,61 @@
+package session
+
+import (
+	"claude-squad/log"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// killGracePeriod is how long the processes of an instance get to exit after SIGTERM before they're
+// killed.
+const killGracePeriod = 3 * time.Second
+
+// pendingTerminations tracks the processes being terminated in the background, so the commands can wait
+// for them before exiting and the ones ignoring SIGTERM are still killed.
+var pendingTerminations sync.WaitGroup
+
+// terminateInBackground terminates the processes of the instance without waiting for them to exit, since
+// pausing and killing run on the UI goroutine. The processes surviving SIGKILL are logged.
+func (i *Instance) terminateInBackground(pids []int, grace time.Duration) {
+	if len(pids) == 0 {
+		return
+	}
+	title := i.Title
+	pendingTerminations.Add(1)
+	go func() {
+		defer pendingTerminations.Done()
+		if err := terminateProcesses(pids, grace); err != nil {
+			log.WarningLog.Printf("could not terminate the processes of %s: %v", title, err)
+		}
+	}()
+}
+
+// WaitForTerminations waits for the processes of the paused and killed instances to be terminated.
+func WaitForTerminations() {
+	pendingTerminations.Wait()
+}
+
+// snapshotProcessTree returns the PIDs of the program of the instance and its descendants, such as the
+// dev servers, watchers and builds it spawned. It must be taken before the session is closed: the
+// processes that outlive the session are reparented, so they can't be traced back to it afterwards. It
+// is not supported on Windows.
+func (i *Instance) snapshotProcessTree() []int {
+	if runtime.GOOS == "windows" || i.tmuxSession == nil {
+		return nil
+	}
+	pid, err := i.tmuxSession.ProcessID()
+	if err != nil {
+		return nil
+	}
+	procs, err := listProcesses()
+	if err != nil {
+		log.WarningLog.Printf("could not list the processes of %s: %v", i.Title, err)
+		return nil
+	}
+	var pids []int
+	for _, p := range processTree(procs, pid) {
+		pids = append(pids, p.pid)
+	}
+	return pids
+}
@@ -0,0 +1,29 @@
+package session
+
+import (
+	"claude-squad/log"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMain runs before all tests to set up the test environment
+func TestMain(m *testing.M) {
+	// Initialize the logger before any tests run
+	log.Initialize(false)
+	defer log.Close()
+
+	exitCode := m.Run()
+	os.Exit(exitCode)
+}
+
+func TestProcessTree(t *testing.T) {
+	procs := parseProcessTable("  1 0 0:01.00 100\n 10 1 0:00.50 10\n 11 10 0:00.10 10\n 12 11 0:00.10 10\n 20 1 0:00.10 10\n")
+	var pids []int
+	for _, p := range processTree(procs, 10) {
+		pids = append(pids, p.pid)
+	}
+	assert.Equal(t, []int{10, 11, 12}, pids)
+	assert.Empty(t, processTree(procs, 99))
+}
@@ -0,0 +1,56 @@
+//go:build !windows
+
+package session
+
+import (
+	"claude-squad/log"
+	"fmt"
+	"syscall"
+	"time"
+)
+
+// terminateProcesses sends SIGTERM to the processes that are still running, and SIGKILL to the ones
+// still running after the grace period. It returns an error listing the processes that survive both.
+func terminateProcesses(pids []int, grace time.Duration) error {
+	alive := alivePIDs(pids)
+	if len(alive) == 0 {
+		return nil
+	}
+	for _, pid := range alive {
+		_ = syscall.Kill(pid, syscall.SIGTERM)
+	}
+	if alive = waitForExit(alive, grace); len(alive) == 0 {
+		return nil
+	}
+
+	log.WarningLog.Printf("killing processes %v that are still running %v after SIGTERM", alive, grace)
+	for _, pid := range alive {
+		_ = syscall.Kill(pid, syscall.SIGKILL)
+	}
+	if alive = waitForExit(alive, time.Second); len(alive) > 0 {
+		return fmt.Errorf("processes %v are still running after SIGKILL", alive)
+	}
+	return nil
+}
+
+// waitForExit waits up to timeout for the processes to exit and returns the ones still running.
+func waitForExit(pids []int, timeout time.Duration) []int {
+	deadline := time.Now().Add(timeout)
+	for len(pids) > 0 && time.Now().Before(deadline) {
+		time.Sleep(100 * time.Millisecond)
+		pids = alivePIDs(pids)
+	}
+	return pids
+}
+
+// alivePIDs returns the processes that are still running.
+func alivePIDs(pids []int) []int {
+	var alive []int
+	for _, pid := range pids {
+		// Signal 0 only checks that the process exists. EPERM means it exists but belongs to someone else.
+		if err := syscall.Kill(pid, 0); err == nil || err == syscall.EPERM {
+			alive = append(alive, pid)
+		}
+	}
+	return alive
+}
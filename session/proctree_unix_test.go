@@ -0,0 +1,40 @@
+//go:build !windows
+
+package session
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTerminateProcesses(t *testing.T) {
+	start := func(script string) int {
+		cmd := exec.Command("sh", "-c", script)
+		require.NoError(t, cmd.Start())
+		// Reap the process so it doesn't linger as a zombie.
+		go func() { _ = cmd.Wait() }()
+		return cmd.Process.Pid
+	}
+	polite := start("exec sleep 30")
+	stubborn := start(`trap "" TERM; while true; do sleep 0.1; done`)
+	// Let the shell install its trap.
+	time.Sleep(200 * time.Millisecond)
+
+	require.NoError(t, terminateProcesses([]int{polite, stubborn}, 300*time.Millisecond))
+	assert.Empty(t, alivePIDs([]int{polite, stubborn}))
+	// Processes that already exited are skipped.
+	assert.NoError(t, terminateProcesses([]int{polite}, time.Second))
+
+	// Pausing and killing don't wait for the processes to exit.
+	stubborn = start(`trap "" TERM; while true; do sleep 0.1; done`)
+	time.Sleep(200 * time.Millisecond)
+	started := time.Now()
+	(&Instance{Title: "fix-login"}).terminateInBackground([]int{stubborn}, 300*time.Millisecond)
+	assert.Less(t, time.Since(started), 100*time.Millisecond)
+	WaitForTerminations()
+	assert.Empty(t, alivePIDs([]int{stubborn}))
+}
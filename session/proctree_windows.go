@@ -0,0 +1,11 @@
+//go:build windows
+
+package session
+
+import "time"
+
+// terminateProcesses does nothing on Windows, where process trees aren't snapshotted. Closing the console
+// ends its program.
+func terminateProcesses(pids []int, grace time.Duration) error {
+	return nil
+}
@@ -0,0 +1,144 @@
+package session
+
+import (
+	"claude-squad/config"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// AssignProviders picks the providers of count new instances according to the policy, without going over
+// the concurrency caps of the providers given the running instances.
+func AssignProviders(providers []config.Provider, policy string, prompt string, count int, instances []*Instance) ([]config.Provider, error) {
+	if len(providers) == 0 {
+		return nil, fmt.Errorf("no providers configured")
+	}
+
+	candidates := append([]config.Provider(nil), providers...)
+	switch policy {
+	case "", config.ProviderPolicyRoundRobin:
+	case config.ProviderPolicyCost:
+		sortByCost(candidates)
+	case config.ProviderPolicyTask:
+		if matching := providersForTask(candidates, prompt); len(matching) > 0 {
+			candidates = matching
+		}
+		sortByCost(candidates)
+	default:
+		return nil, fmt.Errorf("unknown provider policy %q", policy)
+	}
+
+	running := runningProviders(providers, instances)
+	assigned := make([]config.Provider, 0, count)
+	next := 0
+	for len(assigned) < count {
+		picked := -1
+		for k := range candidates {
+			idx := k
+			// The cost policy always takes the cheapest provider with capacity left.
+			if policy != config.ProviderPolicyCost {
+				idx = (next + k) % len(candidates)
+			}
+			provider := candidates[idx]
+			if provider.MaxConcurrent == 0 || running[provider.Name] < provider.MaxConcurrent {
+				picked = idx
+				break
+			}
+		}
+		if picked < 0 {
+			return nil, fmt.Errorf("the providers only have capacity for %d more instances, %d requested",
+				len(assigned), count)
+		}
+		running[candidates[picked].Name]++
+		assigned = append(assigned, candidates[picked])
+		next = picked + 1
+	}
+	return assigned, nil
+}
+
+// FindProvider returns the provider of an instance: the one named name, or if name is empty the first one
+// running program. It returns false if there's none.
+func FindProvider(providers []config.Provider, name string, program string) (config.Provider, bool) {
+	for _, provider := range providers {
+		if (name != "" && provider.Name == name) || (name == "" && provider.Program == program) {
+			return provider, true
+		}
+	}
+	return config.Provider{}, false
+}
+
+// CheckProviderCapacity returns an error if the provider of a new or resumed instance, see FindProvider,
+// is already running its maximum number of instances.
+func CheckProviderCapacity(providers []config.Provider, name string, program string, instances []*Instance) error {
+	provider, ok := FindProvider(providers, name, program)
+	if !ok || provider.MaxConcurrent == 0 {
+		return nil
+	}
+	if running := runningProviders(providers, instances); running[provider.Name] >= provider.MaxConcurrent {
+		return fmt.Errorf("provider %s is already running its maximum of %d instances", provider.Name,
+			provider.MaxConcurrent)
+	}
+	return nil
+}
+
+// runningProviders counts the instances that are not paused by provider name.
+func runningProviders(providers []config.Provider, instances []*Instance) map[string]int {
+	running := make(map[string]int)
+	for _, instance := range instances {
+		if instance.Paused() {
+			continue
+		}
+		if provider, ok := FindProvider(providers, instance.Provider, instance.Program); ok {
+			running[provider.Name]++
+		}
+	}
+	return running
+}
+
+// providersForTask returns the providers with a task type found in the prompt.
+func providersForTask(providers []config.Provider, prompt string) []config.Provider {
+	words := make(map[string]bool)
+	for _, word := range nonWordRegex.Split(strings.ToLower(prompt), -1) {
+		words[word] = true
+	}
+	var matching []config.Provider
+	for _, provider := range providers {
+		for _, taskType := range provider.TaskTypes {
+			if words[strings.ToLower(taskType)] {
+				matching = append(matching, provider)
+				break
+			}
+		}
+	}
+	return matching
+}
+
+func sortByCost(providers []config.Provider) {
+	sort.SliceStable(providers, func(a, b int) bool {
+		return providers[a].Cost < providers[b].Cost
+	})
+}
+
+// loadProviderEnv returns the environment of the provider of the instance, with the variables of the
+// environment of claude-squad its values reference expanded, and the expanded ones, e.g. API keys, as
+// secrets to redact.
+func (i *Instance) loadProviderEnv(providers []config.Provider) (env map[string]string, secrets map[string]string, err error) {
+	if i.Provider == "" {
+		return nil, nil, nil
+	}
+	provider, ok := FindProvider(providers, i.Provider, "")
+	if !ok {
+		return nil, nil, fmt.Errorf("provider %s of instance %s isn't configured anymore", i.Provider, i.Title)
+	}
+	env = make(map[string]string, len(provider.Env))
+	secrets = make(map[string]string)
+	for name, value := range provider.Env {
+		expanded := os.ExpandEnv(value)
+		env[name] = expanded
+		if expanded != value {
+			secrets[name] = expanded
+		}
+	}
+	return env, secrets, nil
+}
@@ -0,0 +1,109 @@
+package session
+
+import (
+	"claude-squad/config"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssignProviders(t *testing.T) {
+	providers := []config.Provider{
+		{Name: "claude", Program: "claude", Cost: 3, MaxConcurrent: 2},
+		{Name: "aider-openai", Program: "aider --model gpt-4o", Cost: 2, TaskTypes: []string{"tests"}},
+		{Name: "gemini", Program: "gemini", Cost: 1, TaskTypes: []string{"docs", "tests"}, MaxConcurrent: 1},
+	}
+
+	// assign returns the programs of the providers assigned.
+	assign := func(providers []config.Provider, policy string, prompt string, count int, instances []*Instance) ([]string, error) {
+		assigned, err := AssignProviders(providers, policy, prompt, count, instances)
+		var programs []string
+		for _, provider := range assigned {
+			programs = append(programs, provider.Program)
+		}
+		return programs, err
+	}
+
+	t.Run("round robin", func(t *testing.T) {
+		programs, err := assign(providers, config.ProviderPolicyRoundRobin, "", 4, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"claude", "aider --model gpt-4o", "gemini", "claude"}, programs)
+	})
+
+	t.Run("round robin skips providers at capacity", func(t *testing.T) {
+		running := []*Instance{{Program: "claude", Status: Running}, {Program: "claude", Status: Running}}
+		programs, err := assign(providers, "", "", 3, running)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"aider --model gpt-4o", "gemini", "aider --model gpt-4o"}, programs)
+	})
+
+	t.Run("paused instances don't count", func(t *testing.T) {
+		paused := []*Instance{{Program: "gemini", Status: Paused}}
+		programs, err := assign(providers, config.ProviderPolicyCost, "", 1, paused)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"gemini"}, programs)
+	})
+
+	t.Run("cost", func(t *testing.T) {
+		programs, err := assign(providers, config.ProviderPolicyCost, "", 3, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"gemini", "aider --model gpt-4o", "aider --model gpt-4o"}, programs)
+	})
+
+	t.Run("task", func(t *testing.T) {
+		programs, err := assign(providers, config.ProviderPolicyTask, "Write tests for the parser", 2, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"gemini", "aider --model gpt-4o"}, programs)
+
+		// No provider is suited for the task, all of them are used.
+		programs, err = assign(providers, config.ProviderPolicyTask, "Fix the login bug", 3, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"gemini", "aider --model gpt-4o", "claude"}, programs)
+	})
+
+	t.Run("not enough capacity", func(t *testing.T) {
+		capped := []config.Provider{{Name: "claude", Program: "claude", MaxConcurrent: 1}}
+		_, err := assign(capped, "", "", 2, nil)
+		assert.ErrorContains(t, err, "capacity for 1 more instances, 2 requested")
+	})
+}
+
+func TestCheckProviderCapacity(t *testing.T) {
+	providers := []config.Provider{{Name: "claude", Program: "claude", MaxConcurrent: 1}}
+	assert.NoError(t, CheckProviderCapacity(providers, "", "claude", nil))
+	assert.ErrorContains(t, CheckProviderCapacity(providers, "", "claude", []*Instance{{Program: "claude"}}),
+		"provider claude is already running its maximum of 1 instances")
+	assert.NoError(t, CheckProviderCapacity(providers, "", "aider", []*Instance{{Program: "claude"}}))
+
+	// Providers running the same program with another model are counted apart.
+	providers = append(providers, config.Provider{Name: "haiku", Program: "claude", MaxConcurrent: 1,
+		Env: map[string]string{"ANTHROPIC_MODEL": "claude-haiku-4-5"}})
+	running := []*Instance{{Program: "claude", Provider: "haiku"}}
+	assert.NoError(t, CheckProviderCapacity(providers, "", "claude", running))
+	assert.ErrorContains(t, CheckProviderCapacity(providers, "haiku", "claude", running),
+		"provider haiku is already running its maximum of 1 instances")
+}
+
+func TestLoadProviderEnv(t *testing.T) {
+	t.Setenv("WORK_ANTHROPIC_API_KEY", "sk-ant-work-key")
+	providers := []config.Provider{{Name: "haiku", Program: "claude", Env: map[string]string{
+		"ANTHROPIC_MODEL":   "claude-haiku-4-5",
+		"ANTHROPIC_API_KEY": "$WORK_ANTHROPIC_API_KEY",
+	}}}
+
+	env, secrets, err := (&Instance{Title: "docs"}).loadProviderEnv(providers)
+	require.NoError(t, err)
+	assert.Nil(t, env)
+	assert.Nil(t, secrets)
+
+	instance := &Instance{Title: "docs", Provider: "haiku"}
+	env, secrets, err = instance.loadProviderEnv(providers)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"ANTHROPIC_MODEL": "claude-haiku-4-5", "ANTHROPIC_API_KEY": "sk-ant-work-key"}, env)
+	// Only the values read from the environment are redacted.
+	assert.Equal(t, map[string]string{"ANTHROPIC_API_KEY": "sk-ant-work-key"}, secrets)
+
+	_, _, err = instance.loadProviderEnv(nil)
+	assert.ErrorContains(t, err, "provider haiku of instance docs isn't configured anymore")
+}
@@ -0,0 +1,133 @@
+package session
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	// backoffBase is the first backoff after the provider rate limits or is overloaded. It doubles with
+	// each consecutive episode, up to backoffMax.
+	backoffBase = 30 * time.Second
+	backoffMax  = 10 * time.Minute
+	// retryStagger is the delay between the retries of the instances once a backoff is over, so they
+	// don't all hit the provider at once.
+	retryStagger = 15 * time.Second
+	// retryGrace is how long the rate limit messages of an instance are ignored after a retry, while the
+	// agent picks it up.
+	retryGrace = 30 * time.Second
+	// rateLimitTailLines is the number of last lines of the pane checked for rate limit messages, so old
+	// messages that scrolled up are ignored.
+	rateLimitTailLines = 15
+	// retryPrompt is sent to an instance to retry once a backoff is over.
+	retryPrompt = "continue"
+)
+
+// rateLimitPatterns are printed by the agents when the provider rate limits or is overloaded.
+var rateLimitPatterns = []string{
+	"rate_limit_error",
+	"overloaded_error",
+	"API Error: 429",
+	"API Error: 529",
+	"Rate limit reached",
+	"RateLimitError",
+	"usage limit reached",
+	"RESOURCE_EXHAUSTED",
+	"Resource has been exhausted",
+}
+
+// IsRateLimited returns true if the last lines of content show a rate limit or overload message.
+func IsRateLimited(content string) bool {
+	lines := strings.Split(strings.TrimRight(content, "\n "), "\n")
+	if len(lines) > rateLimitTailLines {
+		lines = lines[len(lines)-rateLimitTailLines:]
+	}
+	tail := strings.Join(lines, "\n")
+	for _, pattern := range rateLimitPatterns {
+		if strings.Contains(tail, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckRateLimit returns true if the agent of the instance stopped on a rate limit or overload message in
+// its pane content.
+func (i *Instance) CheckRateLimit(content string, now time.Time) bool {
+	if !i.started || i.Paused() || i.Status != Ready || now.Sub(i.rateLimitRetriedAt) < retryGrace {
+		return false
+	}
+	return IsRateLimited(content)
+}
+
+// RetryAfterRateLimit asks the agent of the instance to carry on once the backoff is over.
+func (i *Instance) RetryAfterRateLimit(now time.Time) error {
+	i.rateLimitRetriedAt = now
+	return i.SendPrompt(retryPrompt)
+}
+
+// Backoff coordinates the squad when the provider rate limits or is overloaded: while it is active, no
+// new tasks are dispatched, and once it's over, the rate limited instances retry one at a time instead
+// of all hitting the provider at once.
+type Backoff struct {
+	until     time.Time
+	episodes  int
+	waiting   []*Instance
+	nextRetry time.Time
+}
+
+// Report records that instance hit a rate limit and starts a backoff, unless one is active. Consecutive
+// episodes back off exponentially longer.
+func (b *Backoff) Report(instance *Instance, now time.Time) {
+	for _, waiting := range b.waiting {
+		if waiting == instance {
+			return
+		}
+	}
+	b.waiting = append(b.waiting, instance)
+	if b.Active(now) {
+		return
+	}
+
+	// A rate limit long after the previous one starts over.
+	if now.Sub(b.until) > backoffMax {
+		b.episodes = 0
+	}
+	delay := backoffBase << b.episodes
+	if delay > backoffMax || delay <= 0 {
+		delay = backoffMax
+	} else {
+		b.episodes++
+	}
+	b.until = now.Add(delay)
+	b.nextRetry = b.until
+}
+
+// Active returns true while new tasks shouldn't be dispatched.
+func (b *Backoff) Active(now time.Time) bool {
+	return now.Before(b.until)
+}
+
+// Remaining returns how long the backoff lasts.
+func (b *Backoff) Remaining(now time.Time) time.Duration {
+	if !b.Active(now) {
+		return 0
+	}
+	return b.until.Sub(now)
+}
+
+// Waiting returns the number of instances waiting to retry.
+func (b *Backoff) Waiting() int {
+	return len(b.waiting)
+}
+
+// NextRetry returns the instance that should retry now, if any. Retries are staggered by retryStagger.
+func (b *Backoff) NextRetry(now time.Time) *Instance {
+	if b.Active(now) || len(b.waiting) == 0 || now.Before(b.nextRetry) {
+		return nil
+	}
+	instance := b.waiting[0]
+	b.waiting = b.waiting[1:]
+	b.nextRetry = now.Add(retryStagger)
+	return instance
+}
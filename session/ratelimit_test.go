@@ -0,0 +1,53 @@
+package session
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsRateLimited(t *testing.T) {
+	assert.True(t, IsRateLimited("⏺ Working\n  ⎿  API Error: 529 {\"type\":\"overloaded_error\"}\n> \n"))
+	assert.True(t, IsRateLimited("litellm.RateLimitError: rate limited"))
+	assert.False(t, IsRateLimited("> fix the flaky test"))
+
+	// Messages that scrolled up are ignored.
+	old := "API Error: 429\n" + strings.Repeat("more output\n", rateLimitTailLines)
+	assert.False(t, IsRateLimited(old))
+}
+
+func TestBackoff(t *testing.T) {
+	var b Backoff
+	a, c := &Instance{Title: "a"}, &Instance{Title: "c"}
+	now := time.Now()
+
+	assert.False(t, b.Active(now))
+	b.Report(a, now)
+	assert.True(t, b.Active(now))
+	assert.Equal(t, backoffBase, b.Remaining(now))
+
+	// Other instances hitting the limit during the backoff don't extend it.
+	b.Report(c, now.Add(10*time.Second))
+	b.Report(a, now.Add(10*time.Second))
+	assert.Equal(t, 2, b.Waiting())
+	assert.Nil(t, b.NextRetry(now.Add(20*time.Second)))
+
+	// Retries are staggered.
+	end := now.Add(backoffBase)
+	assert.Same(t, a, b.NextRetry(end))
+	assert.Nil(t, b.NextRetry(end.Add(time.Second)))
+	assert.Same(t, c, b.NextRetry(end.Add(retryStagger)))
+	assert.Equal(t, 0, b.Waiting())
+
+	// A consecutive episode backs off longer.
+	b.Report(a, end.Add(time.Minute))
+	assert.Equal(t, 2*backoffBase, b.Remaining(end.Add(time.Minute)))
+
+	// One long after starts over.
+	later := end.Add(time.Hour)
+	b.NextRetry(later)
+	b.Report(c, later)
+	assert.Equal(t, backoffBase, b.Remaining(later))
+}
@@ -0,0 +1,83 @@
+package session
+
+import (
+	"claude-squad/config"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// readyTailLines is how many of the last lines of the pane are matched against the busy and done patterns,
+// so markers further up, e.g. of an earlier task, don't count.
+const readyTailLines = 10
+
+// exitReporter is a terminal that can keep the session of a program that exited, to tell its exit status.
+type exitReporter interface {
+	KeepOnExit()
+	ExitStatus() (exited bool, status int, err error)
+}
+
+// IsReady returns true if the content of a pane that hasn't changed for quiet shows that the program is
+// done, per the detector: not while a busy pattern matches, as soon as a done or prompt pattern matches,
+// and once the pane was quiet for long enough otherwise.
+func IsReady(detector config.ReadyDetector, content string, quiet time.Duration) bool {
+	lines := strings.Split(strings.TrimRight(ansiEscapeRegex.ReplaceAllString(content, ""), "\n "), "\n")
+	if len(lines) > readyTailLines {
+		lines = lines[len(lines)-readyTailLines:]
+	}
+	tail := strings.Join(lines, "\n")
+	if matchesAny(detector.Busy, tail) {
+		return false
+	}
+	if matchesAny(detector.Done, tail) || matchesAny(detector.Prompt, lines[len(lines)-1]) {
+		return true
+	}
+	return quiet >= detector.Idle
+}
+
+func matchesAny(patterns []*regexp.Regexp, s string) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// stillWorking returns true if the program of the instance is still working although its pane content
+// didn't change since the last check, per the detector of its program. A program kept after it exited is done,
+// and its exit status is recorded once.
+func (i *Instance) stillWorking(detectors []config.ReadyDetector, content string, now time.Time) bool {
+	detector := config.ReadyDetectorFor(detectors, i.Program)
+	if detector == nil {
+		return false
+	}
+	if reporter, ok := i.tmuxSession.(exitReporter); ok && detector.ExitStatus {
+		if exited, status, err := reporter.ExitStatus(); err == nil && exited {
+			if !i.exited {
+				i.exited = true
+				i.Audit(AuditStatus, fmt.Sprintf("exited with status %d", status))
+				if status != 0 {
+					i.RecordError(ErrorOpProgram, fmt.Errorf("%s exited with status %d", i.Program, status))
+				}
+			}
+			return false
+		}
+	}
+	return !IsReady(*detector, content, now.Sub(i.quietSince))
+}
+
+// keepOnExit keeps the pane of the program of the terminal when it exits, if the detector of the program
+// asks for its exit status.
+func (i *Instance) keepOnExit(terminal Terminal, program string) {
+	i.exited = false
+	reporter, ok := terminal.(exitReporter)
+	if !ok {
+		return
+	}
+	detectors, _ := config.LoadConfig().GetReadyDetectors()
+	if detector := config.ReadyDetectorFor(detectors, program); detector != nil && detector.ExitStatus {
+		reporter.KeepOnExit()
+	}
+}
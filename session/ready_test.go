@@ -0,0 +1,38 @@
+package session
+
+import (
+	"claude-squad/config"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsReady(t *testing.T) {
+	detectors, err := (&config.Config{ReadyRules: []config.ReadyRule{
+		{Program: "./agent.sh", IdleSeconds: 30, DonePatterns: []string{`=== done ===`}},
+	}}).GetReadyDetectors()
+	require.NoError(t, err)
+
+	claude := *config.ReadyDetectorFor(detectors, "claude --continue")
+	// claude is busy while it shows how to interrupt it, however long its pane doesn't change.
+	assert.False(t, IsReady(claude, "✻ Thinking… (42s · \x1b[1mesc\x1b[0m to interrupt)\n╭───╮\n│ > │\n╰───╯", time.Minute))
+	assert.False(t, IsReady(claude, "Done.\n╭───╮\n│ > │\n╰───╯", time.Second))
+	assert.True(t, IsReady(claude, "Done.\n╭───╮\n│ > │\n╰───╯", 2*time.Second))
+
+	aider := *config.ReadyDetectorFor(detectors, "aider --model gpt-4o")
+	// aider is ready as soon as its prompt is back.
+	assert.True(t, IsReady(aider, "Applied edit to main.go\n\narchitect> \n\n", 0))
+	assert.False(t, IsReady(aider, "Applied edit to main.go\nRunning tests...", 0))
+
+	script := *config.ReadyDetectorFor(detectors, "./agent.sh --fast")
+	assert.True(t, IsReady(script, "step 3/3\n=== done ===\n", 0))
+	assert.False(t, IsReady(script, "step 2/3", 10*time.Second))
+	assert.True(t, IsReady(script, "step 2/3", 30*time.Second))
+
+	// Other programs are ready once the shell prompt is back.
+	shell := *config.ReadyDetectorFor(detectors, "codex")
+	assert.True(t, IsReady(shell, "build finished\nme@host:~/src$ ", 0))
+	assert.False(t, IsReady(shell, "building...", time.Second))
+}
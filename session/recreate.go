@@ -0,0 +1,76 @@
+package session
+
+import (
+	"claude-squad/log"
+	"fmt"
+	"time"
+)
+
+// SessionGone returns true if the instance should be running but its terminal session is gone, e.g.
+// because the machine rebooted.
+func (i *Instance) SessionGone() bool {
+	return i.started && !i.Paused() && i.tmuxSession != nil && (i.Status == Gone || !i.TmuxAlive())
+}
+
+// MarkGone sets the status of the instance to Gone if its session is gone, so it isn't mistaken for a
+// running instance. It returns true if it did.
+func (i *Instance) MarkGone() bool {
+	if !i.SessionGone() {
+		return false
+	}
+	i.SetStatus(Gone)
+	return true
+}
+
+// Recreate starts the program of an instance whose terminal session is gone again, in its worktree. The
+// worktree is set up again from the branch if it's gone too. If resume is true, claude resumes the last
+// conversation of the instance.
+func (i *Instance) Recreate(resume bool) error {
+	if !i.SessionGone() {
+		return fmt.Errorf("the session of %s isn't gone", i.Title)
+	}
+	// The session is gone, so closing it only releases the terminal it was restored in.
+	if err := i.tmuxSession.Close(); err != nil {
+		log.InfoLog.Printf("closing the gone session of %s: %v", i.Title, err)
+	}
+
+	if !i.gitWorktree.Exists() {
+		if checked, err := i.gitWorktree.IsBranchCheckedOut(); err != nil {
+			return fmt.Errorf("failed to check if branch is checked out: %w", err)
+		} else if checked {
+			return fmt.Errorf("cannot recreate: branch %s is checked out, please switch to a different branch", i.Branch)
+		}
+		setupStart := time.Now()
+		if err := i.gitWorktree.Setup(); err != nil {
+			err = fmt.Errorf("failed to setup git worktree: %w", err)
+			i.RecordError(ErrorOpWorktree, err)
+			return err
+		}
+		i.Audit(AuditWorktree, time.Since(setupStart).Round(time.Millisecond).String())
+	}
+
+	program := i.Program
+	if resume && i.Host == "" && i.isClaude() {
+		if i.ClaudeSessionID == "" {
+			i.ClaudeSessionID = latestConversation(i.gitWorktree.GetWorktreePath())
+		}
+		program = i.resumeProgram()
+	}
+	i.tmuxSession = i.makeTerminal(program)
+	if err := i.loadSecrets(); err != nil {
+		return err
+	}
+	if err := i.startServices(); err != nil {
+		return err
+	}
+	if err := i.tmuxSession.Start(i.gitWorktree.GetWorktreePath()); err != nil {
+		err = fmt.Errorf("failed to start new session: %w", err)
+		i.RecordError(ErrorOpTmux, err)
+		return err
+	}
+
+	i.Audit(AuditResume, "recreated after its session was gone")
+	i.watchConversations()
+	i.SetStatus(Running)
+	return nil
+}
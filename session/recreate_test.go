@@ -0,0 +1,32 @@
+package session
+
+import (
+	"claude-squad/session/git"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionGone(t *testing.T) {
+	workDir := t.TempDir()
+	instance := &Instance{
+		Title:       "gone-session-test",
+		Status:      Running,
+		CreatedAt:   time.Now(),
+		gitWorktree: git.NewGitWorktreeFromStorage("", workDir, "gone-session-test", "gone-session-test", "", false),
+		tmuxSession: newTerminal("gone-session-test", "claude", workDir),
+	}
+	// Not started yet, so there's no session to lose.
+	assert.False(t, instance.SessionGone())
+	assert.Error(t, instance.Recreate(true))
+
+	instance.started = true
+	assert.True(t, instance.SessionGone())
+	assert.True(t, instance.MarkGone())
+	assert.Equal(t, Gone, instance.Status)
+
+	// A paused instance has no session on purpose.
+	instance.Status = Paused
+	assert.False(t, instance.SessionGone())
+}
@@ -0,0 +1,77 @@
+// Package remote runs commands on another machine over ssh, for instances whose worktree and terminal
+// session live there.
+package remote
+
+import (
+	"claude-squad/config"
+	"context"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// sshArgs returns the options of every ssh connection to a remote host. Connections are multiplexed over
+// one master connection per host where supported, since the preview and the diff poll the host often.
+func sshArgs() []string {
+	// Commands can't prompt for a password from the UI.
+	args := []string{"-o", "BatchMode=yes"}
+	if runtime.GOOS == "windows" {
+		return args
+	}
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return args
+	}
+	return append(args,
+		"-o", "ControlMaster=auto",
+		"-o", "ControlPath="+filepath.Join(configDir, "ssh-%C"),
+		"-o", "ControlPersist=10m")
+}
+
+// Command returns the command that runs name with args on host.
+func Command(host string, name string, args ...string) *exec.Cmd {
+	return Script(host, Quote(append([]string{name}, args...)...))
+}
+
+// TTYCommand returns the command that runs name with args on host in a terminal, for interactive
+// programs like tmux attach.
+func TTYCommand(host string, name string, args ...string) *exec.Cmd {
+	sshArgs := append(sshArgs(), "-tt", host, "--", Quote(append([]string{name}, args...)...))
+	return exec.Command("ssh", sshArgs...)
+}
+
+// Script returns the command that runs the shell script on host.
+func Script(host string, script string) *exec.Cmd {
+	return ScriptContext(context.Background(), host, script)
+}
+
+// ScriptContext is like Script but ssh is killed when ctx is done.
+func ScriptContext(ctx context.Context, host string, script string) *exec.Cmd {
+	return exec.CommandContext(ctx, "ssh", append(sshArgs(), host, "--", script)...)
+}
+
+// Quote joins args into a command line for the remote shell. A leading "~/" is left unquoted, so paths
+// relative to the remote home directory are expanded there.
+func Quote(args ...string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if rest, ok := strings.CutPrefix(arg, "~/"); ok {
+			quoted[i] = "~/" + QuoteArg(rest)
+		} else {
+			quoted[i] = QuoteArg(arg)
+		}
+	}
+	return strings.Join(quoted, " ")
+}
+
+// QuoteArg quotes arg for a POSIX shell, unless it only has safe characters. It's the one quoting of the
+// command lines run through a shell, local ones included, e.g. the attach command of an external terminal.
+func QuoteArg(arg string) string {
+	if arg != "" && strings.IndexFunc(arg, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_=/.:@%+", r))
+	}) < 0 {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
@@ -0,0 +1,28 @@
+package remote
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuote(t *testing.T) {
+	assert.Equal(t, "git -C /srv/repo status --porcelain", Quote("git", "-C", "/srv/repo", "status", "--porcelain"))
+	assert.Equal(t, `git commit -m 'it'\''s done'`, Quote("git", "commit", "-m", "it's done"))
+	assert.Equal(t, "tmux new-session -c ~/'.claude-squad/worktrees/fix login'", Quote("tmux", "new-session", "-c", "~/.claude-squad/worktrees/fix login"))
+	assert.Equal(t, "echo ''", Quote("echo", ""))
+	// A single argument keeps a leading "~/" quoted.
+	assert.Equal(t, "'~/.env'", QuoteArg("~/.env"))
+}
+
+func TestCommand(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	cmd := Command("dev@box", "tmux", "has-session", "-t=claudesquad_fix")
+	assert.Equal(t, "ssh", cmd.Args[0])
+	assert.Contains(t, cmd.Args, "BatchMode=yes")
+	assert.Equal(t, []string{"dev@box", "--", "tmux has-session -t=claudesquad_fix"}, cmd.Args[len(cmd.Args)-3:])
+
+	cmd = TTYCommand("dev@box", "tmux", "attach-session", "-t", "claudesquad_fix")
+	assert.Equal(t, []string{"-tt", "dev@box", "--", "tmux attach-session -t claudesquad_fix"}, cmd.Args[len(cmd.Args)-4:])
+}
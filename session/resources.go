@@ -0,0 +1,263 @@
+package session
+
+import (
+	"claude-squad/config"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ResourceUsage is the resource usage of the process tree of an instance.
+type ResourceUsage struct {
+	// CPUPercent is the CPU usage since the previous sample, in percent of one core.
+	CPUPercent float64
+	// MemoryBytes is the resident memory.
+	MemoryBytes uint64
+	// Processes is the number of processes.
+	Processes int
+}
+
+// String formats the usage for the list view, e.g. "45% 1.2G".
+func (u ResourceUsage) String() string {
+	return fmt.Sprintf("%.0f%% %s", u.CPUPercent, formatBytes(u.MemoryBytes))
+}
+
+func formatBytes(b uint64) string {
+	switch {
+	case b >= 1<<30:
+		return fmt.Sprintf("%.1fG", float64(b)/(1<<30))
+	case b >= 1<<20:
+		return fmt.Sprintf("%dM", b>>20)
+	default:
+		return fmt.Sprintf("%dK", b>>10)
+	}
+}
+
+// resourceSample is the state kept between samples of an instance.
+type resourceSample struct {
+	usage ResourceUsage
+	// cpuTime is the cumulative CPU time of the tree, used to compute the CPU usage of the next sample.
+	cpuTime time.Duration
+	at      time.Time
+	// busySince is when the CPU usage went above the limit, if it is above.
+	busySince time.Time
+	// reported is true once a limit violation has been reported, until the usage is back under limits.
+	reported bool
+}
+
+// processInfo is a row of the process table.
+type processInfo struct {
+	pid, ppid int
+	cpuTime   time.Duration
+	rssBytes  uint64
+}
+
+// GetResourceUsage returns the last sampled resource usage of the instance, or nil if it wasn't sampled.
+func (i *Instance) GetResourceUsage() *ResourceUsage {
+	if i.resources == nil || i.Paused() {
+		return nil
+	}
+	return &i.resources.usage
+}
+
+// ProcessSample is the process table of the system along with the root processes of the running
+// instances. Listing them is slow, so it's done in the background and the usage recorded afterwards.
+type ProcessSample struct {
+	procs []processInfo
+	pids  map[*Instance]int
+	at    time.Time
+}
+
+// SampleProcesses lists the processes of the system and the root processes of the running instances. It
+// is not supported on Windows, where it returns nil.
+func SampleProcesses(instances []*Instance, now time.Time) (*ProcessSample, error) {
+	if runtime.GOOS == "windows" {
+		return nil, nil
+	}
+	procs, err := listProcesses()
+	if err != nil {
+		return nil, err
+	}
+
+	sample := &ProcessSample{procs: procs, pids: make(map[*Instance]int), at: now}
+	for _, instance := range instances {
+		if !instance.Started() || instance.Paused() {
+			continue
+		}
+		pid, err := instance.tmuxSession.ProcessID()
+		if err != nil {
+			continue
+		}
+		sample.pids[instance] = pid
+	}
+	return sample, nil
+}
+
+// Record records the resource usage of the process trees of the instances of the sample that are still
+// running.
+func (s *ProcessSample) Record() {
+	if s == nil {
+		return
+	}
+	for instance, pid := range s.pids {
+		if instance.Started() && !instance.Paused() {
+			instance.recordResourceUsage(s.procs, pid, s.at)
+		}
+	}
+}
+
+// recordResourceUsage sums the usage of the tree rooted at pid and computes the CPU usage since the
+// previous sample.
+func (i *Instance) recordResourceUsage(procs []processInfo, pid int, now time.Time) {
+	usage, cpuTime := treeUsage(procs, pid)
+	if i.resources == nil {
+		i.resources = &resourceSample{}
+	} else if elapsed := now.Sub(i.resources.at); elapsed > 0 && cpuTime > i.resources.cpuTime {
+		// The CPU time can go down when processes of the tree exit, in which case the usage is unknown.
+		usage.CPUPercent = float64(cpuTime-i.resources.cpuTime) / float64(elapsed) * 100
+	}
+	i.resources.usage = usage
+	i.resources.cpuTime = cpuTime
+	i.resources.at = now
+}
+
+// CheckResourceLimits returns a description of the limit the instance exceeds, if any. A violation is
+// reported once, until the instance is back under the limits.
+func (i *Instance) CheckResourceLimits(limits config.ResourceLimits, now time.Time) string {
+	sample := i.resources
+	if sample == nil || i.Paused() {
+		return ""
+	}
+
+	var violation string
+	if limits.CPUPercent > 0 && limits.CPUMinutes > 0 && sample.usage.CPUPercent >= limits.CPUPercent {
+		if sample.busySince.IsZero() {
+			sample.busySince = now
+		}
+		if busy := now.Sub(sample.busySince); busy >= time.Duration(limits.CPUMinutes)*time.Minute {
+			violation = fmt.Sprintf("CPU above %.0f%% for %s", limits.CPUPercent, busy.Round(time.Minute))
+		}
+	} else {
+		sample.busySince = time.Time{}
+	}
+	if limits.MemoryMB > 0 && sample.usage.MemoryBytes > uint64(limits.MemoryMB)<<20 {
+		violation = fmt.Sprintf("memory %s above %dM", formatBytes(sample.usage.MemoryBytes), limits.MemoryMB)
+	}
+	if limits.MaxProcesses > 0 && sample.usage.Processes > limits.MaxProcesses {
+		violation = fmt.Sprintf("%d processes, more than %d", sample.usage.Processes, limits.MaxProcesses)
+	}
+
+	if violation == "" {
+		sample.reported = false
+		return ""
+	}
+	if sample.reported {
+		return ""
+	}
+	sample.reported = true
+	return violation
+}
+
+// listProcesses lists the processes of the system with ps.
+func listProcesses() ([]processInfo, error) {
+	output, err := exec.Command("ps", "-A", "-o", "pid=,ppid=,time=,rss=").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list processes: %w", err)
+	}
+	return parseProcessTable(string(output)), nil
+}
+
+// parseProcessTable parses the output of `ps -o pid=,ppid=,time=,rss=`.
+func parseProcessTable(output string) []processInfo {
+	var procs []processInfo
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 4 {
+			continue
+		}
+		pid, err1 := strconv.Atoi(fields[0])
+		ppid, err2 := strconv.Atoi(fields[1])
+		cpuTime, err3 := parseCPUTime(fields[2])
+		rss, err4 := strconv.ParseUint(fields[3], 10, 64)
+		if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
+			continue
+		}
+		procs = append(procs, processInfo{pid: pid, ppid: ppid, cpuTime: cpuTime, rssBytes: rss << 10})
+	}
+	return procs
+}
+
+// parseCPUTime parses the cumulative CPU time printed by ps: "[[dd-]hh:]mm:ss[.cc]".
+func parseCPUTime(s string) (time.Duration, error) {
+	var days int
+	if day, rest, ok := strings.Cut(s, "-"); ok {
+		d, err := strconv.Atoi(day)
+		if err != nil {
+			return 0, err
+		}
+		days, s = d, rest
+	}
+
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("invalid cpu time %q", s)
+	}
+	seconds, err := strconv.ParseFloat(parts[len(parts)-1], 64)
+	if err != nil {
+		return 0, err
+	}
+	total := time.Duration(days)*24*time.Hour + time.Duration(seconds*float64(time.Second))
+	units := []time.Duration{time.Minute, time.Hour}
+	for idx, part := range parts[:len(parts)-1] {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return 0, err
+		}
+		total += time.Duration(n) * units[len(parts)-2-idx]
+	}
+	return total, nil
+}
+
+// treeUsage sums the usage of the process rooted at root and its descendants.
+func treeUsage(procs []processInfo, root int) (ResourceUsage, time.Duration) {
+	var usage ResourceUsage
+	var cpuTime time.Duration
+	for _, p := range processTree(procs, root) {
+		usage.Processes++
+		usage.MemoryBytes += p.rssBytes
+		cpuTime += p.cpuTime
+	}
+	return usage, cpuTime
+}
+
+// processTree returns the process root and its descendants, parents first.
+func processTree(procs []processInfo, root int) []processInfo {
+	children := make(map[int][]int)
+	byPID := make(map[int]processInfo)
+	for _, p := range procs {
+		children[p.ppid] = append(children[p.ppid], p.pid)
+		byPID[p.pid] = p
+	}
+
+	var tree []processInfo
+	queue := []int{root}
+	seen := make(map[int]bool)
+	for len(queue) > 0 {
+		pid := queue[0]
+		queue = queue[1:]
+		if seen[pid] {
+			continue
+		}
+		seen[pid] = true
+		p, ok := byPID[pid]
+		if !ok {
+			continue
+		}
+		tree = append(tree, p)
+		queue = append(queue, children[pid]...)
+	}
+	return tree
+}
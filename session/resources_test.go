@@ -0,0 +1,103 @@
+package session
+
+import (
+	"claude-squad/config"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCPUTime(t *testing.T) {
+	cases := map[string]time.Duration{
+		"00:00:05":   5 * time.Second,
+		"01:02:03":   time.Hour + 2*time.Minute + 3*time.Second,
+		"2-00:00:01": 48*time.Hour + time.Second,
+		"1:30.50":    90*time.Second + 500*time.Millisecond,
+		"120:00.00":  2 * time.Hour,
+	}
+	for input, want := range cases {
+		got, err := parseCPUTime(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, want, got, input)
+	}
+
+	_, err := parseCPUTime("garbage")
+	assert.Error(t, err)
+}
+
+func TestTreeUsage(t *testing.T) {
+	procs := parseProcessTable(`
+    1     0 00:10:00  1000
+  100     1 00:00:10  2048
+  101   100 00:00:20  1024
+  102   101 00:00:30  1024
+  200     1 00:01:00  4096
+`)
+	require.Len(t, procs, 5)
+
+	usage, cpuTime := treeUsage(procs, 100)
+	assert.Equal(t, 3, usage.Processes)
+	assert.Equal(t, uint64(4096<<10), usage.MemoryBytes)
+	assert.Equal(t, time.Minute, cpuTime)
+
+	usage, _ = treeUsage(procs, 999)
+	assert.Equal(t, 0, usage.Processes)
+}
+
+func TestCheckResourceLimits(t *testing.T) {
+	limits := config.ResourceLimits{CPUPercent: 90, CPUMinutes: 10, MaxProcesses: 2}
+	start := time.Now()
+	instance := &Instance{}
+
+	procs := []processInfo{{pid: 1, cpuTime: 0}}
+	instance.recordResourceUsage(procs, 1, start)
+	assert.Empty(t, instance.CheckResourceLimits(limits, start))
+
+	// One core busy for 11 minutes.
+	busy := func(minutes int) time.Time {
+		now := start.Add(time.Duration(minutes) * time.Minute)
+		instance.recordResourceUsage([]processInfo{{pid: 1, cpuTime: time.Duration(minutes) * time.Minute}}, 1, now)
+		return now
+	}
+	assert.Empty(t, instance.CheckResourceLimits(limits, busy(1)))
+	assert.InDelta(t, 100, instance.GetResourceUsage().CPUPercent, 0.01)
+	assert.Empty(t, instance.CheckResourceLimits(limits, busy(5)))
+	assert.Contains(t, instance.CheckResourceLimits(limits, busy(11)), "CPU above 90%")
+	// Reported once per episode.
+	assert.Empty(t, instance.CheckResourceLimits(limits, busy(12)))
+
+	// Back to idle resets the episode, then a runaway build is reported.
+	idle := start.Add(13 * time.Minute)
+	instance.recordResourceUsage([]processInfo{{pid: 1, cpuTime: 12 * time.Minute}}, 1, idle)
+	assert.Empty(t, instance.CheckResourceLimits(limits, idle))
+
+	runaway := []processInfo{
+		{pid: 1, cpuTime: 12 * time.Minute},
+		{pid: 2, ppid: 1},
+		{pid: 3, ppid: 1},
+	}
+	instance.recordResourceUsage(runaway, 1, idle.Add(time.Second))
+	assert.Contains(t, instance.CheckResourceLimits(limits, idle.Add(time.Second)), "3 processes")
+}
+
+func TestProcessSampleRecord(t *testing.T) {
+	running := &Instance{started: true, Status: Running}
+	paused := &Instance{started: true, Status: Running}
+	sample := &ProcessSample{
+		procs: []processInfo{{pid: 1, rssBytes: 1 << 20}, {pid: 2, rssBytes: 1 << 20}},
+		pids:  map[*Instance]int{running: 1, paused: 2},
+		at:    time.Now(),
+	}
+	// The instance was paused while the processes were listed.
+	paused.Status = Paused
+
+	sample.Record()
+	require.NotNil(t, running.GetResourceUsage())
+	assert.Equal(t, uint64(1<<20), running.GetResourceUsage().MemoryBytes)
+	assert.Nil(t, paused.resources)
+
+	// Nothing is sampled on Windows.
+	(*ProcessSample)(nil).Record()
+}
@@ -0,0 +1,170 @@
+package session
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// hunkHeaderRegex matches a hunk header and captures the first old and new line numbers.
+var hunkHeaderRegex = regexp.MustCompile(`^@@ -(\d+)(?:,\d+)? \+(\d+)(?:,\d+)? @@`)
+
+// DiffLine is a line of a diff along with where it is in the files.
+type DiffLine struct {
+	// Text is the line as it appears in the diff.
+	Text string
+	// File is the path of the file the line belongs to.
+	File string
+	// Line is the number of the line in the new version of the file, or in the old one for removed
+	// lines. It's 0 for the headers, which can't be commented on.
+	Line int
+	// Removed is true if the line was removed.
+	Removed bool
+}
+
+// ParseDiffLines splits a unified diff into lines and locates the added, removed and context lines in
+// their files.
+func ParseDiffLines(diff string) []DiffLine {
+	var lines []DiffLine
+	var file string
+	var oldLine, newLine int
+	inHunk := false
+	for _, text := range strings.Split(strings.TrimRight(diff, "\n"), "\n") {
+		line := DiffLine{Text: text, File: file}
+		switch {
+		case strings.HasPrefix(text, "diff --git "):
+			inHunk = false
+			if _, b, ok := strings.Cut(text, " b/"); ok {
+				file = b
+			}
+			line.File = file
+		case strings.HasPrefix(text, "@@"):
+			inHunk = false
+			if m := hunkHeaderRegex.FindStringSubmatch(text); m != nil {
+				oldLine, _ = strconv.Atoi(m[1])
+				newLine, _ = strconv.Atoi(m[2])
+				inHunk = true
+			}
+		case !inHunk:
+		case strings.HasPrefix(text, "+"):
+			line.Line = newLine
+			newLine++
+		case strings.HasPrefix(text, "-"):
+			line.Line = oldLine
+			line.Removed = true
+			oldLine++
+		case strings.HasPrefix(text, " ") || text == "":
+			line.Line = newLine
+			oldLine++
+			newLine++
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// ReviewComment is a comment left on a line of the diff of an instance.
+type ReviewComment struct {
+	// File is the path of the file commented on.
+	File string
+	// Line is the number of the line commented on, in the old version of the file if Removed.
+	Line int
+	// Removed is true if the comment is on a removed line.
+	Removed bool
+	// Code is the line commented on, without the diff marker.
+	Code string
+	// Text is the comment.
+	Text string
+}
+
+// location formats where the comment is, e.g. "line 42" or "removed line 10".
+func (c ReviewComment) location() string {
+	if c.Removed {
+		return fmt.Sprintf("removed line %d", c.Line)
+	}
+	return fmt.Sprintf("line %d", c.Line)
+}
+
+// AddReviewComment adds a comment on a line of the diff to the pending review of the instance, replacing
+// the comment already on the line, if any.
+func (i *Instance) AddReviewComment(line DiffLine, text string) {
+	i.RemoveReviewComment(line)
+	code := line.Text
+	if code != "" {
+		code = code[1:]
+	}
+	i.reviewComments = append(i.reviewComments, ReviewComment{
+		File:    line.File,
+		Line:    line.Line,
+		Removed: line.Removed,
+		Code:    strings.TrimSpace(code),
+		Text:    strings.TrimSpace(text),
+	})
+}
+
+// RemoveReviewComment removes the comment on a line of the diff from the pending review of the instance.
+func (i *Instance) RemoveReviewComment(line DiffLine) {
+	comments := i.reviewComments[:0]
+	for _, comment := range i.reviewComments {
+		if !comment.on(line) {
+			comments = append(comments, comment)
+		}
+	}
+	i.reviewComments = comments
+}
+
+// ReviewCommentOn returns the pending comment on a line of the diff, if any.
+func (i *Instance) ReviewCommentOn(line DiffLine) (ReviewComment, bool) {
+	for _, comment := range i.reviewComments {
+		if comment.on(line) {
+			return comment, true
+		}
+	}
+	return ReviewComment{}, false
+}
+
+func (c ReviewComment) on(line DiffLine) bool {
+	return c.File == line.File && c.Line == line.Line && c.Removed == line.Removed
+}
+
+// ReviewComments returns the pending review comments of the instance.
+func (i *Instance) ReviewComments() []ReviewComment {
+	return i.reviewComments
+}
+
+// SendReview sends the pending review comments to the program as a single follow-up prompt, and clears
+// them.
+func (i *Instance) SendReview() error {
+	if len(i.reviewComments) == 0 {
+		return fmt.Errorf("no review comments to send")
+	}
+	if err := i.SendPrompt(ReviewPrompt(i.reviewComments)); err != nil {
+		return err
+	}
+	i.reviewComments = nil
+	return nil
+}
+
+// ReviewPrompt formats review comments as a prompt asking the agent to address them, grouped by file in
+// the order the files were first commented on.
+func ReviewPrompt(comments []ReviewComment) string {
+	var files []string
+	byFile := make(map[string][]ReviewComment)
+	for _, comment := range comments {
+		if _, ok := byFile[comment.File]; !ok {
+			files = append(files, comment.File)
+		}
+		byFile[comment.File] = append(byFile[comment.File], comment)
+	}
+
+	var b strings.Builder
+	b.WriteString("I reviewed your changes. Address each of these review comments, then summarize what you changed for each of them.\n")
+	for _, file := range files {
+		fmt.Fprintf(&b, "\n%s\n", file)
+		for _, comment := range byFile[file] {
+			fmt.Fprintf(&b, "- %s `%s`: %s\n", comment.location(), comment.Code, comment.Text)
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,57 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const reviewDiff = `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -10,3 +10,3 @@ func main() {
+ 	x := 1
+-	y := 2
++	y := 3
+ 	fmt.Println(x, y)
+`
+
+func TestParseDiffLines(t *testing.T) {
+	lines := ParseDiffLines(reviewDiff)
+	require.Len(t, lines, 9)
+
+	for _, header := range lines[:5] {
+		assert.Equal(t, 0, header.Line, header.Text)
+	}
+	assert.Equal(t, DiffLine{Text: " \tx := 1", File: "main.go", Line: 10}, lines[5])
+	assert.Equal(t, DiffLine{Text: "-\ty := 2", File: "main.go", Line: 11, Removed: true}, lines[6])
+	assert.Equal(t, DiffLine{Text: "+\ty := 3", File: "main.go", Line: 11}, lines[7])
+	assert.Equal(t, DiffLine{Text: " \tfmt.Println(x, y)", File: "main.go", Line: 12}, lines[8])
+}
+
+func TestReviewComments(t *testing.T) {
+	lines := ParseDiffLines(reviewDiff)
+	instance := &Instance{}
+
+	instance.AddReviewComment(lines[7], "use a constant")
+	instance.AddReviewComment(lines[6], "why was this removed?")
+	instance.AddReviewComment(lines[7], " name the constant ")
+	require.Len(t, instance.ReviewComments(), 2)
+
+	comment, ok := instance.ReviewCommentOn(lines[7])
+	require.True(t, ok)
+	assert.Equal(t, ReviewComment{File: "main.go", Line: 11, Code: "y := 3", Text: "name the constant"}, comment)
+
+	assert.Equal(t, "I reviewed your changes. Address each of these review comments, then summarize what you changed for each of them.\n"+
+		"\nmain.go\n"+
+		"- removed line 11 `y := 2`: why was this removed?\n"+
+		"- line 11 `y := 3`: name the constant",
+		ReviewPrompt(instance.ReviewComments()))
+
+	instance.RemoveReviewComment(lines[6])
+	_, ok = instance.ReviewCommentOn(lines[6])
+	assert.False(t, ok)
+	assert.Len(t, instance.ReviewComments(), 1)
+}
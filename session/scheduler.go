@@ -0,0 +1,125 @@
+package session
+
+import (
+	"claude-squad/config"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// RunningInstances returns the number of instances whose program runs: the started ones that aren't
+// paused and whose session isn't gone.
+func RunningInstances(instances []*Instance) int {
+	running := 0
+	for _, instance := range instances {
+		if instance.Started() && !instance.Paused() && instance.Status != Gone {
+			running++
+		}
+	}
+	return running
+}
+
+// HasFreeSlot returns true if one more instance can run without going over maxRunning running instances.
+// There's no cap if maxRunning is 0 or less.
+func HasFreeSlot(maxRunning int, instances []*Instance) bool {
+	return maxRunning <= 0 || RunningInstances(instances) < maxRunning
+}
+
+// Queued returns true if the instance waits for a running slot.
+func (i *Instance) Queued() bool {
+	return !i.QueuedAt.IsZero()
+}
+
+// StartOrQueue starts a new instance like Start, or queues it if maxRunning of the other instances are
+// already running. A queued instance is paused without a worktree until a slot is free, and its prompts
+// are held back until then, see DispatchQueued. Forks and instances resuming a conversation always
+// start, since they copy the changes or the conversation they continue as they are.
+func (i *Instance) StartOrQueue(maxRunning int, instances []*Instance) (bool, error) {
+	if i.forkOf != nil || i.ClaudeResume || HasFreeSlot(maxRunning, others(i, instances)) {
+		return false, i.Start(true)
+	}
+	if i.Title == "" {
+		return false, fmt.Errorf("instance title cannot be empty")
+	}
+	if err := i.newWorktree(); err != nil {
+		return false, err
+	}
+	i.tmuxSession = i.makeTerminal(i.Program)
+	i.started = true
+	i.Status = Paused
+	i.QueuedAt = time.Now()
+	i.Audit(AuditCreated, fmt.Sprintf("branch %s, program %s, queued", i.Branch, i.Program))
+	return true, nil
+}
+
+// ResumeOrQueue resumes a paused instance like Resume, or queues it if maxRunning of the other instances
+// are already running. It returns an error if its provider is already running its maximum of instances,
+// see CheckProviderCapacity.
+func (i *Instance) ResumeOrQueue(maxRunning int, providers []config.Provider, instances []*Instance) (bool, error) {
+	if err := CheckProviderCapacity(providers, i.Provider, i.Program, others(i, instances)); err != nil {
+		return false, err
+	}
+	if HasFreeSlot(maxRunning, others(i, instances)) {
+		return false, i.Resume()
+	}
+	if !i.Paused() {
+		return false, fmt.Errorf("can only resume paused instances")
+	}
+	if !i.Queued() {
+		i.QueuedAt = time.Now()
+		i.Audit(AuditQueue, fmt.Sprintf("%d instances running", RunningInstances(instances)))
+	}
+	return true, nil
+}
+
+// DispatchQueued resumes the queued instances while there are free slots, in the order they were
+// queued, and sends them the prompts held back for them. It returns the instances it resumed. An instance
+// that fails to resume is dequeued, so it doesn't hold back the others. An instance whose provider is
+// already running its maximum of instances stays queued until one of them stops.
+func DispatchQueued(maxRunning int, providers []config.Provider, instances []*Instance) ([]*Instance, error) {
+	var queued []*Instance
+	for _, instance := range instances {
+		if instance.Queued() && instance.Paused() {
+			queued = append(queued, instance)
+		}
+	}
+	sort.SliceStable(queued, func(a, b int) bool {
+		return queued[a].QueuedAt.Before(queued[b].QueuedAt)
+	})
+
+	var dispatched []*Instance
+	var errs []error
+	for _, instance := range queued {
+		if !HasFreeSlot(maxRunning, instances) {
+			break
+		}
+		if CheckProviderCapacity(providers, instance.Provider, instance.Program, instances) != nil {
+			continue
+		}
+		if err := instance.Resume(); err != nil {
+			instance.QueuedAt = time.Time{}
+			errs = append(errs, fmt.Errorf("%s: %w", instance.Title, err))
+			continue
+		}
+		dispatched = append(dispatched, instance)
+		// Prompts waiting for external resources are sent once the instance gets them.
+		if !instance.WaitingForResources {
+			if err := instance.sendQueuedPrompts(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return dispatched, errors.Join(errs...)
+}
+
+// others returns the instances other than i.
+func others(i *Instance, instances []*Instance) []*Instance {
+	var result []*Instance
+	for _, instance := range instances {
+		if instance != i {
+			result = append(result, instance)
+		}
+	}
+	return result
+}
@@ -0,0 +1,77 @@
+package session
+
+import (
+	"claude-squad/config"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHasFreeSlot(t *testing.T) {
+	instances := []*Instance{
+		{Title: "running", Status: Running, started: true},
+		{Title: "ready", Status: Ready, started: true},
+		{Title: "paused", Status: Paused, started: true},
+		{Title: "gone", Status: Gone, started: true},
+		{Title: "naming", Status: Ready},
+	}
+	assert.Equal(t, 2, RunningInstances(instances))
+	assert.True(t, HasFreeSlot(0, instances), "no cap")
+	assert.True(t, HasFreeSlot(3, instances))
+	assert.False(t, HasFreeSlot(2, instances))
+}
+
+func TestResumeOrQueue(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	running := &Instance{Title: "running", Status: Running, started: true}
+	first := &Instance{Title: "first", Status: Paused, started: true}
+	second := &Instance{Title: "second", Status: Paused, started: true}
+	instances := []*Instance{running, first, second}
+
+	queued, err := second.ResumeOrQueue(1, nil, instances)
+	require.NoError(t, err)
+	assert.True(t, queued)
+	queuedAt := second.QueuedAt
+	queued, err = first.ResumeOrQueue(1, nil, instances)
+	require.NoError(t, err)
+	assert.True(t, queued)
+	assert.False(t, first.QueuedAt.Before(queuedAt))
+
+	// Resuming an instance again keeps its place in the queue.
+	_, err = second.ResumeOrQueue(1, nil, instances)
+	require.NoError(t, err)
+	assert.Equal(t, queuedAt, second.QueuedAt)
+
+	// Nothing is resumed while the running instance holds the only slot.
+	dispatched, err := DispatchQueued(1, nil, instances)
+	require.NoError(t, err)
+	assert.Empty(t, dispatched)
+
+	_, err = running.ResumeOrQueue(1, nil, instances)
+	assert.ErrorContains(t, err, "can only resume paused instances")
+	assert.False(t, running.Queued())
+
+	data := second.ToInstanceData()
+	assert.Equal(t, queuedAt, data.QueuedAt)
+}
+
+func TestResumeOrQueueProviderCapacity(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	providers := []config.Provider{{Name: "claude", Program: "claude", MaxConcurrent: 1}}
+	running := &Instance{Title: "running", Program: "claude", Status: Running, started: true}
+	paused := &Instance{Title: "paused", Program: "claude", Status: Paused, started: true}
+	queued := &Instance{Title: "queued", Program: "claude", Status: Paused, started: true, QueuedAt: time.Now()}
+	instances := []*Instance{running, paused, queued}
+
+	_, err := paused.ResumeOrQueue(0, providers, instances)
+	assert.ErrorContains(t, err, "provider claude is already running its maximum of 1 instances")
+	assert.False(t, paused.Queued())
+
+	// A queued instance waits for its provider to have a free slot too.
+	dispatched, err := DispatchQueued(0, providers, instances)
+	require.NoError(t, err)
+	assert.Empty(t, dispatched)
+	assert.True(t, queued.Queued())
+}
@@ -0,0 +1,86 @@
+package session
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// NormalizeScopes cleans up directory scopes given relative to the repository root, dropping empty and
+// duplicate entries.
+func NormalizeScopes(scopes []string) []string {
+	var normalized []string
+	seen := make(map[string]bool)
+	for _, scope := range scopes {
+		scope = strings.Trim(path.Clean(strings.TrimSpace(strings.ReplaceAll(scope, "\\", "/"))), "/")
+		if scope == "" || scope == "." || seen[scope] {
+			continue
+		}
+		seen[scope] = true
+		normalized = append(normalized, scope)
+	}
+	return normalized
+}
+
+// InScope returns true if the file, relative to the repository root, is inside one of the scopes. Every
+// file is in scope if there are no scopes.
+func InScope(file string, scopes []string) bool {
+	if len(scopes) == 0 {
+		return true
+	}
+	for _, scope := range scopes {
+		if file == scope || strings.HasPrefix(file, scope+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// ScopeInstructions tells the agent which directories it owns. It is kept on a single line since it is
+// typed into the program.
+func ScopeInstructions(scopes []string) string {
+	return fmt.Sprintf("[Scope: you own %s. Only change files under these directories and ask before "+
+		"touching anything else.]", strings.Join(scopes, ", "))
+}
+
+// LinkInstructions tells the agent the directories are shared with the repository and other sessions.
+func LinkInstructions(linked []string) string {
+	return fmt.Sprintf("[Shared: %s are links to directories shared with other sessions. Don't delete, "+
+		"move or reinstall them, or write to them; ask before changing dependencies.]", strings.Join(linked, ", "))
+}
+
+// promptInstructions returns the instructions sent along with the first prompt of the instance: its
+// scopes and the shared directories of its worktree, if any.
+func (i *Instance) promptInstructions() string {
+	var instructions []string
+	if len(i.Scopes) > 0 {
+		instructions = append(instructions, ScopeInstructions(i.Scopes))
+	}
+	if i.gitWorktree != nil {
+		if linked := i.gitWorktree.LinkedDirs(); len(linked) > 0 {
+			instructions = append(instructions, LinkInstructions(linked))
+		}
+	}
+	return strings.Join(instructions, " ")
+}
+
+// SetScopes assigns the directories the instance owns. The next prompt tells the agent about them.
+func (i *Instance) SetScopes(scopes []string) {
+	i.Scopes = NormalizeScopes(scopes)
+	i.scopeAnnounced = false
+}
+
+// OutOfScopeFiles returns the changed files of the current diff that are outside of the instance's scopes.
+func (i *Instance) OutOfScopeFiles() []string {
+	stats := i.GetDiffStats()
+	if len(i.Scopes) == 0 || stats == nil || stats.Error != nil {
+		return nil
+	}
+	var files []string
+	for _, file := range stats.ChangedFiles() {
+		if !InScope(file, i.Scopes) {
+			files = append(files, file)
+		}
+	}
+	return files
+}
@@ -0,0 +1,30 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeScopes(t *testing.T) {
+	assert.Equal(t, []string{"services/auth", "web"},
+		NormalizeScopes([]string{" ./services/auth/ ", "", "web", "web/", "."}))
+}
+
+func TestInScope(t *testing.T) {
+	scopes := []string{"services/auth", "README.md"}
+	assert.True(t, InScope("services/auth/login.go", scopes))
+	assert.True(t, InScope("README.md", scopes))
+	assert.False(t, InScope("services/authz/policy.go", scopes))
+	assert.False(t, InScope("main.go", scopes))
+	assert.True(t, InScope("main.go", nil))
+}
+
+func TestPromptInstructions(t *testing.T) {
+	instance := &Instance{Title: "auth"}
+	assert.Empty(t, instance.promptInstructions())
+
+	instance.SetScopes([]string{"services/auth"})
+	assert.Equal(t, ScopeInstructions([]string{"services/auth"}), instance.promptInstructions())
+	assert.Contains(t, LinkInstructions([]string{"node_modules", ".venv"}), "node_modules, .venv are links")
+}
@@ -0,0 +1,84 @@
+package session
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// minRedactLength is the minimum length of a secret value to be redacted. Shorter values like "1" or
+// "true" would redact unrelated output.
+const minRedactLength = 6
+
+// redacted replaces secret values in the previews.
+const redacted = "[REDACTED]"
+
+// LoadSecretEnv reads the variables of the dotenv files, relative to dir. Missing files are skipped.
+// Later files override earlier ones.
+func LoadSecretEnv(dir string, files []string) (map[string]string, error) {
+	env := make(map[string]string)
+	for _, file := range files {
+		f, err := os.Open(filepath.Join(dir, file))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to open secret env file %s: %w", file, err)
+		}
+		err = parseDotenv(f, env)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse secret env file %s: %w", file, err)
+		}
+	}
+	return env, nil
+}
+
+// parseDotenv parses KEY=VALUE lines into env. Blank lines, comments and an "export " prefix are
+// ignored, and values may be quoted.
+func parseDotenv(r io.Reader, env map[string]string) error {
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return fmt.Errorf("line %d: expected KEY=VALUE", lineNo)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		} else if idx := strings.Index(value, " #"); idx >= 0 {
+			value = strings.TrimSpace(value[:idx])
+		}
+		env[key] = value
+	}
+	return scanner.Err()
+}
+
+// RedactSecrets replaces the values of the secrets in content.
+func RedactSecrets(content string, secrets map[string]string) string {
+	values := make([]string, 0, len(secrets))
+	for _, value := range secrets {
+		if len(value) >= minRedactLength {
+			values = append(values, value)
+		}
+	}
+	// Replace longer values first so that a secret containing another one is redacted as a whole.
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	for _, value := range values {
+		content = strings.ReplaceAll(content, value, redacted)
+	}
+	return content
+}
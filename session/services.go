@@ -0,0 +1,142 @@
+package session
+
+import (
+	"claude-squad/config"
+	"claude-squad/log"
+	"fmt"
+	"maps"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// runCompose runs docker compose with the arguments in dir, with env added to the environment. It's
+// replaced in tests.
+var runCompose = func(dir string, env map[string]string, args ...string) error {
+	cmd := exec.Command("docker", append([]string{"compose"}, args...)...)
+	cmd.Dir = dir
+	cmd.Env = os.Environ()
+	for key, value := range env {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("docker compose %v failed: %s (%w)", args, output, err)
+	}
+	return nil
+}
+
+// serviceProjectName returns the docker compose project name of the services of an instance.
+func serviceProjectName(title string) string {
+	return "cs-" + slug(title, 48, "instance")
+}
+
+// formatEnv formats environment variables as KEY=value pairs, sorted by key.
+func formatEnv(env map[string]string) string {
+	pairs := make([]string, 0, len(env))
+	for key, value := range env {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, " ")
+}
+
+// allocatePorts returns a free host port for each of the variables.
+func allocatePorts(variables []string) (map[string]int, error) {
+	ports := make(map[string]int, len(variables))
+	// Keep the listeners open until all ports are picked so the same port isn't picked twice.
+	var listeners []net.Listener
+	defer func() {
+		for _, listener := range listeners {
+			listener.Close()
+		}
+	}()
+	for _, variable := range variables {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return nil, fmt.Errorf("failed to find a free port for %s: %w", variable, err)
+		}
+		listeners = append(listeners, listener)
+		ports[variable] = listener.Addr().(*net.TCPAddr).Port
+	}
+	return ports, nil
+}
+
+// startServices starts the configured docker compose services of the instance in its own compose project,
+// on free host ports. The ports are passed to the compose file and the program as environment variables.
+// Ports are picked again on every start, since the previous ones may have been taken while paused.
+func (i *Instance) startServices() error {
+	services := config.LoadConfig().Services
+	if services.ComposeFile == "" {
+		return nil
+	}
+	if i.Host != "" {
+		log.WarningLog.Printf("services aren't started for %s since it runs on %s", i.Title, i.Host)
+		return nil
+	}
+	if _, err := lookPath("docker"); err != nil {
+		return fmt.Errorf("services are configured but docker is not on PATH: install docker, or remove services from the config (see `cs debug`)")
+	}
+
+	ports, err := allocatePorts(services.PortVariables)
+	if err != nil {
+		return err
+	}
+	i.ServiceProject = serviceProjectName(i.Title)
+	i.ServicePorts = ports
+	env := i.serviceEnv()
+
+	workDir := i.gitWorktree.GetWorktreePath()
+	if err := runCompose(workDir, env, "-f", filepath.Join(workDir, services.ComposeFile), "up", "-d"); err != nil {
+		i.ServicePorts = nil
+		return fmt.Errorf("failed to start services: %w", err)
+	}
+	i.Audit(AuditServices, formatEnv(env))
+
+	sessionEnv := i.sessionEnv()
+	maps.Copy(sessionEnv, env)
+	i.tmuxSession.SetEnv(sessionEnv)
+	return nil
+}
+
+// serviceEnv returns the environment variables of the services of the instance: the compose project and
+// the host ports.
+func (i *Instance) serviceEnv() map[string]string {
+	env := make(map[string]string)
+	if i.ServiceProject == "" {
+		return env
+	}
+	env["COMPOSE_PROJECT_NAME"] = i.ServiceProject
+	for variable, port := range i.ServicePorts {
+		env[variable] = strconv.Itoa(port)
+	}
+	return env
+}
+
+// stopServices stops the services of the instance, keeping their data for when it's resumed.
+func (i *Instance) stopServices() error {
+	if i.ServiceProject == "" {
+		return nil
+	}
+	i.ServicePorts = nil
+	if err := runCompose("", nil, "-p", i.ServiceProject, "stop"); err != nil {
+		return fmt.Errorf("failed to stop services: %w", err)
+	}
+	return nil
+}
+
+// removeServices removes the services of the instance along with their data.
+func (i *Instance) removeServices() error {
+	if i.ServiceProject == "" {
+		return nil
+	}
+	if err := runCompose("", nil, "-p", i.ServiceProject, "down", "-v", "--remove-orphans"); err != nil {
+		return fmt.Errorf("failed to remove services: %w", err)
+	}
+	i.ServiceProject = ""
+	i.ServicePorts = nil
+	return nil
+}
@@ -0,0 +1,61 @@
+package session
+
+import (
+	"claude-squad/config"
+	"claude-squad/session/git"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServices(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	require.NoError(t, config.SaveConfig(&config.Config{Services: config.Services{
+		ComposeFile:   "compose.dev.yml",
+		PortVariables: []string{"POSTGRES_PORT", "REDIS_PORT"},
+	}}))
+
+	origLookPath, origRunCompose := lookPath, runCompose
+	defer func() { lookPath, runCompose = origLookPath, origRunCompose }()
+	lookPath = func(file string) (string, error) { return "/usr/bin/" + file, nil }
+	var calls [][]string
+	var upEnv map[string]string
+	runCompose = func(dir string, env map[string]string, args ...string) error {
+		calls = append(calls, args)
+		if args[len(args)-1] == "-d" {
+			upEnv = env
+		}
+		return nil
+	}
+
+	workDir := t.TempDir()
+	instance := &Instance{
+		Title:       "Fix Login",
+		CreatedAt:   time.Now(),
+		gitWorktree: git.NewGitWorktreeFromStorage("", workDir, "Fix Login", "fix-login", "", false),
+		tmuxSession: newTerminal("Fix Login", "claude", workDir),
+	}
+	require.NoError(t, instance.startServices())
+	assert.Equal(t, "cs-fix-login", instance.ServiceProject)
+	assert.Equal(t, []string{"-f", filepath.Join(workDir, "compose.dev.yml"), "up", "-d"}, calls[0])
+	assert.Equal(t, "cs-fix-login", upEnv["COMPOSE_PROJECT_NAME"])
+	postgres, redis := instance.ServicePorts["POSTGRES_PORT"], instance.ServicePorts["REDIS_PORT"]
+	assert.NotZero(t, postgres)
+	assert.NotEqual(t, postgres, redis)
+	assert.Equal(t, strconv.Itoa(postgres), upEnv["POSTGRES_PORT"])
+
+	require.NoError(t, instance.stopServices())
+	assert.Equal(t, []string{"-p", "cs-fix-login", "stop"}, calls[1])
+	assert.Nil(t, instance.ServicePorts)
+
+	require.NoError(t, instance.removeServices())
+	assert.Equal(t, []string{"-p", "cs-fix-login", "down", "-v", "--remove-orphans"}, calls[2])
+	assert.Empty(t, instance.ServiceProject)
+	// Nothing is left to remove.
+	require.NoError(t, instance.removeServices())
+	assert.Len(t, calls, 3)
+}
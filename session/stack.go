@@ -0,0 +1,112 @@
+package session
+
+import (
+	"claude-squad/session/git"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// BehindParent returns true if the parent instance's branch has commits this instance is not based on,
+// as of the last UpdateStackStatus.
+func (i *Instance) BehindParent() bool {
+	return i.behindParent
+}
+
+// UpdateStackStatus checks whether the parent branch moved since this instance was based on it.
+func (i *Instance) UpdateStackStatus() error {
+	if !i.started || i.Paused() || i.ParentBranch == "" {
+		return nil
+	}
+	behind, err := i.gitWorktree.IsBehind(i.ParentBranch)
+	if err != nil {
+		return err
+	}
+	i.behindParent = behind
+	return nil
+}
+
+// Restack rebases the instance's branch on the current tip of its parent's branch. If the rebase stops
+// on conflicts, they are handed off to the agent, and calling Restack again continues the rebase once
+// the agent resolved them.
+func (i *Instance) Restack() error {
+	if i.ParentBranch == "" {
+		return fmt.Errorf("instance '%s' is not stacked on another instance", i.Title)
+	}
+	if !i.started || i.Paused() {
+		return fmt.Errorf("cannot restack instance '%s' that is not running", i.Title)
+	}
+
+	var err error
+	if i.gitWorktree.IsRebaseInProgress() {
+		err = i.gitWorktree.ContinueRebase(i.ParentBranch)
+	} else {
+		err = i.gitWorktree.Rebase(i.ParentBranch)
+	}
+	var conflictErr *git.ConflictError
+	if errors.As(err, &conflictErr) {
+		return i.handOffConflicts(conflictErr.Files)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to restack instance '%s': %w", i.Title, err)
+	}
+	i.conflicts = nil
+	i.behindParent = false
+	return i.UpdateDiffStats()
+}
+
+// Conflicts returns the conflicted files handed off to the agent, until the rebase is continued.
+func (i *Instance) Conflicts() []string {
+	return i.conflicts
+}
+
+// handOffConflicts sends the conflicted hunks to the agent and asks it to resolve them.
+func (i *Instance) handOffConflicts(files []string) error {
+	hunks, err := i.gitWorktree.ConflictHunks(files)
+	if err != nil {
+		return err
+	}
+	i.conflicts = files
+	if err := i.SendPrompt(ConflictPrompt(i.ParentBranch, files, hunks)); err != nil {
+		return fmt.Errorf("failed to send conflicts to instance '%s': %w", i.Title, err)
+	}
+	return nil
+}
+
+// ConflictPrompt asks the agent to resolve the conflicts of a rebase on branch.
+func ConflictPrompt(branch string, files []string, hunks string) string {
+	return fmt.Sprintf("Rebasing this branch on %s stopped on conflicts in %s. Resolve these conflicts by "+
+		"editing the files so they keep the intent of both sides, and remove all the conflict markers. "+
+		"Don't run git rebase, git commit or git add, I'll continue the rebase once you're done.\n\n%s",
+		branch, strings.Join(files, ", "), hunks)
+}
+
+// StackOrder returns the instances stacked on root, directly or not, parents before their children.
+func StackOrder(root *Instance, instances []*Instance) []*Instance {
+	var stack []*Instance
+	queue := []string{root.Title}
+	for len(queue) > 0 {
+		parent := queue[0]
+		queue = queue[1:]
+		for _, instance := range instances {
+			if instance.Parent == parent && instance != root {
+				stack = append(stack, instance)
+				queue = append(queue, instance.Title)
+			}
+		}
+	}
+	return stack
+}
+
+// StackDepth returns how many ancestors of the instance are in instances.
+func StackDepth(instance *Instance, instances []*Instance) int {
+	byTitle := make(map[string]*Instance, len(instances))
+	for _, other := range instances {
+		byTitle[other.Title] = other
+	}
+	depth := 0
+	for parent := byTitle[instance.Parent]; parent != nil && depth < len(instances); parent = byTitle[parent.Parent] {
+		depth++
+	}
+	return depth
+}
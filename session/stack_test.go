@@ -0,0 +1,31 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStackOrder(t *testing.T) {
+	base := &Instance{Title: "base"}
+	api := &Instance{Title: "api", Parent: "base"}
+	ui := &Instance{Title: "ui", Parent: "api"}
+	docs := &Instance{Title: "docs", Parent: "base"}
+	other := &Instance{Title: "other"}
+	instances := []*Instance{ui, other, docs, api, base}
+
+	assert.Equal(t, []*Instance{docs, api, ui}, StackOrder(base, instances))
+	assert.Equal(t, []*Instance{ui}, StackOrder(api, instances))
+	assert.Empty(t, StackOrder(other, instances))
+
+	assert.Equal(t, 0, StackDepth(base, instances))
+	assert.Equal(t, 2, StackDepth(ui, instances))
+	// The parent was killed.
+	assert.Equal(t, 0, StackDepth(ui, []*Instance{ui, other}))
+}
+
+func TestConflictPrompt(t *testing.T) {
+	prompt := ConflictPrompt("me/base", []string{"a.go", "b.go"}, "a.go:3\n<<<<<<< HEAD\n...")
+	assert.Contains(t, prompt, "on me/base stopped on conflicts in a.go, b.go")
+	assert.Contains(t, prompt, "<<<<<<< HEAD")
+}
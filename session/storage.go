@@ -2,11 +2,23 @@ package session
 
 import (
 	"claude-squad/config"
+	"claude-squad/log"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
+	"sync"
 	"time"
 )
 
+// The blobs of a paused instance: its diff and its screen when it was paused, which are kept out of the
+// state file since they can be big. A running instance has none, its diff is recomputed from its worktree
+// after loading.
+const (
+	diffBlobName   = "diff"
+	screenBlobName = "screen"
+)
+
 // InstanceData represents the serializable data of an Instance
 type InstanceData struct {
 	Title     string    `json:"title"`
@@ -18,6 +30,38 @@ type InstanceData struct {
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 	AutoYes   bool      `json:"auto_yes"`
+	Group     string    `json:"group,omitempty"`
+	Scopes    []string  `json:"scopes,omitempty"`
+	Notes     string    `json:"notes,omitempty"`
+	Todos     []Todo    `json:"todos,omitempty"`
+
+	Parent         string         `json:"parent,omitempty"`
+	ParentBranch   string         `json:"parent_branch,omitempty"`
+	TouchedFiles   []string       `json:"touched_files,omitempty"`
+	ServiceProject string         `json:"service_project,omitempty"`
+	ServicePorts   map[string]int `json:"service_ports,omitempty"`
+	Teardown       []string       `json:"teardown,omitempty"`
+	Host           string         `json:"host,omitempty"`
+	Pipeline       *PipelineStage `json:"pipeline,omitempty"`
+	Checks         *CheckResult   `json:"checks,omitempty"`
+	Suspended      bool           `json:"suspended,omitempty"`
+	Errors         []ErrorRecord  `json:"errors,omitempty"`
+	TerminalName   string         `json:"terminal_name,omitempty"`
+
+	ExternalResources   []string  `json:"external_resources,omitempty"`
+	WaitingForResources bool      `json:"waiting_for_resources,omitempty"`
+	QueuedPrompts       []string  `json:"queued_prompts,omitempty"`
+	QueuedAt            time.Time `json:"queued_at"`
+
+	ClaudeSessionID string `json:"claude_session_id,omitempty"`
+
+	TimeBudget time.Duration `json:"time_budget,omitempty"`
+	Deadline   time.Time     `json:"deadline,omitempty"`
+	WrappedUp  bool          `json:"wrapped_up,omitempty"`
+
+	SplitRequested bool `json:"split_requested,omitempty"`
+
+	Provider string `json:"provider,omitempty"`
 
 	Program   string          `json:"program"`
 	Worktree  GitWorktreeData `json:"worktree"`
@@ -31,18 +75,75 @@ type GitWorktreeData struct {
 	SessionName   string `json:"session_name"`
 	BranchName    string `json:"branch_name"`
 	BaseCommitSHA string `json:"base_commit_sha"`
+	// ExistingBranch is true if the branch existed before the instance and is kept when it's killed.
+	ExistingBranch bool `json:"existing_branch,omitempty"`
+	// VCS is the backend the worktree is made with, "jj" for a Jujutsu workspace. Empty is a git worktree.
+	VCS string `json:"vcs,omitempty"`
+	// CopiedFiles are the files copy_on_create copied into the worktree, left out of its diff.
+	CopiedFiles []string `json:"copied_files,omitempty"`
 }
 
 // DiffStatsData represents the serializable data of a DiffStats
 type DiffStatsData struct {
-	Added   int    `json:"added"`
-	Removed int    `json:"removed"`
-	Content string `json:"content"`
+	Added   int `json:"added"`
+	Removed int `json:"removed"`
+	// Content is kept by archived instances. The stored instances only have it in the state of older
+	// versions, see diffBlobName.
+	Content string `json:"content,omitempty"`
 }
 
 // Storage handles saving and loading instances using the state interface
 type Storage struct {
 	state config.InstanceStorage
+
+	// mu serializes the saves and loads, which compare the instances to the ones saved last.
+	mu sync.Mutex
+	// saved are the instances as they were last saved or loaded, by their blob key, and order is their
+	// keys in the order they were stored. saved is nil until the instances are first saved or loaded.
+	saved map[string]savedInstance
+	order []string
+}
+
+// savedInstance is an instance as it was last saved or loaded, to only save the instances that changed.
+type savedInstance struct {
+	// fingerprint is the instance data without its update time and diff, which are compared to tell if
+	// the instance changed.
+	fingerprint []byte
+	// entry is the instance data as it's stored.
+	entry json.RawMessage
+	// blobs is the content of the blobs of the instance, by name.
+	blobs map[string]string
+}
+
+// blobKey returns the key the bulky data of the instance is stored under. Like the audit log, it's named
+// after the creation time too, since titles can be reused.
+func blobKey(data InstanceData) string {
+	return fmt.Sprintf("%s-%d", slug(data.Title, 64, "instance"), data.CreatedAt.Unix())
+}
+
+// instanceBlobs returns the content of the blobs of the instance, by name.
+func instanceBlobs(instance *Instance, data InstanceData) map[string]string {
+	if data.Status != Paused {
+		return nil
+	}
+	return map[string]string{diffBlobName: data.DiffStats.Content, screenBlobName: instance.pausedScreen}
+}
+
+// instanceFingerprint returns the instance data without its update time and diff.
+func instanceFingerprint(data InstanceData) ([]byte, error) {
+	data.UpdatedAt = time.Time{}
+	data.DiffStats.Content = ""
+	return json.Marshal(data)
+}
+
+// ParseInstances returns the data of the instances stored in the state, without loading them, which
+// restores their terminal sessions.
+func ParseInstances(state config.InstanceStorage) ([]InstanceData, error) {
+	var instances []InstanceData
+	if err := json.Unmarshal(state.GetInstances(), &instances); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal instances: %w", err)
+	}
+	return instances, nil
 }
 
 // NewStorage creates a new storage instance
@@ -52,46 +153,261 @@ func NewStorage(state config.InstanceStorage) (*Storage, error) {
 	}, nil
 }
 
-// SaveInstances saves the list of instances to disk
+// SaveInstances saves the list of instances to disk. The blobs of the paused instances are saved to their
+// own files, and only when they changed. The instances that didn't change since the last save keep their stored data, and the
+// state isn't written at all if none did.
 func (s *Storage) SaveInstances(instances []*Instance) error {
-	// Convert instances to InstanceData
-	data := make([]InstanceData, 0)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	saved := make(map[string]savedInstance)
+	var order []string
+	entries := make([]json.RawMessage, 0)
+	changed := false
 	for _, instance := range instances {
-		if instance.Started() {
-			data = append(data, instance.ToInstanceData())
+		if !instance.Started() {
+			continue
+		}
+		data := instance.ToInstanceData()
+		key := blobKey(data)
+		current := savedInstance{blobs: instanceBlobs(instance, data)}
+		fingerprint, err := instanceFingerprint(data)
+		if err != nil {
+			return fmt.Errorf("failed to marshal instances: %w", err)
+		}
+		current.fingerprint = fingerprint
+
+		previous, ok := s.saved[key]
+		if err := s.saveBlobs(key, previous.blobs, current.blobs); err != nil {
+			return fmt.Errorf("failed to save the data of %s: %w", data.Title, err)
 		}
+		if ok && slices.Equal(previous.fingerprint, current.fingerprint) {
+			current.entry = previous.entry
+		} else {
+			data.DiffStats.Content = ""
+			if current.entry, err = json.Marshal(data); err != nil {
+				return fmt.Errorf("failed to marshal instances: %w", err)
+			}
+			changed = true
+		}
+		saved[key] = current
+		order = append(order, key)
+		entries = append(entries, current.entry)
 	}
 
-	// Marshal to JSON
-	jsonData, err := json.Marshal(data)
-	if err != nil {
-		return fmt.Errorf("failed to marshal instances: %w", err)
+	if s.saved == nil || changed || !slices.Equal(order, s.order) {
+		jsonData, err := json.Marshal(entries)
+		if err != nil {
+			return fmt.Errorf("failed to marshal instances: %w", err)
+		}
+		if err := s.state.SaveInstances(jsonData); err != nil {
+			return err
+		}
 	}
+	for key := range s.saved {
+		if _, ok := saved[key]; !ok {
+			if err := s.state.DeleteInstanceBlobs(key); err != nil {
+				log.WarningLog.Print(err)
+			}
+		}
+	}
+	s.saved = saved
+	s.order = order
+	return nil
+}
 
-	return s.state.SaveInstances(jsonData)
+// saveBlobs saves the blobs of the instance stored under key that changed, or removes them all if it
+// has none.
+func (s *Storage) saveBlobs(key string, previous, current map[string]string) error {
+	if len(current) == 0 {
+		if len(previous) == 0 {
+			return nil
+		}
+		return s.state.DeleteInstanceBlobs(key)
+	}
+	for name, content := range current {
+		if content == previous[name] {
+			continue
+		}
+		if err := s.state.SaveInstanceBlob(key, name, []byte(content)); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // LoadInstances loads the list of instances from disk
 func (s *Storage) LoadInstances() ([]*Instance, error) {
-	jsonData := s.state.GetInstances()
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	var instancesData []InstanceData
-	if err := json.Unmarshal(jsonData, &instancesData); err != nil {
+	var entries []json.RawMessage
+	if err := json.Unmarshal(s.state.GetInstances(), &entries); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal instances: %w", err)
 	}
 
-	instances := make([]*Instance, len(instancesData))
-	for i, data := range instancesData {
+	saved := make(map[string]savedInstance)
+	var order []string
+	instances := make([]*Instance, len(entries))
+	for i, entry := range entries {
+		var data InstanceData
+		if err := json.Unmarshal(entry, &data); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal instances: %w", err)
+		}
+		key := blobKey(data)
+		// Instances saved before the diffs had their own files have it inline. They aren't recorded as
+		// saved, so their diff is moved out on the next save.
+		inline := data.DiffStats.Content != ""
+		var screen string
+		if data.Status == Paused && !inline {
+			data.DiffStats.Content = s.loadBlob(key, diffBlobName, data.Title)
+			screen = s.loadBlob(key, screenBlobName, data.Title)
+		}
+		order = append(order, key)
+
 		instance, err := FromInstanceData(data)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create instance %s: %w", data.Title, err)
 		}
+		instance.pausedScreen = screen
+		if fingerprint, err := instanceFingerprint(data); err == nil && !inline {
+			saved[key] = savedInstance{fingerprint: fingerprint, entry: entry, blobs: instanceBlobs(instance, data)}
+		}
 		instances[i] = instance
 	}
+	s.saved = saved
+	s.order = order
 
 	return instances, nil
 }
 
+// loadBlob returns the named blob of the instance stored under key, or nothing if it can't be read.
+func (s *Storage) loadBlob(key, name, title string) string {
+	content, err := s.state.GetInstanceBlob(key, name)
+	if err != nil {
+		log.WarningLog.Printf("failed to load the %s of %s: %v", name, title, err)
+	}
+	return string(content)
+}
+
+// ExistingBranches returns the branches the stored instances reused instead of creating them, without
+// starting the instances.
+func (s *Storage) ExistingBranches() ([]string, error) {
+	var instancesData []InstanceData
+	if err := json.Unmarshal(s.state.GetInstances(), &instancesData); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal instances: %w", err)
+	}
+
+	var branches []string
+	for _, data := range instancesData {
+		if data.Worktree.ExistingBranch {
+			branches = append(branches, data.Worktree.BranchName)
+		}
+	}
+	return branches, nil
+}
+
+// ArchiveInstance adds an archived instance to storage. It doesn't remove the instance from the stored
+// instances. If object storage is configured, the final diff and the conversation are offloaded to it,
+// and the offloaded archives that expired are deleted.
+func (s *Storage) ArchiveInstance(archived ArchivedInstance) error {
+	artifacts := config.LoadConfig().ArtifactStorage
+	if artifacts.URL != "" {
+		offloaded, err := offloadArchive(archived, artifacts)
+		if err != nil {
+			// Keep the archive locally rather than losing it.
+			log.WarningLog.Print(err)
+		}
+		archived = offloaded
+	}
+
+	instances, err := s.ArchivedInstances()
+	if err != nil {
+		return err
+	}
+	if err := s.saveArchivedInstances(append(instances, archived)); err != nil {
+		return err
+	}
+	if _, err := s.PruneArchivedInstances(time.Now()); err != nil {
+		log.WarningLog.Printf("failed to delete expired archives: %v", err)
+	}
+	return nil
+}
+
+// PruneArchivedInstances deletes the offloaded archives older than the configured expiry, and returns
+// how many were deleted.
+func (s *Storage) PruneArchivedInstances(now time.Time) (int, error) {
+	expireDays := config.LoadConfig().ArtifactStorage.ExpireDays
+	if expireDays <= 0 {
+		return 0, nil
+	}
+	instances, err := s.ArchivedInstances()
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := now.AddDate(0, 0, -expireDays)
+	var errs []error
+	remaining := make([]ArchivedInstance, 0, len(instances))
+	for _, instance := range instances {
+		if instance.BundleURL == "" || instance.ArchivedAt.After(cutoff) {
+			remaining = append(remaining, instance)
+			continue
+		}
+		if err := deleteObject(instance.BundleURL); err != nil {
+			errs = append(errs, err)
+			remaining = append(remaining, instance)
+		}
+	}
+	if len(remaining) == len(instances) {
+		return 0, errors.Join(errs...)
+	}
+	if err := s.saveArchivedInstances(remaining); err != nil {
+		return 0, err
+	}
+	return len(instances) - len(remaining), errors.Join(errs...)
+}
+
+// ArchivedInstances returns the archived instances, in the order they were archived.
+func (s *Storage) ArchivedInstances() ([]ArchivedInstance, error) {
+	var instances []ArchivedInstance
+	if err := json.Unmarshal(s.state.GetArchivedInstances(), &instances); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal archived instances: %w", err)
+	}
+	return instances, nil
+}
+
+// DeleteArchivedInstance removes an archived instance from storage.
+func (s *Storage) DeleteArchivedInstance(archived ArchivedInstance) error {
+	instances, err := s.ArchivedInstances()
+	if err != nil {
+		return err
+	}
+
+	remaining := make([]ArchivedInstance, 0, len(instances))
+	for _, instance := range instances {
+		if instance.Title != archived.Title || !instance.ArchivedAt.Equal(archived.ArchivedAt) {
+			remaining = append(remaining, instance)
+		} else if instance.BundleURL != "" {
+			if err := deleteObject(instance.BundleURL); err != nil {
+				log.WarningLog.Printf("failed to delete the offloaded archive of %s: %v", instance.Title, err)
+			}
+		}
+	}
+	if len(remaining) == len(instances) {
+		return fmt.Errorf("archived instance not found: %s", archived.Title)
+	}
+	return s.saveArchivedInstances(remaining)
+}
+
+func (s *Storage) saveArchivedInstances(instances []ArchivedInstance) error {
+	jsonData, err := json.Marshal(instances)
+	if err != nil {
+		return fmt.Errorf("failed to marshal archived instances: %w", err)
+	}
+	return s.state.SaveArchivedInstances(jsonData)
+}
+
 // DeleteInstance removes an instance from storage
 func (s *Storage) DeleteInstance(title string) error {
 	instances, err := s.LoadInstances()
@@ -144,5 +460,9 @@ func (s *Storage) UpdateInstance(instance *Instance) error {
 
 // DeleteAllInstances removes all stored instances
 func (s *Storage) DeleteAllInstances() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.saved = nil
+	s.order = nil
 	return s.state.DeleteAllInstances()
 }
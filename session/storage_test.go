@@ -0,0 +1,145 @@
+package session
+
+import (
+	"claude-squad/config"
+	"claude-squad/session/git"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingState counts the writes of the instances to the state.
+type countingState struct {
+	*config.State
+	saves int
+}
+
+func (c *countingState) SaveInstances(instancesJSON json.RawMessage) error {
+	c.saves++
+	return c.State.SaveInstances(instancesJSON)
+}
+
+func TestSaveInstancesDifferential(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	state := &countingState{State: config.DefaultState()}
+	storage, err := NewStorage(state)
+	require.NoError(t, err)
+
+	created := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	first := &Instance{Title: "first", Status: Paused, started: true, CreatedAt: created,
+		gitWorktree: git.NewGitWorktreeFromStorage("/repo", "/worktrees/first", "first", "first", "abc", false),
+		diffStats:   &git.DiffStats{Added: 1, Content: "+first change"}}
+	second := &Instance{Title: "second", Status: Paused, started: true, CreatedAt: created.Add(time.Minute),
+		gitWorktree: git.NewGitWorktreeFromStorage("/repo", "/worktrees/second", "second", "second", "abc", false),
+		diffStats:   &git.DiffStats{}}
+	instances := []*Instance{first, second}
+
+	require.NoError(t, storage.SaveInstances(instances))
+	assert.Equal(t, 1, state.saves)
+	assert.NotContains(t, string(state.GetInstances()), "+first change")
+	diff, err := state.GetInstanceBlob(blobKey(first.ToInstanceData()), diffBlobName)
+	require.NoError(t, err)
+	assert.Equal(t, "+first change", string(diff))
+
+	t.Run("unchanged instances aren't written", func(t *testing.T) {
+		require.NoError(t, storage.SaveInstances(instances))
+		assert.Equal(t, 1, state.saves)
+	})
+
+	t.Run("a changed diff is only written to its file", func(t *testing.T) {
+		first.diffStats.Content = "+first change\n+another one"
+		require.NoError(t, storage.SaveInstances(instances))
+		assert.Equal(t, 1, state.saves)
+
+		first.diffStats.Added = 2
+		require.NoError(t, storage.SaveInstances(instances))
+		assert.Equal(t, 2, state.saves)
+	})
+
+	t.Run("loading reads the diffs back", func(t *testing.T) {
+		loadedStorage, err := NewStorage(state)
+		require.NoError(t, err)
+		loaded, err := loadedStorage.LoadInstances()
+		require.NoError(t, err)
+		require.Len(t, loaded, 2)
+		assert.Equal(t, &git.DiffStats{Added: 2, Content: "+first change\n+another one"}, loaded[0].GetDiffStats())
+
+		require.NoError(t, loadedStorage.SaveInstances(loaded))
+		assert.Equal(t, 2, state.saves)
+	})
+
+	t.Run("removed instances lose their diff", func(t *testing.T) {
+		require.NoError(t, storage.SaveInstances([]*Instance{second}))
+		assert.Equal(t, 3, state.saves)
+		diff, err := state.GetInstanceBlob(blobKey(first.ToInstanceData()), diffBlobName)
+		require.NoError(t, err)
+		assert.Nil(t, diff)
+	})
+
+	t.Run("running instances don't store their diff", func(t *testing.T) {
+		second.diffStats = &git.DiffStats{Added: 1, Content: "+second change"}
+		require.NoError(t, storage.SaveInstances([]*Instance{second}))
+		diff, err := state.GetInstanceBlob(blobKey(second.ToInstanceData()), diffBlobName)
+		require.NoError(t, err)
+		assert.Equal(t, "+second change", string(diff))
+
+		second.Status = Running
+		require.NoError(t, storage.SaveInstances([]*Instance{second}))
+		assert.NotContains(t, string(state.GetInstances()), "+second change")
+		diff, err = state.GetInstanceBlob(blobKey(second.ToInstanceData()), diffBlobName)
+		require.NoError(t, err)
+		assert.Nil(t, diff)
+	})
+}
+
+func TestLoadInstancesMovesInlineDiffs(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	state := config.DefaultState()
+	created := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	legacy, err := json.Marshal([]InstanceData{{Title: "legacy", Status: Paused, CreatedAt: created,
+		DiffStats: DiffStatsData{Added: 1, Content: "+inline"}}})
+	require.NoError(t, err)
+	require.NoError(t, state.SaveInstances(legacy))
+
+	storage, err := NewStorage(state)
+	require.NoError(t, err)
+	instances, err := storage.LoadInstances()
+	require.NoError(t, err)
+	require.Len(t, instances, 1)
+	assert.Equal(t, "+inline", instances[0].GetDiffStats().Content)
+
+	require.NoError(t, storage.SaveInstances(instances))
+	assert.NotContains(t, string(state.GetInstances()), "+inline")
+	diff, err := state.GetInstanceBlob(blobKey(instances[0].ToInstanceData()), diffBlobName)
+	require.NoError(t, err)
+	assert.Equal(t, "+inline", string(diff))
+}
+
+func TestPausedScreenIsKept(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	state := config.DefaultState()
+	storage, err := NewStorage(state)
+	require.NoError(t, err)
+
+	instance := &Instance{Title: "paused", Status: Paused, started: true,
+		CreatedAt:    time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC),
+		gitWorktree:  git.NewGitWorktreeFromStorage("/repo", "/worktrees/paused", "paused", "paused", "abc", false),
+		diffStats:    &git.DiffStats{Added: 1, Content: "+change"},
+		pausedScreen: "> Fixed the login form"}
+	require.NoError(t, storage.SaveInstances([]*Instance{instance}))
+	assert.NotContains(t, string(state.GetInstances()), "Fixed the login form")
+
+	loaded, err := NewStorage(state)
+	require.NoError(t, err)
+	instances, err := loaded.LoadInstances()
+	require.NoError(t, err)
+	require.Len(t, instances, 1)
+	assert.Equal(t, "> Fixed the login form", instances[0].PausedScreen())
+	assert.Equal(t, "+change", instances[0].GetDiffStats().Content)
+
+	instances[0].Status = Running
+	assert.Empty(t, instances[0].PausedScreen())
+}
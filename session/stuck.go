@@ -0,0 +1,49 @@
+package session
+
+import (
+	"claude-squad/session/tmux"
+	"regexp"
+	"strings"
+)
+
+// stuckTailLines is how many of the last lines of the pane are matched against the stuck patterns, so
+// prompts that were already answered further up don't count.
+const stuckTailLines = 5
+
+// IsStuck returns true if the last lines of content show a prompt matching one of the patterns, and the
+// prompt isn't one auto-yes answers.
+func IsStuck(program, content string, patterns []*regexp.Regexp) bool {
+	if len(patterns) == 0 || tmux.HasPrompt(program, content) {
+		return false
+	}
+	lines := strings.Split(strings.TrimRight(content, "\n "), "\n")
+	if len(lines) > stuckTailLines {
+		lines = lines[len(lines)-stuckTailLines:]
+	}
+	tail := strings.Join(lines, "\n")
+	for _, pattern := range patterns {
+		if pattern.MatchString(tail) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckStuck checks whether the pane content of the instance shows it waits on a prompt matching one of
+// the patterns, and sets its status to NeedsAttention while it does. changed is true when the status changed, so the user is
+// notified once.
+func (i *Instance) CheckStuck(content string, patterns []*regexp.Regexp) (stuck bool, changed bool) {
+	if !i.started || i.Paused() {
+		return false, false
+	}
+	stuck = IsStuck(i.Program, content, patterns)
+	switch {
+	case stuck && i.Status != NeedsAttention:
+		i.SetStatus(NeedsAttention)
+		return true, true
+	case !stuck && i.Status == NeedsAttention:
+		i.SetStatus(Running)
+		return false, true
+	}
+	return stuck, false
+}
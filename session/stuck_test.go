@@ -0,0 +1,20 @@
+package session
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsStuck(t *testing.T) {
+	patterns := []*regexp.Regexp{regexp.MustCompile(`Do you want to`), regexp.MustCompile(`(?i)press y to continue`)}
+
+	assert.True(t, IsStuck("aider", "Running migrations...\nPress Y to continue\n\n", patterns))
+	assert.False(t, IsStuck("aider", "Running migrations...\nDone.", patterns))
+	// A prompt that was answered further up doesn't count.
+	assert.False(t, IsStuck("aider", "Do you want to overwrite it? y\n1\n2\n3\n4\n5\n6", patterns))
+	// Prompts auto-yes answers aren't stuck.
+	assert.False(t, IsStuck("claude", "Do you want to proceed?\n❯ 1. Yes\n  2. No, and tell Claude what to do differently", patterns))
+	assert.False(t, IsStuck("aider", "Press y to continue", nil))
+}
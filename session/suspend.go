@@ -0,0 +1,49 @@
+package session
+
+import (
+	"claude-squad/config"
+	"errors"
+	"fmt"
+)
+
+// Suspend pauses every running instance, committing the changes of their worktrees, and marks them as
+// suspended so ResumeSuspended resumes them again. Instances whose sessions are gone are paused too. It
+// returns the instances it suspended, and joins the errors of the ones it couldn't.
+func Suspend(instances []*Instance) ([]*Instance, error) {
+	var suspended []*Instance
+	var errs []error
+	for _, instance := range instances {
+		if !instance.Started() || instance.Paused() {
+			continue
+		}
+		if err := instance.Pause(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", instance.Title, err))
+			continue
+		}
+		instance.Suspended = true
+		suspended = append(suspended, instance)
+	}
+	return suspended, errors.Join(errs...)
+}
+
+// ResumeSuspended resumes the paused instances Suspend suspended, leaving the instances paused on their
+// own alone. The instances beyond maxRunning running instances are queued, see ResumeOrQueue. It returns
+// the instances it resumed, and joins the errors of the ones it couldn't.
+func ResumeSuspended(maxRunning int, providers []config.Provider, instances []*Instance) ([]*Instance, error) {
+	var resumed []*Instance
+	var errs []error
+	for _, instance := range instances {
+		if !instance.Suspended || !instance.Paused() || instance.Queued() {
+			continue
+		}
+		queued, err := instance.ResumeOrQueue(maxRunning, providers, instances)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", instance.Title, err))
+			continue
+		}
+		if !queued {
+			resumed = append(resumed, instance)
+		}
+	}
+	return resumed, errors.Join(errs...)
+}
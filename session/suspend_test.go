@@ -0,0 +1,39 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSuspendSkipsInstancesNotRunning(t *testing.T) {
+	notStarted := &Instance{Title: "not-started", Status: Running}
+	paused := &Instance{Title: "paused", Status: Paused, started: true}
+
+	suspended, err := Suspend([]*Instance{notStarted, paused})
+	require.NoError(t, err)
+	assert.Empty(t, suspended)
+	assert.False(t, notStarted.Suspended)
+	assert.False(t, paused.Suspended)
+}
+
+func TestResumeSuspendedOnlyResumesSuspendedInstances(t *testing.T) {
+	pausedByUser := &Instance{Title: "paused-by-user", Status: Paused, started: true}
+	// Not started, so resuming it fails, which shows it was picked.
+	suspended := &Instance{Title: "suspended", Status: Paused, Suspended: true}
+
+	resumed, err := ResumeSuspended(0, nil, []*Instance{pausedByUser, suspended})
+	assert.Empty(t, resumed)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "suspended: cannot resume instance that has not been started")
+	assert.NotContains(t, err.Error(), "paused-by-user")
+	assert.True(t, suspended.Suspended)
+}
+
+func TestSuspendedIsSaved(t *testing.T) {
+	instance := &Instance{Title: "suspended", Status: Paused, Suspended: true}
+	restored, err := FromInstanceData(instance.ToInstanceData())
+	require.NoError(t, err)
+	assert.True(t, restored.Suspended)
+}
@@ -0,0 +1,100 @@
+package session
+
+import (
+	"claude-squad/config"
+	"claude-squad/log"
+	"claude-squad/session/remote"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"slices"
+	"time"
+)
+
+// teardownTimeout is how long a teardown command may run.
+const teardownTimeout = time.Minute
+
+// runTeardown runs the teardown commands of the instance in its worktree before it's removed: the global
+// ones, the repository's and the template's. The commands get the secrets and the services of the
+// instance in their environment, along with CS_TITLE, CS_BRANCH and CS_TEARDOWN_REASON ("kill",
+// "pause" or "archive"). A failing command doesn't stop the others. The commands of a remote instance
+// run on its host, without the repository's, which are there too.
+func (i *Instance) runTeardown(reason string) error {
+	worktreePath := i.gitWorktree.GetWorktreePath()
+	if !i.gitWorktree.Exists() {
+		// The worktree was already torn down when the instance was paused.
+		return nil
+	}
+
+	commands := append([]string{}, config.LoadConfig().TeardownCommands...)
+	if i.Host == "" {
+		repoConfig, err := config.LoadRepoConfig(i.gitWorktree.GetRepoPath())
+		if err != nil {
+			return err
+		}
+		if repoConfig.Untrusted {
+			log.WarningLog.Printf("not running the commands in the %s of %s: run `cs trust` in the repository to trust them",
+				config.RepoConfigFileName, i.Title)
+		}
+		commands = append(commands, repoConfig.TeardownCommands...)
+	}
+	commands = append(commands, i.Teardown...)
+	if len(commands) == 0 {
+		return nil
+	}
+
+	extra := i.commandEnv()
+	extra["CS_TEARDOWN_REASON"] = reason
+	env := os.Environ()
+	for key, value := range extra {
+		env = append(env, key+"="+value)
+	}
+
+	var errs []error
+	for _, command := range commands {
+		ctx, cancel := context.WithTimeout(context.Background(), teardownTimeout)
+		var cmd *exec.Cmd
+		if i.Host != "" {
+			cmd = remote.ScriptContext(ctx, i.Host, remoteTeardownScript(worktreePath, extra, command))
+		} else {
+			cmd = shellCommand(ctx, command)
+			cmd.Dir = worktreePath
+			cmd.Env = env
+		}
+		output, err := cmd.CombinedOutput()
+		cancel()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("teardown command %q failed: %s (%w)", command, output, err))
+			continue
+		}
+		i.Audit(AuditTeardown, command)
+	}
+	return errors.Join(errs...)
+}
+
+// commandEnv returns the environment variables of the commands run in the worktree of the instance, on
+// top of the environment of the app: its secrets, its services, CS_TITLE and CS_BRANCH.
+func (i *Instance) commandEnv() map[string]string {
+	env := maps.Clone(i.secrets)
+	if env == nil {
+		env = make(map[string]string)
+	}
+	maps.Copy(env, i.serviceEnv())
+	env["CS_TITLE"] = i.Title
+	env["CS_BRANCH"] = i.Branch
+	return env
+}
+
+// remoteTeardownScript returns the shell script that runs command in the worktree at path on a remote host,
+// with the extra environment variables.
+func remoteTeardownScript(path string, env map[string]string, command string) string {
+	args := []string{"env"}
+	for _, key := range slices.Sorted(maps.Keys(env)) {
+		args = append(args, key+"="+env[key])
+	}
+	args = append(args, "sh", "-c", command)
+	return "cd " + remote.Quote(path) + " && " + remote.Quote(args...)
+}
@@ -0,0 +1,64 @@
+package session
+
+import (
+	"claude-squad/config"
+	"claude-squad/session/git"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunTeardown(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the commands use sh")
+	}
+	t.Setenv("HOME", t.TempDir())
+	require.NoError(t, config.SaveConfig(&config.Config{
+		TeardownCommands: []string{`echo "global $CS_TEARDOWN_REASON $CS_TITLE $API_KEY" >> log`},
+	}))
+	repoPath, worktreePath := t.TempDir(), t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, config.RepoConfigFileName),
+		[]byte(`{"teardown_commands": ["exit 3", "echo repo >> log"]}`), 0644))
+
+	instance := &Instance{
+		Title:       "fix-login",
+		CreatedAt:   time.Now(),
+		Teardown:    []string{"echo template $COMPOSE_PROJECT_NAME $PGPORT >> log"},
+		secrets:     map[string]string{"API_KEY": "s3cr3t"},
+		gitWorktree: git.NewGitWorktreeFromStorage(repoPath, worktreePath, "fix-login", "fix-login", "", false),
+	}
+	instance.ServiceProject = "cs-fix-login"
+	instance.ServicePorts = map[string]int{"PGPORT": 54321}
+
+	// The commands of the repository don't run until they're trusted.
+	require.NoError(t, os.MkdirAll(worktreePath, 0755))
+	assert.NoError(t, instance.runTeardown("pause"))
+	log, err := os.ReadFile(filepath.Join(worktreePath, "log"))
+	require.NoError(t, err)
+	assert.Equal(t, "global pause fix-login s3cr3t\ntemplate cs-fix-login 54321\n", string(log))
+	require.NoError(t, os.Remove(filepath.Join(worktreePath, "log")))
+	trustRepoConfig(t, repoPath)
+
+	// A failing command is reported without stopping the others.
+	err = instance.runTeardown("kill")
+	assert.ErrorContains(t, err, `teardown command "exit 3" failed`)
+	log, err = os.ReadFile(filepath.Join(worktreePath, "log"))
+	require.NoError(t, err)
+	assert.Equal(t, "global kill fix-login s3cr3t\nrepo\ntemplate cs-fix-login 54321\n", string(log))
+
+	// Nothing runs once the worktree is gone.
+	require.NoError(t, os.RemoveAll(worktreePath))
+	assert.NoError(t, instance.runTeardown("kill"))
+	assert.NoFileExists(t, filepath.Join(worktreePath, "log"))
+}
+
+func TestRemoteTeardownScript(t *testing.T) {
+	script := remoteTeardownScript("~/.claude-squad/worktrees/fix-login_1",
+		map[string]string{"CS_TITLE": "fix login", "CS_TEARDOWN_REASON": "kill"}, "make clean")
+	assert.Equal(t, "cd ~/.claude-squad/worktrees/fix-login_1 && env CS_TEARDOWN_REASON=kill 'CS_TITLE=fix login' sh -c 'make clean'", script)
+}
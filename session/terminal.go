@@ -0,0 +1,103 @@
+package session
+
+import (
+	"claude-squad/config"
+	"claude-squad/log"
+	"claude-squad/session/tmux"
+	"fmt"
+)
+
+// Terminal runs the program of an instance in a terminal session that keeps running while detached.
+// It is backed by tmux, except on Windows where tmux isn't available natively and a ConPTY backend is
+// used instead.
+type Terminal interface {
+	// Start starts the program in a new session in workDir.
+	Start(workDir string) error
+	// Restore reconnects to an existing session.
+	Restore() error
+	// Close terminates the session.
+	Close() error
+	// Attach connects the session to stdin/stdout. The channel is closed on detach.
+	Attach() (chan struct{}, error)
+	// AttachCommand returns the command line that attaches to the session from another terminal.
+	AttachCommand() ([]string, error)
+	// SetDetachedSize sets the size of the session while detached.
+	SetDetachedSize(width, height int) error
+	// DoesSessionExist returns true if the session is running.
+	DoesSessionExist() bool
+	// ProcessID returns the PID of the program, the root of the process tree of the session.
+	ProcessID() (int, error)
+	// CapturePaneContent returns the visible content of the session.
+	CapturePaneContent() (string, error)
+	// CapturePaneContentWithOptions returns the content between the start and end lines, "-" meaning
+	// the start or end of the history.
+	CapturePaneContentWithOptions(start, end string) (string, error)
+	// HasUpdated returns true if content, just captured, changed since the last call, and whether the
+	// program shows a permission prompt in it.
+	HasUpdated(content string) (updated bool, hasPrompt bool)
+	// SendKeys types the keys into the session.
+	SendKeys(keys string) error
+	// TapEnter presses enter in the session.
+	TapEnter() error
+	// SetEnv sets environment variables for sessions started afterwards.
+	SetEnv(env map[string]string)
+}
+
+// makeTerminal returns the terminal session of the instance running program. Remote instances run in a
+// tmux session on their host.
+func (i *Instance) makeTerminal(program string) Terminal {
+	var terminal Terminal
+	if i.Host != "" {
+		terminal = tmux.NewRemoteTmuxSession(i.TerminalName(), program, i.Host)
+	} else {
+		terminal = newTerminal(i.TerminalName(), i.withHooks(program), i.gitWorktree.GetWorktreePath())
+	}
+	i.keepOnExit(terminal, program)
+	return terminal
+}
+
+// TerminalName returns the name of the terminal session of the instance. The sessions of instances created
+// before the names were stored are named after their title with the default prefix.
+func (i *Instance) TerminalName() string {
+	if i.terminalName == "" {
+		return tmux.SessionName(tmux.TmuxPrefix, i.Title)
+	}
+	return i.terminalName
+}
+
+// maxTerminalNameAttempts is how many numbered names are tried for the terminal session of a new
+// instance before giving up on finding a free one.
+const maxTerminalNameAttempts = 100
+
+// freeTerminalName returns the name of the terminal session of a new instance: its title with the
+// configured prefix, and a number appended if a session of that name exists already, e.g. one left behind
+// by a crash, or of an instance whose title only differs in whitespace or dots.
+func (i *Instance) freeTerminalName() string {
+	base := tmux.SessionName(config.LoadConfig().GetTmuxPrefix(), i.Title)
+	name := base
+	for n := 2; n <= maxTerminalNameAttempts; n++ {
+		var terminal Terminal
+		if i.Host != "" {
+			terminal = tmux.NewRemoteTmuxSession(name, "", i.Host)
+		} else {
+			terminal = newTerminal(name, "", "")
+		}
+		if !terminal.DoesSessionExist() {
+			break
+		}
+		log.WarningLog.Printf("terminal session %s exists already, trying another name for %s", name, i.Title)
+		name = fmt.Sprintf("%s_%d", base, n)
+	}
+	return name
+}
+
+// terminalPrefixes returns the prefixes of the names of the terminal sessions of instances: the
+// configured one, and the default one of the instances created before it was set. The sessions of the
+// default prefix belong to the default profile, so a profile only claims its own.
+func terminalPrefixes() []string {
+	prefix := config.LoadConfig().GetTmuxPrefix()
+	if prefix == tmux.TmuxPrefix || config.Profile() != "" {
+		return []string{prefix}
+	}
+	return []string{prefix, tmux.TmuxPrefix}
+}
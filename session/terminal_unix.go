@@ -0,0 +1,47 @@
+//go:build !windows
+
+package session
+
+import (
+	"claude-squad/cmd"
+	"claude-squad/session/tmux"
+	"context"
+	"os/exec"
+)
+
+// TerminalsPersist is true if terminal sessions keep running after claude-squad exits.
+const TerminalsPersist = true
+
+// newTerminal returns the terminal session of an instance. workDir is the worktree of the instance, if
+// known.
+func newTerminal(name string, program string, workDir string) Terminal {
+	return tmux.NewNamedTmuxSession(name, program)
+}
+
+// CleanupTerminals kills the terminal sessions of all instances.
+func CleanupTerminals() error {
+	return tmux.CleanupSessions(cmd.MakeExecutor(), terminalPrefixes()...)
+}
+
+// listTerminals returns the terminal sessions whose names start with one of the prefixes.
+func listTerminals(prefixes []string) ([]OrphanTerminal, error) {
+	sessions, err := tmux.ListSessions(cmd.MakeExecutor(), prefixes...)
+	if err != nil {
+		return nil, err
+	}
+	terminals := make([]OrphanTerminal, len(sessions))
+	for i, session := range sessions {
+		terminals[i] = OrphanTerminal{Name: session.Name, Path: session.Path}
+	}
+	return terminals, nil
+}
+
+// killTerminal kills the terminal session with the name.
+func killTerminal(name string) error {
+	return tmux.KillSession(cmd.MakeExecutor(), name)
+}
+
+// shellCommand returns the command that runs command with the shell.
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
@@ -0,0 +1,42 @@
+//go:build windows
+
+package session
+
+import (
+	"claude-squad/session/conpty"
+	"context"
+	"fmt"
+	"os/exec"
+)
+
+// TerminalsPersist is true if terminal sessions keep running after claude-squad exits. ConPTY sessions
+// end with the claude-squad process.
+const TerminalsPersist = false
+
+// newTerminal returns the terminal session of an instance. workDir is the worktree of the instance, if
+// known. ConPTY sessions live in the claude-squad process, so the workDir is used to restart the
+// program when an instance is restored after a restart.
+func newTerminal(name string, program string, workDir string) Terminal {
+	return conpty.NewSession(name, program, workDir)
+}
+
+// CleanupTerminals kills the terminal sessions of all instances.
+func CleanupTerminals() error {
+	return conpty.CloseAll()
+}
+
+// listTerminals returns the terminal sessions whose names start with one of the prefixes. ConPTY sessions
+// end with the claude-squad process, so none are left behind by another one.
+func listTerminals(prefixes []string) ([]OrphanTerminal, error) {
+	return nil, nil
+}
+
+// killTerminal kills the terminal session with the name.
+func killTerminal(name string) error {
+	return fmt.Errorf("console session %s isn't owned by this process", name)
+}
+
+// shellCommand returns the command that runs command with the shell.
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	return exec.CommandContext(ctx, "cmd", "/C", command)
+}
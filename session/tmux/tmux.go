@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"claude-squad/cmd"
 	"claude-squad/log"
+	"claude-squad/session/remote"
 	"context"
 	"crypto/sha256"
 	"errors"
@@ -12,6 +13,8 @@ import (
 	"os"
 	"os/exec"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -35,6 +38,12 @@ type TmuxSession struct {
 	ptyFactory PtyFactory
 	// cmdExec is used to execute commands in the tmux session.
 	cmdExec cmd.Executor
+	// env holds extra environment variables for the program, set by SetEnv.
+	env map[string]string
+	// host is the machine the session runs on over ssh, if it's remote.
+	host string
+	// keepOnExit keeps the pane of the program when it exits, set by KeepOnExit.
+	keepOnExit bool
 
 	// Initialized by Start or Restore
 	//
@@ -57,14 +66,20 @@ type TmuxSession struct {
 	wg     *sync.WaitGroup
 }
 
+// TmuxPrefix is the default prefix of the names of the tmux sessions of instances.
 const TmuxPrefix = "claudesquad_"
 
 var whiteSpaceRegex = regexp.MustCompile(`\s+`)
 
+// SessionName returns the name of the tmux session of an instance: its name with the prefix, without the
+// characters tmux doesn't allow.
+func SessionName(prefix string, name string) string {
+	str := whiteSpaceRegex.ReplaceAllString(prefix+name, "")
+	return strings.ReplaceAll(str, ".", "_") // tmux replaces all . with _
+}
+
 func toClaudeSquadTmuxName(str string) string {
-	str = whiteSpaceRegex.ReplaceAllString(str, "")
-	str = strings.ReplaceAll(str, ".", "_") // tmux replaces all . with _
-	return fmt.Sprintf("%s%s", TmuxPrefix, str)
+	return SessionName(TmuxPrefix, str)
 }
 
 // NewTmuxSession creates a new TmuxSession with the given name and program.
@@ -72,6 +87,22 @@ func NewTmuxSession(name string, program string) *TmuxSession {
 	return newTmuxSession(name, program, MakePtyFactory(), cmd.MakeExecutor())
 }
 
+// NewNamedTmuxSession creates a new TmuxSession for the tmux session with the name, as returned by
+// SessionName, and the program.
+func NewNamedTmuxSession(sessionName string, program string) *TmuxSession {
+	t := NewTmuxSession("", program)
+	t.sanitizedName = sessionName
+	return t
+}
+
+// NewRemoteTmuxSession creates a new TmuxSession for the tmux session with the name, as returned by
+// SessionName, and the program, running on host over ssh.
+func NewRemoteTmuxSession(sessionName string, program string, host string) *TmuxSession {
+	t := NewNamedTmuxSession(sessionName, program)
+	t.host = host
+	return t
+}
+
 func newTmuxSession(name string, program string, ptyFactory PtyFactory, cmdExec cmd.Executor) *TmuxSession {
 	return &TmuxSession{
 		sanitizedName: toClaudeSquadTmuxName(name),
@@ -89,21 +120,38 @@ func (t *TmuxSession) Start(workDir string) error {
 		return fmt.Errorf("tmux session already exists: %s", t.sanitizedName)
 	}
 
+	program := t.program
+	envFile := ""
+	if len(t.env) > 0 {
+		var err error
+		if envFile, err = t.writeEnvFile(); err != nil {
+			return err
+		}
+		quoted := remote.QuoteArg(envFile)
+		program = fmt.Sprintf(". %s && rm -f %s && %s", quoted, quoted, program)
+	}
+
 	// Create a new detached tmux session and start claude in it
+	args := []string{"new-session", "-d", "-s", t.sanitizedName, "-c", workDir}
 	// If the program contains spaces, we need to use shell to execute it properly
-	var cmd *exec.Cmd
-	if strings.Contains(t.program, " ") {
+	if strings.Contains(program, " ") {
 		// Use sh -c to handle commands with arguments
-		cmd = exec.Command("tmux", "new-session", "-d", "-s", t.sanitizedName, "-c", workDir, "sh", "-c", t.program)
+		args = append(args, "sh", "-c", program)
 	} else {
-		cmd = exec.Command("tmux", "new-session", "-d", "-s", t.sanitizedName, "-c", workDir, t.program)
+		args = append(args, program)
+	}
+	if t.keepOnExit {
+		// Set in the same command as the session is created, so a program exiting right away is kept too.
+		args = append(args, ";", "set-option", "-t", t.sanitizedName, "remain-on-exit", "on")
 	}
+	cmd := t.ttyCommand(args...)
 
 	ptmx, err := t.ptyFactory.Start(cmd)
 	if err != nil {
+		t.removeEnvFile(envFile)
 		// Cleanup any partially created session if any exists.
 		if t.DoesSessionExist() {
-			cleanupCmd := exec.Command("tmux", "kill-session", "-t", t.sanitizedName)
+			cleanupCmd := t.command("kill-session", "-t", t.sanitizedName)
 			if cleanupErr := t.cmdExec.Run(cleanupCmd); cleanupErr != nil {
 				err = fmt.Errorf("%v (cleanup error: %v)", err, cleanupErr)
 			}
@@ -117,6 +165,7 @@ func (t *TmuxSession) Start(workDir string) error {
 	for !t.DoesSessionExist() {
 		select {
 		case <-timeout:
+			t.removeEnvFile(envFile)
 			// Cleanup on window size update failure
 			if cleanupErr := t.Close(); cleanupErr != nil {
 				err = fmt.Errorf("%v (cleanup error: %v)", err, cleanupErr)
@@ -136,11 +185,11 @@ func (t *TmuxSession) Start(workDir string) error {
 		return fmt.Errorf("error restoring tmux session: %w", err)
 	}
 
-	if t.program == ProgramClaude || strings.HasPrefix(t.program, ProgramAider) || strings.HasPrefix(t.program, ProgramGemini) {
+	if isClaude(t.program) || strings.HasPrefix(t.program, ProgramAider) || strings.HasPrefix(t.program, ProgramGemini) {
 		searchString := "Do you trust the files in this folder?"
 		tapFunc := t.TapEnter
 		iterations := 5
-		if t.program != ProgramClaude {
+		if !isClaude(t.program) {
 			searchString = "Open documentation url for more info"
 			tapFunc = t.TapDAndEnter
 			iterations = 10 // Aider takes longer to start :/
@@ -163,9 +212,97 @@ func (t *TmuxSession) Start(workDir string) error {
 	return nil
 }
 
+// SetEnv sets environment variables for the program. It only applies to sessions started afterwards.
+func (t *TmuxSession) SetEnv(env map[string]string) {
+	t.env = env
+}
+
+// KeepOnExit keeps the pane of the program when it exits instead of closing the session, so ExitStatus can
+// tell how it exited. It only applies to sessions started afterwards.
+func (t *TmuxSession) KeepOnExit() {
+	t.keepOnExit = true
+}
+
+// ExitStatus returns true if the program of the session exited, with its exit status, or -1 if it was
+// killed by a signal. The session is only kept once its program exited if KeepOnExit was called before
+// it was started.
+func (t *TmuxSession) ExitStatus() (exited bool, status int, err error) {
+	cmd := t.command("display-message", "-p", "-t", t.sanitizedName, "#{pane_dead} #{pane_dead_status}")
+	output, err := t.cmdExec.Output(cmd)
+	if err != nil {
+		return false, 0, fmt.Errorf("error getting pane status: %v", err)
+	}
+	fields := strings.Fields(string(output))
+	if len(fields) == 0 || fields[0] != "1" {
+		return false, 0, nil
+	}
+	// The status is empty if the program was killed by a signal.
+	if len(fields) < 2 {
+		return true, -1, nil
+	}
+	if status, err = strconv.Atoi(fields[1]); err != nil {
+		return true, -1, nil
+	}
+	return true, status, nil
+}
+
+// writeEnvFile writes the environment variables of the program to a file only the user can read, on the
+// host of the session, for the program to source and remove as it starts. Their values, often secrets,
+// would be visible to other users in the arguments of tmux.
+func (t *TmuxSession) writeEnvFile() (string, error) {
+	var content strings.Builder
+	for _, key := range sortedKeys(t.env) {
+		fmt.Fprintf(&content, "export %s=%s\n", key, remote.QuoteArg(t.env[key]))
+	}
+	if t.host != "" {
+		cmd := remote.Script(t.host, `umask 077 && f=$(mktemp) && cat > "$f" && echo "$f"`)
+		cmd.Stdin = strings.NewReader(content.String())
+		output, err := t.cmdExec.Output(cmd)
+		if err != nil {
+			return "", fmt.Errorf("error writing the environment of the session on %s: %v", t.host, err)
+		}
+		return strings.TrimSpace(string(output)), nil
+	}
+	// The file is created with mode 0600.
+	f, err := os.CreateTemp("", "claude-squad-env-")
+	if err != nil {
+		return "", fmt.Errorf("error writing the environment of the session: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content.String()); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("error writing the environment of the session: %v", err)
+	}
+	return f.Name(), nil
+}
+
+// removeEnvFile removes the environment file of a program that didn't start, if there is one.
+func (t *TmuxSession) removeEnvFile(path string) {
+	if path == "" {
+		return
+	}
+	if t.host != "" {
+		err := t.cmdExec.Run(t.command("rm", "-f", path))
+		if err != nil {
+			log.WarningLog.Printf("could not remove the environment file %s on %s: %v", path, t.host, err)
+		}
+		return
+	}
+	os.Remove(path)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // Restore attaches to an existing session and restores the window size
 func (t *TmuxSession) Restore() error {
-	ptmx, err := t.ptyFactory.Start(exec.Command("tmux", "attach-session", "-t", t.sanitizedName))
+	ptmx, err := t.ptyFactory.Start(t.ttyCommand("attach-session", "-t", t.sanitizedName))
 	if err != nil {
 		return fmt.Errorf("error opening PTY: %w", err)
 	}
@@ -214,23 +351,10 @@ func (t *TmuxSession) SendKeys(keys string) error {
 	return err
 }
 
-// HasUpdated checks if the tmux pane content has changed since the last tick. It also returns true if
-// the tmux pane has a prompt for aider or claude code.
-func (t *TmuxSession) HasUpdated() (updated bool, hasPrompt bool) {
-	content, err := t.CapturePaneContent()
-	if err != nil {
-		log.ErrorLog.Printf("error capturing pane content in status monitor: %v", err)
-		return false, false
-	}
-
-	// Only set hasPrompt for claude and aider. Use these strings to check for a prompt.
-	if t.program == ProgramClaude {
-		hasPrompt = strings.Contains(content, "No, and tell Claude what to do differently")
-	} else if strings.HasPrefix(t.program, ProgramAider) {
-		hasPrompt = strings.Contains(content, "(Y)es/(N)o/(D)on't ask again")
-	} else if strings.HasPrefix(t.program, ProgramGemini) {
-		hasPrompt = strings.Contains(content, "Yes, allow once")
-	}
+// HasUpdated checks if the tmux pane content, captured on this tick, has changed since the last tick. It
+// also returns true if the tmux pane has a prompt for aider or claude code.
+func (t *TmuxSession) HasUpdated(content string) (updated bool, hasPrompt bool) {
+	hasPrompt = HasPrompt(t.program, content)
 
 	if !bytes.Equal(t.monitor.hash(content), t.monitor.prevOutputHash) {
 		t.monitor.prevOutputHash = t.monitor.hash(content)
@@ -316,6 +440,24 @@ func (t *TmuxSession) Attach() (chan struct{}, error) {
 	return t.attachCh, nil
 }
 
+// isClaude returns true if program runs claude, with or without arguments.
+func isClaude(program string) bool {
+	return program == ProgramClaude || strings.HasPrefix(program, ProgramClaude+" ")
+}
+
+// HasPrompt returns true if the pane content shows a permission prompt of the program. Only claude, aider
+// and gemini prompts are detected.
+func HasPrompt(program string, content string) bool {
+	if isClaude(program) {
+		return strings.Contains(content, "No, and tell Claude what to do differently")
+	} else if strings.HasPrefix(program, ProgramAider) {
+		return strings.Contains(content, "(Y)es/(N)o/(D)on't ask again")
+	} else if strings.HasPrefix(program, ProgramGemini) {
+		return strings.Contains(content, "Yes, allow once")
+	}
+	return false
+}
+
 // Detach disconnects from the current tmux session. It panics if detaching fails. At the moment, there's no
 // way to recover from a failed detach.
 func (t *TmuxSession) Detach() {
@@ -363,7 +505,7 @@ func (t *TmuxSession) Close() error {
 		t.ptmx = nil
 	}
 
-	cmd := exec.Command("tmux", "kill-session", "-t", t.sanitizedName)
+	cmd := t.command("kill-session", "-t", t.sanitizedName)
 	if err := t.cmdExec.Run(cmd); err != nil {
 		errs = append(errs, fmt.Errorf("error killing tmux session: %w", err))
 	}
@@ -398,16 +540,59 @@ func (t *TmuxSession) updateWindowSize(cols, rows int) error {
 	})
 }
 
+// AttachCommand returns the command line that attaches to the tmux session from another terminal.
+func (t *TmuxSession) AttachCommand() ([]string, error) {
+	// "=" makes tmux match the session name exactly instead of by prefix.
+	if t.host != "" {
+		return remote.TTYCommand(t.host, "tmux", "attach-session", "-t", "="+t.sanitizedName).Args, nil
+	}
+	return []string{"tmux", "attach-session", "-t", "=" + t.sanitizedName}, nil
+}
+
+// command returns the tmux command with args, run over ssh if the session is remote.
+func (t *TmuxSession) command(args ...string) *exec.Cmd {
+	if t.host != "" {
+		return remote.Command(t.host, "tmux", args...)
+	}
+	return exec.Command("tmux", args...)
+}
+
+// ttyCommand returns the tmux command with args for a PTY. Remote sessions need a terminal on the other
+// end of the ssh connection too.
+func (t *TmuxSession) ttyCommand(args ...string) *exec.Cmd {
+	if t.host != "" {
+		return remote.TTYCommand(t.host, "tmux", args...)
+	}
+	return exec.Command("tmux", args...)
+}
+
+// ProcessID returns the PID of the program running in the tmux pane.
+func (t *TmuxSession) ProcessID() (int, error) {
+	if t.host != "" {
+		return 0, fmt.Errorf("the program runs on %s", t.host)
+	}
+	cmd := exec.Command("tmux", "display-message", "-p", "-t", t.sanitizedName, "#{pane_pid}")
+	output, err := t.cmdExec.Output(cmd)
+	if err != nil {
+		return 0, fmt.Errorf("error getting pane pid: %v", err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(output)))
+	if err != nil {
+		return 0, fmt.Errorf("error parsing pane pid %q: %v", output, err)
+	}
+	return pid, nil
+}
+
 func (t *TmuxSession) DoesSessionExist() bool {
 	// Using "-t name" does a prefix match, which is wrong. `-t=` does an exact match.
-	existsCmd := exec.Command("tmux", "has-session", fmt.Sprintf("-t=%s", t.sanitizedName))
+	existsCmd := t.command("has-session", fmt.Sprintf("-t=%s", t.sanitizedName))
 	return t.cmdExec.Run(existsCmd) == nil
 }
 
 // CapturePaneContent captures the content of the tmux pane
 func (t *TmuxSession) CapturePaneContent() (string, error) {
 	// Add -e flag to preserve escape sequences (ANSI color codes)
-	cmd := exec.Command("tmux", "capture-pane", "-p", "-e", "-J", "-t", t.sanitizedName)
+	cmd := t.command("capture-pane", "-p", "-e", "-J", "-t", t.sanitizedName)
 	output, err := t.cmdExec.Output(cmd)
 	if err != nil {
 		return "", fmt.Errorf("error capturing pane content: %v", err)
@@ -419,7 +604,7 @@ func (t *TmuxSession) CapturePaneContent() (string, error) {
 // start and end specify the starting and ending line numbers (use "-" for the start/end of history)
 func (t *TmuxSession) CapturePaneContentWithOptions(start, end string) (string, error) {
 	// Add -e flag to preserve escape sequences (ANSI color codes)
-	cmd := exec.Command("tmux", "capture-pane", "-p", "-e", "-J", "-S", start, "-E", end, "-t", t.sanitizedName)
+	cmd := t.command("capture-pane", "-p", "-e", "-J", "-S", start, "-E", end, "-t", t.sanitizedName)
 	output, err := t.cmdExec.Output(cmd)
 	if err != nil {
 		return "", fmt.Errorf("failed to capture tmux pane content with options: %v", err)
@@ -427,31 +612,54 @@ func (t *TmuxSession) CapturePaneContentWithOptions(start, end string) (string,
 	return string(output), nil
 }
 
-// CleanupSessions kills all tmux sessions that start with "session-"
-func CleanupSessions(cmdExec cmd.Executor) error {
-	// First try to list sessions
-	cmd := exec.Command("tmux", "ls")
-	output, err := cmdExec.Output(cmd)
+// SessionInfo is a tmux session of an instance.
+type SessionInfo struct {
+	Name string
+	// Path is the directory the session was started in, the worktree of its instance.
+	Path string
+}
 
-	// If there's an error and it's because no server is running, that's fine
-	// Exit code 1 typically means no sessions exist
+// ListSessions returns the tmux sessions whose names start with one of the prefixes.
+func ListSessions(cmdExec cmd.Executor, prefixes ...string) ([]SessionInfo, error) {
+	output, err := cmdExec.Output(exec.Command("tmux", "list-sessions", "-F", "#{session_name}\t#{session_path}"))
 	if err != nil {
+		// Exit code 1 typically means no server is running, so no sessions exist.
 		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
-			return nil // No sessions to clean up
+			return nil, nil
 		}
-		return fmt.Errorf("failed to list tmux sessions: %v", err)
+		return nil, fmt.Errorf("failed to list tmux sessions: %v", err)
 	}
+	var sessions []SessionInfo
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		name, path, _ := strings.Cut(line, "\t")
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(name, prefix) {
+				sessions = append(sessions, SessionInfo{Name: name, Path: path})
+				break
+			}
+		}
+	}
+	return sessions, nil
+}
 
-	re := regexp.MustCompile(fmt.Sprintf(`%s.*:`, TmuxPrefix))
-	matches := re.FindAllString(string(output), -1)
-	for i, match := range matches {
-		matches[i] = match[:strings.Index(match, ":")]
+// KillSession kills the tmux session with the name.
+func KillSession(cmdExec cmd.Executor, name string) error {
+	if err := cmdExec.Run(exec.Command("tmux", "kill-session", "-t", name)); err != nil {
+		return fmt.Errorf("failed to kill tmux session %s: %v", name, err)
 	}
+	return nil
+}
 
-	for _, match := range matches {
-		log.InfoLog.Printf("cleaning up session: %s", match)
-		if err := cmdExec.Run(exec.Command("tmux", "kill-session", "-t", match)); err != nil {
-			return fmt.Errorf("failed to kill tmux session %s: %v", match, err)
+// CleanupSessions kills all tmux sessions whose names start with one of the prefixes.
+func CleanupSessions(cmdExec cmd.Executor, prefixes ...string) error {
+	sessions, err := ListSessions(cmdExec, prefixes...)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		log.InfoLog.Printf("cleaning up session: %s", session.Name)
+		if err := KillSession(cmdExec, session.Name); err != nil {
+			return err
 		}
 	}
 	return nil
@@ -2,6 +2,7 @@ package tmux
 
 import (
 	cmd2 "claude-squad/cmd"
+	"claude-squad/log"
 	"fmt"
 	"math/rand"
 	"os"
@@ -15,6 +16,15 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+func TestMain(m *testing.M) {
+	// Initialize the logger before any tests run
+	log.Initialize(false)
+	defer log.Close()
+
+	exitCode := m.Run()
+	os.Exit(exitCode)
+}
+
 type MockPtyFactory struct {
 	t *testing.T
 
@@ -86,3 +96,164 @@ func TestStartTmuxSession(t *testing.T) {
 	_, err = ptyFactory.files[1].Stat()
 	require.NoError(t, err)
 }
+
+func TestStartTmuxSessionWithEnv(t *testing.T) {
+	ptyFactory := NewMockPtyFactory(t)
+
+	created := false
+	cmdExec := cmd_test.MockCmdExec{
+		RunFunc: func(cmd *exec.Cmd) error {
+			if strings.Contains(cmd.String(), "has-session") && !created {
+				created = true
+				return fmt.Errorf("session already exists")
+			}
+			return nil
+		},
+		OutputFunc: func(cmd *exec.Cmd) ([]byte, error) {
+			return []byte("output"), nil
+		},
+	}
+
+	workdir := t.TempDir()
+	session := newTmuxSession("test-session", "aider --yes", ptyFactory, cmdExec)
+	session.SetEnv(map[string]string{"B_TOKEN": "it's secret", "A_KEY": "a"})
+
+	err := session.Start(workdir)
+	require.NoError(t, err)
+	// The values are passed in a file only the user can read, not in the arguments of tmux.
+	started := ptyFactory.cmds[0].Args
+	script := started[len(started)-1]
+	require.Equal(t, []string{"tmux", "new-session", "-d", "-s", "claudesquad_test-session", "-c", workdir, "sh", "-c"},
+		started[:len(started)-1])
+	envFile := strings.Fields(script)[1]
+	require.Equal(t, fmt.Sprintf(". %s && rm -f %s && aider --yes", envFile, envFile), script)
+	info, err := os.Stat(envFile)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	content, err := os.ReadFile(envFile)
+	require.NoError(t, err)
+	require.Equal(t, "export A_KEY=a\nexport B_TOKEN='it'\\''s secret'\n", string(content))
+
+	// The program gets the variables, and the file is gone once it started.
+	out, err := exec.Command("sh", "-c", strings.TrimSuffix(script, "aider --yes")+`echo "$B_TOKEN"`).Output()
+	require.NoError(t, err)
+	require.Equal(t, "it's secret\n", string(out))
+	_, err = os.Stat(envFile)
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestKeepOnExit(t *testing.T) {
+	ptyFactory := NewMockPtyFactory(t)
+
+	created := false
+	paneStatus := "0 \n"
+	cmdExec := cmd_test.MockCmdExec{
+		RunFunc: func(cmd *exec.Cmd) error {
+			if strings.Contains(cmd.String(), "has-session") && !created {
+				created = true
+				return fmt.Errorf("session already exists")
+			}
+			return nil
+		},
+		OutputFunc: func(cmd *exec.Cmd) ([]byte, error) {
+			if strings.Contains(cmd.String(), "display-message") {
+				return []byte(paneStatus), nil
+			}
+			return []byte("output"), nil
+		},
+	}
+
+	workdir := t.TempDir()
+	session := newTmuxSession("test-session", "./agent.sh", ptyFactory, cmdExec)
+	session.KeepOnExit()
+
+	require.NoError(t, session.Start(workdir))
+	require.Equal(t, fmt.Sprintf("tmux new-session -d -s claudesquad_test-session -c %s ./agent.sh ; set-option -t claudesquad_test-session remain-on-exit on", workdir),
+		cmd2.ToString(ptyFactory.cmds[0]))
+
+	exited, _, err := session.ExitStatus()
+	require.NoError(t, err)
+	require.False(t, exited)
+
+	paneStatus = "1 3\n"
+	exited, status, err := session.ExitStatus()
+	require.NoError(t, err)
+	require.True(t, exited)
+	require.Equal(t, 3, status)
+
+	// Killed by a signal.
+	paneStatus = "1 \n"
+	_, status, err = session.ExitStatus()
+	require.NoError(t, err)
+	require.Equal(t, -1, status)
+}
+
+func TestStartRemoteTmuxSession(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	ptyFactory := NewMockPtyFactory(t)
+
+	created := false
+	var ran []string
+	cmdExec := cmd_test.MockCmdExec{
+		RunFunc: func(cmd *exec.Cmd) error {
+			ran = append(ran, cmd2.ToString(cmd))
+			if strings.Contains(cmd.String(), "has-session") && !created {
+				created = true
+				return fmt.Errorf("session already exists")
+			}
+			return nil
+		},
+		OutputFunc: func(cmd *exec.Cmd) ([]byte, error) {
+			return []byte("output"), nil
+		},
+	}
+
+	session := newTmuxSession("test-session", "aider --yes", ptyFactory, cmdExec)
+	session.host = "dev@box"
+
+	require.NoError(t, session.Start("~/.claude-squad/worktrees/test"))
+	require.Equal(t, 2, len(ptyFactory.cmds))
+	require.True(t, strings.HasSuffix(cmd2.ToString(ptyFactory.cmds[0]),
+		"-tt dev@box -- tmux new-session -d -s claudesquad_test-session -c ~/.claude-squad/worktrees/test sh -c 'aider --yes'"))
+	require.True(t, strings.HasSuffix(cmd2.ToString(ptyFactory.cmds[1]),
+		"-tt dev@box -- tmux attach-session -t claudesquad_test-session"))
+	require.True(t, strings.HasPrefix(ran[0], "ssh "))
+	require.True(t, strings.HasSuffix(ran[0], "dev@box -- tmux has-session -t=claudesquad_test-session"))
+
+	attach, err := session.AttachCommand()
+	require.NoError(t, err)
+	require.Equal(t, "ssh", attach[0])
+	require.Equal(t, []string{"-tt", "dev@box", "--", "tmux attach-session -t =claudesquad_test-session"}, attach[len(attach)-4:])
+
+	_, err = session.ProcessID()
+	require.Error(t, err)
+}
+
+func TestSessionName(t *testing.T) {
+	require.Equal(t, "cs-work_fixlogin_v2", SessionName("cs-work_", "fix login.v2"))
+	require.Equal(t, toClaudeSquadTmuxName("fix login"), SessionName(TmuxPrefix, "fix login"))
+	require.Equal(t, "cs_asdf", NewNamedTmuxSession("cs_asdf", "program").sanitizedName)
+}
+
+func TestListSessions(t *testing.T) {
+	var killed []string
+	cmdExec := cmd_test.MockCmdExec{
+		RunFunc: func(cmd *exec.Cmd) error {
+			killed = append(killed, cmd2.ToString(cmd))
+			return nil
+		},
+		OutputFunc: func(cmd *exec.Cmd) ([]byte, error) {
+			return []byte("claudesquad_fix\t/home/me/.claude-squad/worktrees/fix\nwork\t/home/me\ncs_docs\t/tmp/docs\n"), nil
+		},
+	}
+
+	sessions, err := ListSessions(cmdExec, "cs_", TmuxPrefix)
+	require.NoError(t, err)
+	require.Equal(t, []SessionInfo{
+		{Name: "claudesquad_fix", Path: "/home/me/.claude-squad/worktrees/fix"},
+		{Name: "cs_docs", Path: "/tmp/docs"},
+	}, sessions)
+
+	require.NoError(t, CleanupSessions(cmdExec, TmuxPrefix))
+	require.Equal(t, []string{"tmux kill-session -t claudesquad_fix"}, killed)
+}
@@ -0,0 +1,40 @@
+package session
+
+import (
+	"claude-squad/tracing"
+	"context"
+)
+
+// startSpan starts a span of an operation of the instance, with the attributes telling which instance it
+// is.
+func (i *Instance) startSpan(ctx context.Context, name string, attrs ...tracing.Attribute) (context.Context, *tracing.Span) {
+	return tracing.Start(ctx, name, append([]tracing.Attribute{
+		tracing.String("instance.title", i.Title),
+		tracing.String("instance.program", i.Program),
+		tracing.String("instance.branch", i.Branch),
+		tracing.String("instance.host", i.Host),
+	}, attrs...)...)
+}
+
+// setupWorktree sets up the worktree of the instance in a span. The instance is Loading meanwhile, with the
+// progress of the slow steps, like checking out submodules, in SetupProgress.
+func (i *Instance) setupWorktree(ctx context.Context) error {
+	_, span := tracing.Start(ctx, "worktree.setup", tracing.String("worktree.path", i.gitWorktree.GetWorktreePath()))
+	status := i.Status
+	i.Status = Loading
+	i.gitWorktree.SetProgress(i.setSetupProgress)
+	err := i.gitWorktree.Setup()
+	i.gitWorktree.SetProgress(nil)
+	i.setSetupProgress("")
+	i.Status = status
+	span.End(err)
+	return err
+}
+
+// startTerminal starts the terminal session of the instance in its worktree in a span.
+func (i *Instance) startTerminal(ctx context.Context) error {
+	_, span := tracing.Start(ctx, "tmux.start")
+	err := i.tmuxSession.Start(i.gitWorktree.GetWorktreePath())
+	span.End(err)
+	return err
+}
@@ -0,0 +1,84 @@
+package session
+
+import (
+	"claude-squad/session/git"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ImportFile writes data to a file named name in the scratch directory of the instance, for its agent to
+// read, e.g. the clipboard. An existing file isn't overwritten: a number is added to the name instead. It
+// returns the path of the file relative to the worktree.
+func (i *Instance) ImportFile(name string, data []byte) (string, error) {
+	dir, err := i.scratchDir()
+	if err != nil {
+		return "", err
+	}
+	name = filepath.Base(strings.TrimSpace(name))
+	if name == "." || name == ".." || name == string(filepath.Separator) {
+		return "", fmt.Errorf("invalid file name %q", name)
+	}
+	path := uniquePath(filepath.Join(dir, name))
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return filepath.ToSlash(filepath.Join(git.ScratchDirName, filepath.Base(path))), nil
+}
+
+// CopyIntoWorktree copies the local file at path into the scratch directory of the instance, e.g. a log or
+// a spec, and returns its path relative to the worktree. The path can be dropped from a file manager:
+// quotes and escaped spaces are removed, and a leading "~/" is the home directory.
+func (i *Instance) CopyIntoWorktree(path string) (string, error) {
+	path = strings.Trim(strings.TrimSpace(path), `'"`)
+	path = strings.ReplaceAll(path, `\ `, " ")
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get the home directory: %w", err)
+		}
+		path = filepath.Join(home, rest)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("%s is a directory, only files can be copied", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return i.ImportFile(filepath.Base(path), data)
+}
+
+// scratchDir returns the scratch directory of the worktree of the instance, creating it if the worktree
+// was set up before it had one.
+func (i *Instance) scratchDir() (string, error) {
+	if !i.started || i.Paused() {
+		return "", fmt.Errorf("instance '%s' isn't running", i.Title)
+	}
+	if i.Host != "" {
+		return "", fmt.Errorf("files can't be copied into remote instance '%s'", i.Title)
+	}
+	dir := i.gitWorktree.ScratchDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create the scratch directory: %w", err)
+	}
+	return dir, nil
+}
+
+// uniquePath returns path, or path with a number added before its extension if it exists, e.g.
+// "build-2.log".
+func uniquePath(path string) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for n := 2; ; n++ {
+		if _, err := os.Lstat(path); os.IsNotExist(err) {
+			return path
+		}
+		path = fmt.Sprintf("%s-%d%s", base, n, ext)
+	}
+}
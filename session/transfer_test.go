@@ -0,0 +1,47 @@
+package session
+
+import (
+	"claude-squad/session/git"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportFile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	worktreePath := t.TempDir()
+	instance := &Instance{Title: "fix", Status: Running, started: true,
+		gitWorktree: git.NewGitWorktreeFromStorage("", worktreePath, "fix", "fix", "", false)}
+
+	path, err := instance.ImportFile("../stack trace.txt", []byte("panic: nil map"))
+	require.NoError(t, err)
+	assert.Equal(t, git.ScratchDirName+"/stack trace.txt", path)
+	content, err := os.ReadFile(filepath.Join(worktreePath, path))
+	require.NoError(t, err)
+	assert.Equal(t, "panic: nil map", string(content))
+
+	// Existing files are kept.
+	path, err = instance.ImportFile("stack trace.txt", []byte("again"))
+	require.NoError(t, err)
+	assert.Equal(t, git.ScratchDirName+"/stack trace-2.txt", path)
+
+	// A path dropped onto the terminal is quoted or has its spaces escaped.
+	require.NoError(t, os.WriteFile(filepath.Join(home, "my spec.md"), []byte("# Spec"), 0644))
+	path, err = instance.CopyIntoWorktree(`'` + filepath.Join(home, "my spec.md") + `'`)
+	require.NoError(t, err)
+	assert.Equal(t, git.ScratchDirName+"/my spec.md", path)
+	path, err = instance.CopyIntoWorktree(`~/my\ spec.md`)
+	require.NoError(t, err)
+	assert.Equal(t, git.ScratchDirName+"/my spec-2.md", path)
+
+	_, err = instance.CopyIntoWorktree(home)
+	assert.ErrorContains(t, err, "is a directory")
+
+	instance.Status = Paused
+	_, err = instance.ImportFile("notes.txt", nil)
+	assert.ErrorContains(t, err, "isn't running")
+}
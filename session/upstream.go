@@ -0,0 +1,100 @@
+package session
+
+import (
+	"claude-squad/session/git"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// UpstreamStatus is how far the branch of an instance is from the upstream base branch, e.g.
+// origin/main, as of the last fetch.
+type UpstreamStatus struct {
+	// Branch is the upstream base branch.
+	Branch string
+	// Ahead is the number of commits of the instance's branch the upstream base branch doesn't contain.
+	Ahead int
+	// Behind is the number of commits of the upstream base branch the instance's branch isn't based on.
+	Behind int
+}
+
+// Drifted returns true if the upstream base branch moved on by at least threshold commits, so the
+// instance needs a rebase. A threshold of 0 never flags it.
+func (s *UpstreamStatus) Drifted(threshold int) bool {
+	return s != nil && threshold > 0 && s.Behind >= threshold
+}
+
+// String returns e.g. "2 ahead, 14 behind origin/main".
+func (s *UpstreamStatus) String() string {
+	return fmt.Sprintf("%d ahead, %d behind %s", s.Ahead, s.Behind, s.Branch)
+}
+
+// Upstream returns how far the branch of the instance is from the upstream base branch, as of the last
+// SetUpstream, or nil if it's unknown.
+func (i *Instance) Upstream() *UpstreamStatus {
+	return i.upstream
+}
+
+// SetUpstream records the result of CheckUpstream.
+func (i *Instance) SetUpstream(status *UpstreamStatus) {
+	i.upstream = status
+}
+
+// CheckUpstream compares the branch of the instance with the upstream base branch, fetching origin first
+// if fetch is true. It's safe to run in the background, as it doesn't change the instance.
+func (i *Instance) CheckUpstream(fetch bool) (*UpstreamStatus, error) {
+	if !i.started {
+		return nil, fmt.Errorf("instance '%s' has not been started", i.Title)
+	}
+	if fetch {
+		if err := i.gitWorktree.FetchOrigin(); err != nil {
+			return nil, err
+		}
+	}
+	branch, err := i.gitWorktree.UpstreamBaseBranch()
+	if err != nil {
+		return nil, err
+	}
+	ahead, behind, err := i.gitWorktree.AheadBehind(branch)
+	if err != nil {
+		return nil, err
+	}
+	return &UpstreamStatus{Branch: branch, Ahead: ahead, Behind: behind}, nil
+}
+
+// RebaseOnUpstream rebases the branch of the paused instance on the upstream base branch, as of the last
+// fetch, so it doesn't rot while the instance is paused. It returns true if the branch
+// was rebased. If the rebase conflicts, it's aborted and the conflicts are recorded as an error of the
+// instance, to be resolved once it's resumed. Remote and stacked instances are left alone, as are
+// branches checked out elsewhere and queued instances whose branch wasn't created yet.
+func (i *Instance) RebaseOnUpstream() (bool, error) {
+	if !i.started || !i.Paused() || i.Host != "" || i.ParentBranch != "" || i.gitWorktree.GetBaseCommitSHA() == "" {
+		return false, nil
+	}
+	if checked, err := i.gitWorktree.IsBranchCheckedOut(); err != nil || checked {
+		return false, err
+	}
+	status, err := i.CheckUpstream(false)
+	if err != nil {
+		return false, err
+	}
+	i.upstream = status
+	if status.Behind == 0 {
+		return false, nil
+	}
+
+	err = i.gitWorktree.RebaseBranch(status.Branch)
+	var conflictErr *git.ConflictError
+	if errors.As(err, &conflictErr) {
+		i.RecordError(ErrorOpRebase, fmt.Errorf("rebasing on %s conflicts in %s, resume it to resolve them",
+			status.Branch, strings.Join(conflictErr.Files, ", ")))
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	i.ResolveErrors(ErrorOpRebase)
+	i.upstream = &UpstreamStatus{Branch: status.Branch, Ahead: status.Ahead}
+	i.Audit(AuditRebase, "on "+status.Branch)
+	return true, nil
+}
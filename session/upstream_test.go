@@ -0,0 +1,22 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRebaseOnUpstreamSkipsInstances(t *testing.T) {
+	for name, instance := range map[string]*Instance{
+		"running": {Title: "running", Status: Running, started: true},
+		"remote":  {Title: "remote", Status: Paused, started: true, Host: "devbox"},
+		"stacked": {Title: "stacked", Status: Paused, started: true, ParentBranch: "me/base"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			rebased, err := instance.RebaseOnUpstream()
+			require.NoError(t, err)
+			assert.False(t, rebased)
+		})
+	}
+}
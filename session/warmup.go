@@ -0,0 +1,114 @@
+package session
+
+import (
+	"claude-squad/config"
+	"claude-squad/log"
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// warmupTimeout is how long the warmup commands of an instance may run in total.
+const warmupTimeout = 15 * time.Minute
+
+// warmupWaitDelay is how long the output of a cancelled warmup command is waited for, in case the
+// processes it started keep it open.
+const warmupWaitDelay = 5 * time.Second
+
+// Warmup states, as shown in the instance list.
+const (
+	WarmupNone    = ""
+	WarmupRunning = "running"
+	WarmupReady   = "ready"
+	WarmupFailed  = "failed"
+)
+
+// warmup tracks the warmup commands running in the background for the worktree of an instance.
+type warmup struct {
+	mu     sync.Mutex
+	state  string
+	cancel context.CancelFunc
+}
+
+func (w *warmup) setState(state string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.state = state
+}
+
+// startWarmup runs the warmup commands of the repository in the new worktree of the instance in the
+// background, e.g. to build the caches of the language servers so the tooling is hot when the worktree
+// is opened in an editor. They get the same environment as the teardown commands.
+func (i *Instance) startWarmup() {
+	if i.Host != "" || i.gitWorktree == nil {
+		return
+	}
+	repoConfig, err := config.LoadRepoConfig(i.gitWorktree.GetRepoPath())
+	if err != nil {
+		log.WarningLog.Printf("could not load the warmup commands of %s: %v", i.Title, err)
+		return
+	}
+	if repoConfig.Untrusted {
+		log.WarningLog.Printf("not running the commands in the %s of %s: run `cs trust` in the repository to trust them",
+			config.RepoConfigFileName, i.Title)
+	}
+	if len(repoConfig.WarmupCommands) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), warmupTimeout)
+	w := &warmup{state: WarmupRunning, cancel: cancel}
+	i.warmup = w
+	dir := i.gitWorktree.GetWorktreePath()
+	env := os.Environ()
+	for key, value := range i.commandEnv() {
+		env = append(env, key+"="+value)
+	}
+	go func() {
+		defer cancel()
+		start := time.Now()
+		for _, command := range repoConfig.WarmupCommands {
+			cmd := shellCommand(ctx, command)
+			cmd.Dir = dir
+			cmd.Env = env
+			cmd.WaitDelay = warmupWaitDelay
+			output, err := cmd.CombinedOutput()
+			if ctx.Err() == context.Canceled {
+				// The instance was paused or killed.
+				return
+			}
+			if err != nil {
+				log.WarningLog.Printf("warmup command %q of %s failed: %v\n%s", command, i.Title, err,
+					tail(RedactSecrets(string(output), i.secrets), maxCheckOutput))
+				w.setState(WarmupFailed)
+				i.Audit(AuditWarmup, fmt.Sprintf("failed: %s", command))
+				return
+			}
+		}
+		w.setState(WarmupReady)
+		i.Audit(AuditWarmup, fmt.Sprintf("ready in %s", time.Since(start).Round(time.Second)))
+	}()
+}
+
+// stopWarmup stops the warmup commands of the instance, if they're running.
+func (i *Instance) stopWarmup() {
+	if i.warmup == nil {
+		return
+	}
+	i.warmup.cancel()
+	i.warmup = nil
+}
+
+// WarmupState returns the state of the warmup commands of the worktree of the instance, one of the
+// Warmup constants. It's WarmupNone if the repository has none, or the worktree wasn't created since the
+// app started.
+func (i *Instance) WarmupState() string {
+	if i.warmup == nil {
+		return WarmupNone
+	}
+	i.warmup.mu.Lock()
+	defer i.warmup.mu.Unlock()
+	return i.warmup.state
+}
@@ -0,0 +1,68 @@
+package session
+
+import (
+	"claude-squad/config"
+	"claude-squad/session/git"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWarmup(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the warmup commands use sh")
+	}
+	t.Setenv("HOME", t.TempDir())
+	repoPath := t.TempDir()
+	output, err := exec.Command("git", "-C", repoPath, "init").CombinedOutput()
+	require.NoError(t, err, string(output))
+	instance := &Instance{
+		Title:       "fix-login",
+		started:     true,
+		gitWorktree: git.NewGitWorktreeFromStorage(repoPath, repoPath, "fix-login", "fix-login", "", false),
+	}
+
+	// Without warmup commands, nothing runs.
+	instance.startWarmup()
+	assert.Equal(t, WarmupNone, instance.WarmupState())
+
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, config.RepoConfigFileName),
+		[]byte(`{"warmup_commands": ["echo $CS_TITLE > warm", "test -f warm"]}`), 0644))
+	// Nothing runs until the commands are trusted.
+	instance.startWarmup()
+	assert.Equal(t, WarmupNone, instance.WarmupState())
+	trustRepoConfig(t, repoPath)
+	instance.startWarmup()
+	require.Eventually(t, func() bool { return instance.WarmupState() == WarmupReady }, 5*time.Second, 10*time.Millisecond)
+	data, err := os.ReadFile(filepath.Join(repoPath, "warm"))
+	require.NoError(t, err)
+	assert.Equal(t, "fix-login\n", string(data))
+
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, config.RepoConfigFileName),
+		[]byte(`{"warmup_commands": ["exit 3", "touch never"]}`), 0644))
+	trustRepoConfig(t, repoPath)
+	instance.startWarmup()
+	require.Eventually(t, func() bool { return instance.WarmupState() == WarmupFailed }, 5*time.Second, 10*time.Millisecond)
+	assert.NoFileExists(t, filepath.Join(repoPath, "never"))
+
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, config.RepoConfigFileName),
+		[]byte(`{"warmup_commands": ["sleep 30"]}`), 0644))
+	trustRepoConfig(t, repoPath)
+	instance.startWarmup()
+	assert.Equal(t, WarmupRunning, instance.WarmupState())
+	instance.stopWarmup()
+	assert.Equal(t, WarmupNone, instance.WarmupState())
+}
+
+// trustRepoConfig trusts the commands of the settings file of the repository, as `cs trust` does.
+func trustRepoConfig(t *testing.T, repoPath string) {
+	trusted, err := config.TrustRepoConfig(repoPath, func(*config.RepoConfig) bool { return true })
+	require.NoError(t, err)
+	require.True(t, trusted)
+}
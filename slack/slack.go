@@ -0,0 +1,174 @@
+// Package slack bridges the squad to Slack: it receives /squad commands over socket mode and posts
+// messages about the instances to a channel.
+package slack
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// requestTimeout bounds the calls to the Slack API.
+	requestTimeout = 10 * time.Second
+	// reconnectDelay is how long Listen waits before opening a new connection after losing one.
+	reconnectDelay = 5 * time.Second
+)
+
+// apiURL is the base URL of the Slack Web API. It's a variable so tests can point it to a fake server.
+var apiURL = "https://slack.com/api/"
+
+// Client calls the Slack API with the tokens of a Slack app.
+type Client struct {
+	// appToken is the app-level token ("xapp-...") socket mode connections are opened with.
+	appToken string
+	// botToken is the bot token ("xoxb-...") messages are posted with.
+	botToken string
+	http     *http.Client
+}
+
+// NewClient returns a client for the Slack app with the tokens.
+func NewClient(appToken string, botToken string) *Client {
+	return &Client{appToken: appToken, botToken: botToken, http: &http.Client{Timeout: requestTimeout}}
+}
+
+// Command is a /squad command, e.g. `/squad new repo=web prompt="fix the login page"`.
+type Command struct {
+	// Name is the first word of the command, e.g. "new".
+	Name string
+	// Args are the key=value arguments.
+	Args map[string]string
+	// Positional are the other arguments, in order.
+	Positional []string
+	// User is the ID of the Slack user who sent the command.
+	User string
+	// ResponseURL is where the response to the command is posted.
+	ResponseURL string
+}
+
+// ParseCommand parses the text of a /squad command. Values with spaces are quoted with double quotes.
+func ParseCommand(text string) (Command, error) {
+	words, err := splitWords(text)
+	if err != nil {
+		return Command{}, err
+	}
+	if len(words) == 0 {
+		return Command{}, fmt.Errorf("missing command")
+	}
+	cmd := Command{Name: strings.ToLower(words[0]), Args: make(map[string]string)}
+	for _, word := range words[1:] {
+		if key, value, ok := strings.Cut(word, "="); ok && key != "" && !strings.ContainsAny(key, " \"") {
+			cmd.Args[strings.ToLower(key)] = value
+			continue
+		}
+		cmd.Positional = append(cmd.Positional, word)
+	}
+	return cmd, nil
+}
+
+// splitWords splits text on spaces, keeping the text between double quotes, which Slack may have turned
+// into smart quotes, together.
+func splitWords(text string) ([]string, error) {
+	text = strings.NewReplacer("“", `"`, "”", `"`).Replace(text)
+	var words []string
+	var word strings.Builder
+	inWord, quoted := false, false
+	for _, r := range text {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			inWord = true
+		case (r == ' ' || r == '\t' || r == '\n') && !quoted:
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// PostMessage posts the text to the channel, in the thread of the message with the timestamp threadTS if
+// it's set, and returns the timestamp of the new message.
+func (c *Client) PostMessage(channel string, threadTS string, text string) (string, error) {
+	body := map[string]string{"channel": channel, "text": text}
+	if threadTS != "" {
+		body["thread_ts"] = threadTS
+	}
+	var result struct {
+		TS string `json:"ts"`
+	}
+	if err := c.call("chat.postMessage", c.botToken, body, &result); err != nil {
+		return "", err
+	}
+	return result.TS, nil
+}
+
+// Respond posts the response to a command, visible to the channel it was sent in.
+func (c *Client) Respond(responseURL string, text string) error {
+	data, err := json.Marshal(map[string]string{"response_type": "in_channel", "text": text})
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Post(responseURL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to respond to slack command: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to respond to slack command: %s", resp.Status)
+	}
+	return nil
+}
+
+// call calls the API method with the token and decodes its result.
+func (c *Client) call(method string, token string, body any, result any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, apiURL+method, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call slack %s: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	data, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read slack %s response: %w", method, err)
+	}
+	var status struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &status); err != nil {
+		return fmt.Errorf("failed to parse slack %s response (%s): %w", method, resp.Status, err)
+	}
+	if !status.OK {
+		return fmt.Errorf("slack %s failed: %s", method, status.Error)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(data, result)
+}
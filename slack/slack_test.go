@@ -0,0 +1,149 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+func TestParseCommand(t *testing.T) {
+	cmd, err := ParseCommand(`new repo=web prompt="fix the login page" extra`)
+	require.NoError(t, err)
+	assert.Equal(t, "new", cmd.Name)
+	assert.Equal(t, map[string]string{"repo": "web", "prompt": "fix the login page"}, cmd.Args)
+	assert.Equal(t, []string{"extra"}, cmd.Positional)
+
+	// Slack turns quotes into smart quotes.
+	cmd, err = ParseCommand("new prompt=“add dark mode”")
+	require.NoError(t, err)
+	assert.Equal(t, "add dark mode", cmd.Args["prompt"])
+
+	cmd, err = ParseCommand("diff fix-login")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"fix-login"}, cmd.Positional)
+
+	_, err = ParseCommand(`new prompt="oops`)
+	assert.Error(t, err)
+	_, err = ParseCommand("  ")
+	assert.Error(t, err)
+}
+
+// fakeSlack serves the Web API methods the client calls and a socket mode connection, which sends the
+// envelopes and records the acks.
+type fakeSlack struct {
+	server    *httptest.Server
+	envelopes []string
+	acks      chan map[string]any
+	posted    chan map[string]string
+}
+
+func newFakeSlack(t *testing.T, envelopes ...string) *fakeSlack {
+	f := &fakeSlack{envelopes: envelopes, acks: make(chan map[string]any, 10), posted: make(chan map[string]string, 10)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/apps.connections.open", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer xapp-token", r.Header.Get("Authorization"))
+		wsURL := "ws" + strings.TrimPrefix(f.server.URL, "http") + "/socket"
+		_ = json.NewEncoder(w).Encode(map[string]any{"ok": true, "url": wsURL})
+	})
+	mux.HandleFunc("/api/chat.postMessage", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer xoxb-token", r.Header.Get("Authorization"))
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		f.posted <- body
+		if body["channel"] == "missing" {
+			_ = json.NewEncoder(w).Encode(map[string]any{"ok": false, "error": "channel_not_found"})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"ok": true, "ts": "1700000000.000100"})
+	})
+	mux.Handle("/socket", websocket.Handler(func(conn *websocket.Conn) {
+		for _, e := range f.envelopes {
+			if _, err := conn.Write([]byte(e)); err != nil {
+				return
+			}
+			if !strings.Contains(e, "envelope_id") {
+				continue
+			}
+			var ack map[string]any
+			if err := websocket.JSON.Receive(conn, &ack); err != nil {
+				return
+			}
+			f.acks <- ack
+		}
+		// Keep the connection open until the client closes it.
+		var discard []byte
+		_ = websocket.Message.Receive(conn, &discard)
+	}))
+	f.server = httptest.NewServer(mux)
+	t.Cleanup(f.server.Close)
+
+	original := apiURL
+	apiURL = f.server.URL + "/api/"
+	t.Cleanup(func() { apiURL = original })
+	return f
+}
+
+func TestPostMessage(t *testing.T) {
+	f := newFakeSlack(t)
+	client := NewClient("xapp-token", "xoxb-token")
+
+	ts, err := client.PostMessage("C123", "", "`fix-login` is ready")
+	require.NoError(t, err)
+	assert.Equal(t, "1700000000.000100", ts)
+	assert.Equal(t, map[string]string{"channel": "C123", "text": "`fix-login` is ready"}, <-f.posted)
+
+	_, err = client.PostMessage("C123", ts, "failed its checks")
+	require.NoError(t, err)
+	assert.Equal(t, ts, (<-f.posted)["thread_ts"])
+
+	_, err = client.PostMessage("missing", "", "hi")
+	assert.ErrorContains(t, err, "channel_not_found")
+}
+
+func TestListen(t *testing.T) {
+	f := newFakeSlack(t,
+		`{"type": "hello"}`,
+		`{"type": "slash_commands", "envelope_id": "e1", "payload": {"command": "/squad", "text": "new prompt=\"fix it\"", "user_id": "U1", "response_url": "https://hooks.slack.com/r1"}}`,
+		`{"type": "slash_commands", "envelope_id": "e2", "payload": {"command": "/squad", "text": "new prompt=\"oops", "user_id": "U1"}}`,
+	)
+	client := NewClient("xapp-token", "xoxb-token")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	commands := make(chan Command)
+	done := make(chan struct{})
+	go func() {
+		client.Listen(ctx, commands)
+		close(done)
+	}()
+
+	select {
+	case cmd := <-commands:
+		assert.Equal(t, "new", cmd.Name)
+		assert.Equal(t, "fix it", cmd.Args["prompt"])
+		assert.Equal(t, "U1", cmd.User)
+		assert.Equal(t, "https://hooks.slack.com/r1", cmd.ResponseURL)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no command received")
+	}
+	assert.Equal(t, map[string]any{"envelope_id": "e1"}, <-f.acks)
+
+	// A command that can't be parsed is answered in the ack.
+	ack := <-f.acks
+	assert.Equal(t, "e2", ack["envelope_id"])
+	assert.Contains(t, ack["payload"], "text")
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Listen didn't stop")
+	}
+}
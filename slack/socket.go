@@ -0,0 +1,104 @@
+package slack
+
+import (
+	"claude-squad/log"
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// envelope is a message of a socket mode connection.
+type envelope struct {
+	Type       string `json:"type"`
+	EnvelopeID string `json:"envelope_id"`
+	Payload    struct {
+		Command     string `json:"command"`
+		Text        string `json:"text"`
+		UserID      string `json:"user_id"`
+		ResponseURL string `json:"response_url"`
+	} `json:"payload"`
+}
+
+// ack acknowledges an envelope, which Slack otherwise sends again.
+type ack struct {
+	EnvelopeID string `json:"envelope_id"`
+	Payload    any    `json:"payload,omitempty"`
+}
+
+// Listen receives the /squad commands over socket mode and sends them to commands until ctx is done.
+// Commands that can't be parsed are answered with the error directly. Lost connections are opened again.
+func (c *Client) Listen(ctx context.Context, commands chan<- Command) {
+	for {
+		err := c.listenOnce(ctx, commands)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.WarningLog.Printf("slack connection lost, reconnecting in %s: %v", reconnectDelay, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
+
+// listenOnce receives commands over one socket mode connection, until Slack asks to reconnect or the
+// connection is lost.
+func (c *Client) listenOnce(ctx context.Context, commands chan<- Command) error {
+	var opened struct {
+		URL string `json:"url"`
+	}
+	if err := c.call("apps.connections.open", c.appToken, struct{}{}, &opened); err != nil {
+		return err
+	}
+	origin := "https://slack.com/"
+	if u, err := url.Parse(apiURL); err == nil {
+		origin = u.Scheme + "://" + u.Host + "/"
+	}
+	conn, err := websocket.Dial(opened.URL, "", origin)
+	if err != nil {
+		return fmt.Errorf("failed to connect to slack: %w", err)
+	}
+	defer conn.Close()
+	// Receive blocks, so close the connection to stop it.
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	for {
+		var e envelope
+		if err := websocket.JSON.Receive(conn, &e); err != nil {
+			return err
+		}
+		switch e.Type {
+		case "disconnect":
+			return nil
+		case "slash_commands":
+		default:
+			continue
+		}
+
+		cmd, parseErr := ParseCommand(e.Payload.Text)
+		response := ack{EnvelopeID: e.EnvelopeID}
+		if parseErr != nil {
+			response.Payload = map[string]string{"text": fmt.Sprintf("%s: %v", e.Payload.Command, parseErr)}
+		}
+		if err := websocket.JSON.Send(conn, response); err != nil {
+			return err
+		}
+		if parseErr != nil {
+			continue
+		}
+		cmd.User = e.Payload.UserID
+		cmd.ResponseURL = e.Payload.ResponseURL
+		select {
+		case commands <- cmd:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
@@ -0,0 +1,282 @@
+// Package snapshot shares the diff and summary of an instance read-only, so a colleague can look at what
+// the agent did without pulling its branch: as a self-contained HTML page, or as a secret gist. Snapshots
+// expire, and the expired ones are deleted.
+package snapshot
+
+import (
+	"bytes"
+	"claude-squad/config"
+	"claude-squad/log"
+	"claude-squad/report"
+	"claude-squad/session"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+//go:embed snapshot.html
+var pageTemplate string
+
+var page = template.Must(template.New("snapshot").Funcs(template.FuncMap{
+	"lineClass": lineClass,
+	"lines":     func(s string) []string { return strings.Split(strings.TrimRight(s, "\n"), "\n") },
+}).Parse(pageTemplate))
+
+const indexFileName = "index.json"
+
+// runCommand runs a command line tool with stdin as its input and returns its output. It's replaced in
+// tests.
+var runCommand = func(stdin []byte, name string, args ...string) ([]byte, error) {
+	if _, err := exec.LookPath(name); err != nil {
+		return nil, fmt.Errorf("%s is not on PATH: it's needed to publish gists", name)
+	}
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s %s failed: %s (%w)", name, strings.Join(args, " "), strings.TrimSpace(stderr.String()), err)
+	}
+	return output, nil
+}
+
+// Snapshot is the diff and summary of an instance at a point in time.
+type Snapshot struct {
+	Summary   report.Summary
+	Diff      string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// Of takes a snapshot of the instance that expires after ttl, with the patterns redacted.
+func Of(instance *session.Instance, patterns []*regexp.Regexp, now time.Time, ttl time.Duration) Snapshot {
+	summary := report.SummaryOf(instance)
+	summary.LastMessage = report.Redact(summary.LastMessage, patterns)
+	var diff string
+	if stats := instance.GetDiffStats(); stats != nil && stats.Error == nil {
+		diff = report.Redact(stats.Content, patterns)
+	}
+	return Snapshot{Summary: summary, Diff: diff, CreatedAt: now, ExpiresAt: now.Add(ttl)}
+}
+
+// HTML renders the snapshot as a page without external resources, which hides its content once it
+// expired.
+func (s Snapshot) HTML() ([]byte, error) {
+	var b bytes.Buffer
+	if err := page.Execute(&b, s); err != nil {
+		return nil, fmt.Errorf("failed to render snapshot: %w", err)
+	}
+	return b.Bytes(), nil
+}
+
+// Markdown renders the snapshot as markdown, which GitHub renders with the diff highlighted.
+func (s Snapshot) Markdown() string {
+	var b strings.Builder
+	b.WriteString(report.FormatSummary(s.Summary))
+	if s.Diff != "" {
+		// The fence has to be longer than any run of backticks in the diff.
+		fence := "```"
+		for strings.Contains(s.Diff, fence) {
+			fence += "`"
+		}
+		fmt.Fprintf(&b, "\n#### Diff\n\n%sdiff\n%s\n%s\n", fence, strings.TrimRight(s.Diff, "\n"), fence)
+	}
+	fmt.Fprintf(&b, "\n_Snapshot taken %s, expires %s._\n", s.CreatedAt.Format(time.RFC822), s.ExpiresAt.Format(time.RFC822))
+	return b.String()
+}
+
+// lineClass returns the CSS class of a line of a diff.
+func lineClass(line string) string {
+	switch {
+	case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"), strings.HasPrefix(line, "diff "):
+		return "file"
+	case strings.HasPrefix(line, "@@"):
+		return "hunk"
+	case strings.HasPrefix(line, "+"):
+		return "add"
+	case strings.HasPrefix(line, "-"):
+		return "del"
+	}
+	return ""
+}
+
+// Record is a published snapshot, kept in the index of the snapshots so it's deleted once it expires.
+type Record struct {
+	Title string `json:"title"`
+	// Path is the file of a snapshot saved locally.
+	Path string `json:"path,omitempty"`
+	// Gist is the URL of a snapshot published as a gist.
+	Gist      string    `json:"gist,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Location returns where the snapshot is, its file or its URL.
+func (r Record) Location() string {
+	if r.Gist != "" {
+		return r.Gist
+	}
+	return r.Path
+}
+
+// Dir returns the directory the snapshots are saved to by default, and their index is kept in.
+func Dir() (string, error) {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "snapshots"), nil
+}
+
+// DefaultPath returns the file a snapshot of the instance is saved to by default.
+func DefaultPath(title string, now time.Time) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fmt.Sprintf("%s-%d.html", session.TitleFromText(title), now.Unix())), nil
+}
+
+// Save saves the snapshot as an HTML page to the file, and records it so it's deleted once it expires.
+func Save(s Snapshot, path string) (Record, error) {
+	data, err := s.HTML()
+	if err != nil {
+		return Record{}, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return Record{}, fmt.Errorf("failed to create directory for snapshot: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return Record{}, fmt.Errorf("failed to save snapshot: %w", err)
+	}
+	record := Record{Title: s.Summary.Title, Path: path, ExpiresAt: s.ExpiresAt}
+	return record, addRecord(record)
+}
+
+// Records returns the snapshots that haven't been deleted yet, oldest first.
+func Records() ([]Record, error) {
+	return loadIndex()
+}
+
+// PublishGist publishes the snapshot as a secret gist with the gh command line tool, so its credentials
+// apply, and records it so it's deleted once it expires.
+func PublishGist(s Snapshot) (Record, error) {
+	filename := session.TitleFromText(s.Summary.Title) + ".md"
+	description := fmt.Sprintf("Snapshot of %s, expires %s", s.Summary.Title, s.ExpiresAt.Format("2006-01-02 15:04"))
+	output, err := runCommand([]byte(s.Markdown()), "gh", "gist", "create", "--filename", filename, "--desc", description, "-")
+	if err != nil {
+		return Record{}, err
+	}
+	url := strings.TrimSpace(string(output))
+	if idx := strings.LastIndex(url, "\n"); idx >= 0 {
+		url = strings.TrimSpace(url[idx+1:])
+	}
+	record := Record{Title: s.Summary.Title, Gist: url, ExpiresAt: s.ExpiresAt}
+	return record, addRecord(record)
+}
+
+// Prune deletes the snapshots that expired before now, and returns how many it deleted. The snapshots it
+// couldn't delete are kept in the index, to try again.
+func Prune(now time.Time) (int, error) {
+	records, err := loadIndex()
+	if err != nil {
+		return 0, err
+	}
+	var kept []Record
+	var errs []error
+	for _, record := range records {
+		if now.Before(record.ExpiresAt) {
+			kept = append(kept, record)
+			continue
+		}
+		if err := deleteRecord(record); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete snapshot %s: %w", record.Location(), err))
+			kept = append(kept, record)
+		}
+	}
+	if len(kept) == len(records) {
+		return 0, errors.Join(errs...)
+	}
+	if err := saveIndex(kept); err != nil {
+		errs = append(errs, err)
+	}
+	return len(records) - len(kept), errors.Join(errs...)
+}
+
+func deleteRecord(record Record) error {
+	if record.Gist != "" {
+		_, err := runCommand(nil, "gh", "gist", "delete", "--yes", record.Gist)
+		return err
+	}
+	if err := os.Remove(record.Path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func indexPath() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, indexFileName), nil
+}
+
+func loadIndex() ([]Record, error) {
+	path, err := indexPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot index: %w", err)
+	}
+	var records []Record
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot index: %w", err)
+	}
+	return records, nil
+}
+
+func saveIndex(records []Record) error {
+	path, err := indexPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to save snapshot index: %w", err)
+	}
+	return nil
+}
+
+// addRecord adds the snapshot to the index, and deletes the expired ones while at it.
+func addRecord(record Record) error {
+	records, err := loadIndex()
+	if err != nil {
+		return err
+	}
+	if err := saveIndex(append(records, record)); err != nil {
+		return err
+	}
+	if _, err := Prune(time.Now()); err != nil {
+		log.WarningLog.Printf("failed to delete expired snapshots: %v", err)
+	}
+	return nil
+}
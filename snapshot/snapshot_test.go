@@ -0,0 +1,108 @@
+package snapshot
+
+import (
+	"claude-squad/log"
+	"claude-squad/report"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	log.Initialize(false)
+	defer log.Close()
+	os.Exit(m.Run())
+}
+
+var now = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func testSnapshot() Snapshot {
+	return Snapshot{
+		Summary: report.Summary{
+			Title:       "fix-login",
+			Branch:      "me/fix-login",
+			Status:      "ready",
+			Added:       1,
+			Removed:     1,
+			Files:       []string{"auth/login.go"},
+			LastMessage: "Fixed <the> redirect.",
+		},
+		Diff: "diff --git a/auth/login.go b/auth/login.go\n--- a/auth/login.go\n+++ b/auth/login.go\n" +
+			"@@ -1 +1 @@\n-old()\n+new()\n",
+		CreatedAt: now,
+		ExpiresAt: now.Add(24 * time.Hour),
+	}
+}
+
+func TestHTML(t *testing.T) {
+	page, err := testSnapshot().HTML()
+	require.NoError(t, err)
+	html := string(page)
+
+	assert.Contains(t, html, "<h1>fix-login</h1>")
+	assert.Contains(t, html, "Fixed &lt;the&gt; redirect.")
+	assert.Contains(t, html, `<span class="del">-old()</span>`)
+	assert.Contains(t, html, `<span class="add">&#43;new()</span>`)
+	assert.Contains(t, html, `<span class="hunk">@@ -1 &#43;1 @@</span>`)
+	assert.Contains(t, html, "Date.now() >=  1714651200000 ")
+	assert.NotContains(t, html, "<link")
+}
+
+func TestMarkdown(t *testing.T) {
+	snap := testSnapshot()
+	snap.Diff = "+```go\n+x\n+```\n"
+	md := snap.Markdown()
+	assert.True(t, strings.HasPrefix(md, report.FormatSummary(snap.Summary)))
+	assert.Contains(t, md, "\n````diff\n+```go\n+x\n+```\n````\n")
+	assert.Contains(t, md, "expires 02 May 24 12:00 UTC")
+}
+
+func TestSaveAndPrune(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	original := runCommand
+	defer func() { runCommand = original }()
+	var commands []string
+	runCommand = func(stdin []byte, name string, args ...string) ([]byte, error) {
+		commands = append(commands, name+" "+strings.Join(args, " "))
+		return []byte("https://gist.github.com/me/abc\n"), nil
+	}
+
+	expiring := testSnapshot()
+	expiring.ExpiresAt = time.Now().Add(time.Hour)
+	path, err := DefaultPath("Fix login", now)
+	require.NoError(t, err)
+	record, err := Save(expiring, path)
+	require.NoError(t, err)
+	assert.FileExists(t, record.Path)
+
+	gist, err := PublishGist(expiring)
+	require.NoError(t, err)
+	assert.Equal(t, "https://gist.github.com/me/abc", gist.Gist)
+	require.Len(t, commands, 1)
+	assert.True(t, strings.HasPrefix(commands[0], "gh gist create --filename fix-login.md"))
+
+	kept := testSnapshot()
+	kept.ExpiresAt = time.Now().Add(48 * time.Hour)
+	keptPath := filepath.Join(t.TempDir(), "kept.html")
+	_, err = Save(kept, keptPath)
+	require.NoError(t, err)
+	records, err := Records()
+	require.NoError(t, err)
+	assert.Len(t, records, 3)
+
+	deleted, err := Prune(time.Now().Add(2 * time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+	assert.NoFileExists(t, record.Path)
+	assert.FileExists(t, keptPath)
+	assert.Equal(t, "gh gist delete --yes https://gist.github.com/me/abc", commands[len(commands)-1])
+	records, err = Records()
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, keptPath, records[0].Path)
+}
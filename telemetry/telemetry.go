@@ -0,0 +1,229 @@
+// Package telemetry counts how often the features of claude-squad are used and how often errors of each
+// category happen, to help prioritize development. It's strictly opt-in: nothing is counted unless the
+// telemetry mode is "preview" or "on", and nothing leaves the machine unless it's "on" with an endpoint.
+// Only fixed names are counted, never code, prompts, titles or paths.
+package telemetry
+
+import (
+	"bytes"
+	"claude-squad/config"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// FileName is the file in the config directory the counts not sent yet are kept in.
+const FileName = "telemetry.json"
+
+// sendTimeout is how long sending the counts may take, so a slow endpoint doesn't hold up exiting.
+const sendTimeout = 5 * time.Second
+
+// sendInterval is how often the counts are sent.
+var sendInterval = 24 * time.Hour
+
+// nameRegex matches the names that are counted: the names of actions, commands and error categories.
+// Anything else is dropped, so nothing the user typed can end up in the counts.
+var nameRegex = regexp.MustCompile(`^[a-z][a-z0-9 :-]{0,39}$`)
+
+// Report is exactly what is sent.
+type Report struct {
+	// InstallID is a random ID generated on first use, to tell installs apart without identifying anyone.
+	InstallID string `json:"install_id"`
+	Version   string `json:"version"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+	// Since is when the counts started, i.e. when they were last sent.
+	Since time.Time `json:"since"`
+	// Features are how often each feature was used, e.g. "key:checkout" or "command:archive list".
+	Features map[string]int `json:"features"`
+	// Errors are how often an error of each category happened, e.g. "worktree setup".
+	Errors map[string]int `json:"errors"`
+}
+
+var (
+	mu       sync.Mutex
+	settings config.Telemetry
+	features = make(map[string]int)
+	errs     = make(map[string]int)
+)
+
+// Configure sets the telemetry settings. Until it's called, nothing is counted.
+func Configure(cfg config.Telemetry) {
+	mu.Lock()
+	defer mu.Unlock()
+	settings = cfg
+}
+
+// Feature counts a use of the named feature, if the telemetry is collecting.
+func Feature(name string) {
+	count(features, name)
+}
+
+// Error counts an error of the category, if the telemetry is collecting.
+func Error(category string) {
+	count(errs, category)
+}
+
+func count(counts map[string]int, name string) {
+	if !nameRegex.MatchString(name) {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if settings.Collecting() {
+		counts[name]++
+	}
+}
+
+// Flush adds the counts of this process to the ones kept on disk, then sends them if the telemetry is on
+// and they weren't sent for a day. The counts are kept on disk if sending fails, to try again later.
+func Flush(version string) error {
+	mu.Lock()
+	cfg := settings
+	newFeatures, newErrs := features, errs
+	features, errs = make(map[string]int), make(map[string]int)
+	mu.Unlock()
+	if !cfg.Collecting() {
+		return nil
+	}
+
+	path, err := filePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	unlock, err := config.LockPath(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	report, err := load(path, version)
+	if err != nil {
+		return err
+	}
+	for name, n := range newFeatures {
+		report.Features[name] += n
+	}
+	for name, n := range newErrs {
+		report.Errors[name] += n
+	}
+	if cfg.Sending() && time.Since(report.Since) >= sendInterval {
+		if err := send(cfg.Endpoint, report); err != nil {
+			if saveErr := save(path, report); saveErr != nil {
+				return fmt.Errorf("%w (%v)", err, saveErr)
+			}
+			return err
+		}
+		report.Since = time.Now()
+		report.Features = make(map[string]int)
+		report.Errors = make(map[string]int)
+	}
+	return save(path, report)
+}
+
+// Preview returns the report that would be sent next, with the counts of this process not flushed yet.
+func Preview(version string) (Report, error) {
+	path, err := filePath()
+	if err != nil {
+		return Report{}, err
+	}
+	report, err := load(path, version)
+	if err != nil {
+		return Report{}, err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for name, n := range features {
+		report.Features[name] += n
+	}
+	for name, n := range errs {
+		report.Errors[name] += n
+	}
+	return report, nil
+}
+
+func filePath() (string, error) {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get config directory: %w", err)
+	}
+	return filepath.Join(configDir, FileName), nil
+}
+
+// load returns the report kept on disk, or a new one with a new install ID if there is none.
+func load(path, version string) (Report, error) {
+	var report Report
+	data, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		id := make([]byte, 16)
+		if _, err := rand.Read(id); err != nil {
+			return Report{}, fmt.Errorf("failed to generate the install ID: %w", err)
+		}
+		report.InstallID = hex.EncodeToString(id)
+		report.Since = time.Now()
+	case err != nil:
+		return Report{}, fmt.Errorf("failed to read the telemetry counts: %w", err)
+	default:
+		if err := json.Unmarshal(data, &report); err != nil {
+			return Report{}, fmt.Errorf("failed to parse the telemetry counts: %w", err)
+		}
+	}
+	report.Version = version
+	report.OS = runtime.GOOS
+	report.Arch = runtime.GOARCH
+	if report.Features == nil {
+		report.Features = make(map[string]int)
+	}
+	if report.Errors == nil {
+		report.Errors = make(map[string]int)
+	}
+	return report, nil
+}
+
+func save(path string, report Report) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to save the telemetry counts: %w", err)
+	}
+	return nil
+}
+
+// send posts the report to the endpoint as JSON.
+func send(endpoint string, report Report) error {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to send telemetry: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send telemetry: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to send telemetry: %s", resp.Status)
+	}
+	return nil
+}
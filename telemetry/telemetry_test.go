@@ -0,0 +1,73 @@
+package telemetry
+
+import (
+	"claude-squad/config"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTelemetryIsOptIn(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	Configure(config.Telemetry{})
+	Feature("key:checkout")
+	require.NoError(t, Flush("1.0.0"))
+	report, err := Preview("1.0.0")
+	require.NoError(t, err)
+	assert.Empty(t, report.Features)
+}
+
+func TestTelemetryPreview(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	Configure(config.Telemetry{Mode: config.TelemetryPreview})
+	defer Configure(config.Telemetry{})
+
+	Feature("key:checkout")
+	Feature("key:checkout")
+	Error("worktree setup")
+	// Names that aren't fixed, like titles, are dropped.
+	Feature("Fix the login of Alice's account")
+	require.NoError(t, Flush("1.0.0"))
+	Feature("command:archive list")
+
+	report, err := Preview("1.0.0")
+	require.NoError(t, err)
+	assert.Len(t, report.InstallID, 32)
+	assert.Equal(t, map[string]int{"key:checkout": 2, "command:archive list": 1}, report.Features)
+	assert.Equal(t, map[string]int{"worktree setup": 1}, report.Errors)
+	require.NoError(t, Flush("1.0.0"))
+}
+
+func TestTelemetrySend(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	var received []Report
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var report Report
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&report))
+		received = append(received, report)
+	}))
+	defer server.Close()
+
+	Configure(config.Telemetry{Mode: config.TelemetryOn, Endpoint: server.URL})
+	defer Configure(config.Telemetry{})
+	Feature("key:new")
+	require.NoError(t, Flush("1.0.0"))
+	assert.Empty(t, received, "the counts are only sent once a day")
+
+	defer func(interval time.Duration) { sendInterval = interval }(sendInterval)
+	sendInterval = 0
+	Feature("key:new")
+	require.NoError(t, Flush("1.0.0"))
+	require.Len(t, received, 1)
+	assert.Equal(t, map[string]int{"key:new": 2}, received[0].Features)
+
+	report, err := Preview("1.0.0")
+	require.NoError(t, err)
+	assert.Empty(t, report.Features)
+	assert.Equal(t, received[0].InstallID, report.InstallID)
+}
@@ -0,0 +1,291 @@
+package template
+
+import (
+	"claude-squad/config"
+	"claude-squad/schema"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TemplatesDirName is the directory in the config directory where imported packs are stored.
+const TemplatesDirName = "templates"
+
+// fetchTimeout bounds the download of a pack from a URL.
+const fetchTimeout = 30 * time.Second
+
+var packNameRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// Template describes how to start an instance for a kind of task.
+type Template struct {
+	// Name identifies the template within its pack.
+	Name string `yaml:"name"`
+	// Description is shown in the template picker.
+	Description string `yaml:"description,omitempty"`
+	// Prompt is sent to the instance before the task.
+	Prompt string `yaml:"prompt"`
+	// Program overrides the default program, if set.
+	Program string `yaml:"program,omitempty"`
+	// Validators are commands that should pass before the agent considers the task done.
+	Validators []string `yaml:"validators,omitempty"`
+	// Teardown are commands run in the worktree before it's removed, e.g. to stop the dev servers the
+	// template's tasks start.
+	Teardown []string `yaml:"teardown,omitempty"`
+	// Resources are the external resources the template's tasks use, e.g. "staging-db". Its instances
+	// wait for them while other instances use them.
+	Resources []string `yaml:"resources,omitempty"`
+
+	// Pack is the pack the template was loaded from.
+	Pack *Pack `yaml:"-"`
+}
+
+// Pack is a shareable collection of templates.
+type Pack struct {
+	// Name identifies the pack. It is also the name of the file the pack is stored in.
+	Name string `yaml:"name"`
+	// Description of the pack.
+	Description string `yaml:"description,omitempty"`
+	// Author of the pack.
+	Author string `yaml:"author,omitempty"`
+	// Templates of the pack.
+	Templates []Template `yaml:"templates"`
+
+	// Source is the URL or file the pack was imported from. Set on import.
+	Source string `yaml:"source,omitempty"`
+	// ImportedAt is the time the pack was imported. Set on import.
+	ImportedAt time.Time `yaml:"imported_at,omitempty"`
+}
+
+// Schema returns the JSON Schema of template pack files.
+func Schema() *schema.Schema {
+	return schema.Generate(Pack{}, "yaml", "claude-squad template pack")
+}
+
+// ParsePack parses and validates a YAML template pack.
+func ParsePack(data []byte) (*Pack, error) {
+	var pack Pack
+	if problems, err := schema.Validate(data, Schema()); err == nil && len(problems) > 0 {
+		return nil, fmt.Errorf("invalid template pack: %w", schema.Join(problems))
+	}
+	if err := yaml.Unmarshal(data, &pack); err != nil {
+		return nil, fmt.Errorf("failed to parse template pack: %w", err)
+	}
+	if !packNameRegex.MatchString(pack.Name) {
+		return nil, fmt.Errorf("invalid template pack name %q", pack.Name)
+	}
+	if len(pack.Templates) == 0 {
+		return nil, fmt.Errorf("template pack %s has no templates", pack.Name)
+	}
+	seen := make(map[string]bool)
+	for i := range pack.Templates {
+		t := &pack.Templates[i]
+		if t.Name == "" {
+			return nil, fmt.Errorf("template %d of pack %s has no name", i+1, pack.Name)
+		}
+		if seen[t.Name] {
+			return nil, fmt.Errorf("template pack %s has duplicate template %s", pack.Name, t.Name)
+		}
+		if strings.TrimSpace(t.Prompt) == "" {
+			return nil, fmt.Errorf("template %s of pack %s has no prompt", t.Name, pack.Name)
+		}
+		seen[t.Name] = true
+		t.Pack = &pack
+	}
+	return &pack, nil
+}
+
+// FullName returns the name of the template qualified by its pack, e.g. "starter/bug-fixer".
+func (t *Template) FullName() string {
+	if t.Pack == nil {
+		return t.Name
+	}
+	return t.Pack.Name + "/" + t.Name
+}
+
+// Provenance describes where the template comes from.
+func (t *Template) Provenance() string {
+	if t.Pack == nil {
+		return ""
+	}
+	var parts []string
+	if t.Pack.Author != "" {
+		parts = append(parts, "by "+t.Pack.Author)
+	}
+	if t.Pack.Source != "" {
+		parts = append(parts, "from "+t.Pack.Source)
+	}
+	if !t.Pack.ImportedAt.IsZero() {
+		parts = append(parts, "imported "+t.Pack.ImportedAt.Format("2006-01-02"))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// InitialPrompt combines the template prompt with the task and the validators into the prompt sent to
+// the instance.
+func (t *Template) InitialPrompt(task string) string {
+	prompt := strings.TrimSpace(t.Prompt)
+	if task = strings.TrimSpace(task); task != "" {
+		prompt += "\n\nTask: " + task
+	}
+	if len(t.Validators) > 0 {
+		prompt += "\n\nBefore you finish, make sure these commands pass: " + strings.Join(t.Validators, "; ")
+	}
+	return prompt
+}
+
+// templatesDir returns the directory imported packs are stored in.
+func templatesDir() (string, error) {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get config directory: %w", err)
+	}
+	return filepath.Join(configDir, TemplatesDirName), nil
+}
+
+// fetch reads a pack from an http(s) URL or a file.
+func fetch(source string) ([]byte, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		data, err := os.ReadFile(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template pack: %w", err)
+		}
+		return data, nil
+	}
+
+	client := &http.Client{Timeout: fetchTimeout}
+	resp, err := client.Get(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download template pack: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download template pack: %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// Import fetches a pack from a URL or file, records its provenance and stores it. An existing pack with
+// the same name is replaced.
+func Import(source string) (*Pack, error) {
+	data, err := fetch(source)
+	if err != nil {
+		return nil, err
+	}
+	pack, err := ParsePack(data)
+	if err != nil {
+		return nil, err
+	}
+	if abs, err := filepath.Abs(source); err == nil && !strings.Contains(source, "://") {
+		source = abs
+	}
+	pack.Source = source
+	pack.ImportedAt = time.Now()
+
+	dir, err := templatesDir()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create templates directory: %w", err)
+	}
+	out, err := yaml.Marshal(pack)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal template pack: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, pack.Name+".yaml"), out, 0644); err != nil {
+		return nil, fmt.Errorf("failed to save template pack: %w", err)
+	}
+	return pack, nil
+}
+
+// Remove deletes an imported pack. The name is checked like the names of imported packs, so it can't
+// point outside of the templates directory.
+func Remove(name string) error {
+	if !packNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid template pack name %q", name)
+	}
+	dir, err := templatesDir()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(dir, name+".yaml")); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("template pack %s is not installed", name)
+		}
+		return fmt.Errorf("failed to remove template pack: %w", err)
+	}
+	return nil
+}
+
+// LoadPacks loads the imported packs, sorted by name.
+func LoadPacks() ([]*Pack, error) {
+	dir, err := templatesDir()
+	if err != nil {
+		return nil, err
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+
+	var packs []*Pack
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template pack: %w", err)
+		}
+		pack, err := ParsePack(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(file), err)
+		}
+		packs = append(packs, pack)
+	}
+	sort.Slice(packs, func(i, j int) bool { return packs[i].Name < packs[j].Name })
+	return packs, nil
+}
+
+// LoadTemplates returns the templates of all the imported packs.
+func LoadTemplates() ([]*Template, error) {
+	packs, err := LoadPacks()
+	if err != nil {
+		return nil, err
+	}
+	var templates []*Template
+	for _, pack := range packs {
+		for i := range pack.Templates {
+			templates = append(templates, &pack.Templates[i])
+		}
+	}
+	return templates, nil
+}
+
+// Find returns the template with the given name, either qualified by its pack ("pack/name") or not. An
+// unqualified name must be unique across the packs.
+func Find(templates []*Template, name string) (*Template, error) {
+	var matches []*Template
+	for _, t := range templates {
+		if t.FullName() == name {
+			return t, nil
+		}
+		if t.Name == name {
+			matches = append(matches, t)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("template %s not found", name)
+	case 1:
+		return matches[0], nil
+	default:
+		return nil, fmt.Errorf("template %s is ambiguous, qualify it with its pack (e.g. %s)", name,
+			matches[0].FullName())
+	}
+}
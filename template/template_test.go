@@ -0,0 +1,88 @@
+package template
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const starterPack = `name: starter
+author: octocat
+templates:
+  - name: bug-fixer
+    description: Reproduce and fix a bug
+    prompt: Write a failing test that reproduces the bug, then fix it.
+    validators:
+      - go test ./...
+  - name: doc-updater
+    prompt: Update the documentation.
+    program: aider
+`
+
+func TestParsePack(t *testing.T) {
+	pack, err := ParsePack([]byte(starterPack))
+	require.NoError(t, err)
+	assert.Equal(t, "starter", pack.Name)
+	require.Len(t, pack.Templates, 2)
+	assert.Equal(t, "starter/bug-fixer", pack.Templates[0].FullName())
+	assert.Equal(t, "aider", pack.Templates[1].Program)
+
+	_, err = ParsePack([]byte("name: ../evil\ntemplates:\n  - name: a\n    prompt: b\n"))
+	assert.Error(t, err)
+	_, err = ParsePack([]byte("name: empty\n"))
+	assert.Error(t, err)
+	_, err = ParsePack([]byte("name: dup\ntemplates:\n  - name: a\n    prompt: b\n  - name: a\n    prompt: c\n"))
+	assert.Error(t, err)
+	_, err = ParsePack([]byte("name: typo\ntemplates:\n  - name: a\n    promt: b\n"))
+	assert.ErrorContains(t, err, `4:5: templates[0]: unknown field "promt"`)
+}
+
+func TestInitialPrompt(t *testing.T) {
+	pack, err := ParsePack([]byte(starterPack))
+	require.NoError(t, err)
+	assert.Equal(t, "Write a failing test that reproduces the bug, then fix it.\n\n"+
+		"Task: login redirects forever\n\n"+
+		"Before you finish, make sure these commands pass: go test ./...",
+		pack.Templates[0].InitialPrompt("login redirects forever"))
+}
+
+func TestImport(t *testing.T) {
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", t.TempDir())
+	defer os.Setenv("HOME", originalHome)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(starterPack))
+	}))
+	defer server.Close()
+
+	pack, err := Import(server.URL + "/starter.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, server.URL+"/starter.yaml", pack.Source)
+
+	local := filepath.Join(t.TempDir(), "local.yaml")
+	require.NoError(t, os.WriteFile(local, []byte("name: local\ntemplates:\n  - name: bug-fixer\n    prompt: fix\n"), 0644))
+	_, err = Import(local)
+	require.NoError(t, err)
+
+	templates, err := LoadTemplates()
+	require.NoError(t, err)
+	require.Len(t, templates, 3)
+	assert.Equal(t, "local/bug-fixer", templates[0].FullName())
+	assert.Contains(t, templates[1].Provenance(), "by octocat, from "+server.URL)
+
+	_, err = Find(templates, "bug-fixer")
+	assert.ErrorContains(t, err, "ambiguous")
+	found, err := Find(templates, "doc-updater")
+	require.NoError(t, err)
+	assert.Equal(t, "starter/doc-updater", found.FullName())
+
+	require.NoError(t, Remove("local"))
+	assert.Error(t, Remove("local"))
+	assert.ErrorContains(t, Remove("../../config"), "invalid template pack name")
+}
@@ -0,0 +1,30 @@
+// Package textutil cuts text shown to people or sent to services on character boundaries, so multi-byte
+// characters aren't split into invalid UTF-8.
+package textutil
+
+import "unicode/utf8"
+
+// Prefix returns the first n characters of s, or s if it's shorter.
+func Prefix(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	for idx := range s {
+		if n == 0 {
+			return s[:idx]
+		}
+		n--
+	}
+	return s
+}
+
+// Truncate returns s cut to at most max characters, ending with "..." if it was cut.
+func Truncate(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	if max <= 3 {
+		return Prefix(s, max)
+	}
+	return Prefix(s, max-3) + "..."
+}
@@ -0,0 +1,21 @@
+package textutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrefix(t *testing.T) {
+	assert.Equal(t, "Gér", Prefix("Gérer", 3))
+	assert.Equal(t, "Gérer", Prefix("Gérer", 5))
+	assert.Equal(t, "Gérer", Prefix("Gérer", 10))
+	assert.Equal(t, "", Prefix("Gérer", 0))
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "déjà vu", Truncate("déjà vu", 7))
+	assert.Equal(t, "déjà...", Truncate("déjà vu again", 7))
+	assert.Equal(t, "界界界...", Truncate("界界界界界界界界", 6))
+	assert.Equal(t, "dé", Truncate("déjà vu", 2))
+}
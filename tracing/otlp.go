@@ -0,0 +1,201 @@
+package tracing
+
+import (
+	"bytes"
+	"claude-squad/config"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// exportTimeout is how long exporting the spans may take, so a slow endpoint doesn't hold up exiting.
+	exportTimeout = 5 * time.Second
+	// defaultEndpoint is the traces endpoint of an OpenTelemetry Collector running locally.
+	defaultEndpoint = "http://localhost:4318/v1/traces"
+	// FileName is the file in the config directory the file exporter appends to by default.
+	FileName = "traces.jsonl"
+)
+
+// OTLP status codes and span kinds.
+const (
+	statusCodeError  = 2
+	spanKindInternal = 1
+)
+
+// exportFunc exports an OTLP/JSON ExportTraceServiceRequest.
+type exportFunc func(data []byte) error
+
+// newExporter returns the exporter of the settings, nil if tracing is off.
+func newExporter(cfg config.Tracing) exportFunc {
+	switch cfg.Exporter {
+	case config.TracingOTLP:
+		endpoint := cfg.Endpoint
+		if endpoint == "" {
+			endpoint = os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+		}
+		if endpoint == "" {
+			if base := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); base != "" {
+				endpoint = strings.TrimSuffix(base, "/") + "/v1/traces"
+			}
+		}
+		if endpoint == "" {
+			endpoint = defaultEndpoint
+		}
+		return func(data []byte) error {
+			return post(endpoint, cfg.Headers, data)
+		}
+	case config.TracingFile:
+		return func(data []byte) error {
+			return appendLine(cfg.File, data)
+		}
+	}
+	return nil
+}
+
+// post sends the spans to the OTLP/HTTP endpoint.
+func post(endpoint string, headers map[string]string, data []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to export spans: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to export spans: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to export spans: %s", resp.Status)
+	}
+	return nil
+}
+
+// appendLine appends the spans to the file as a line, the config directory's FileName if it's empty.
+func appendLine(path string, data []byte) error {
+	if path == "" {
+		configDir, err := config.GetConfigDir()
+		if err != nil {
+			return fmt.Errorf("failed to get config directory: %w", err)
+		}
+		path = filepath.Join(configDir, FileName)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create the directory of %s: %w", path, err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write spans to %s: %w", path, err)
+	}
+	return nil
+}
+
+// The OTLP/JSON encoding of spans, see
+// https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding.
+type (
+	exportRequest struct {
+		ResourceSpans []resourceSpans `json:"resourceSpans"`
+	}
+	resourceSpans struct {
+		Resource   otlpResource `json:"resource"`
+		ScopeSpans []scopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []keyValue `json:"attributes"`
+	}
+	scopeSpans struct {
+		Scope scope      `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	scope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string     `json:"traceId"`
+		SpanID            string     `json:"spanId"`
+		ParentSpanID      string     `json:"parentSpanId,omitempty"`
+		Name              string     `json:"name"`
+		Kind              int        `json:"kind"`
+		StartTimeUnixNano string     `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+		Attributes        []keyValue `json:"attributes,omitempty"`
+		Status            *status    `json:"status,omitempty"`
+	}
+	keyValue struct {
+		Key   string   `json:"key"`
+		Value anyValue `json:"value"`
+	}
+	anyValue struct {
+		StringValue *string `json:"stringValue,omitempty"`
+		IntValue    *string `json:"intValue,omitempty"`
+		BoolValue   *bool   `json:"boolValue,omitempty"`
+	}
+	status struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+)
+
+// encode returns the spans as an OTLP/JSON ExportTraceServiceRequest.
+func encode(resource []Attribute, spans []*Span) []byte {
+	encoded := make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		span.mu.Lock()
+		s := otlpSpan{
+			TraceID:           span.traceID,
+			SpanID:            span.spanID,
+			ParentSpanID:      span.parentID,
+			Name:              span.name,
+			Kind:              spanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(span.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.end.UnixNano(), 10),
+			Attributes:        keyValues(span.attrs),
+		}
+		if span.err != nil {
+			s.Status = &status{Code: statusCodeError, Message: span.err.Error()}
+		}
+		span.mu.Unlock()
+		encoded = append(encoded, s)
+	}
+	data, _ := json.Marshal(exportRequest{ResourceSpans: []resourceSpans{{
+		Resource:   otlpResource{Attributes: keyValues(resource)},
+		ScopeSpans: []scopeSpans{{Scope: scope{Name: "claude-squad"}, Spans: encoded}},
+	}}})
+	return data
+}
+
+func keyValues(attrs []Attribute) []keyValue {
+	values := make([]keyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		var value anyValue
+		switch v := attr.Value.(type) {
+		case string:
+			value.StringValue = &v
+		case int:
+			s := strconv.Itoa(v)
+			value.IntValue = &s
+		case bool:
+			value.BoolValue = &v
+		default:
+			s := fmt.Sprint(v)
+			value.StringValue = &s
+		}
+		values = append(values, keyValue{Key: attr.Key, Value: value})
+	}
+	return values
+}
@@ -0,0 +1,201 @@
+// Package tracing records OpenTelemetry spans of the operations of the instances, like setting up their
+// worktree, starting their terminal, sending them prompts and computing their diff, and exports them over
+// OTLP, so slow or failing operations can be debugged when claude-squad runs in automation. Nothing is
+// recorded unless an exporter is configured.
+package tracing
+
+import (
+	"claude-squad/config"
+	"claude-squad/log"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+)
+
+const (
+	// exportInterval is how often the ended spans are exported.
+	exportInterval = 5 * time.Second
+	// maxPending caps the spans waiting to be exported. Spans ended beyond it are dropped, so an
+	// unreachable endpoint can't grow the memory without bounds.
+	maxPending = 2048
+)
+
+// traceParentRegex matches a W3C traceparent, e.g. the TRACEPARENT of a CI job, which the root spans
+// become children of.
+var traceParentRegex = regexp.MustCompile(`^00-([0-9a-f]{32})-([0-9a-f]{16})-[0-9a-f]{2}$`)
+
+// Attribute is a key and a value recorded on a span. The value is a string, an int or a bool.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// String returns a string attribute.
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Int returns an int attribute.
+func Int(key string, value int) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Bool returns a bool attribute.
+func Bool(key string, value bool) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Span is an operation being traced. A nil Span, which Start returns while tracing is off, ignores
+// everything.
+type Span struct {
+	name     string
+	traceID  string
+	spanID   string
+	parentID string
+	start    time.Time
+	end      time.Time
+	mu       sync.Mutex
+	attrs    []Attribute
+	err      error
+}
+
+type spanKey struct{}
+
+var (
+	mu       sync.Mutex
+	exporter exportFunc
+	resource []Attribute
+	pending  []*Span
+	// remoteTraceID and remoteParentID are the trace and the span of $TRACEPARENT, if it's set.
+	remoteTraceID, remoteParentID string
+	stopExport                    chan struct{}
+)
+
+// Configure sets up the exporter of the settings, or turns tracing off if they don't have one. Once a span
+// ends, the spans are exported every few seconds in the background, and by Flush.
+func Configure(cfg config.Tracing, version string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if stopExport != nil {
+		close(stopExport)
+		stopExport = nil
+	}
+	exporter = newExporter(cfg)
+	pending = nil
+	if exporter == nil {
+		return
+	}
+
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = os.Getenv("OTEL_SERVICE_NAME")
+	}
+	if serviceName == "" {
+		serviceName = "claude-squad"
+	}
+	resource = []Attribute{String("service.name", serviceName), String("service.version", version)}
+	remoteTraceID, remoteParentID = "", ""
+	if match := traceParentRegex.FindStringSubmatch(os.Getenv("TRACEPARENT")); match != nil {
+		remoteTraceID, remoteParentID = match[1], match[2]
+	}
+}
+
+// Start starts a span named after the operation, e.g. "worktree.setup", the child of the span of ctx if
+// it has one. The returned context carries the span, for the spans of the operations it's made of.
+func Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, *Span) {
+	mu.Lock()
+	enabled := exporter != nil
+	traceID, parentID := remoteTraceID, remoteParentID
+	mu.Unlock()
+	if !enabled {
+		return ctx, nil
+	}
+
+	if parent, ok := ctx.Value(spanKey{}).(*Span); ok && parent != nil {
+		traceID, parentID = parent.traceID, parent.spanID
+	}
+	if traceID == "" {
+		traceID = randomID(16)
+	}
+	span := &Span{
+		name:     name,
+		traceID:  traceID,
+		spanID:   randomID(8),
+		parentID: parentID,
+		start:    time.Now(),
+		attrs:    attrs,
+	}
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// SetAttributes records attributes on the span.
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attrs = append(s.attrs, attrs...)
+}
+
+// End ends the span, with an error status if err isn't nil, and queues it for export.
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.end = time.Now()
+	s.err = err
+	s.mu.Unlock()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if exporter == nil {
+		return
+	}
+	if len(pending) >= maxPending {
+		return
+	}
+	pending = append(pending, s)
+	if stopExport == nil {
+		stopExport = make(chan struct{})
+		go exportPeriodically(stopExport)
+	}
+}
+
+// Flush exports the ended spans, e.g. before exiting.
+func Flush() error {
+	mu.Lock()
+	export, spans, res := exporter, pending, resource
+	pending = nil
+	mu.Unlock()
+	if export == nil || len(spans) == 0 {
+		return nil
+	}
+	return export(encode(res, spans))
+}
+
+func exportPeriodically(stop chan struct{}) {
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := Flush(); err != nil {
+				log.WarningLog.Printf("failed to export spans: %v", err)
+			}
+		}
+	}
+}
+
+func randomID(n int) string {
+	id := make([]byte, n)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
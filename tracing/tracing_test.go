@@ -0,0 +1,105 @@
+package tracing
+
+import (
+	"claude-squad/config"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracingIsOff(t *testing.T) {
+	Configure(config.Tracing{}, "1.0.0")
+	ctx, span := Start(context.Background(), "instance.start")
+	assert.Nil(t, span)
+	assert.Equal(t, context.Background(), ctx)
+	// A nil span ignores everything.
+	span.SetAttributes(String("instance.title", "fix-login"))
+	span.End(errors.New("failed"))
+	assert.NoError(t, Flush())
+}
+
+func TestExportOTLP(t *testing.T) {
+	t.Setenv("TRACEPARENT", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	var request exportRequest
+	var apiKey string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		apiKey = r.Header.Get("X-Api-Key")
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(body, &request))
+	}))
+	defer server.Close()
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", server.URL+"/")
+	Configure(config.Tracing{Exporter: config.TracingOTLP, Headers: map[string]string{"X-Api-Key": "s3cr3t"}}, "1.0.0")
+	defer Configure(config.Tracing{}, "")
+
+	ctx, parent := Start(context.Background(), "instance.start", String("instance.title", "fix-login"))
+	_, child := Start(ctx, "worktree.setup")
+	child.SetAttributes(Int("files", 3), Bool("cached", true))
+	child.End(errors.New("worktree exists"))
+	parent.End(nil)
+	require.NoError(t, Flush())
+
+	assert.Equal(t, "s3cr3t", apiKey)
+	require.Len(t, request.ResourceSpans, 1)
+	assert.Equal(t, "service.name", request.ResourceSpans[0].Resource.Attributes[0].Key)
+	assert.Equal(t, "claude-squad", *request.ResourceSpans[0].Resource.Attributes[0].Value.StringValue)
+	spans := request.ResourceSpans[0].ScopeSpans[0].Spans
+	require.Len(t, spans, 2)
+	setup, start := spans[0], spans[1]
+
+	// The root span joins the trace of $TRACEPARENT, and the other spans the trace of their parent.
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", start.TraceID)
+	assert.Equal(t, "b7ad6b7169203331", start.ParentSpanID)
+	assert.Equal(t, start.TraceID, setup.TraceID)
+	assert.Equal(t, start.SpanID, setup.ParentSpanID)
+	assert.Len(t, setup.SpanID, 16)
+
+	assert.Equal(t, "worktree.setup", setup.Name)
+	assert.Equal(t, &status{Code: statusCodeError, Message: "worktree exists"}, setup.Status)
+	assert.Nil(t, start.Status)
+	assert.Equal(t, "3", *setup.Attributes[0].Value.IntValue)
+	assert.True(t, *setup.Attributes[1].Value.BoolValue)
+	assert.LessOrEqual(t, start.StartTimeUnixNano, start.EndTimeUnixNano)
+
+	// The exported spans aren't exported again.
+	request = exportRequest{}
+	require.NoError(t, Flush())
+	assert.Empty(t, request.ResourceSpans)
+}
+
+func TestExportFile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("TRACEPARENT", "")
+	Configure(config.Tracing{Exporter: config.TracingFile, ServiceName: "ci"}, "1.0.0")
+	defer Configure(config.Tracing{}, "")
+
+	for _, name := range []string{"instance.send_prompt", "instance.diff"} {
+		_, span := Start(context.Background(), name)
+		span.End(nil)
+		require.NoError(t, Flush())
+	}
+
+	configDir, err := config.GetConfigDir()
+	require.NoError(t, err)
+	data, err := os.ReadFile(filepath.Join(configDir, FileName))
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+	var request exportRequest
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &request))
+	assert.Equal(t, "ci", *request.ResourceSpans[0].Resource.Attributes[0].Value.StringValue)
+	assert.Equal(t, "instance.diff", request.ResourceSpans[0].ScopeSpans[0].Spans[0].Name)
+	assert.Empty(t, request.ResourceSpans[0].ScopeSpans[0].Spans[0].ParentSpanID)
+}
@@ -9,12 +9,6 @@ import (
 	"github.com/charmbracelet/lipgloss"
 )
 
-var (
-	AdditionStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#22c55e"))
-	DeletionStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#ef4444"))
-	HunkStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("#0ea5e9"))
-)
-
 type DiffPane struct {
 	viewport viewport.Model
 	diff     string
@@ -89,7 +83,15 @@ func (d *DiffPane) SetDiff(instance *session.Instance) {
 		additions := AdditionStyle.Render(fmt.Sprintf("%d additions(+)", stats.Added))
 		deletions := DeletionStyle.Render(fmt.Sprintf("%d deletions(-)", stats.Removed))
 		d.stats = lipgloss.JoinHorizontal(lipgloss.Center, additions, " ", deletions)
-		d.diff = colorizeDiff(stats.Content)
+		if upstream := instance.Upstream(); upstream != nil {
+			d.stats = lipgloss.JoinHorizontal(lipgloss.Center, d.stats, " ", upstream.String())
+		}
+		if outside := instance.OutOfScopeFiles(); len(outside) > 0 {
+			d.stats = lipgloss.JoinVertical(lipgloss.Left, d.stats, WarningStyle.Render(fmt.Sprintf(
+				"⚠ %d file(s) outside of scope %s: %s", len(outside), strings.Join(instance.Scopes, ", "),
+				strings.Join(outside, ", "))))
+		}
+		d.diff = ColorizeDiff(stats.Content)
 		d.viewport.SetContent(lipgloss.JoinVertical(lipgloss.Left, d.stats, d.diff))
 	}
 }
@@ -108,7 +110,8 @@ func (d *DiffPane) ScrollDown() {
 	d.viewport.LineDown(1)
 }
 
-func colorizeDiff(diff string) string {
+// ColorizeDiff colors the added, removed and hunk header lines of a diff.
+func ColorizeDiff(diff string) string {
 	var coloredOutput strings.Builder
 
 	lines := strings.Split(diff, "\n")
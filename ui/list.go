@@ -1,11 +1,13 @@
 package ui
 
 import (
+	"claude-squad/config"
 	"claude-squad/log"
 	"claude-squad/session"
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/lipgloss"
@@ -13,44 +15,11 @@ import (
 
 const readyIcon = "● "
 const pausedIcon = "⏸ "
-
-var readyStyle = lipgloss.NewStyle().
-	Foreground(lipgloss.AdaptiveColor{Light: "#51bd73", Dark: "#51bd73"})
-
-var addedLinesStyle = lipgloss.NewStyle().
-	Foreground(lipgloss.AdaptiveColor{Light: "#51bd73", Dark: "#51bd73"})
-
-var removedLinesStyle = lipgloss.NewStyle().
-	Foreground(lipgloss.Color("#de613e"))
-
-var pausedStyle = lipgloss.NewStyle().
-	Foreground(lipgloss.AdaptiveColor{Light: "#888888", Dark: "#888888"})
-
-var titleStyle = lipgloss.NewStyle().
-	Padding(1, 1, 0, 1).
-	Foreground(lipgloss.AdaptiveColor{Light: "#1a1a1a", Dark: "#dddddd"})
-
-var listDescStyle = lipgloss.NewStyle().
-	Padding(0, 1, 1, 1).
-	Foreground(lipgloss.AdaptiveColor{Light: "#A49FA5", Dark: "#777777"})
-
-var selectedTitleStyle = lipgloss.NewStyle().
-	Padding(1, 1, 0, 1).
-	Background(lipgloss.Color("#dde4f0")).
-	Foreground(lipgloss.AdaptiveColor{Light: "#1a1a1a", Dark: "#1a1a1a"})
-
-var selectedDescStyle = lipgloss.NewStyle().
-	Padding(0, 1, 1, 1).
-	Background(lipgloss.Color("#dde4f0")).
-	Foreground(lipgloss.AdaptiveColor{Light: "#1a1a1a", Dark: "#1a1a1a"})
-
-var mainTitle = lipgloss.NewStyle().
-	Background(lipgloss.Color("62")).
-	Foreground(lipgloss.Color("230"))
-
-var autoYesStyle = lipgloss.NewStyle().
-	Background(lipgloss.Color("#dde4f0")).
-	Foreground(lipgloss.Color("#1a1a1a"))
+const needsAuthIcon = "⚿ "
+const needsAttentionIcon = "! "
+const needsApprovalIcon = "? "
+const goneIcon = "✕ "
+const failedIcon = "⚠ "
 
 type List struct {
 	items         []*session.Instance
@@ -58,6 +27,12 @@ type List struct {
 	height, width int
 	renderer      *InstanceRenderer
 	autoyes       bool
+	// banner is a squad-wide notice shown under the title, if any.
+	banner string
+	// profile is the name of the profile shown in the title, empty for the default one.
+	profile string
+	// blurred dims the title while another pane has the focus.
+	blurred bool
 
 	// map of repo name to number of instances using it. Used to display the repo name only if there are
 	// multiple repos in play.
@@ -80,6 +55,33 @@ func (l *List) SetSize(width, height int) {
 	l.renderer.setWidth(width)
 }
 
+// SetBanner sets the squad-wide notice shown under the title. An empty text hides it.
+func (l *List) SetBanner(text string) {
+	l.banner = text
+}
+
+// SetFocused dims the title of the list unless it has the focus.
+func (l *List) SetFocused(focused bool) {
+	l.blurred = !focused
+}
+
+// SetProfile sets the name of the profile shown in the title. An empty name shows none, for the default
+// profile.
+func (l *List) SetProfile(profile string) {
+	l.profile = profile
+}
+
+// SetDriftThreshold sets the number of commits behind the upstream base branch that flags an instance
+// for a rebase.
+func (l *List) SetDriftThreshold(threshold int) {
+	l.renderer.driftThreshold = threshold
+}
+
+// SetDiffBudget sets the limits on the size of the diffs past which instances are marked.
+func (l *List) SetDiffBudget(budget config.DiffBudget) {
+	l.renderer.diffBudget = budget
+}
+
 // SetSessionPreviewSize sets the height and width for the tmux sessions. This makes the stdout line have the correct
 // width and height.
 func (l *List) SetSessionPreviewSize(width, height int) (err error) {
@@ -104,6 +106,11 @@ func (l *List) NumInstances() int {
 type InstanceRenderer struct {
 	spinner *spinner.Model
 	width   int
+	// driftThreshold is the number of commits behind the upstream base branch that flags an instance
+	// for a rebase.
+	driftThreshold int
+	// diffBudget is the limits on the size of the diffs past which instances are marked.
+	diffBudget config.DiffBudget
 }
 
 func (r *InstanceRenderer) setWidth(width int) {
@@ -113,7 +120,8 @@ func (r *InstanceRenderer) setWidth(width int) {
 // ɹ and ɻ are other options.
 const branchIcon = "Ꮧ"
 
-func (r *InstanceRenderer) Render(i *session.Instance, idx int, selected bool, hasMultipleRepos bool) string {
+// Render renders an instance. depth is the number of instances it is stacked on, which indents it.
+func (r *InstanceRenderer) Render(i *session.Instance, idx int, selected bool, hasMultipleRepos bool, depth int) string {
 	prefix := fmt.Sprintf(" %d. ", idx)
 	if idx >= 10 {
 		prefix = prefix[:len(prefix)-1]
@@ -128,17 +136,32 @@ func (r *InstanceRenderer) Render(i *session.Instance, idx int, selected bool, h
 	// add spinner next to title if it's running
 	var join string
 	switch i.Status {
-	case session.Running:
+	case session.Running, session.Loading:
 		join = fmt.Sprintf("%s ", r.spinner.View())
 	case session.Ready:
 		join = readyStyle.Render(readyIcon)
 	case session.Paused:
 		join = pausedStyle.Render(pausedIcon)
+	case session.NeedsAuth:
+		join = needsAuthStyle.Render(needsAuthIcon)
+	case session.NeedsAttention:
+		join = needsAuthStyle.Render(needsAttentionIcon)
+	case session.NeedsApproval:
+		join = needsAuthStyle.Render(needsApprovalIcon)
+	case session.Gone:
+		join = pausedStyle.Render(goneIcon)
 	default:
 	}
+	// An instance whose last operations failed needs recovering more than its status says.
+	if i.Status != session.Running && i.UnresolvedErrors() > 0 {
+		join = needsAuthStyle.Render(failedIcon)
+	}
 
 	// Cut the title if it's too long
 	titleText := i.Title
+	if depth > 0 {
+		titleText = strings.Repeat("  ", depth-1) + "↳ " + titleText
+	}
 	widthAvail := r.width - 3 - len(prefix) - 1
 	if widthAvail > 0 && widthAvail < len(titleText) && len(titleText) >= widthAvail-3 {
 		titleText = titleText[:widthAvail-3] + "..."
@@ -162,17 +185,30 @@ func (r *InstanceRenderer) Render(i *session.Instance, idx int, selected bool, h
 	} else {
 		addedDiff = fmt.Sprintf("+%d", stat.Added)
 		removedDiff = fmt.Sprintf("-%d ", stat.Removed)
+		addedS, removedS := addedLinesStyle, removedLinesStyle
+		switch i.DiffSize(r.diffBudget) {
+		case session.DiffWarned:
+			addedS, removedS = diffWarnedStyle, diffWarnedStyle
+		case session.DiffFlagged:
+			addedS, removedS = diffFlaggedStyle, diffFlaggedStyle
+		}
 		diff = lipgloss.JoinHorizontal(
 			lipgloss.Center,
-			addedLinesStyle.Background(descS.GetBackground()).Render(addedDiff),
+			addedS.Background(descS.GetBackground()).Render(addedDiff),
 			lipgloss.Style{}.Background(descS.GetBackground()).Foreground(descS.GetForeground()).Render(","),
-			removedLinesStyle.Background(descS.GetBackground()).Render(removedDiff),
+			removedS.Background(descS.GetBackground()).Render(removedDiff),
 		)
 	}
 
+	var usage string
+	if u := i.GetResourceUsage(); u != nil {
+		usage = u.String() + " "
+	}
+
 	remainingWidth := r.width
 	remainingWidth -= len(prefix)
 	remainingWidth -= len(branchIcon)
+	remainingWidth -= len(usage)
 
 	diffWidth := len(addedDiff) + len(removedDiff)
 	if diffWidth > 0 {
@@ -191,6 +227,68 @@ func (r *InstanceRenderer) Render(i *session.Instance, idx int, selected bool, h
 			branch += fmt.Sprintf(" (%s)", repoName)
 		}
 	}
+	if i.Host != "" {
+		branch += " @" + i.Host
+	}
+	if i.Provider != "" {
+		branch += " ⚙ " + i.Provider
+	}
+	if len(i.Conflicts()) > 0 {
+		branch += " ⚠ conflicts"
+	} else if i.BehindParent() {
+		branch += " ↻ restack"
+	} else if upstream := i.Upstream(); upstream.Drifted(r.driftThreshold) {
+		branch += fmt.Sprintf(" ↓%d rebase", upstream.Behind)
+	}
+	switch i.ChecksState() {
+	case session.ChecksRunning:
+		branch += " … checks"
+	case session.ChecksPassed:
+		branch += " ✓ checks"
+	case session.ChecksFailed:
+		branch += " ✗ checks"
+	case session.ChecksStale:
+		branch += " ? checks"
+	}
+	switch i.WarmupState() {
+	case session.WarmupRunning:
+		branch += " … warmup"
+	case session.WarmupReady:
+		branch += " ✓ warm"
+	case session.WarmupFailed:
+		branch += " ✗ warmup"
+	}
+	if progress := i.SetupProgress(); progress != "" {
+		branch += " … " + progress
+	}
+	if i.Queued() {
+		branch += " ⧗ queued"
+	}
+	if i.DiffSize(r.diffBudget) == session.DiffFlagged {
+		branch += " ⚑ split"
+	}
+	if kind, ok := i.HeldPrompt(); ok {
+		branch += " ? approve " + string(kind)
+	}
+	if left, ok := i.TimeLeft(time.Now()); ok {
+		branch += " ⏱ " + session.FormatBudget(left)
+		if i.WrappedUp {
+			branch += " wrap-up"
+		}
+	}
+	if i.WaitingForResources && !i.Paused() {
+		branch += " ⧗ " + strings.Join(i.ExternalResources, ", ")
+	}
+	if n := i.OpenTodos(); n == 1 {
+		branch += " ☐ 1 todo"
+	} else if n > 1 {
+		branch += fmt.Sprintf(" ☐ %d todos", n)
+	}
+	if n := i.UnresolvedErrors(); n == 1 {
+		branch += " ⚠ 1 error"
+	} else if n > 1 {
+		branch += fmt.Sprintf(" ⚠ %d errors", n)
+	}
 	// Don't show branch if there's no space for it. Or show ellipsis if it's too long.
 	if remainingWidth < 0 {
 		branch = ""
@@ -210,7 +308,7 @@ func (r *InstanceRenderer) Render(i *session.Instance, idx int, selected bool, h
 		spaces = strings.Repeat(" ", remainingWidth)
 	}
 
-	branchLine := fmt.Sprintf("%s %s-%s%s%s", strings.Repeat(" ", len(prefix)), branchIcon, branch, spaces, diff)
+	branchLine := fmt.Sprintf("%s %s-%s%s%s%s", strings.Repeat(" ", len(prefix)), branchIcon, branch, spaces, usage, diff)
 
 	// join title and subtitle
 	text := lipgloss.JoinVertical(
@@ -223,9 +321,17 @@ func (r *InstanceRenderer) Render(i *session.Instance, idx int, selected bool, h
 }
 
 func (l *List) String() string {
-	const titleText = " Instances "
+	titleText := " Instances "
+	if l.profile != "" {
+		titleText = fmt.Sprintf(" Instances (%s) ", l.profile)
+	}
 	const autoYesText = " auto-yes "
 
+	title := mainTitle
+	if l.blurred {
+		title = blurredTitle
+	}
+
 	// Write the title.
 	var b strings.Builder
 	b.WriteString("\n")
@@ -236,10 +342,10 @@ func (l *List) String() string {
 	titleWidth := AdjustPreviewWidth(l.width) + 2
 	if !l.autoyes {
 		b.WriteString(lipgloss.Place(
-			titleWidth, 1, lipgloss.Left, lipgloss.Bottom, mainTitle.Render(titleText)))
+			titleWidth, 1, lipgloss.Left, lipgloss.Bottom, title.Render(titleText)))
 	} else {
 		title := lipgloss.Place(
-			titleWidth/2, 1, lipgloss.Left, lipgloss.Bottom, mainTitle.Render(titleText))
+			titleWidth/2, 1, lipgloss.Left, lipgloss.Bottom, title.Render(titleText))
 		autoYes := lipgloss.Place(
 			titleWidth-(titleWidth/2), 1, lipgloss.Right, lipgloss.Bottom, autoYesStyle.Render(autoYesText))
 		b.WriteString(lipgloss.JoinHorizontal(
@@ -247,11 +353,15 @@ func (l *List) String() string {
 	}
 
 	b.WriteString("\n")
+	if l.banner != "" {
+		b.WriteString(bannerStyle.Render(l.banner))
+		b.WriteString("\n")
+	}
 	b.WriteString("\n")
 
 	// Render the list.
 	for i, item := range l.items {
-		b.WriteString(l.renderer.Render(item, i+1, i == l.selectedIdx, len(l.repos) > 1))
+		b.WriteString(l.renderer.Render(item, i+1, i == l.selectedIdx, len(l.repos) > 1, session.StackDepth(item, l.items)))
 		if i != len(l.items)-1 {
 			b.WriteString("\n\n")
 		}
@@ -298,6 +408,36 @@ func (l *List) Kill() {
 	l.items = append(l.items[:l.selectedIdx], l.items[l.selectedIdx+1:]...)
 }
 
+// KillInstance kills the given instance and removes it from the list.
+func (l *List) KillInstance(instance *session.Instance) {
+	for idx, item := range l.items {
+		if item == instance {
+			l.selectedIdx = idx
+			l.Kill()
+			return
+		}
+	}
+}
+
+// RemoveInstance removes the given instance from the list without killing it.
+func (l *List) RemoveInstance(instance *session.Instance) {
+	for idx, item := range l.items {
+		if item != instance {
+			continue
+		}
+		if repoName, err := instance.RepoName(); err != nil {
+			log.ErrorLog.Printf("could not get repo name: %v", err)
+		} else {
+			l.rmRepo(repoName)
+		}
+		l.items = append(l.items[:idx], l.items[idx+1:]...)
+		if l.selectedIdx >= len(l.items) && l.selectedIdx > 0 {
+			l.selectedIdx--
+		}
+		return
+	}
+}
+
 func (l *List) Attach() (chan struct{}, error) {
 	targetInstance := l.items[l.selectedIdx]
 	return targetInstance.Attach()
@@ -9,29 +9,9 @@ import (
 	"github.com/charmbracelet/lipgloss"
 )
 
-var keyStyle = lipgloss.NewStyle().Foreground(lipgloss.AdaptiveColor{
-	Light: "#655F5F",
-	Dark:  "#7F7A7A",
-})
-
-var descStyle = lipgloss.NewStyle().Foreground(lipgloss.AdaptiveColor{
-	Light: "#7A7474",
-	Dark:  "#9C9494",
-})
-
-var sepStyle = lipgloss.NewStyle().Foreground(lipgloss.AdaptiveColor{
-	Light: "#DDDADA",
-	Dark:  "#3C3C3C",
-})
-
-var actionGroupStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("99"))
-
 var separator = " • "
 var verticalSeparator = " │ "
 
-var menuStyle = lipgloss.NewStyle().
-	Foreground(lipgloss.Color("205"))
-
 // MenuState represents different states the menu can be in
 type MenuState int
 
@@ -54,7 +34,7 @@ type Menu struct {
 }
 
 var defaultMenuOptions = []keys.KeyName{keys.KeyNew, keys.KeyPrompt, keys.KeyClaudeResume, keys.KeyHelp, keys.KeyQuit}
-var newInstanceMenuOptions = []keys.KeyName{keys.KeySubmitName}
+var newInstanceMenuOptions = []keys.KeyName{keys.KeySubmitName, keys.KeyNextProvider}
 var promptMenuOptions = []keys.KeyName{keys.KeySubmitName}
 
 func NewMenu() *Menu {
@@ -131,6 +111,9 @@ func (m *Menu) addInstanceOptions() {
 	} else {
 		actionGroup = append(actionGroup, keys.KeyCheckout)
 	}
+	if m.instance.UnresolvedErrors() > 0 {
+		actionGroup = append(actionGroup, keys.KeyErrors)
+	}
 
 	// Navigation group (when in diff tab)
 	if m.isInDiffTab {
@@ -0,0 +1,63 @@
+package ui
+
+import (
+	"claude-squad/session"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// NotesPane shows the notes and the TODOs of the selected instance.
+type NotesPane struct {
+	viewport viewport.Model
+	width    int
+	height   int
+}
+
+func NewNotesPane() *NotesPane {
+	return &NotesPane{
+		viewport: viewport.New(0, 0),
+	}
+}
+
+func (n *NotesPane) SetSize(width, height int) {
+	n.width = width
+	n.height = height
+	n.viewport.Width = width
+	n.viewport.Height = height
+}
+
+// SetNotes shows the notes and TODOs of the instance, which may be nil.
+func (n *NotesPane) SetNotes(instance *session.Instance) {
+	if instance == nil || (instance.Notes == "" && len(instance.Todos) == 0) {
+		n.viewport.SetContent(lipgloss.Place(n.width, n.height, lipgloss.Center, lipgloss.Center,
+			"No notes. Press 'm' to write notes and TODOs, one per line starting with '- [ ]'."))
+		return
+	}
+
+	var lines []string
+	if len(instance.Todos) > 0 {
+		done := len(instance.Todos) - instance.OpenTodos()
+		lines = append(lines, HunkStyle.Render(fmt.Sprintf("TODOs %d/%d done", done, len(instance.Todos))))
+		for _, todo := range instance.Todos {
+			if todo.Done {
+				lines = append(lines, "[x] "+todoDoneStyle.Render(todo.Text))
+			} else {
+				lines = append(lines, "[ ] "+todo.Text)
+			}
+		}
+	}
+	if instance.Notes != "" {
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, lipgloss.NewStyle().Width(n.width).Render(instance.Notes))
+	}
+	n.viewport.SetContent(strings.Join(lines, "\n"))
+}
+
+func (n *NotesPane) String() string {
+	return n.viewport.View()
+}
@@ -0,0 +1,163 @@
+package overlay
+
+import (
+	"claude-squad/ui/theme"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// ComparisonColumn is a single column of the comparison overlay
+type ComparisonColumn struct {
+	// Header is rendered at the top of the column and never scrolls
+	Header string
+	// Body is rendered below the header and scrolls
+	Body string
+}
+
+// ComparisonOverlay shows several columns side by side and lets the user pick one of them
+type ComparisonOverlay struct {
+	// Title is displayed above the columns
+	Title string
+	// Whether the overlay has been dismissed
+	Dismissed bool
+	// Whether a column was picked (as opposed to the overlay being canceled)
+	Submitted bool
+	// Columns to compare
+	columns []ComparisonColumn
+	// Index of the highlighted column
+	selectedIdx int
+	// offset is the number of body lines scrolled past
+	offset        int
+	width, height int
+}
+
+// NewComparisonOverlay creates a new comparison overlay with the given title and columns
+func NewComparisonOverlay(title string, columns []ComparisonColumn) *ComparisonOverlay {
+	return &ComparisonOverlay{
+		Title:   title,
+		columns: columns,
+		width:   100,
+		height:  30,
+	}
+}
+
+// HandleKeyPress processes a key press and updates the state
+// Returns true if the overlay should be closed
+func (c *ComparisonOverlay) HandleKeyPress(msg tea.KeyMsg) bool {
+	switch msg.String() {
+	case "left", "h", "shift+tab":
+		if c.selectedIdx > 0 {
+			c.selectedIdx--
+		}
+	case "right", "l", "tab":
+		if c.selectedIdx < len(c.columns)-1 {
+			c.selectedIdx++
+		}
+	case "up", "k":
+		if c.offset > 0 {
+			c.offset--
+		}
+	case "down", "j":
+		c.offset++
+	case "enter":
+		if len(c.columns) == 0 {
+			return false
+		}
+		c.Dismissed = true
+		c.Submitted = true
+		return true
+	case "esc", "q":
+		c.Dismissed = true
+		return true
+	}
+	return false
+}
+
+// GetSelectedIndex returns the index of the highlighted column
+func (c *ComparisonOverlay) GetSelectedIndex() int {
+	return c.selectedIdx
+}
+
+// SetSize sets the size of the comparison overlay
+func (c *ComparisonOverlay) SetSize(width, height int) {
+	c.width = width
+	c.height = height
+}
+
+// Render renders the comparison overlay
+func (c *ComparisonOverlay) Render(opts ...WhitespaceOption) string {
+	style := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(theme.Current().Border).
+		Padding(0, 1)
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(theme.Current().Accent).
+		Bold(true)
+
+	hintStyle := lipgloss.NewStyle().
+		Foreground(theme.Current().Muted)
+
+	if len(c.columns) == 0 {
+		return style.Render(titleStyle.Render(c.Title) + "\n\nNothing to compare")
+	}
+
+	// Account for the outer border and padding, and the border of each column.
+	columnWidth := (c.width-4)/len(c.columns) - 2
+	if columnWidth < 10 {
+		columnWidth = 10
+	}
+	// Account for the title, the hint and the borders.
+	bodyHeight := c.height - 8
+
+	rendered := make([]string, len(c.columns))
+	for i, column := range c.columns {
+		columnStyle := lipgloss.NewStyle().
+			Border(lipgloss.NormalBorder()).
+			BorderForeground(theme.Current().Muted).
+			Width(columnWidth)
+		if i == c.selectedIdx {
+			columnStyle = columnStyle.
+				Border(lipgloss.ThickBorder()).
+				BorderForeground(theme.Current().Border)
+		}
+
+		headerLines := strings.Split(column.Header, "\n")
+		bodyLines := strings.Split(column.Body, "\n")
+		offset := c.offset
+		if offset > len(bodyLines) {
+			offset = len(bodyLines)
+		}
+		bodyLines = bodyLines[offset:]
+		if visible := bodyHeight - len(headerLines) - 1; visible >= 0 && len(bodyLines) > visible {
+			bodyLines = bodyLines[:visible]
+		}
+
+		var lines []string
+		for _, line := range headerLines {
+			lines = append(lines, truncateLine(line, columnWidth))
+		}
+		lines = append(lines, strings.Repeat("─", columnWidth))
+		for _, line := range bodyLines {
+			lines = append(lines, truncateLine(line, columnWidth))
+		}
+		rendered[i] = columnStyle.Render(strings.Join(lines, "\n"))
+	}
+
+	content := lipgloss.JoinVertical(lipgloss.Left,
+		titleStyle.Render(c.Title),
+		lipgloss.JoinHorizontal(lipgloss.Top, rendered...),
+		hintStyle.Render("←/→ to choose • ↑/↓ to scroll • enter to keep • esc to cancel"),
+	)
+	return style.Render(content)
+}
+
+// truncateLine cuts a possibly styled line to the given printable width.
+func truncateLine(line string, width int) string {
+	if lipgloss.Width(line) <= width {
+		return line
+	}
+	return lipgloss.NewStyle().MaxWidth(width).Render(line)
+}
@@ -1,6 +1,8 @@
 package overlay
 
 import (
+	"claude-squad/ui/theme"
+
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
@@ -22,7 +24,7 @@ type ConfirmationOverlay struct {
 	// Custom cancel key (defaults to 'n')
 	CancelKey string
 	// Custom styling options
-	borderColor lipgloss.Color
+	borderColor lipgloss.TerminalColor
 }
 
 // NewConfirmationOverlay creates a new confirmation dialog overlay with the given message
@@ -33,7 +35,7 @@ func NewConfirmationOverlay(message string) *ConfirmationOverlay {
 		width:       50, // Default width
 		ConfirmKey:  "y",
 		CancelKey:   "n",
-		borderColor: lipgloss.Color("#de613e"), // Red color for confirmations
+		borderColor: theme.Current().Danger, // Red color for confirmations
 	}
 }
 
@@ -83,7 +85,7 @@ func (c *ConfirmationOverlay) SetWidth(width int) {
 }
 
 // SetBorderColor sets the border color of the confirmation overlay
-func (c *ConfirmationOverlay) SetBorderColor(color lipgloss.Color) {
+func (c *ConfirmationOverlay) SetBorderColor(color lipgloss.TerminalColor) {
 	c.borderColor = color
 }
 
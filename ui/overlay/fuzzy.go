@@ -0,0 +1,70 @@
+package overlay
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Bonuses and penalty of the fuzzy match score: matches at the start of a word or right after the
+// previous match rank higher, and every character skipped between matches ranks lower.
+const (
+	fuzzyWordStartBonus   = 8
+	fuzzyConsecutiveBonus = 5
+	fuzzyGapPenalty       = 1
+)
+
+// FuzzyScore returns how well query matches text, if all the characters of query appear in text in
+// order, ignoring case. Higher is better.
+func FuzzyScore(query, text string) (int, bool) {
+	query = strings.ToLower(query)
+	runes := []rune(strings.ToLower(text))
+	score := 0
+	pos := 0
+	last := -1
+	for _, q := range query {
+		if unicode.IsSpace(q) {
+			continue
+		}
+		found := -1
+		for k := pos; k < len(runes); k++ {
+			if runes[k] == q {
+				found = k
+				break
+			}
+		}
+		if found < 0 {
+			return 0, false
+		}
+		score++
+		switch {
+		case found == last+1 && last >= 0:
+			score += fuzzyConsecutiveBonus
+		case found == 0 || !unicode.IsLetter(runes[found-1]) && !unicode.IsDigit(runes[found-1]):
+			score += fuzzyWordStartBonus
+		}
+		if last >= 0 {
+			score -= (found - last - 1) * fuzzyGapPenalty
+		}
+		last = found
+		pos = found + 1
+	}
+	return score, true
+}
+
+// FuzzyFilter returns the indexes of the items matching query, best matches first. Items matching equally
+// well keep their order, so all of them are returned in order for an empty query.
+func FuzzyFilter(query string, items []string) []int {
+	indexes := make([]int, 0, len(items))
+	scores := make(map[int]int, len(items))
+	for idx, item := range items {
+		if score, ok := FuzzyScore(query, item); ok {
+			indexes = append(indexes, idx)
+			scores[idx] = score
+		}
+	}
+	sort.SliceStable(indexes, func(a, b int) bool {
+		return scores[indexes[a]] > scores[indexes[b]]
+	})
+	return indexes
+}
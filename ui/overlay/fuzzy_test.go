@@ -0,0 +1,46 @@
+package overlay
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	_, ok := FuzzyScore("fxlg", "fix-login")
+	assert.True(t, ok)
+	_, ok = FuzzyScore("FixLogin", "fix-login")
+	assert.True(t, ok, "case and missing separators are ignored")
+	_, ok = FuzzyScore("lgf", "fix-login")
+	assert.False(t, ok, "the characters have to be in order")
+
+	prefix, _ := FuzzyScore("log", "login-page")
+	scattered, _ := FuzzyScore("log", "lint-docs-generator")
+	assert.Greater(t, prefix, scattered)
+	wordStart, _ := FuzzyScore("api", "fix the api client")
+	inWord, _ := FuzzyScore("api", "rapid fix")
+	assert.Greater(t, wordStart, inWord)
+}
+
+func TestFuzzyFilter(t *testing.T) {
+	items := []string{"refactor-parser  ⎇ cs/refactor-parser", "fix-login  ⎇ cs/fix-login", "action: kill - kill (D)"}
+	assert.Equal(t, []int{0, 1, 2}, FuzzyFilter("", items))
+	assert.Equal(t, []int{1}, FuzzyFilter("fix log", items))
+	assert.Equal(t, []int{2}, FuzzyFilter("kill", items))
+}
+
+func TestFilterSelectionOverlay(t *testing.T) {
+	s := NewFilterSelectionOverlay("Find", []string{"alpha", "beta", "gamma"})
+	for _, r := range "ga" {
+		s.HandleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	assert.Equal(t, 2, s.GetSelectedIndex())
+	s.HandleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'z'}})
+	assert.Equal(t, -1, s.GetSelectedIndex())
+	assert.False(t, s.HandleKeyPress(tea.KeyMsg{Type: tea.KeyEnter}), "nothing to submit")
+	s.HandleKeyPress(tea.KeyMsg{Type: tea.KeyBackspace})
+	assert.True(t, s.HandleKeyPress(tea.KeyMsg{Type: tea.KeyEnter}))
+	assert.True(t, s.Submitted)
+	assert.Equal(t, 2, s.GetSelectedIndex())
+}
@@ -0,0 +1,148 @@
+package overlay
+
+import (
+	"claude-squad/ui/theme"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// ObserveOverlay mirrors the pane of an instance, with its whole scrollback, without ever sending it
+// input. It follows the output as it comes until it's scrolled up.
+type ObserveOverlay struct {
+	// Title is displayed above the pane
+	Title string
+	// lines are the lines of the pane, its scrollback included
+	lines []string
+	// offset is the index of the first line shown
+	offset int
+	// follow keeps the last lines shown as new output comes
+	follow        bool
+	width, height int
+}
+
+// NewObserveOverlay creates a new observe overlay with the given title, following the output.
+func NewObserveOverlay(title string) *ObserveOverlay {
+	return &ObserveOverlay{
+		Title:  title,
+		follow: true,
+		width:  100,
+		height: 30,
+	}
+}
+
+// SetContent replaces the content of the pane, keeping the scroll position unless it follows the output.
+func (o *ObserveOverlay) SetContent(content string) {
+	o.lines = strings.Split(strings.TrimRight(content, "\n"), "\n")
+	o.clamp()
+}
+
+// SetSize sets the size of the observe overlay
+func (o *ObserveOverlay) SetSize(width, height int) {
+	o.width = width
+	o.height = height
+	o.clamp()
+}
+
+// bodyHeight is how many lines of the pane are shown, without the title, the hint and the border.
+func (o *ObserveOverlay) bodyHeight() int {
+	if height := o.height - 4; height > 1 {
+		return height
+	}
+	return 1
+}
+
+// maxOffset is the offset that shows the last lines of the pane.
+func (o *ObserveOverlay) maxOffset() int {
+	if n := len(o.lines) - o.bodyHeight(); n > 0 {
+		return n
+	}
+	return 0
+}
+
+func (o *ObserveOverlay) clamp() {
+	if o.follow || o.offset > o.maxOffset() {
+		o.offset = o.maxOffset()
+	}
+	if o.offset < 0 {
+		o.offset = 0
+	}
+}
+
+// Scroll moves the view by delta lines, following the output again once it reaches the bottom.
+func (o *ObserveOverlay) Scroll(delta int) {
+	o.offset += delta
+	o.follow = false
+	if o.offset >= o.maxOffset() {
+		o.follow = true
+	}
+	o.clamp()
+}
+
+// HandleKeyPress processes a key press and updates the state. Keys other than the ones scrolling or
+// closing the overlay are ignored. Returns true if the overlay should be closed.
+func (o *ObserveOverlay) HandleKeyPress(msg tea.KeyMsg) bool {
+	switch msg.String() {
+	case "up", "k":
+		o.Scroll(-1)
+	case "down", "j":
+		o.Scroll(1)
+	case "pgup", "ctrl+u":
+		o.Scroll(-o.bodyHeight())
+	case "pgdown", "ctrl+d", " ":
+		o.Scroll(o.bodyHeight())
+	case "home", "g":
+		o.Scroll(-len(o.lines))
+	case "end", "G":
+		o.Scroll(len(o.lines))
+	case "esc", "q":
+		return true
+	}
+	return false
+}
+
+// Render renders the observe overlay
+func (o *ObserveOverlay) Render(opts ...WhitespaceOption) string {
+	style := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(theme.Current().Border).
+		Padding(0, 1)
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(theme.Current().Accent).
+		Bold(true)
+
+	hintStyle := lipgloss.NewStyle().
+		Foreground(theme.Current().Muted)
+
+	// Account for the border and the padding.
+	width := o.width - 4
+	if width < 10 {
+		width = 10
+	}
+	end := o.offset + o.bodyHeight()
+	if end > len(o.lines) {
+		end = len(o.lines)
+	}
+	body := make([]string, 0, o.bodyHeight())
+	for _, line := range o.lines[o.offset:end] {
+		body = append(body, truncateLine(line, width))
+	}
+	for len(body) < o.bodyHeight() {
+		body = append(body, "")
+	}
+
+	position := "following"
+	if !o.follow {
+		position = fmt.Sprintf("line %d/%d", end, len(o.lines))
+	}
+	title := titleStyle.Render(o.Title) + hintStyle.Render(" · read-only · "+position)
+	content := lipgloss.JoinVertical(lipgloss.Left,
+		title,
+		strings.Join(body, "\n"),
+		hintStyle.Render("↑/↓ pgup/pgdn to scroll • g/G for top/bottom • esc to close"),
+	)
+	return style.Render(content)
+}
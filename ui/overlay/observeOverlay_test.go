@@ -0,0 +1,42 @@
+package overlay
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObserveOverlayFollows(t *testing.T) {
+	lines := func(n int) string {
+		var b strings.Builder
+		for i := 1; i <= n; i++ {
+			fmt.Fprintf(&b, "line %d\n", i)
+		}
+		return b.String()
+	}
+	o := NewObserveOverlay("Observing test")
+	o.SetSize(80, 14) // 10 lines of the pane are shown.
+
+	o.SetContent(lines(50))
+	assert.Equal(t, 40, o.offset)
+	o.SetContent(lines(60))
+	assert.Equal(t, 50, o.offset, "the last lines are shown as output comes")
+
+	// Scrolling up stops following, so new output doesn't move the view.
+	o.HandleKeyPress(tea.KeyMsg{Type: tea.KeyPgUp})
+	assert.Equal(t, 40, o.offset)
+	o.SetContent(lines(70))
+	assert.Equal(t, 40, o.offset)
+	assert.Contains(t, o.Render(), "line 50/70")
+
+	// Going back to the bottom follows again.
+	o.HandleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("G")})
+	o.SetContent(lines(80))
+	assert.Equal(t, 70, o.offset)
+
+	assert.False(t, o.HandleKeyPress(tea.KeyMsg{Type: tea.KeyEnter}), "other keys are ignored")
+	assert.True(t, o.HandleKeyPress(tea.KeyMsg{Type: tea.KeyEsc}))
+}
@@ -0,0 +1,213 @@
+package overlay
+
+import (
+	"claude-squad/ui/theme"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// ReviewAction is what the user asked for when the review overlay closed
+type ReviewAction int
+
+const (
+	// ReviewClose closes the review, keeping the comments
+	ReviewClose ReviewAction = iota
+	// ReviewComment comments on the line under the cursor
+	ReviewComment
+	// ReviewDelete deletes the comment on the line under the cursor
+	ReviewDelete
+	// ReviewSubmit sends the comments
+	ReviewSubmit
+)
+
+// ReviewLine is a line of the diff shown in the review overlay
+type ReviewLine struct {
+	// Text is the line, possibly styled
+	Text string
+	// Commentable is true if the line can be commented on
+	Commentable bool
+	// Comment is the comment on the line, if any
+	Comment string
+}
+
+// ReviewOverlay shows a diff with a cursor to pick the lines to comment on
+type ReviewOverlay struct {
+	// Title is displayed above the diff
+	Title string
+	// Action is what the user asked for when the overlay closed
+	Action ReviewAction
+	// Lines of the diff
+	lines []ReviewLine
+	// cursor is the index of the line under the cursor
+	cursor int
+	// offset is the index of the first line shown
+	offset        int
+	width, height int
+}
+
+// NewReviewOverlay creates a new review overlay with the cursor on the first line that can be commented
+// on
+func NewReviewOverlay(title string, lines []ReviewLine) *ReviewOverlay {
+	r := &ReviewOverlay{
+		Title:  title,
+		lines:  lines,
+		width:  100,
+		height: 30,
+	}
+	r.SetCursor(0)
+	return r
+}
+
+// SetCursor moves the cursor to the line at idx, or the next one that can be commented on
+func (r *ReviewOverlay) SetCursor(idx int) {
+	if next := r.nextCommentable(idx, 1); next >= 0 {
+		r.cursor = next
+	} else if prev := r.nextCommentable(idx, -1); prev >= 0 {
+		r.cursor = prev
+	}
+}
+
+// GetCursor returns the index of the line under the cursor
+func (r *ReviewOverlay) GetCursor() int {
+	return r.cursor
+}
+
+// nextCommentable returns the index of the first line that can be commented on from idx in the
+// direction step, or -1 if there is none
+func (r *ReviewOverlay) nextCommentable(idx int, step int) int {
+	for ; idx >= 0 && idx < len(r.lines); idx += step {
+		if r.lines[idx].Commentable {
+			return idx
+		}
+	}
+	return -1
+}
+
+// move moves the cursor by count commentable lines in the direction step
+func (r *ReviewOverlay) move(step int, count int) {
+	for ; count > 0; count-- {
+		next := r.nextCommentable(r.cursor+step, step)
+		if next < 0 {
+			return
+		}
+		r.cursor = next
+	}
+}
+
+// HandleKeyPress processes a key press and updates the state
+// Returns true if the overlay should be closed
+func (r *ReviewOverlay) HandleKeyPress(msg tea.KeyMsg) bool {
+	switch msg.String() {
+	case "up", "k":
+		r.move(-1, 1)
+	case "down", "j":
+		r.move(1, 1)
+	case "pgup", "shift+up":
+		r.move(-1, r.bodyHeight()/2)
+	case "pgdown", "shift+down":
+		r.move(1, r.bodyHeight()/2)
+	case "enter", "c":
+		if r.nextCommentable(r.cursor, 1) != r.cursor {
+			return false
+		}
+		r.Action = ReviewComment
+		return true
+	case "d":
+		if r.cursor >= len(r.lines) || r.lines[r.cursor].Comment == "" {
+			return false
+		}
+		r.Action = ReviewDelete
+		return true
+	case "s":
+		r.Action = ReviewSubmit
+		return true
+	case "esc", "q":
+		r.Action = ReviewClose
+		return true
+	}
+	return false
+}
+
+// SetSize sets the size of the review overlay
+func (r *ReviewOverlay) SetSize(width, height int) {
+	r.width = width
+	r.height = height
+}
+
+// bodyHeight is the number of diff lines that fit, accounting for the title, the hint and the border
+func (r *ReviewOverlay) bodyHeight() int {
+	if height := r.height - 6; height > 1 {
+		return height
+	}
+	return 1
+}
+
+// Render renders the review overlay
+func (r *ReviewOverlay) Render(opts ...WhitespaceOption) string {
+	style := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(theme.Current().Border).
+		Padding(0, 1)
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(theme.Current().Accent).
+		Bold(true)
+
+	hintStyle := lipgloss.NewStyle().
+		Foreground(theme.Current().Muted)
+
+	cursorStyle := lipgloss.NewStyle().
+		Foreground(theme.Current().Accent).
+		Bold(true)
+
+	commentStyle := lipgloss.NewStyle().
+		Foreground(theme.Current().Warning)
+
+	// Account for the border, the padding and the cursor column.
+	lineWidth := r.width - 6
+	if lineWidth < 10 {
+		lineWidth = 10
+	}
+
+	// Render the lines with their comments, then scroll to keep the cursor in view.
+	var rendered []string
+	cursorRow := 0
+	for i, line := range r.lines {
+		marker := "  "
+		if i == r.cursor {
+			marker = cursorStyle.Render("▶ ")
+			cursorRow = len(rendered)
+		}
+		rendered = append(rendered, marker+truncateLine(line.Text, lineWidth))
+		if line.Comment != "" {
+			for _, commentLine := range strings.Split(line.Comment, "\n") {
+				rendered = append(rendered, "  "+commentStyle.Render(truncateLine("  💬 "+commentLine, lineWidth)))
+			}
+		}
+	}
+
+	height := r.bodyHeight()
+	if cursorRow < r.offset {
+		r.offset = cursorRow
+	} else if cursorRow >= r.offset+height {
+		r.offset = cursorRow - height + 1
+	}
+	if r.offset > len(rendered) {
+		r.offset = len(rendered)
+	}
+	visible := rendered[r.offset:]
+	if len(visible) > height {
+		visible = visible[:height]
+	}
+
+	content := lipgloss.JoinVertical(lipgloss.Left,
+		titleStyle.Render(r.Title),
+		"",
+		strings.Join(visible, "\n"),
+		"",
+		hintStyle.Render("↑/↓ to move • enter/c to comment • d to delete • s to send • esc to close"),
+	)
+	return style.Render(content)
+}
@@ -0,0 +1,209 @@
+package overlay
+
+import (
+	"claude-squad/ui/theme"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+)
+
+// SelectionOverlay represents a list picker overlay
+type SelectionOverlay struct {
+	// Title is displayed above the items
+	Title string
+	// Whether the overlay has been dismissed
+	Dismissed bool
+	// Whether an item was selected (as opposed to the overlay being canceled)
+	Submitted bool
+	// Callback function to be called with the index of the selected item
+	OnSelect func(idx int)
+	// Items to choose from
+	items []string
+	// visible are the indexes of the items matching the query, in the order they're shown
+	visible []int
+	// Index of the highlighted item among the visible ones
+	selectedIdx int
+	// filterable is true if typing filters the items by fuzzy matching instead of moving
+	filterable bool
+	// query is what was typed to filter the items
+	query string
+	// Width of the overlay
+	width int
+	// maxVisible is the maximum number of items rendered at once
+	maxVisible int
+}
+
+// NewSelectionOverlay creates a new selection overlay with the given title and items
+func NewSelectionOverlay(title string, items []string) *SelectionOverlay {
+	s := &SelectionOverlay{
+		Title:      title,
+		items:      items,
+		width:      60,
+		maxVisible: 10,
+	}
+	s.filter()
+	return s
+}
+
+// NewFilterSelectionOverlay creates a selection overlay whose items are filtered by fuzzy matching what's
+// typed, best matches first.
+func NewFilterSelectionOverlay(title string, items []string) *SelectionOverlay {
+	s := NewSelectionOverlay(title, items)
+	s.filterable = true
+	return s
+}
+
+// filter updates the visible items to the ones matching the query and resets the highlight.
+func (s *SelectionOverlay) filter() {
+	s.visible = FuzzyFilter(s.query, s.items)
+	s.selectedIdx = 0
+}
+
+// HandleKeyPress processes a key press and updates the state
+// Returns true if the overlay should be closed
+func (s *SelectionOverlay) HandleKeyPress(msg tea.KeyMsg) bool {
+	if s.filterable {
+		return s.handleFilterKeyPress(msg)
+	}
+	switch msg.String() {
+	case "up", "k", "shift+tab":
+		if s.selectedIdx > 0 {
+			s.selectedIdx--
+		}
+		return false
+	case "down", "j", "tab":
+		if s.selectedIdx < len(s.visible)-1 {
+			s.selectedIdx++
+		}
+		return false
+	case "enter":
+		return s.submit()
+	case "esc", "q":
+		s.Dismissed = true
+		return true
+	default:
+		return false
+	}
+}
+
+// handleFilterKeyPress handles a key press of a filterable overlay, where letters are typed into the query.
+func (s *SelectionOverlay) handleFilterKeyPress(msg tea.KeyMsg) bool {
+	switch msg.Type {
+	case tea.KeyUp, tea.KeyShiftTab, tea.KeyCtrlP:
+		if s.selectedIdx > 0 {
+			s.selectedIdx--
+		}
+	case tea.KeyDown, tea.KeyTab, tea.KeyCtrlN:
+		if s.selectedIdx < len(s.visible)-1 {
+			s.selectedIdx++
+		}
+	case tea.KeyEnter:
+		return s.submit()
+	case tea.KeyEsc, tea.KeyCtrlC:
+		s.Dismissed = true
+		return true
+	case tea.KeyBackspace:
+		if s.query != "" {
+			runes := []rune(s.query)
+			s.query = string(runes[:len(runes)-1])
+			s.filter()
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		s.query += string(msg.Runes)
+		s.filter()
+	}
+	return false
+}
+
+func (s *SelectionOverlay) submit() bool {
+	if len(s.visible) == 0 {
+		return false
+	}
+	s.Dismissed = true
+	s.Submitted = true
+	if s.OnSelect != nil {
+		s.OnSelect(s.GetSelectedIndex())
+	}
+	return true
+}
+
+// GetSelectedIndex returns the index of the highlighted item among all the items, -1 if none matches the
+// query.
+func (s *SelectionOverlay) GetSelectedIndex() int {
+	if len(s.visible) == 0 {
+		return -1
+	}
+	return s.visible[s.selectedIdx]
+}
+
+// Render renders the selection overlay
+func (s *SelectionOverlay) Render(opts ...WhitespaceOption) string {
+	style := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(theme.Current().Border).
+		Padding(1, 2).
+		Width(s.width)
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(theme.Current().Accent).
+		Bold(true).
+		MarginBottom(1)
+
+	selectedStyle := lipgloss.NewStyle().
+		Background(theme.Current().Accent).
+		Foreground(theme.Current().AccentText)
+
+	hintStyle := lipgloss.NewStyle().
+		Foreground(theme.Current().Muted)
+
+	// Keep the selected item in view by scrolling the window of visible items.
+	start := 0
+	if s.selectedIdx >= s.maxVisible {
+		start = s.selectedIdx - s.maxVisible + 1
+	}
+	end := start + s.maxVisible
+	if end > len(s.visible) {
+		end = len(s.visible)
+	}
+
+	// Leave room for the border and padding.
+	itemWidth := s.width - 6
+
+	var b strings.Builder
+	b.WriteString(titleStyle.Render(s.Title) + "\n")
+	if s.filterable {
+		b.WriteString("> " + s.query + "\n\n")
+	}
+	if len(s.items) == 0 {
+		b.WriteString(hintStyle.Render("Nothing to select") + "\n")
+	} else if len(s.visible) == 0 {
+		b.WriteString(hintStyle.Render("Nothing matches") + "\n")
+	}
+	for i := start; i < end; i++ {
+		item := s.items[s.visible[i]]
+		if itemWidth > 3 {
+			// Cut by display width, so wide characters aren't split or overflow the box.
+			item = runewidth.Truncate(item, itemWidth, "...")
+		}
+		if i == s.selectedIdx {
+			b.WriteString(selectedStyle.Render(item))
+		} else {
+			b.WriteString(item)
+		}
+		b.WriteString("\n")
+	}
+	if s.filterable {
+		b.WriteString("\n" + hintStyle.Render("type to filter • ↑/↓ to move • enter to select • esc to cancel"))
+	} else {
+		b.WriteString("\n" + hintStyle.Render("↑/↓ to move • enter to select • esc to cancel"))
+	}
+
+	return style.Render(b.String())
+}
+
+// SetWidth sets the width of the selection overlay
+func (s *SelectionOverlay) SetWidth(width int) {
+	s.width = width
+}
@@ -0,0 +1,25 @@
+package overlay
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectionOverlayCutsLongItems(t *testing.T) {
+	s := NewSelectionOverlay("Pick an issue", []string{
+		"#42 " + strings.Repeat("é", 60),
+		"#43 " + strings.Repeat("界", 40),
+		"#44 short",
+	})
+	s.SetWidth(30) // Items get 24 columns.
+
+	rendered := s.Render()
+	assert.True(t, utf8.ValidString(rendered))
+	assert.Contains(t, rendered, "#42 "+strings.Repeat("é", 17)+"...")
+	// Wide characters take two columns each.
+	assert.Contains(t, rendered, "#43 "+strings.Repeat("界", 8)+"...")
+	assert.Contains(t, rendered, "#44 short")
+}
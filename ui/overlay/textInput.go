@@ -1,6 +1,8 @@
 package overlay
 
 import (
+	"claude-squad/ui/theme"
+
 	"github.com/charmbracelet/bubbles/textarea"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -124,21 +126,21 @@ func (t *TextInputOverlay) Render() string {
 	// Create styles
 	style := lipgloss.NewStyle().
 		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("62")).
+		BorderForeground(theme.Current().Border).
 		Padding(1, 2)
 
 	titleStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("62")).
+		Foreground(theme.Current().Accent).
 		Bold(true).
 		MarginBottom(1)
 
 	buttonStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("7"))
+		Foreground(theme.Current().Text)
 
 	focusedButtonStyle := buttonStyle
 	focusedButtonStyle = focusedButtonStyle.
-		Background(lipgloss.Color("62")).
-		Foreground(lipgloss.Color("0"))
+		Background(theme.Current().Accent).
+		Foreground(theme.Current().AccentText)
 
 	// Set textarea width to fit within the overlay
 	t.textarea.SetWidth(t.width - 6) // Account for padding and borders
@@ -8,14 +8,13 @@ import (
 	"github.com/charmbracelet/lipgloss"
 )
 
-var previewPaneStyle = lipgloss.NewStyle().
-	Foreground(lipgloss.AdaptiveColor{Light: "#1a1a1a", Dark: "#dddddd"})
-
 type PreviewPane struct {
 	width  int
 	height int
 
 	previewState previewState
+	// quickInput is shown at the bottom while it's open, with the bottom of the pane above it.
+	quickInput *QuickInput
 }
 
 type previewState struct {
@@ -34,6 +33,11 @@ func (p *PreviewPane) SetSize(width, maxHeight int) {
 	p.height = maxHeight
 }
 
+// SetQuickInput shows the quick input at the bottom of the pane, or hides it if it's nil.
+func (p *PreviewPane) SetQuickInput(q *QuickInput) {
+	p.quickInput = q
+}
+
 // setFallbackState sets the preview state with fallback text and a message
 func (p *PreviewPane) setFallbackState(message string) {
 	p.previewState = previewState{
@@ -48,25 +52,44 @@ func (p *PreviewPane) UpdateContent(instance *session.Instance) error {
 	case instance == nil:
 		p.setFallbackState("No agents running yet. Spin up a new instance with 'n' to get started!")
 		return nil
+	case instance.Queued():
+		p.setFallbackState(lipgloss.JoinVertical(lipgloss.Center,
+			"Session is queued: max_running_instances sessions are running.",
+			"",
+			pausedNoteStyle.Render("It starts once one of them is paused or killed, along with the prompts sent to it meanwhile."),
+		))
+		return nil
+	case instance.Status == session.Paused && instance.PausedScreen() != "":
+		// Show the screen the instance was paused on, to recall what the agent was doing.
+		p.previewState = previewState{
+			fallback: false,
+			text: pausedNoteStyle.Render(fmt.Sprintf(
+				"Paused, showing its last screen. Press 'r' to resume, or check out '%s'.", instance.Branch,
+			)) + "\n\n" + instance.PausedScreen(),
+		}
+		return nil
 	case instance.Status == session.Paused:
 		p.setFallbackState(lipgloss.JoinVertical(lipgloss.Center,
 			"Session is paused. Press 'r' to resume.",
 			"",
-			lipgloss.NewStyle().
-				Foreground(lipgloss.AdaptiveColor{
-					Light: "#FFD700",
-					Dark:  "#FFD700",
-				}).
-				Render(fmt.Sprintf(
-					"The instance can be checked out at '%s' (copied to your clipboard)",
-					instance.Branch,
-				)),
+			pausedNoteStyle.Render(fmt.Sprintf(
+				"The instance can be checked out at '%s' (copied to your clipboard)",
+				instance.Branch,
+			)),
 		))
 		return nil
 	}
 
 	content, err := instance.Preview()
 	if err != nil {
+		if instance.SessionGone() {
+			p.setFallbackState(lipgloss.JoinVertical(lipgloss.Center,
+				"The session is gone, e.g. because the machine rebooted.",
+				"",
+				fmt.Sprintf("Press enter to recreate it from the branch '%s'.", instance.Branch),
+			))
+			return nil
+		}
 		return err
 	}
 
@@ -87,10 +110,14 @@ func (p *PreviewPane) String() string {
 	if p.width == 0 || p.height == 0 {
 		return strings.Repeat("\n", p.height)
 	}
+	inputHeight := 0
+	if p.quickInput != nil {
+		inputHeight = p.quickInput.Height()
+	}
 
 	if p.previewState.fallback {
 		// Calculate available height for fallback text
-		availableHeight := p.height - 3 - 4 // 2 for borders, 1 for margin, 1 for padding
+		availableHeight := p.height - 3 - 4 - inputHeight // 2 for borders, 1 for margin, 1 for padding
 
 		// Count the number of lines in the fallback text
 		fallbackLines := len(strings.Split(p.previewState.text, "\n"))
@@ -115,20 +142,29 @@ func (p *PreviewPane) String() string {
 		}
 
 		// Center both vertically and horizontally
-		return previewPaneStyle.
+		return p.withQuickInput(previewPaneStyle.
 			Width(p.width).
 			Align(lipgloss.Center).
-			Render(strings.Join(lines, ""))
+			Render(strings.Join(lines, "")))
 	}
 
 	// Calculate available height accounting for border and margin
-	availableHeight := p.height - 1 //  1 for ellipsis
+	availableHeight := p.height - 1 - inputHeight //  1 for ellipsis
 
 	lines := strings.Split(p.previewState.text, "\n")
+	if p.quickInput != nil {
+		// The blank lines under the cursor would push the question out of the pane.
+		for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+			lines = lines[:len(lines)-1]
+		}
+	}
 
 	// Truncate if we have more lines than available height
 	if availableHeight > 0 {
-		if len(lines) > availableHeight {
+		if p.quickInput != nil && len(lines) > availableHeight+1 {
+			// The bottom of the pane is kept, where the agent asks its questions.
+			lines = lines[len(lines)-availableHeight-1:]
+		} else if len(lines) > availableHeight {
 			lines = lines[:availableHeight]
 			lines = append(lines, "...")
 		} else {
@@ -140,5 +176,13 @@ func (p *PreviewPane) String() string {
 
 	content := strings.Join(lines, "\n")
 	rendered := previewPaneStyle.Width(p.width).Render(content)
-	return rendered
+	return p.withQuickInput(rendered)
+}
+
+// withQuickInput appends the quick input to the rendered pane, if it's open.
+func (p *PreviewPane) withQuickInput(rendered string) string {
+	if p.quickInput == nil {
+		return rendered
+	}
+	return rendered + "\n" + p.quickInput.String(p.width)
 }
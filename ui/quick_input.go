@@ -0,0 +1,120 @@
+package ui
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// quickInputHint tells what the keys of the quick input do, under it.
+const quickInputHint = "enter sends the line · ctrl+c, esc, tab and arrows go straight through · ctrl+q closes"
+
+// QuickInput is the bar at the bottom of the preview that types into the selected instance without
+// attaching to it, e.g. to answer a question the agent asked. A line is edited in the bar and sent with
+// enter, while the special keys are sent as they're pressed.
+type QuickInput struct {
+	line   []rune
+	cursor int
+}
+
+// NewQuickInput returns an empty quick input.
+func NewQuickInput() *QuickInput {
+	return &QuickInput{}
+}
+
+// HandleKeyPress returns what the key press types into the instance, if anything: the line followed by
+// enter, which is sent separately so it isn't taken for a new line of a paste, or the sequence of a
+// special key. closed is true if the quick input is closed.
+func (q *QuickInput) HandleKeyPress(msg tea.KeyMsg) (keys []string, closed bool) {
+	switch msg.Type {
+	case tea.KeyCtrlQ:
+		return nil, true
+	case tea.KeyEnter:
+		line := string(q.line)
+		q.clear()
+		if line == "" {
+			return []string{"\r"}, false
+		}
+		return []string{line, "\r"}, false
+	case tea.KeyEsc:
+		// Esc clears the line first, so a typo doesn't need backspacing.
+		if len(q.line) > 0 {
+			q.clear()
+			return nil, false
+		}
+		return []string{"\x1b"}, false
+	case tea.KeyTab:
+		return []string{"\t"}, false
+	case tea.KeyShiftTab:
+		return []string{"\x1b[Z"}, false
+	case tea.KeyUp:
+		return []string{"\x1b[A"}, false
+	case tea.KeyDown:
+		return []string{"\x1b[B"}, false
+	case tea.KeyLeft:
+		if len(q.line) == 0 {
+			return []string{"\x1b[D"}, false
+		}
+		q.cursor = max(q.cursor-1, 0)
+		return nil, false
+	case tea.KeyRight:
+		if len(q.line) == 0 {
+			return []string{"\x1b[C"}, false
+		}
+		q.cursor = min(q.cursor+1, len(q.line))
+		return nil, false
+	case tea.KeyBackspace:
+		if len(q.line) == 0 {
+			return []string{"\x7f"}, false
+		}
+		if q.cursor > 0 {
+			q.line = append(q.line[:q.cursor-1], q.line[q.cursor:]...)
+			q.cursor--
+		}
+		return nil, false
+	case tea.KeyRunes, tea.KeySpace:
+		runes := msg.Runes
+		if msg.Type == tea.KeySpace {
+			runes = []rune{' '}
+		}
+		q.line = append(q.line[:q.cursor], append(runes, q.line[q.cursor:]...)...)
+		q.cursor += len(runes)
+		return nil, false
+	}
+	// The other control keys, like ctrl+c or ctrl+d, are sent as their control characters, clearing the
+	// line since they usually interrupt what's being typed.
+	if msg.Type >= tea.KeyCtrlA && msg.Type <= tea.KeyCtrlZ {
+		q.clear()
+		return []string{string(rune(msg.Type))}, false
+	}
+	return nil, false
+}
+
+func (q *QuickInput) clear() {
+	q.line = nil
+	q.cursor = 0
+}
+
+// Height is the number of lines the quick input takes.
+func (q *QuickInput) Height() int {
+	return 2
+}
+
+// String renders the quick input, the line with its cursor and the hint, truncated to width.
+func (q *QuickInput) String(width int) string {
+	cursor := " "
+	if q.cursor < len(q.line) {
+		cursor = string(q.line[q.cursor])
+	}
+	var after string
+	if q.cursor < len(q.line) {
+		after = string(q.line[q.cursor+1:])
+	}
+	line := quickInputPromptStyle.Render("› ") + string(q.line[:q.cursor]) +
+		quickInputCursorStyle.Render(cursor) + after
+	hint := quickInputHintStyle.Render(quickInputHint)
+	return strings.Join([]string{
+		quickInputStyle.MaxWidth(width).Render(line),
+		quickInputStyle.MaxWidth(width).Render(hint),
+	}, "\n")
+}
@@ -0,0 +1,66 @@
+package ui
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuickInputHandleKeyPress(t *testing.T) {
+	runes := func(s string) tea.KeyMsg { return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)} }
+	key := func(k tea.KeyType) tea.KeyMsg { return tea.KeyMsg{Type: k} }
+
+	tests := []struct {
+		name string
+		// typed are the keys pressed before key, and line and cursor the state of the line after it.
+		typed  []tea.KeyMsg
+		key    tea.KeyMsg
+		keys   []string
+		closed bool
+		line   string
+		cursor int
+	}{
+		{name: "runes are edited in the line", key: runes("hé"), line: "hé", cursor: 2},
+		{name: "space is typed", typed: []tea.KeyMsg{runes("a")}, key: key(tea.KeySpace), line: "a ", cursor: 2},
+		{name: "enter sends the line, then enter", typed: []tea.KeyMsg{runes("yes")}, key: key(tea.KeyEnter),
+			keys: []string{"yes", "\r"}},
+		{name: "enter on an empty line is sent", key: key(tea.KeyEnter), keys: []string{"\r"}},
+		{name: "esc clears the line", typed: []tea.KeyMsg{runes("typo")}, key: key(tea.KeyEsc)},
+		{name: "esc on an empty line is sent", key: key(tea.KeyEsc), keys: []string{"\x1b"}},
+		{name: "ctrl+c is sent and clears the line", typed: []tea.KeyMsg{runes("half")}, key: key(tea.KeyCtrlC),
+			keys: []string{"\x03"}},
+		{name: "ctrl+d is sent", key: key(tea.KeyCtrlD), keys: []string{"\x04"}},
+		{name: "tab is sent", typed: []tea.KeyMsg{runes("ab")}, key: key(tea.KeyTab), keys: []string{"\t"},
+			line: "ab", cursor: 2},
+		{name: "shift+tab is sent", key: key(tea.KeyShiftTab), keys: []string{"\x1b[Z"}},
+		{name: "arrows are sent", key: key(tea.KeyUp), keys: []string{"\x1b[A"}},
+		{name: "left on an empty line is sent", key: key(tea.KeyLeft), keys: []string{"\x1b[D"}},
+		{name: "left moves the cursor in the line", typed: []tea.KeyMsg{runes("ab")}, key: key(tea.KeyLeft),
+			line: "ab", cursor: 1},
+		{name: "right stops at the end of the line", typed: []tea.KeyMsg{runes("ab")}, key: key(tea.KeyRight),
+			line: "ab", cursor: 2},
+		{name: "runes are inserted at the cursor", typed: []tea.KeyMsg{runes("ac"), key(tea.KeyLeft)}, key: runes("b"),
+			line: "abc", cursor: 2},
+		{name: "backspace deletes before the cursor", typed: []tea.KeyMsg{runes("abc"), key(tea.KeyLeft)},
+			key: key(tea.KeyBackspace), line: "ac", cursor: 1},
+		{name: "backspace at the start does nothing", typed: []tea.KeyMsg{runes("a"), key(tea.KeyLeft)},
+			key: key(tea.KeyBackspace), line: "a", cursor: 0},
+		{name: "backspace on an empty line is sent", key: key(tea.KeyBackspace), keys: []string{"\x7f"}},
+		{name: "ctrl+q closes", typed: []tea.KeyMsg{runes("ab")}, key: key(tea.KeyCtrlQ), closed: true,
+			line: "ab", cursor: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := NewQuickInput()
+			for _, msg := range tt.typed {
+				q.HandleKeyPress(msg)
+			}
+			keys, closed := q.HandleKeyPress(tt.key)
+			assert.Equal(t, tt.keys, keys)
+			assert.Equal(t, tt.closed, closed)
+			assert.Equal(t, tt.line, string(q.line))
+			assert.Equal(t, tt.cursor, q.cursor)
+		})
+	}
+}
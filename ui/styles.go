@@ -0,0 +1,129 @@
+package ui
+
+import (
+	"claude-squad/ui/theme"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// The styles of the panes are built from the theme, and built again when it changes.
+var (
+	readyStyle        lipgloss.Style
+	addedLinesStyle   lipgloss.Style
+	removedLinesStyle lipgloss.Style
+	// diffWarnedStyle and diffFlaggedStyle color the diff stat of an instance past the warn and the flag
+	// limits of the diff budget.
+	diffWarnedStyle    lipgloss.Style
+	diffFlaggedStyle   lipgloss.Style
+	needsAuthStyle     lipgloss.Style
+	pausedStyle        lipgloss.Style
+	titleStyle         lipgloss.Style
+	listDescStyle      lipgloss.Style
+	selectedTitleStyle lipgloss.Style
+	selectedDescStyle  lipgloss.Style
+	mainTitle          lipgloss.Style
+	// blurredTitle is the title of the list while another pane has the focus.
+	blurredTitle lipgloss.Style
+	bannerStyle  lipgloss.Style
+	autoYesStyle lipgloss.Style
+
+	AdditionStyle lipgloss.Style
+	DeletionStyle lipgloss.Style
+	HunkStyle     lipgloss.Style
+	WarningStyle  lipgloss.Style
+
+	keyStyle         lipgloss.Style
+	descStyle        lipgloss.Style
+	sepStyle         lipgloss.Style
+	actionGroupStyle lipgloss.Style
+	menuStyle        lipgloss.Style
+
+	errStyle         lipgloss.Style
+	todoDoneStyle    lipgloss.Style
+	previewPaneStyle lipgloss.Style
+	pausedNoteStyle  lipgloss.Style
+
+	quickInputStyle       lipgloss.Style
+	quickInputPromptStyle lipgloss.Style
+	quickInputCursorStyle lipgloss.Style
+	quickInputHintStyle   lipgloss.Style
+
+	inactiveTabStyle lipgloss.Style
+	activeTabStyle   lipgloss.Style
+	windowStyle      lipgloss.Style
+)
+
+func init() {
+	theme.OnChange(applyTheme)
+}
+
+// applyTheme builds the styles of the panes from the theme.
+func applyTheme(t theme.Theme) {
+	readyStyle = lipgloss.NewStyle().Foreground(t.Ready)
+	addedLinesStyle = lipgloss.NewStyle().Foreground(t.Added)
+	removedLinesStyle = lipgloss.NewStyle().Foreground(t.Removed)
+	diffWarnedStyle = lipgloss.NewStyle().Foreground(t.Warning)
+	diffFlaggedStyle = lipgloss.NewStyle().Bold(true).Foreground(t.Danger)
+	needsAuthStyle = lipgloss.NewStyle().Foreground(t.Danger)
+	pausedStyle = lipgloss.NewStyle().Foreground(t.Paused)
+	titleStyle = lipgloss.NewStyle().
+		Padding(1, 1, 0, 1).
+		Foreground(t.Text)
+	listDescStyle = lipgloss.NewStyle().
+		Padding(0, 1, 1, 1).
+		Foreground(t.Subtle)
+	selectedTitleStyle = lipgloss.NewStyle().
+		Padding(1, 1, 0, 1).
+		Background(t.SelectedBackground).
+		Foreground(t.SelectedText)
+	selectedDescStyle = lipgloss.NewStyle().
+		Padding(0, 1, 1, 1).
+		Background(t.SelectedBackground).
+		Foreground(t.SelectedText)
+	mainTitle = lipgloss.NewStyle().
+		Background(t.Accent).
+		Foreground(t.AccentText)
+	blurredTitle = lipgloss.NewStyle().
+		Background(t.Muted).
+		Foreground(t.AccentText)
+	bannerStyle = lipgloss.NewStyle().
+		Background(t.Danger).
+		Foreground(lipgloss.Color("#ffffff"))
+	autoYesStyle = lipgloss.NewStyle().
+		Background(t.SelectedBackground).
+		Foreground(t.SelectedText)
+
+	AdditionStyle = lipgloss.NewStyle().Foreground(t.Added)
+	DeletionStyle = lipgloss.NewStyle().Foreground(t.Removed)
+	HunkStyle = lipgloss.NewStyle().Foreground(t.Hunk)
+	WarningStyle = lipgloss.NewStyle().Foreground(t.Warning)
+
+	keyStyle = lipgloss.NewStyle().Foreground(t.Muted)
+	descStyle = lipgloss.NewStyle().Foreground(t.Subtle)
+	sepStyle = lipgloss.NewStyle().Foreground(t.Faint)
+	actionGroupStyle = lipgloss.NewStyle().Foreground(t.Accent)
+	menuStyle = lipgloss.NewStyle().Foreground(t.Highlight)
+
+	errStyle = lipgloss.NewStyle().Foreground(t.Danger)
+	todoDoneStyle = lipgloss.NewStyle().
+		Foreground(t.Subtle).
+		Strikethrough(true)
+	previewPaneStyle = lipgloss.NewStyle().Foreground(t.Text)
+	pausedNoteStyle = lipgloss.NewStyle().Foreground(t.Warning)
+
+	quickInputStyle = lipgloss.NewStyle().Foreground(t.Text)
+	quickInputPromptStyle = lipgloss.NewStyle().Bold(true).Foreground(t.Accent)
+	quickInputCursorStyle = lipgloss.NewStyle().Reverse(true)
+	quickInputHintStyle = lipgloss.NewStyle().Foreground(t.Muted)
+
+	inactiveTabStyle = lipgloss.NewStyle().
+		Border(inactiveTabBorder, true).
+		BorderForeground(t.Border).
+		AlignHorizontal(lipgloss.Center)
+	activeTabStyle = inactiveTabStyle.
+		Border(activeTabBorder, true).
+		AlignHorizontal(lipgloss.Center)
+	windowStyle = lipgloss.NewStyle().
+		BorderForeground(t.Border).
+		Border(lipgloss.NormalBorder(), false, true, true, true)
+}
@@ -17,22 +17,12 @@ func tabBorderWithBottom(left, middle, right string) lipgloss.Border {
 var (
 	inactiveTabBorder = tabBorderWithBottom("┴", "─", "┴")
 	activeTabBorder   = tabBorderWithBottom("┘", " ", "└")
-	highlightColor    = lipgloss.AdaptiveColor{Light: "#874BFD", Dark: "#7D56F4"}
-	inactiveTabStyle  = lipgloss.NewStyle().
-				Border(inactiveTabBorder, true).
-				BorderForeground(highlightColor).
-				AlignHorizontal(lipgloss.Center)
-	activeTabStyle = inactiveTabStyle.
-			Border(activeTabBorder, true).
-			AlignHorizontal(lipgloss.Center)
-	windowStyle = lipgloss.NewStyle().
-			BorderForeground(highlightColor).
-			Border(lipgloss.NormalBorder(), false, true, true, true)
 )
 
 const (
 	PreviewTab = iota
 	DiffTab
+	NotesTab
 )
 
 type Tab struct {
@@ -41,7 +31,8 @@ type Tab struct {
 }
 
 // TabbedWindow has tabs at the top of a pane which can be selected. The tabs
-// take up one rune of height.
+// take up one rune of height. In the split layout, it shows the preview and the
+// diff side by side instead, each under its own tab.
 type TabbedWindow struct {
 	tabs []string
 
@@ -49,18 +40,28 @@ type TabbedWindow struct {
 	height    int
 	width     int
 
+	// split shows the preview and the diff side by side, previewWidth and diffWidth wide.
+	split        bool
+	previewWidth int
+	diffWidth    int
+	// diffFocused highlights the diff instead of the preview in the split layout.
+	diffFocused bool
+
 	preview *PreviewPane
 	diff    *DiffPane
+	notes   *NotesPane
 }
 
-func NewTabbedWindow(preview *PreviewPane, diff *DiffPane) *TabbedWindow {
+func NewTabbedWindow(preview *PreviewPane, diff *DiffPane, notes *NotesPane) *TabbedWindow {
 	return &TabbedWindow{
 		tabs: []string{
 			"Preview",
 			"Diff",
+			"Notes",
 		},
 		preview: preview,
 		diff:    diff,
+		notes:   notes,
 	}
 }
 
@@ -69,20 +70,52 @@ func AdjustPreviewWidth(width int) int {
 	return int(float64(width) * 0.9)
 }
 
+// SetSize sets the size of the window and shows the panes in tabs.
 func (w *TabbedWindow) SetSize(width, height int) {
+	w.split = false
 	w.width = AdjustPreviewWidth(width)
 	w.height = height
 
-	// Calculate the content height by subtracting:
-	// 1. Tab height (including border and padding)
-	// 2. Window style vertical frame size
-	// 3. Additional padding/spacing (2 for the newline and spacing)
-	tabHeight := activeTabStyle.GetVerticalFrameSize() + 1
-	contentHeight := height - tabHeight - windowStyle.GetVerticalFrameSize() - 2
+	contentHeight := w.contentHeight()
 	contentWidth := w.width - windowStyle.GetHorizontalFrameSize()
 
 	w.preview.SetSize(contentWidth, contentHeight)
 	w.diff.SetSize(contentWidth, contentHeight)
+	w.notes.SetSize(contentWidth, contentHeight)
+}
+
+// SetSplitSize sets the size of the window and shows the preview and the diff side by side, sharing the
+// width by the ratio of previewRatio to diffRatio.
+func (w *TabbedWindow) SetSplitSize(width, height, previewRatio, diffRatio int) {
+	w.split = true
+	w.width = AdjustPreviewWidth(width)
+	w.height = height
+	// Each pane has its own border.
+	w.previewWidth = (w.width - windowStyle.GetHorizontalFrameSize()) * previewRatio / (previewRatio + diffRatio)
+	w.diffWidth = w.width - windowStyle.GetHorizontalFrameSize() - w.previewWidth
+
+	contentHeight := w.contentHeight()
+	w.preview.SetSize(w.previewWidth-windowStyle.GetHorizontalFrameSize(), contentHeight)
+	w.diff.SetSize(w.diffWidth-windowStyle.GetHorizontalFrameSize(), contentHeight)
+}
+
+// contentHeight returns the height of the panes, which is the height of the window minus:
+// 1. Tab height (including border and padding)
+// 2. Window style vertical frame size
+// 3. Additional padding/spacing (2 for the newline and spacing)
+func (w *TabbedWindow) contentHeight() int {
+	tabHeight := activeTabStyle.GetVerticalFrameSize() + 1
+	return w.height - tabHeight - windowStyle.GetVerticalFrameSize() - 2
+}
+
+// IsSplit returns true if the preview and the diff are shown side by side.
+func (w *TabbedWindow) IsSplit() bool {
+	return w.split
+}
+
+// SetDiffFocused highlights the diff instead of the preview in the split layout.
+func (w *TabbedWindow) SetDiffFocused(focused bool) {
+	w.diffFocused = focused
 }
 
 func (w *TabbedWindow) GetPreviewSize() (width, height int) {
@@ -93,43 +126,64 @@ func (w *TabbedWindow) Toggle() {
 	w.activeTab = (w.activeTab + 1) % len(w.tabs)
 }
 
+// SetQuickInput shows the quick input in the preview, switching to its tab, or hides it if it's nil.
+func (w *TabbedWindow) SetQuickInput(q *QuickInput) {
+	w.preview.SetQuickInput(q)
+	if q != nil && !w.split {
+		w.activeTab = PreviewTab
+	}
+}
+
 // UpdatePreview updates the content of the preview pane. instance may be nil.
 func (w *TabbedWindow) UpdatePreview(instance *session.Instance) error {
-	if w.activeTab != PreviewTab {
+	if !w.split && w.activeTab != PreviewTab {
 		return nil
 	}
 	return w.preview.UpdateContent(instance)
 }
 
 func (w *TabbedWindow) UpdateDiff(instance *session.Instance) {
-	if w.activeTab != DiffTab {
+	if !w.split && w.activeTab != DiffTab {
 		return
 	}
 	w.diff.SetDiff(instance)
 }
 
+// UpdateNotes updates the notes pane with the notes and TODOs of the instance, which may be nil.
+func (w *TabbedWindow) UpdateNotes(instance *session.Instance) {
+	if w.split || w.activeTab != NotesTab {
+		return
+	}
+	w.notes.SetNotes(instance)
+}
+
 // Add these new methods for handling scroll events
 func (w *TabbedWindow) ScrollUp() {
-	if w.activeTab == 1 { // Diff tab
+	if w.IsInDiffTab() {
 		w.diff.ScrollUp()
 	}
 }
 
 func (w *TabbedWindow) ScrollDown() {
-	if w.activeTab == 1 { // Diff tab
+	if w.IsInDiffTab() {
 		w.diff.ScrollDown()
 	}
 }
 
-// IsInDiffTab returns true if the diff tab is currently active
+// IsInDiffTab returns true if the diff is shown, i.e. the diff tab is active or the layout is split
 func (w *TabbedWindow) IsInDiffTab() bool {
-	return w.activeTab == 1
+	return w.split || w.activeTab == DiffTab
 }
 
 func (w *TabbedWindow) String() string {
 	if w.width == 0 || w.height == 0 {
 		return ""
 	}
+	if w.split {
+		return lipgloss.JoinVertical(lipgloss.Left, "\n", lipgloss.JoinHorizontal(lipgloss.Top,
+			w.renderPane("Preview", w.preview.String(), w.previewWidth, !w.diffFocused),
+			w.renderPane("Diff", w.diff.String(), w.diffWidth, w.diffFocused)))
+	}
 
 	var renderedTabs []string
 
@@ -167,10 +221,13 @@ func (w *TabbedWindow) String() string {
 
 	row := lipgloss.JoinHorizontal(lipgloss.Top, renderedTabs...)
 	var content string
-	if w.activeTab == 0 {
+	switch w.activeTab {
+	case PreviewTab:
 		content = w.preview.String()
-	} else {
+	case DiffTab:
 		content = w.diff.String()
+	default:
+		content = w.notes.String()
 	}
 	window := windowStyle.Render(
 		lipgloss.Place(
@@ -179,3 +236,23 @@ func (w *TabbedWindow) String() string {
 
 	return lipgloss.JoinVertical(lipgloss.Left, "\n", row, window)
 }
+
+// renderPane renders a pane of the split layout under a tab with its name, which is highlighted like the
+// active tab if the pane is focused.
+func (w *TabbedWindow) renderPane(name, content string, width int, focused bool) string {
+	style := inactiveTabStyle
+	border, _, _, _, _ := style.GetBorder()
+	border.BottomLeft, border.BottomRight = "├", "┤"
+	if focused {
+		style = activeTabStyle
+		border, _, _, _, _ = style.GetBorder()
+		border.BottomLeft, border.BottomRight = "│", "│"
+	}
+	tab := style.Border(border).Width(width).Render(name)
+	tabHeight := activeTabStyle.GetVerticalFrameSize() + 1
+	window := windowStyle.Render(
+		lipgloss.Place(
+			width, w.height-2-windowStyle.GetVerticalFrameSize()-tabHeight,
+			lipgloss.Left, lipgloss.Top, content))
+	return lipgloss.JoinVertical(lipgloss.Left, tab, window)
+}
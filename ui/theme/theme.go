@@ -0,0 +1,181 @@
+// Package theme holds the color themes of the TUI. The packages drawing the TUI register with OnChange to
+// restyle when the theme changes, so it can be switched while the app runs.
+package theme
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Theme is the palette of the TUI.
+type Theme struct {
+	// Name is how the theme is picked, in the config or at runtime.
+	Name string
+	// Description tells what the theme is for, in the theme picker.
+	Description string
+
+	// Text is the color of the main text, e.g. the titles of the instances and the preview.
+	Text lipgloss.TerminalColor
+	// Subtle is the color of secondary text, e.g. the branches of the instances.
+	Subtle lipgloss.TerminalColor
+	// Muted is the color of hints and of the titles of the panes without the focus.
+	Muted lipgloss.TerminalColor
+	// Faint is the color of separators and shadows.
+	Faint lipgloss.TerminalColor
+	// Accent is the color of titles and of the selected items of the overlays.
+	Accent lipgloss.TerminalColor
+	// AccentText is the color of text on the Accent color.
+	AccentText lipgloss.TerminalColor
+	// Highlight is the color of keys in the menu and the help.
+	Highlight lipgloss.TerminalColor
+	// Heading is the color of the headings of the help.
+	Heading lipgloss.TerminalColor
+	// Border is the color of the borders of the panes and the overlays.
+	Border lipgloss.TerminalColor
+	// SelectedBackground and SelectedText are the colors of the selected instance.
+	SelectedBackground lipgloss.TerminalColor
+	SelectedText       lipgloss.TerminalColor
+
+	// Ready is the color of instances that are ready.
+	Ready lipgloss.TerminalColor
+	// Paused is the color of instances that are paused or gone.
+	Paused lipgloss.TerminalColor
+	// Warning is the color of instances and diffs that need a look, e.g. past the warn limit of the diff
+	// budget.
+	Warning lipgloss.TerminalColor
+	// Danger is the color of instances that need attention, and of errors.
+	Danger lipgloss.TerminalColor
+
+	// Added and Removed are the colors of the added and removed lines of diffs.
+	Added   lipgloss.TerminalColor
+	Removed lipgloss.TerminalColor
+	// Hunk is the color of the hunk headers of diffs.
+	Hunk lipgloss.TerminalColor
+}
+
+// Default is the original theme, made for dark terminals.
+var Default = Theme{
+	Name:        "default",
+	Description: "made for dark terminals",
+
+	Text:               lipgloss.AdaptiveColor{Light: "#1a1a1a", Dark: "#dddddd"},
+	Subtle:             lipgloss.AdaptiveColor{Light: "#A49FA5", Dark: "#777777"},
+	Muted:              lipgloss.Color("241"),
+	Faint:              lipgloss.AdaptiveColor{Light: "#DDDADA", Dark: "#3C3C3C"},
+	Accent:             lipgloss.Color("62"),
+	AccentText:         lipgloss.Color("230"),
+	Highlight:          lipgloss.Color("#FFCC00"),
+	Heading:            lipgloss.Color("#36CFC9"),
+	Border:             lipgloss.AdaptiveColor{Light: "#874BFD", Dark: "#7D56F4"},
+	SelectedBackground: lipgloss.Color("#dde4f0"),
+	SelectedText:       lipgloss.Color("#1a1a1a"),
+
+	Ready:   lipgloss.Color("#51bd73"),
+	Paused:  lipgloss.Color("#888888"),
+	Warning: lipgloss.Color("#f0a500"),
+	Danger:  lipgloss.Color("#de613e"),
+
+	Added:   lipgloss.Color("#22c55e"),
+	Removed: lipgloss.Color("#ef4444"),
+	Hunk:    lipgloss.Color("#0ea5e9"),
+}
+
+// Light is a theme for light terminals, with darker colors that stay readable on a white background.
+var Light = Theme{
+	Name:        "light",
+	Description: "for light terminals",
+
+	Text:               lipgloss.Color("#1f2328"),
+	Subtle:             lipgloss.Color("#57606a"),
+	Muted:              lipgloss.Color("#6e7781"),
+	Faint:              lipgloss.Color("#d0d7de"),
+	Accent:             lipgloss.Color("#6639ba"),
+	AccentText:         lipgloss.Color("#ffffff"),
+	Highlight:          lipgloss.Color("#953800"),
+	Heading:            lipgloss.Color("#0550ae"),
+	Border:             lipgloss.Color("#8250df"),
+	SelectedBackground: lipgloss.Color("#ddf4ff"),
+	SelectedText:       lipgloss.Color("#1f2328"),
+
+	Ready:   lipgloss.Color("#1a7f37"),
+	Paused:  lipgloss.Color("#6e7781"),
+	Warning: lipgloss.Color("#9a6700"),
+	Danger:  lipgloss.Color("#cf222e"),
+
+	Added:   lipgloss.Color("#1a7f37"),
+	Removed: lipgloss.Color("#cf222e"),
+	Hunk:    lipgloss.Color("#0969da"),
+}
+
+// Colorblind is a theme without red and green pairs, from the Okabe-Ito palette, readable with the common
+// kinds of color blindness. Added lines are blue and removed ones orange.
+var Colorblind = Theme{
+	Name:        "colorblind",
+	Description: "without red and green pairs, for color blindness",
+
+	Text:               lipgloss.AdaptiveColor{Light: "#1a1a1a", Dark: "#dddddd"},
+	Subtle:             lipgloss.AdaptiveColor{Light: "#6e6e6e", Dark: "#9a9a9a"},
+	Muted:              lipgloss.Color("245"),
+	Faint:              lipgloss.AdaptiveColor{Light: "#d0d0d0", Dark: "#3c3c3c"},
+	Accent:             lipgloss.Color("#0072B2"),
+	AccentText:         lipgloss.Color("#ffffff"),
+	Highlight:          lipgloss.Color("#E69F00"),
+	Heading:            lipgloss.Color("#56B4E9"),
+	Border:             lipgloss.Color("#56B4E9"),
+	SelectedBackground: lipgloss.Color("#dde4f0"),
+	SelectedText:       lipgloss.Color("#1a1a1a"),
+
+	Ready:   lipgloss.Color("#56B4E9"),
+	Paused:  lipgloss.Color("#999999"),
+	Warning: lipgloss.Color("#E69F00"),
+	Danger:  lipgloss.Color("#D55E00"),
+
+	Added:   lipgloss.Color("#0072B2"),
+	Removed: lipgloss.Color("#E69F00"),
+	Hunk:    lipgloss.Color("#CC79A7"),
+}
+
+// Themes are the themes to pick from.
+var Themes = []Theme{Default, Light, Colorblind}
+
+var (
+	current = Default
+	// listeners are called with the theme when it changes.
+	listeners []func(Theme)
+)
+
+// Find returns the theme named name. An empty name is the default theme.
+func Find(name string) (Theme, error) {
+	if name == "" {
+		return Default, nil
+	}
+	names := make([]string, len(Themes))
+	for i, t := range Themes {
+		if strings.EqualFold(t.Name, name) {
+			return t, nil
+		}
+		names[i] = t.Name
+	}
+	return Default, fmt.Errorf("unknown theme %q, pick one of %s", name, strings.Join(names, ", "))
+}
+
+// Current returns the theme in use.
+func Current() Theme {
+	return current
+}
+
+// Set switches to the theme, restyling the TUI.
+func Set(t Theme) {
+	current = t
+	for _, listener := range listeners {
+		listener(t)
+	}
+}
+
+// OnChange calls listener with the theme in use, and again whenever it changes.
+func OnChange(listener func(Theme)) {
+	listeners = append(listeners, listener)
+	listener(current)
+}
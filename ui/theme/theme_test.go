@@ -0,0 +1,32 @@
+package theme
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFind(t *testing.T) {
+	found, err := Find("Light")
+	require.NoError(t, err)
+	assert.Equal(t, "light", found.Name)
+
+	found, err = Find("")
+	require.NoError(t, err)
+	assert.Equal(t, "default", found.Name)
+
+	// An unknown theme falls back to the default one.
+	found, err = Find("solarized")
+	assert.ErrorContains(t, err, "default, light, colorblind")
+	assert.Equal(t, "default", found.Name)
+}
+
+func TestOnChange(t *testing.T) {
+	defer Set(Default)
+	var names []string
+	OnChange(func(t Theme) { names = append(names, t.Name) })
+	Set(Colorblind)
+	assert.Equal(t, []string{"default", "colorblind"}, names)
+	assert.Equal(t, "colorblind", Current().Name)
+}